@@ -0,0 +1,265 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/ernesto27/go-npm/utils"
+	"github.com/ernesto27/go-npm/version"
+)
+
+// bulkPath is the npm bulk advisory endpoint, relative to the registry
+const bulkPath = "-/npm/v1/security/advisories/bulk"
+
+// Severities are the advisory severities, least severe first
+var Severities = []string{"info", "low", "moderate", "high", "critical"}
+
+// LevelNone is the --audit-level that never fails
+const LevelNone = "none"
+
+// ValidateLevel checks that level is one of Severities or none
+func ValidateLevel(level string) error {
+	if level == LevelNone || slices.Contains(Severities, level) {
+		return nil
+	}
+	return fmt.Errorf("unknown audit level %q (expected %s or %s)", level, strings.Join(Severities, ", "), LevelNone)
+}
+
+// severityRank orders severities, -1 for an unknown one
+func severityRank(severity string) int {
+	return slices.Index(Severities, severity)
+}
+
+// Advisory is an entry of the bulk advisory response
+type Advisory struct {
+	ID                 int    `json:"id"`
+	Title              string `json:"title"`
+	URL                string `json:"url"`
+	Severity           string `json:"severity"`
+	VulnerableVersions string `json:"vulnerable_versions"`
+}
+
+// Finding is an installed package version affected by an advisory. FixedIn
+// is the lowest published version above it that the advisory does not
+// affect, empty when there is none or the versions are unknown.
+type Finding struct {
+	Name     string   `json:"name"`
+	Version  string   `json:"version"`
+	Advisory Advisory `json:"advisory"`
+	FixedIn  string   `json:"fixedIn,omitempty"`
+}
+
+// Report is the result of an audit, printed as is with --json
+type Report struct {
+	Findings []Finding      `json:"findings"`
+	Counts   map[string]int `json:"counts"`
+}
+
+// Client queries a registry's bulk advisory endpoint
+type Client struct {
+	registryURL string
+	retryPolicy utils.RetryPolicy
+}
+
+// New creates a Client for registryURL
+func New(registryURL string) *Client {
+	if !strings.HasSuffix(registryURL, "/") {
+		registryURL += "/"
+	}
+	return &Client{
+		registryURL: registryURL,
+		retryPolicy: utils.DefaultRetryPolicy(),
+	}
+}
+
+// SetRetries sets how many times a failed request is retried
+func (c *Client) SetRetries(retries int) {
+	c.retryPolicy.Retries = retries
+}
+
+// SetTimeout sets how long a single request may take before it is abandoned
+// and retried; zero waits indefinitely
+func (c *Client) SetTimeout(timeout time.Duration) {
+	c.retryPolicy.Timeout = timeout
+}
+
+// Bulk posts the installed versions of each package and returns the
+// advisories affecting any of them, keyed by package name
+func (c *Client) Bulk(ctx context.Context, installed map[string][]string) (map[string][]Advisory, error) {
+	body, err := json.Marshal(installed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode audit request: %w", err)
+	}
+
+	response, _, err := utils.PostWithRetryContext(ctx, c.registryURL+bulkPath, body, map[string]string{
+		"Content-Type": "application/json",
+		"Accept":       "application/json",
+	}, c.retryPolicy)
+	if err != nil {
+		return nil, fmt.Errorf("audit request to %s failed: %w", c.registryURL, err)
+	}
+
+	var advisories map[string][]Advisory
+	if err := json.Unmarshal(response, &advisories); err != nil {
+		return nil, fmt.Errorf("failed to parse audit response: %w", err)
+	}
+	return advisories, nil
+}
+
+// InstalledPackages returns the versions of each package in lock, skipping
+// links and optional packages not installed on this platform
+func InstalledPackages(lock *packagejson.PackageLock) map[string][]string {
+	installed := make(map[string][]string)
+	for pkgPath, item := range lock.Packages {
+		i := strings.LastIndex(pkgPath, "node_modules/")
+		if i < 0 || item.Link || item.Skipped || item.Version == "" {
+			continue
+		}
+		name := pkgPath[i+len("node_modules/"):]
+		if !slices.Contains(installed[name], item.Version) {
+			installed[name] = append(installed[name], item.Version)
+		}
+	}
+	for _, versions := range installed {
+		slices.Sort(versions)
+	}
+	return installed
+}
+
+// Findings matches the advisories against the installed versions. published
+// returns the versions of a package on the registry, to find the fix; it may
+// be nil.
+func Findings(installed map[string][]string, advisories map[string][]Advisory, published func(name string) []string) []Finding {
+	v := version.New()
+	var findings []Finding
+	for _, name := range slices.Sorted(maps.Keys(advisories)) {
+		var versions []string
+		if published != nil {
+			versions = published(name)
+		}
+		for _, installedVersion := range installed[name] {
+			for _, advisory := range advisories[name] {
+				if !v.SatisfiesConstraint(installedVersion, advisory.VulnerableVersions) {
+					continue
+				}
+				findings = append(findings, Finding{
+					Name:     name,
+					Version:  installedVersion,
+					Advisory: advisory,
+					FixedIn:  fixedVersion(v, installedVersion, advisory.VulnerableVersions, versions),
+				})
+			}
+		}
+	}
+
+	// Most severe first, then by package
+	slices.SortStableFunc(findings, func(a, b Finding) int {
+		return severityRank(b.Advisory.Severity) - severityRank(a.Advisory.Severity)
+	})
+	return findings
+}
+
+// fixedVersion returns the lowest of published above installed that
+// vulnerable does not match
+func fixedVersion(v *version.Info, installed, vulnerable string, published []string) string {
+	current, err := semver.NewVersion(installed)
+	if err != nil {
+		return ""
+	}
+
+	var fixed *semver.Version
+	for _, candidate := range published {
+		parsed, err := semver.NewVersion(candidate)
+		if err != nil || parsed.Prerelease() != "" || !parsed.GreaterThan(current) {
+			continue
+		}
+		if v.SatisfiesConstraint(candidate, vulnerable) {
+			continue
+		}
+		if fixed == nil || parsed.LessThan(fixed) {
+			fixed = parsed
+		}
+	}
+	if fixed == nil {
+		return ""
+	}
+	return fixed.Original()
+}
+
+// NewReport counts the findings by severity
+func NewReport(findings []Finding) Report {
+	report := Report{Findings: findings, Counts: make(map[string]int)}
+	if report.Findings == nil {
+		report.Findings = []Finding{}
+	}
+	for _, severity := range Severities {
+		report.Counts[severity] = 0
+	}
+	for _, finding := range findings {
+		report.Counts[finding.Advisory.Severity]++
+	}
+	return report
+}
+
+// AtOrAbove counts the findings whose severity is level or higher
+func (r Report) AtOrAbove(level string) int {
+	if level == LevelNone {
+		return 0
+	}
+	count := 0
+	for _, finding := range r.Findings {
+		if severityRank(finding.Advisory.Severity) >= severityRank(level) {
+			count++
+		}
+	}
+	return count
+}
+
+// Write prints the findings grouped by severity, most severe first
+func (r Report) Write(w io.Writer) {
+	if len(r.Findings) == 0 {
+		fmt.Fprintln(w, "found 0 vulnerabilities")
+		return
+	}
+
+	for i := len(Severities) - 1; i >= 0; i-- {
+		severity := Severities[i]
+		if r.Counts[severity] == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "%s (%d)\n", severity, r.Counts[severity])
+		for _, finding := range r.Findings {
+			if finding.Advisory.Severity != severity {
+				continue
+			}
+			fmt.Fprintf(w, "  %s@%s: %s\n", finding.Name, finding.Version, finding.Advisory.Title)
+			fmt.Fprintf(w, "    vulnerable: %s", finding.Advisory.VulnerableVersions)
+			if finding.FixedIn != "" {
+				fmt.Fprintf(w, ", fixed in %s", finding.FixedIn)
+			} else {
+				fmt.Fprint(w, ", no fix available")
+			}
+			fmt.Fprintln(w)
+			if finding.Advisory.URL != "" {
+				fmt.Fprintf(w, "    %s\n", finding.Advisory.URL)
+			}
+		}
+		fmt.Fprintln(w)
+	}
+
+	var counts []string
+	for i := len(Severities) - 1; i >= 0; i-- {
+		if n := r.Counts[Severities[i]]; n > 0 {
+			counts = append(counts, fmt.Sprintf("%d %s", n, Severities[i]))
+		}
+	}
+	fmt.Fprintf(w, "found %d vulnerabilities (%s)\n", len(r.Findings), strings.Join(counts, ", "))
+}
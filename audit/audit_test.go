@@ -0,0 +1,174 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBulk(t *testing.T) {
+	testCases := []struct {
+		name          string
+		statuses      []int
+		body          string
+		expected      map[string][]Advisory
+		errorContains string
+	}{
+		{
+			name:     "parses the advisories",
+			statuses: []int{http.StatusOK},
+			body:     `{"lodash": [{"id": 1, "title": "Prototype Pollution", "url": "https://example.com/1", "severity": "high", "vulnerable_versions": "<4.17.21"}]}`,
+			expected: map[string][]Advisory{
+				"lodash": {{ID: 1, Title: "Prototype Pollution", URL: "https://example.com/1", Severity: "high", VulnerableVersions: "<4.17.21"}},
+			},
+		},
+		{
+			name:     "retries server errors",
+			statuses: []int{http.StatusServiceUnavailable, http.StatusOK},
+			body:     `{}`,
+			expected: map[string][]Advisory{},
+		},
+		{
+			name:          "client error",
+			statuses:      []int{http.StatusBadRequest},
+			errorContains: "HTTP error",
+		},
+		{
+			name:          "invalid response",
+			statuses:      []int{http.StatusOK},
+			body:          `[`,
+			errorContains: "failed to parse audit response",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			attempt := 0
+			var request map[string][]string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, http.MethodPost, r.Method)
+				assert.Equal(t, "/-/npm/v1/security/advisories/bulk", r.URL.Path)
+				body, _ := io.ReadAll(r.Body)
+				require.NoError(t, json.Unmarshal(body, &request))
+
+				status := tc.statuses[min(attempt, len(tc.statuses)-1)]
+				attempt++
+				w.WriteHeader(status)
+				w.Write([]byte(tc.body))
+			}))
+			defer server.Close()
+
+			client := New(server.URL)
+			client.retryPolicy.BaseDelay = time.Millisecond
+			advisories, err := client.Bulk(context.Background(), map[string][]string{"lodash": {"4.17.20"}})
+
+			assert.Equal(t, map[string][]string{"lodash": {"4.17.20"}}, request)
+			if tc.errorContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.errorContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, advisories)
+			assert.Equal(t, len(tc.statuses), attempt)
+		})
+	}
+}
+
+func TestInstalledPackages(t *testing.T) {
+	lock := &packagejson.PackageLock{Packages: map[string]packagejson.PackageItem{
+		"":                                   {Version: "1.0.0"},
+		"node_modules/lodash":                {Version: "4.17.21"},
+		"node_modules/a/node_modules/lodash": {Version: "4.17.20"},
+		"node_modules/@scope/pkg":            {Version: "2.0.0"},
+		"node_modules/local":                 {Version: "1.0.0", Link: true},
+		"node_modules/fsevents":              {Version: "2.3.3", Skipped: true},
+	}}
+
+	assert.Equal(t, map[string][]string{
+		"lodash":     {"4.17.20", "4.17.21"},
+		"@scope/pkg": {"2.0.0"},
+	}, InstalledPackages(lock))
+}
+
+func TestFindings(t *testing.T) {
+	installed := map[string][]string{
+		"lodash":   {"4.17.20", "4.17.21"},
+		"minimist": {"1.2.0"},
+		"nofix":    {"1.0.0"},
+	}
+	advisories := map[string][]Advisory{
+		"lodash":   {{ID: 1, Severity: "high", VulnerableVersions: "<4.17.21"}},
+		"minimist": {{ID: 2, Severity: "critical", VulnerableVersions: ">=1.0.0 <1.2.6"}},
+		"nofix":    {{ID: 3, Severity: "low", VulnerableVersions: "*"}},
+	}
+	published := map[string][]string{
+		"lodash":   {"4.17.19", "4.17.20", "4.17.21", "5.0.0-beta.1"},
+		"minimist": {"1.2.0", "1.2.5", "1.2.6", "1.2.8"},
+		"nofix":    {"1.0.0", "1.1.0"},
+	}
+
+	findings := Findings(installed, advisories, func(name string) []string { return published[name] })
+
+	assert.Equal(t, []Finding{
+		{Name: "minimist", Version: "1.2.0", Advisory: advisories["minimist"][0], FixedIn: "1.2.6"},
+		{Name: "lodash", Version: "4.17.20", Advisory: advisories["lodash"][0], FixedIn: "4.17.21"},
+		{Name: "nofix", Version: "1.0.0", Advisory: advisories["nofix"][0]},
+	}, findings)
+}
+
+func TestReport(t *testing.T) {
+	report := NewReport([]Finding{
+		{Name: "minimist", Version: "1.2.0", Advisory: Advisory{Title: "Prototype Pollution", Severity: "critical", VulnerableVersions: "<1.2.6", URL: "https://example.com/2"}, FixedIn: "1.2.6"},
+		{Name: "nofix", Version: "1.0.0", Advisory: Advisory{Title: "Bad", Severity: "low", VulnerableVersions: "*"}},
+	})
+
+	testCases := []struct {
+		level    string
+		expected int
+	}{
+		{level: "info", expected: 2},
+		{level: "low", expected: 2},
+		{level: "high", expected: 1},
+		{level: "critical", expected: 1},
+		{level: "none", expected: 0},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.level, func(t *testing.T) {
+			assert.Equal(t, tc.expected, report.AtOrAbove(tc.level))
+		})
+	}
+
+	var out bytes.Buffer
+	report.Write(&out)
+	assert.Equal(t, `critical (1)
+  minimist@1.2.0: Prototype Pollution
+    vulnerable: <1.2.6, fixed in 1.2.6
+    https://example.com/2
+
+low (1)
+  nofix@1.0.0: Bad
+    vulnerable: *, no fix available
+
+found 2 vulnerabilities (1 critical, 1 low)
+`, out.String())
+
+	out.Reset()
+	NewReport(nil).Write(&out)
+	assert.Equal(t, "found 0 vulnerabilities\n", out.String())
+}
+
+func TestValidateLevel(t *testing.T) {
+	assert.NoError(t, ValidateLevel("moderate"))
+	assert.NoError(t, ValidateLevel("none"))
+	assert.Error(t, ValidateLevel("medium"))
+}
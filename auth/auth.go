@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNoToken is returned when no auth token is configured for a registry
+var ErrNoToken = errors.New("no auth token configured")
+
+// TokenEnv overrides the .npmrc files, e.g. for CI
+const TokenEnv = "GO_NPM_AUTH_TOKEN"
+
+// Resolver finds the auth token of a registry in the environment and in
+// .npmrc files, the way npm reads `//host/path/:_authToken` entries
+type Resolver struct {
+	files []string
+
+	// defaultToken is used for any registry without an .npmrc entry
+	defaultToken string
+}
+
+// New creates a Resolver reading the project .npmrc, then the user one, and
+// falling back to defaultToken, the auth-token setting of the config file
+func New(defaultToken string) *Resolver {
+	files := []string{".npmrc"}
+	if homeDir, err := os.UserHomeDir(); err == nil {
+		files = append(files, filepath.Join(homeDir, ".npmrc"))
+	}
+	return &Resolver{files: files, defaultToken: defaultToken}
+}
+
+// Token returns the auth token for registryURL. GO_NPM_AUTH_TOKEN wins over
+// the .npmrc files, which are searched in order and win over the default
+// token; ${VAR} references in them are expanded like npm does.
+func (r *Resolver) Token(registryURL string) (string, error) {
+	if token := os.Getenv(TokenEnv); token != "" {
+		return token, nil
+	}
+
+	key := registryKey(registryURL)
+	for _, file := range r.files {
+		token, err := readToken(file, key)
+		if err != nil {
+			return "", err
+		}
+		if token != "" {
+			return token, nil
+		}
+	}
+	if r.defaultToken != "" {
+		return r.defaultToken, nil
+	}
+
+	return "", fmt.Errorf("%w for %s", ErrNoToken, registryURL)
+}
+
+// registryKey is the .npmrc key prefix of a registry URL: the URL without
+// its scheme, always ending in a slash ("//registry.npmjs.org/")
+func registryKey(registryURL string) string {
+	key := registryURL
+	if i := strings.Index(key, "//"); i >= 0 {
+		key = key[i:]
+	}
+	if !strings.HasSuffix(key, "/") {
+		key += "/"
+	}
+	return key
+}
+
+// readToken returns the _authToken for key in the .npmrc at path, empty when
+// the file or the entry does not exist
+func readToken(path, key string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		name, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		name = strings.TrimSpace(name)
+		if name == key+":_authToken" || name == strings.TrimSuffix(key, "/")+":_authToken" {
+			return os.ExpandEnv(strings.Trim(strings.TrimSpace(value), `"`)), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	return "", nil
+}
@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolverToken(t *testing.T) {
+	testCases := []struct {
+		name         string
+		env          string
+		projectRC    string
+		userRC       string
+		defaultToken string
+		registryURL  string
+		expected     string
+		expectError  bool
+	}{
+		{
+			name:        "environment variable wins",
+			env:         "env-token",
+			projectRC:   "//registry.npmjs.org/:_authToken=file-token\n",
+			registryURL: "https://registry.npmjs.org/",
+			expected:    "env-token",
+		},
+		{
+			name:        "project .npmrc before user .npmrc",
+			projectRC:   "//registry.npmjs.org/:_authToken=project-token\n",
+			userRC:      "//registry.npmjs.org/:_authToken=user-token\n",
+			registryURL: "https://registry.npmjs.org/",
+			expected:    "project-token",
+		},
+		{
+			name:        "user .npmrc",
+			userRC:      "# comment\nregistry=https://registry.npmjs.org/\n//registry.npmjs.org/:_authToken = user-token\n",
+			registryURL: "https://registry.npmjs.org/",
+			expected:    "user-token",
+		},
+		{
+			name:        "matches registries with a path and no trailing slash",
+			userRC:      "//npm.example.com/api/npm/:_authToken=path-token\n",
+			registryURL: "https://npm.example.com/api/npm",
+			expected:    "path-token",
+		},
+		{
+			name:        "expands environment references",
+			userRC:      "//registry.npmjs.org/:_authToken=${TEST_NPM_TOKEN}\n",
+			registryURL: "https://registry.npmjs.org/",
+			expected:    "expanded-token",
+		},
+		{
+			name:        "token of another registry is not used",
+			userRC:      "//npm.example.com/:_authToken=other-token\n",
+			registryURL: "https://registry.npmjs.org/",
+			expectError: true,
+		},
+		{
+			name:         ".npmrc wins over the config file token",
+			userRC:       "//registry.npmjs.org/:_authToken=user-token\n",
+			defaultToken: "config-token",
+			registryURL:  "https://registry.npmjs.org/",
+			expected:     "user-token",
+		},
+		{
+			name:         "config file token",
+			userRC:       "//npm.example.com/:_authToken=other-token\n",
+			defaultToken: "config-token",
+			registryURL:  "https://registry.npmjs.org/",
+			expected:     "config-token",
+		},
+		{
+			name:        "no .npmrc",
+			registryURL: "https://registry.npmjs.org/",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			projectDir := t.TempDir()
+			homeDir := t.TempDir()
+			t.Setenv(TokenEnv, tc.env)
+			t.Setenv("TEST_NPM_TOKEN", "expanded-token")
+			t.Setenv("HOME", homeDir)
+
+			projectRC := filepath.Join(projectDir, ".npmrc")
+			if tc.projectRC != "" {
+				require.NoError(t, os.WriteFile(projectRC, []byte(tc.projectRC), 0644))
+			}
+			if tc.userRC != "" {
+				require.NoError(t, os.WriteFile(filepath.Join(homeDir, ".npmrc"), []byte(tc.userRC), 0644))
+			}
+
+			r := &Resolver{files: []string{projectRC, filepath.Join(homeDir, ".npmrc")}, defaultToken: tc.defaultToken}
+			token, err := r.Token(tc.registryURL)
+			if tc.expectError {
+				assert.ErrorIs(t, err, ErrNoToken)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, token)
+		})
+	}
+}
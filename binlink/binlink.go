@@ -4,13 +4,24 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
 )
 
 type BinLinker struct {
 	nodeModulesPath string
 	binPath         string
 	isGlobal        bool
+	goos            string
+
+	// directDependencies are the packages listed in the project's package.json
+	directDependencies map[string]bool
+
+	// warn reports a bin that could not be linked or lost a name conflict
+	warn func(format string, args ...any)
 }
 
 type PackageJSON struct {
@@ -23,9 +34,18 @@ func NewBinLinker(nodeModulesPath string) *BinLinker {
 		nodeModulesPath: nodeModulesPath,
 		binPath:         filepath.Join(nodeModulesPath, ".bin"),
 		isGlobal:        false,
+		goos:            runtime.GOOS,
+		warn: func(format string, args ...any) {
+			fmt.Fprintf(os.Stderr, "Warning: "+format+"\n", args...)
+		},
 	}
 }
 
+// SetWarn sets how warnings are reported, on stderr by default
+func (bl *BinLinker) SetWarn(warn func(format string, args ...any)) {
+	bl.warn = warn
+}
+
 func (bl *BinLinker) SetGlobalMode(nodeModulesPath string, globalBinPath string) {
 	bl.nodeModulesPath = nodeModulesPath
 	bl.binPath = globalBinPath
@@ -39,6 +59,35 @@ func (bl *BinLinker) CreateBinDirectory() error {
 	return nil
 }
 
+// SetDirectDependencies records the packages listed in the project's
+// package.json; they win bin name conflicts against transitive packages
+func (bl *BinLinker) SetDirectDependencies(names []string) {
+	bl.directDependencies = make(map[string]bool, len(names))
+	for _, name := range names {
+		bl.directDependencies[name] = true
+	}
+}
+
+// binEntry is a bin provided by a package in node_modules
+type binEntry struct {
+	pkgName string
+	pkgPath string
+	target  string
+}
+
+// isPackageDir reports whether entry of dir is a directory, following
+// symlinks so that packages installed with the symlink strategy are linked
+func isPackageDir(dir string, entry os.DirEntry) bool {
+	if entry.IsDir() {
+		return true
+	}
+	if entry.Type()&os.ModeSymlink == 0 {
+		return false
+	}
+	info, err := os.Stat(filepath.Join(dir, entry.Name()))
+	return err == nil && info.IsDir()
+}
+
 func (bl *BinLinker) LinkAllPackages() error {
 	if err := bl.CreateBinDirectory(); err != nil {
 		return err
@@ -49,8 +98,9 @@ func (bl *BinLinker) LinkAllPackages() error {
 		return fmt.Errorf("failed to read node_modules: %w", err)
 	}
 
+	pkgPaths := []string{}
 	for _, entry := range entries {
-		if !entry.IsDir() || entry.Name() == ".bin" {
+		if !isPackageDir(bl.nodeModulesPath, entry) || entry.Name() == ".bin" {
 			continue
 		}
 
@@ -63,52 +113,110 @@ func (bl *BinLinker) LinkAllPackages() error {
 				continue
 			}
 			for _, scopedEntry := range scopedEntries {
-				if scopedEntry.IsDir() {
-					scopedPkgPath := filepath.Join(pkgPath, scopedEntry.Name())
-					if err := bl.LinkPackage(scopedPkgPath); err != nil {
-						fmt.Printf("Warning: failed to link %s: %v\n", scopedPkgPath, err)
-					}
+				if isPackageDir(pkgPath, scopedEntry) {
+					pkgPaths = append(pkgPaths, filepath.Join(pkgPath, scopedEntry.Name()))
 				}
 			}
 		} else {
-			if err := bl.LinkPackage(pkgPath); err != nil {
-				fmt.Printf("Warning: failed to link %s: %v\n", pkgPath, err)
+			pkgPaths = append(pkgPaths, pkgPath)
+		}
+	}
+
+	// Collect every bin first so two packages providing the same name are
+	// resolved once instead of whichever is linked last silently winning
+	owners := make(map[string]binEntry)
+	for _, pkgPath := range pkgPaths {
+		pkgName, bins, err := bl.readBins(pkgPath)
+		if err != nil {
+			bl.warn("failed to link %s: %v", pkgPath, err)
+			continue
+		}
+
+		for binName, target := range bins {
+			candidate := binEntry{pkgName: pkgName, pkgPath: pkgPath, target: target}
+			if owner, exists := owners[binName]; exists {
+				candidate = bl.resolveBinConflict(binName, owner, candidate)
 			}
+			owners[binName] = candidate
+		}
+	}
+
+	binNames := make([]string, 0, len(owners))
+	for binName := range owners {
+		binNames = append(binNames, binName)
+	}
+	sort.Strings(binNames)
+
+	for _, binName := range binNames {
+		owner := owners[binName]
+		if err := bl.createSymlink(owner.pkgPath, binName, owner.target); err != nil {
+			bl.warn("failed to link %s: %v", owner.pkgPath, err)
 		}
 	}
 
 	return nil
 }
 
+// resolveBinConflict picks which of two packages providing binName gets
+// linked: a direct dependency beats a transitive one, otherwise the package
+// seen first is kept. A warning names both packages.
+func (bl *BinLinker) resolveBinConflict(binName string, owner, candidate binEntry) binEntry {
+	winner, loser := owner, candidate
+	if bl.directDependencies[candidate.pkgName] && !bl.directDependencies[owner.pkgName] {
+		winner, loser = candidate, owner
+	}
+
+	reason := "linked first"
+	if bl.directDependencies[winner.pkgName] && !bl.directDependencies[loser.pkgName] {
+		reason = "direct dependency"
+	}
+
+	bl.warn("bin %q is provided by both %s and %s; using %s (%s)",
+		binName, owner.pkgName, candidate.pkgName, winner.pkgName, reason)
+
+	return winner
+}
+
 func (bl *BinLinker) LinkPackage(pkgPath string) error {
+	_, bins, err := bl.readBins(pkgPath)
+	if err != nil {
+		return err
+	}
+
+	for binName, binPath := range bins {
+		if err := bl.createSymlink(pkgPath, binName, binPath); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readBins returns the package name and bin entries of the package at
+// pkgPath. A missing or unreadable package.json yields no bins.
+func (bl *BinLinker) readBins(pkgPath string) (string, map[string]string, error) {
 	packageJSONPath := filepath.Join(pkgPath, "package.json")
 
 	data, err := os.ReadFile(packageJSONPath)
 	if err != nil {
-		return nil
+		return "", nil, nil
 	}
 
 	var pkg PackageJSON
 	if err := json.Unmarshal(data, &pkg); err != nil {
-		return nil
+		return "", nil, nil
 	}
 
 	if len(pkg.Bin) == 0 {
-		return nil
+		return pkg.Name, nil, nil
 	}
 
 	bins, err := bl.parseBinField(pkg.Name, pkg.Bin)
 	if err != nil {
-		return err
-	}
-
-	for binName, binPath := range bins {
-		if err := bl.createSymlink(pkgPath, binName, binPath); err != nil {
-			return err
-		}
+		return "", nil, err
 	}
 
-	return nil
+	return pkg.Name, bins, nil
 }
 
 func (bl *BinLinker) parseBinField(pkgName string, binField json.RawMessage) (map[string]string, error) {
@@ -119,7 +227,7 @@ func (bl *BinLinker) parseBinField(pkgName string, binField json.RawMessage) (ma
 	if err := json.Unmarshal(binField, &binString); err == nil {
 		// For scoped packages (@scope/name), use only the name part after /
 		binName := pkgName
-		if pkgName[0] == '@' {
+		if strings.HasPrefix(pkgName, "@") {
 			if idx := filepath.Base(pkgName); idx != "" {
 				binName = idx
 			}
@@ -131,7 +239,15 @@ func (bl *BinLinker) parseBinField(pkgName string, binField json.RawMessage) (ma
 	// Try parsing as object
 	var binObject map[string]string
 	if err := json.Unmarshal(binField, &binObject); err == nil {
-		return binObject, nil
+		for binName, binPath := range binObject {
+			// Bin names are file names in .bin: strip any scope or directory
+			binName = path.Base(binName)
+			if binName == "." || binName == ".." || binName == "/" {
+				continue
+			}
+			bins[binName] = binPath
+		}
+		return bins, nil
 	}
 
 	return nil, fmt.Errorf("invalid bin field format")
@@ -166,6 +282,11 @@ func (bl *BinLinker) createSymlink(pkgPath, binName, binRelativePath string) err
 		return fmt.Errorf("failed to make %s executable: %w", absoluteTargetPath, err)
 	}
 
+	// Windows cannot execute symlinked scripts, so write cmd/PowerShell shims instead
+	if bl.goos == "windows" {
+		return bl.writeShims(linkPath, targetPath)
+	}
+
 	// Check if symlink already exists and is correct
 	if existingTarget, err := os.Readlink(linkPath); err == nil {
 		if existingTarget == targetPath {
@@ -225,6 +346,9 @@ func (bl *BinLinker) UnlinkPackage(pkgName string) error {
 		if err := os.Remove(linkPath); err != nil && !os.IsNotExist(err) {
 			return fmt.Errorf("failed to remove symlink %s: %w", linkPath, err)
 		}
+		if err := bl.removeShims(linkPath); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -2,8 +2,11 @@ package binlink
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -348,6 +351,20 @@ func TestParseBinField(t *testing.T) {
 			},
 			expectError: false,
 		},
+		{
+			name:    "Object format - scoped and nested bin names are stripped",
+			pkgName: "@eslint/tools",
+			binField: json.RawMessage(`{
+				"@eslint/eslint-config": "./bin/config.js",
+				"sub/lint": "./bin/lint.js",
+				"..": "./bin/evil.js"
+			}`),
+			expected: map[string]string{
+				"eslint-config": "./bin/config.js",
+				"lint":          "./bin/lint.js",
+			},
+			expectError: false,
+		},
 		{
 			name:        "Empty object",
 			pkgName:     "empty-obj",
@@ -958,3 +975,185 @@ func TestLinkAllPackages(t *testing.T) {
 		})
 	}
 }
+
+func TestWindowsShims(t *testing.T) {
+	testCases := []struct {
+		name           string
+		global         bool
+		scope          string
+		pkgName        string
+		binField       interface{}
+		binName        string
+		expectedTarget string
+	}{
+		{
+			name:           "Local installation - regular package",
+			pkgName:        "express",
+			binField:       "./bin/cli.js",
+			binName:        "express",
+			expectedTarget: "../express/bin/cli.js",
+		},
+		{
+			name:           "Local installation - scoped package",
+			scope:          "@babel",
+			pkgName:        "cli",
+			binField:       map[string]string{"babel": "./bin/babel.js"},
+			binName:        "babel",
+			expectedTarget: "../@babel/cli/bin/babel.js",
+		},
+		{
+			name:     "Global installation uses absolute paths",
+			global:   true,
+			pkgName:  "typescript",
+			binField: map[string]string{"tsc": "bin/tsc"},
+			binName:  "tsc",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			nodeModules := filepath.Join(tmpDir, "node_modules")
+
+			bl := NewBinLinker(nodeModules)
+			if tc.global {
+				bl.SetGlobalMode(nodeModules, filepath.Join(tmpDir, "bin"))
+			}
+			bl.goos = "windows"
+			assert.NoError(t, bl.CreateBinDirectory())
+
+			var pkgPath string
+			if tc.scope != "" {
+				pkgPath = createScopedPackage(t, nodeModules, tc.scope, tc.pkgName, tc.binField)
+			} else {
+				pkgPath = createTestPackage(t, nodeModules, tc.pkgName, tc.binField)
+			}
+
+			assert.NoError(t, bl.LinkPackage(pkgPath))
+
+			linkPath := filepath.Join(bl.binPath, tc.binName)
+			_, err := os.Lstat(linkPath)
+			assert.True(t, os.IsNotExist(err), "no symlink should be created on windows")
+
+			cmdContent, err := os.ReadFile(linkPath + ".cmd")
+			assert.NoError(t, err)
+			ps1Content, err := os.ReadFile(linkPath + ".ps1")
+			assert.NoError(t, err)
+
+			expectedTarget := tc.expectedTarget
+			if tc.global {
+				bins := tc.binField.(map[string]string)
+				expectedTarget = filepath.Join(pkgPath, bins[tc.binName])
+			}
+			assert.Contains(t, string(cmdContent), strings.ReplaceAll(expectedTarget, "/", `\`))
+			assert.Equal(t, cmdShim(expectedTarget), string(cmdContent))
+			assert.Equal(t, ps1Shim(expectedTarget), string(ps1Content))
+
+			// The script referenced by the cmd shim must resolve from the shim's own directory
+			match := regexp.MustCompile(`node "([^"]+)" %\*`).FindStringSubmatch(string(cmdContent))
+			if assert.Len(t, match, 2) {
+				script := strings.ReplaceAll(strings.ReplaceAll(match[1], `%~dp0\`, bl.binPath+`\`), `\`, "/")
+				assert.FileExists(t, filepath.FromSlash(script))
+			}
+
+			// The ps1 shim resolves its script from $basedir
+			match = regexp.MustCompile(`& \$node "([^"]+)" \$args`).FindStringSubmatch(string(ps1Content))
+			if assert.Len(t, match, 2) {
+				script := strings.Replace(match[1], "$basedir", bl.binPath, 1)
+				assert.FileExists(t, filepath.FromSlash(script))
+			}
+
+			assert.NoError(t, bl.UnlinkPackage(strings.TrimPrefix(filepath.ToSlash(strings.TrimPrefix(pkgPath, nodeModules)), "/")))
+			assert.NoFileExists(t, linkPath+".cmd")
+			assert.NoFileExists(t, linkPath+".ps1")
+		})
+	}
+}
+
+func TestCmdShim(t *testing.T) {
+	content := cmdShim("../pkg/bin.js")
+
+	assert.True(t, strings.HasPrefix(content, "@ECHO off\r\n"))
+	assert.Contains(t, content, `node "%~dp0\..\pkg\bin.js" %*`)
+	assert.Contains(t, content, `"%~dp0\node.exe" "%~dp0\..\pkg\bin.js" %*`)
+	assert.NotContains(t, strings.ReplaceAll(content, "\r\n", ""), "\n")
+}
+
+func TestPs1Shim(t *testing.T) {
+	content := ps1Shim("../pkg/bin.js")
+
+	assert.Contains(t, content, `$input | & $node "$basedir/../pkg/bin.js" $args`)
+	assert.Contains(t, content, `& $node "$basedir/../pkg/bin.js" $args`)
+	assert.True(t, strings.HasSuffix(content, "exit $LASTEXITCODE\n"))
+}
+
+func TestLinkAllPackagesBinConflicts(t *testing.T) {
+	testCases := []struct {
+		name     string
+		direct   []string
+		expected map[string]string
+		warning  string
+	}{
+		{
+			name:   "direct dependency wins over a transitive package",
+			direct: []string{"eslint"},
+			expected: map[string]string{
+				"eslint":        "../eslint/bin/eslint.js",
+				"eslint-config": "../eslint/bin/config.js",
+				"lint":          "../a-lint/bin/lint.js",
+			},
+			warning: `bin "lint" is provided by both a-lint and z-lint; using a-lint (linked first)`,
+		},
+		{
+			name:   "direct dependency wins even when linked later",
+			direct: []string{"eslint", "z-lint"},
+			expected: map[string]string{
+				"eslint":        "../eslint/bin/eslint.js",
+				"eslint-config": "../eslint/bin/config.js",
+				"lint":          "../z-lint/bin/lint.js",
+			},
+			warning: `bin "lint" is provided by both a-lint and z-lint; using z-lint (direct dependency)`,
+		},
+		{
+			name:   "without a direct dependency the first package is kept",
+			direct: nil,
+			expected: map[string]string{
+				"eslint":        "../eslint/bin/eslint.js",
+				"eslint-config": "../@other/eslint-config/bin/config.js",
+				"lint":          "../a-lint/bin/lint.js",
+			},
+			warning: `bin "lint" is provided by both a-lint and z-lint; using a-lint (linked first)`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			nodeModules := filepath.Join(tmpDir, "node_modules")
+
+			createTestPackage(t, nodeModules, "eslint", map[string]string{
+				"eslint":        "bin/eslint.js",
+				"eslint-config": "bin/config.js",
+			})
+			// The scoped string bin is named after the package without its scope
+			createScopedPackage(t, nodeModules, "@other", "eslint-config", "bin/config.js")
+			createTestPackage(t, nodeModules, "a-lint", map[string]string{"lint": "bin/lint.js"})
+			createTestPackage(t, nodeModules, "z-lint", map[string]string{"lint": "bin/lint.js"})
+
+			bl := NewBinLinker(nodeModules)
+			bl.SetDirectDependencies(tc.direct)
+			var warnings []string
+			bl.SetWarn(func(format string, args ...any) {
+				warnings = append(warnings, fmt.Sprintf(format, args...))
+			})
+
+			assert.NoError(t, bl.LinkAllPackages())
+			assert.Len(t, warnings, 2)
+			assert.Contains(t, warnings, tc.warning)
+
+			for binName, target := range tc.expected {
+				verifySymlink(t, filepath.Join(bl.binPath, binName), target)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package binlink
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// shimExtensions are the wrapper files written for every bin on Windows,
+// where symlinks to scripts cannot be executed directly
+var shimExtensions = []string{".cmd", ".ps1"}
+
+// cmdShim returns a cmd.exe wrapper that runs target with node, like npm's
+// cmd-shim. A relative target is resolved from the shim's own directory.
+func cmdShim(target string) string {
+	script := strings.ReplaceAll(target, "/", `\`)
+	if !filepath.IsAbs(target) {
+		script = `%~dp0\` + script
+	}
+
+	return "@ECHO off\r\n" +
+		"SETLOCAL\r\n" +
+		`IF EXIST "%~dp0\node.exe" (` + "\r\n" +
+		`  "%~dp0\node.exe" "` + script + `" %*` + "\r\n" +
+		") ELSE (\r\n" +
+		`  node "` + script + `" %*` + "\r\n" +
+		")\r\n"
+}
+
+// ps1Shim returns a PowerShell wrapper that runs target with node, forwarding
+// piped input and the exit code. A relative target is resolved from the
+// shim's own directory.
+func ps1Shim(target string) string {
+	script := strings.ReplaceAll(target, `\`, "/")
+	if !filepath.IsAbs(target) {
+		script = "$basedir/" + script
+	}
+
+	return "#!/usr/bin/env pwsh\n" +
+		"$basedir=Split-Path $MyInvocation.MyCommand.Definition -Parent\n" +
+		"\n" +
+		"$exe=\"\"\n" +
+		"if ($PSVersionTable.PSVersion -lt \"6.0\" -or $IsWindows) {\n" +
+		"  $exe=\".exe\"\n" +
+		"}\n" +
+		"$node=\"node$exe\"\n" +
+		"if (Test-Path \"$basedir/node$exe\") {\n" +
+		"  $node=\"$basedir/node$exe\"\n" +
+		"}\n" +
+		"if ($MyInvocation.ExpectingInput) {\n" +
+		"  $input | & $node \"" + script + "\" $args\n" +
+		"} else {\n" +
+		"  & $node \"" + script + "\" $args\n" +
+		"}\n" +
+		"exit $LASTEXITCODE\n"
+}
+
+// writeShims writes the .cmd and .ps1 wrappers for linkPath pointing at target
+func (bl *BinLinker) writeShims(linkPath, target string) error {
+	shims := map[string]string{
+		".cmd": cmdShim(target),
+		".ps1": ps1Shim(target),
+	}
+
+	for _, ext := range shimExtensions {
+		shimPath := linkPath + ext
+		if err := os.WriteFile(shimPath, []byte(shims[ext]), 0755); err != nil {
+			return fmt.Errorf("failed to write shim %s: %w", shimPath, err)
+		}
+	}
+
+	return nil
+}
+
+// removeShims deletes the Windows wrappers of linkPath, if any
+func (bl *BinLinker) removeShims(linkPath string) error {
+	for _, ext := range shimExtensions {
+		shimPath := linkPath + ext
+		if err := os.Remove(shimPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove shim %s: %w", shimPath, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,256 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ernesto27/go-npm/config"
+	"github.com/ernesto27/go-npm/integrity"
+	"github.com/ernesto27/go-npm/tarball"
+	"github.com/ernesto27/go-npm/utils"
+)
+
+// Cache inspects and maintains the local package and tarball cache.
+// It only reads local state and never touches the network.
+type Cache struct {
+	config    *config.Config
+	validator *integrity.Validator
+}
+
+// Stats summarizes the contents of the cache
+type Stats struct {
+	Packages int   // extracted name@version directories under PackagesDir
+	Tarballs int   // files under TarballDir
+	Size     int64 // total bytes used by PackagesDir and TarballDir
+}
+
+// VerifyResult is the outcome of Verify
+type VerifyResult struct {
+	Verified   int      // tarballs that matched their recorded integrity
+	Unverified int      // readable tarballs without a recorded integrity
+	Removed    []string // corrupt or incomplete tarballs that were deleted
+}
+
+// manifestIntegrity is the subset of a cached registry manifest needed to
+// find the integrity recorded for each version
+type manifestIntegrity struct {
+	Name     string `json:"name"`
+	Versions map[string]struct {
+		Dist struct {
+			Integrity string `json:"integrity"`
+		} `json:"dist"`
+	} `json:"versions"`
+}
+
+// New creates a Cache for the directories of cfg
+func New(cfg *config.Config) *Cache {
+	return &Cache{
+		config:    cfg,
+		validator: integrity.New(),
+	}
+}
+
+// Stats returns the package count, tarball count and size of the cache
+func (c *Cache) Stats() (Stats, error) {
+	var stats Stats
+
+	packages, err := c.packageDirs()
+	if err != nil {
+		return stats, err
+	}
+	stats.Packages = len(packages)
+
+	tarballs, err := readDir(c.config.TarballDir)
+	if err != nil {
+		return stats, err
+	}
+	stats.Tarballs = len(tarballs)
+
+	for _, dir := range []string{c.config.PackagesDir, c.config.StoreDir, c.config.TarballDir} {
+		size, err := utils.DirSize(dir)
+		if err != nil {
+			return stats, err
+		}
+		stats.Size += size
+	}
+
+	return stats, nil
+}
+
+// Clean removes everything under PackagesDir, StoreDir and TarballDir and returns the
+// removed paths. With dryRun the paths are returned without removing them.
+func (c *Cache) Clean(dryRun bool) ([]string, error) {
+	var removed []string
+
+	for _, dir := range []string{c.config.PackagesDir, c.config.StoreDir, c.config.TarballDir} {
+		entries, err := readDir(dir)
+		if err != nil {
+			return removed, err
+		}
+
+		for _, entry := range entries {
+			entryPath := filepath.Join(dir, entry.Name())
+			if !dryRun {
+				if err := os.RemoveAll(entryPath); err != nil {
+					return removed, fmt.Errorf("failed to remove %s: %w", entryPath, err)
+				}
+			}
+			removed = append(removed, entryPath)
+		}
+	}
+
+	return removed, nil
+}
+
+// Verify revalidates every cached tarball against the integrity recorded in
+// the cached manifests and removes the ones that fail. Tarballs without a
+// recorded integrity (e.g. git dependencies) are only checked to be readable
+// gzip archives. Leftover .tmp and .part files from interrupted downloads
+// are removed.
+func (c *Cache) Verify() (VerifyResult, error) {
+	var result VerifyResult
+
+	recorded, err := c.recordedIntegrity()
+	if err != nil {
+		return result, err
+	}
+
+	entries, err := readDir(c.config.TarballDir)
+	if err != nil {
+		return result, err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		tarballPath := filepath.Join(c.config.TarballDir, entry.Name())
+
+		valid := false
+		switch hash, ok := recorded[entry.Name()]; {
+		case strings.HasSuffix(entry.Name(), ".tmp"), strings.HasSuffix(entry.Name(), utils.PartSuffix):
+		case ok:
+			valid = c.validator.ValidateFileStrict(tarballPath, hash) == nil
+			if valid {
+				result.Verified++
+			}
+		default:
+			valid = utils.ValidateTarball(tarballPath)
+			if valid {
+				result.Unverified++
+			}
+		}
+
+		if valid {
+			continue
+		}
+
+		if err := os.Remove(tarballPath); err != nil {
+			return result, fmt.Errorf("failed to remove %s: %w", tarballPath, err)
+		}
+		result.Removed = append(result.Removed, tarballPath)
+	}
+
+	return result, nil
+}
+
+// recordedIntegrity maps tarball filenames to the dist.integrity of their
+// version in the cached manifests
+func (c *Cache) recordedIntegrity() (map[string]string, error) {
+	recorded := make(map[string]string)
+
+	err := filepath.WalkDir(c.config.ManifestDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || filepath.Ext(p) != ".json" {
+			return nil
+		}
+
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return fmt.Errorf("failed to read manifest %s: %w", p, err)
+		}
+
+		// A truncated manifest is re-downloaded on the next install; it just
+		// records nothing here
+		var m manifestIntegrity
+		if err := json.Unmarshal(data, &m); err != nil || m.Name == "" {
+			return nil
+		}
+
+		for version, v := range m.Versions {
+			if v.Dist.Integrity != "" {
+				recorded[tarball.UniqueName(m.Name, version)] = v.Dist.Integrity
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cached manifests: %w", err)
+	}
+
+	return recorded, nil
+}
+
+// packageDirs lists the cached name@version directories, looking one level
+// into @scope directories for scoped packages
+func (c *Cache) packageDirs() ([]string, error) {
+	entries, err := readDir(c.config.PackagesDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var dirs []string
+	for _, entry := range entries {
+		if !isPackageDir(entry) {
+			continue
+		}
+
+		entryPath := filepath.Join(c.config.PackagesDir, entry.Name())
+		if !strings.HasPrefix(entry.Name(), "@") || strings.Contains(entry.Name()[1:], "@") {
+			dirs = append(dirs, entryPath)
+			continue
+		}
+
+		scoped, err := readDir(entryPath)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range scoped {
+			if isPackageDir(s) {
+				dirs = append(dirs, filepath.Join(entryPath, s.Name()))
+			}
+		}
+	}
+
+	sort.Strings(dirs)
+	return dirs, nil
+}
+
+// isPackageDir reports whether entry is a name@version directory, or a link
+// to its content store entry
+func isPackageDir(entry os.DirEntry) bool {
+	return entry.IsDir() || entry.Type()&os.ModeSymlink != 0
+}
+
+// readDir is os.ReadDir that treats a missing directory as empty
+func readDir(dir string) ([]os.DirEntry, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+	return entries, nil
+}
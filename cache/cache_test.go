@@ -0,0 +1,180 @@
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha512"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ernesto27/go-npm/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestConfig(t *testing.T) *config.Config {
+	baseDir := t.TempDir()
+	cfg := &config.Config{
+		BaseDir:     baseDir,
+		ManifestDir: filepath.Join(baseDir, "manifest"),
+		TarballDir:  filepath.Join(baseDir, "tarball"),
+		PackagesDir: filepath.Join(baseDir, "packages"),
+	}
+	for _, dir := range []string{cfg.ManifestDir, cfg.TarballDir, cfg.PackagesDir} {
+		require.NoError(t, os.MkdirAll(dir, 0755))
+	}
+	return cfg
+}
+
+func gzipBytes(t *testing.T, content string) []byte {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	_, err := gw.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, gw.Close())
+	return buf.Bytes()
+}
+
+func sha512Integrity(data []byte) string {
+	sum := sha512.Sum512(data)
+	return "sha512-" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, data, 0644))
+}
+
+func TestCacheVerify(t *testing.T) {
+	good := gzipBytes(t, "good")
+	scoped := gzipBytes(t, "scoped")
+
+	testCases := []struct {
+		name               string
+		tarballs           map[string][]byte
+		expectedVerified   int
+		expectedUnverified int
+		expectedRemoved    []string
+	}{
+		{
+			name:             "tarballs matching their recorded integrity are kept",
+			tarballs:         map[string][]byte{"lodash-4.17.21.tgz": good, "@types-node-20.0.0.tgz": scoped},
+			expectedVerified: 2,
+		},
+		{
+			name:             "tarball not matching its recorded integrity is removed",
+			tarballs:         map[string][]byte{"lodash-4.17.21.tgz": gzipBytes(t, "tampered"), "@types-node-20.0.0.tgz": scoped},
+			expectedVerified: 1,
+			expectedRemoved:  []string{"lodash-4.17.21.tgz"},
+		},
+		{
+			name:               "tarball without recorded integrity is checked as gzip",
+			tarballs:           map[string][]byte{"owner-repo-abc123.tgz": good, "broken-1.0.0.tgz": []byte("not gzip")},
+			expectedUnverified: 1,
+			expectedRemoved:    []string{"broken-1.0.0.tgz"},
+		},
+		{
+			name:            "leftover partial downloads are removed",
+			tarballs:        map[string][]byte{"lodash-4.17.21.tgz.tmp": good, "@types-node-20.0.0.tgz.part": scoped},
+			expectedRemoved: []string{"lodash-4.17.21.tgz.tmp", "@types-node-20.0.0.tgz.part"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := newTestConfig(t)
+			writeFile(t, filepath.Join(cfg.ManifestDir, "lodash.json"),
+				[]byte(`{"name": "lodash", "versions": {"4.17.21": {"dist": {"integrity": "`+sha512Integrity(good)+`"}}}}`))
+			writeFile(t, filepath.Join(cfg.ManifestDir, "@types", "node.json"),
+				[]byte(`{"name": "@types/node", "versions": {"20.0.0": {"dist": {"integrity": "`+sha512Integrity(scoped)+`"}}}}`))
+			writeFile(t, filepath.Join(cfg.ManifestDir, "truncated.json"), []byte(`{"name": "trunc`))
+
+			for name, data := range tc.tarballs {
+				writeFile(t, filepath.Join(cfg.TarballDir, name), data)
+			}
+
+			result, err := New(cfg).Verify()
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expectedVerified, result.Verified)
+			assert.Equal(t, tc.expectedUnverified, result.Unverified)
+			assert.Len(t, result.Removed, len(tc.expectedRemoved))
+			for _, name := range tc.expectedRemoved {
+				assert.Contains(t, result.Removed, filepath.Join(cfg.TarballDir, name))
+				assert.NoFileExists(t, filepath.Join(cfg.TarballDir, name))
+			}
+		})
+	}
+}
+
+func TestCacheClean(t *testing.T) {
+	testCases := []struct {
+		name   string
+		dryRun bool
+	}{
+		{name: "removes packages and tarballs"},
+		{name: "dry run keeps everything", dryRun: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := newTestConfig(t)
+			pkg := filepath.Join(cfg.PackagesDir, "lodash@4.17.21")
+			tgz := filepath.Join(cfg.TarballDir, "lodash-4.17.21.tgz")
+			manifest := filepath.Join(cfg.ManifestDir, "lodash.json")
+			writeFile(t, filepath.Join(pkg, "package.json"), []byte(`{}`))
+			writeFile(t, tgz, gzipBytes(t, "lodash"))
+			writeFile(t, manifest, []byte(`{}`))
+
+			removed, err := New(cfg).Clean(tc.dryRun)
+			require.NoError(t, err)
+
+			assert.ElementsMatch(t, []string{pkg, tgz}, removed)
+			assert.FileExists(t, manifest)
+			assert.DirExists(t, cfg.PackagesDir)
+			assert.DirExists(t, cfg.TarballDir)
+			if tc.dryRun {
+				assert.DirExists(t, pkg)
+				assert.FileExists(t, tgz)
+			} else {
+				assert.NoDirExists(t, pkg)
+				assert.NoFileExists(t, tgz)
+			}
+		})
+	}
+}
+
+func TestCacheStats(t *testing.T) {
+	cfg := newTestConfig(t)
+	writeFile(t, filepath.Join(cfg.PackagesDir, "lodash@4.17.21", "package.json"), []byte("0123456789"))
+	writeFile(t, filepath.Join(cfg.PackagesDir, "@types", "node@20.0.0", "package.json"), []byte("01234"))
+	writeFile(t, filepath.Join(cfg.PackagesDir, "@types", "react@18.0.0", "index.d.ts"), []byte("0"))
+	writeFile(t, filepath.Join(cfg.TarballDir, "lodash-4.17.21.tgz"), []byte("0123"))
+	writeFile(t, filepath.Join(cfg.ManifestDir, "lodash.json"), []byte("not counted"))
+
+	stats, err := New(cfg).Stats()
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, stats.Packages)
+	assert.Equal(t, 1, stats.Tarballs)
+	assert.Equal(t, int64(20), stats.Size)
+}
+
+func TestCacheStatsMissingDirectories(t *testing.T) {
+	baseDir := t.TempDir()
+	cfg := &config.Config{
+		ManifestDir: filepath.Join(baseDir, "manifest"),
+		TarballDir:  filepath.Join(baseDir, "tarball"),
+		PackagesDir: filepath.Join(baseDir, "packages"),
+	}
+
+	stats, err := New(cfg).Stats()
+	require.NoError(t, err)
+	assert.Equal(t, Stats{}, stats)
+
+	result, err := New(cfg).Verify()
+	require.NoError(t, err)
+	assert.Equal(t, VerifyResult{}, result)
+}
@@ -4,27 +4,97 @@ import (
 	"fmt"
 
 	"github.com/ernesto27/go-npm/manager"
+	"github.com/ernesto27/go-npm/packagejson"
 	"github.com/ernesto27/go-npm/types"
 	"github.com/spf13/cobra"
 )
 
+var (
+	addIncludePrereleaseFlag    bool
+	addSaveDevFlag              bool
+	addSaveOptionalFlag         bool
+	addSavePeerFlag             bool
+	addEngineStrictFlag         bool
+	addNoPeerFlag               bool
+	addVerifySignaturesFlag     bool
+	addOfflineFlag              bool
+	addPreferOfflineFlag        bool
+	addOmitLockfileRegistryFlag bool
+	addProgressFlag             string
+	addJSONFlag                 bool
+	addCacheLockFlag            bool
+	addDryRunFlag               bool
+	addForceFlag                bool
+	addNoSaveFlag               bool
+)
+
 var addCmd = &cobra.Command{
 	Use:   "add <package[@version]>",
 	Short: "Add a package to package.json and install it",
-	Long:  `Add a package to package.json dependencies and install it.`,
+	Long:  `Add a package to package.json dependencies and install it. Use --save-dev, --save-optional or --save-peer to save it to another section.`,
 	Args:  cobra.ExactArgs(1),
 	RunE:  runAdd,
 }
 
 func init() {
 	rootCmd.AddCommand(addCmd)
+	addCmd.Flags().BoolVar(&addIncludePrereleaseFlag, "include-prerelease", false, "Allow latest to resolve to a prerelease version")
+	addCmd.Flags().BoolVarP(&addSaveDevFlag, "save-dev", "D", false, "Save the package to devDependencies")
+	addCmd.Flags().BoolVarP(&addSaveOptionalFlag, "save-optional", "O", false, "Save the package to optionalDependencies")
+	addCmd.Flags().BoolVar(&addSavePeerFlag, "save-peer", false, "Save the package to peerDependencies")
+	addCmd.Flags().BoolVar(&addEngineStrictFlag, "engine-strict", false, "Fail when a package's engines.node does not match the installed node")
+	addCmd.Flags().BoolVar(&addNoPeerFlag, "no-peer", false, "Do not install, validate or lock peer dependencies")
+	addCmd.Flags().BoolVar(&addVerifySignaturesFlag, "verify-signatures", false, "Verify registry signatures of packages and fail on an invalid one")
+	addCmd.Flags().BoolVar(&addOfflineFlag, "offline", false, "Resolve and install from the cache only, failing on any package missing from it")
+	addCmd.Flags().BoolVar(&addPreferOfflineFlag, "prefer-offline", false, "Use cached data first and the network only for packages or versions missing from the cache")
+	addCmd.Flags().BoolVar(&addOmitLockfileRegistryFlag, "omit-lockfile-registry", false, "Write registry URLs in the lock file against the public npm registry instead of the registry or mirror used")
+	addCmd.Flags().StringVar(&addProgressFlag, "progress", "spinner", "Progress renderer: spinner, or lines to print one line per installed package for CI logs")
+	addCmd.Flags().BoolVar(&addJSONFlag, "json", false, "Print a JSON summary of the added, removed and updated packages instead of progress")
+	addCmd.Flags().BoolVar(&addCacheLockFlag, "cache-lock", false, "Lock each package in the cache while it is extracted so concurrent go-npm processes can share a cache")
+	addCmd.Flags().BoolVar(&addDryRunFlag, "dry-run", false, "Print the packages that would be downloaded and installed without changing any files")
+	addCmd.Flags().BoolVar(&addForceFlag, "force", false, "Install packages whose os or cpu does not match the current platform instead of failing")
+	addCmd.Flags().BoolVar(&addNoSaveFlag, "no-save", false, "Install the package into node_modules without adding it to package.json or the lock file")
+	addCmd.MarkFlagsMutuallyExclusive("json", "progress")
+	addCmd.MarkFlagsMutuallyExclusive("save-dev", "save-optional", "save-peer")
+	addCmd.MarkFlagsMutuallyExclusive("offline", "prefer-offline")
+	addCmd.MarkFlagsMutuallyExclusive("offline", "verify-signatures")
+	addCmd.MarkFlagsMutuallyExclusive("dry-run", "offline")
+	addCmd.MarkFlagsMutuallyExclusive("no-save", "save-dev", "save-optional", "save-peer")
 }
 
 func runAdd(cmd *cobra.Command, args []string) error {
 	pkg, version := parsePackageArg(args[0])
+	if err := packagejson.ValidateName(pkg); err != nil {
+		return err
+	}
+
+	kind := packagejson.DependencyProd
+	switch {
+	case addSaveDevFlag:
+		kind = packagejson.DependencyDev
+	case addSaveOptionalFlag:
+		kind = packagejson.DependencyOptional
+	case addSavePeerFlag:
+		kind = packagejson.DependencyPeer
+	}
 
 	opts := types.BuildOptions{
-		Version: getVersion(),
+		Version:              getVersion(),
+		LogLevel:             logLevelFlag,
+		Quiet:                quietFlag,
+		IncludePrerelease:    addIncludePrereleaseFlag,
+		EngineStrict:         addEngineStrictFlag,
+		NoPeer:               addNoPeerFlag,
+		VerifySignatures:     addVerifySignaturesFlag,
+		Offline:              addOfflineFlag,
+		PreferOffline:        addPreferOfflineFlag,
+		OmitLockfileRegistry: addOmitLockfileRegistryFlag,
+		Progress:             addProgressFlag,
+		JSON:                 addJSONFlag,
+		CacheLock:            addCacheLockFlag,
+		DryRun:               addDryRunFlag,
+		Force:                addForceFlag,
+		NoSave:               addNoSaveFlag,
 	}
 	deps, err := manager.BuildDependencies(opts)
 	if err != nil {
@@ -35,10 +105,16 @@ func runAdd(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("error creating package manager: %w", err)
 	}
+	defer packageManager.Close()
+	packageManager.SetContext(cmd.Context())
 
-	if err := packageManager.Add(pkg, version, false); err != nil {
+	if err := packageManager.Add(pkg, version, kind, false); err != nil {
 		return fmt.Errorf("error adding package: %w", err)
 	}
 
+	if addDryRunFlag {
+		packageManager.FinishDryRun()
+	}
+
 	return nil
 }
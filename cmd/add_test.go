@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/ernesto27/go-npm/packagejson"
@@ -207,6 +208,81 @@ func TestAddCLI(t *testing.T) {
 				assert.Equal(t, "3.0.1", isOddPkg.Version, "is-odd should have version 3.0.1 in lock file")
 			},
 		},
+		{
+			name: "saves a dist-tag as a caret range on the resolved version",
+			setupFunc: func(t *testing.T, testDir string) {
+				err := os.WriteFile(filepath.Join(testDir, "package.json"), []byte(`{"name": "test-project", "version": "1.0.0", "dependencies": {}}`), 0644)
+				require.NoError(t, err)
+			},
+			args:        []string{"add", "react@next"},
+			expectError: false,
+			validate: func(t *testing.T, testDir string, cacheDir string, output string) {
+				mainPkgJSONContent, err := os.ReadFile(filepath.Join(testDir, "package.json"))
+				require.NoError(t, err)
+				var mainPkgJSON packagejson.PackageJSON
+				err = json.Unmarshal(mainPkgJSONContent, &mainPkgJSON)
+				require.NoError(t, err)
+
+				spec := mainPkgJSON.GetDependencies()["react"]
+				assert.True(t, strings.HasPrefix(spec, "^"), "package.json should save a caret range, got %q", spec)
+
+				lockContent, err := os.ReadFile(filepath.Join(testDir, "go-npm-lock.json"))
+				require.NoError(t, err)
+				var lockFile packagejson.PackageLock
+				err = json.Unmarshal(lockContent, &lockFile)
+				require.NoError(t, err)
+
+				reactPkg, exists := lockFile.Packages["node_modules/react"]
+				require.True(t, exists, "lock file should contain node_modules/react entry")
+				assert.Equal(t, strings.TrimPrefix(spec, "^"), reactPkg.Version, "lock file should record the exact tagged version")
+				assert.Equal(t, spec, lockFile.Dependencies["react"], "lock file should record the saved range")
+
+				installedPkgJSON, err := os.ReadFile(filepath.Join(testDir, "node_modules", "react", "package.json"))
+				require.NoError(t, err)
+				assert.Contains(t, string(installedPkgJSON), `"version": "`+reactPkg.Version+`"`)
+			},
+		},
+		{
+			name: "saves latest as a caret range on the resolved version",
+			setupFunc: func(t *testing.T, testDir string) {
+				err := os.WriteFile(filepath.Join(testDir, "package.json"), []byte(`{"name": "test-project", "version": "1.0.0", "dependencies": {}}`), 0644)
+				require.NoError(t, err)
+			},
+			args:        []string{"add", "is-odd@latest"},
+			expectError: false,
+			validate: func(t *testing.T, testDir string, cacheDir string, output string) {
+				mainPkgJSONContent, err := os.ReadFile(filepath.Join(testDir, "package.json"))
+				require.NoError(t, err)
+				var mainPkgJSON packagejson.PackageJSON
+				err = json.Unmarshal(mainPkgJSONContent, &mainPkgJSON)
+				require.NoError(t, err)
+				assert.Equal(t, "^3.0.1", mainPkgJSON.GetDependencies()["is-odd"])
+
+				lockContent, err := os.ReadFile(filepath.Join(testDir, "go-npm-lock.json"))
+				require.NoError(t, err)
+				var lockFile packagejson.PackageLock
+				err = json.Unmarshal(lockContent, &lockFile)
+				require.NoError(t, err)
+				assert.Equal(t, "3.0.1", lockFile.Packages["node_modules/is-odd"].Version)
+				assert.Equal(t, "^3.0.1", lockFile.Dependencies["is-odd"])
+			},
+		},
+		{
+			name: "rejects an invalid package name",
+			setupFunc: func(t *testing.T, testDir string) {
+				err := os.WriteFile(filepath.Join(testDir, "package.json"), []byte(`{"name": "test-project", "dependencies": {}}`), 0644)
+				require.NoError(t, err)
+			},
+			args:        []string{"add", "Is-Odd"},
+			expectError: true,
+			validate: func(t *testing.T, testDir string, cacheDir string, output string) {
+				assert.Contains(t, output, "capital letters")
+
+				pkgJSONContent, err := os.ReadFile(filepath.Join(testDir, "package.json"))
+				require.NoError(t, err)
+				assert.NotContains(t, string(pkgJSONContent), "Is-Odd")
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/ernesto27/go-npm/audit"
+	"github.com/ernesto27/go-npm/config"
+	"github.com/ernesto27/go-npm/manifest"
+	"github.com/ernesto27/go-npm/parsejson"
+	"github.com/ernesto27/go-npm/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	auditJSONFlag  bool
+	auditLevelFlag string
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Report installed packages with known vulnerabilities",
+	Long:  `Send the packages in go-npm-lock.json to the registry's bulk advisory endpoint and print the vulnerabilities found, grouped by severity, with the version that fixes each one. Nothing is changed. The command fails when a vulnerability is at or above --audit-level.`,
+	Args:  cobra.NoArgs,
+	RunE:  runAudit,
+}
+
+func init() {
+	rootCmd.AddCommand(auditCmd)
+	auditCmd.Flags().BoolVar(&auditJSONFlag, "json", false, "Print the report as JSON")
+	auditCmd.Flags().StringVar(&auditLevelFlag, "audit-level", "low", "Fail when a vulnerability is at or above this severity: info, low, moderate, high, critical or none")
+}
+
+func runAudit(cmd *cobra.Command, args []string) error {
+	if err := audit.ValidateLevel(auditLevelFlag); err != nil {
+		return fmt.Errorf("invalid --audit-level: %w", err)
+	}
+
+	cfg, err := config.New()
+	if err != nil {
+		return fmt.Errorf("failed to create config: %w", err)
+	}
+
+	lock, err := projectLock(cfg)
+	if err != nil {
+		return err
+	}
+	if lock == nil {
+		return fmt.Errorf("no go-npm-lock.json found: run 'go-npm install' first")
+	}
+
+	installed := audit.InstalledPackages(lock)
+	client := audit.New(cfg.PrimaryRegistry())
+	client.SetRetries(cfg.FetchRetries)
+	client.SetTimeout(cfg.HTTPTimeout)
+	advisories, err := client.Bulk(cmd.Context(), installed)
+	if err != nil {
+		return err
+	}
+
+	published, err := publishedVersions(cmd, cfg)
+	if err != nil {
+		return err
+	}
+	report := audit.NewReport(audit.Findings(installed, advisories, published))
+
+	if auditJSONFlag {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode report: %w", err)
+		}
+		fmt.Println(string(data))
+	} else {
+		report.Write(os.Stdout)
+	}
+
+	if count := report.AtOrAbove(auditLevelFlag); count > 0 {
+		return fmt.Errorf("found %d vulnerabilities at or above %s", count, auditLevelFlag)
+	}
+	return nil
+}
+
+// publishedVersions returns a lookup of the versions of a package on the
+// registry. Only affected packages are looked up, so their manifests are
+// refreshed to see the latest fixes, falling back to the cached ones. A
+// package without a manifest has no known versions and no fix is reported.
+func publishedVersions(cmd *cobra.Command, cfg *config.Config) (func(name string) []string, error) {
+	m, err := manifest.NewManifest(cfg.BaseDir, cfg.PrimaryRegistry())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create manifest: %w", err)
+	}
+	m.SetFallbackRegistries(cfg.FallbackRegistries())
+	m.SetScopeRegistries(cfg.ScopeRegistries)
+	m.SetRetries(cfg.FetchRetries)
+	m.SetTimeout(cfg.HTTPTimeout)
+	m.SetRateLimiters(utils.NewRateLimiters(cfg.RateLimit))
+	m.SetContext(cmd.Context())
+	parser := parsejson.New()
+
+	return func(name string) []string {
+		m.Download(name, "")
+		npmPackage, err := parser.Parse(m.FilePath(name))
+		if err != nil {
+			return nil
+		}
+		versions := make([]string, 0, len(npmPackage.Versions))
+		for v := range npmPackage.Versions {
+			versions = append(versions, v)
+		}
+		slices.Sort(versions)
+		return versions
+	}, nil
+}
@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ernesto27/go-npm/audit"
+	"github.com/ernesto27/go-npm/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditCLI(t *testing.T) {
+	projectRoot, err := filepath.Abs("..")
+	require.NoError(t, err)
+	binaryPath := utils.BuildTestBinary(t, projectRoot)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/-/npm/v1/security/advisories/bulk":
+			w.Write([]byte(`{"lodash": [{"id": 1, "title": "Prototype Pollution", "url": "https://example.com/1", "severity": "high", "vulnerable_versions": "<4.17.21"}]}`))
+		case "/lodash":
+			w.Write([]byte(`{"name": "lodash", "versions": {"4.17.20": {"version": "4.17.20"}, "4.17.21": {"version": "4.17.21"}}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	t.Setenv("GO_NPM_REGISTRIES", server.URL)
+
+	lock := `{"name": "app", "lockfileVersion": 3, "packages": {
+  "": {"name": "app", "dependencies": {"lodash": "^4.17.0"}},
+  "node_modules/lodash": {"version": "4.17.20"}
+}}`
+
+	testCases := []struct {
+		name        string
+		noLock      bool
+		args        []string
+		expectError bool
+		validate    func(t *testing.T, output string)
+	}{
+		{
+			name:        "reports vulnerabilities and fails at the default level",
+			args:        []string{"audit"},
+			expectError: true,
+			validate: func(t *testing.T, output string) {
+				assert.Contains(t, output, "high (1)\n  lodash@4.17.20: Prototype Pollution\n    vulnerable: <4.17.21, fixed in 4.17.21\n")
+				assert.Contains(t, output, "found 1 vulnerabilities (1 high)")
+				assert.Contains(t, output, "found 1 vulnerabilities at or above low")
+			},
+		},
+		{
+			name: "passes below the audit level",
+			args: []string{"audit", "--audit-level", "critical"},
+			validate: func(t *testing.T, output string) {
+				assert.Contains(t, output, "lodash@4.17.20")
+			},
+		},
+		{
+			name: "prints JSON",
+			args: []string{"audit", "--json", "--audit-level", "none"},
+			validate: func(t *testing.T, output string) {
+				var report audit.Report
+				require.NoError(t, json.Unmarshal([]byte(output), &report))
+				require.Len(t, report.Findings, 1)
+				assert.Equal(t, "4.17.21", report.Findings[0].FixedIn)
+				assert.Equal(t, 1, report.Counts["high"])
+			},
+		},
+		{
+			name:        "rejects an unknown audit level",
+			args:        []string{"audit", "--audit-level", "medium"},
+			expectError: true,
+			validate: func(t *testing.T, output string) {
+				assert.Contains(t, output, "invalid --audit-level")
+			},
+		},
+		{
+			name:        "requires a lock file",
+			noLock:      true,
+			args:        []string{"audit"},
+			expectError: true,
+			validate: func(t *testing.T, output string) {
+				assert.Contains(t, output, "run 'go-npm install' first")
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			testDir := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(testDir, "package.json"), []byte(`{"name": "app", "dependencies": {"lodash": "^4.17.0"}}`), 0644))
+			if !tc.noLock {
+				require.NoError(t, os.WriteFile(filepath.Join(testDir, "go-npm-lock.json"), []byte(lock), 0644))
+			}
+
+			output, err, _ := utils.RunWithIsolatedCache(t, binaryPath, testDir, tc.args...)
+			t.Logf("CLI output:\n%s", string(output))
+			if tc.expectError {
+				assert.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			tc.validate(t, strings.TrimSpace(string(output))+"\n")
+		})
+	}
+}
@@ -2,7 +2,10 @@ package cmd
 
 import (
 	"fmt"
+
+	"github.com/ernesto27/go-npm/cache"
 	"github.com/ernesto27/go-npm/config"
+	"github.com/ernesto27/go-npm/utils"
 
 	"github.com/spf13/cobra"
 )
@@ -20,9 +23,37 @@ var cacheRmCmd = &cobra.Command{
 	RunE:  runCacheRm,
 }
 
+var cacheCleanCmd = &cobra.Command{
+	Use:   "clean",
+	Short: "Remove cached packages and tarballs",
+	Long:  `Remove everything under the packages and tarball cache directories. Manifests and global installations are kept.`,
+	RunE:  runCacheClean,
+}
+
+var cacheVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Verify cached tarballs and remove corrupt ones",
+	Long:  `Revalidate every cached tarball against the integrity recorded in its cached manifest and remove the ones that fail. Works offline.`,
+	RunE:  runCacheVerify,
+}
+
+var cacheLsCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "Show cache size and package count",
+	Long:  `Print the number of cached packages and tarballs and the total size of the cache.`,
+	RunE:  runCacheLs,
+}
+
+var cacheCleanDryRun bool
+
 func init() {
 	rootCmd.AddCommand(cacheCmd)
 	cacheCmd.AddCommand(cacheRmCmd)
+	cacheCmd.AddCommand(cacheCleanCmd)
+	cacheCmd.AddCommand(cacheVerifyCmd)
+	cacheCmd.AddCommand(cacheLsCmd)
+
+	cacheCleanCmd.Flags().BoolVar(&cacheCleanDryRun, "dry-run", false, "List what would be removed without removing it")
 }
 
 func runCacheRm(cmd *cobra.Command, args []string) error {
@@ -38,3 +69,62 @@ func runCacheRm(cmd *cobra.Command, args []string) error {
 	fmt.Println("Cache cleared successfully")
 	return nil
 }
+
+func runCacheClean(cmd *cobra.Command, args []string) error {
+	cfg, err := config.New()
+	if err != nil {
+		return fmt.Errorf("failed to create config: %w", err)
+	}
+
+	removed, err := cache.New(cfg).Clean(cacheCleanDryRun)
+	if err != nil {
+		return fmt.Errorf("failed to clean cache: %w", err)
+	}
+
+	if cacheCleanDryRun {
+		for _, p := range removed {
+			fmt.Printf("Would remove %s\n", p)
+		}
+		fmt.Printf("%d cache entries would be removed\n", len(removed))
+		return nil
+	}
+
+	fmt.Printf("Removed %d cache entries\n", len(removed))
+	return nil
+}
+
+func runCacheVerify(cmd *cobra.Command, args []string) error {
+	cfg, err := config.New()
+	if err != nil {
+		return fmt.Errorf("failed to create config: %w", err)
+	}
+
+	result, err := cache.New(cfg).Verify()
+	if err != nil {
+		return fmt.Errorf("failed to verify cache: %w", err)
+	}
+
+	for _, p := range result.Removed {
+		fmt.Printf("Removed corrupt tarball %s\n", p)
+	}
+	fmt.Printf("Verified %d tarballs, %d without recorded integrity, removed %d\n",
+		result.Verified, result.Unverified, len(result.Removed))
+	return nil
+}
+
+func runCacheLs(cmd *cobra.Command, args []string) error {
+	cfg, err := config.New()
+	if err != nil {
+		return fmt.Errorf("failed to create config: %w", err)
+	}
+
+	stats, err := cache.New(cfg).Stats()
+	if err != nil {
+		return fmt.Errorf("failed to read cache: %w", err)
+	}
+
+	fmt.Printf("Packages: %d\n", stats.Packages)
+	fmt.Printf("Tarballs: %d\n", stats.Tarballs)
+	fmt.Printf("Size:     %s\n", utils.FormatBytes(stats.Size))
+	return nil
+}
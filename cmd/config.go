@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ernesto27/go-npm/config"
+
+	"github.com/spf13/cobra"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage the go-npm config file",
+	Long: `Get, set, list and delete the settings saved in config.json under GO_NPM_HOME.
+The GO_NPM_* environment variables win over the file, and command flags over both.`,
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print a setting saved in the config file",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runConfigGet,
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Save a setting in the config file",
+	Args:  cobra.ExactArgs(2),
+	RunE:  runConfigSet,
+}
+
+var configListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List the settings saved in the config file",
+	Long:    `List the settings saved in the config file, masking secrets, and note the ones an environment variable overrides.`,
+	Args:    cobra.NoArgs,
+	RunE:    runConfigList,
+}
+
+var configDeleteCmd = &cobra.Command{
+	Use:   "delete <key>",
+	Short: "Remove a setting from the config file",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runConfigDelete,
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configListCmd)
+	configCmd.AddCommand(configDeleteCmd)
+}
+
+func runConfigGet(cmd *cobra.Command, args []string) error {
+	file, err := config.LoadFile()
+	if err != nil {
+		return err
+	}
+	if _, ok := config.LookupSetting(args[0]); !ok {
+		return fmt.Errorf("unknown config key %q", args[0])
+	}
+
+	value, ok := file.Values[args[0]]
+	if !ok {
+		return fmt.Errorf("%s is not set", args[0])
+	}
+	fmt.Println(value)
+	return nil
+}
+
+func runConfigSet(cmd *cobra.Command, args []string) error {
+	file, err := config.LoadFile()
+	if err != nil {
+		return err
+	}
+	if err := file.Set(args[0], args[1]); err != nil {
+		return err
+	}
+	return file.Save()
+}
+
+func runConfigList(cmd *cobra.Command, args []string) error {
+	file, err := config.LoadFile()
+	if err != nil {
+		return err
+	}
+
+	for _, setting := range config.Settings {
+		value, ok := file.Values[setting.Key]
+		if !ok {
+			continue
+		}
+		if setting.Secret {
+			value = "(protected)"
+		}
+		line := fmt.Sprintf("%s = %s", setting.Key, value)
+		if os.Getenv(setting.Env) != "" {
+			line += fmt.Sprintf(" (overridden by %s)", setting.Env)
+		}
+		fmt.Println(line)
+	}
+	return nil
+}
+
+func runConfigDelete(cmd *cobra.Command, args []string) error {
+	file, err := config.LoadFile()
+	if err != nil {
+		return err
+	}
+
+	deleted, err := file.Delete(args[0])
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return nil
+	}
+	return file.Save()
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ernesto27/go-npm/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigCLI(t *testing.T) {
+	projectRoot, err := filepath.Abs("..")
+	require.NoError(t, err)
+	binaryPath := utils.BuildTestBinary(t, projectRoot)
+
+	home := t.TempDir()
+	run := func(env []string, args ...string) (string, error) {
+		cmd := exec.Command(binaryPath, append([]string{"config"}, args...)...)
+		cmd.Dir = t.TempDir()
+		cmd.Env = append(os.Environ(), append([]string{"GO_NPM_HOME=" + home, "HOME=" + home, "GO_NPM_REGISTRIES="}, env...)...)
+		output, err := cmd.CombinedOutput()
+		t.Logf("CLI output:\n%s", string(output))
+		return string(output), err
+	}
+
+	_, err = run(nil, "set", "registry", "https://npm.internal")
+	require.NoError(t, err)
+	_, err = run(nil, "set", "auth-token", "npm_secret")
+	require.NoError(t, err)
+
+	output, err := run(nil, "get", "registry")
+	require.NoError(t, err)
+	assert.Equal(t, "https://npm.internal", strings.TrimSpace(output))
+
+	info, err := os.Stat(filepath.Join(home, "config.json"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	output, err = run([]string{"GO_NPM_REGISTRIES=https://mirror.example/"}, "list")
+	require.NoError(t, err)
+	assert.Contains(t, output, "registry = https://npm.internal (overridden by GO_NPM_REGISTRIES)")
+	assert.Contains(t, output, "auth-token = (protected)")
+	assert.NotContains(t, output, "npm_secret")
+
+	output, err = run(nil, "set", "concurrency", "none")
+	assert.Error(t, err)
+	assert.Contains(t, output, `invalid concurrency value "none"`)
+
+	output, err = run(nil, "set", "colour", "always")
+	assert.Error(t, err)
+	assert.Contains(t, output, `unknown config key "colour"`)
+
+	_, err = run(nil, "delete", "registry")
+	require.NoError(t, err)
+	output, err = run(nil, "get", "registry")
+	assert.Error(t, err)
+	assert.Contains(t, output, "registry is not set")
+}
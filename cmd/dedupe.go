@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/ernesto27/go-npm/config"
+	"github.com/ernesto27/go-npm/dedupe"
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/ernesto27/go-npm/utils"
+	"github.com/ernesto27/go-npm/yarnlock"
+	"github.com/spf13/cobra"
+)
+
+var dedupeDryRun bool
+
+var dedupeCmd = &cobra.Command{
+	Use:     "dedupe",
+	Aliases: []string{"ddp"},
+	Short:   "Hoist duplicated nested packages",
+	Long:    `Find packages installed at several node_modules paths that can share a single top-level version satisfying all their dependents, rewrite go-npm-lock.json and remove the redundant nested copies from node_modules.`,
+	RunE:    runDedupe,
+}
+
+func init() {
+	rootCmd.AddCommand(dedupeCmd)
+	dedupeCmd.Flags().BoolVar(&dedupeDryRun, "dry-run", false, "Show what would be deduplicated without changing anything")
+}
+
+func runDedupe(cmd *cobra.Command, args []string) error {
+	cfg, err := config.New()
+	if err != nil {
+		return fmt.Errorf("failed to create config: %w", err)
+	}
+
+	parser := packagejson.NewPackageJSONParser(cfg, yarnlock.NewYarnLockParser())
+	if _, err := parser.ParseDefault(); err != nil {
+		return fmt.Errorf("failed to parse package.json: %w", err)
+	}
+
+	if parser.PackageLock == nil {
+		return fmt.Errorf("no lock file found. Run 'go-npm install' first")
+	}
+
+	lock, changes := dedupe.New(parser.PackageLock).Plan()
+	if len(changes) == 0 {
+		fmt.Println("No duplicated packages to dedupe")
+		return nil
+	}
+
+	packages := 0
+	for _, change := range changes {
+		packages += change.Packages
+		if change.Hoisted != "" {
+			fmt.Printf("%s@%s: hoisted %s, removed %d copies\n", change.Name, change.Version, change.Hoisted, len(change.Removed))
+		} else {
+			fmt.Printf("%s@%s: removed %d nested copies\n", change.Name, change.Version, len(change.Removed))
+		}
+	}
+
+	saved, err := dedupe.Apply(".", changes, dedupeDryRun)
+	if err != nil {
+		return fmt.Errorf("failed to dedupe node_modules: %w", err)
+	}
+
+	if dedupeDryRun {
+		fmt.Printf("%d packages would be removed, saving %s\n", packages, utils.FormatBytes(saved))
+		return nil
+	}
+
+	if err := parser.CreateLockFile(lock, false); err != nil {
+		return err
+	}
+
+	fmt.Printf("Removed %d packages, saved %s\n", packages, utils.FormatBytes(saved))
+	return nil
+}
@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ernesto27/go-npm/config"
+	"github.com/ernesto27/go-npm/doctor"
+	"github.com/ernesto27/go-npm/manager"
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/ernesto27/go-npm/prune"
+	"github.com/ernesto27/go-npm/types"
+	"github.com/ernesto27/go-npm/yarnlock"
+	"github.com/spf13/cobra"
+)
+
+var doctorFixFlag bool
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the go-npm environment and node_modules for common problems",
+	Long:  `Run a set of checks against the go-npm environment and print how to fix any problems found. Inside a project with go-npm-lock.json, node_modules is checked against the lock too: every locked package is installed at its locked version, no extraneous packages are installed, peer dependencies are satisfied and cached tarballs still match their integrity. With --fix, missing packages are reinstalled and extraneous ones pruned before the checks run.`,
+	RunE:  runDoctor,
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+	doctorCmd.Flags().BoolVar(&doctorFixFlag, "fix", false, "Reinstall missing packages and prune extraneous ones")
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	cfg, err := config.New()
+	if err != nil {
+		return fmt.Errorf("failed to create config: %w", err)
+	}
+
+	lock, err := projectLock(cfg)
+	if err != nil {
+		return err
+	}
+
+	if doctorFixFlag && lock != nil {
+		if err := fixTree(cmd, cfg, lock); err != nil {
+			return err
+		}
+		// The install may have rewritten the lock
+		if lock, err = projectLock(cfg); err != nil {
+			return err
+		}
+	}
+
+	d := doctor.New(cfg, os.Stdout)
+	if lock != nil {
+		d.SetProject(".", lock)
+	}
+
+	failed := 0
+	for _, result := range d.Run() {
+		if !result.OK {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("doctor found %d problem(s)", failed)
+	}
+
+	return nil
+}
+
+// projectLock returns the lock of the project in the current directory, nil
+// outside a project or before its first install
+func projectLock(cfg *config.Config) (*packagejson.PackageLock, error) {
+	if _, err := os.Stat(filepath.Join(".", "package.json")); err != nil {
+		return nil, nil
+	}
+
+	parser := packagejson.NewPackageJSONParser(cfg, yarnlock.NewYarnLockParser())
+	if _, err := parser.ParseDefault(); err != nil {
+		return nil, fmt.Errorf("failed to parse package.json: %w", err)
+	}
+	return parser.PackageLock, nil
+}
+
+// fixTree prunes the extraneous packages, then reinstalls the missing ones
+// and those at the wrong version
+func fixTree(cmd *cobra.Command, cfg *config.Config, lock *packagejson.PackageLock) error {
+	d := doctor.New(cfg, os.Stdout)
+	d.SetProject(".", lock)
+
+	extraneous, err := d.Extraneous()
+	if err != nil {
+		return fmt.Errorf("failed to scan node_modules: %w", err)
+	}
+	if len(extraneous) > 0 {
+		if _, err := prune.Apply(".", extraneous, false); err != nil {
+			return fmt.Errorf("failed to prune node_modules: %w", err)
+		}
+		fmt.Printf("Removed %d extraneous packages\n", len(extraneous))
+	}
+
+	problems, _ := doctor.FindTreeProblems(".", lock)
+	if len(problems) == 0 {
+		return nil
+	}
+
+	// Installs only place packages whose directory is missing
+	for _, problem := range problems {
+		if problem.Installed != "" {
+			if err := os.RemoveAll(problem.Path); err != nil {
+				return fmt.Errorf("failed to remove %s: %w", problem.Path, err)
+			}
+		}
+	}
+	fmt.Printf("Reinstalling %d packages\n", len(problems))
+
+	deps, err := manager.BuildDependencies(types.BuildOptions{
+		Version:  getVersion(),
+		LogLevel: logLevelFlag,
+		Quiet:    quietFlag,
+	})
+	if err != nil {
+		return fmt.Errorf("error building dependencies: %w", err)
+	}
+
+	packageManager, err := manager.New(deps)
+	if err != nil {
+		return fmt.Errorf("error creating package manager: %w", err)
+	}
+	defer packageManager.Close()
+	packageManager.SetContext(cmd.Context())
+
+	if err := packageManager.ParsePackageJSON(); err != nil {
+		return fmt.Errorf("error parsing package.json: %w", err)
+	}
+	if err := packageManager.InstallFromCache(); err != nil {
+		return err
+	}
+	fmt.Println()
+	return nil
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/ernesto27/go-npm/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDoctorCLI_Fix(t *testing.T) {
+	projectRoot, err := filepath.Abs("..")
+	require.NoError(t, err)
+	binaryPath := utils.BuildTestBinary(t, projectRoot)
+
+	server := serveTestTarballs(t, map[string]string{"first": "1.0.0", "second": "2.0.0"})
+	testDir := t.TempDir()
+	cacheDir := t.TempDir()
+	// Keep the environment check green so only the tree decides the outcome
+	t.Setenv("PATH", os.Getenv("PATH")+string(os.PathListSeparator)+filepath.Join(cacheDir, "global", "bin"))
+
+	packageJSON := fmt.Sprintf(`{"name": "app", "version": "1.0.0", "dependencies": {"first": "%[1]s/first-1.0.0.tgz", "second": "%[1]s/second-2.0.0.tgz"}}`, server.URL)
+	require.NoError(t, os.WriteFile(filepath.Join(testDir, "package.json"), []byte(packageJSON), 0644))
+	runWithCache(t, binaryPath, testDir, cacheDir, "install")
+
+	output := runWithCache(t, binaryPath, testDir, cacheDir, "doctor")
+	assert.Contains(t, output, "✓ installed packages: 2 packages match go-npm-lock.json")
+
+	// Break the tree: a missing package, one at the wrong version and an
+	// extraneous one
+	require.NoError(t, os.RemoveAll(filepath.Join(testDir, "node_modules", "first")))
+	// The installed file is hardlinked to the cache, so replace it instead of
+	// writing through it
+	secondJSON := filepath.Join(testDir, "node_modules", "second", "package.json")
+	require.NoError(t, os.Remove(secondJSON))
+	require.NoError(t, os.WriteFile(secondJSON, []byte(`{"name": "second", "version": "1.0.0"}`), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(testDir, "node_modules", "stray"), 0755))
+
+	cmd := exec.Command(binaryPath, "doctor")
+	cmd.Dir = testDir
+	cmd.Env = append(os.Environ(), "GO_NPM_HOME="+cacheDir, "HOME="+cacheDir)
+	out, err := cmd.CombinedOutput()
+	t.Logf("go-npm doctor:\n%s", string(out))
+	require.Error(t, err, "doctor fails on a broken tree")
+	for _, expected := range []string{
+		"✗ installed packages: 2 of 2 locked packages are missing or at the wrong version",
+		"- node_modules/first: missing, lock has 1.0.0",
+		"- node_modules/second: 1.0.0 installed, lock has 2.0.0",
+		"✗ extraneous packages: 1 packages are not in go-npm-lock.json",
+		"- node_modules/stray",
+	} {
+		assert.Contains(t, string(out), expected)
+	}
+
+	output = runWithCache(t, binaryPath, testDir, cacheDir, "doctor", "--fix")
+	assert.Contains(t, output, "Removed 1 extraneous packages")
+	assert.Contains(t, output, "Reinstalling 2 packages")
+	assert.Contains(t, output, "✓ installed packages: 2 packages match go-npm-lock.json")
+	assert.Contains(t, output, "✓ extraneous packages: none found")
+
+	assert.NoDirExists(t, filepath.Join(testDir, "node_modules", "stray"))
+	data, err := os.ReadFile(filepath.Join(testDir, "node_modules", "second", "package.json"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"2.0.0"`)
+	assert.FileExists(t, filepath.Join(testDir, "node_modules", "first", "package.json"))
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ernesto27/go-npm/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runWithCache runs the binary in workDir against the given cache, so that
+// several commands of a test share it
+func runWithCache(t *testing.T, binaryPath, workDir, cacheDir string, args ...string) string {
+	t.Helper()
+
+	cmd := exec.Command(binaryPath, args...)
+	cmd.Dir = workDir
+	cmd.Env = append(os.Environ(), "GO_NPM_HOME="+cacheDir, "HOME="+cacheDir)
+	output, err := cmd.CombinedOutput()
+	t.Logf("go-npm %s:\n%s", strings.Join(args, " "), string(output))
+	require.NoError(t, err, "command failed with output: %s", string(output))
+	return string(output)
+}
+
+// snapshotFiles maps every file and symlink under dir to its content or
+// target
+func snapshotFiles(t *testing.T, dir string) map[string]string {
+	t.Helper()
+
+	files := make(map[string]string)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		switch {
+		case d.Type()&fs.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			files[rel] = "-> " + target
+		case d.Type().IsRegular():
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			files[rel] = string(data)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	return files
+}
+
+func TestDryRunCLI(t *testing.T) {
+	projectRoot, err := filepath.Abs("..")
+	require.NoError(t, err)
+	binaryPath := utils.BuildTestBinary(t, projectRoot)
+
+	server := serveTestTarballs(t, map[string]string{"first": "1.0.0", "second": "2.0.0"})
+	tarballURL := func(name, version string) string {
+		return fmt.Sprintf("%s/%s-%s.tgz", server.URL, name, version)
+	}
+	writePackageJSON := func(t *testing.T, testDir string, deps ...string) {
+		t.Helper()
+		var entries []string
+		for _, dep := range deps {
+			name, version, _ := strings.Cut(dep, "@")
+			entries = append(entries, fmt.Sprintf(`%q: %q`, name, tarballURL(name, version)))
+		}
+		packageJSON := fmt.Sprintf(`{"name": "test-project", "version": "1.0.0", "dependencies": {%s}}`, strings.Join(entries, ", "))
+		require.NoError(t, os.WriteFile(filepath.Join(testDir, "package.json"), []byte(packageJSON), 0644))
+	}
+
+	testCases := []struct {
+		name      string
+		installed []string
+		args      []string
+		expected  []string
+	}{
+		{
+			name:     "fresh install",
+			args:     []string{"install", "--dry-run"},
+			expected: []string{"Would download:\n  first@1.0.0", "Would install:\n  first@1.0.0", "1 to download, 1 to install, 0 to remove"},
+		},
+		{
+			name:      "add",
+			installed: []string{"first@1.0.0"},
+			args:      []string{"add", "second@" + tarballURL("second", "2.0.0"), "--dry-run"},
+			expected:  []string{"Would download:\n  second@2.0.0", "Would install:\n  second@2.0.0", "1 to download, 1 to install, 0 to remove"},
+		},
+		{
+			name:      "uninstall",
+			installed: []string{"first@1.0.0", "second@2.0.0"},
+			args:      []string{"uninstall", "first", "--dry-run"},
+			expected:  []string{"Would remove:\n  first@1.0.0", "0 to download, 0 to install, 1 to remove"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			testDir := t.TempDir()
+			cacheDir := t.TempDir()
+
+			if len(tc.installed) > 0 {
+				writePackageJSON(t, testDir, tc.installed...)
+				runWithCache(t, binaryPath, testDir, cacheDir, "install")
+			} else {
+				writePackageJSON(t, testDir, "first@1.0.0")
+			}
+
+			project := snapshotFiles(t, testDir)
+			cache := snapshotFiles(t, cacheDir)
+
+			output := runWithCache(t, binaryPath, testDir, cacheDir, tc.args...)
+			for _, expected := range tc.expected {
+				assert.Contains(t, output, expected)
+			}
+
+			assert.Equal(t, project, snapshotFiles(t, testDir), "--dry-run should not change the project")
+			assert.Equal(t, cache, snapshotFiles(t, cacheDir), "--dry-run should not change the cache")
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/ernesto27/go-npm/config"
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/ernesto27/go-npm/yarnlock"
+	"github.com/spf13/cobra"
+)
+
+var exportLockOutput string
+
+var exportLockCmd = &cobra.Command{
+	Use:   "export-lock",
+	Short: "Export the lock file as an npm package-lock.json",
+	Long:  `Convert go-npm-lock.json into an npm lockfileVersion 3 package-lock.json so the project can be installed with npm.`,
+	RunE:  runExportLock,
+}
+
+func init() {
+	rootCmd.AddCommand(exportLockCmd)
+	exportLockCmd.Flags().StringVarP(&exportLockOutput, "output", "o", packagejson.LOCK_FILE_NAME_NPM, "Path of the generated lock file")
+}
+
+func runExportLock(cmd *cobra.Command, args []string) error {
+	cfg, err := config.New()
+	if err != nil {
+		return fmt.Errorf("failed to create config: %w", err)
+	}
+
+	parser := packagejson.NewPackageJSONParser(cfg, yarnlock.NewYarnLockParser())
+	if _, err := parser.ParseDefault(); err != nil {
+		return fmt.Errorf("failed to parse package.json: %w", err)
+	}
+
+	if parser.PackageLock == nil {
+		return fmt.Errorf("no lock file found. Run 'go-npm install' first")
+	}
+
+	if err := parser.WriteNPMLockFile(parser.PackageLock, exportLockOutput); err != nil {
+		return err
+	}
+
+	fmt.Printf("Exported %s\n", exportLockOutput)
+	return nil
+}
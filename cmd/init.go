@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/spf13/cobra"
+)
+
+var (
+	initYes   bool
+	initForce bool
+)
+
+// initPackage is the package.json written by init, in npm's field order
+type initPackage struct {
+	Name        string `json:"name"`
+	Version     string `json:"version"`
+	Description string `json:"description"`
+	Main        string `json:"main"`
+	License     string `json:"license"`
+}
+
+var initCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Create a package.json in the current directory",
+	Long:  `Create a package.json in the current directory, prompting for the package name, version, description, entry point and license. Press enter to accept the default shown in parentheses, or pass --yes to accept all defaults. An existing package.json is only overwritten with --force.`,
+	Args:  cobra.NoArgs,
+	RunE:  runInit,
+}
+
+func init() {
+	rootCmd.AddCommand(initCmd)
+	initCmd.Flags().BoolVarP(&initYes, "yes", "y", false, "Accept all defaults without prompting")
+	initCmd.Flags().BoolVarP(&initForce, "force", "f", false, "Overwrite an existing package.json")
+}
+
+func runInit(cmd *cobra.Command, args []string) error {
+	if _, err := os.Stat("package.json"); err == nil && !initForce {
+		return fmt.Errorf("package.json already exists. Use --force to overwrite it")
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+
+	pkg := initPackage{
+		Name:    defaultPackageName(filepath.Base(cwd)),
+		Version: "1.0.0",
+		Main:    "index.js",
+		License: "ISC",
+	}
+
+	if initYes {
+		if err := packagejson.ValidateName(pkg.Name); err != nil {
+			return fmt.Errorf("%w. Run 'go-npm init' without --yes to choose a name", err)
+		}
+	} else {
+		pkg, err = promptPackage(os.Stdin, os.Stdout, pkg)
+		if err != nil {
+			return err
+		}
+	}
+
+	data, err := marshalInitPackage(pkg)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile("package.json", data, 0644); err != nil {
+		return fmt.Errorf("failed to write package.json: %w", err)
+	}
+
+	fmt.Printf("Wrote to %s:\n\n%s\n", filepath.Join(cwd, "package.json"), data)
+	return nil
+}
+
+// promptPackage asks for each field of pkg, keeping its current value when
+// the answer is empty. Invalid names and versions are asked again; the end
+// of input accepts the remaining defaults.
+func promptPackage(in io.Reader, out io.Writer, pkg initPackage) (initPackage, error) {
+	scanner := bufio.NewScanner(in)
+
+	ask := func(label, value string, validate func(string) error) (string, error) {
+		for {
+			fmt.Fprintf(out, "%s: ", label)
+			if value != "" {
+				fmt.Fprintf(out, "(%s) ", value)
+			}
+
+			if !scanner.Scan() {
+				fmt.Fprintln(out)
+				if err := scanner.Err(); err != nil {
+					return "", fmt.Errorf("failed to read answer: %w", err)
+				}
+				if validate != nil {
+					if err := validate(value); err != nil {
+						return "", err
+					}
+				}
+				return value, nil
+			}
+
+			answer := strings.TrimSpace(scanner.Text())
+			if answer == "" {
+				answer = value
+			}
+			if validate != nil {
+				if err := validate(answer); err != nil {
+					fmt.Fprintln(out, err)
+					continue
+				}
+			}
+			return answer, nil
+		}
+	}
+
+	var err error
+	if pkg.Name, err = ask("package name", pkg.Name, packagejson.ValidateName); err != nil {
+		return pkg, err
+	}
+	if pkg.Version, err = ask("version", pkg.Version, validateInitVersion); err != nil {
+		return pkg, err
+	}
+	if pkg.Description, err = ask("description", pkg.Description, nil); err != nil {
+		return pkg, err
+	}
+	if pkg.Main, err = ask("entry point", pkg.Main, nil); err != nil {
+		return pkg, err
+	}
+	if pkg.License, err = ask("license", pkg.License, nil); err != nil {
+		return pkg, err
+	}
+
+	return pkg, nil
+}
+
+// defaultPackageName turns a directory name into a package name the way npm
+// does: lowercased, with spaces replaced by dashes
+func defaultPackageName(dir string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(dir)), " ", "-")
+}
+
+// validateInitVersion checks that v is a full semver version
+func validateInitVersion(v string) error {
+	if _, err := semver.StrictNewVersion(v); err != nil {
+		return fmt.Errorf("invalid version %q: must be a semver version like 1.0.0", v)
+	}
+	return nil
+}
+
+// marshalInitPackage formats pkg as a two-space indented package.json,
+// without escaping characters like & or < in the description
+func marshalInitPackage(pkg initPackage) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(pkg); err != nil {
+		return nil, fmt.Errorf("failed to encode package.json: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ernesto27/go-npm/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInitCLI(t *testing.T) {
+	projectRoot, err := filepath.Abs("..")
+	require.NoError(t, err)
+	binaryPath := utils.BuildTestBinary(t, projectRoot)
+
+	existing := `{"name": "existing", "version": "2.0.0"}`
+
+	testCases := []struct {
+		name        string
+		setupFunc   func(t *testing.T, testDir string)
+		args        []string
+		expectError bool
+		validate    func(t *testing.T, testDir string, output string)
+	}{
+		{
+			name:        "--yes writes the defaults",
+			args:        []string{"init", "--yes"},
+			expectError: false,
+			validate: func(t *testing.T, testDir string, output string) {
+				data, err := os.ReadFile(filepath.Join(testDir, "package.json"))
+				require.NoError(t, err)
+
+				var pkg map[string]string
+				require.NoError(t, json.Unmarshal(data, &pkg))
+				assert.Equal(t, map[string]string{
+					"name":        "my-app",
+					"version":     "1.0.0",
+					"description": "",
+					"main":        "index.js",
+					"license":     "ISC",
+				}, pkg)
+				assert.True(t, strings.HasPrefix(string(data), "{\n  \"name\": \"my-app\",\n  \"version\": \"1.0.0\""))
+				assert.Contains(t, output, "Wrote to")
+			},
+		},
+		{
+			name: "refuses to overwrite an existing package.json",
+			setupFunc: func(t *testing.T, testDir string) {
+				require.NoError(t, os.WriteFile(filepath.Join(testDir, "package.json"), []byte(existing), 0644))
+			},
+			args:        []string{"init", "--yes"},
+			expectError: true,
+			validate: func(t *testing.T, testDir string, output string) {
+				assert.Contains(t, output, "package.json already exists")
+
+				data, err := os.ReadFile(filepath.Join(testDir, "package.json"))
+				require.NoError(t, err)
+				assert.Equal(t, existing, string(data))
+			},
+		},
+		{
+			name: "--force overwrites an existing package.json",
+			setupFunc: func(t *testing.T, testDir string) {
+				require.NoError(t, os.WriteFile(filepath.Join(testDir, "package.json"), []byte(existing), 0644))
+			},
+			args:        []string{"init", "--yes", "--force"},
+			expectError: false,
+			validate: func(t *testing.T, testDir string, output string) {
+				data, err := os.ReadFile(filepath.Join(testDir, "package.json"))
+				require.NoError(t, err)
+				assert.Contains(t, string(data), `"name": "my-app"`)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			testDir := filepath.Join(t.TempDir(), "My App")
+			require.NoError(t, os.Mkdir(testDir, 0755))
+
+			if tc.setupFunc != nil {
+				tc.setupFunc(t, testDir)
+			}
+
+			output, err, _ := utils.RunWithIsolatedCache(t, binaryPath, testDir, tc.args...)
+
+			t.Logf("CLI output:\n%s", string(output))
+
+			if tc.expectError {
+				assert.Error(t, err)
+			} else {
+				require.NoError(t, err, "command failed with output: %s", string(output))
+			}
+
+			if tc.validate != nil {
+				tc.validate(t, testDir, string(output))
+			}
+		})
+	}
+}
+
+func TestPromptPackage(t *testing.T) {
+	defaults := initPackage{Name: "my-app", Version: "1.0.0", Main: "index.js", License: "ISC"}
+
+	testCases := []struct {
+		name        string
+		input       string
+		expectError bool
+		expected    initPackage
+		validate    func(t *testing.T, output string)
+	}{
+		{
+			name:     "empty answers keep the defaults",
+			input:    "\n\n\n\n\n",
+			expected: defaults,
+			validate: func(t *testing.T, output string) {
+				assert.Contains(t, output, "package name: (my-app) ")
+				assert.Contains(t, output, "entry point: (index.js) ")
+			},
+		},
+		{
+			name:     "answers replace the defaults",
+			input:    "@me/tool\n0.1.0\nA tool & more\nmain.js\nMIT\n",
+			expected: initPackage{Name: "@me/tool", Version: "0.1.0", Description: "A tool & more", Main: "main.js", License: "MIT"},
+		},
+		{
+			name:     "invalid name and version are asked again",
+			input:    "Bad Name\nok-name\nnot-a-version\n2.0.0\n",
+			expected: initPackage{Name: "ok-name", Version: "2.0.0", Main: "index.js", License: "ISC"},
+			validate: func(t *testing.T, output string) {
+				assert.Equal(t, 2, strings.Count(output, "package name:"))
+				assert.Contains(t, output, "name cannot contain capital letters")
+				assert.Contains(t, output, `invalid version "not-a-version"`)
+			},
+		},
+		{
+			name:        "end of input with an invalid default fails",
+			input:       "",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			start := defaults
+			if tc.expectError {
+				start.Name = "Invalid Name"
+			}
+
+			var out bytes.Buffer
+			pkg, err := promptPackage(strings.NewReader(tc.input), &out, start)
+
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, pkg)
+
+			if tc.validate != nil {
+				tc.validate(t, out.String())
+			}
+		})
+	}
+}
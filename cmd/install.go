@@ -2,25 +2,56 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 	"strings"
 
 	"github.com/ernesto27/go-npm/manager"
+	"github.com/ernesto27/go-npm/progress"
 	"github.com/ernesto27/go-npm/types"
 	"github.com/spf13/cobra"
 )
 
 var (
-	globalFlag       bool
-	productionFlag   bool
-	verboseFlag      bool
-	ignoreScriptsFlag bool
+	globalFlag               bool
+	productionFlag           bool
+	verboseFlag              bool
+	ignoreScriptsFlag        bool
+	explainResolutionFlag    bool
+	includePrereleaseFlag    bool
+	maxConcurrencyFlag       int
+	engineStrictFlag         bool
+	noPeerFlag               bool
+	verifySignaturesFlag     bool
+	atomicFlag               bool
+	reportConflictsFlag      bool
+	forceResolutionsFlag     bool
+	installStrategyFlag      string
+	installLinksFlag         bool
+	packLinksFlag            bool
+	workspaceFlags           []string
+	allWorkspacesFlag        bool
+	offlineFlag              bool
+	preferOfflineFlag        bool
+	omitLockfileRegistryFlag bool
+	progressFlag             string
+	jsonFlag                 bool
+	cacheLockFlag            bool
+	resolutionOnlyFlag       bool
+	noOptionalFlag           bool
+	ignoreOptionalFlag       bool
+	dryRunFlag               bool
+	noBinLinksFlag           bool
+	minimalFlag              bool
+	timingFlag               bool
+	forceFlag                bool
+	omitFlags                []string
 )
 
 var installCmd = &cobra.Command{
 	Use:     "install [package[@version]]",
 	Aliases: []string{"i"},
 	Short:   "Install packages",
-	Long:    `Install packages from package.json or install a specific package globally.`,
+	Long:    `Install packages from package.json or install a specific package globally. Run with --global and no package inside a project to link its bins globally.`,
 	RunE:    runInstall,
 }
 
@@ -28,25 +59,112 @@ func init() {
 	rootCmd.AddCommand(installCmd)
 	installCmd.Flags().BoolVarP(&globalFlag, "global", "g", false, "Install package globally")
 	installCmd.Flags().BoolVar(&productionFlag, "production", false, "Install only production dependencies")
+	installCmd.Flags().StringSliceVar(&omitFlags, "omit", nil, "Leave dependency groups out of the lock file and node_modules: dev, optional or peer (repeatable or comma-separated)")
 	installCmd.Flags().BoolVarP(&verboseFlag, "verbose", "v", false, "Show verbose output with all installed packages")
 	installCmd.Flags().BoolVar(&ignoreScriptsFlag, "ignore-scripts", false, "Skip running lifecycle scripts")
+	installCmd.Flags().BoolVar(&explainResolutionFlag, "explain-resolution", false, "Print a JSON trace of version resolution decisions to stderr")
+	installCmd.Flags().BoolVar(&includePrereleaseFlag, "include-prerelease", false, "Allow latest to resolve to a prerelease version")
+	installCmd.Flags().IntVar(&maxConcurrencyFlag, "max-concurrency", 0, "Maximum number of packages fetched in parallel (default NumCPU*4)")
+	installCmd.Flags().BoolVar(&engineStrictFlag, "engine-strict", false, "Fail when a package's engines.node does not match the installed node")
+	installCmd.Flags().BoolVar(&noPeerFlag, "no-peer", false, "Do not install, validate or lock peer dependencies")
+	installCmd.Flags().BoolVar(&verifySignaturesFlag, "verify-signatures", false, "Verify registry signatures of packages and fail on an invalid one")
+	installCmd.Flags().BoolVar(&atomicFlag, "atomic", false, "Build node_modules in node_modules.tmp and swap it into place once complete")
+	installCmd.Flags().BoolVar(&reportConflictsFlag, "report-conflicts", false, "Report packages whose requested ranges no single version satisfies")
+	installCmd.Flags().BoolVar(&forceResolutionsFlag, "force-resolutions", false, "Install one version of each conflicting package, the highest satisfying the most ranges")
+	installCmd.Flags().StringVar(&installStrategyFlag, "install-strategy", "", "How packages are placed from the cache: copy, hardlink or symlink (default hardlink)")
+	installCmd.Flags().BoolVar(&installLinksFlag, "install-links", false, "Copy workspace and file: dependencies into node_modules instead of symlinking them")
+	installCmd.Flags().BoolVar(&packLinksFlag, "pack-links", false, "Copy workspace and file: dependencies with only the files npm would publish (implies --install-links)")
+	installCmd.Flags().StringArrayVarP(&workspaceFlags, "workspace", "w", nil, "Install only the dependencies of the named workspace (repeatable, accepts globs like @org/* and workspace directories)")
+	installCmd.Flags().BoolVar(&allWorkspacesFlag, "workspaces", false, "Install the dependencies of every workspace, skipping the root dependencies")
+	installCmd.Flags().BoolVar(&offlineFlag, "offline", false, "Resolve and install from the cache only, failing on any package missing from it")
+	installCmd.Flags().BoolVar(&preferOfflineFlag, "prefer-offline", false, "Use cached data first and the network only for packages or versions missing from the cache")
+	installCmd.Flags().BoolVar(&omitLockfileRegistryFlag, "omit-lockfile-registry", false, "Write registry URLs in the lock file against the public npm registry instead of the registry or mirror used")
+	installCmd.Flags().StringVar(&progressFlag, "progress", "spinner", "Progress renderer: spinner, or lines to print one line per installed package for CI logs")
+	installCmd.Flags().BoolVar(&jsonFlag, "json", false, "Print a JSON summary of the added, removed and updated packages instead of progress")
+	installCmd.Flags().BoolVar(&cacheLockFlag, "cache-lock", false, "Lock each package in the cache while it is extracted so concurrent go-npm processes can share a cache")
+	installCmd.Flags().BoolVar(&resolutionOnlyFlag, "resolution-only", false, "Resolve dependencies and write the lock file without creating or changing node_modules")
+	installCmd.Flags().BoolVar(&noOptionalFlag, "no-optional", false, "Leave optional dependencies out of the lock file and node_modules")
+	installCmd.Flags().BoolVar(&ignoreOptionalFlag, "ignore-optional", false, "Record optional dependencies in the lock file without downloading or installing them")
+	installCmd.Flags().BoolVar(&dryRunFlag, "dry-run", false, "Print the packages that would be downloaded, installed and removed without changing any files")
+	installCmd.Flags().BoolVar(&noBinLinksFlag, "no-bin-links", false, "Do not link package executables into node_modules/.bin")
+	installCmd.Flags().BoolVar(&minimalFlag, "minimal", false, "Fastest install for throwaway environments: --no-bin-links, --ignore-scripts and --progress lines")
+	installCmd.Flags().BoolVar(&timingFlag, "timing", false, "Print the slowest packages and the time spent fetching manifests, downloading, extracting and copying")
+	installCmd.Flags().BoolVar(&forceFlag, "force", false, "Install packages whose os or cpu does not match the current platform instead of failing")
+	installCmd.MarkFlagsMutuallyExclusive("global", "atomic")
+	installCmd.MarkFlagsMutuallyExclusive("atomic", "workspace")
+	installCmd.MarkFlagsMutuallyExclusive("atomic", "workspaces")
+	installCmd.MarkFlagsMutuallyExclusive("json", "progress")
+	installCmd.MarkFlagsMutuallyExclusive("offline", "prefer-offline")
+	installCmd.MarkFlagsMutuallyExclusive("offline", "verify-signatures")
+	installCmd.MarkFlagsMutuallyExclusive("global", "workspace")
+	installCmd.MarkFlagsMutuallyExclusive("global", "workspaces")
+	installCmd.MarkFlagsMutuallyExclusive("no-optional", "ignore-optional")
+	installCmd.MarkFlagsMutuallyExclusive("resolution-only", "global")
+	installCmd.MarkFlagsMutuallyExclusive("resolution-only", "workspace")
+	installCmd.MarkFlagsMutuallyExclusive("resolution-only", "workspaces")
+	installCmd.MarkFlagsMutuallyExclusive("dry-run", "global")
+	installCmd.MarkFlagsMutuallyExclusive("dry-run", "workspace")
+	installCmd.MarkFlagsMutuallyExclusive("dry-run", "workspaces")
+	installCmd.MarkFlagsMutuallyExclusive("dry-run", "resolution-only")
+	installCmd.MarkFlagsMutuallyExclusive("dry-run", "offline")
+	installCmd.MarkFlagsMutuallyExclusive("minimal", "global")
+	installCmd.MarkFlagsMutuallyExclusive("minimal", "progress")
+	installCmd.MarkFlagsMutuallyExclusive("minimal", "json")
 }
 
 func parsePackageArg(pkgArg string) (string, string) {
-	parts := strings.Split(pkgArg, "@")
-	pkg := parts[0]
-	version := ""
-	if len(parts) > 1 {
-		version = parts[1]
+	// The leading @ of a scoped package (@scope/name@version) is not a separator
+	start := 0
+	if strings.HasPrefix(pkgArg, "@") {
+		start = 1
 	}
-	return pkg, version
+	if idx := strings.Index(pkgArg[start:], "@"); idx != -1 {
+		return pkgArg[:start+idx], pkgArg[start+idx+1:]
+	}
+	return pkgArg, ""
 }
 
 func runInstall(cmd *cobra.Command, args []string) error {
 	opts := types.BuildOptions{
-		Version:       getVersion(),
-		Verbose:       verboseFlag,
-		IgnoreScripts: ignoreScriptsFlag,
+		Version:              getVersion(),
+		LogLevel:             logLevelFlag,
+		Quiet:                quietFlag,
+		Production:           productionFlag,
+		Omit:                 omitFlags,
+		Verbose:              verboseFlag,
+		IgnoreScripts:        ignoreScriptsFlag,
+		ExplainResolution:    explainResolutionFlag,
+		IncludePrerelease:    includePrereleaseFlag,
+		MaxConcurrency:       maxConcurrencyFlag,
+		EngineStrict:         engineStrictFlag,
+		NoPeer:               noPeerFlag,
+		VerifySignatures:     verifySignaturesFlag,
+		AtomicInstall:        atomicFlag,
+		ReportConflicts:      reportConflictsFlag,
+		ForceResolutions:     forceResolutionsFlag,
+		InstallStrategy:      installStrategyFlag,
+		InstallLinks:         installLinksFlag,
+		PackLinks:            packLinksFlag,
+		Workspaces:           workspaceFlags,
+		AllWorkspaces:        allWorkspacesFlag,
+		Offline:              offlineFlag,
+		PreferOffline:        preferOfflineFlag,
+		OmitLockfileRegistry: omitLockfileRegistryFlag,
+		Progress:             progressFlag,
+		JSON:                 jsonFlag,
+		CacheLock:            cacheLockFlag,
+		ResolutionOnly:       resolutionOnlyFlag,
+		NoOptional:           noOptionalFlag,
+		IgnoreOptional:       ignoreOptionalFlag,
+		DryRun:               dryRunFlag,
+		NoBinLinks:           noBinLinksFlag,
+		Timing:               timingFlag,
+		Force:                forceFlag,
+	}
+	if minimalFlag {
+		opts.NoBinLinks = true
+		opts.IgnoreScripts = true
+		opts.Progress = progress.RendererLines
 	}
 	deps, err := manager.BuildDependencies(opts)
 	if err != nil {
@@ -57,18 +175,31 @@ func runInstall(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("error creating package manager: %w", err)
 	}
+	defer packageManager.Close()
+	packageManager.SetContext(cmd.Context())
 
 	if globalFlag {
 		if len(args) < 1 {
-			return fmt.Errorf("package name is required for global installation")
+			if _, err := os.Stat("package.json"); err != nil {
+				return fmt.Errorf("package name is required for global installation")
+			}
 		}
 
-		pkg, version := parsePackageArg(args[0])
-
 		if err := packageManager.SetupGlobal(); err != nil {
 			return fmt.Errorf("error setting up global installation: %w", err)
 		}
 
+		// Without a package name, link the current project globally (like npm link)
+		if len(args) < 1 {
+			if err := packageManager.LinkGlobal("."); err != nil {
+				return fmt.Errorf("error linking globally: %w", err)
+			}
+
+			return nil
+		}
+
+		pkg, version := parsePackageArg(args[0])
+
 		if err := packageManager.InstallGlobal(pkg, version); err != nil {
 			return fmt.Errorf("error installing globally: %w", err)
 		}
@@ -76,10 +207,20 @@ func runInstall(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
-	if err := packageManager.ParsePackageJSON(productionFlag); err != nil {
+	if err := packageManager.ParsePackageJSON(); err != nil {
 		return fmt.Errorf("error parsing package.json: %w", err)
 	}
 
+	if resolutionOnlyFlag {
+		packageManager.FinishResolution()
+		return nil
+	}
+
+	if dryRunFlag {
+		packageManager.FinishDryRun()
+		return nil
+	}
+
 	if err := packageManager.InstallFromCache(); err != nil {
 		return err
 	}
@@ -1,7 +1,14 @@
 package cmd
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -267,3 +274,185 @@ func TestInstallCLI_Global(t *testing.T) {
 		})
 	}
 }
+
+// serveTestTarballs serves an npm-style tarball of a dependency-free package
+// at /<name>-<version>.tgz for each name@version, so installs of tarball URL
+// dependencies run without the registry
+func serveTestTarballs(t *testing.T, packages map[string]string) *httptest.Server {
+	t.Helper()
+
+	tarballs := make(map[string][]byte)
+	for name, version := range packages {
+		var buf bytes.Buffer
+		gzw := gzip.NewWriter(&buf)
+		tw := tar.NewWriter(gzw)
+		content := fmt.Sprintf(`{"name": %q, "version": %q}`, name, version)
+		require.NoError(t, tw.WriteHeader(&tar.Header{
+			Name:     "package/package.json",
+			Mode:     0644,
+			Size:     int64(len(content)),
+			Typeflag: tar.TypeReg,
+		}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+		require.NoError(t, tw.Close())
+		require.NoError(t, gzw.Close())
+		tarballs[fmt.Sprintf("/%s-%s.tgz", name, version)] = buf.Bytes()
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := tarballs[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestInstallCLI_ResolutionOnly(t *testing.T) {
+	projectRoot, err := filepath.Abs("..")
+	require.NoError(t, err)
+	binaryPath := utils.BuildTestBinary(t, projectRoot)
+
+	server := serveTestTarballs(t, map[string]string{"first": "1.0.0", "second": "2.0.0"})
+	writePackageJSON := func(t *testing.T, testDir string, deps map[string]string) {
+		t.Helper()
+		var entries []string
+		for name, version := range deps {
+			entries = append(entries, fmt.Sprintf(`%q: "%s/%s-%s.tgz"`, name, server.URL, name, version))
+		}
+		packageJSON := fmt.Sprintf(`{"name": "test-project", "version": "1.0.0", "dependencies": {%s}}`, strings.Join(entries, ", "))
+		require.NoError(t, os.WriteFile(filepath.Join(testDir, "package.json"), []byte(packageJSON), 0644))
+	}
+
+	testCases := []struct {
+		name     string
+		runs     []map[string]string
+		expected []string
+	}{
+		{
+			name:     "creates the lock file",
+			runs:     []map[string]string{{"first": "1.0.0"}},
+			expected: []string{"node_modules/first"},
+		},
+		{
+			name:     "updates an existing lock file",
+			runs:     []map[string]string{{"first": "1.0.0"}, {"first": "1.0.0", "second": "2.0.0"}},
+			expected: []string{"node_modules/first", "node_modules/second"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			testDir := t.TempDir()
+
+			for _, deps := range tc.runs {
+				writePackageJSON(t, testDir, deps)
+				output, err, _ := utils.RunWithIsolatedCache(t, binaryPath, testDir, "install", "--resolution-only")
+				t.Logf("CLI output:\n%s", string(output))
+				require.NoError(t, err, "command failed with output: %s", string(output))
+			}
+
+			lockData, err := os.ReadFile(filepath.Join(testDir, "go-npm-lock.json"))
+			require.NoError(t, err, "go-npm-lock.json should be created")
+			for _, pkgPath := range tc.expected {
+				assert.Contains(t, string(lockData), fmt.Sprintf("%q", pkgPath))
+			}
+			assert.NoDirExists(t, filepath.Join(testDir, "node_modules"),
+				"node_modules should not be created with --resolution-only")
+		})
+	}
+}
+
+func TestInstallCLI_ConflictingFlags(t *testing.T) {
+	projectRoot, err := filepath.Abs("..")
+	require.NoError(t, err)
+	binaryPath := utils.BuildTestBinary(t, projectRoot)
+
+	testCases := []struct {
+		name          string
+		args          []string
+		errorContains string
+	}{
+		{
+			name:          "atomic with a workspace",
+			args:          []string{"--atomic", "--workspace", "a"},
+			errorContains: "[atomic workspace] were all set",
+		},
+		{
+			name:          "atomic with every workspace",
+			args:          []string{"--atomic", "--workspaces"},
+			errorContains: "[atomic workspaces] were all set",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			testDir := t.TempDir()
+			packageJSON := `{"name": "root", "private": true, "workspaces": ["packages/*"]}`
+			require.NoError(t, os.WriteFile(filepath.Join(testDir, "package.json"), []byte(packageJSON), 0644))
+
+			output, err, _ := utils.RunWithIsolatedCache(t, binaryPath, testDir, append([]string{"install"}, tc.args...)...)
+			t.Logf("CLI output:\n%s", string(output))
+			assert.Error(t, err)
+			assert.Contains(t, string(output), tc.errorContains)
+			assert.NoDirExists(t, filepath.Join(testDir, "node_modules.tmp"))
+		})
+	}
+}
+
+func TestInstallCLI_CacheLock(t *testing.T) {
+	projectRoot, err := filepath.Abs("..")
+	require.NoError(t, err)
+	binaryPath := utils.BuildTestBinary(t, projectRoot)
+
+	packages := map[string]string{"first": "1.0.0", "second": "2.0.0", "third": "3.0.0", "fourth": "4.0.0"}
+	server := serveTestTarballs(t, packages)
+	var entries []string
+	for name, version := range packages {
+		entries = append(entries, fmt.Sprintf(`%q: "%s/%s-%s.tgz"`, name, server.URL, name, version))
+	}
+	packageJSON := fmt.Sprintf(`{"name": "test-project", "version": "1.0.0", "dependencies": {%s}}`, strings.Join(entries, ", "))
+
+	// Two processes install the same packages into one shared cache at once
+	cacheDir := t.TempDir()
+	projects := []string{t.TempDir(), t.TempDir()}
+	cmds := make([]*exec.Cmd, len(projects))
+	outputs := make([]bytes.Buffer, len(projects))
+	for i, projectDir := range projects {
+		require.NoError(t, os.WriteFile(filepath.Join(projectDir, "package.json"), []byte(packageJSON), 0644))
+
+		cmds[i] = exec.Command(binaryPath, "install", "--cache-lock")
+		cmds[i].Dir = projectDir
+		cmds[i].Env = append(os.Environ(), "GO_NPM_HOME="+cacheDir, "HOME="+cacheDir)
+		cmds[i].Stdout = &outputs[i]
+		cmds[i].Stderr = &outputs[i]
+		require.NoError(t, cmds[i].Start())
+	}
+	for i, cmd := range cmds {
+		require.NoError(t, cmd.Wait(), "install %d failed with output: %s", i, outputs[i].String())
+	}
+
+	for _, projectDir := range projects {
+		for name := range packages {
+			assert.FileExists(t, filepath.Join(projectDir, "node_modules", name, "package.json"))
+		}
+	}
+
+	// Every cache entry is complete, with no extraction left half done
+	cached, err := os.ReadDir(filepath.Join(cacheDir, "packages"))
+	require.NoError(t, err)
+	assert.Len(t, cached, len(packages))
+	for _, entry := range cached {
+		assert.False(t, strings.HasSuffix(entry.Name(), ".tmp"), "partial extraction %s left in the cache", entry.Name())
+		assert.FileExists(t, filepath.Join(cacheDir, "packages", entry.Name(), "package.json"))
+	}
+	tarballs, err := os.ReadDir(filepath.Join(cacheDir, "tarball"))
+	require.NoError(t, err)
+	for _, entry := range tarballs {
+		assert.True(t, strings.HasSuffix(entry.Name(), ".tgz"), "partial download %s left in the cache", entry.Name())
+	}
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/ernesto27/go-npm/manager"
+	"github.com/ernesto27/go-npm/types"
+	"github.com/spf13/cobra"
+)
+
+var linkCmd = &cobra.Command{
+	Use:   "link [package]",
+	Short: "Link a local package for development",
+	Long: `Without arguments, register the package in the current directory in the global link store.
+With a package name, symlink a globally linked package into ./node_modules.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runLink,
+}
+
+var unlinkCmd = &cobra.Command{
+	Use:   "unlink [package]",
+	Short: "Remove a link created by link",
+	Long: `Without arguments, remove the package in the current directory from the global link store.
+With a package name, remove its symlink from ./node_modules.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runUnlink,
+}
+
+func init() {
+	rootCmd.AddCommand(linkCmd)
+	rootCmd.AddCommand(unlinkCmd)
+}
+
+func newLinkPackageManager(global bool) (*manager.PackageManager, error) {
+	deps, err := manager.BuildDependencies(types.BuildOptions{Version: getVersion(), LogLevel: logLevelFlag, Quiet: quietFlag})
+	if err != nil {
+		return nil, fmt.Errorf("error building dependencies: %w", err)
+	}
+
+	packageManager, err := manager.New(deps)
+	if err != nil {
+		return nil, fmt.Errorf("error creating package manager: %w", err)
+	}
+
+	if global {
+		if err := packageManager.SetupGlobal(); err != nil {
+			return nil, fmt.Errorf("error setting up global installation: %w", err)
+		}
+	}
+
+	return packageManager, nil
+}
+
+func runLink(cmd *cobra.Command, args []string) error {
+	packageManager, err := newLinkPackageManager(len(args) == 0)
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		if err := packageManager.Link("."); err != nil {
+			return fmt.Errorf("error linking package: %w", err)
+		}
+		return nil
+	}
+
+	if err := packageManager.LinkPackage(args[0]); err != nil {
+		return fmt.Errorf("error linking package: %w", err)
+	}
+
+	return nil
+}
+
+func runUnlink(cmd *cobra.Command, args []string) error {
+	packageManager, err := newLinkPackageManager(len(args) == 0)
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		if err := packageManager.UnlinkGlobal("."); err != nil {
+			return fmt.Errorf("error unlinking package: %w", err)
+		}
+		fmt.Println("Package unlinked successfully")
+		return nil
+	}
+
+	if err := packageManager.UnlinkPackage(args[0]); err != nil {
+		return fmt.Errorf("error unlinking package: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ernesto27/go-npm/config"
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/ernesto27/go-npm/yarnlock"
+	"github.com/spf13/cobra"
+)
+
+var migrateForceFlag bool
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate [lock-file]",
+	Short: "Convert another package manager's lock file to go-npm-lock.json",
+	Long:  `Convert npm-shrinkwrap.json, package-lock.json, yarn.lock (v1) or pnpm-lock.yaml (lockfileVersion 6 and 9) to go-npm-lock.json without installing anything. Without an argument the first of them found in the current directory is converted.`,
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  runMigrate,
+}
+
+func init() {
+	rootCmd.AddCommand(migrateCmd)
+	migrateCmd.Flags().BoolVar(&migrateForceFlag, "force", false, "Overwrite an existing go-npm-lock.json")
+}
+
+func runMigrate(cmd *cobra.Command, args []string) error {
+	var lockFile string
+	if len(args) > 0 {
+		lockFile = args[0]
+	} else {
+		found, ok := packagejson.FindForeignLockFile(".")
+		if !ok {
+			return fmt.Errorf("no lock file to migrate, looked for %s", strings.Join(packagejson.ForeignLockFiles, ", "))
+		}
+		lockFile = found
+	}
+
+	if _, err := os.Stat(packagejson.LOCK_FILE_NAME_GO_NPM); err == nil && !migrateForceFlag {
+		return fmt.Errorf("%s already exists, use --force to overwrite it", packagejson.LOCK_FILE_NAME_GO_NPM)
+	}
+
+	cfg, err := config.New()
+	if err != nil {
+		return fmt.Errorf("failed to create config: %w", err)
+	}
+
+	parser := packagejson.NewPackageJSONParser(cfg, yarnlock.NewYarnLockParser())
+	if _, err := parser.ParseDefault(); err != nil {
+		return fmt.Errorf("failed to parse package.json: %w", err)
+	}
+
+	warnings, err := parser.Migrate(lockFile)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Migrated %s to %s: %d packages\n", lockFile, packagejson.LOCK_FILE_NAME_GO_NPM, len(parser.PackageLock.Packages))
+	for _, warning := range warnings {
+		fmt.Printf("Warning: %s\n", warning)
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/ernesto27/go-npm/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateCLI(t *testing.T) {
+	projectRoot, err := filepath.Abs("..")
+	require.NoError(t, err)
+	binaryPath := utils.BuildTestBinary(t, projectRoot)
+
+	yarnLock := `# yarn lockfile v1
+
+express@^4.18.2:
+  version "4.18.2"
+  resolved "https://registry.yarnpkg.com/express/-/express-4.18.2.tgz"
+  integrity sha512-express
+  dependencies:
+    debug "2.6.9"
+
+debug@2.6.9:
+  version "2.6.9"
+  resolved "https://registry.yarnpkg.com/debug/-/debug-2.6.9.tgz"
+`
+
+	testCases := []struct {
+		name        string
+		files       map[string]string
+		args        []string
+		expectError bool
+		output      []string
+		expected    map[string]packagejson.PackageItem
+	}{
+		{
+			name: "converts yarn.lock",
+			files: map[string]string{
+				"package.json": `{"name": "app", "dependencies": {"express": "^4.18.2"}}`,
+				"yarn.lock":    yarnLock,
+			},
+			args: []string{"migrate"},
+			output: []string{
+				"Migrated yarn.lock to go-npm-lock.json: 2 packages",
+				"Warning: debug@2.6.9 has no integrity, its tarball will not be verified",
+			},
+			expected: map[string]packagejson.PackageItem{
+				"node_modules/express": {
+					Name:         "express",
+					Version:      "4.18.2",
+					Resolved:     "https://registry.yarnpkg.com/express/-/express-4.18.2.tgz",
+					Integrity:    "sha512-express",
+					Dependencies: map[string]string{"debug": "2.6.9"},
+				},
+				"node_modules/debug": {
+					Name:     "debug",
+					Version:  "2.6.9",
+					Resolved: "https://registry.yarnpkg.com/debug/-/debug-2.6.9.tgz",
+				},
+			},
+		},
+		{
+			name: "refuses to overwrite a go-npm lock",
+			files: map[string]string{
+				"package.json":     `{"name": "app"}`,
+				"yarn.lock":        yarnLock,
+				"go-npm-lock.json": `{}`,
+			},
+			args:        []string{"migrate"},
+			expectError: true,
+		},
+		{
+			name: "converts pnpm-lock.yaml",
+			files: map[string]string{
+				"package.json": `{"name": "app", "dependencies": {"debug": "^2.6.9"}}`,
+				"pnpm-lock.yaml": `lockfileVersion: '9.0'
+importers:
+  .:
+    dependencies:
+      debug:
+        specifier: ^2.6.9
+        version: 2.6.9
+packages:
+  debug@2.6.9:
+    resolution: {integrity: sha512-debug}
+  ms@2.0.0:
+    resolution: {integrity: sha512-ms}
+snapshots:
+  debug@2.6.9:
+    dependencies:
+      ms: 2.0.0
+  ms@2.0.0: {}
+`,
+			},
+			args:   []string{"migrate"},
+			output: []string{"Migrated pnpm-lock.yaml to go-npm-lock.json: 2 packages"},
+			expected: map[string]packagejson.PackageItem{
+				"node_modules/debug": {
+					Name:         "debug",
+					Version:      "2.6.9",
+					Resolved:     "https://registry.npmjs.org/debug/-/debug-2.6.9.tgz",
+					Integrity:    "sha512-debug",
+					Dependencies: map[string]string{"ms": "2.0.0"},
+				},
+				"node_modules/ms": {
+					Name:      "ms",
+					Version:   "2.0.0",
+					Resolved:  "https://registry.npmjs.org/ms/-/ms-2.0.0.tgz",
+					Integrity: "sha512-ms",
+				},
+			},
+		},
+		{
+			name: "pnpm lockfileVersion 5 is not supported",
+			files: map[string]string{
+				"package.json":   `{"name": "app"}`,
+				"pnpm-lock.yaml": "lockfileVersion: 5.4\n",
+			},
+			args:        []string{"migrate"},
+			expectError: true,
+		},
+		{
+			name: "npm lockfileVersion 1 is not supported",
+			files: map[string]string{
+				"package.json":      `{"name": "app"}`,
+				"package-lock.json": `{"name": "app", "lockfileVersion": 1, "dependencies": {}}`,
+			},
+			args:        []string{"migrate", "package-lock.json"},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			testDir := t.TempDir()
+			for name, content := range tc.files {
+				require.NoError(t, os.WriteFile(filepath.Join(testDir, name), []byte(content), 0644))
+			}
+
+			output, err, cacheDir := utils.RunWithIsolatedCache(t, binaryPath, testDir, tc.args...)
+			t.Logf("CLI output:\n%s", string(output))
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err, "command failed with output: %s", string(output))
+
+			for _, expected := range tc.output {
+				assert.Contains(t, string(output), expected)
+			}
+
+			data, err := os.ReadFile(filepath.Join(testDir, packagejson.LOCK_FILE_NAME_GO_NPM))
+			require.NoError(t, err)
+			var lock packagejson.PackageLock
+			require.NoError(t, json.Unmarshal(data, &lock))
+			assert.Equal(t, tc.expected, lock.Packages)
+
+			// Nothing is installed or downloaded
+			assert.NoDirExists(t, filepath.Join(testDir, "node_modules"))
+			tarballs, err := os.ReadDir(filepath.Join(cacheDir, "tarball"))
+			require.NoError(t, err)
+			assert.Empty(t, tarballs)
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ernesto27/go-npm/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstallCLI_Minimal(t *testing.T) {
+	projectRoot, err := filepath.Abs("..")
+	require.NoError(t, err)
+	binaryPath := utils.BuildTestBinary(t, projectRoot)
+
+	var buf bytes.Buffer
+	gzw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gzw)
+	for name, content := range map[string]string{
+		"package.json": `{"name": "tool", "version": "1.0.0", "bin": {"tool": "cli.js"}}`,
+		"cli.js":       "#!/usr/bin/env node\n",
+	} {
+		require.NoError(t, tw.WriteHeader(&tar.Header{
+			Name:     "package/" + name,
+			Mode:     0755,
+			Size:     int64(len(content)),
+			Typeflag: tar.TypeReg,
+		}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gzw.Close())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(buf.Bytes())
+	}))
+	defer server.Close()
+
+	testCases := []struct {
+		name          string
+		args          []string
+		expectBinLink bool
+		expectScripts bool
+	}{
+		{
+			name:          "default install links bins and runs scripts",
+			args:          []string{"install"},
+			expectBinLink: true,
+			expectScripts: true,
+		},
+		{
+			name: "minimal install skips bins and scripts",
+			args: []string{"install", "--minimal"},
+		},
+		{
+			name:          "no-bin-links alone still runs scripts",
+			args:          []string{"install", "--no-bin-links"},
+			expectScripts: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			testDir := t.TempDir()
+			packageJSON := fmt.Sprintf(`{
+				"name": "test-project",
+				"version": "1.0.0",
+				"scripts": {"postinstall": "touch postinstall-ran"},
+				"dependencies": {"tool": %q}
+			}`, server.URL+"/tool-1.0.0.tgz")
+			require.NoError(t, os.WriteFile(filepath.Join(testDir, "package.json"), []byte(packageJSON), 0644))
+
+			output, err, _ := utils.RunWithIsolatedCache(t, binaryPath, testDir, tc.args...)
+			t.Logf("CLI output:\n%s", string(output))
+			require.NoError(t, err, "command failed with output: %s", string(output))
+
+			assert.FileExists(t, filepath.Join(testDir, "node_modules", "tool", "package.json"))
+			assert.FileExists(t, filepath.Join(testDir, "node_modules", "tool", "cli.js"))
+
+			binPath := filepath.Join(testDir, "node_modules", ".bin", "tool")
+			if tc.expectBinLink {
+				_, err := os.Lstat(binPath)
+				assert.NoError(t, err)
+			} else {
+				assert.NoFileExists(t, binPath)
+			}
+
+			markerPath := filepath.Join(testDir, "postinstall-ran")
+			if tc.expectScripts {
+				assert.FileExists(t, markerPath)
+			} else {
+				assert.NoFileExists(t, markerPath)
+			}
+		})
+	}
+}
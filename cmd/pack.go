@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/ernesto27/go-npm/integrity"
+	"github.com/ernesto27/go-npm/pack"
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/ernesto27/go-npm/utils"
+	"github.com/ernesto27/go-npm/workspace"
+	"github.com/spf13/cobra"
+)
+
+var packDestinationFlag string
+
+var packCmd = &cobra.Command{
+	Use:   "pack",
+	Short: "Create a tarball of the package as it would be published",
+	Long:  `Pack the current package into <name>-<version>.tgz, with the files npm would publish under package/. The "files" list of package.json, .npmignore and .gitignore decide what is included.`,
+	Args:  cobra.NoArgs,
+	RunE:  runPack,
+}
+
+func init() {
+	rootCmd.AddCommand(packCmd)
+	packCmd.Flags().StringVar(&packDestinationFlag, "pack-destination", ".", "Directory to write the tarball to")
+}
+
+func runPack(cmd *cobra.Command, args []string) error {
+	parser := packagejson.NewPackageJSONParser(nil, nil)
+	pkg, err := parser.ParseDefault()
+	if err != nil {
+		return fmt.Errorf("failed to parse package.json: %w", err)
+	}
+	if err := packagejson.ValidateName(pkg.Name); err != nil {
+		return err
+	}
+	version, ok := pkg.Version.(string)
+	if !ok || version == "" {
+		return fmt.Errorf("package.json has no version")
+	}
+
+	files, err := pack.Files(".", pkg)
+	if err != nil {
+		return err
+	}
+	manifest, err := packedManifest(".", parser, nil)
+	if err != nil {
+		return err
+	}
+	contents := map[string][]byte{"package.json": manifest}
+
+	// A tarball left by an earlier pack is overwritten, never packed into itself
+	filename := filepath.Join(packDestinationFlag, pack.Filename(pkg.Name, version))
+	files = slices.DeleteFunc(files, func(file string) bool {
+		return filepath.Clean(filepath.FromSlash(file)) == filepath.Clean(filename)
+	})
+
+	fmt.Printf("package: %s@%s\n", pkg.Name, version)
+	var unpackedSize int64
+	for _, file := range files {
+		size := int64(len(contents[file]))
+		if _, ok := contents[file]; !ok {
+			info, err := os.Stat(filepath.FromSlash(file))
+			if err != nil {
+				return fmt.Errorf("failed to stat %s: %w", file, err)
+			}
+			size = info.Size()
+		}
+		unpackedSize += size
+		fmt.Printf("%10s  %s\n", utils.FormatBytes(size), file)
+	}
+
+	out, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", filename, err)
+	}
+	if err := pack.Write(out, ".", files, contents); err != nil {
+		out.Close()
+		os.Remove(filename)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(filename)
+		return fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+
+	sri, err := integrity.GenerateIntegrity(filename, []string{"sha512"})
+	if err != nil {
+		return fmt.Errorf("failed to hash %s: %w", filename, err)
+	}
+
+	fmt.Printf("total files: %d\n", len(files))
+	fmt.Printf("unpacked size: %s\n", utils.FormatBytes(unpackedSize))
+	fmt.Printf("integrity: %s\n", sri)
+	fmt.Println(filename)
+	return nil
+}
+
+// packedManifest returns the package.json of the package in dir as it is
+// packed: with its workspace: dependencies resolved against the workspaces of
+// registry, or of the monorepo dir is in when registry is nil
+func packedManifest(dir string, parser *packagejson.PackageJSONParser, registry *workspace.WorkspaceRegistry) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read package.json: %w", err)
+	}
+	if registry == nil {
+		if registry, err = workspace.FindRegistry(dir, parser); err != nil {
+			return nil, fmt.Errorf("failed to discover workspaces: %w", err)
+		}
+	}
+	return registry.ResolveManifest(data)
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ernesto27/go-npm/integrity"
+	"github.com/ernesto27/go-npm/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPackCLI(t *testing.T) {
+	projectRoot, err := filepath.Abs("..")
+	require.NoError(t, err)
+	binaryPath := utils.BuildTestBinary(t, projectRoot)
+
+	testCases := []struct {
+		name        string
+		files       map[string]string
+		dir         string
+		tarball     string
+		expected    []string
+		manifest    []string
+		expectError bool
+	}{
+		{
+			name: "packs the files list under package/",
+			files: map[string]string{
+				"package.json":              `{"name": "@scope/lib", "version": "1.2.0", "files": ["dist"]}`,
+				"README.md":                 "# lib\n",
+				"dist/index.js":             "module.exports = 1\n",
+				"src/index.ts":              "export default 1\n",
+				"node_modules/dep/index.js": "",
+			},
+			tarball:  "scope-lib-1.2.0.tgz",
+			expected: []string{"package/README.md", "package/dist/index.js", "package/package.json"},
+		},
+		{
+			name: "does not pack a tarball left by an earlier pack",
+			files: map[string]string{
+				"package.json":  `{"name": "lib", "version": "1.0.0"}`,
+				"index.js":      "",
+				"lib-1.0.0.tgz": "stale",
+			},
+			tarball:  "lib-1.0.0.tgz",
+			expected: []string{"package/index.js", "package/package.json"},
+		},
+		{
+			name: "resolves workspace: dependencies in the packed package.json",
+			files: map[string]string{
+				"package.json":            `{"name": "root", "private": true, "workspaces": ["packages/*"]}`,
+				"packages/a/package.json": `{"name": "a", "version": "1.0.0", "dependencies": {"b": "workspace:^", "c": "workspace:*"}}`,
+				"packages/a/index.js":     "",
+				"packages/b/package.json": `{"name": "b", "version": "2.0.0"}`,
+				"packages/c/package.json": `{"name": "c", "version": "3.1.0"}`,
+			},
+			dir:      "packages/a",
+			tarball:  "a-1.0.0.tgz",
+			expected: []string{"package/index.js", "package/package.json"},
+			manifest: []string{`"b": "^2.0.0"`, `"c": "3.1.0"`},
+		},
+		{
+			name: "fails without a version",
+			files: map[string]string{
+				"package.json": `{"name": "lib"}`,
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			testDir := t.TempDir()
+			for name, content := range tc.files {
+				filePath := filepath.Join(testDir, filepath.FromSlash(name))
+				require.NoError(t, os.MkdirAll(filepath.Dir(filePath), 0755))
+				require.NoError(t, os.WriteFile(filePath, []byte(content), 0644))
+			}
+
+			packDir := filepath.Join(testDir, filepath.FromSlash(tc.dir))
+			output, err, _ := utils.RunWithIsolatedCache(t, binaryPath, packDir, "pack")
+			t.Logf("CLI output:\n%s", string(output))
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err, "command failed with output: %s", string(output))
+
+			for _, entry := range tc.expected {
+				assert.Contains(t, string(output), entry[len("package/"):])
+			}
+			assert.Contains(t, string(output), tc.tarball)
+
+			sri, err := integrity.ComputeSRI(filepath.Join(packDir, tc.tarball), "sha512")
+			require.NoError(t, err)
+			assert.Contains(t, string(output), "integrity: "+sri)
+
+			file, err := os.Open(filepath.Join(packDir, tc.tarball))
+			require.NoError(t, err)
+			defer file.Close()
+			gzr, err := gzip.NewReader(file)
+			require.NoError(t, err)
+			tr := tar.NewReader(gzr)
+
+			var entries []string
+			for {
+				header, err := tr.Next()
+				if err == io.EOF {
+					break
+				}
+				require.NoError(t, err)
+				entries = append(entries, header.Name)
+
+				if header.Name == "package/package.json" {
+					manifest, err := io.ReadAll(tr)
+					require.NoError(t, err)
+					for _, expected := range tc.manifest {
+						assert.Contains(t, string(manifest), expected)
+					}
+					assert.NotContains(t, string(manifest), "workspace:")
+				}
+			}
+			assert.Equal(t, tc.expected, entries)
+		})
+	}
+}
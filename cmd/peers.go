@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ernesto27/go-npm/config"
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/ernesto27/go-npm/peers"
+	"github.com/ernesto27/go-npm/yarnlock"
+	"github.com/spf13/cobra"
+)
+
+var peersJSON bool
+
+var peersCmd = &cobra.Command{
+	Use:   "peers",
+	Short: "Show the peer dependency matrix",
+	Long:  `Show every peer dependency in go-npm-lock.json with the packages requiring it, their ranges and the installed version, marking the dependents that version does not satisfy.`,
+	RunE:  runPeers,
+}
+
+func init() {
+	rootCmd.AddCommand(peersCmd)
+	peersCmd.Flags().BoolVar(&peersJSON, "json", false, "Print the matrix as JSON")
+}
+
+func runPeers(cmd *cobra.Command, args []string) error {
+	cfg, err := config.New()
+	if err != nil {
+		return fmt.Errorf("failed to create config: %w", err)
+	}
+
+	parser := packagejson.NewPackageJSONParser(cfg, yarnlock.NewYarnLockParser())
+	if _, err := parser.ParseDefault(); err != nil {
+		return fmt.Errorf("failed to parse package.json: %w", err)
+	}
+
+	if parser.PackageLock == nil {
+		return fmt.Errorf("no lock file found. Run 'go-npm install' first")
+	}
+
+	entries := peers.BuildMatrix(parser.PackageLock)
+	if peersJSON {
+		return peers.WriteJSON(os.Stdout, entries)
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("No peer dependencies")
+		return nil
+	}
+
+	if err := peers.WriteTable(os.Stdout, entries); err != nil {
+		return err
+	}
+
+	conflicts := 0
+	for _, entry := range entries {
+		if entry.Conflict {
+			conflicts++
+		}
+	}
+	if conflicts > 0 {
+		fmt.Printf("\n%d of %d peer dependencies have unsatisfied dependents\n", conflicts, len(entries))
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/ernesto27/go-npm/config"
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/ernesto27/go-npm/prune"
+	"github.com/ernesto27/go-npm/utils"
+	"github.com/ernesto27/go-npm/yarnlock"
+	"github.com/spf13/cobra"
+)
+
+var (
+	pruneDryRun     bool
+	pruneProduction bool
+)
+
+var pruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove extraneous packages from node_modules",
+	Long:  `Remove packages from node_modules that go-npm-lock.json does not list, e.g. after switching branches. With --production, packages only needed by devDependencies are removed as well. Workspace and linked package symlinks are kept.`,
+	RunE:  runPrune,
+}
+
+func init() {
+	rootCmd.AddCommand(pruneCmd)
+	pruneCmd.Flags().BoolVar(&pruneDryRun, "dry-run", false, "Show what would be removed without changing anything")
+	pruneCmd.Flags().BoolVar(&pruneProduction, "production", false, "Also remove packages only needed by devDependencies")
+}
+
+func runPrune(cmd *cobra.Command, args []string) error {
+	cfg, err := config.New()
+	if err != nil {
+		return fmt.Errorf("failed to create config: %w", err)
+	}
+
+	parser := packagejson.NewPackageJSONParser(cfg, yarnlock.NewYarnLockParser())
+	if _, err := parser.ParseDefault(); err != nil {
+		return fmt.Errorf("failed to parse package.json: %w", err)
+	}
+
+	if parser.PackageLock == nil {
+		return fmt.Errorf("no lock file found. Run 'go-npm install' first")
+	}
+
+	pruner := prune.New(parser.PackageLock)
+	pruner.SetProduction(pruneProduction)
+	pruner.SetPackagesDir(cfg.PackagesDir)
+	pruner.SetStoreDir(cfg.StoreDir)
+
+	extraneous, err := pruner.Plan(".")
+	if err != nil {
+		return fmt.Errorf("failed to scan node_modules: %w", err)
+	}
+	if len(extraneous) == 0 {
+		fmt.Println("No extraneous packages")
+		return nil
+	}
+
+	for _, pkgPath := range extraneous {
+		fmt.Printf("- %s\n", pkgPath)
+	}
+
+	saved, err := prune.Apply(".", extraneous, pruneDryRun)
+	if err != nil {
+		return fmt.Errorf("failed to prune node_modules: %w", err)
+	}
+
+	if pruneDryRun {
+		fmt.Printf("%d packages would be removed, saving %s\n", len(extraneous), utils.FormatBytes(saved))
+		return nil
+	}
+
+	fmt.Printf("Removed %d packages, saved %s\n", len(extraneous), utils.FormatBytes(saved))
+	return nil
+}
@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/ernesto27/go-npm/auth"
+	"github.com/ernesto27/go-npm/config"
+	"github.com/ernesto27/go-npm/pack"
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/ernesto27/go-npm/publish"
+	"github.com/ernesto27/go-npm/utils"
+	"github.com/ernesto27/go-npm/workspace"
+	"github.com/spf13/cobra"
+)
+
+var (
+	publishTagFlag           string
+	publishAccessFlag        string
+	publishDryRunFlag        bool
+	publishWorkspaceFlags    []string
+	publishAllWorkspacesFlag bool
+)
+
+var publishCmd = &cobra.Command{
+	Use:   "publish",
+	Short: "Publish the package to the registry",
+	Long: `Pack the current package and upload it to the registry with the configured auth token.
+The registry is publishConfig.registry, else the registry of the package scope, else the primary one. With --workspace or --workspaces each selected workspace package is published instead, skipping the private ones.`,
+	Args: cobra.NoArgs,
+	RunE: runPublish,
+}
+
+func init() {
+	rootCmd.AddCommand(publishCmd)
+	publishCmd.Flags().StringVar(&publishTagFlag, "tag", "latest", "Dist-tag to point at the published version (default publishConfig.tag or latest)")
+	publishCmd.Flags().StringVar(&publishAccessFlag, "access", "", "Publish as public or restricted (default publishConfig.access)")
+	publishCmd.Flags().BoolVar(&publishDryRunFlag, "dry-run", false, "Show what would be published without uploading it")
+	publishCmd.Flags().StringArrayVarP(&publishWorkspaceFlags, "workspace", "w", nil, "Publish the named workspace (repeatable, accepts globs like @org/* and workspace directories)")
+	publishCmd.Flags().BoolVar(&publishAllWorkspacesFlag, "workspaces", false, "Publish every workspace")
+}
+
+func runPublish(cmd *cobra.Command, args []string) error {
+	if publishAccessFlag != "" && publishAccessFlag != "public" && publishAccessFlag != "restricted" {
+		return fmt.Errorf("invalid --access %q: must be public or restricted", publishAccessFlag)
+	}
+
+	cfg, err := config.New()
+	if err != nil {
+		return fmt.Errorf("failed to create config: %w", err)
+	}
+	parser := packagejson.NewPackageJSONParser(cfg, nil)
+
+	pkg, err := parser.ParseDefault()
+	if err != nil {
+		return fmt.Errorf("failed to parse package.json: %w", err)
+	}
+
+	if len(publishWorkspaceFlags) == 0 && !publishAllWorkspacesFlag {
+		return publishPackage(cmd, cfg, parser, nil, ".", pkg)
+	}
+
+	if len(pkg.GetWorkspaces()) == 0 {
+		return fmt.Errorf("no workspaces defined in package.json")
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+
+	registry := workspace.NewWorkspaceRegistry(cwd, parser)
+	if err := registry.Discover(pkg); err != nil {
+		return fmt.Errorf("failed to discover workspaces: %w", err)
+	}
+
+	selected := registry.Sorted()
+	if len(publishWorkspaceFlags) > 0 {
+		if selected, err = registry.Select(publishWorkspaceFlags); err != nil {
+			return err
+		}
+	}
+
+	publishable := workspace.Publishable(selected)
+	for _, ws := range selected {
+		if !slices.Contains(publishable, ws) {
+			fmt.Printf("skipping private workspace %s\n", ws.Name)
+		}
+	}
+
+	// Workspaces are published after the ones they depend on, so a consumer
+	// never sees a version requiring one that is not there yet
+	layers, err := registry.TopologicalSort(publishable)
+	if err != nil {
+		return err
+	}
+	for _, layer := range layers {
+		for _, ws := range layer {
+			if err := publishPackage(cmd, cfg, parser, registry, ws.Path, ws.PackageJSON); err != nil {
+				return fmt.Errorf("workspace %s: %w", ws.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// publishPackage packs the package in dir and uploads it, or only lists what
+// would be uploaded with --dry-run. Its workspace: dependencies are resolved
+// against registry, or the monorepo dir is in when registry is nil.
+func publishPackage(cmd *cobra.Command, cfg *config.Config, parser *packagejson.PackageJSONParser, registry *workspace.WorkspaceRegistry, dir string, pkg *packagejson.PackageJSON) error {
+	if err := pkg.CheckPublishable(); err != nil {
+		return err
+	}
+	if err := packagejson.ValidateName(pkg.Name); err != nil {
+		return err
+	}
+	version, ok := pkg.Version.(string)
+	if !ok || version == "" {
+		return fmt.Errorf("package.json has no version")
+	}
+
+	// The published manifest keeps every field of package.json, not only the
+	// ones PackageJSON knows, and is the package.json of the tarball
+	data, err := packedManifest(dir, parser, registry)
+	if err != nil {
+		return err
+	}
+	var manifest map[string]any
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return fmt.Errorf("failed to parse package.json: %w", err)
+	}
+
+	files, err := pack.Files(dir, pkg)
+	if err != nil {
+		return err
+	}
+	var tarball bytes.Buffer
+	if err := pack.Write(&tarball, dir, files, map[string][]byte{"package.json": data}); err != nil {
+		return err
+	}
+
+	publishConfig := pkg.GetPublishConfig()
+	opts := publish.Options{Tag: publishTagFlag, Access: publishAccessFlag}
+	if !cmd.Flags().Changed("tag") && publishConfig["tag"] != "" {
+		opts.Tag = publishConfig["tag"]
+	}
+	if opts.Access == "" {
+		opts.Access = publishConfig["access"]
+	}
+
+	registryURL := cfg.PrimaryRegistry()
+	if scope, _, ok := strings.Cut(pkg.Name, "/"); ok && cfg.ScopeRegistries[scope] != "" {
+		registryURL = cfg.ScopeRegistries[scope]
+	}
+	registryURL = pkg.PublishRegistry(registryURL)
+
+	document, err := publish.Document(registryURL, manifest, tarball.Bytes(), opts)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("package: %s@%s\n", pkg.Name, version)
+	for _, file := range files {
+		fmt.Printf("  %s\n", file)
+	}
+	fmt.Printf("total files: %d\n", len(files))
+	fmt.Printf("package size: %s\n", utils.FormatBytes(int64(tarball.Len())))
+
+	if publishDryRunFlag {
+		fmt.Printf("+ %s@%s (dry run, not published to %s with tag %s)\n", pkg.Name, version, registryURL, opts.Tag)
+		return nil
+	}
+
+	token, err := auth.New(cfg.AuthToken).Token(registryURL)
+	if errors.Is(err, auth.ErrNoToken) {
+		return fmt.Errorf("not logged in to %s: set %s, add an _authToken for it to .npmrc or run `go-npm config set auth-token <token>`", registryURL, auth.TokenEnv)
+	}
+	if err != nil {
+		return err
+	}
+
+	client := publish.New(registryURL, token, &http.Client{Timeout: cfg.HTTPTimeout})
+	if err := client.Publish(cmd.Context(), document); err != nil {
+		return err
+	}
+
+	fmt.Printf("+ %s@%s\n", pkg.Name, version)
+	return nil
+}
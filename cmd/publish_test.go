@@ -0,0 +1,202 @@
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/ernesto27/go-npm/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublishCLI(t *testing.T) {
+	projectRoot, err := filepath.Abs("..")
+	require.NoError(t, err)
+	binaryPath := utils.BuildTestBinary(t, projectRoot)
+
+	type upload struct {
+		Path     string
+		Document struct {
+			Name        string                    `json:"name"`
+			Access      string                    `json:"access"`
+			DistTags    map[string]string         `json:"dist-tags"`
+			Versions    map[string]map[string]any `json:"versions"`
+			Attachments map[string]struct {
+				Data   string `json:"data"`
+				Length int    `json:"length"`
+			} `json:"_attachments"`
+		}
+	}
+	var mu sync.Mutex
+	var uploads []upload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.Header.Get("Authorization") != "Bearer secret-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var u upload
+		u.Path = r.URL.EscapedPath()
+		if err := json.NewDecoder(r.Body).Decode(&u.Document); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		uploads = append(uploads, u)
+		mu.Unlock()
+		w.Write([]byte(`{"ok": true}`))
+	}))
+	defer server.Close()
+
+	testCases := []struct {
+		name          string
+		files         map[string]string
+		args          []string
+		expectError   bool
+		errorContains string
+		validate      func(t *testing.T, output string, uploads []upload)
+	}{
+		{
+			name: "uploads the packed tarball with access and tag",
+			files: map[string]string{
+				"package.json": fmt.Sprintf(`{"name": "@scope/lib", "version": "1.2.0", "files": ["index.js"], "publishConfig": {"access": "public", "registry": %q}}`, server.URL),
+				"index.js":     "module.exports = 1\n",
+				"src/index.ts": "export default 1\n",
+			},
+			args: []string{"--tag", "next"},
+			validate: func(t *testing.T, output string, uploads []upload) {
+				require.Len(t, uploads, 1)
+				document := uploads[0].Document
+				assert.Equal(t, "/@scope%2Flib", uploads[0].Path)
+				assert.Equal(t, "@scope/lib", document.Name)
+				assert.Equal(t, "public", document.Access)
+				assert.Equal(t, map[string]string{"next": "1.2.0"}, document.DistTags)
+				assert.Equal(t, "1.2.0", document.Versions["1.2.0"]["version"])
+
+				attachment, ok := document.Attachments["@scope/lib-1.2.0.tgz"]
+				require.True(t, ok, "missing tarball attachment")
+				data, err := base64.StdEncoding.DecodeString(attachment.Data)
+				require.NoError(t, err)
+				assert.Equal(t, len(data), attachment.Length)
+				assert.Equal(t, []string{"package/index.js", "package/package.json"}, tarEntries(t, data))
+				assert.Contains(t, output, "+ @scope/lib@1.2.0")
+			},
+		},
+		{
+			name: "uploads a scoped package to the registry of its scope",
+			files: map[string]string{
+				"package.json": `{"name": "@corp/lib", "version": "1.0.0"}`,
+			},
+			validate: func(t *testing.T, output string, uploads []upload) {
+				require.Len(t, uploads, 1)
+				assert.Equal(t, "/corp/@corp%2Flib", uploads[0].Path)
+			},
+		},
+		{
+			name: "dry run uploads nothing",
+			files: map[string]string{
+				"package.json": `{"name": "lib", "version": "1.0.0"}`,
+				"index.js":     "",
+			},
+			args: []string{"--dry-run"},
+			validate: func(t *testing.T, output string, uploads []upload) {
+				assert.Empty(t, uploads)
+				assert.Contains(t, output, "index.js")
+				assert.Contains(t, output, "dry run, not published to "+server.URL+"/ with tag latest")
+			},
+		},
+		{
+			name: "rejects an invalid access",
+			files: map[string]string{
+				"package.json": `{"name": "lib", "version": "1.0.0"}`,
+			},
+			args:          []string{"--access", "open"},
+			expectError:   true,
+			errorContains: `invalid --access "open"`,
+		},
+		{
+			name: "refuses a private package",
+			files: map[string]string{
+				"package.json": `{"name": "lib", "version": "1.0.0", "private": true}`,
+			},
+			expectError:   true,
+			errorContains: "cannot publish private package lib",
+		},
+		{
+			name: "publishes the workspaces after their dependencies skipping the private ones",
+			files: map[string]string{
+				"package.json":            `{"name": "root", "private": true, "workspaces": ["packages/*"]}`,
+				"packages/a/package.json": `{"name": "a", "version": "1.0.0", "dependencies": {"b": "workspace:~", "c": "workspace:^"}}`,
+				"packages/b/package.json": `{"name": "b", "version": "2.0.0", "private": true}`,
+				"packages/c/package.json": `{"name": "c", "version": "3.0.0"}`,
+			},
+			args: []string{"--workspaces"},
+			validate: func(t *testing.T, output string, uploads []upload) {
+				require.Len(t, uploads, 2)
+				assert.Equal(t, "c", uploads[0].Document.Name)
+				assert.Equal(t, "a", uploads[1].Document.Name)
+				assert.Equal(t, map[string]any{"b": "~2.0.0", "c": "^3.0.0"}, uploads[1].Document.Versions["1.0.0"]["dependencies"])
+				assert.Contains(t, output, "skipping private workspace b")
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mu.Lock()
+			uploads = nil
+			mu.Unlock()
+
+			testDir := t.TempDir()
+			for name, content := range tc.files {
+				filePath := filepath.Join(testDir, filepath.FromSlash(name))
+				require.NoError(t, os.MkdirAll(filepath.Dir(filePath), 0755))
+				require.NoError(t, os.WriteFile(filePath, []byte(content), 0644))
+			}
+			t.Setenv("GO_NPM_AUTH_TOKEN", "secret-token")
+			t.Setenv("GO_NPM_REGISTRIES", server.URL)
+			t.Setenv("GO_NPM_SCOPE_REGISTRIES", "@corp="+server.URL+"/corp/")
+
+			output, err, _ := utils.RunWithIsolatedCache(t, binaryPath, testDir, append([]string{"publish"}, tc.args...)...)
+			t.Logf("CLI output:\n%s", string(output))
+			if tc.expectError {
+				assert.Error(t, err)
+				assert.Contains(t, string(output), tc.errorContains)
+				return
+			}
+			require.NoError(t, err, "command failed with output: %s", string(output))
+
+			mu.Lock()
+			defer mu.Unlock()
+			tc.validate(t, string(output), uploads)
+		})
+	}
+}
+
+func tarEntries(t *testing.T, data []byte) []string {
+	t.Helper()
+	gzr, err := gzip.NewReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	tr := tar.NewReader(gzr)
+
+	var entries []string
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		entries = append(entries, header.Name)
+	}
+	return entries
+}
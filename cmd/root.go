@@ -1,10 +1,13 @@
 package cmd
 
 import (
+	"context"
 	_ "embed"
 	"encoding/json"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/spf13/cobra"
 )
@@ -24,6 +27,11 @@ func getVersion() string {
 	return versionInfo.Version
 }
 
+var (
+	logLevelFlag string
+	quietFlag    bool
+)
+
 var rootCmd = &cobra.Command{
 	Use:     "go-npm",
 	Short:   "A Go implementation of npm package manager",
@@ -31,13 +39,32 @@ var rootCmd = &cobra.Command{
 	Version: getVersion(),
 }
 
+// Execute runs the CLI. The first SIGINT or SIGTERM cancels the command's
+// context so an install can stop cleanly; a second one exits immediately.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		signal.Stop(signals)
+		fmt.Fprintln(os.Stderr, "\nInterrupted, cleaning up (press Ctrl-C again to exit now)...")
+		cancel()
+	}()
+
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
 		fmt.Fprintln(os.Stderr, err)
+		if ctx.Err() != nil {
+			os.Exit(130)
+		}
 		os.Exit(1)
 	}
 }
 
 func init() {
 	rootCmd.CompletionOptions.DisableDefaultCmd = true
+	rootCmd.PersistentFlags().StringVar(&logLevelFlag, "log-level", "info", "Log messages at or above this level: error, warn, info or debug")
+	rootCmd.PersistentFlags().BoolVar(&quietFlag, "quiet", false, "Only log warnings and errors")
 }
@@ -1,26 +1,38 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"sort"
+	"sync"
 
 	"github.com/ernesto27/go-npm/config"
 	"github.com/ernesto27/go-npm/packagejson"
 	"github.com/ernesto27/go-npm/scripts"
+	"github.com/ernesto27/go-npm/workspace"
 	"github.com/spf13/cobra"
 )
 
+var (
+	runWorkspaceFlags    []string
+	runAllWorkspacesFlag bool
+	runParallelFlag      bool
+)
+
 var runCmd = &cobra.Command{
 	Use:   "run <script>",
 	Short: "Run a script defined in package.json",
-	Long:  `Execute a script defined in the "scripts" section of package.json.`,
+	Long:  `Execute a script defined in the "scripts" section of package.json. With --workspace or --workspaces the script of each selected workspace package is run in its directory instead, after the workspaces it depends on.`,
 	Args:  cobra.ExactArgs(1),
 	RunE:  runScript,
 }
 
 func init() {
 	rootCmd.AddCommand(runCmd)
+	runCmd.Flags().StringArrayVarP(&runWorkspaceFlags, "workspace", "w", nil, "Run the script in the named workspace (repeatable, accepts globs like @org/* and workspace directories)")
+	runCmd.Flags().BoolVar(&runAllWorkspacesFlag, "workspaces", false, "Run the script in every workspace")
+	runCmd.Flags().BoolVar(&runParallelFlag, "parallel", false, "With --workspace or --workspaces, run workspaces that do not depend on each other in parallel")
 }
 
 func runScript(cmd *cobra.Command, args []string) error {
@@ -37,6 +49,10 @@ func runScript(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to parse package.json: %w", err)
 	}
 
+	if len(runWorkspaceFlags) > 0 || runAllWorkspacesFlag {
+		return runWorkspaceScripts(parser, pkgJSON, scriptName)
+	}
+
 	if len(pkgJSON.Scripts) == 0 {
 		return fmt.Errorf("no scripts defined in package.json")
 	}
@@ -70,6 +86,83 @@ func runScript(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// runWorkspaceScripts runs scriptName in the directory of each workspace
+// selected by --workspace or --workspaces, with the root node_modules/.bin
+// on the PATH. Workspaces run after the workspaces they depend on; with
+// --parallel the workspaces of one dependency layer run at the same time.
+// It stops after the first layer with a failing script.
+func runWorkspaceScripts(parser *packagejson.PackageJSONParser, pkgJSON *packagejson.PackageJSON, scriptName string) error {
+	if len(pkgJSON.GetWorkspaces()) == 0 {
+		return fmt.Errorf("no workspaces defined in package.json")
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+
+	registry := workspace.NewWorkspaceRegistry(cwd, parser)
+	if err := registry.Discover(pkgJSON); err != nil {
+		return fmt.Errorf("failed to discover workspaces: %w", err)
+	}
+
+	selected := registry.Sorted()
+	if len(runWorkspaceFlags) > 0 {
+		if selected, err = registry.Select(runWorkspaceFlags); err != nil {
+			return err
+		}
+	}
+
+	for _, ws := range selected {
+		if _, exists := ws.PackageJSON.Scripts[scriptName]; !exists {
+			return fmt.Errorf("script %q not found in workspace %s", scriptName, ws.Name)
+		}
+	}
+
+	layers, err := registry.TopologicalSort(selected)
+	if err != nil {
+		return err
+	}
+
+	executor := scripts.NewScriptExecutor(cwd + "/node_modules")
+	run := func(ws *workspace.Workspace) error {
+		fmt.Printf("\n> %s@%s %s\n", ws.Name, ws.Version, scriptName)
+
+		if err := executor.Execute(ws.PackageJSON.Scripts[scriptName], ws.Path, ws.Name, ws.Version, scriptName); err != nil {
+			return fmt.Errorf("workspace %s: %w", ws.Name, err)
+		}
+		return nil
+	}
+
+	for _, layer := range layers {
+		if !runParallelFlag {
+			for _, ws := range layer {
+				if err := run(ws); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		errs := make([]error, len(layer))
+		var wg sync.WaitGroup
+		for i, ws := range layer {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs[i] = run(ws)
+			}()
+		}
+		wg.Wait()
+
+		if err := errors.Join(errs...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func formatAvailableScripts(scripts map[string]string) string {
 	names := make([]string, 0, len(scripts))
 	for name := range scripts {
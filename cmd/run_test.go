@@ -3,6 +3,7 @@ package cmd
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/ernesto27/go-npm/utils"
@@ -97,6 +98,78 @@ func TestRunCLI(t *testing.T) {
 				assert.Contains(t, output, "accepts 1 arg")
 			},
 		},
+		{
+			name:        "--workspace runs the script in the workspace directory",
+			setupFunc:   writeRunWorkspaces,
+			args:        []string{"run", "where", "--workspace", "@org/api"},
+			expectError: false,
+			validate: func(t *testing.T, output string) {
+				assert.Contains(t, output, "> @org/api@1.0.0 where")
+				assert.Contains(t, output, "in api")
+				assert.NotContains(t, output, "in web")
+			},
+		},
+		{
+			name:        "--workspace accepts globs",
+			setupFunc:   writeRunWorkspaces,
+			args:        []string{"run", "where", "-w", "@org/*"},
+			expectError: false,
+			validate: func(t *testing.T, output string) {
+				assert.Contains(t, output, "in api")
+				assert.Contains(t, output, "in web")
+				assert.Less(t, strings.Index(output, "in api"), strings.Index(output, "in web"))
+			},
+		},
+		{
+			name:        "--workspaces fails when a workspace lacks the script",
+			setupFunc:   writeRunWorkspaces,
+			args:        []string{"run", "where", "--workspaces"},
+			expectError: true,
+			validate: func(t *testing.T, output string) {
+				assert.Contains(t, output, `script "where" not found in workspace docs`)
+				assert.NotContains(t, output, "in api")
+			},
+		},
+		{
+			name: "--workspaces builds dependencies first",
+			setupFunc: func(t *testing.T, testDir string) {
+				writeWorkspaceProject(t, testDir, map[string]string{
+					"app":  `{"name": "app", "version": "1.0.0", "dependencies": {"z-lib": "workspace:*"}, "scripts": {"build": "test -f ../lib/dist && echo app built"}}`,
+					"lib":  `{"name": "z-lib", "version": "1.0.0", "scripts": {"build": "touch dist && echo lib built"}}`,
+					"docs": `{"name": "docs", "version": "1.0.0", "scripts": {"build": "echo docs built"}}`,
+				})
+			},
+			args:        []string{"run", "build", "--workspaces", "--parallel"},
+			expectError: false,
+			validate: func(t *testing.T, output string) {
+				assert.Contains(t, output, "docs built")
+				assert.Contains(t, output, "app built")
+				assert.Less(t, strings.Index(output, "lib built"), strings.Index(output, "app built"))
+			},
+		},
+		{
+			name: "fails on a workspace dependency cycle",
+			setupFunc: func(t *testing.T, testDir string) {
+				writeWorkspaceProject(t, testDir, map[string]string{
+					"a": `{"name": "a", "version": "1.0.0", "dependencies": {"b": "workspace:*"}, "scripts": {"build": "echo a"}}`,
+					"b": `{"name": "b", "version": "1.0.0", "dependencies": {"a": "workspace:*"}, "scripts": {"build": "echo b"}}`,
+				})
+			},
+			args:        []string{"run", "build", "--workspaces"},
+			expectError: true,
+			validate: func(t *testing.T, output string) {
+				assert.Contains(t, output, "workspace dependency cycle: a -> b -> a")
+			},
+		},
+		{
+			name:        "fails for an unknown workspace",
+			setupFunc:   writeRunWorkspaces,
+			args:        []string{"run", "where", "--workspace", "@org/missing"},
+			expectError: true,
+			validate: func(t *testing.T, output string) {
+				assert.Contains(t, output, `no workspace matches "@org/missing"`)
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -121,3 +194,30 @@ func TestRunCLI(t *testing.T) {
 		})
 	}
 }
+
+// writeRunWorkspaces writes a project with three workspaces, two of which
+// print the name of the directory they run in
+func writeRunWorkspaces(t *testing.T, testDir string) {
+	t.Helper()
+
+	writeWorkspaceProject(t, testDir, map[string]string{
+		"api":  `{"name": "@org/api", "version": "1.0.0", "scripts": {"where": "echo in $(basename $PWD)"}}`,
+		"web":  `{"name": "@org/web", "version": "2.0.0", "scripts": {"where": "echo in $(basename $PWD)"}}`,
+		"docs": `{"name": "docs", "version": "1.0.0"}`,
+	})
+}
+
+// writeWorkspaceProject writes a root package.json with a packages/<dir>
+// workspace for each package.json in workspaces
+func writeWorkspaceProject(t *testing.T, testDir string, workspaces map[string]string) {
+	t.Helper()
+
+	for dir, packageJSON := range workspaces {
+		wsDir := filepath.Join(testDir, "packages", dir)
+		require.NoError(t, os.MkdirAll(wsDir, 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(wsDir, "package.json"), []byte(packageJSON), 0644))
+	}
+
+	rootPackageJSON := `{"name": "root", "version": "1.0.0", "workspaces": ["packages/*"]}`
+	require.NoError(t, os.WriteFile(filepath.Join(testDir, "package.json"), []byte(rootPackageJSON), 0644))
+}
@@ -8,7 +8,11 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var uninstallGlobalFlag bool
+var (
+	uninstallGlobalFlag bool
+	uninstallJSONFlag   bool
+	uninstallDryRunFlag bool
+)
 
 var uninstallCmd = &cobra.Command{
 	Use:   "uninstall <package>",
@@ -21,11 +25,18 @@ var uninstallCmd = &cobra.Command{
 func init() {
 	rootCmd.AddCommand(uninstallCmd)
 	uninstallCmd.Flags().BoolVarP(&uninstallGlobalFlag, "global", "g", false, "Uninstall package globally")
+	uninstallCmd.Flags().BoolVar(&uninstallJSONFlag, "json", false, "Print a JSON summary of the removed packages")
+	uninstallCmd.Flags().BoolVar(&uninstallDryRunFlag, "dry-run", false, "Print the packages that would be removed without changing any files")
+	uninstallCmd.MarkFlagsMutuallyExclusive("dry-run", "global")
 }
 
 func runUninstall(cmd *cobra.Command, args []string) error {
 	opts := types.BuildOptions{
-		Version: getVersion(),
+		Version:  getVersion(),
+		LogLevel: logLevelFlag,
+		Quiet:    quietFlag,
+		JSON:     uninstallJSONFlag,
+		DryRun:   uninstallDryRunFlag,
 	}
 	deps, err := manager.BuildDependencies(opts)
 	if err != nil {
@@ -36,6 +47,7 @@ func runUninstall(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("error creating package manager: %w", err)
 	}
+	defer packageManager.Close()
 
 	if uninstallGlobalFlag {
 		if err := packageManager.SetupGlobal(); err != nil {
@@ -51,6 +63,16 @@ func runUninstall(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	if uninstallDryRunFlag {
+		packageManager.FinishDryRun()
+		return nil
+	}
+
+	if uninstallJSONFlag {
+		packageManager.Finish()
+		return nil
+	}
+
 	fmt.Println("Package removed successfully")
 	return nil
 }
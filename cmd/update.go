@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/ernesto27/go-npm/manager"
+	"github.com/ernesto27/go-npm/types"
+	"github.com/spf13/cobra"
+)
+
+var updateLatestFlag bool
+
+var updateCmd = &cobra.Command{
+	Use:     "update [package...]",
+	Aliases: []string{"up", "upgrade"},
+	Short:   "Update packages to the newest version in their range",
+	Long:    `Update the named dependencies, or all of them, to the newest version satisfying their package.json range and rewrite go-npm-lock.json and node_modules. package.json is left untouched unless --latest widens the ranges to the latest published version.`,
+	RunE:    runUpdate,
+}
+
+func init() {
+	rootCmd.AddCommand(updateCmd)
+	updateCmd.Flags().BoolVar(&updateLatestFlag, "latest", false, "Widen package.json ranges to the version tagged latest, across majors")
+}
+
+func runUpdate(cmd *cobra.Command, args []string) error {
+	opts := types.BuildOptions{
+		Version:  getVersion(),
+		LogLevel: logLevelFlag,
+		Quiet:    quietFlag,
+	}
+	deps, err := manager.BuildDependencies(opts)
+	if err != nil {
+		return fmt.Errorf("error building dependencies: %w", err)
+	}
+
+	packageManager, err := manager.New(deps)
+	if err != nil {
+		return fmt.Errorf("error creating package manager: %w", err)
+	}
+	packageManager.SetContext(cmd.Context())
+
+	updates, err := packageManager.Update(args, updateLatestFlag)
+	if err != nil {
+		return fmt.Errorf("error updating packages: %w", err)
+	}
+
+	manager.PrintUpdates(updates)
+	return nil
+}
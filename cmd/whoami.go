@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/ernesto27/go-npm/auth"
+	"github.com/ernesto27/go-npm/config"
+	"github.com/ernesto27/go-npm/whoami"
+	"github.com/spf13/cobra"
+)
+
+var whoamiRegistryFlag string
+
+var whoamiCmd = &cobra.Command{
+	Use:   "whoami",
+	Short: "Print the username of the configured registry auth token",
+	Long:  `Ask the registry which user the configured auth token belongs to, to check that authentication works before publishing.`,
+	Args:  cobra.NoArgs,
+	RunE:  runWhoami,
+}
+
+func init() {
+	rootCmd.AddCommand(whoamiCmd)
+	whoamiCmd.Flags().StringVar(&whoamiRegistryFlag, "registry", "", "Registry to ask (default the configured registry)")
+}
+
+func runWhoami(cmd *cobra.Command, args []string) error {
+	cfg, err := config.New()
+	if err != nil {
+		return fmt.Errorf("failed to create config: %w", err)
+	}
+
+	registryURL := whoamiRegistryFlag
+	if registryURL == "" {
+		registryURL = cfg.PrimaryRegistry()
+	}
+
+	token, err := auth.New(cfg.AuthToken).Token(registryURL)
+	if errors.Is(err, auth.ErrNoToken) {
+		return fmt.Errorf("not logged in to %s: set %s, add an _authToken for it to .npmrc or run `go-npm config set auth-token <token>`", registryURL, auth.TokenEnv)
+	}
+	if err != nil {
+		return err
+	}
+
+	client := whoami.New(registryURL, token, &http.Client{Timeout: cfg.HTTPTimeout})
+	username, err := client.Username(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(username)
+	return nil
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ernesto27/go-npm/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWhoamiCLI(t *testing.T) {
+	projectRoot, err := filepath.Abs("..")
+	require.NoError(t, err)
+	binaryPath := utils.BuildTestBinary(t, projectRoot)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/-/whoami" || r.Header.Get("Authorization") != "Bearer secret-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"username": "octocat"}`))
+	}))
+	defer server.Close()
+
+	testCases := []struct {
+		name        string
+		envToken    string
+		npmrc       string
+		configured  bool
+		expectError bool
+		validate    func(t *testing.T, output string)
+	}{
+		{
+			name:     "prints the username of the token from the environment",
+			envToken: "secret-token",
+			validate: func(t *testing.T, output string) {
+				assert.Equal(t, "octocat", strings.TrimSpace(output))
+			},
+		},
+		{
+			name:  "prints the username of the token from .npmrc",
+			npmrc: "//" + strings.TrimPrefix(server.URL, "http://") + "/:_authToken=secret-token\n",
+			validate: func(t *testing.T, output string) {
+				assert.Equal(t, "octocat", strings.TrimSpace(output))
+			},
+		},
+		{
+			name:       "asks the configured registry without --registry",
+			envToken:   "secret-token",
+			configured: true,
+			validate: func(t *testing.T, output string) {
+				assert.Equal(t, "octocat", strings.TrimSpace(output))
+			},
+		},
+		{
+			name:        "fails clearly without auth",
+			expectError: true,
+			validate: func(t *testing.T, output string) {
+				assert.Contains(t, output, "not logged in to "+server.URL)
+				assert.Contains(t, output, "GO_NPM_AUTH_TOKEN")
+			},
+		},
+		{
+			name:        "fails when the registry rejects the token",
+			envToken:    "wrong-token",
+			expectError: true,
+			validate: func(t *testing.T, output string) {
+				assert.Contains(t, output, "rejected the auth token")
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			testDir := t.TempDir()
+			t.Setenv("GO_NPM_AUTH_TOKEN", tc.envToken)
+			if tc.npmrc != "" {
+				require.NoError(t, os.WriteFile(filepath.Join(testDir, ".npmrc"), []byte(tc.npmrc), 0644))
+			}
+
+			args := []string{"whoami", "--registry", server.URL}
+			if tc.configured {
+				t.Setenv("GO_NPM_REGISTRIES", server.URL)
+				args = []string{"whoami"}
+			}
+
+			output, err, _ := utils.RunWithIsolatedCache(t, binaryPath, testDir, args...)
+			t.Logf("CLI output:\n%s", string(output))
+
+			if tc.expectError {
+				assert.Error(t, err)
+			} else {
+				require.NoError(t, err, "command failed with output: %s", string(output))
+			}
+			tc.validate(t, string(output))
+		})
+	}
+}
@@ -2,11 +2,36 @@ package config
 
 import (
 	"fmt"
+	"math"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ernesto27/go-npm/manifest"
+	"github.com/ernesto27/go-npm/utils"
 )
 
-const NPMRegistryURL = "https://registry.npmjs.org/"
+const (
+	NPMRegistryURL      = "https://registry.npmjs.org/"
+	DefaultFetchRetries = utils.DefaultRetries
+	DefaultHTTPTimeout  = 30 * time.Second
+
+	// DefaultManifestMaxAge is how long a cached manifest is trusted before
+	// it is revalidated with the registry
+	DefaultManifestMaxAge = 5 * time.Minute
+)
+
+// Install strategies: how cached packages are placed in node_modules
+const (
+	InstallStrategyCopy     = "copy"     // copy every file
+	InstallStrategyHardlink = "hardlink" // hardlink every file, copying across filesystems
+	InstallStrategySymlink  = "symlink"  // symlink the whole package directory
+)
 
 type Config struct {
 	// Base directories
@@ -14,6 +39,7 @@ type Config struct {
 	ManifestDir string
 	TarballDir  string
 	PackagesDir string
+	StoreDir    string
 
 	// Local installation paths
 	LocalNodeModules string
@@ -25,17 +51,157 @@ type Config struct {
 	GlobalBinDir      string
 	GlobalPackageJSON string
 	GlobalLockFile    string
+
+	// Network settings
+	FetchRetries int
+	Concurrency  int
+
+	// RateLimit caps manifest and tarball requests per second to each
+	// registry host, across all workers; zero disables it
+	RateLimit float64
+
+	// Registries are the registries manifests and tarballs are downloaded
+	// from, primary first. The others are fallbacks, tried in order when a
+	// registry cannot be reached or does not have a package.
+	Registries []string
+
+	// ScopeRegistries routes the packages of a scope ("@myorg") to their own
+	// registry instead of Registries, with no fallback
+	ScopeRegistries map[string]string
+
+	// HTTPTimeout bounds each manifest and tarball request; zero disables it
+	HTTPTimeout time.Duration
+
+	// ManifestMaxAge is how long a cached manifest is used as is. Past it,
+	// the manifest is revalidated with If-None-Match, so newly published
+	// versions are seen; zero revalidates on every install.
+	ManifestMaxAge time.Duration
+
+	// InstallStrategy is one of the InstallStrategy* constants
+	InstallStrategy string
+
+	// InstallLinks copies workspace and file: dependencies into node_modules
+	// instead of symlinking them. PackLinks copies only the files npm would
+	// publish, per their files list and .npmignore.
+	InstallLinks bool
+	PackLinks    bool
+
+	// EngineStrict fails the install when a package's engines.node range
+	// does not match the installed node, instead of only warning
+	EngineStrict bool
+
+	// Omit holds the dependency groups left out of the lock and
+	// node_modules: dev with --production, optional with --no-optional,
+	// peer with --no-peer, or any of them with --omit
+	Omit OmitSet
+
+	// Force installs packages whose os or cpu constraints exclude the
+	// current platform instead of failing with EBADPLATFORM
+	Force bool
+
+	// NoBinLinks skips linking package executables into node_modules/.bin
+	NoBinLinks bool
+
+	// Timing records how long each package spends fetching its manifest,
+	// downloading, extracting and copying, and prints the slowest packages
+	// and the phase totals at the end of the install
+	Timing bool
+
+	// VerifySignatures checks each registry package's dist.signatures against
+	// the registry's public keys and fails the install on an invalid one
+	VerifySignatures bool
+
+	// AtomicInstall stages the whole node_modules tree in node_modules.tmp and
+	// swaps it into place only once it is complete
+	AtomicInstall bool
+
+	// ReportConflicts prints the packages whose requested ranges no single
+	// version satisfies after resolving dependencies
+	ReportConflicts bool
+
+	// ForceResolutions installs a single version of each conflicting package,
+	// the highest one satisfying the most ranges, and warns about the rest
+	ForceResolutions bool
+
+	// Workspaces limits the install to the workspace packages matching these
+	// names or globs, and AllWorkspaces to every workspace package, skipping
+	// the root dependencies
+	Workspaces    []string
+	AllWorkspaces bool
+
+	// Offline resolves and installs from the cache only, failing on a miss.
+	// PreferOffline uses the cache first and the network only on a miss.
+	Offline       bool
+	PreferOffline bool
+
+	// LocalTarballDir is a directory of <name>-<version>.tgz files that
+	// registry packages are resolved from and copied out of instead of the
+	// registry, for machines without one
+	LocalTarballDir string
+
+	// OmitLockfileRegistry writes registry tarball URLs to the lock file
+	// against the public registry and leaves out the registries the lock was
+	// resolved against, so the lock does not depend on a private mirror
+	OmitLockfileRegistry bool
+
+	// CacheLock takes a file lock per package@version while it is extracted
+	// into the cache, so concurrent go-npm processes sharing a cache do not
+	// write the same entry at once
+	CacheLock bool
+
+	// IgnoreOptional resolves and locks optional dependencies, so other
+	// platforms can install them from the lock, but does not install them.
+	// Omit.Optional leaves them out of the lock too.
+	IgnoreOptional bool
+
+	// ResolutionOnly resolves the dependencies into the cache and lock file
+	// without creating or changing node_modules
+	ResolutionOnly bool
+
+	// DryRun resolves the dependencies against a scratch cache and reports
+	// what would change, without writing node_modules, the cache,
+	// package.json or the lock file
+	DryRun bool
+
+	// NoSave installs into node_modules without writing package.json or the
+	// lock file, which are only updated in memory
+	NoSave bool
+
+	// ContentStore keeps extracted packages under StoreDir keyed by their
+	// sha512 integrity, with the name@version directories in PackagesDir
+	// becoming links to them, so identical tarballs are stored once
+	ContentStore bool
+
+	// ManifestFetchMode is manifest.FetchFull or manifest.FetchAbbreviated
+	ManifestFetchMode manifest.FetchMode
+
+	// ScriptsAllow and ScriptsDeny are package name globs whose lifecycle
+	// scripts always, or never, run regardless of trustedDependencies. Deny
+	// wins over allow.
+	ScriptsAllow []string
+	ScriptsDeny  []string
+
+	// AuthToken is the auth-token setting, the registry token used when
+	// neither GO_NPM_AUTH_TOKEN nor an .npmrc has one
+	AuthToken string
 }
 
+// New builds the configuration from the defaults, the config file saved by
+// `go-npm config set` and the GO_NPM_* environment variables, which win over
+// the file. Commands apply their flags to the result.
 func New() (*Config, error) {
-	// Allow overriding base directory via environment variable (useful for testing)
-	baseDir := os.Getenv("GO_NPM_HOME")
+	file, err := LoadFile()
+	if err != nil {
+		return nil, err
+	}
+
+	// Allow overriding base directory via environment variable (useful for
+	// testing) or the cache-dir setting; the config file stays in Home
+	baseDir := file.lookup("GO_NPM_HOME")
 	if baseDir == "" {
-		homeDir, err := os.UserHomeDir()
-		if err != nil {
+		if baseDir, err = Home(); err != nil {
 			return nil, err
 		}
-		baseDir = filepath.Join(homeDir, ".config", "go-npm")
 	}
 	globalDir := filepath.Join(baseDir, "global")
 
@@ -44,6 +210,7 @@ func New() (*Config, error) {
 		ManifestDir: filepath.Join(baseDir, "manifest"),
 		TarballDir:  filepath.Join(baseDir, "tarball"),
 		PackagesDir: filepath.Join(baseDir, "packages"),
+		StoreDir:    filepath.Join(baseDir, "store"),
 
 		LocalNodeModules: "./node_modules",
 		LocalBinDir:      "./node_modules/.bin",
@@ -53,6 +220,21 @@ func New() (*Config, error) {
 		GlobalBinDir:      filepath.Join(globalDir, "bin"),
 		GlobalPackageJSON: filepath.Join(globalDir, "package.json"),
 		GlobalLockFile:    filepath.Join(globalDir, "go-package-lock.json"),
+
+		Registries:      []string{NPMRegistryURL},
+		FetchRetries:    DefaultFetchRetries,
+		HTTPTimeout:     DefaultHTTPTimeout,
+		ManifestMaxAge:  DefaultManifestMaxAge,
+		Concurrency:     runtime.NumCPU() * 4,
+		InstallStrategy: InstallStrategyHardlink,
+
+		ManifestFetchMode: manifest.FetchFull,
+
+		AuthToken: file.lookup("GO_NPM_AUTH_TOKEN"),
+	}
+
+	if err := cfg.applySettings(file.lookup); err != nil {
+		return nil, err
 	}
 
 	if err := cfg.EnsureDirectories(); err != nil {
@@ -62,12 +244,281 @@ func New() (*Config, error) {
 	return cfg, nil
 }
 
+// applySettings reads the settings that are parsed and validated from
+// lookup, which returns an environment variable or its config file value
+func (c *Config) applySettings(lookup func(env string) string) error {
+	// A comma-separated list of registries, primary first
+	if list := lookup("GO_NPM_REGISTRIES"); list != "" {
+		registries, err := ParseRegistries(list)
+		if err != nil {
+			return fmt.Errorf("invalid GO_NPM_REGISTRIES: %w", err)
+		}
+		c.Registries = registries
+	}
+
+	// A comma-separated list of @scope=registry pairs
+	if list := lookup("GO_NPM_SCOPE_REGISTRIES"); list != "" {
+		scopeRegistries, err := ParseScopeRegistries(list)
+		if err != nil {
+			return fmt.Errorf("invalid GO_NPM_SCOPE_REGISTRIES: %w", err)
+		}
+		c.ScopeRegistries = scopeRegistries
+	}
+
+	// Allow tuning the number of download retries (e.g. in CI)
+	if retries := lookup("GO_NPM_FETCH_RETRIES"); retries != "" {
+		n, err := strconv.Atoi(retries)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid GO_NPM_FETCH_RETRIES value %q", retries)
+		}
+		c.FetchRetries = n
+	}
+
+	// Accepts a Go duration ("45s", "2m") or a number of seconds
+	if timeout := lookup("GO_NPM_HTTP_TIMEOUT"); timeout != "" {
+		d, err := ParseTimeout(timeout)
+		if err != nil {
+			return fmt.Errorf("invalid GO_NPM_HTTP_TIMEOUT value %q", timeout)
+		}
+		c.HTTPTimeout = d
+	}
+
+	// Accepts a Go duration ("1h") or a number of seconds
+	if maxAge := lookup("GO_NPM_MANIFEST_MAX_AGE"); maxAge != "" {
+		d, err := ParseTimeout(maxAge)
+		if err != nil {
+			return fmt.Errorf("invalid GO_NPM_MANIFEST_MAX_AGE value %q", maxAge)
+		}
+		c.ManifestMaxAge = d
+	}
+
+	// Requests per second, e.g. "10" or "0.5"
+	if rate := lookup("GO_NPM_RATE_LIMIT"); rate != "" {
+		n, err := strconv.ParseFloat(rate, 64)
+		if err != nil || n < 0 || math.IsInf(n, 0) || math.IsNaN(n) {
+			return fmt.Errorf("invalid GO_NPM_RATE_LIMIT value %q", rate)
+		}
+		c.RateLimit = n
+	}
+
+	if dir := lookup("GO_NPM_LOCAL_TARBALL_DIR"); dir != "" {
+		info, err := os.Stat(dir)
+		if err != nil || !info.IsDir() {
+			return fmt.Errorf("invalid GO_NPM_LOCAL_TARBALL_DIR: %s is not a directory", dir)
+		}
+		c.LocalTarballDir = dir
+	}
+
+	if concurrency := lookup("GO_NPM_CONCURRENCY"); concurrency != "" {
+		n, err := strconv.Atoi(concurrency)
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid GO_NPM_CONCURRENCY value %q", concurrency)
+		}
+		c.Concurrency = n
+	}
+
+	if strategy := lookup("GO_NPM_INSTALL_STRATEGY"); strategy != "" {
+		if err := ValidateInstallStrategy(strategy); err != nil {
+			return fmt.Errorf("invalid GO_NPM_INSTALL_STRATEGY: %w", err)
+		}
+		c.InstallStrategy = strategy
+	}
+
+	if mode := lookup("GO_NPM_MANIFEST_FETCH_MODE"); mode != "" {
+		fetchMode, err := manifest.ParseFetchMode(mode)
+		if err != nil {
+			return fmt.Errorf("invalid GO_NPM_MANIFEST_FETCH_MODE: %w", err)
+		}
+		c.ManifestFetchMode = fetchMode
+	}
+
+	// Opt in to the content-addressed store while the name@version layout
+	// remains the default
+	if store := lookup("GO_NPM_CONTENT_STORE"); store != "" {
+		enabled, err := strconv.ParseBool(store)
+		if err != nil {
+			return fmt.Errorf("invalid GO_NPM_CONTENT_STORE value %q", store)
+		}
+		c.ContentStore = enabled
+	}
+
+	allow, err := ParsePackagePatterns(lookup("GO_NPM_SCRIPTS_ALLOW"))
+	if err != nil {
+		return fmt.Errorf("invalid GO_NPM_SCRIPTS_ALLOW: %w", err)
+	}
+	c.ScriptsAllow = allow
+
+	deny, err := ParsePackagePatterns(lookup("GO_NPM_SCRIPTS_DENY"))
+	if err != nil {
+		return fmt.Errorf("invalid GO_NPM_SCRIPTS_DENY: %w", err)
+	}
+	c.ScriptsDeny = deny
+
+	return nil
+}
+
+// ParsePackagePatterns splits a comma-separated list of package name globs,
+// like "esbuild,@org/*", checking that each one is a valid pattern
+func ParsePackagePatterns(list string) ([]string, error) {
+	var patterns []string
+	for _, pattern := range strings.Split(list, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid package pattern %q: %w", pattern, err)
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+// Dependency groups that --omit leaves out of an install
+const (
+	OmitDev      = "dev"
+	OmitOptional = "optional"
+	OmitPeer     = "peer"
+)
+
+// OmitSet holds the dependency groups left out of an install. A group is
+// left out with everything only reachable from it.
+type OmitSet struct {
+	Dev      bool
+	Optional bool
+	Peer     bool
+}
+
+// ParseOmit parses --omit values, each a group or a comma-separated list of
+// groups like "dev,optional"
+func ParseOmit(values []string) (OmitSet, error) {
+	var omit OmitSet
+	for _, value := range values {
+		for _, group := range strings.Split(value, ",") {
+			switch strings.TrimSpace(group) {
+			case OmitDev:
+				omit.Dev = true
+			case OmitOptional:
+				omit.Optional = true
+			case OmitPeer:
+				omit.Peer = true
+			case "":
+			default:
+				return OmitSet{}, fmt.Errorf("unknown dependency group %q (expected %s, %s or %s)", group, OmitDev, OmitOptional, OmitPeer)
+			}
+		}
+	}
+	return omit, nil
+}
+
+// ParseRegistries splits a comma-separated list of registry URLs, checking
+// that each one is an http or https URL and ending each with a slash
+func ParseRegistries(list string) ([]string, error) {
+	var registries []string
+	for _, registry := range strings.Split(list, ",") {
+		registry = strings.TrimSpace(registry)
+		if registry == "" {
+			continue
+		}
+		registry, err := parseRegistryURL(registry)
+		if err != nil {
+			return nil, err
+		}
+		registries = append(registries, registry)
+	}
+	if len(registries) == 0 {
+		return nil, fmt.Errorf("no registry URL in %q", list)
+	}
+	return registries, nil
+}
+
+// ParseScopeRegistries parses a comma-separated list of @scope=registry
+// pairs, like "@myorg=https://npm.internal/", into a map from scope to
+// registry URL
+func ParseScopeRegistries(list string) (map[string]string, error) {
+	scopeRegistries := make(map[string]string)
+	for _, pair := range strings.Split(list, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		scope, registry, ok := strings.Cut(pair, "=")
+		scope = strings.TrimSpace(scope)
+		if !ok || !strings.HasPrefix(scope, "@") || len(scope) == 1 || strings.Contains(scope, "/") {
+			return nil, fmt.Errorf("invalid scope registry %q (expected @scope=url)", pair)
+		}
+		registry, err := parseRegistryURL(strings.TrimSpace(registry))
+		if err != nil {
+			return nil, err
+		}
+		scopeRegistries[scope] = registry
+	}
+	return scopeRegistries, nil
+}
+
+// parseRegistryURL checks that registry is an http or https URL and ends it
+// with a slash
+func parseRegistryURL(registry string) (string, error) {
+	u, err := url.Parse(registry)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("invalid registry URL %q", registry)
+	}
+	return strings.TrimSuffix(registry, "/") + "/", nil
+}
+
+// PrimaryRegistry returns the first of Registries, the public registry when
+// none is set
+func (c *Config) PrimaryRegistry() string {
+	if len(c.Registries) == 0 {
+		return NPMRegistryURL
+	}
+	return c.Registries[0]
+}
+
+// FallbackRegistries returns the registries tried after the primary one
+func (c *Config) FallbackRegistries() []string {
+	if len(c.Registries) < 2 {
+		return nil
+	}
+	return c.Registries[1:]
+}
+
+// ParseTimeout parses a timeout given as a Go duration or as a plain number of
+// seconds. Zero disables the timeout.
+func ParseTimeout(value string) (time.Duration, error) {
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, fmt.Errorf("negative timeout %q", value)
+		}
+		return time.Duration(seconds) * time.Second, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("negative timeout %q", value)
+	}
+	return d, nil
+}
+
+// ValidateInstallStrategy checks that strategy is copy, hardlink or symlink
+func ValidateInstallStrategy(strategy string) error {
+	switch strategy {
+	case InstallStrategyCopy, InstallStrategyHardlink, InstallStrategySymlink:
+		return nil
+	}
+	return fmt.Errorf("unknown install strategy %q (expected copy, hardlink or symlink)", strategy)
+}
+
 func (c *Config) EnsureDirectories() error {
 	dirs := []string{
 		c.BaseDir,
 		c.ManifestDir,
 		c.TarballDir,
 		c.PackagesDir,
+		c.StoreDir,
 		c.GlobalDir,
 
 		filepath.Join(c.BaseDir, "etag"),
@@ -86,8 +537,10 @@ func (c *Config) ClearCache() error {
 	cacheDirs := []string{
 		c.ManifestDir,
 		c.PackagesDir,
+		c.StoreDir,
 		c.TarballDir,
 		filepath.Join(c.BaseDir, "etag"),
+		filepath.Join(c.BaseDir, "resume"),
 	}
 
 	for _, dir := range cacheDirs {
@@ -100,3 +553,48 @@ func (c *Config) ClearCache() error {
 
 	return nil
 }
+
+// Shells whose startup file the PATH line is written to
+const (
+	ShellBash = "bash"
+	ShellZsh  = "zsh"
+	ShellFish = "fish"
+)
+
+// CurrentShell returns the user's shell from $SHELL, falling back to bash
+// for any shell other than zsh and fish
+func CurrentShell() string {
+	switch filepath.Base(os.Getenv("SHELL")) {
+	case ShellZsh:
+		return ShellZsh
+	case ShellFish:
+		return ShellFish
+	}
+	return ShellBash
+}
+
+// GlobalBinExportLine returns the shell line that puts GlobalBinDir on PATH,
+// in fish syntax for fish users
+func (c *Config) GlobalBinExportLine() string {
+	if CurrentShell() == ShellFish {
+		return fmt.Sprintf("set -gx PATH \"%s\" $PATH", c.GlobalBinDir)
+	}
+	return fmt.Sprintf("export PATH=\"%s:$PATH\"", c.GlobalBinDir)
+}
+
+// ShellRCFile returns the startup file of the user's shell, where the PATH
+// line is written: ~/.zshrc, ~/.config/fish/config.fish or ~/.bashrc
+func ShellRCFile() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+
+	switch CurrentShell() {
+	case ShellZsh:
+		return filepath.Join(homeDir, ".zshrc"), nil
+	case ShellFish:
+		return filepath.Join(homeDir, ".config", "fish", "config.fish"), nil
+	}
+	return filepath.Join(homeDir, ".bashrc"), nil
+}
@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -157,3 +158,568 @@ func TestNew(t *testing.T) {
 	assert.Contains(t, cfg.PackagesDir, "packages", "PackagesDir should contain packages")
 	assert.Contains(t, cfg.GlobalDir, "global", "GlobalDir should contain global")
 }
+
+func TestNew_FetchRetries(t *testing.T) {
+	testCases := []struct {
+		name        string
+		envValue    string
+		expectError bool
+		expected    int
+	}{
+		{
+			name:     "Defaults when env var is unset",
+			envValue: "",
+			expected: DefaultFetchRetries,
+		},
+		{
+			name:     "Reads retries from env var",
+			envValue: "5",
+			expected: 5,
+		},
+		{
+			name:        "Rejects invalid value",
+			envValue:    "many",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("GO_NPM_HOME", t.TempDir())
+			t.Setenv("GO_NPM_FETCH_RETRIES", tc.envValue)
+
+			cfg, err := New()
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, cfg.FetchRetries)
+		})
+	}
+}
+
+func TestNew_HTTPTimeout(t *testing.T) {
+	testCases := []struct {
+		name        string
+		envValue    string
+		expectError bool
+		expected    time.Duration
+	}{
+		{
+			name:     "Defaults when env var is unset",
+			envValue: "",
+			expected: DefaultHTTPTimeout,
+		},
+		{
+			name:     "Reads seconds from env var",
+			envValue: "5",
+			expected: 5 * time.Second,
+		},
+		{
+			name:     "Reads duration from env var",
+			envValue: "2m",
+			expected: 2 * time.Minute,
+		},
+		{
+			name:     "Zero disables the timeout",
+			envValue: "0",
+			expected: 0,
+		},
+		{
+			name:        "Rejects negative value",
+			envValue:    "-5s",
+			expectError: true,
+		},
+		{
+			name:        "Rejects invalid value",
+			envValue:    "soon",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("GO_NPM_HOME", t.TempDir())
+			t.Setenv("GO_NPM_HTTP_TIMEOUT", tc.envValue)
+
+			cfg, err := New()
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, cfg.HTTPTimeout)
+		})
+	}
+}
+
+func TestNew_ContentStore(t *testing.T) {
+	testCases := []struct {
+		name        string
+		envValue    string
+		expectError bool
+		expected    bool
+	}{
+		{
+			name:     "Legacy layout when env var is unset",
+			envValue: "",
+			expected: false,
+		},
+		{
+			name:     "Enables the store from env var",
+			envValue: "1",
+			expected: true,
+		},
+		{
+			name:     "Disables the store from env var",
+			envValue: "false",
+			expected: false,
+		},
+		{
+			name:        "Rejects invalid value",
+			envValue:    "sometimes",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			home := t.TempDir()
+			t.Setenv("GO_NPM_HOME", home)
+			t.Setenv("GO_NPM_CONTENT_STORE", tc.envValue)
+
+			cfg, err := New()
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, cfg.ContentStore)
+			assert.DirExists(t, filepath.Join(home, "store"))
+		})
+	}
+}
+
+func TestNew_InstallStrategy(t *testing.T) {
+	testCases := []struct {
+		name        string
+		envValue    string
+		expectError bool
+		expected    string
+	}{
+		{
+			name:     "Defaults to hardlink",
+			envValue: "",
+			expected: InstallStrategyHardlink,
+		},
+		{
+			name:     "Reads strategy from env var",
+			envValue: "symlink",
+			expected: InstallStrategySymlink,
+		},
+		{
+			name:        "Rejects unknown strategy",
+			envValue:    "reflink",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("GO_NPM_HOME", t.TempDir())
+			t.Setenv("GO_NPM_INSTALL_STRATEGY", tc.envValue)
+
+			cfg, err := New()
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, cfg.InstallStrategy)
+		})
+	}
+}
+
+func TestNew_ScriptsPolicy(t *testing.T) {
+	testCases := []struct {
+		name          string
+		allow         string
+		deny          string
+		expectError   bool
+		expectedAllow []string
+		expectedDeny  []string
+	}{
+		{
+			name: "Defaults to no policy",
+		},
+		{
+			name:          "Reads comma-separated globs",
+			allow:         "esbuild, @org/*",
+			deny:          "evil-*,",
+			expectedAllow: []string{"esbuild", "@org/*"},
+			expectedDeny:  []string{"evil-*"},
+		},
+		{
+			name:        "Rejects a malformed glob",
+			deny:        "evil-[",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("GO_NPM_HOME", t.TempDir())
+			t.Setenv("GO_NPM_SCRIPTS_ALLOW", tc.allow)
+			t.Setenv("GO_NPM_SCRIPTS_DENY", tc.deny)
+
+			cfg, err := New()
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedAllow, cfg.ScriptsAllow)
+			assert.Equal(t, tc.expectedDeny, cfg.ScriptsDeny)
+		})
+	}
+}
+
+func TestShellRCFile(t *testing.T) {
+	testCases := []struct {
+		name       string
+		shell      string
+		rcFile     string
+		exportLine string
+	}{
+		{
+			name:       "bash",
+			shell:      "/bin/bash",
+			rcFile:     ".bashrc",
+			exportLine: `export PATH="/opt/go-npm/bin:$PATH"`,
+		},
+		{
+			name:       "zsh",
+			shell:      "/usr/bin/zsh",
+			rcFile:     ".zshrc",
+			exportLine: `export PATH="/opt/go-npm/bin:$PATH"`,
+		},
+		{
+			name:       "fish",
+			shell:      "/usr/local/bin/fish",
+			rcFile:     filepath.Join(".config", "fish", "config.fish"),
+			exportLine: `set -gx PATH "/opt/go-npm/bin" $PATH`,
+		},
+		{
+			name:       "unset shell falls back to bash",
+			shell:      "",
+			rcFile:     ".bashrc",
+			exportLine: `export PATH="/opt/go-npm/bin:$PATH"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+			t.Setenv("SHELL", tc.shell)
+
+			rcFile, err := ShellRCFile()
+			assert.NoError(t, err)
+			assert.Equal(t, filepath.Join(home, tc.rcFile), rcFile)
+
+			cfg := &Config{GlobalBinDir: "/opt/go-npm/bin"}
+			assert.Equal(t, tc.exportLine, cfg.GlobalBinExportLine())
+		})
+	}
+}
+
+func TestNew_Registries(t *testing.T) {
+	testCases := []struct {
+		name             string
+		envValue         string
+		expectError      bool
+		expected         []string
+		expectedPrimary  string
+		expectedFallback []string
+	}{
+		{
+			name:            "Defaults to the public registry",
+			expected:        []string{NPMRegistryURL},
+			expectedPrimary: NPMRegistryURL,
+		},
+		{
+			name:             "Reads primary and fallbacks in order",
+			envValue:         "https://npm.example.com/repository/npm, https://registry.npmjs.org/",
+			expected:         []string{"https://npm.example.com/repository/npm/", NPMRegistryURL},
+			expectedPrimary:  "https://npm.example.com/repository/npm/",
+			expectedFallback: []string{NPMRegistryURL},
+		},
+		{
+			name:        "Rejects a URL without a scheme",
+			envValue:    "npm.example.com",
+			expectError: true,
+		},
+		{
+			name:        "Rejects an empty list",
+			envValue:    " , ",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("GO_NPM_HOME", t.TempDir())
+			t.Setenv("GO_NPM_REGISTRIES", tc.envValue)
+
+			cfg, err := New()
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, cfg.Registries)
+			assert.Equal(t, tc.expectedPrimary, cfg.PrimaryRegistry())
+			assert.Equal(t, tc.expectedFallback, cfg.FallbackRegistries())
+		})
+	}
+}
+
+func TestNew_ScopeRegistries(t *testing.T) {
+	testCases := []struct {
+		name        string
+		envValue    string
+		expectError bool
+		expected    map[string]string
+	}{
+		{
+			name: "Defaults to no scope registries",
+		},
+		{
+			name:     "Reads scope and registry pairs",
+			envValue: "@myorg=https://npm.internal, @other=http://localhost:4873/",
+			expected: map[string]string{"@myorg": "https://npm.internal/", "@other": "http://localhost:4873/"},
+		},
+		{
+			name:        "Rejects a scope without @",
+			envValue:    "myorg=https://npm.internal/",
+			expectError: true,
+		},
+		{
+			name:        "Rejects a pair without a registry",
+			envValue:    "@myorg",
+			expectError: true,
+		},
+		{
+			name:        "Rejects an invalid registry URL",
+			envValue:    "@myorg=npm.internal",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("GO_NPM_HOME", t.TempDir())
+			t.Setenv("GO_NPM_SCOPE_REGISTRIES", tc.envValue)
+
+			cfg, err := New()
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, cfg.ScopeRegistries)
+		})
+	}
+}
+
+func TestParseOmit(t *testing.T) {
+	testCases := []struct {
+		name        string
+		values      []string
+		expected    OmitSet
+		expectError bool
+	}{
+		{
+			name: "Omits nothing by default",
+		},
+		{
+			name:     "Reads a single group",
+			values:   []string{"optional"},
+			expected: OmitSet{Optional: true},
+		},
+		{
+			name:     "Reads repeated and comma-separated groups",
+			values:   []string{"dev, optional", "peer"},
+			expected: OmitSet{Dev: true, Optional: true, Peer: true},
+		},
+		{
+			name:        "Rejects an unknown group",
+			values:      []string{"dev,bundled"},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			omit, err := ParseOmit(tc.values)
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, omit)
+		})
+	}
+}
+
+func TestNew_ManifestMaxAge(t *testing.T) {
+	testCases := []struct {
+		name        string
+		envValue    string
+		expectError bool
+		expected    time.Duration
+	}{
+		{
+			name:     "Defaults when env var is unset",
+			expected: DefaultManifestMaxAge,
+		},
+		{
+			name:     "Reads a duration",
+			envValue: "1h",
+			expected: time.Hour,
+		},
+		{
+			name:     "Zero revalidates every time",
+			envValue: "0",
+			expected: 0,
+		},
+		{
+			name:        "Rejects invalid value",
+			envValue:    "daily",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("GO_NPM_HOME", t.TempDir())
+			t.Setenv("GO_NPM_MANIFEST_MAX_AGE", tc.envValue)
+
+			cfg, err := New()
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, cfg.ManifestMaxAge)
+		})
+	}
+}
+
+func TestNew_RateLimit(t *testing.T) {
+	testCases := []struct {
+		name        string
+		envValue    string
+		expectError bool
+		expected    float64
+	}{
+		{
+			name:     "Disabled when env var is unset",
+			expected: 0,
+		},
+		{
+			name:     "Reads requests per second",
+			envValue: "10",
+			expected: 10,
+		},
+		{
+			name:     "Accepts a fraction",
+			envValue: "0.5",
+			expected: 0.5,
+		},
+		{
+			name:        "Rejects a negative rate",
+			envValue:    "-1",
+			expectError: true,
+		},
+		{
+			name:        "Rejects invalid value",
+			envValue:    "fast",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("GO_NPM_HOME", t.TempDir())
+			t.Setenv("GO_NPM_RATE_LIMIT", tc.envValue)
+
+			cfg, err := New()
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, cfg.RateLimit)
+		})
+	}
+}
+
+func TestNew_LocalTarballDir(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "lib-1.0.0.tgz")
+	assert.NoError(t, os.WriteFile(file, nil, 0644))
+
+	testCases := []struct {
+		name        string
+		envValue    string
+		expectError bool
+		expected    string
+	}{
+		{
+			name: "Unset by default",
+		},
+		{
+			name:     "Reads a directory",
+			envValue: dir,
+			expected: dir,
+		},
+		{
+			name:        "Rejects a file",
+			envValue:    file,
+			expectError: true,
+		},
+		{
+			name:        "Rejects a missing directory",
+			envValue:    filepath.Join(dir, "missing"),
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("GO_NPM_HOME", t.TempDir())
+			t.Setenv("GO_NPM_LOCAL_TARBALL_DIR", tc.envValue)
+
+			cfg, err := New()
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, cfg.LocalTarballDir)
+		})
+	}
+}
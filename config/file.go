@@ -0,0 +1,178 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// FileName is the config file in GO_NPM_HOME written by `go-npm config set`
+const FileName = "config.json"
+
+// Setting is a key of the config file and the environment variable it is the
+// default of: the environment wins over the file, and flags over both
+type Setting struct {
+	Key    string
+	Env    string
+	Secret bool // masked by `go-npm config list`
+}
+
+// Settings are the keys the config file accepts, in the order they are listed
+var Settings = []Setting{
+	{Key: "registry", Env: "GO_NPM_REGISTRIES"},
+	{Key: "scope-registries", Env: "GO_NPM_SCOPE_REGISTRIES"},
+	{Key: "auth-token", Env: "GO_NPM_AUTH_TOKEN", Secret: true},
+	{Key: "cache-dir", Env: "GO_NPM_HOME"},
+	{Key: "concurrency", Env: "GO_NPM_CONCURRENCY"},
+	{Key: "install-strategy", Env: "GO_NPM_INSTALL_STRATEGY"},
+	{Key: "fetch-retries", Env: "GO_NPM_FETCH_RETRIES"},
+	{Key: "rate-limit", Env: "GO_NPM_RATE_LIMIT"},
+	{Key: "http-timeout", Env: "GO_NPM_HTTP_TIMEOUT"},
+	{Key: "manifest-max-age", Env: "GO_NPM_MANIFEST_MAX_AGE"},
+	{Key: "manifest-fetch-mode", Env: "GO_NPM_MANIFEST_FETCH_MODE"},
+	{Key: "content-store", Env: "GO_NPM_CONTENT_STORE"},
+	{Key: "local-tarball-dir", Env: "GO_NPM_LOCAL_TARBALL_DIR"},
+	{Key: "scripts-allow", Env: "GO_NPM_SCRIPTS_ALLOW"},
+	{Key: "scripts-deny", Env: "GO_NPM_SCRIPTS_DENY"},
+}
+
+// LookupSetting returns the setting of a config file key
+func LookupSetting(key string) (Setting, bool) {
+	for _, setting := range Settings {
+		if setting.Key == key {
+			return setting, true
+		}
+	}
+	return Setting{}, false
+}
+
+// File holds the settings saved with `go-npm config set`, by key
+type File struct {
+	Path   string
+	Values map[string]string
+}
+
+// Home returns the directory holding the config file: GO_NPM_HOME, or else
+// ~/.config/go-npm. The cache-dir setting never moves the file itself.
+func Home() (string, error) {
+	if home := os.Getenv("GO_NPM_HOME"); home != "" {
+		return home, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".config", "go-npm"), nil
+}
+
+// LoadFile reads the config file in Home, empty when it does not exist yet
+func LoadFile() (*File, error) {
+	home, err := Home()
+	if err != nil {
+		return nil, err
+	}
+
+	file := &File{Path: filepath.Join(home, FileName), Values: make(map[string]string)}
+	data, err := os.ReadFile(file.Path)
+	if os.IsNotExist(err) {
+		return file, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", file.Path, err)
+	}
+	if err := json.Unmarshal(data, &file.Values); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", file.Path, err)
+	}
+	if file.Values == nil {
+		file.Values = make(map[string]string)
+	}
+	return file, nil
+}
+
+// Set validates value the way New reads it from the environment and sets key
+// to it. Save writes the change.
+func (f *File) Set(key, value string) error {
+	setting, ok := LookupSetting(key)
+	if !ok {
+		return fmt.Errorf("unknown config key %q", key)
+	}
+
+	switch setting.Key {
+	case "auth-token":
+	case "cache-dir":
+		if !filepath.IsAbs(value) {
+			return fmt.Errorf("invalid %s value %q: not an absolute path", key, value)
+		}
+	default:
+		lookup := func(env string) string {
+			if env == setting.Env {
+				return value
+			}
+			return ""
+		}
+		if err := (&Config{}).applySettings(lookup); err != nil {
+			return fmt.Errorf("invalid %s value %q", key, value)
+		}
+	}
+
+	f.Values[key] = value
+	return nil
+}
+
+// Delete removes key from the file, reporting whether it was set
+func (f *File) Delete(key string) (bool, error) {
+	if _, ok := LookupSetting(key); !ok {
+		return false, fmt.Errorf("unknown config key %q", key)
+	}
+	_, ok := f.Values[key]
+	delete(f.Values, key)
+	return ok, nil
+}
+
+// Save writes the file readable by its owner only, as it may hold an auth
+// token. It is replaced atomically, so a concurrent New never reads half of it.
+func (f *File) Save() error {
+	data, err := json.MarshalIndent(f.Values, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(f.Path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+
+	// CreateTemp creates the file with 0600
+	tmp, err := os.CreateTemp(dir, FileName+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", f.Path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", f.Path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", f.Path, err)
+	}
+	if err := os.Rename(tmp.Name(), f.Path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", f.Path, err)
+	}
+	return nil
+}
+
+// lookup returns the environment variable env, or else the value saved in
+// the file for its setting
+func (f *File) lookup(env string) string {
+	if value := os.Getenv(env); value != "" {
+		return value
+	}
+	for _, setting := range Settings {
+		if setting.Env == env {
+			return f.Values[setting.Key]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,158 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew_ConfigFile(t *testing.T) {
+	testCases := []struct {
+		name                string
+		file                map[string]string
+		env                 map[string]string
+		expectError         bool
+		expectedConcurrency int
+		expectedRegistry    string
+	}{
+		{
+			name:                "Reads settings from the file",
+			file:                map[string]string{"concurrency": "2", "registry": "https://npm.internal"},
+			expectedConcurrency: 2,
+			expectedRegistry:    "https://npm.internal/",
+		},
+		{
+			name:                "Environment wins over the file",
+			file:                map[string]string{"concurrency": "2", "registry": "https://npm.internal"},
+			env:                 map[string]string{"GO_NPM_CONCURRENCY": "3"},
+			expectedConcurrency: 3,
+			expectedRegistry:    "https://npm.internal/",
+		},
+		{
+			name:        "Rejects an invalid value in the file",
+			file:        map[string]string{"concurrency": "many"},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			home := t.TempDir()
+			t.Setenv("GO_NPM_HOME", home)
+			t.Setenv("GO_NPM_CONCURRENCY", "")
+			t.Setenv("GO_NPM_REGISTRIES", "")
+			for env, value := range tc.env {
+				t.Setenv(env, value)
+			}
+			file := &File{Path: filepath.Join(home, FileName), Values: tc.file}
+			assert.NoError(t, file.Save())
+
+			cfg, err := New()
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedConcurrency, cfg.Concurrency)
+			assert.Equal(t, tc.expectedRegistry, cfg.PrimaryRegistry())
+		})
+	}
+}
+
+func TestNew_ConfigFileCacheDir(t *testing.T) {
+	home := t.TempDir()
+	cacheDir := filepath.Join(t.TempDir(), "cache")
+	t.Setenv("GO_NPM_HOME", home)
+	t.Setenv("GO_NPM_AUTH_TOKEN", "")
+
+	file, err := LoadFile()
+	assert.NoError(t, err)
+	assert.NoError(t, file.Set("cache-dir", cacheDir))
+	assert.NoError(t, file.Set("auth-token", "secret"))
+	assert.NoError(t, file.Save())
+
+	// GO_NPM_HOME holds the file, so it wins over cache-dir
+	cfg, err := New()
+	assert.NoError(t, err)
+	assert.Equal(t, home, cfg.BaseDir)
+	assert.Equal(t, "secret", cfg.AuthToken)
+
+	t.Setenv("GO_NPM_HOME", "")
+	t.Setenv("HOME", t.TempDir())
+	file.Path = filepath.Join(os.Getenv("HOME"), ".config", "go-npm", FileName)
+	assert.NoError(t, file.Save())
+
+	cfg, err = New()
+	assert.NoError(t, err)
+	assert.Equal(t, cacheDir, cfg.BaseDir)
+	assert.Equal(t, filepath.Join(cacheDir, "manifest"), cfg.ManifestDir)
+	assert.NoFileExists(t, filepath.Join(cacheDir, FileName))
+}
+
+func TestFile_Set(t *testing.T) {
+	testCases := []struct {
+		name        string
+		key         string
+		value       string
+		expectError bool
+	}{
+		{name: "Registry", key: "registry", value: "https://npm.internal/"},
+		{name: "Install strategy", key: "install-strategy", value: "copy"},
+		{name: "Auth token", key: "auth-token", value: "npm_abc"},
+		{name: "Unknown key", key: "colour", value: "always", expectError: true},
+		{name: "Invalid registry", key: "registry", value: "ftp://npm.internal", expectError: true},
+		{name: "Invalid concurrency", key: "concurrency", value: "0", expectError: true},
+		{name: "Invalid install strategy", key: "install-strategy", value: "move", expectError: true},
+		{name: "Relative cache dir", key: "cache-dir", value: "cache", expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			file := &File{Values: make(map[string]string)}
+
+			err := file.Set(tc.key, tc.value)
+			if tc.expectError {
+				assert.Error(t, err)
+				assert.Empty(t, file.Values)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.value, file.Values[tc.key])
+		})
+	}
+}
+
+func TestFile_SaveKeepsSecretsPrivate(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("GO_NPM_HOME", home)
+	path := filepath.Join(home, FileName)
+
+	// A file created by hand with looser permissions is tightened too
+	assert.NoError(t, os.WriteFile(path, []byte(`{"registry": "https://npm.internal/"}`), 0644))
+
+	file, err := LoadFile()
+	assert.NoError(t, err)
+	assert.NoError(t, file.Set("auth-token", "npm_abc"))
+	assert.NoError(t, file.Save())
+
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	file, err = LoadFile()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"registry": "https://npm.internal/", "auth-token": "npm_abc"}, file.Values)
+
+	deleted, err := file.Delete("auth-token")
+	assert.NoError(t, err)
+	assert.True(t, deleted)
+	assert.NoError(t, file.Save())
+
+	file, err = LoadFile()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"registry": "https://npm.internal/"}, file.Values)
+}
@@ -0,0 +1,401 @@
+package dedupe
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/ernesto27/go-npm/utils"
+	"github.com/ernesto27/go-npm/version"
+)
+
+const nodeModules = "node_modules/"
+
+// Change describes one package name collapsed to a single top-level copy
+type Change struct {
+	Name     string
+	Version  string   // version kept at node_modules/<name>
+	Hoisted  string   // nested copy moved to node_modules/<name>, empty if the top-level copy was kept
+	Removed  []string // copies deleted from node_modules, subtrees included
+	Packages int      // lock entries dropped, nested dependencies of removed copies included
+}
+
+// Deduper finds packages installed at several node_modules paths whose
+// dependents can all share one top-level version
+type Deduper struct {
+	lock        *packagejson.PackageLock
+	versionInfo *version.Info
+}
+
+// dependent is a package (or the root, at path "") requiring a name
+type dependent struct {
+	path string
+	spec string
+}
+
+// New creates a Deduper for lock. The lock is not modified.
+func New(lock *packagejson.PackageLock) *Deduper {
+	return &Deduper{
+		lock:        lock,
+		versionInfo: version.New(),
+	}
+}
+
+// Plan returns the deduplicated lock and the changes that lead to it. A name
+// is only collapsed when one of its installed versions satisfies every
+// dependent and no other dependency in the tree resolves worse afterwards.
+func (d *Deduper) Plan() (*packagejson.PackageLock, []Change) {
+	lock := *d.lock
+	lock.Packages = make(map[string]packagejson.PackageItem, len(d.lock.Packages))
+	for key, item := range d.lock.Packages {
+		lock.Packages[key] = item
+	}
+
+	var changes []Change
+	for _, name := range duplicatedNames(&lock) {
+		next, change, ok := d.collapse(&lock, name)
+		if !ok {
+			continue
+		}
+
+		if !subset(d.violations(next), d.violations(&lock)) {
+			continue
+		}
+
+		lock = *next
+		changes = append(changes, change)
+	}
+
+	return &lock, changes
+}
+
+// Apply carries out changes in the node_modules tree under dir and returns
+// the bytes freed. With dryRun nothing is removed; the size is only measured.
+func Apply(dir string, changes []Change, dryRun bool) (int64, error) {
+	var saved int64
+
+	for _, change := range changes {
+		for _, removed := range change.Removed {
+			removedPath := filepath.Join(dir, removed)
+			size, err := utils.DirSize(removedPath)
+			if err != nil {
+				return saved, err
+			}
+			saved += size
+
+			if dryRun {
+				continue
+			}
+			if err := os.RemoveAll(removedPath); err != nil {
+				return saved, fmt.Errorf("failed to remove %s: %w", removedPath, err)
+			}
+		}
+
+		if change.Hoisted == "" || dryRun {
+			continue
+		}
+
+		target := filepath.Join(dir, nodeModules+change.Name)
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return saved, fmt.Errorf("failed to create %s: %w", filepath.Dir(target), err)
+		}
+		if err := os.Rename(filepath.Join(dir, change.Hoisted), target); err != nil {
+			return saved, fmt.Errorf("failed to move %s to %s: %w", change.Hoisted, target, err)
+		}
+	}
+
+	return saved, nil
+}
+
+// collapse returns a copy of lock where name is only installed at the top
+// level, with a version that satisfies all its dependents
+func (d *Deduper) collapse(lock *packagejson.PackageLock, name string) (*packagejson.PackageLock, Change, bool) {
+	change := Change{Name: name}
+
+	copies := make(map[string]bool)
+	for key, item := range lock.Packages {
+		if installName(key) != name {
+			continue
+		}
+		// Links, skipped optionals and git checkouts are left as they are
+		if item.Link || item.Skipped || !isSemver(item.Version) {
+			return nil, change, false
+		}
+		copies[key] = true
+	}
+
+	// An earlier change may have already removed the other copies
+	if len(copies) < 2 {
+		return nil, change, false
+	}
+
+	// Every dependent has to ask for the same package by a semver range
+	realName := ""
+	var ranges []string
+	for _, dep := range dependents(lock, name) {
+		if resolved, ok := lock.Resolve(dep.path, name); !ok || !copies[resolved] {
+			continue
+		}
+
+		depName, rng := splitAlias(name, dep.spec)
+		if !isRange(rng) || (realName != "" && depName != realName) {
+			return nil, change, false
+		}
+		realName = depName
+		ranges = append(ranges, rng)
+	}
+
+	top := nodeModules + name
+	change.Version = d.pickVersion(lock, copies, ranges)
+	if change.Version == "" {
+		return nil, change, false
+	}
+
+	if lock.Packages[top].Version != change.Version || !copies[top] {
+		change.Hoisted = hoistSource(lock, copies, change.Version)
+		if change.Hoisted == "" {
+			return nil, change, false
+		}
+	}
+
+	next := *lock
+	next.Packages = make(map[string]packagejson.PackageItem, len(lock.Packages))
+	for key, item := range lock.Packages {
+		next.Packages[key] = item
+	}
+
+	paths := make([]string, 0, len(copies))
+	for path := range copies {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		if path == change.Hoisted || (path == top && change.Hoisted == "") {
+			continue
+		}
+		change.Removed = append(change.Removed, path)
+		removeSubtree(&next, path)
+	}
+
+	if change.Hoisted != "" {
+		for key, item := range lock.Packages {
+			if key == change.Hoisted || strings.HasPrefix(key, change.Hoisted+"/") {
+				delete(next.Packages, key)
+				next.Packages[top+strings.TrimPrefix(key, change.Hoisted)] = item
+			}
+		}
+	}
+
+	change.Packages = len(lock.Packages) - len(next.Packages)
+	return &next, change, true
+}
+
+// pickVersion returns the highest installed version of the copies that
+// satisfies all ranges, or "" if there is none
+func (d *Deduper) pickVersion(lock *packagejson.PackageLock, copies map[string]bool, ranges []string) string {
+	var candidates []*semver.Version
+	seen := make(map[string]bool)
+	for path := range copies {
+		v := lock.Packages[path].Version
+		if seen[v] {
+			continue
+		}
+		seen[v] = true
+		if parsed, err := semver.NewVersion(v); err == nil {
+			candidates = append(candidates, parsed)
+		}
+	}
+	sort.Sort(sort.Reverse(semver.Collection(candidates)))
+
+	for _, candidate := range candidates {
+		satisfied := true
+		for _, rng := range ranges {
+			if !d.versionInfo.SatisfiesConstraint(candidate.Original(), rng) {
+				satisfied = false
+				break
+			}
+		}
+		if satisfied {
+			return candidate.Original()
+		}
+	}
+
+	return ""
+}
+
+// violations returns the dependencies in lock, as "path>name", that resolve
+// to no package or to a version outside their semver range
+func (d *Deduper) violations(lock *packagejson.PackageLock) map[string]bool {
+	result := make(map[string]bool)
+
+	for _, path := range dependentPaths(lock) {
+		for name, spec := range requires(lock, path) {
+			_, rng := splitAlias(name, spec)
+			if !isRange(rng) {
+				continue
+			}
+
+			resolved, ok := lock.Resolve(path, name)
+			if !ok || !d.versionInfo.SatisfiesConstraint(lock.Packages[resolved].Version, rng) {
+				result[path+">"+name] = true
+			}
+		}
+	}
+
+	return result
+}
+
+// duplicatedNames returns the sorted names installed at more than one path
+func duplicatedNames(lock *packagejson.PackageLock) []string {
+	counts := make(map[string]int)
+	for key := range lock.Packages {
+		if name := installName(key); name != "" {
+			counts[name]++
+		}
+	}
+
+	var names []string
+	for name, count := range counts {
+		if count > 1 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// dependents returns every package, and the root, that requires name
+func dependents(lock *packagejson.PackageLock, name string) []dependent {
+	var result []dependent
+	for _, path := range dependentPaths(lock) {
+		if spec, ok := requires(lock, path)[name]; ok {
+			result = append(result, dependent{path: path, spec: spec})
+		}
+	}
+	return result
+}
+
+// dependentPaths returns the root ("") followed by the sorted package paths
+func dependentPaths(lock *packagejson.PackageLock) []string {
+	paths := make([]string, 0, len(lock.Packages)+1)
+	for key, item := range lock.Packages {
+		if key != "" && !item.Link && !item.Skipped {
+			paths = append(paths, key)
+		}
+	}
+	sort.Strings(paths)
+	return append([]string{""}, paths...)
+}
+
+// requires returns the dependencies and optional dependencies of path
+func requires(lock *packagejson.PackageLock, path string) map[string]string {
+	result := make(map[string]string)
+
+	var sections []map[string]string
+	if path == "" {
+		sections = []map[string]string{lock.Dependencies, lock.DevDependencies, lock.OptionalDependencies}
+	} else {
+		item := lock.Packages[path]
+		sections = []map[string]string{item.Dependencies, item.OptionalDependencies}
+	}
+
+	for _, section := range sections {
+		for name, spec := range section {
+			result[name] = spec
+		}
+	}
+	return result
+}
+
+// hoistSource returns the shallowest copy of version that is not nested
+// inside another copy of the same package
+func hoistSource(lock *packagejson.PackageLock, copies map[string]bool, version string) string {
+	best := ""
+	for path := range copies {
+		if lock.Packages[path].Version != version {
+			continue
+		}
+
+		nested := false
+		for other := range copies {
+			if other != path && strings.HasPrefix(path, other+"/") {
+				nested = true
+				break
+			}
+		}
+		if nested {
+			continue
+		}
+
+		if best == "" || len(path) < len(best) || (len(path) == len(best) && path < best) {
+			best = path
+		}
+	}
+	return best
+}
+
+// removeSubtree deletes path and everything nested under it from lock
+func removeSubtree(lock *packagejson.PackageLock, path string) {
+	for key := range lock.Packages {
+		if key == path || strings.HasPrefix(key, path+"/") {
+			delete(lock.Packages, key)
+		}
+	}
+}
+
+// installName returns the name a lock path is installed under:
+// "node_modules/a/node_modules/@scope/b" -> "@scope/b". Paths outside
+// node_modules (workspace packages) have no install name.
+func installName(path string) string {
+	i := strings.LastIndex(path, nodeModules)
+	if i < 0 || (i > 0 && path[i-1] != '/') {
+		return ""
+	}
+	return path[i+len(nodeModules):]
+}
+
+// splitAlias returns the package and range a dependency spec asks for:
+// "npm:real@^1.0.0" -> ("real", "^1.0.0")
+func splitAlias(name, spec string) (string, string) {
+	aliased, ok := strings.CutPrefix(spec, "npm:")
+	if !ok {
+		return name, spec
+	}
+
+	at := strings.LastIndex(aliased, "@")
+	if at <= 0 {
+		return aliased, "latest"
+	}
+	return aliased[:at], aliased[at+1:]
+}
+
+// isRange reports whether spec is a semver range rather than a git, file
+// or tarball reference
+func isRange(spec string) bool {
+	if spec == "" || spec == "*" || spec == "latest" {
+		return true
+	}
+	_, err := semver.NewConstraint(spec)
+	return err == nil
+}
+
+// isSemver reports whether v is a registry version rather than a commit
+func isSemver(v string) bool {
+	_, err := semver.NewVersion(v)
+	return err == nil
+}
+
+// subset reports whether every key of a is in b
+func subset(a, b map[string]bool) bool {
+	for key := range a {
+		if !b[key] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,189 @@
+package dedupe
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func item(version string, deps map[string]string) packagejson.PackageItem {
+	return packagejson.PackageItem{Version: version, Dependencies: deps}
+}
+
+func TestDeduperPlan(t *testing.T) {
+	testCases := []struct {
+		name     string
+		lock     *packagejson.PackageLock
+		validate func(t *testing.T, lock *packagejson.PackageLock, changes []Change)
+	}{
+		{
+			name: "nested copy satisfied by the top-level version is removed",
+			lock: &packagejson.PackageLock{
+				Dependencies: map[string]string{"a": "^1.0.0", "x": "^1.0.0"},
+				Packages: map[string]packagejson.PackageItem{
+					"node_modules/a":                item("1.0.0", map[string]string{"x": "^1.1.0"}),
+					"node_modules/x":                item("1.2.0", nil),
+					"node_modules/a/node_modules/x": item("1.1.0", nil),
+				},
+			},
+			validate: func(t *testing.T, lock *packagejson.PackageLock, changes []Change) {
+				require.Len(t, changes, 1)
+				assert.Equal(t, Change{
+					Name:     "x",
+					Version:  "1.2.0",
+					Removed:  []string{"node_modules/a/node_modules/x"},
+					Packages: 1,
+				}, changes[0])
+				assert.NotContains(t, lock.Packages, "node_modules/a/node_modules/x")
+				assert.Equal(t, "1.2.0", lock.Packages["node_modules/x"].Version)
+			},
+		},
+		{
+			name: "nested version that satisfies everyone is hoisted with its subtree",
+			lock: &packagejson.PackageLock{
+				Dependencies: map[string]string{"a": "^1.0.0", "b": "^1.0.0", "x": "^1.0.0"},
+				Packages: map[string]packagejson.PackageItem{
+					"node_modules/a":                               item("1.0.0", map[string]string{"x": "^1.2.0"}),
+					"node_modules/b":                               item("1.0.0", map[string]string{"x": "^1.2.0"}),
+					"node_modules/x":                               item("1.0.0", nil),
+					"node_modules/a/node_modules/x":                item("1.2.0", map[string]string{"y": "^2.0.0"}),
+					"node_modules/a/node_modules/x/node_modules/y": item("2.0.0", nil),
+					"node_modules/b/node_modules/x":                item("1.2.0", map[string]string{"y": "^2.0.0"}),
+					"node_modules/b/node_modules/x/node_modules/y": item("2.0.0", nil),
+				},
+			},
+			validate: func(t *testing.T, lock *packagejson.PackageLock, changes []Change) {
+				require.Len(t, changes, 1)
+				assert.Equal(t, "1.2.0", changes[0].Version)
+				assert.Equal(t, "node_modules/a/node_modules/x", changes[0].Hoisted)
+				assert.Equal(t, []string{"node_modules/b/node_modules/x", "node_modules/x"}, changes[0].Removed)
+				assert.Equal(t, 3, changes[0].Packages)
+
+				assert.Len(t, lock.Packages, 4)
+				assert.Equal(t, "1.2.0", lock.Packages["node_modules/x"].Version)
+				assert.Equal(t, "2.0.0", lock.Packages["node_modules/x/node_modules/y"].Version)
+			},
+		},
+		{
+			name: "conflicting ranges are left alone",
+			lock: &packagejson.PackageLock{
+				Dependencies: map[string]string{"a": "^1.0.0", "x": "1.0.0"},
+				Packages: map[string]packagejson.PackageItem{
+					"node_modules/a":                item("1.0.0", map[string]string{"x": "^2.0.0"}),
+					"node_modules/x":                item("1.0.0", nil),
+					"node_modules/a/node_modules/x": item("2.0.0", nil),
+				},
+			},
+			validate: func(t *testing.T, lock *packagejson.PackageLock, changes []Change) {
+				assert.Empty(t, changes)
+				assert.Len(t, lock.Packages, 3)
+			},
+		},
+		{
+			name: "aliases of different packages are left alone",
+			lock: &packagejson.PackageLock{
+				Dependencies: map[string]string{"a": "^1.0.0", "x": "npm:other@^1.0.0"},
+				Packages: map[string]packagejson.PackageItem{
+					"node_modules/a":                item("1.0.0", map[string]string{"x": "^1.0.0"}),
+					"node_modules/x":                item("1.1.0", nil),
+					"node_modules/a/node_modules/x": item("1.0.0", nil),
+				},
+			},
+			validate: func(t *testing.T, lock *packagejson.PackageLock, changes []Change) {
+				assert.Empty(t, changes)
+			},
+		},
+		{
+			name: "hoist that breaks another dependency is rejected",
+			lock: &packagejson.PackageLock{
+				Dependencies: map[string]string{"a": "^1.0.0", "w": "^1.0.0", "x": "^1.0.0"},
+				Packages: map[string]packagejson.PackageItem{
+					"node_modules/a":                item("1.0.0", map[string]string{"x": "^1.2.0", "w": "^2.0.0"}),
+					"node_modules/w":                item("1.0.0", nil),
+					"node_modules/x":                item("1.0.0", nil),
+					"node_modules/a/node_modules/w": item("2.0.0", nil),
+					"node_modules/a/node_modules/x": item("1.2.0", map[string]string{"w": "^2.0.0"}),
+				},
+			},
+			validate: func(t *testing.T, lock *packagejson.PackageLock, changes []Change) {
+				assert.Empty(t, changes)
+				assert.Len(t, lock.Packages, 5)
+			},
+		},
+		{
+			name: "git dependencies are left alone",
+			lock: &packagejson.PackageLock{
+				Dependencies: map[string]string{"a": "^1.0.0", "x": "git+https://github.com/owner/x.git"},
+				Packages: map[string]packagejson.PackageItem{
+					"node_modules/a":                item("1.0.0", map[string]string{"x": "^1.0.0"}),
+					"node_modules/x":                item("0123456789abcdef0123456789abcdef01234567", nil),
+					"node_modules/a/node_modules/x": item("1.0.0", nil),
+				},
+			},
+			validate: func(t *testing.T, lock *packagejson.PackageLock, changes []Change) {
+				assert.Empty(t, changes)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			before := len(tc.lock.Packages)
+			lock, changes := New(tc.lock).Plan()
+			assert.Len(t, tc.lock.Packages, before, "Plan must not modify the input lock")
+			tc.validate(t, lock, changes)
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	writePackage := func(t *testing.T, dir, lockPath, version string) {
+		t.Helper()
+		pkgDir := filepath.Join(dir, lockPath)
+		require.NoError(t, os.MkdirAll(pkgDir, 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(pkgDir, "package.json"), []byte(`{"version": "`+version+`"}`), 0644))
+	}
+
+	changes := []Change{{
+		Name:    "x",
+		Version: "1.2.0",
+		Hoisted: "node_modules/a/node_modules/x",
+		Removed: []string{"node_modules/b/node_modules/x", "node_modules/x"},
+	}}
+
+	setup := func(t *testing.T) string {
+		dir := t.TempDir()
+		writePackage(t, dir, "node_modules/x", "1.0.0")
+		writePackage(t, dir, "node_modules/a/node_modules/x", "1.2.0")
+		writePackage(t, dir, "node_modules/b/node_modules/x", "1.2.0")
+		return dir
+	}
+
+	t.Run("moves and removes copies", func(t *testing.T) {
+		dir := setup(t)
+
+		saved, err := Apply(dir, changes, false)
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(`{"version": "1.2.0"}`)+len(`{"version": "1.0.0"}`)), saved)
+
+		content, err := os.ReadFile(filepath.Join(dir, "node_modules/x/package.json"))
+		require.NoError(t, err)
+		assert.Contains(t, string(content), "1.2.0")
+		assert.NoDirExists(t, filepath.Join(dir, "node_modules/a/node_modules/x"))
+		assert.NoDirExists(t, filepath.Join(dir, "node_modules/b/node_modules/x"))
+	})
+
+	t.Run("dry run only measures", func(t *testing.T) {
+		dir := setup(t)
+
+		saved, err := Apply(dir, changes, true)
+		require.NoError(t, err)
+		assert.Positive(t, saved)
+		assert.DirExists(t, filepath.Join(dir, "node_modules/a/node_modules/x"))
+		assert.DirExists(t, filepath.Join(dir, "node_modules/b/node_modules/x"))
+	})
+}
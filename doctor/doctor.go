@@ -0,0 +1,137 @@
+package doctor
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ernesto27/go-npm/config"
+	"github.com/ernesto27/go-npm/packagejson"
+)
+
+// Report categories, printed as headings in this order
+const (
+	CategoryEnvironment = "Environment"
+	CategoryTree        = "node_modules"
+	CategoryCache       = "Cache"
+)
+
+// Result is the outcome of a single doctor check. Details lists the
+// offending entries of a failed check.
+type Result struct {
+	Name        string
+	Category    string
+	OK          bool
+	Message     string
+	Details     []string
+	Remediation []string
+}
+
+// Doctor runs environment and installation health checks
+type Doctor struct {
+	config *config.Config
+	out    io.Writer
+
+	// dir and lock are the project whose node_modules is checked, set by
+	// SetProject
+	dir  string
+	lock *packagejson.PackageLock
+}
+
+// New creates a Doctor that prints its report to out
+func New(cfg *config.Config, out io.Writer) *Doctor {
+	return &Doctor{
+		config: cfg,
+		out:    out,
+	}
+}
+
+// SetProject makes Run also check the node_modules tree under dir against
+// lock, and the cached tarballs of the locked packages
+func (d *Doctor) SetProject(dir string, lock *packagejson.PackageLock) {
+	d.dir = dir
+	d.lock = lock
+}
+
+// Run executes all checks, prints a report grouped by category and returns
+// the results
+func (d *Doctor) Run() []Result {
+	results := []Result{
+		d.CheckGlobalBinInPath(os.Getenv("PATH")),
+	}
+	if d.lock != nil {
+		results = append(results,
+			d.CheckInstalledPackages(),
+			d.CheckExtraneous(),
+			d.CheckPeers(),
+			d.CheckCachedTarballs(),
+		)
+	}
+
+	category := ""
+	for i, result := range results {
+		if result.Category != category {
+			if i > 0 {
+				fmt.Fprintln(d.out)
+			}
+			fmt.Fprintln(d.out, result.Category)
+			category = result.Category
+		}
+		d.print(result)
+	}
+
+	return results
+}
+
+// CheckGlobalBinInPath verifies that the global bin directory is listed in pathEnv
+func (d *Doctor) CheckGlobalBinInPath(pathEnv string) Result {
+	result := Result{Name: "global bin directory on PATH", Category: CategoryEnvironment}
+	binDir := filepath.Clean(d.config.GlobalBinDir)
+
+	for _, dir := range filepath.SplitList(pathEnv) {
+		if dir != "" && filepath.Clean(dir) == binDir {
+			result.OK = true
+			result.Message = fmt.Sprintf("%s is on PATH", binDir)
+			return result
+		}
+	}
+
+	exportLine := d.config.GlobalBinExportLine()
+	result.Message = fmt.Sprintf("%s is not on PATH", binDir)
+	result.Remediation = []string{"Add it to your PATH:", "  " + exportLine}
+
+	rcFile, err := config.ShellRCFile()
+	if err != nil {
+		return result
+	}
+
+	content, err := os.ReadFile(rcFile)
+	if err == nil && strings.Contains(string(content), exportLine) {
+		result.Remediation = []string{
+			fmt.Sprintf("The export line was added to %s:", rcFile),
+			"  " + exportLine,
+			fmt.Sprintf("Run 'source %s' or open a new terminal to apply it", rcFile),
+		}
+		return result
+	}
+
+	result.Remediation = append(result.Remediation, fmt.Sprintf("Append the line above to %s to make it permanent", rcFile))
+	return result
+}
+
+func (d *Doctor) print(result Result) {
+	if result.OK {
+		fmt.Fprintf(d.out, "✓ %s: %s\n", result.Name, result.Message)
+		return
+	}
+
+	fmt.Fprintf(d.out, "✗ %s: %s\n", result.Name, result.Message)
+	for _, line := range result.Details {
+		fmt.Fprintf(d.out, "    - %s\n", line)
+	}
+	for _, line := range result.Remediation {
+		fmt.Fprintf(d.out, "    %s\n", line)
+	}
+}
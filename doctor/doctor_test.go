@@ -0,0 +1,81 @@
+package doctor
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ernesto27/go-npm/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckGlobalBinInPath(t *testing.T) {
+	testCases := []struct {
+		name      string
+		setupFunc func(t *testing.T, cfg *config.Config, homeDir string) string
+		expectOK  bool
+		validate  func(t *testing.T, cfg *config.Config, homeDir string, output string)
+	}{
+		{
+			name: "reports success when global bin directory is on PATH",
+			setupFunc: func(t *testing.T, cfg *config.Config, homeDir string) string {
+				return strings.Join([]string{"/usr/bin", cfg.GlobalBinDir}, string(os.PathListSeparator))
+			},
+			expectOK: true,
+			validate: func(t *testing.T, cfg *config.Config, homeDir string, output string) {
+				assert.Contains(t, output, "✓")
+				assert.NotContains(t, output, "export PATH")
+			},
+		},
+		{
+			name: "reports missing directory and prints export line",
+			setupFunc: func(t *testing.T, cfg *config.Config, homeDir string) string {
+				return "/usr/bin"
+			},
+			expectOK: false,
+			validate: func(t *testing.T, cfg *config.Config, homeDir string, output string) {
+				assert.Contains(t, output, "✗")
+				assert.Contains(t, output, "is not on PATH")
+				assert.Contains(t, output, cfg.GlobalBinExportLine())
+				assert.Contains(t, output, filepath.Join(homeDir, ".bashrc"))
+			},
+		},
+		{
+			name: "points to the rc file the export line was added to",
+			setupFunc: func(t *testing.T, cfg *config.Config, homeDir string) string {
+				rcContent := "\n# Added by go-npm\n" + cfg.GlobalBinExportLine() + "\n"
+				err := os.WriteFile(filepath.Join(homeDir, ".bashrc"), []byte(rcContent), 0644)
+				assert.NoError(t, err)
+				return "/usr/bin"
+			},
+			expectOK: false,
+			validate: func(t *testing.T, cfg *config.Config, homeDir string, output string) {
+				rcFile := filepath.Join(homeDir, ".bashrc")
+				assert.Contains(t, output, "The export line was added to "+rcFile)
+				assert.Contains(t, output, cfg.GlobalBinExportLine())
+				assert.Contains(t, output, "source "+rcFile)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			homeDir := t.TempDir()
+			t.Setenv("HOME", homeDir)
+			t.Setenv("SHELL", "/bin/bash")
+			cfg := &config.Config{GlobalBinDir: filepath.Join(homeDir, ".config", "go-npm", "global", "bin")}
+
+			pathEnv := tc.setupFunc(t, cfg, homeDir)
+
+			var buf bytes.Buffer
+			d := New(cfg, &buf)
+			result := d.CheckGlobalBinInPath(pathEnv)
+			d.print(result)
+
+			assert.Equal(t, tc.expectOK, result.OK)
+			tc.validate(t, cfg, homeDir, buf.String())
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package doctor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ernesto27/go-npm/integrity"
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/ernesto27/go-npm/peers"
+	"github.com/ernesto27/go-npm/prune"
+	"github.com/ernesto27/go-npm/tarball"
+)
+
+// TreeProblem is a locked package node_modules does not match. Installed is
+// the version found, empty when the package is missing.
+type TreeProblem struct {
+	Path      string
+	Locked    string
+	Installed string
+}
+
+func (p TreeProblem) String() string {
+	if p.Installed == "" {
+		return fmt.Sprintf("%s: missing, lock has %s", p.Path, p.Locked)
+	}
+	return fmt.Sprintf("%s: %s installed, lock has %s", p.Path, p.Installed, p.Locked)
+}
+
+// FindTreeProblems compares the node_modules tree under dir with lock. Every
+// locked package must be installed at its locked version; skipped and missing
+// optional packages are fine, and linked packages only have to exist.
+func FindTreeProblems(dir string, lock *packagejson.PackageLock) ([]TreeProblem, int) {
+	var problems []TreeProblem
+	checked := 0
+	for _, pkgPath := range lockedPackagePaths(lock) {
+		item := lock.Packages[pkgPath]
+		if item.Skipped {
+			continue
+		}
+		checked++
+
+		installed, err := installedVersion(filepath.Join(dir, pkgPath))
+		switch {
+		case err != nil && item.Optional:
+		case err != nil:
+			problems = append(problems, TreeProblem{Path: pkgPath, Locked: item.Version})
+		case item.Link || item.Version == "" || installed == item.Version:
+		default:
+			problems = append(problems, TreeProblem{Path: pkgPath, Locked: item.Version, Installed: installed})
+		}
+	}
+	return problems, checked
+}
+
+// CheckInstalledPackages verifies that every locked package is installed at
+// its locked version
+func (d *Doctor) CheckInstalledPackages() Result {
+	result := Result{Name: "installed packages", Category: CategoryTree}
+	problems, checked := FindTreeProblems(d.dir, d.lock)
+	if len(problems) == 0 {
+		result.OK = true
+		result.Message = fmt.Sprintf("%d packages match %s", checked, packagejson.LOCK_FILE_NAME_GO_NPM)
+		return result
+	}
+
+	result.Message = fmt.Sprintf("%d of %d locked packages are missing or at the wrong version", len(problems), checked)
+	for _, problem := range problems {
+		result.Details = append(result.Details, problem.String())
+	}
+	result.Remediation = []string{"Run 'go-npm doctor --fix' or 'go-npm install' to reinstall them"}
+	return result
+}
+
+// CheckExtraneous verifies that node_modules holds no package the lock does
+// not list
+func (d *Doctor) CheckExtraneous() Result {
+	result := Result{Name: "extraneous packages", Category: CategoryTree}
+	extraneous, err := d.pruner().Plan(d.dir)
+	if err != nil {
+		result.Message = fmt.Sprintf("failed to scan node_modules: %v", err)
+		return result
+	}
+	if len(extraneous) == 0 {
+		result.OK = true
+		result.Message = "none found"
+		return result
+	}
+
+	result.Message = fmt.Sprintf("%d packages are not in %s", len(extraneous), packagejson.LOCK_FILE_NAME_GO_NPM)
+	result.Details = extraneous
+	result.Remediation = []string{"Run 'go-npm doctor --fix' or 'go-npm prune' to remove them"}
+	return result
+}
+
+// CheckPeers verifies that every locked peer dependency is satisfied
+func (d *Doctor) CheckPeers() Result {
+	result := Result{Name: "peer dependencies", Category: CategoryTree}
+	entries := peers.BuildMatrix(d.lock)
+	warnings := peers.Warnings(entries)
+	if len(warnings) == 0 {
+		result.OK = true
+		result.Message = fmt.Sprintf("%d peer dependencies satisfied", len(entries))
+		return result
+	}
+
+	result.Message = fmt.Sprintf("%d dependents have an unsatisfied peer dependency", len(warnings))
+	result.Details = warnings
+	result.Remediation = []string{"Run 'go-npm peers' to see every dependent, then install versions that satisfy them"}
+	return result
+}
+
+// CheckCachedTarballs verifies the cached tarballs of the locked packages
+// against their locked integrity. Tarballs that are not cached are skipped.
+func (d *Doctor) CheckCachedTarballs() Result {
+	result := Result{Name: "cached tarballs", Category: CategoryCache}
+	validator := integrity.New()
+	seen := make(map[string]bool)
+	checked := 0
+
+	for _, pkgPath := range lockedPackagePaths(d.lock) {
+		item := d.lock.Packages[pkgPath]
+		if item.Link || item.Integrity == "" || item.Version == "" {
+			continue
+		}
+
+		name := packageName(pkgPath)
+		filename := tarball.UniqueName(name, tarball.CacheVersion(name, item.Version, item.Resolved))
+		if seen[filename] {
+			continue
+		}
+		seen[filename] = true
+
+		tarballPath := filepath.Join(d.config.TarballDir, filename)
+		if _, err := os.Stat(tarballPath); err != nil {
+			continue
+		}
+		checked++
+		if err := validator.ValidateFileStrict(tarballPath, item.Integrity); err != nil {
+			result.Details = append(result.Details, fmt.Sprintf("%s (%s): %v", filename, pkgPath, err))
+		}
+	}
+
+	if len(result.Details) == 0 {
+		result.OK = true
+		result.Message = fmt.Sprintf("%d cached tarballs verified", checked)
+		return result
+	}
+
+	result.Message = fmt.Sprintf("%d of %d cached tarballs fail their integrity check", len(result.Details), checked)
+	result.Remediation = []string{"Run 'go-npm cache verify' to remove them; the next install downloads them again"}
+	return result
+}
+
+// pruner returns the pruner that finds extraneous packages, the same way
+// 'go-npm prune' does
+func (d *Doctor) pruner() *prune.Pruner {
+	pruner := prune.New(d.lock)
+	pruner.SetPackagesDir(d.config.PackagesDir)
+	pruner.SetStoreDir(d.config.StoreDir)
+	return pruner
+}
+
+// Extraneous returns the lock-style paths of the extraneous packages
+func (d *Doctor) Extraneous() ([]string, error) {
+	return d.pruner().Plan(d.dir)
+}
+
+// lockedPackagePaths returns the sorted node_modules paths of lock, leaving
+// out the root and workspace packages
+func lockedPackagePaths(lock *packagejson.PackageLock) []string {
+	var paths []string
+	for pkgPath := range lock.Packages {
+		if strings.HasPrefix(pkgPath, "node_modules/") {
+			paths = append(paths, pkgPath)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// packageName returns the name a lock path installs, the part after its last
+// node_modules/
+func packageName(pkgPath string) string {
+	return pkgPath[strings.LastIndex(pkgPath, "node_modules/")+len("node_modules/"):]
+}
+
+// installedVersion reads the version from the package.json of the package
+// installed at pkgDir
+func installedVersion(pkgDir string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(pkgDir, "package.json"))
+	if err != nil {
+		return "", err
+	}
+	var pkg struct {
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return "", fmt.Errorf("invalid package.json: %w", err)
+	}
+	return pkg.Version, nil
+}
@@ -0,0 +1,150 @@
+package doctor
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ernesto27/go-npm/config"
+	"github.com/ernesto27/go-npm/integrity"
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/ernesto27/go-npm/tarball"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeInstalled writes node_modules packages under dir, keyed by lock path
+func writeInstalled(t *testing.T, dir string, versions map[string]string) {
+	t.Helper()
+	for pkgPath, version := range versions {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, pkgPath), 0755))
+		content := `{"name": "` + packageName(pkgPath) + `", "version": "` + version + `"}`
+		require.NoError(t, os.WriteFile(filepath.Join(dir, pkgPath, "package.json"), []byte(content), 0644))
+	}
+}
+
+func TestFindTreeProblems(t *testing.T) {
+	testCases := []struct {
+		name      string
+		lock      map[string]packagejson.PackageItem
+		installed map[string]string
+		expected  []TreeProblem
+		checked   int
+	}{
+		{
+			name: "tree matches the lock",
+			lock: map[string]packagejson.PackageItem{
+				"":                              {Name: "app"},
+				"node_modules/a":                {Version: "1.0.0"},
+				"node_modules/a/node_modules/b": {Version: "2.0.0"},
+				"node_modules/@scope/c":         {Version: "3.0.0"},
+			},
+			installed: map[string]string{
+				"node_modules/a":                "1.0.0",
+				"node_modules/a/node_modules/b": "2.0.0",
+				"node_modules/@scope/c":         "3.0.0",
+			},
+			checked: 3,
+		},
+		{
+			name: "missing and mismatched packages",
+			lock: map[string]packagejson.PackageItem{
+				"node_modules/a": {Version: "1.0.0"},
+				"node_modules/b": {Version: "2.0.0"},
+			},
+			installed: map[string]string{"node_modules/b": "1.9.0"},
+			expected: []TreeProblem{
+				{Path: "node_modules/a", Locked: "1.0.0"},
+				{Path: "node_modules/b", Locked: "2.0.0", Installed: "1.9.0"},
+			},
+			checked: 2,
+		},
+		{
+			name: "skipped and missing optional packages are fine",
+			lock: map[string]packagejson.PackageItem{
+				"node_modules/fsevents": {Version: "2.3.3", Optional: true, Skipped: true},
+				"node_modules/opt":      {Version: "1.0.0", Optional: true},
+			},
+			checked: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeInstalled(t, dir, tc.installed)
+
+			problems, checked := FindTreeProblems(dir, &packagejson.PackageLock{Packages: tc.lock})
+			assert.Equal(t, tc.expected, problems)
+			assert.Equal(t, tc.checked, checked)
+		})
+	}
+}
+
+func TestRunWithProject(t *testing.T) {
+	dir := t.TempDir()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	cfg := &config.Config{
+		GlobalBinDir: filepath.Join(home, "bin"),
+		TarballDir:   filepath.Join(home, "tarball"),
+		PackagesDir:  filepath.Join(home, "packages"),
+		StoreDir:     filepath.Join(home, "store"),
+	}
+	t.Setenv("PATH", cfg.GlobalBinDir)
+
+	// A cached tarball whose content no longer matches the lock
+	require.NoError(t, os.MkdirAll(cfg.TarballDir, 0755))
+	good := filepath.Join(cfg.TarballDir, tarball.UniqueName("a", "1.0.0"))
+	require.NoError(t, os.WriteFile(good, []byte("a"), 0644))
+	goodSRI, err := integrity.ComputeSRI(good, "sha512")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(cfg.TarballDir, tarball.UniqueName("react-dom", "18.0.0")), []byte("tampered"), 0644))
+
+	lock := &packagejson.PackageLock{Packages: map[string]packagejson.PackageItem{
+		"":               {Name: "app"},
+		"node_modules/a": {Version: "1.0.0", Integrity: goodSRI},
+		"node_modules/react-dom": {
+			Version:          "18.0.0",
+			Integrity:        goodSRI,
+			PeerDependencies: map[string]string{"react": "^18.0.0"},
+		},
+	}}
+	writeInstalled(t, dir, map[string]string{
+		"node_modules/a":          "1.0.0",
+		"node_modules/react-dom":  "18.0.0",
+		"node_modules/extraneous": "1.0.0",
+	})
+
+	var buf bytes.Buffer
+	d := New(cfg, &buf)
+	d.SetProject(dir, lock)
+	results := d.Run()
+
+	outcomes := make(map[string]bool)
+	for _, result := range results {
+		outcomes[result.Name] = result.OK
+	}
+	assert.Equal(t, map[string]bool{
+		"global bin directory on PATH": true,
+		"installed packages":           true,
+		"extraneous packages":          false,
+		"peer dependencies":            false,
+		"cached tarballs":              false,
+	}, outcomes)
+
+	output := buf.String()
+	for _, expected := range []string{
+		"Environment\n✓ global bin directory on PATH",
+		"\nnode_modules\n✓ installed packages: 2 packages match go-npm-lock.json",
+		"    - node_modules/extraneous",
+		"    - node_modules/react-dom requires peer react@^18.0.0 but it is not installed",
+		"\nCache\n✗ cached tarballs: 1 of 2 cached tarballs fail their integrity check",
+		"    - react-dom-18.0.0.tgz (node_modules/react-dom)",
+	} {
+		assert.Contains(t, output, expected)
+	}
+	assert.Equal(t, 1, strings.Count(output, "node_modules\n"), "each category heading is printed once")
+}
@@ -7,16 +7,22 @@ import (
 	"github.com/ernesto27/go-npm/utils"
 	"os"
 	"path/filepath"
+	"sync"
+	"time"
 )
 
 type Etag struct {
+	mu       sync.Mutex
 	packages map[string]packagejson.Dependency
 	etagPath string
 	etagData map[string]EtagEntry
 }
 
+// EtagEntry is what is kept about a cached manifest: the ETag the registry
+// sent for it and when it was last fetched or revalidated
 type EtagEntry struct {
-	Etag string `json:"etag"`
+	Etag      string    `json:"etag"`
+	FetchedAt time.Time `json:"fetchedAt,omitzero"`
 }
 
 func NewEtag(configPath string) (*Etag, error) {
@@ -45,20 +51,39 @@ func (e *Etag) setPackages(packages map[string]packagejson.Dependency) {
 }
 
 func (e *Etag) Get(packageName string) string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
 	if entry, ok := e.etagData[packageName]; ok {
 		return entry.Etag
 	}
 	return ""
 }
 
+// Set records the ETag of a manifest fetched or revalidated at fetchedAt
+func (e *Etag) Set(packageName, etag string, fetchedAt time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.etagData[packageName] = EtagEntry{Etag: etag, FetchedAt: fetchedAt}
+}
+
+// FetchedAt returns when the manifest of packageName was last fetched or
+// revalidated, or the zero time if that was not recorded
+func (e *Etag) FetchedAt(packageName string) time.Time {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.etagData[packageName].FetchedAt
+}
+
 func (e *Etag) Save() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
 	etagFilePath := filepath.Join(e.etagPath, "etag.json")
 
 	for pkgName, dep := range e.packages {
 		if dep.Etag != "" {
-			e.etagData[pkgName] = EtagEntry{
-				Etag: dep.Etag,
-			}
+			entry := e.etagData[pkgName]
+			entry.Etag = dep.Etag
+			e.etagData[pkgName] = entry
 		}
 	}
 
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -265,3 +266,31 @@ func TestEtagSave(t *testing.T) {
 		})
 	}
 }
+
+func TestEtagSetPersistsFetchTime(t *testing.T) {
+	configDir := setupTestEtagDir(t)
+	etag, err := NewEtag(configDir)
+	assert.NoError(t, err)
+
+	assert.True(t, etag.FetchedAt("express").IsZero(), "nothing is recorded before a fetch")
+
+	fetchedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	etag.Set("express", "W/\"abc123\"", fetchedAt)
+	assert.Equal(t, "W/\"abc123\"", etag.Get("express"))
+	assert.True(t, fetchedAt.Equal(etag.FetchedAt("express")))
+	assert.NoError(t, etag.Save())
+
+	// A new instance reads the fetch time back from disk
+	reloaded, err := NewEtag(configDir)
+	assert.NoError(t, err)
+	assert.Equal(t, "W/\"abc123\"", reloaded.Get("express"))
+	assert.True(t, fetchedAt.Equal(reloaded.FetchedAt("express")))
+
+	// Saving an etag from setPackages keeps the recorded fetch time
+	reloaded.setPackages(map[string]packagejson.Dependency{
+		"express": {Name: "express", Version: "4.18.0", Etag: "W/\"def456\""},
+	})
+	assert.NoError(t, reloaded.Save())
+	assert.Equal(t, "W/\"def456\"", reloaded.Get("express"))
+	assert.True(t, fetchedAt.Equal(reloaded.FetchedAt("express")))
+}
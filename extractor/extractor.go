@@ -8,16 +8,25 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
 )
 
+// maxBufferedFileSize is the largest file handed to the write workers. Larger
+// files are streamed to disk straight from the tar reader, so a big package
+// never holds more than a few of these buffers in memory.
+const maxBufferedFileSize = 1024 * 1024
+
 type TGZExtractor struct {
 	bufferSize int
+	workers    int
 }
 
 func NewTGZExtractor() *TGZExtractor {
 	return &TGZExtractor{
 		bufferSize: 32 * 1024,
+		workers:    min(runtime.NumCPU(), 8),
 	}
 }
 
@@ -43,6 +52,10 @@ func (e *TGZExtractor) Extract(srcPath, destPath string) error {
 	return nil
 }
 
+// extractToDirectory streams the tarball through gzip and tar. The tar
+// reader is sequential, so regular files are read into memory and written by
+// a pool of workers. Symlinks are created after every file is written, so no
+// file is ever written through one.
 func (e *TGZExtractor) extractToDirectory(srcPath, destPath string) error {
 	file, err := os.Open(srcPath)
 	if err != nil {
@@ -60,57 +73,133 @@ func (e *TGZExtractor) extractToDirectory(srcPath, destPath string) error {
 
 	tr := tar.NewReader(gzr)
 
+	pool := newWritePool(max(e.workers, 1))
 	copyBuffer := make([]byte, e.bufferSize)
+	written := make(map[string]bool)
+	var symlinks []*tar.Header
 
-	for {
-		header, err := tr.Next()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return fmt.Errorf("failed to read tar header: %w", err)
-		}
-
-		relativePath := e.stripPackagePrefix(header.Name)
-		if relativePath == "" {
-			continue
-		}
-		target := filepath.Join(destPath, relativePath)
+	readErr := func() error {
+		for {
+			if err := pool.Err(); err != nil {
+				return err
+			}
 
-		if !e.isValidPath(target, destPath) {
-			fmt.Printf("Skipping unsafe path: %s\n", header.Name)
-			continue
-		}
+			header, err := tr.Next()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("failed to read tar header: %w", err)
+			}
 
-		switch header.Typeflag {
-		case tar.TypeReg:
-			if err := e.extractFile(tr, target, header, copyBuffer); err != nil {
+			target, err := e.entryTarget(destPath, header.Name)
+			if err != nil {
 				return err
 			}
+			if target == "" {
+				continue
+			}
+
+			switch header.Typeflag {
+			case tar.TypeDir:
+				if err := os.MkdirAll(target, 0755); err != nil {
+					return fmt.Errorf("failed to create directory %s: %w", target, err)
+				}
+			case tar.TypeReg:
+				// A tarball may hold the same path twice; the last entry
+				// wins, so the earlier write has to finish first
+				if written[target] {
+					pool.Wait()
+				}
+				written[target] = true
+
+				if header.Size > maxBufferedFileSize {
+					if err := e.extractFile(tr, target, header, copyBuffer); err != nil {
+						return err
+					}
+					continue
+				}
+
+				data := make([]byte, header.Size)
+				if _, err := io.ReadFull(tr, data); err != nil {
+					return fmt.Errorf("failed to read file %s: %w", target, err)
+				}
+				pool.Submit(writeJob{target: target, mode: fileMode(header), data: data})
+			case tar.TypeSymlink:
+				symlinks = append(symlinks, header)
+			}
+		}
+	}()
+
+	if err := pool.Close(); err != nil && readErr == nil {
+		readErr = err
+	}
+	if readErr != nil {
+		return readErr
+	}
+
+	for _, header := range symlinks {
+		if err := e.extractSymlink(destPath, header); err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
 
+// entryTarget returns where the tarball entry name is extracted, or "" for
+// entries outside the package directory. Absolute names and names whose
+// cleaned path escapes destPath are rejected, so a crafted tarball cannot
+// write anywhere else.
+func (e *TGZExtractor) entryTarget(destPath, name string) (string, error) {
+	if isAbsEntry(name) {
+		return "", fmt.Errorf("unsafe path %s in tarball: absolute paths are not allowed", name)
+	}
+
+	relativePath := e.stripPackagePrefix(name)
+	if relativePath == "" {
+		return "", nil
+	}
+	if isAbsEntry(relativePath) {
+		return "", fmt.Errorf("unsafe path %s in tarball: absolute paths are not allowed", name)
+	}
+
+	target := filepath.Join(destPath, relativePath)
+	if !e.isValidPath(target, destPath) {
+		return "", fmt.Errorf("unsafe path %s in tarball: it escapes the package directory", name)
+	}
+	return target, nil
+}
+
+// isAbsEntry reports whether a tarball entry name is absolute. Tar names use
+// forward slashes, but a Windows drive or UNC path is absolute too.
+func isAbsEntry(name string) bool {
+	return strings.HasPrefix(name, "/") || strings.HasPrefix(name, `\`) || filepath.IsAbs(name) || filepath.VolumeName(name) != ""
+}
+
 func (e *TGZExtractor) isValidPath(target string, destPath string) bool {
 	cleanDest := filepath.Clean(destPath) + string(os.PathSeparator)
 	cleanTarget := filepath.Clean(target)
 	return strings.HasPrefix(cleanTarget, cleanDest)
 }
 
-func (e *TGZExtractor) extractFile(tr *tar.Reader, target string, header *tar.Header, copyBuffer []byte) error {
+// isWithin reports whether path is destPath or inside it
+func (e *TGZExtractor) isWithin(path, destPath string) bool {
+	return filepath.Clean(path) == filepath.Clean(destPath) || e.isValidPath(path, destPath)
+}
+
+func (e *TGZExtractor) extractFile(r io.Reader, target string, header *tar.Header, copyBuffer []byte) error {
 	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
 		return fmt.Errorf("failed to create parent directory for %s: %w", target, err)
 	}
 
-	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode))
+	f, err := createFile(target, fileMode(header))
 	if err != nil {
-		return fmt.Errorf("failed to create file %s: %w", target, err)
+		return err
 	}
 	defer f.Close()
 
-	_, err = io.CopyBuffer(f, tr, copyBuffer)
+	_, err = io.CopyBuffer(f, r, copyBuffer)
 	if err != nil {
 		return fmt.Errorf("failed to write file %s: %w", target, err)
 	}
@@ -118,9 +207,163 @@ func (e *TGZExtractor) extractFile(tr *tar.Reader, target string, header *tar.He
 	return nil
 }
 
+// extractSymlink creates the symlink described by header. The link must
+// point inside destPath, and so must the directory it is created in, which
+// could otherwise be reached through an earlier symlink.
+func (e *TGZExtractor) extractSymlink(destPath string, header *tar.Header) error {
+	target, err := e.entryTarget(destPath, header.Name)
+	if err != nil {
+		return err
+	}
+	if isAbsEntry(header.Linkname) {
+		return fmt.Errorf("unsafe symlink %s -> %s in tarball: it escapes the package directory", header.Name, header.Linkname)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create parent directory for %s: %w", target, err)
+	}
+	realDest, err := filepath.EvalSymlinks(destPath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", destPath, err)
+	}
+	realParent, err := filepath.EvalSymlinks(filepath.Dir(target))
+	if err != nil {
+		return fmt.Errorf("failed to resolve parent directory of %s: %w", target, err)
+	}
+	if !e.isWithin(realParent, realDest) {
+		return fmt.Errorf("unsafe symlink %s in tarball: it is created outside the package directory", header.Name)
+	}
+	if !e.isWithin(filepath.Join(realParent, header.Linkname), realDest) {
+		return fmt.Errorf("unsafe symlink %s -> %s in tarball: it escapes the package directory", header.Name, header.Linkname)
+	}
+
+	os.Remove(target)
+	if err := os.Symlink(header.Linkname, target); err != nil {
+		return fmt.Errorf("failed to create symlink %s: %w", target, err)
+	}
+	return nil
+}
+
 func (e *TGZExtractor) stripPackagePrefix(path string) string {
 	if idx := strings.Index(path, "/"); idx != -1 {
 		return path[idx+1:]
 	}
 	return ""
 }
+
+// writeJob is a regular file read from the tarball, waiting to be written
+type writeJob struct {
+	target string
+	mode   os.FileMode
+	data   []byte
+}
+
+// writePool writes files with a fixed number of workers. The jobs channel is
+// as large as the pool, bounding the file contents held in memory.
+type writePool struct {
+	jobs    chan writeJob
+	workers sync.WaitGroup
+	pending sync.WaitGroup
+
+	mu  sync.Mutex
+	err error
+}
+
+func newWritePool(workers int) *writePool {
+	p := &writePool{jobs: make(chan writeJob, workers)}
+	p.workers.Add(workers)
+	for range workers {
+		go p.run()
+	}
+	return p
+}
+
+func (p *writePool) run() {
+	defer p.workers.Done()
+	for job := range p.jobs {
+		if p.Err() == nil {
+			p.fail(writeFile(job))
+		}
+		p.pending.Done()
+	}
+}
+
+// Submit queues job, blocking while every worker is busy
+func (p *writePool) Submit(job writeJob) {
+	p.pending.Add(1)
+	p.jobs <- job
+}
+
+// Wait blocks until every submitted job is written
+func (p *writePool) Wait() {
+	p.pending.Wait()
+}
+
+// Err returns the first write error
+func (p *writePool) Err() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+// Close waits for the queued jobs, stops the workers and returns the first
+// write error
+func (p *writePool) Close() error {
+	close(p.jobs)
+	p.workers.Wait()
+	return p.Err()
+}
+
+func (p *writePool) fail(err error) {
+	if err == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err == nil {
+		p.err = err
+	}
+}
+
+func writeFile(job writeJob) error {
+	if err := os.MkdirAll(filepath.Dir(job.target), 0755); err != nil {
+		return fmt.Errorf("failed to create parent directory for %s: %w", job.target, err)
+	}
+
+	f, err := createFile(job.target, job.mode)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(job.data); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write file %s: %w", job.target, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write file %s: %w", job.target, err)
+	}
+	return nil
+}
+
+// fileMode returns the permissions a regular file entry is extracted with.
+// Like npm, every file is readable and writable by its owner and readable by
+// everyone, whatever the tarball says, while executable bits are kept.
+func fileMode(header *tar.Header) os.FileMode {
+	return os.FileMode(header.Mode).Perm() | 0644
+}
+
+// createFile creates or truncates target with mode. The mode is set again when
+// it has executable bits, which an existing file or the umask would otherwise
+// leave out.
+func createFile(target string, mode os.FileMode) (*os.File, error) {
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file %s: %w", target, err)
+	}
+	if mode&0111 != 0 {
+		if err := f.Chmod(mode); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to set mode of %s: %w", target, err)
+		}
+	}
+	return f, nil
+}
@@ -3,11 +3,14 @@ package extractor
 import (
 	"archive/tar"
 	"compress/gzip"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // setupTestExtractorDirs creates temporary directories for testing
@@ -47,6 +50,46 @@ func createTestTarball(t *testing.T, path string, entries map[string]string) {
 	}
 }
 
+// tarEntry is a tarball entry written by createTestTarballEntries
+type tarEntry struct {
+	name     string
+	typeflag byte
+	mode     int64
+	content  string
+	linkname string
+}
+
+// createTestTarballEntries creates a test .tgz file with the entries in order
+func createTestTarballEntries(t testing.TB, path string, entries []tarEntry) {
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	gzw := gzip.NewWriter(file)
+	defer gzw.Close()
+
+	tw := tar.NewWriter(gzw)
+	defer tw.Close()
+
+	for _, entry := range entries {
+		header := &tar.Header{
+			Name:     entry.name,
+			Mode:     entry.mode,
+			Size:     int64(len(entry.content)),
+			Typeflag: entry.typeflag,
+			Linkname: entry.linkname,
+		}
+		if entry.typeflag != tar.TypeReg {
+			header.Size = 0
+		}
+		require.NoError(t, tw.WriteHeader(header))
+		if header.Size > 0 {
+			_, err := tw.Write([]byte(entry.content))
+			require.NoError(t, err)
+		}
+	}
+}
+
 func TestTGZExtractorStripPackagePrefix(t *testing.T) {
 	testCases := []struct {
 		name        string
@@ -170,3 +213,182 @@ func TestTGZExtractorExtract(t *testing.T) {
 		})
 	}
 }
+
+func TestTGZExtractorExtractSymlinksAndModes(t *testing.T) {
+	srcDir, destDir := setupTestExtractorDirs(t)
+	tarballPath := filepath.Join(srcDir, "test.tgz")
+	bigContent := strings.Repeat("x", maxBufferedFileSize+1)
+
+	createTestTarballEntries(t, tarballPath, []tarEntry{
+		{name: "package/", typeflag: tar.TypeDir, mode: 0755},
+		{name: "package/package.json", typeflag: tar.TypeReg, mode: 0644, content: `{"name":"test"}`},
+		{name: "package/bin/cli.js", typeflag: tar.TypeReg, mode: 0755, content: "#!/usr/bin/env node"},
+		{name: "package/bin/private.sh", typeflag: tar.TypeReg, mode: 0700, content: "#!/bin/sh"},
+		{name: "package/README.md", typeflag: tar.TypeReg, mode: 0, content: "readme"},
+		{name: "package/lib/index.js", typeflag: tar.TypeReg, mode: 0644, content: "first"},
+		{name: "package/lib/index.js", typeflag: tar.TypeReg, mode: 0644, content: "second"},
+		{name: "package/dist/big.js", typeflag: tar.TypeReg, mode: 0644, content: bigContent},
+		{name: "package/main.js", typeflag: tar.TypeSymlink, linkname: "lib/index.js"},
+		{name: "package/empty/", typeflag: tar.TypeDir, mode: 0755},
+	})
+
+	require.NoError(t, NewTGZExtractor().Extract(tarballPath, destDir))
+
+	info, err := os.Stat(filepath.Join(destDir, "bin", "cli.js"))
+	require.NoError(t, err)
+	assert.NotZero(t, info.Mode().Perm()&0100, "executable bit is preserved")
+
+	info, err = os.Stat(filepath.Join(destDir, "package.json"))
+	require.NoError(t, err)
+	assert.Zero(t, info.Mode().Perm()&0111)
+
+	// Like npm, files are made readable whatever the tarball says
+	info, err = os.Stat(filepath.Join(destDir, "bin", "private.sh"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0744), info.Mode().Perm())
+
+	info, err = os.Stat(filepath.Join(destDir, "README.md"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+
+	content, err := os.ReadFile(filepath.Join(destDir, "lib", "index.js"))
+	require.NoError(t, err)
+	assert.Equal(t, "second", string(content), "the last duplicate entry wins")
+
+	content, err = os.ReadFile(filepath.Join(destDir, "dist", "big.js"))
+	require.NoError(t, err)
+	assert.Equal(t, bigContent, string(content))
+
+	link, err := os.Readlink(filepath.Join(destDir, "main.js"))
+	require.NoError(t, err)
+	assert.Equal(t, "lib/index.js", link)
+
+	assert.DirExists(t, filepath.Join(destDir, "empty"))
+}
+
+func TestTGZExtractorExtractRejectsUnsafePaths(t *testing.T) {
+	testCases := []struct {
+		name    string
+		entries []tarEntry
+		errMsg  string
+	}{
+		{
+			name: "file escaping with ../",
+			entries: []tarEntry{
+				{name: "package/index.js", typeflag: tar.TypeReg, mode: 0644, content: "ok"},
+				{name: "package/../../evil.js", typeflag: tar.TypeReg, mode: 0644, content: "evil"},
+			},
+			errMsg: "unsafe path package/../../evil.js",
+		},
+		{
+			name: "nested file escaping to a system directory",
+			entries: []tarEntry{
+				{name: "package/../../etc/cron.d/x", typeflag: tar.TypeReg, mode: 0644, content: "* * * * * root evil"},
+			},
+			errMsg: "unsafe path package/../../etc/cron.d/x in tarball: it escapes the package directory",
+		},
+		{
+			name: "absolute path",
+			entries: []tarEntry{
+				{name: "/etc/cron.d/x", typeflag: tar.TypeReg, mode: 0644, content: "evil"},
+			},
+			errMsg: "unsafe path /etc/cron.d/x in tarball: absolute paths are not allowed",
+		},
+		{
+			name: "absolute path after the package prefix",
+			entries: []tarEntry{
+				{name: "package//etc/cron.d/x", typeflag: tar.TypeReg, mode: 0644, content: "evil"},
+			},
+			errMsg: "unsafe path package//etc/cron.d/x in tarball: absolute paths are not allowed",
+		},
+		{
+			name: "directory escaping with ../",
+			entries: []tarEntry{
+				{name: "package/../../evil/", typeflag: tar.TypeDir, mode: 0755},
+			},
+			errMsg: "unsafe path package/../../evil/",
+		},
+		{
+			name: "symlink pointing outside",
+			entries: []tarEntry{
+				{name: "package/index.js", typeflag: tar.TypeReg, mode: 0644, content: "ok"},
+				{name: "package/evil", typeflag: tar.TypeSymlink, linkname: "../../outside"},
+			},
+			errMsg: "unsafe symlink package/evil -> ../../outside",
+		},
+		{
+			name: "symlink to an absolute path",
+			entries: []tarEntry{
+				{name: "package/index.js", typeflag: tar.TypeReg, mode: 0644, content: "ok"},
+				{name: "package/passwd", typeflag: tar.TypeSymlink, linkname: "/etc/passwd"},
+			},
+			errMsg: "unsafe symlink package/passwd -> /etc/passwd",
+		},
+		{
+			name: "symlink created through another symlink",
+			entries: []tarEntry{
+				{name: "package/index.js", typeflag: tar.TypeReg, mode: 0644, content: "ok"},
+				{name: "package/self", typeflag: tar.TypeSymlink, linkname: "."},
+				{name: "package/self/up", typeflag: tar.TypeSymlink, linkname: ".."},
+			},
+			errMsg: "unsafe symlink package/self/up -> ..",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			srcDir, destDir := setupTestExtractorDirs(t)
+			tarballPath := filepath.Join(srcDir, "test.tgz")
+			createTestTarballEntries(t, tarballPath, tc.entries)
+
+			err := NewTGZExtractor().Extract(tarballPath, destDir)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.errMsg)
+
+			// Nothing is left behind, inside or outside the destination
+			root, err := os.ReadDir(filepath.Dir(srcDir))
+			require.NoError(t, err)
+			require.Len(t, root, 1)
+			assert.Equal(t, "src", root[0].Name())
+			src, err := os.ReadDir(srcDir)
+			require.NoError(t, err)
+			require.Len(t, src, 1)
+			assert.Equal(t, "test.tgz", src[0].Name())
+		})
+	}
+}
+
+func BenchmarkTGZExtractorExtract(b *testing.B) {
+	srcDir := b.TempDir()
+	tarballPath := filepath.Join(srcDir, "bench.tgz")
+
+	// Roughly the shape of a large package: many small files in nested
+	// directories and a few big bundles
+	var entries []tarEntry
+	for i := range 2000 {
+		entries = append(entries, tarEntry{
+			name:     fmt.Sprintf("package/lib/dir%d/file%d.d.ts", i%50, i),
+			typeflag: tar.TypeReg,
+			mode:     0644,
+			content:  strings.Repeat("export declare const x: number;\n", 100),
+		})
+	}
+	for i := range 4 {
+		entries = append(entries, tarEntry{
+			name:     fmt.Sprintf("package/bundle%d.js", i),
+			typeflag: tar.TypeReg,
+			mode:     0644,
+			content:  strings.Repeat("var a = 1;\n", 300000),
+		})
+	}
+	createTestTarballEntries(b, tarballPath, entries)
+
+	extractor := NewTGZExtractor()
+	b.ResetTimer()
+	for i := range b.N {
+		destDir := filepath.Join(srcDir, fmt.Sprintf("dest%d", i))
+		if err := extractor.Extract(tarballPath, destDir); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -3,7 +3,6 @@ package info
 import (
 	"fmt"
 	"os"
-	"path/filepath"
 	"sort"
 	"strings"
 
@@ -11,6 +10,7 @@ import (
 	"github.com/ernesto27/go-npm/config"
 	"github.com/ernesto27/go-npm/manifest"
 	"github.com/ernesto27/go-npm/parsejson"
+	"github.com/ernesto27/go-npm/utils"
 	"github.com/ernesto27/go-npm/version"
 )
 
@@ -35,10 +35,13 @@ type Info struct {
 
 // New creates a new Info instance
 func New(cfg *config.Config) (*Info, error) {
-	m, err := manifest.NewManifest(cfg.BaseDir, config.NPMRegistryURL)
+	m, err := manifest.NewManifest(cfg.BaseDir, cfg.PrimaryRegistry())
 	if err != nil {
 		return nil, fmt.Errorf("failed to create manifest: %w", err)
 	}
+	m.SetFallbackRegistries(cfg.FallbackRegistries())
+	m.SetScopeRegistries(cfg.ScopeRegistries)
+	m.SetRateLimiters(utils.NewRateLimiters(cfg.RateLimit))
 
 	return &Info{
 		manifest: m,
@@ -49,7 +52,7 @@ func New(cfg *config.Config) (*Info, error) {
 
 // Show fetches package info and prints it to stdout
 func (i *Info) Show(pkgName, requestedVersion string) error {
-	manifestPath := filepath.Join(i.manifest.Path, pkgName+".json")
+	manifestPath := i.manifest.FilePath(pkgName)
 
 	// Check if manifest exists in cache, download if not
 	if _, err := os.Stat(manifestPath); os.IsNotExist(err) {
@@ -215,14 +218,5 @@ func printDistTags(tags manifest.DistTags) {
 }
 
 func formatBytes(bytes int) string {
-	const unit = 1024
-	if bytes < unit {
-		return fmt.Sprintf("%d B", bytes)
-	}
-	div, exp := int64(unit), 0
-	for n := bytes / unit; n >= unit; n /= unit {
-		div *= unit
-		exp++
-	}
-	return fmt.Sprintf("%.2f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+	return utils.FormatBytes(int64(bytes))
 }
@@ -1,6 +1,7 @@
 package integrity
 
 import (
+	"crypto/sha1"
 	"crypto/sha256"
 	"crypto/sha512"
 	"encoding/base64"
@@ -97,24 +98,128 @@ func ComputeHash(filePath, algorithm string) (string, error) {
 	}
 	defer file.Close()
 
-	var h hash.Hash
+	h, err := newHash(algorithm)
+	if err != nil {
+		return "", err
+	}
+
+	// Stream the file to avoid loading entire tarball into memory
+	if _, err := io.Copy(h, file); err != nil {
+		return "", fmt.Errorf("failed to hash file: %w", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
+}
+
+// newHash returns a hash for algorithm, one of sha512, sha384, sha256 or sha1
+func newHash(algorithm string) (hash.Hash, error) {
 	switch algorithm {
 	case "sha512":
-		h = sha512.New()
+		return sha512.New(), nil
 	case "sha384":
-		h = sha512.New384()
+		return sha512.New384(), nil
 	case "sha256":
-		h = sha256.New()
-	default:
+		return sha256.New(), nil
+	case "sha1":
+		return sha1.New(), nil
+	}
+	return nil, ErrUnsupportedAlgorithm
+}
+
+// ComputeSRI computes the hash of a file as an SRI string ("{algorithm}-{base64hash}"),
+// the format npm records in dist.integrity and lock files
+func ComputeSRI(filePath, algorithm string) (string, error) {
+	hash, err := ComputeHash(filePath, algorithm)
+	if err != nil {
+		return "", err
+	}
+	return algorithm + "-" + hash, nil
+}
+
+// GenerateIntegrity computes an SRI string for a file with each of
+// algorithms, space-joined strongest first like ParseIntegrity orders them.
+// The file is read once whatever the number of algorithms; only the
+// algorithms ParseIntegrity accepts can be generated.
+func GenerateIntegrity(filePath string, algorithms []string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	return GenerateIntegrityFrom(file, algorithms)
+}
+
+// GenerateIntegrityFrom is GenerateIntegrity for the data read from r, like
+// a tarball packed in memory
+func GenerateIntegrityFrom(r io.Reader, algorithms []string) (string, error) {
+	if len(algorithms) == 0 {
 		return "", ErrUnsupportedAlgorithm
 	}
 
-	// Stream the file to avoid loading entire tarball into memory
-	if _, err := io.Copy(h, file); err != nil {
-		return "", fmt.Errorf("failed to hash file: %w", err)
+	hashes := make(map[string]hash.Hash, len(algorithms))
+	writers := make([]io.Writer, 0, len(algorithms))
+	for _, algorithm := range algorithms {
+		if _, ok := algorithmStrength[algorithm]; !ok {
+			return "", fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, algorithm)
+		}
+		if _, ok := hashes[algorithm]; ok {
+			continue
+		}
+		h, err := newHash(algorithm)
+		if err != nil {
+			return "", err
+		}
+		hashes[algorithm] = h
+		writers = append(writers, h)
 	}
 
-	return base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
+	if _, err := io.Copy(io.MultiWriter(writers...), r); err != nil {
+		return "", fmt.Errorf("failed to hash: %w", err)
+	}
+
+	sorted := make([]string, 0, len(hashes))
+	for algorithm := range hashes {
+		sorted = append(sorted, algorithm)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return algorithmStrength[sorted[i]] > algorithmStrength[sorted[j]]
+	})
+
+	parts := make([]string, 0, len(sorted))
+	for _, algorithm := range sorted {
+		parts = append(parts, algorithm+"-"+base64.StdEncoding.EncodeToString(hashes[algorithm].Sum(nil)))
+	}
+	return strings.Join(parts, " "), nil
+}
+
+// IsStrong reports whether integrity has a hash of a supported algorithm,
+// unlike an empty or sha1-only integrity left by older lock files
+func IsStrong(integrity string) bool {
+	_, err := ParseIntegrity(integrity)
+	return err == nil
+}
+
+// Upgrade returns the sha512 SRI of a file to replace a weak integrity. The
+// sha1 hashes in integrity, if any, must match the file first, so a file
+// that changed since it was locked is not given a new hash.
+func Upgrade(filePath, integrity string) (string, error) {
+	for _, part := range strings.Fields(integrity) {
+		hashValue, ok := strings.CutPrefix(part, "sha1-")
+		if !ok {
+			continue
+		}
+		computed, err := ComputeHash(filePath, "sha1")
+		if err != nil {
+			return "", err
+		}
+		if computed != hashValue {
+			return "", fmt.Errorf("%w: expected %s, got %s (algorithm: sha1)",
+				ErrIntegrityMismatch, hashValue, computed)
+		}
+	}
+
+	return GenerateIntegrity(filePath, []string{"sha512"})
 }
 
 // ValidateFile validates a file against an SRI integrity string
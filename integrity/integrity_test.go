@@ -1,11 +1,13 @@
 package integrity
 
 import (
+	"crypto/sha1"
 	"crypto/sha256"
 	"crypto/sha512"
 	"encoding/base64"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -218,6 +220,124 @@ func TestComputeHash(t *testing.T) {
 	}
 }
 
+func TestComputeSRI(t *testing.T) {
+	tmpDir := t.TempDir()
+	filePath := filepath.Join(tmpDir, "package.tgz")
+	assert.NoError(t, os.WriteFile(filePath, []byte("tarball content"), 0644))
+
+	t.Run("sha512 SRI string validates", func(t *testing.T) {
+		sri, err := ComputeSRI(filePath, "sha512")
+		assert.NoError(t, err)
+
+		h := sha512.Sum512([]byte("tarball content"))
+		assert.Equal(t, "sha512-"+base64.StdEncoding.EncodeToString(h[:]), sri)
+		assert.NoError(t, New().ValidateFileStrict(filePath, sri))
+	})
+
+	t.Run("unsupported algorithm", func(t *testing.T) {
+		_, err := ComputeSRI(filePath, "md5")
+		assert.ErrorIs(t, err, ErrUnsupportedAlgorithm)
+	})
+}
+
+func TestGenerateIntegrity(t *testing.T) {
+	tmpDir := t.TempDir()
+	filePath := filepath.Join(tmpDir, "package.tgz")
+	assert.NoError(t, os.WriteFile(filePath, []byte("tarball content"), 0644))
+
+	sha512SRI, err := ComputeSRI(filePath, "sha512")
+	assert.NoError(t, err)
+	sha384SRI, err := ComputeSRI(filePath, "sha384")
+	assert.NoError(t, err)
+	sha256SRI, err := ComputeSRI(filePath, "sha256")
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		name        string
+		algorithms  []string
+		expected    string
+		expectError error
+	}{
+		{name: "single algorithm", algorithms: []string{"sha512"}, expected: sha512SRI},
+		{name: "sorted strongest first", algorithms: []string{"sha256", "sha512", "sha384"}, expected: sha512SRI + " " + sha384SRI + " " + sha256SRI},
+		{name: "duplicates are dropped", algorithms: []string{"sha256", "sha256"}, expected: sha256SRI},
+		{name: "no algorithms", expectError: ErrUnsupportedAlgorithm},
+		{name: "sha1 is not generated", algorithms: []string{"sha512", "sha1"}, expectError: ErrUnsupportedAlgorithm},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sri, err := GenerateIntegrity(filePath, tc.algorithms)
+			if tc.expectError != nil {
+				assert.ErrorIs(t, err, tc.expectError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, sri)
+
+			// The generated string round-trips through ParseIntegrity and ValidateFile
+			hashes, err := ParseIntegrity(sri)
+			assert.NoError(t, err)
+			raw := make([]string, 0, len(hashes))
+			for _, h := range hashes {
+				raw = append(raw, h.Raw)
+			}
+			assert.Equal(t, sri, strings.Join(raw, " "))
+
+			algorithm, err := New().ValidateFile(filePath, sri)
+			assert.NoError(t, err)
+			assert.Equal(t, hashes[0].Algorithm, algorithm)
+		})
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := GenerateIntegrity(filepath.Join(tmpDir, "missing.tgz"), []string{"sha512"})
+		assert.Error(t, err)
+	})
+
+	t.Run("reader", func(t *testing.T) {
+		sri, err := GenerateIntegrityFrom(strings.NewReader("tarball content"), []string{"sha256", "sha512"})
+		assert.NoError(t, err)
+		assert.Equal(t, sha512SRI+" "+sha256SRI, sri)
+	})
+}
+
+func TestUpgrade(t *testing.T) {
+	tmpDir := t.TempDir()
+	filePath := filepath.Join(tmpDir, "package.tgz")
+	assert.NoError(t, os.WriteFile(filePath, []byte("tarball content"), 0644))
+
+	sha1Sum := sha1.Sum([]byte("tarball content"))
+	sha1SRI := "sha1-" + base64.StdEncoding.EncodeToString(sha1Sum[:])
+	sha512SRI, err := ComputeSRI(filePath, "sha512")
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		name        string
+		integrity   string
+		expectError error
+	}{
+		{name: "matching sha1", integrity: sha1SRI},
+		{name: "missing integrity", integrity: ""},
+		{name: "mismatched sha1", integrity: "sha1-" + base64.StdEncoding.EncodeToString([]byte("other")), expectError: ErrIntegrityMismatch},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.False(t, IsStrong(tc.integrity))
+
+			sri, err := Upgrade(filePath, tc.integrity)
+			if tc.expectError != nil {
+				assert.ErrorIs(t, err, tc.expectError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, sha512SRI, sri)
+			assert.True(t, IsStrong(sri))
+		})
+	}
+}
+
 func TestValidatorValidateFile(t *testing.T) {
 	testCases := []struct {
 		name        string
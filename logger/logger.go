@@ -0,0 +1,121 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Level is the severity of a log message; a logger prints the messages at
+// or below its level
+type Level int
+
+const (
+	LevelError Level = iota
+	LevelWarn
+	LevelInfo
+	LevelDebug
+)
+
+var levelNames = []string{"error", "warn", "info", "debug"}
+
+func (l Level) String() string {
+	if l < LevelError || l > LevelDebug {
+		return fmt.Sprintf("Level(%d)", int(l))
+	}
+	return levelNames[l]
+}
+
+// ParseLevel parses error, warn, info or debug
+func ParseLevel(name string) (Level, error) {
+	for i, levelName := range levelNames {
+		if strings.EqualFold(name, levelName) {
+			return Level(i), nil
+		}
+	}
+	return LevelInfo, fmt.Errorf("unknown log level %q (expected error, warn, info or debug)", name)
+}
+
+// Pauser is anything drawing on the terminal that must stop while a log
+// line is written, such as a spinner
+type Pauser interface {
+	Pause(fn func())
+}
+
+// Logger writes leveled messages to a single output
+type Logger struct {
+	mu     sync.Mutex
+	level  Level
+	out    io.Writer
+	pauser Pauser
+}
+
+// New creates a logger printing the messages at or below level to stdout
+func New(level Level) *Logger {
+	return &Logger{level: level, out: os.Stdout}
+}
+
+// SetOutput sets where messages are written
+func (l *Logger) SetOutput(w io.Writer) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.out = w
+}
+
+// SetPauser sets what is paused while a message is written
+func (l *Logger) SetPauser(p Pauser) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.pauser = p
+}
+
+// Level returns the level of the logger
+func (l *Logger) Level() Level {
+	return l.level
+}
+
+// Enabled reports whether messages at level are printed
+func (l *Logger) Enabled(level Level) bool {
+	return level <= l.level
+}
+
+// Errorf logs a message at error level
+func (l *Logger) Errorf(format string, args ...any) {
+	l.log(LevelError, "Error: ", format, args...)
+}
+
+// Warnf logs a message at warn level
+func (l *Logger) Warnf(format string, args ...any) {
+	l.log(LevelWarn, "Warning: ", format, args...)
+}
+
+// Infof logs a message at info level
+func (l *Logger) Infof(format string, args ...any) {
+	l.log(LevelInfo, "", format, args...)
+}
+
+// Debugf logs a message at debug level
+func (l *Logger) Debugf(format string, args ...any) {
+	l.log(LevelDebug, "debug: ", format, args...)
+}
+
+func (l *Logger) log(level Level, prefix, format string, args ...any) {
+	if !l.Enabled(level) {
+		return
+	}
+	message := prefix + fmt.Sprintf(format, args...)
+	if !strings.HasSuffix(message, "\n") {
+		message += "\n"
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	write := func() { io.WriteString(l.out, message) }
+	if l.pauser != nil {
+		l.pauser.Pause(write)
+		return
+	}
+	write()
+}
@@ -0,0 +1,103 @@
+package logger
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLevel(t *testing.T) {
+	testCases := []struct {
+		name        string
+		input       string
+		expected    Level
+		expectError bool
+	}{
+		{name: "error", input: "error", expected: LevelError},
+		{name: "warn", input: "warn", expected: LevelWarn},
+		{name: "info", input: "info", expected: LevelInfo},
+		{name: "debug in upper case", input: "DEBUG", expected: LevelDebug},
+		{name: "unknown level", input: "trace", expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			level, err := ParseLevel(tc.input)
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, level)
+		})
+	}
+}
+
+func TestLoggerLevels(t *testing.T) {
+	testCases := []struct {
+		name     string
+		level    Level
+		expected string
+	}{
+		{
+			name:     "error only",
+			level:    LevelError,
+			expected: "Error: e\n",
+		},
+		{
+			name:     "warn and above",
+			level:    LevelWarn,
+			expected: "Error: e\nWarning: w\n",
+		},
+		{
+			name:     "info and above",
+			level:    LevelInfo,
+			expected: "Error: e\nWarning: w\ni\n",
+		},
+		{
+			name:     "everything at debug",
+			level:    LevelDebug,
+			expected: "Error: e\nWarning: w\ni\ndebug: d\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			log := New(tc.level)
+			log.SetOutput(&buf)
+
+			log.Errorf("e")
+			log.Warnf("w")
+			log.Infof("i")
+			log.Debugf("d")
+
+			assert.Equal(t, tc.expected, buf.String())
+		})
+	}
+}
+
+type countingPauser struct {
+	pauses int
+}
+
+func (p *countingPauser) Pause(fn func()) {
+	p.pauses++
+	fn()
+}
+
+func TestLoggerPausesWhileWriting(t *testing.T) {
+	var buf bytes.Buffer
+	pauser := &countingPauser{}
+	log := New(LevelInfo)
+	log.SetOutput(&buf)
+	log.SetPauser(pauser)
+
+	log.Infof("shown")
+	log.Debugf("hidden")
+
+	assert.Equal(t, "shown\n", buf.String())
+	assert.Equal(t, 1, pauser.pauses, "only written messages pause the spinner")
+}
@@ -0,0 +1,172 @@
+package manager
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ernesto27/go-npm/config"
+	"github.com/ernesto27/go-npm/packagejson"
+)
+
+// deferredScript is a package whose lifecycle scripts run after a staged
+// node_modules has been swapped into place
+type deferredScript struct {
+	pkgName string
+	namePkg string // path below node_modules, e.g. a/node_modules/b
+	item    packagejson.PackageItem
+}
+
+// stagingPath returns the sibling directory a whole tree is staged into
+func stagingPath(nodeModulesPath string) string {
+	return filepath.Clean(nodeModulesPath) + ".tmp"
+}
+
+// copyPackageAtomic places a cached package at targetPath with the given
+// install strategy. The copy or symlink is made at a temporary sibling of
+// targetPath and renamed into place, so an interrupted install never leaves a
+// half-copied package behind. A leftover temporary copy from an earlier
+// interrupted run is discarded first.
+func (pm *PackageManager) copyPackageAtomic(srcPath, targetPath, strategy string) error {
+	tmpPath := targetPath + ".tmp"
+	if err := os.RemoveAll(tmpPath); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", tmpPath, err)
+	}
+
+	var err error
+	switch strategy {
+	case config.InstallStrategySymlink:
+		err = symlinkPackage(srcPath, tmpPath)
+	case config.InstallStrategyCopy:
+		err = pm.packageCopy.DeepCopyDirectory(srcPath, tmpPath)
+	default:
+		err = pm.packageCopy.CopyDirectory(srcPath, tmpPath)
+	}
+	if err != nil {
+		os.RemoveAll(tmpPath)
+		return err
+	}
+
+	if err := os.Rename(tmpPath, targetPath); err != nil {
+		os.RemoveAll(tmpPath)
+		return fmt.Errorf("failed to move %s into place: %w", targetPath, err)
+	}
+
+	return nil
+}
+
+// symlinkPackage links linkPath to the whole cached package directory
+func symlinkPackage(srcPath, linkPath string) error {
+	absSrc, err := filepath.Abs(srcPath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", srcPath, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(linkPath), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(linkPath), err)
+	}
+
+	if err := os.Symlink(absSrc, linkPath); err != nil {
+		return fmt.Errorf("failed to link %s: %w", linkPath, err)
+	}
+
+	return nil
+}
+
+// installStrategy returns how the package at lock path pkgPath is placed in
+// node_modules. Packages whose install scripts, or prepare script for git
+// dependencies, will run always get a private copy, since hardlinked or
+// symlinked files would let a script modify the cache. The symlink strategy
+// falls back to hardlinks for packages with nested node_modules, which would
+// otherwise be written into the cache, and for global installs, which must
+// survive a cache clean.
+func (pm *PackageManager) installStrategy(pkgPath, pkgName string, item packagejson.PackageItem, hasNested map[string]bool) string {
+	strategy := pm.config.InstallStrategy
+	if strategy == config.InstallStrategyCopy {
+		return strategy
+	}
+
+	if pm.lifecycleManager.WillRunPackageScripts(pkgName, item.Scripts) {
+		return config.InstallStrategyCopy
+	}
+	// prepareDependencies runs prepare in node_modules for git dependencies
+	if _, isGit := parseGitDependency(item.Resolved); isGit && pm.lifecycleManager.WillRunPrepare(pkgName, item.Scripts) {
+		return config.InstallStrategyCopy
+	}
+
+	if strategy == config.InstallStrategySymlink && (hasNested[pkgPath] || pm.isGlobal) {
+		return config.InstallStrategyHardlink
+	}
+
+	return strategy
+}
+
+// nestedParents returns the lock paths that have packages nested in their
+// own node_modules directory
+func nestedParents(packages map[string]packagejson.PackageItem) map[string]bool {
+	parents := make(map[string]bool)
+	for pkgPath := range packages {
+		if i := strings.LastIndex(pkgPath, "/node_modules/"); i >= 0 {
+			parents[pkgPath[:i]] = true
+		}
+	}
+	return parents
+}
+
+// installBatches groups lock package paths by nesting depth, outermost first
+func installBatches(packages map[string]packagejson.PackageItem) [][]string {
+	byDepth := make(map[int][]string)
+	for pkgPath := range packages {
+		if pkgPath == "" {
+			continue
+		}
+		depth := strings.Count(pkgPath, "/node_modules/")
+		byDepth[depth] = append(byDepth[depth], pkgPath)
+	}
+
+	depths := make([]int, 0, len(byDepth))
+	for depth := range byDepth {
+		depths = append(depths, depth)
+	}
+	sort.Ints(depths)
+
+	batches := make([][]string, 0, len(depths))
+	for _, depth := range depths {
+		sort.Strings(byDepth[depth])
+		batches = append(batches, byDepth[depth])
+	}
+	return batches
+}
+
+// swapNodeModules replaces nodeModulesPath with the complete tree at
+// stagedPath. The previous tree is moved aside first and restored if the
+// staged tree cannot be moved into place.
+func swapNodeModules(stagedPath, nodeModulesPath string) error {
+	oldPath := filepath.Clean(nodeModulesPath) + ".old"
+	if err := os.RemoveAll(oldPath); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", oldPath, err)
+	}
+
+	hadPrevious := false
+	if _, err := os.Lstat(nodeModulesPath); err == nil {
+		if err := os.Rename(nodeModulesPath, oldPath); err != nil {
+			return fmt.Errorf("failed to move %s aside: %w", nodeModulesPath, err)
+		}
+		hadPrevious = true
+	}
+
+	if err := os.Rename(stagedPath, nodeModulesPath); err != nil {
+		if hadPrevious {
+			os.Rename(oldPath, nodeModulesPath)
+		}
+		return fmt.Errorf("failed to move staged %s into place: %w", stagedPath, err)
+	}
+
+	if err := os.RemoveAll(oldPath); err != nil {
+		return fmt.Errorf("failed to remove previous %s: %w", oldPath, err)
+	}
+
+	return nil
+}
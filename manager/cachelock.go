@@ -0,0 +1,43 @@
+package manager
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/ernesto27/go-npm/utils"
+)
+
+// lockPackage serializes work on a package@version cache entry within this
+// process and, with CacheLock set, across every go-npm process sharing the
+// cache. The returned function releases both locks.
+func (pm *PackageManager) lockPackage(packageKey string) (func(), error) {
+	pm.downloadMu.Lock()
+	packageLock, exists := pm.downloadLocks[packageKey]
+	if !exists {
+		packageLock = &sync.Mutex{}
+		pm.downloadLocks[packageKey] = packageLock
+	}
+	pm.downloadMu.Unlock()
+
+	packageLock.Lock()
+	if !pm.config.CacheLock {
+		return packageLock.Unlock, nil
+	}
+
+	unlockFile, err := utils.LockFile(cacheLockPath(pm.config.BaseDir, packageKey))
+	if err != nil {
+		packageLock.Unlock()
+		return nil, err
+	}
+	return func() {
+		unlockFile()
+		packageLock.Unlock()
+	}, nil
+}
+
+// cacheLockPath is the lock file of a package@version, with the scope
+// separator flattened so scoped packages share the locks directory
+func cacheLockPath(baseDir, packageKey string) string {
+	return filepath.Join(baseDir, "locks", strings.ReplaceAll(packageKey, "/", "+")+".lock")
+}
@@ -0,0 +1,143 @@
+package manager
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// ConflictRange is one range requested for a conflicting package
+type ConflictRange struct {
+	Parent    string // lock path of the dependent, or "package.json"
+	Range     string
+	Satisfied bool // whether the forced version satisfies Range
+}
+
+// VersionConflict is a package whose requested ranges no single published
+// version satisfies, so the installer has to nest several copies of it
+type VersionConflict struct {
+	Name   string
+	Ranges []ConflictRange
+	Chosen string // forced version, empty unless --force-resolutions is set
+}
+
+// findConflicts returns the packages in requested (name -> ranges) that no
+// single version in available (name -> published versions) satisfies
+func (pm *PackageManager) findConflicts(requested map[string][]ConflictRange, available map[string][]string) []VersionConflict {
+	var conflicts []VersionConflict
+
+	for name, ranges := range requested {
+		if len(ranges) < 2 {
+			continue
+		}
+
+		_, satisfied := pm.bestVersion(ranges, available[name])
+		if satisfied == len(ranges) {
+			continue
+		}
+
+		sorted := append([]ConflictRange(nil), ranges...)
+		sort.Slice(sorted, func(i, j int) bool {
+			if sorted[i].Range != sorted[j].Range {
+				return sorted[i].Range < sorted[j].Range
+			}
+			return sorted[i].Parent < sorted[j].Parent
+		})
+		conflicts = append(conflicts, VersionConflict{Name: name, Ranges: sorted})
+	}
+
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].Name < conflicts[j].Name
+	})
+	return conflicts
+}
+
+// forceResolutions picks, for every conflict, the highest version that
+// satisfies the most ranges, marks which ranges it satisfies and returns the
+// chosen versions by name. Conflicts where no version satisfies any range are
+// left to the normal resolution.
+func (pm *PackageManager) forceResolutions(conflicts []VersionConflict, available map[string][]string) map[string]string {
+	forced := make(map[string]string)
+
+	for i := range conflicts {
+		conflict := &conflicts[i]
+		chosen, satisfied := pm.bestVersion(conflict.Ranges, available[conflict.Name])
+		if satisfied == 0 {
+			continue
+		}
+
+		conflict.Chosen = chosen
+		for j := range conflict.Ranges {
+			conflict.Ranges[j].Satisfied = pm.versionInfo.SatisfiesConstraint(chosen, conflict.Ranges[j].Range)
+		}
+		forced[conflict.Name] = chosen
+	}
+
+	return forced
+}
+
+// bestVersion returns the highest of versions satisfying the most ranges and
+// how many ranges it satisfies
+func (pm *PackageManager) bestVersion(ranges []ConflictRange, versions []string) (string, int) {
+	var candidates []*semver.Version
+	for _, v := range versions {
+		if parsed, err := semver.NewVersion(v); err == nil {
+			candidates = append(candidates, parsed)
+		}
+	}
+	sort.Sort(sort.Reverse(semver.Collection(candidates)))
+
+	best, bestCount := "", 0
+	for _, candidate := range candidates {
+		count := 0
+		for _, r := range ranges {
+			if pm.versionInfo.SatisfiesConstraint(candidate.Original(), r.Range) {
+				count++
+			}
+		}
+		if count > bestCount {
+			best, bestCount = candidate.Original(), count
+		}
+	}
+
+	return best, bestCount
+}
+
+// isRemoteSpec reports whether spec points at a git repository, a tarball
+// URL or a local directory instead of a registry range
+func isRemoteSpec(spec string) bool {
+	if _, ok := parseGitHubDependency(spec); ok {
+		return true
+	}
+	if _, ok := parseGitDependency(spec); ok {
+		return true
+	}
+	if _, ok := parseFileDependency(spec); ok {
+		return true
+	}
+	return isTarballURL(spec)
+}
+
+// printConflicts reports each conflict with the ranges that caused it and,
+// when forced, the chosen version and the ranges it leaves unsatisfied
+func printConflicts(w io.Writer, conflicts []VersionConflict) {
+	fmt.Fprintln(w, "\n⚠️  Version conflicts:")
+	for _, conflict := range conflicts {
+		if conflict.Chosen != "" {
+			fmt.Fprintf(w, "   %s: forced to %s\n", conflict.Name, conflict.Chosen)
+		} else {
+			fmt.Fprintf(w, "   %s: no single version satisfies all ranges\n", conflict.Name)
+		}
+
+		for _, r := range conflict.Ranges {
+			status := ""
+			if conflict.Chosen != "" && !r.Satisfied {
+				status = " (unsatisfied)"
+			}
+			fmt.Fprintf(w, "     %s from %s%s\n", r.Range, r.Parent, status)
+		}
+	}
+	fmt.Fprintln(w)
+}
@@ -0,0 +1,46 @@
+package manager
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Deprecation is a resolved package version its publisher deprecated
+type Deprecation struct {
+	Name    string
+	Version string
+	Message string
+}
+
+// String returns the name@version of the deprecated package
+func (d Deprecation) String() string {
+	return d.Name + "@" + d.Version
+}
+
+// Warning returns the warning printed when the version is resolved
+func (d Deprecation) Warning() string {
+	return fmt.Sprintf("%s is deprecated: %s", d, d.Message)
+}
+
+// sortDeprecations returns the deprecations in byKey sorted by name@version
+func sortDeprecations(byKey map[string]Deprecation) []Deprecation {
+	deprecations := make([]Deprecation, 0, len(byKey))
+	for _, deprecation := range byKey {
+		deprecations = append(deprecations, deprecation)
+	}
+	sort.Slice(deprecations, func(i, j int) bool {
+		return deprecations[i].String() < deprecations[j].String()
+	})
+	return deprecations
+}
+
+// printDeprecations prints the summary of deprecated packages at the end of
+// the install
+func printDeprecations(w io.Writer, deprecations []Deprecation) {
+	fmt.Fprintln(w, "\n⚠️  Deprecated packages:")
+	for _, deprecation := range deprecations {
+		fmt.Fprintf(w, "   %s: %s\n", deprecation, deprecation.Message)
+	}
+	fmt.Fprintln(w)
+}
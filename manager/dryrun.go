@@ -0,0 +1,136 @@
+package manager
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ernesto27/go-npm/config"
+	"github.com/ernesto27/go-npm/progress"
+	"github.com/ernesto27/go-npm/tarball"
+	"github.com/ernesto27/go-npm/utils"
+)
+
+// scratchCache returns a copy of cfg whose cache directories are in a new
+// temporary directory, so that a dry run can resolve and download packages
+// without writing to the real cache
+func scratchCache(cfg *config.Config) (*config.Config, error) {
+	dir, err := os.MkdirTemp("", "go-npm-dry-run-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create scratch cache: %w", err)
+	}
+
+	scratch := *cfg
+	scratch.BaseDir = dir
+	scratch.ManifestDir = filepath.Join(dir, "manifest")
+	scratch.TarballDir = filepath.Join(dir, "tarball")
+	scratch.PackagesDir = filepath.Join(dir, "packages")
+	scratch.StoreDir = filepath.Join(dir, "store")
+	for _, d := range []string{scratch.ManifestDir, scratch.TarballDir, scratch.PackagesDir, scratch.StoreDir, filepath.Join(dir, "etag")} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			os.RemoveAll(dir)
+			return nil, fmt.Errorf("failed to create scratch cache: %w", err)
+		}
+	}
+
+	return &scratch, nil
+}
+
+// Close removes the scratch cache of a dry run
+func (pm *PackageManager) Close() error {
+	if pm.cache == nil {
+		return nil
+	}
+	return os.RemoveAll(pm.config.BaseDir)
+}
+
+// dryRunSummary is the summary of the command with the packages it would
+// install, those added or updated in the lock or missing from node_modules,
+// and the ones of them it would download because the real cache does not
+// hold them yet
+func (pm *PackageManager) dryRunSummary() progress.Summary {
+	summary := pm.summary()
+	summary.DryRun = true
+	summary.Installs = []progress.Change{}
+	summary.Downloads = []progress.Change{}
+
+	if pm.packageLock == nil {
+		return summary
+	}
+	for pkgPath, item := range pm.packageLock.Packages {
+		if pkgPath == "" || item.Link || item.Skipped || pm.ignoredOptional(item) {
+			continue
+		}
+		change := progress.Change{Name: lockPathName(pkgPath), Version: item.Version}
+		previous, existed := pm.previousPackages[pkgPath]
+		if existed && previous.Version != item.Version {
+			change.Previous = previous.Version
+		} else if existed && utils.FolderExists(filepath.Join(pm.extractedPath, strings.TrimPrefix(pkgPath, "node_modules/"))) {
+			continue
+		}
+
+		summary.Installs = append(summary.Installs, change)
+		if !pm.inRealCache(change.Name, tarball.CacheVersion(change.Name, item.Version, item.Resolved), item.Integrity) {
+			summary.Downloads = append(summary.Downloads, progress.Change{Name: change.Name, Version: item.Version})
+		}
+	}
+	for _, changes := range [][]progress.Change{summary.Installs, summary.Downloads} {
+		sort.Slice(changes, func(i, j int) bool {
+			if changes[i].Name != changes[j].Name {
+				return changes[i].Name < changes[j].Name
+			}
+			return changes[i].Version < changes[j].Version
+		})
+	}
+	return summary
+}
+
+// inRealCache reports whether the real cache of a dry run holds name@version
+func (pm *PackageManager) inRealCache(name, version, sri string) bool {
+	if pm.cache == nil {
+		return false
+	}
+	if utils.FolderExists(filepath.Join(pm.cache.PackagesDir, name+"@"+version)) {
+		return true
+	}
+	if pm.cache.ContentStore {
+		if dir, ok := contentStorePath(pm.cache.StoreDir, sri); ok && utils.FolderExists(dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// FinishDryRun prints what a --dry-run command would have done: the
+// packages it would download, install in node_modules and remove from it. With --json it prints the summary with the downloads.
+func (pm *PackageManager) FinishDryRun() {
+	summary := pm.dryRunSummary()
+	if pm.progress.JSON() {
+		pm.progress.SetSummary(summary)
+		pm.progress.Finish()
+		return
+	}
+
+	pm.progress.Stop()
+	printChanges := func(title string, changes []progress.Change) {
+		if len(changes) == 0 {
+			return
+		}
+		fmt.Printf("%s:\n", title)
+		for _, change := range changes {
+			if change.Previous != "" {
+				fmt.Printf("  %s@%s (from %s)\n", change.Name, change.Version, change.Previous)
+			} else {
+				fmt.Printf("  %s@%s\n", change.Name, change.Version)
+			}
+		}
+	}
+	printChanges("Would download", summary.Downloads)
+	printChanges("Would install", summary.Installs)
+	printChanges("Would remove", summary.Removed)
+
+	fmt.Printf("Dry run: %d to download, %d to install, %d to remove; no files were changed\n",
+		len(summary.Downloads), len(summary.Installs), len(summary.Removed))
+}
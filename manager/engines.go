@@ -0,0 +1,56 @@
+package manager
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/ernesto27/go-npm/progress"
+)
+
+// detectNodeVersion returns the version of the node binary on PATH without the
+// leading "v", or an empty string when node is not installed
+func detectNodeVersion() string {
+	output, err := exec.Command("node", "--version").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.TrimSpace(string(output)), "v")
+}
+
+// engineNodeRange returns the engines.node range of a package.json or manifest
+// version. Legacy array-style engines fields are ignored.
+func engineNodeRange(engines any) string {
+	m, ok := engines.(map[string]any)
+	if !ok {
+		return ""
+	}
+	nodeRange, _ := m["node"].(string)
+	return strings.TrimSpace(nodeRange)
+}
+
+// checkEngine compares the engines.node range of pkgName@version against the
+// installed node. It returns an error under engine-strict and only warns
+// otherwise; the check is skipped when node cannot be found.
+func (pm *PackageManager) checkEngine(pkgName, version string, engines any) error {
+	nodeRange := engineNodeRange(engines)
+	if nodeRange == "" {
+		return nil
+	}
+
+	nodeVersion := pm.nodeVersion()
+	if nodeVersion == "" {
+		return nil
+	}
+
+	if pm.versionInfo.SatisfiesConstraint(nodeVersion, nodeRange) {
+		return nil
+	}
+
+	if pm.config.EngineStrict {
+		return fmt.Errorf("unsupported engine for %s@%s: wanted node %s (current: %s)", pkgName, version, nodeRange, nodeVersion)
+	}
+
+	pm.warn(progress.WarningEngine, "unsupported engine for %s@%s: wanted node %s (current: %s)", pkgName, version, nodeRange, nodeVersion)
+	return nil
+}
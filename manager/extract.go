@@ -0,0 +1,76 @@
+package manager
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// extractPackage extracts tarballPath into dest and normalizes the layout of
+// tarballs that nest the package one directory deeper than package/
+func (pm *PackageManager) extractPackage(tarballPath, dest string) error {
+	if err := pm.extractor.Extract(tarballPath, dest); err != nil {
+		return err
+	}
+	if _, err := normalizePackageRoot(dest); err != nil {
+		return fmt.Errorf("failed to normalize %s: %w", dest, err)
+	}
+	return nil
+}
+
+// normalizePackageRoot moves the contents of the only subdirectory of dir
+// holding a package.json up into dir, when dir has no package.json itself.
+// It reports whether anything was moved.
+func normalizePackageRoot(dir string) (bool, error) {
+	if _, err := os.Stat(filepath.Join(dir, "package.json")); err == nil || !os.IsNotExist(err) {
+		return false, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false, err
+	}
+	nested := ""
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dir, entry.Name(), "package.json")); err != nil {
+			continue
+		}
+		if nested != "" {
+			// Several candidates, so there is no telling which one is the package
+			return false, nil
+		}
+		nested = entry.Name()
+	}
+	if nested == "" {
+		return false, nil
+	}
+
+	// Rename first so an entry named like the subdirectory can move up
+	tmpDir, err := os.MkdirTemp(dir, ".nested-")
+	if err != nil {
+		return false, err
+	}
+	src := filepath.Join(tmpDir, nested)
+	if err := os.Rename(filepath.Join(dir, nested), src); err != nil {
+		os.Remove(tmpDir)
+		return false, err
+	}
+
+	inner, err := os.ReadDir(src)
+	if err != nil {
+		return false, err
+	}
+	for _, entry := range inner {
+		target := filepath.Join(dir, entry.Name())
+		if _, err := os.Lstat(target); err == nil {
+			return false, fmt.Errorf("%s already exists", target)
+		}
+		if err := os.Rename(filepath.Join(src, entry.Name()), target); err != nil {
+			return false, err
+		}
+	}
+	return true, os.RemoveAll(tmpDir)
+}
@@ -0,0 +1,27 @@
+package manager
+
+import (
+	"maps"
+	"slices"
+
+	manifestpkg "github.com/ernesto27/go-npm/manifest"
+)
+
+// maxTarballFallbacks bounds how many versions of a package whose tarball is
+// gone from the registry are skipped before the install fails
+const maxTarballFallbacks = 3
+
+// withoutVersions returns a copy of npmPackage without versions, or the
+// dist-tags pointing at them, so resolving it picks the next best version
+func withoutVersions(npmPackage *manifestpkg.NPMPackage, versions []string) *manifestpkg.NPMPackage {
+	pruned := *npmPackage
+	pruned.Versions = maps.Clone(npmPackage.Versions)
+	pruned.DistTags = maps.Clone(npmPackage.DistTags)
+	for _, version := range versions {
+		delete(pruned.Versions, version)
+	}
+	maps.DeleteFunc(pruned.DistTags, func(_, version string) bool {
+		return slices.Contains(versions, version)
+	})
+	return &pruned
+}
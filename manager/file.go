@@ -0,0 +1,108 @@
+package manager
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ernesto27/go-npm/pack"
+	"github.com/ernesto27/go-npm/workspace"
+)
+
+// parseFileDependency returns the directory of a file: dependency spec,
+// e.g. "file:../shared" -> "../shared"
+func parseFileDependency(spec string) (string, bool) {
+	dir, ok := strings.CutPrefix(spec, "file:")
+	if !ok || dir == "" {
+		return "", false
+	}
+	return dir, true
+}
+
+// placeLocalPackage puts the local package directory srcPath at
+// node_modules/pkgName: symlinked by default, or copied with --install-links,
+// with only its published files for --pack-links. A copy from an earlier
+// install is refreshed, and a symlink or copy left by the other mode is
+// replaced.
+func (pm *PackageManager) placeLocalPackage(pkgName, srcPath string) error {
+	targetPath := filepath.Join(pm.extractedPath, pkgName)
+
+	if !pm.config.InstallLinks {
+		if info, err := os.Lstat(targetPath); err == nil && info.Mode()&os.ModeSymlink == 0 {
+			if err := os.RemoveAll(targetPath); err != nil {
+				return fmt.Errorf("failed to remove %s: %w", targetPath, err)
+			}
+		}
+		return workspace.SymlinkPackage(pm.extractedPath, pkgName, srcPath)
+	}
+
+	tmpPath := targetPath + ".tmp"
+	if err := os.RemoveAll(tmpPath); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", tmpPath, err)
+	}
+
+	if err := pm.copyLocalPackage(srcPath, tmpPath); err != nil {
+		os.RemoveAll(tmpPath)
+		return fmt.Errorf("failed to copy %s: %w", pkgName, err)
+	}
+
+	if err := os.RemoveAll(targetPath); err != nil {
+		os.RemoveAll(tmpPath)
+		return fmt.Errorf("failed to remove %s: %w", targetPath, err)
+	}
+
+	if err := os.Rename(tmpPath, targetPath); err != nil {
+		os.RemoveAll(tmpPath)
+		return fmt.Errorf("failed to move %s into place: %w", targetPath, err)
+	}
+
+	return nil
+}
+
+// copyLocalPackage copies the local package srcPath to dst, whole but for
+// node_modules and .git, or for --pack-links only the files it would publish
+func (pm *PackageManager) copyLocalPackage(srcPath, dst string) error {
+	if !pm.config.PackLinks {
+		return pm.packageCopy.CopySource(srcPath, dst)
+	}
+
+	pkgJSON, err := pm.packageJsonParse.Parse(filepath.Join(srcPath, "package.json"))
+	if err != nil {
+		return err
+	}
+	files, err := pack.Files(srcPath, pkgJSON)
+	if err != nil {
+		return err
+	}
+	return pm.packageCopy.CopyFiles(srcPath, dst, files)
+}
+
+// placeFileDependencies places the file: dependencies recorded in the lock.
+// Workspaces are placed by CreateWorkspaceSymlinks.
+func (pm *PackageManager) placeFileDependencies() error {
+	for pkgPath, item := range pm.packageLock.Packages {
+		if !item.Link {
+			continue
+		}
+
+		pkgName, ok := strings.CutPrefix(pkgPath, "node_modules/")
+		if !ok || strings.Contains(pkgName, "/node_modules/") {
+			continue
+		}
+		if pm.workspaceRegistry != nil && pm.workspaceRegistry.IsWorkspacePackage(pkgName) {
+			continue
+		}
+
+		dir, isFile := parseFileDependency(item.Resolved)
+		if !isFile {
+			continue
+		}
+
+		if err := pm.placeLocalPackage(pkgName, dir); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,262 @@
+package manager
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// GitDependency represents a dependency on a git repository given as a full
+// URL, e.g. git+https://github.com/owner/repo.git#v1.0.0 or
+// git+ssh://git@host/owner/repo.git#semver:^1.0.0
+type GitDependency struct {
+	URL         string // the spec without its #fragment
+	Protocol    string // https, http, ssh, git or file
+	Host        string // host without user or port, empty for file URLs
+	Path        string // repository path without the leading slash and .git suffix
+	CloneURL    string // URL understood by git clone and git ls-remote
+	Ref         string // tag, branch, or commit SHA (empty for the default branch)
+	SemverRange string // set for #semver:<range> fragments, resolved against tags
+}
+
+var (
+	gitCommitPattern = regexp.MustCompile(`^[0-9a-f]{40}$`)
+	// scpLikePattern matches the scp-style form npm accepts after git+ssh://,
+	// e.g. git@github.com:owner/repo.git
+	scpLikePattern = regexp.MustCompile(`^([^@/]+@)?([^:/]+):([^/].*)$`)
+)
+
+// parseGitDependency parses git+https, git+http, git+ssh, git+file and git:// specs
+func parseGitDependency(spec string) (*GitDependency, bool) {
+	var protocol, rest string
+	switch {
+	case strings.HasPrefix(spec, "git+https://"):
+		protocol, rest = "https", strings.TrimPrefix(spec, "git+https://")
+	case strings.HasPrefix(spec, "git+http://"):
+		protocol, rest = "http", strings.TrimPrefix(spec, "git+http://")
+	case strings.HasPrefix(spec, "git+ssh://"):
+		protocol, rest = "ssh", strings.TrimPrefix(spec, "git+ssh://")
+	case strings.HasPrefix(spec, "git+file://"):
+		protocol, rest = "file", strings.TrimPrefix(spec, "git+file://")
+	case strings.HasPrefix(spec, "git://"):
+		protocol, rest = "git", strings.TrimPrefix(spec, "git://")
+	default:
+		return nil, false
+	}
+
+	location, fragment, _ := strings.Cut(rest, "#")
+	if location == "" {
+		return nil, false
+	}
+
+	dep := &GitDependency{URL: strings.TrimSuffix(spec, "#"+fragment), Protocol: protocol}
+	if strings.HasPrefix(fragment, "semver:") {
+		dep.SemverRange = strings.TrimPrefix(fragment, "semver:")
+	} else {
+		dep.Ref = fragment
+	}
+
+	if protocol == "file" {
+		dep.Path = strings.TrimSuffix(location, ".git")
+		dep.CloneURL = "file://" + location
+		return dep, true
+	}
+
+	// git+ssh://git@host:owner/repo.git uses ":" instead of "/" before the path
+	if protocol == "ssh" {
+		if m := scpLikePattern.FindStringSubmatch(location); m != nil {
+			dep.Host = m[2]
+			dep.Path = strings.TrimSuffix(m[3], ".git")
+			dep.CloneURL = m[1] + m[2] + ":" + m[3]
+			if optionLike(dep.CloneURL) || optionLike(dep.Host) {
+				return nil, false
+			}
+			return dep, true
+		}
+	}
+
+	u, err := url.Parse(protocol + "://" + location)
+	if err != nil || u.Host == "" {
+		return nil, false
+	}
+
+	dep.Host = u.Hostname()
+	dep.Path = strings.TrimSuffix(strings.TrimPrefix(u.Path, "/"), ".git")
+	if dep.Path == "" {
+		return nil, false
+	}
+	dep.CloneURL = u.String()
+
+	// A host starting with "-" reaches ssh as an option
+	if optionLike(dep.Host) {
+		return nil, false
+	}
+
+	return dep, true
+}
+
+// optionLike reports whether git or ssh would read s as an option
+func optionLike(s string) bool {
+	return strings.HasPrefix(s, "-")
+}
+
+// ResolvedURL returns the lock file "resolved" URL pinned to commitSHA
+func (d *GitDependency) ResolvedURL(commitSHA string) string {
+	return d.URL + "#" + commitSHA
+}
+
+// ArchiveURL returns a tarball URL for commitSHA on hosts that serve one.
+// Other hosts have to be cloned.
+func (d *GitDependency) ArchiveURL(commitSHA string) (string, bool) {
+	owner, repo, ok := strings.Cut(d.Path, "/")
+	if !ok || strings.Contains(repo, "/") {
+		return "", false
+	}
+
+	switch d.Host {
+	case "github.com":
+		return buildGitHubTarballURL(owner, repo, commitSHA), true
+	case "gitlab.com":
+		return fmt.Sprintf("https://gitlab.com/%s/%s/-/archive/%s/%s-%s.tar.gz", owner, repo, commitSHA, repo, commitSHA), true
+	case "bitbucket.org":
+		return fmt.Sprintf("https://bitbucket.org/%s/%s/get/%s.tar.gz", owner, repo, commitSHA), true
+	}
+
+	return "", false
+}
+
+// resolveGitRef resolves the dependency's ref or semver range to a full
+// commit SHA using git ls-remote. A full SHA is returned as is.
+func resolveGitRef(dep *GitDependency) (string, error) {
+	if gitCommitPattern.MatchString(dep.Ref) {
+		return dep.Ref, nil
+	}
+
+	output, err := exec.Command("git", "ls-remote", "--", dep.CloneURL).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to list refs of %s: %w", dep.CloneURL, err)
+	}
+
+	return selectGitRef(parseLsRemote(string(output)), dep)
+}
+
+// parseLsRemote maps ref names to commit SHAs from git ls-remote output.
+// Annotated tags are replaced by the commit they point to (the ^{} entry).
+func parseLsRemote(output string) map[string]string {
+	refs := make(map[string]string)
+	peeled := make(map[string]string)
+
+	for _, line := range strings.Split(output, "\n") {
+		sha, ref, ok := strings.Cut(strings.TrimSpace(line), "\t")
+		if !ok {
+			continue
+		}
+		if name, isPeeled := strings.CutSuffix(ref, "^{}"); isPeeled {
+			peeled[name] = sha
+			continue
+		}
+		refs[ref] = sha
+	}
+
+	for name, sha := range peeled {
+		refs[name] = sha
+	}
+
+	return refs
+}
+
+// selectGitRef picks the commit for dep from refs: the highest tag matching a
+// semver range, a tag or branch named by Ref, or HEAD when Ref is empty
+func selectGitRef(refs map[string]string, dep *GitDependency) (string, error) {
+	if dep.SemverRange != "" {
+		constraint, err := semver.NewConstraint(dep.SemverRange)
+		if err != nil {
+			return "", fmt.Errorf("invalid semver range %q for %s: %w", dep.SemverRange, dep.CloneURL, err)
+		}
+
+		var best *semver.Version
+		bestSHA := ""
+		for ref, sha := range refs {
+			tag, isTag := strings.CutPrefix(ref, "refs/tags/")
+			if !isTag {
+				continue
+			}
+			v, err := semver.NewVersion(tag)
+			if err != nil || !constraint.Check(v) {
+				continue
+			}
+			if best == nil || v.GreaterThan(best) {
+				best, bestSHA = v, sha
+			}
+		}
+
+		if best == nil {
+			return "", fmt.Errorf("no tag of %s satisfies semver:%s", dep.CloneURL, dep.SemverRange)
+		}
+		return bestSHA, nil
+	}
+
+	if dep.Ref == "" {
+		if sha, ok := refs["HEAD"]; ok {
+			return sha, nil
+		}
+		return "", fmt.Errorf("no HEAD ref found for %s", dep.CloneURL)
+	}
+
+	for _, candidate := range []string{dep.Ref, "refs/tags/" + dep.Ref, "refs/heads/" + dep.Ref} {
+		if sha, ok := refs[candidate]; ok {
+			return sha, nil
+		}
+	}
+
+	return "", fmt.Errorf("ref %q not found in %s", dep.Ref, dep.CloneURL)
+}
+
+// cloneGitPackage checks out commitSHA of cloneURL into dest, without the
+// .git directory, for hosts that have no tarball endpoint
+func cloneGitPackage(cloneURL, commitSHA, dest string) error {
+	// The commit comes from the lock; only a full SHA is checked out
+	if !gitCommitPattern.MatchString(commitSHA) {
+		return fmt.Errorf("invalid commit %q for %s: expected a full commit SHA", commitSHA, cloneURL)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(dest), err)
+	}
+
+	tmpDir, err := os.MkdirTemp(filepath.Dir(dest), ".git-clone-*")
+	if err != nil {
+		return fmt.Errorf("failed to create clone directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if output, err := exec.Command("git", "clone", "--quiet", "--", cloneURL, tmpDir).CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to clone %s: %w: %s", cloneURL, err, strings.TrimSpace(string(output)))
+	}
+
+	// The trailing "--" makes git read commitSHA as a revision, never a path
+	if output, err := exec.Command("git", "-C", tmpDir, "checkout", "--quiet", commitSHA, "--").CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to checkout %s in %s: %w: %s", commitSHA, cloneURL, err, strings.TrimSpace(string(output)))
+	}
+
+	if err := os.RemoveAll(filepath.Join(tmpDir, ".git")); err != nil {
+		return fmt.Errorf("failed to clean clone of %s: %w", cloneURL, err)
+	}
+
+	// MkdirTemp creates 0700 directories; cached packages are world-readable
+	if err := os.Chmod(tmpDir, 0755); err != nil {
+		return fmt.Errorf("failed to set permissions on clone of %s: %w", cloneURL, err)
+	}
+
+	if err := os.Rename(tmpDir, dest); err != nil {
+		return fmt.Errorf("failed to move clone of %s to %s: %w", cloneURL, dest, err)
+	}
+
+	return nil
+}
@@ -65,7 +65,7 @@ func buildGitHubResolvedURL(owner, repo, commitSHA string) string {
 	return fmt.Sprintf("git+ssh://git@github.com/%s/%s.git#%s", owner, repo, commitSHA)
 }
 
-// convertGitURLToTarball converts git URLs to GitHub tarball URLs
+// convertGitURLToTarball converts git URLs to GitHub (or GitLab/Bitbucket) tarball URLs
 // Handles formats like:
 // - git+ssh://git@github.com/owner/repo.git#commit
 // - git+https://github.com/owner/repo.git#commit
@@ -79,6 +79,12 @@ func convertGitURLToTarball(gitURL string) (tarballURL string, filename string,
 
 	matches := gitPattern.FindStringSubmatch(gitURL)
 	if len(matches) != 4 {
+		// Other hosts that serve archives (GitLab, Bitbucket) for a locked commit
+		if gitDep, ok := parseGitDependency(gitURL); ok && gitCommitPattern.MatchString(gitDep.Ref) {
+			if archiveURL, ok := gitDep.ArchiveURL(gitDep.Ref); ok {
+				return archiveURL, fmt.Sprintf("%s.tar.gz", gitDep.Ref), true
+			}
+		}
 		return "", "", false
 	}
 
@@ -0,0 +1,82 @@
+package manager
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/ernesto27/go-npm/integrity"
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/ernesto27/go-npm/tarball"
+	"github.com/ernesto27/go-npm/utils"
+)
+
+// upgradeLockIntegrity gives the registry packages of the lock whose
+// integrity is sha1-only or missing, as in locks migrated from older tools, a
+// sha512 integrity computed from their cached tarball, and rewrites the lock.
+// Packages whose tarball is no longer cached keep their integrity until an
+// install downloads it again, so the lock moves to strong hashes without a
+// full reinstall and without going online.
+func (pm *PackageManager) upgradeLockIntegrity() error {
+	upgraded := make(map[string]string)
+
+	for pkgPath, item := range pm.packageLock.Packages {
+		if item.Link || integrity.IsStrong(item.Integrity) || !isRegistryTarball(item.Resolved) {
+			continue
+		}
+
+		pkgName := strings.TrimPrefix(pkgPath, "node_modules/")
+		if i := strings.LastIndex(pkgName, "/node_modules/"); i >= 0 {
+			pkgName = pkgName[i+len("/node_modules/"):]
+		}
+
+		tarballPath := filepath.Join(pm.tarball.TarballPath, generateUniqueTarballName(pkgName, tarball.CacheVersion(pkgName, item.Version, item.Resolved)))
+		if !utils.ValidateTarball(tarballPath) {
+			continue
+		}
+
+		sri, err := integrity.Upgrade(tarballPath, item.Integrity)
+		if err != nil {
+			return fmt.Errorf("SECURITY: integrity check failed for %s@%s: %w", pkgName, item.Version, err)
+		}
+		upgraded[pkgPath] = sri
+	}
+
+	if len(upgraded) == 0 {
+		return nil
+	}
+
+	// A workspace-filtered install works on a subtree of the parsed lock, so
+	// the entries are upgraded in both and the whole lock is written
+	lock := pm.packageJsonParse.PackageLock
+	if lock == nil {
+		lock = pm.packageLock
+	}
+	for pkgPath, sri := range upgraded {
+		for _, packages := range []map[string]packagejson.PackageItem{pm.packageLock.Packages, lock.Packages} {
+			if item, ok := packages[pkgPath]; ok {
+				item.Integrity = sri
+				packages[pkgPath] = item
+			}
+		}
+	}
+
+	if err := pm.packageJsonParse.CreateLockFile(lock, false); err != nil {
+		return err
+	}
+	pm.logger.Infof("Upgraded the integrity of %d packages to sha512", len(upgraded))
+	return nil
+}
+
+// isRegistryTarball reports whether resolved is a tarball downloaded over
+// http(s) rather than a git, file: or linked dependency
+func isRegistryTarball(resolved string) bool {
+	if !strings.HasPrefix(resolved, "https://") && !strings.HasPrefix(resolved, "http://") {
+		return false
+	}
+	if _, _, isGit := convertGitURLToTarball(resolved); isGit {
+		return false
+	}
+	_, isGit := parseGitDependency(resolved)
+	return !isGit
+}
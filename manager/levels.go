@@ -0,0 +1,87 @@
+package manager
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// sortLevel orders a level of the dependency tree by the path of the package
+// requiring each item, then by name, so the items are placed in the same
+// order on every run whichever worker queued them
+func sortLevel(level []QueueItem) {
+	slices.SortStableFunc(level, func(a, b QueueItem) int {
+		return cmp.Or(
+			cmp.Compare(a.ParentName, b.ParentName),
+			cmp.Compare(a.Dep.Name, b.Dep.Name),
+			cmp.Compare(a.Dep.Version, b.Dep.Version),
+			compareBool(a.IsDev, b.IsDev),
+			compareBool(a.IsOptional, b.IsOptional),
+			compareBool(a.IsPeer, b.IsPeer),
+			cmp.Compare(strings.Join(a.Path, " "), strings.Join(b.Path, " ")),
+		)
+	})
+}
+
+func compareBool(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case !a:
+		return -1
+	}
+	return 1
+}
+
+// turns lets the workers of a level take a step, placing their package in
+// the tree, in the order of the level: index i waits until every lower index
+// has finished its turn. The rest of the work runs in parallel.
+type turns struct {
+	mu       sync.Mutex
+	cond     *sync.Cond
+	next     int
+	finished map[int]bool
+	stopped  bool
+}
+
+func newTurns() *turns {
+	t := &turns{finished: make(map[int]bool)}
+	t.cond = sync.NewCond(&t.mu)
+	return t
+}
+
+// wait blocks until it is index's turn. It returns false once the level is
+// stopped, when the install failed or was interrupted.
+func (t *turns) wait(index int) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for t.next != index && !t.stopped {
+		t.cond.Wait()
+	}
+	return !t.stopped
+}
+
+// finish ends index's turn, or gives it up when index returns without taking
+// it. Finishing twice is harmless.
+func (t *turns) finish(index int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if index < t.next {
+		return
+	}
+	t.finished[index] = true
+	for t.finished[t.next] {
+		delete(t.finished, t.next)
+		t.next++
+	}
+	t.cond.Broadcast()
+}
+
+// stop wakes every waiting worker and makes wait return false
+func (t *turns) stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stopped = true
+	t.cond.Broadcast()
+}
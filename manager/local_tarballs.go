@@ -0,0 +1,164 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	manifestpkg "github.com/ernesto27/go-npm/manifest"
+	"github.com/ernesto27/go-npm/utils"
+)
+
+// ErrNoLocalTarball reports a package with no matching tarball in the local
+// tarball directory
+var ErrNoLocalTarball = errors.New("no matching tarball in the local tarball directory")
+
+// localTarballPrefixes returns the file name prefixes, relative to the local
+// tarball directory, that tarballs of name are looked up by. npm pack names
+// @scope/name tarballs scope-name-<version>.tgz; @scope-name-<version>.tgz
+// and @scope/name-<version>.tgz are accepted too.
+func localTarballPrefixes(name string) []string {
+	scope, pkg, ok := strings.Cut(name, "/")
+	if !ok || !strings.HasPrefix(scope, "@") {
+		return []string{name + "-"}
+	}
+	return []string{
+		strings.TrimPrefix(scope, "@") + "-" + pkg + "-",
+		scope + "-" + pkg + "-",
+		filepath.Join(scope, pkg+"-"),
+	}
+}
+
+// localTarballs returns the path of each version of name in the local
+// tarball directory
+func (pm *PackageManager) localTarballs(name string) (map[string]string, error) {
+	tarballs := make(map[string]string)
+	for _, prefix := range localTarballPrefixes(name) {
+		subdir := filepath.Dir(prefix)
+		dir := filepath.Join(pm.config.LocalTarballDir, subdir)
+		entries, err := os.ReadDir(dir)
+		if errors.Is(err, os.ErrNotExist) && subdir != "." {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read local tarball directory: %w", err)
+		}
+
+		base := filepath.Base(prefix)
+		for _, entry := range entries {
+			rest, ok := strings.CutPrefix(entry.Name(), base)
+			if !ok || entry.IsDir() {
+				continue
+			}
+			// The version check keeps lib-extra-1.0.0.tgz from being read as a
+			// version of lib
+			version, ok := strings.CutSuffix(rest, ".tgz")
+			if !ok {
+				continue
+			}
+			if _, err := semver.StrictNewVersion(version); err != nil {
+				continue
+			}
+			if _, seen := tarballs[version]; !seen {
+				tarballs[version] = filepath.Join(dir, entry.Name())
+			}
+		}
+	}
+	return tarballs, nil
+}
+
+// resolveLocalTarball resolves spec against the tarballs of name in the local
+// tarball directory. It returns a manifest listing those versions, so the
+// resolved package goes through the same checks as one from the registry,
+// and the path of the chosen tarball.
+func (pm *PackageManager) resolveLocalTarball(name, spec string) (*manifestpkg.NPMPackage, string, string, error) {
+	tarballs, err := pm.localTarballs(name)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	npmPackage := &manifestpkg.NPMPackage{
+		Name:     name,
+		DistTags: manifestpkg.DistTags{},
+		Versions: make(map[string]manifestpkg.Version, len(tarballs)),
+	}
+	var latest *semver.Version
+	for v := range tarballs {
+		npmPackage.Versions[v] = manifestpkg.Version{Name: name, Version: v}
+		if parsed := semver.MustParse(v); latest == nil || preferLatest(parsed, latest) {
+			latest = parsed
+		}
+	}
+	if latest != nil {
+		npmPackage.DistTags["latest"] = latest.Original()
+	}
+
+	version := pm.versionInfo.GetVersion(spec, npmPackage)
+	if version == "" || !pm.matchesManifest(spec, npmPackage) {
+		return nil, "", "", fmt.Errorf("%w: %s@%s (looked for %s<version>.tgz in %s)",
+			ErrNoLocalTarball, name, spec, localTarballPrefixes(name)[0], pm.config.LocalTarballDir)
+	}
+	return npmPackage, version, tarballs[version], nil
+}
+
+// preferLatest reports whether a rather than b is tagged latest: a stable
+// version over a prerelease, as on the registry, then the higher version
+func preferLatest(a, b *semver.Version) bool {
+	aStable, bStable := a.Prerelease() == "", b.Prerelease() == ""
+	if aStable != bStable {
+		return aStable
+	}
+	return a.GreaterThan(b)
+}
+
+// copyLocalTarball copies the local tarball of name@version into the tarball
+// cache as filename, unless a valid copy is already there
+func (pm *PackageManager) copyLocalTarball(name, version, filename string) error {
+	dest := filepath.Join(pm.tarball.TarballPath, filename)
+	if utils.ValidateTarball(dest) {
+		return nil
+	}
+
+	tarballs, err := pm.localTarballs(name)
+	if err != nil {
+		return err
+	}
+	src, ok := tarballs[version]
+	if !ok {
+		return fmt.Errorf("%w: %s@%s (looked for %s%s.tgz in %s)",
+			ErrNoLocalTarball, name, version, localTarballPrefixes(name)[0], version, pm.config.LocalTarballDir)
+	}
+	return copyFile(src, dest)
+}
+
+// copyFile copies src to dest through a temporary file, so a concurrent
+// reader never sees a partial copy
+func copyFile(src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", src, err)
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(dest), err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dest), filepath.Base(dest)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", dest, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, in); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to copy %s: %w", src, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to copy %s: %w", src, err)
+	}
+	return os.Rename(tmp.Name(), dest)
+}
@@ -1,27 +1,37 @@
 package manager
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"path"
 	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/ernesto27/go-npm/binlink"
 	"github.com/ernesto27/go-npm/config"
 	"github.com/ernesto27/go-npm/etag"
 	"github.com/ernesto27/go-npm/extractor"
 	"github.com/ernesto27/go-npm/integrity"
+	"github.com/ernesto27/go-npm/logger"
 	manifestpkg "github.com/ernesto27/go-npm/manifest"
 	"github.com/ernesto27/go-npm/packagecopy"
 	"github.com/ernesto27/go-npm/packagejson"
 	"github.com/ernesto27/go-npm/parsejson"
+	"github.com/ernesto27/go-npm/peers"
 	"github.com/ernesto27/go-npm/progress"
 	"github.com/ernesto27/go-npm/scripts"
+	"github.com/ernesto27/go-npm/signature"
 	"github.com/ernesto27/go-npm/tarball"
+	"github.com/ernesto27/go-npm/timing"
 	"github.com/ernesto27/go-npm/types"
 	"github.com/ernesto27/go-npm/utils"
 	"github.com/ernesto27/go-npm/version"
@@ -50,7 +60,7 @@ type PackageManager struct {
 	processedPackages map[string]packagejson.Dependency
 	configPath        string
 	packagesPath      string
-	Etag              etag.Etag
+	Etag              *etag.Etag
 	isAdd             bool
 	isGlobal          bool
 	config            *config.Config
@@ -68,8 +78,30 @@ type PackageManager struct {
 	downloadMu        sync.Mutex
 	downloadLocks     map[string]*sync.Mutex
 	progress          *progress.Progress
+	logger            *logger.Logger
 	version           string
 	lifecycleManager  *scripts.LifecycleManager
+	concurrency       int
+	nodeVersion       func() string
+	signatures        map[string]*signature.Verifier
+	signaturesMu      sync.Mutex
+	forcedVersions    map[string]string
+	conflicts         []VersionConflict
+	deprecations      []Deprecation
+	ctx               context.Context
+
+	// timing is nil unless --timing is set
+	timing *timing.Report
+
+	// previousPackages are the lock packages before the command, see snapshotLock
+	previousPackages map[string]packagejson.PackageItem
+
+	// cache is the real cache during a dry run, see scratchCache
+	cache *config.Config
+
+	// verifiedSignatures holds the name@version of the packages whose
+	// registry signature was verified, see verifyLockSignatures
+	verifiedSignatures sync.Map
 }
 
 type Package struct {
@@ -93,7 +125,12 @@ type Dependencies struct {
 	PackageJsonParse  *packagejson.PackageJSONParser
 	BinLinker         *binlink.BinLinker
 	Progress          *progress.Progress
+	Logger            *logger.Logger
 	LifecycleManager  *scripts.LifecycleManager
+
+	// Cache is the real cache of a dry run, whose Config points at a scratch
+	// one; nil otherwise
+	Cache *config.Config
 }
 
 type QueueItem struct {
@@ -103,14 +140,69 @@ type QueueItem struct {
 	IsOptional     bool
 	IsPeer         bool
 	IsPeerOptional bool
+	// Ancestry holds the names of the packages that led to this one, outermost first
+	Ancestry []string
+	// Path holds the resolved name@version of the same packages, to detect cycles
+	Path []string
+}
+
+// resolutionPath returns the Path of the dependencies of item, which
+// resolved to name@version
+func resolutionPath(item QueueItem, name, version string) []string {
+	return append(slices.Clone(item.Path), name+"@"+version)
+}
+
+// closesCycle reports whether packageKey is installed in one of the
+// directories Node searches from item's parent: the parent and the packages
+// it is nested in. They are the last entries of item.Path, one per
+// node_modules level of item.ParentName.
+func closesCycle(item QueueItem, packageKey string) bool {
+	levels := strings.Count(item.ParentName, "node_modules/")
+	return slices.Contains(item.Path[max(len(item.Path)-levels, 0):], packageKey)
+}
+
+// buildTarballURL returns the registry tarball URL of packageName@version.
+// Unlike manifest requests, the scope slash stays literal:
+// @scope/name -> <registry>@scope/name/-/name-1.0.0.tgz
+func buildTarballURL(packageName, version string) string {
+	return registryTarballURL(npmRegistryURL, packageName, version)
+}
+
+// registryTarballURL is buildTarballURL on the registry at registryURL
+func registryTarballURL(registryURL, packageName, version string) string {
+	tarballName := packageName
+	if strings.HasPrefix(packageName, "@") && strings.Contains(packageName, "/") {
+		parts := strings.Split(packageName, "/")
+		tarballName = parts[1]
+	}
+	return fmt.Sprintf("%s%s/-/%s-%s.tgz", registryURL, packageName, tarballName, version)
+}
+
+// tarballURL returns the tarball URL of packageName@version on the registry
+// of its scope, when one is configured, and on the default registry otherwise
+func (pm *PackageManager) tarballURL(packageName, version string) string {
+	if registry, ok := pm.manifest.ScopeRegistry(packageName); ok {
+		return registryTarballURL(registry, packageName, version)
+	}
+	return buildTarballURL(packageName, version)
 }
 
 // generateUniqueTarballName creates a unique tarball filename to avoid collisions
 // between scoped and non-scoped packages with the same base name.
 // Example: @jest/expect and expect both produce expect-30.2.0.tgz without this
 func generateUniqueTarballName(packageName, version string) string {
-	safeName := strings.ReplaceAll(packageName, "/", "-")
-	return safeName + "-" + version + ".tgz"
+	return tarball.UniqueName(packageName, version)
+}
+
+// newSubDependency builds the queue dependency for a package's own dependency,
+// resolving npm aliases ("npm:actual-package@version") to the real package
+func newSubDependency(name, version string) packagejson.Dependency {
+	dep := packagejson.Dependency{Name: name, Version: version, ActualName: name}
+	if actualPkg, actualVersion, isAlias := parseAliasVersion(version); isAlias {
+		dep.ActualName = actualPkg
+		dep.Version = actualVersion
+	}
+	return dep
 }
 
 // parseAliasVersion detects npm package aliases in the format "npm:package@version"
@@ -188,41 +280,173 @@ func BuildDependencies(opts types.BuildOptions) (*Dependencies, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to create config: %w", err)
 	}
+	if opts.MaxConcurrency > 0 {
+		cfg.Concurrency = opts.MaxConcurrency
+	}
+	cfg.EngineStrict = opts.EngineStrict
+	cfg.Force = opts.Force
+	cfg.VerifySignatures = opts.VerifySignatures
+	cfg.AtomicInstall = opts.AtomicInstall
+	cfg.ReportConflicts = opts.ReportConflicts
+	cfg.ForceResolutions = opts.ForceResolutions
+	cfg.InstallLinks = opts.InstallLinks || opts.PackLinks
+	cfg.PackLinks = opts.PackLinks
+	cfg.Workspaces = opts.Workspaces
+	cfg.AllWorkspaces = opts.AllWorkspaces
+	cfg.Offline = opts.Offline
+	cfg.PreferOffline = opts.PreferOffline
+	cfg.OmitLockfileRegistry = opts.OmitLockfileRegistry
+	cfg.CacheLock = opts.CacheLock
+	cfg.ResolutionOnly = opts.ResolutionOnly
+	cfg.IgnoreOptional = opts.IgnoreOptional
+	cfg.DryRun = opts.DryRun
+	cfg.NoSave = opts.NoSave
+	cfg.NoBinLinks = opts.NoBinLinks
+	cfg.Timing = opts.Timing
+	omit, err := config.ParseOmit(opts.Omit)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --omit: %w", err)
+	}
+	omit.Dev = omit.Dev || opts.Production
+	omit.Optional = omit.Optional || opts.NoOptional
+	omit.Peer = omit.Peer || opts.NoPeer
+	cfg.Omit = omit
+	if opts.InstallStrategy != "" {
+		if err := config.ValidateInstallStrategy(opts.InstallStrategy); err != nil {
+			return nil, fmt.Errorf("invalid --install-strategy: %w", err)
+		}
+		cfg.InstallStrategy = opts.InstallStrategy
+	}
+
+	if opts.Progress != "" {
+		if err := progress.ValidateRenderer(opts.Progress); err != nil {
+			return nil, fmt.Errorf("invalid --progress: %w", err)
+		}
+	}
+
+	logLevel := logger.LevelInfo
+	if opts.LogLevel != "" {
+		if logLevel, err = logger.ParseLevel(opts.LogLevel); err != nil {
+			return nil, fmt.Errorf("invalid --log-level: %w", err)
+		}
+	}
+	if opts.Quiet {
+		logLevel = min(logLevel, logger.LevelWarn)
+	}
+
+	// A dry run downloads into a scratch cache removed by Close; the real
+	// cache is only read, to report which packages it is missing
+	var realCache *config.Config
+	if cfg.DryRun {
+		realCache = cfg
+		if cfg, err = scratchCache(cfg); err != nil {
+			return nil, err
+		}
+	}
 
-	manifest, err := manifestpkg.NewManifest(cfg.BaseDir, npmRegistryURL)
+	manifest, err := manifestpkg.NewManifest(cfg.BaseDir, cfg.PrimaryRegistry())
 	if err != nil {
 		return nil, fmt.Errorf("failed to create manifest: %w", err)
 	}
+	manifest.SetFallbackRegistries(cfg.FallbackRegistries())
+	manifest.SetScopeRegistries(cfg.ScopeRegistries)
+	manifest.SetRetries(cfg.FetchRetries)
+	manifest.SetTimeout(cfg.HTTPTimeout)
+	manifest.SetOffline(cfg.Offline)
+	manifest.SetFetchMode(cfg.ManifestFetchMode)
+
+	// Manifests and tarballs share the limiter of each registry, so the rate
+	// holds for the install as a whole
+	limiters := utils.NewRateLimiters(cfg.RateLimit)
+	manifest.SetRateLimiters(limiters)
+
+	tarballDownloader := tarball.NewTarball(cfg.TarballDir)
+	tarballDownloader.SetRegistries(cfg.Registries)
+	tarballDownloader.SetRetries(cfg.FetchRetries)
+	tarballDownloader.SetTimeout(cfg.HTTPTimeout)
+	tarballDownloader.SetRateLimiters(limiters)
+	tarballDownloader.SetOffline(cfg.Offline)
 
 	etag, err := etag.NewEtag(cfg.BaseDir)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create etag: %w", err)
 	}
 
+	versionInfo := version.New()
+	if opts.ExplainResolution {
+		versionInfo.SetTraceWriter(os.Stderr)
+	}
+	versionInfo.SetIncludePrerelease(opts.IncludePrerelease)
+
+	progressRenderer := progress.New(opts.Version, opts.Verbose)
+	progressRenderer.SetRenderer(opts.Progress)
+	progressRenderer.SetJSON(opts.JSON)
+
+	log := logger.New(logLevel)
+	log.SetPauser(progressRenderer)
+	if opts.JSON {
+		log.SetOutput(os.Stderr)
+	}
+
+	lifecycleManager := scripts.NewLifecycleManager(cfg.LocalNodeModules, opts.IgnoreScripts)
+	lifecycleManager.SetScriptPolicy(cfg.ScriptsAllow, cfg.ScriptsDeny)
+	if opts.JSON {
+		// Keep stdout to the JSON summary
+		lifecycleManager.SetOutput(os.Stderr)
+	}
+	for key, value := range scriptConfig(cfg, opts) {
+		lifecycleManager.SetConfig(key, value)
+	}
+
 	return &Dependencies{
 		Config:            cfg,
 		Manifest:          manifest,
 		Etag:              etag,
-		Tarball:           tarball.NewTarball(cfg.TarballDir),
+		Tarball:           tarballDownloader,
 		Extractor:         extractor.NewTGZExtractor(),
 		PackageCopy:       packagecopy.NewPackageCopy(),
 		ParseJsonManifest: parsejson.New(),
-		VersionInfo:       version.New(),
+		VersionInfo:       versionInfo,
 		PackageJsonParse:  packagejson.NewPackageJSONParser(cfg, yarnlock.NewYarnLockParser()),
 		BinLinker:         binlink.NewBinLinker(cfg.LocalNodeModules),
-		Progress:          progress.New(opts.Version, opts.Verbose),
-		LifecycleManager:  scripts.NewLifecycleManager(cfg.LocalNodeModules, opts.IgnoreScripts),
+		Progress:          progressRenderer,
+		Logger:            log,
+		LifecycleManager:  lifecycleManager,
+		Cache:             realCache,
 	}, nil
 }
 
+// scriptConfig returns the effective config lifecycle scripts see as
+// npm_config_* variables
+func scriptConfig(cfg *config.Config, opts types.BuildOptions) map[string]string {
+	return map[string]string{
+		"user-agent":     fmt.Sprintf("go-npm/%s %s %s", opts.Version, runtime.GOOS, runtime.GOARCH),
+		"registry":       cfg.PrimaryRegistry(),
+		"cache":          cfg.BaseDir,
+		"prefix":         cfg.GlobalDir,
+		"ignore-scripts": strconv.FormatBool(opts.IgnoreScripts),
+		"engine-strict":  strconv.FormatBool(cfg.EngineStrict),
+	}
+}
+
 func New(deps *Dependencies) (*PackageManager, error) {
+	log := deps.Logger
+	if log == nil {
+		log = logger.New(logger.LevelInfo)
+	}
+
+	var report *timing.Report
+	if deps.Config.Timing {
+		report = timing.New()
+	}
+
 	return &PackageManager{
 		dependencies:      make(map[string]string),
 		extractedPath:     deps.Config.LocalNodeModules,
 		processedPackages: make(map[string]packagejson.Dependency),
 		configPath:        deps.Config.BaseDir,
 		packagesPath:      deps.Config.PackagesDir,
-		Etag:              *deps.Etag,
+		Etag:              deps.Etag,
 		isAdd:             false,
 		isGlobal:          false,
 		config:            deps.Config,
@@ -237,10 +461,27 @@ func New(deps *Dependencies) (*PackageManager, error) {
 		binLinker:         deps.BinLinker,
 		downloadLocks:     make(map[string]*sync.Mutex),
 		progress:          deps.Progress,
+		logger:            log,
 		lifecycleManager:  deps.LifecycleManager,
+		concurrency:       deps.Config.Concurrency,
+		nodeVersion:       sync.OnceValue(detectNodeVersion),
+		signatures:        make(map[string]*signature.Verifier),
+		ctx:               context.Background(),
+		cache:             deps.Cache,
+		timing:            report,
 	}, nil
 }
 
+// SetContext sets the context of the install. Cancelling it (e.g. on Ctrl-C)
+// stops downloads and new packages from being fetched or placed; packages in
+// flight are finished or discarded whole, so the cache and node_modules never
+// keep a partially written package.
+func (pm *PackageManager) SetContext(ctx context.Context) {
+	pm.ctx = ctx
+	pm.tarball.SetContext(ctx)
+	pm.manifest.SetContext(ctx)
+}
+
 func (pm *PackageManager) SetupGlobal() error {
 	// Create global directory first
 	if err := utils.CreateDir(pm.config.GlobalDir); err != nil {
@@ -258,6 +499,7 @@ func (pm *PackageManager) SetupGlobal() error {
 	pm.extractedPath = pm.config.GlobalNodeModules
 
 	pm.binLinker.SetGlobalMode(pm.config.GlobalNodeModules, pm.config.GlobalBinDir)
+	pm.lifecycleManager.SetConfig("global", "true")
 
 	// Load existing global lock file if it exists
 	if _, err := os.Stat(pm.config.GlobalLockFile); err == nil {
@@ -292,14 +534,21 @@ func (pm *PackageManager) SetupGlobal() error {
 	return nil
 }
 
-func (pm *PackageManager) ParsePackageJSON(isProduction bool) error {
+func (pm *PackageManager) ParsePackageJSON() error {
 	pm.progress.Start()
+	pm.snapshotLock()
 
 	data, err := pm.packageJsonParse.ParseDefault()
 	if err != nil {
 		return err
 	}
 
+	if !pm.config.Force {
+		if err := checkRootPlatform(data); err != nil {
+			return err
+		}
+	}
+
 	pm.lifecycleManager.SetTrustedDependencies(data.GetTrustedDependencies())
 
 	// Discover workspaces first (needed for both fresh and incremental installs)
@@ -315,18 +564,35 @@ func (pm *PackageManager) ParsePackageJSON(isProduction bool) error {
 
 		if errors := registry.Validate(); len(errors) > 0 {
 			for _, e := range errors {
-				fmt.Printf("Warning: %v\n", e)
+				pm.warn(progress.WarningOther, "%v", e)
 			}
 		}
 	}
 
+	if len(pm.config.Workspaces) > 0 || pm.config.AllWorkspaces {
+		return pm.installWorkspaces(data)
+	}
+
 	lockFileExists := false
 
-	if pm.packageJsonParse.PackageLock != nil {
+	// A lock resolved with different overrides or resolutions no longer describes the tree
+	overridesChanged := pm.packageJsonParse.PackageLock != nil &&
+		(!maps.Equal(data.GetOverrides(), pm.packageJsonParse.PackageLock.Overrides) ||
+			!maps.Equal(data.GetResolutions(), pm.packageJsonParse.PackageLock.Resolutions))
+	if overridesChanged {
+		pm.logger.Infof("\nOverrides or resolutions changed, re-resolving dependencies")
+	}
+
+	if pm.packageJsonParse.PackageLock != nil && !overridesChanged {
+		warnRegistryChange(os.Stderr, pm.packageJsonParse.PackageLock.Registries, pm.registries())
+
 		packagesToAdd, packagesToRemove := pm.packageJsonParse.ResolveDependencies()
 
 		for _, pkg := range packagesToAdd {
-			err = pm.Add(pkg.Name, pkg.Version, true)
+			if pm.omitted(pkg.Kind) {
+				continue
+			}
+			err = pm.Add(pkg.Name, pkg.Version, pkg.Kind, true)
 			if err != nil {
 				return err
 			}
@@ -339,39 +605,50 @@ func (pm *PackageManager) ParsePackageJSON(isProduction bool) error {
 			}
 		}
 
-		if isProduction && len(pm.packageJsonParse.PackageLock.DevDependencies) > 0 {
-			pm.removeDevOnlyPackages()
-		}
+		pm.removeOmittedPackages()
 
 		pm.packageLock = pm.packageJsonParse.PackageLock
 
 		lockFileExists = true
-	} else {
+	} else if !overridesChanged {
 		// Priority 1: Try npm lock file (package-lock.json)
 		err := pm.packageJsonParse.MigrateFromPackageLock()
 		if err == nil {
-			fmt.Println("\nMigrating from package-lock.json")
+			pm.logger.Infof("\nMigrating from package-lock.json")
 			pm.packageLock = pm.packageJsonParse.PackageLock
 			lockFileExists = true
 		} else {
 			// Priority 2: Try yarn.lock (v1 only)
 			err = pm.packageJsonParse.MigrateFromYarnLock()
 			if err == nil {
-				fmt.Println("\nMigrating from yarn.lock")
+				pm.logger.Infof("\nMigrating from yarn.lock")
 				pm.packageLock = pm.packageJsonParse.PackageLock
 				lockFileExists = true
+			} else {
+				// Priority 3: Try pnpm-lock.yaml (lockfileVersion 6 and 9)
+				warnings, err := pm.packageJsonParse.MigrateFromPnpmLock()
+				if err == nil {
+					pm.logger.Infof("\nMigrating from pnpm-lock.yaml")
+					for _, warning := range warnings {
+						pm.logger.Warnf("%s", warning)
+					}
+					pm.packageLock = pm.packageJsonParse.PackageLock
+					lockFileExists = true
+				}
 			}
 		}
 	}
 
 	// Create workspace symlinks even when lock file exists
-	err = pm.CreateWorkspaceSymlinks()
-	if err != nil {
-		return err
+	if !pm.config.ResolutionOnly && !pm.config.DryRun {
+		err = pm.CreateWorkspaceSymlinks()
+		if err != nil {
+			return err
+		}
 	}
 
 	if !lockFileExists {
-		err = pm.fetchToCache(*data, isProduction)
+		err = pm.fetchToCache(*data)
 		if err != nil {
 			return err
 		}
@@ -385,35 +662,84 @@ func (pm *PackageManager) ParsePackageJSON(isProduction bool) error {
 	return nil
 }
 
+// CreateWorkspaceSymlinks places every workspace package in node_modules,
+// symlinked or, with --install-links, copied
 func (pm *PackageManager) CreateWorkspaceSymlinks() error {
 	if pm.workspaceRegistry == nil {
 		return nil
 	}
 
 	for _, wsPkg := range pm.workspaceRegistry.Packages {
-		err := pm.workspaceRegistry.CreateSymlink(pm.extractedPath, wsPkg.Name, wsPkg.Path)
-		if err != nil {
-			return fmt.Errorf("failed to create symlink for %s: %w", wsPkg.Name, err)
+		if err := pm.placeLocalPackage(wsPkg.Name, wsPkg.Path); err != nil {
+			return fmt.Errorf("failed to link workspace %s: %w", wsPkg.Name, err)
 		}
 	}
 
 	return nil
 }
 
-func (pm *PackageManager) removeDevOnlyPackages() {
-	pkgsToRemoveMap := make(map[string]bool)
+// manifestStale reports whether the cached manifest of name is past its
+// max-age. Manifests cached before fetch times were recorded are aged by
+// their file's modification time. Offline installs and --prefer-offline
+// never revalidate.
+func (pm *PackageManager) manifestStale(name string) bool {
+	if pm.config.Offline || pm.config.PreferOffline {
+		return false
+	}
+	fetchedAt := pm.Etag.FetchedAt(pm.manifest.EtagKey(name))
+	if fetchedAt.IsZero() {
+		info, err := os.Stat(pm.manifest.FilePath(name))
+		if err != nil {
+			return false
+		}
+		fetchedAt = info.ModTime()
+	}
+	return time.Since(fetchedAt) >= pm.config.ManifestMaxAge
+}
+
+// omitted reports whether dependencies of kind are left out by the omit set
+func (pm *PackageManager) omitted(kind packagejson.DependencyKind) bool {
+	switch kind {
+	case packagejson.DependencyDev:
+		return pm.config.Omit.Dev
+	case packagejson.DependencyOptional:
+		return pm.config.Omit.Optional
+	case packagejson.DependencyPeer:
+		return pm.config.Omit.Peer
+	}
+	return false
+}
 
-	for name := range pm.packageJsonParse.PackageLock.DevDependencies {
-		pkgToRemove := pm.packageJsonParse.ResolveDependenciesToRemove(name)
+// removeOmittedPackages drops the root dependencies of the omitted groups
+// from the lock read from disk, with every package only reachable from them
+func (pm *PackageManager) removeOmittedPackages() {
+	lock := pm.packageJsonParse.PackageLock
+	var groups []map[string]string
+	if pm.config.Omit.Dev {
+		groups = append(groups, lock.DevDependencies)
+	}
+	if pm.config.Omit.Optional {
+		groups = append(groups, lock.OptionalDependencies)
+	}
+	if pm.config.Omit.Peer {
+		groups = append(groups, lock.PeerDependencies)
+	}
 
-		for _, pkg := range pkgToRemove {
-			pkgsToRemoveMap[pkg] = true
-			delete(pm.packageJsonParse.PackageLock.Dependencies, pkg)
+	pkgsToRemoveMap := make(map[string]bool)
+	for _, group := range groups {
+		for name := range group {
+			for _, pkg := range pm.packageJsonParse.ResolveDependenciesToRemove(name) {
+				pkgsToRemoveMap[pkg] = true
+				delete(lock.Dependencies, pkg)
+			}
 		}
 	}
+	if len(pkgsToRemoveMap) == 0 {
+		return
+	}
 
 	pathsToDelete := []string{}
-	for pkgPath := range pm.packageJsonParse.PackageLock.Packages {
+	for pkgPath := range lock.Packages {
 		shouldDelete := false
 
 		pkgName := strings.TrimPrefix(pkgPath, "node_modules/")
@@ -440,17 +766,13 @@ func (pm *PackageManager) removeDevOnlyPackages() {
 	}
 
 	for _, pkgPath := range pathsToDelete {
-		delete(pm.packageJsonParse.PackageLock.Packages, pkgPath)
+		delete(lock.Packages, pkgPath)
 	}
 }
 
 func (pm *PackageManager) InstallFromCache() error {
-	// Track total count from lock file
-	for _, item := range pm.packageLock.Packages {
-		if item.Link {
-			continue
-		}
-		pm.progress.IncrementCount()
+	if err := pm.verifyLockSignatures(); err != nil {
+		return err
 	}
 
 	// Track top-level packages (from package.json dependencies)
@@ -467,11 +789,27 @@ func (pm *PackageManager) InstallFromCache() error {
 		}
 	}
 
+	// With --atomic the whole tree is staged next to node_modules and swapped
+	// in once complete; package scripts wait for the swap so that they see
+	// their dependencies in the real node_modules
+	nodeModulesPath := pm.extractedPath
+	staged := pm.config.AtomicInstall && !pm.isGlobal
+	var deferredScripts []deferredScript
+	if staged {
+		// A staging tree left by an interrupted run is resumed: packages in it
+		// were renamed into place whole, so they are complete
+		pm.extractedPath = stagingPath(nodeModulesPath)
+		defer func() { pm.extractedPath = nodeModulesPath }()
+		if err := os.MkdirAll(pm.extractedPath, 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", pm.extractedPath, err)
+		}
+	}
+
 	packagesToInstall := make(map[string]packagejson.PackageItem)
 	for pkgPath := range pm.packageLock.Packages {
 		item := pm.packageLock.Packages[pkgPath]
 
-		if item.Link {
+		if item.Link || pm.ignoredOptional(item) {
 			continue
 		}
 
@@ -485,112 +823,292 @@ func (pm *PackageManager) InstallFromCache() error {
 		exists := utils.FolderExists(targetPath)
 		if !exists {
 			packagesToInstall[pkgPath] = item
+		} else {
+			// Counted as installed already; the others count as they complete
+			pm.progress.IncrementCount()
 		}
 	}
 
-	var wg sync.WaitGroup
+	hasNested := nestedParents(pm.packageLock.Packages)
+	gitPaths := gitDependencyPaths(packagesToInstall)
+
+	// A lock that recorded no install scripts skips the script pass entirely
+	runScripts := pm.packageLock.RunsInstallScripts()
+
+	var scriptsMu sync.Mutex
 	errChan := make(chan error, len(packagesToInstall))
-	for name, item := range packagesToInstall {
-		if name == "" {
-			continue
+	installItem := func(name string, item packagejson.PackageItem, timings *timing.Buffer) {
+		if err := pm.ctx.Err(); err != nil {
+			errChan <- fmt.Errorf("install interrupted: %w", err)
+			return
 		}
 
-		wg.Add(1)
-		go func(name string, item packagejson.PackageItem) {
-			defer wg.Done()
+		// Only packages missing from the cache are downloaded and extracted
+		tracker := pm.progress.Track("")
+		defer tracker.Done()
 
-			namePkg := strings.TrimPrefix(name, "node_modules/")
-			pkgName := namePkg
-			if strings.Contains(namePkg, "/node_modules/") {
-				parts := strings.Split(namePkg, "/node_modules/")
-				pkgName = parts[len(parts)-1]
-			}
+		namePkg := strings.TrimPrefix(name, "node_modules/")
+		pkgName := namePkg
+		if strings.Contains(namePkg, "/node_modules/") {
+			parts := strings.Split(namePkg, "/node_modules/")
+			pkgName = parts[len(parts)-1]
+		}
 
-			pathPkg := path.Join(pm.packagesPath, pkgName+"@"+item.Version)
+		// Tarball URL dependencies are cached apart from the registry package
+		// of the same version
+		cacheVersion := tarball.CacheVersion(pkgName, item.Version, item.Resolved)
+		pathPkg := path.Join(pm.packagesPath, pkgName+"@"+cacheVersion)
+		// With the content store the package is found by its integrity, under
+		// whichever name it was first cached
+		if stored, ok := pm.storedPackage(item.Integrity); ok {
+			pathPkg = stored
+		}
 
-			exists := utils.FolderExists(pathPkg)
-			if !exists {
-				if item.Resolved == "" {
-					return
+		exists := utils.FolderExists(pathPkg)
+		if !exists {
+			if item.Resolved == "" {
+				return
+			}
+
+			// Check if this is a git URL and convert to tarball URL if needed
+			downloadURL := item.Resolved
+			tarballFilename := generateUniqueTarballName(pkgName, cacheVersion)
+
+			var cloneDep *GitDependency
+			validate := false
+			// Only registry packages are looked up in the local tarball
+			// directory, like in fetchToCache
+			fromLocalDir := false
+			if tarballURL, filename, isGit := convertGitURLToTarball(item.Resolved); isGit {
+				downloadURL = tarballURL
+				tarballFilename = filename
+			} else if gitDep, isGit := parseGitDependency(item.Resolved); isGit {
+				// Git hosts without an archive endpoint are cloned at the locked commit
+				cloneDep = gitDep
+			} else if isRegistryTarball(item.Resolved) {
+				// Tarballs are validated against the locked integrity. Locks
+				// migrated from npm or yarn may have none, and those tarballs
+				// are downloaded without verification, as migrate warns.
+				registryPackage := cacheVersion == item.Version
+				validate = item.Integrity != ""
+				fromLocalDir = registryPackage && pm.config.LocalTarballDir != ""
+
+				// A scoped package only ever comes from the registry of its
+				// scope, whichever registry the lock names, so a public package
+				// of the same name cannot take its place
+				if _, ok := pm.manifest.ScopeRegistry(pkgName); ok && registryPackage {
+					downloadURL = pm.tarballURL(pkgName, item.Version)
 				}
+			}
 
-				// Check if this is a git URL and convert to tarball URL if needed
-				downloadURL := item.Resolved
-				tarballFilename := generateUniqueTarballName(pkgName, item.Version)
+			// Lock based on package@version to prevent concurrent extractions to the same directory
+			// Use the same locking key as fetchToCache to prevent race conditions
+			packageKey := pkgName + "@" + cacheVersion
+			unlock, err := pm.lockPackage(packageKey)
+			if err != nil {
+				errChan <- err
+				return
+			}
 
-				if tarballURL, filename, isGit := convertGitURLToTarball(item.Resolved); isGit {
-					downloadURL = tarballURL
-					tarballFilename = filename
+			if cloneDep != nil && !utils.FolderExists(pathPkg) {
+				var err error
+				if pm.config.Offline && cloneDep.Protocol != "file" {
+					err = errNotCached(pkgName, item.Version)
+				} else {
+					err = cloneGitPackage(cloneDep.CloneURL, cloneDep.Ref, pathPkg)
 				}
-
-				// Lock based on package@version to prevent concurrent extractions to the same directory
-				// Use the same locking key as fetchToCache to prevent race conditions
-				packageKey := pkgName + "@" + item.Version
-				pm.downloadMu.Lock()
-				packageLock_, exists := pm.downloadLocks[packageKey]
-				if !exists {
-					packageLock_ = &sync.Mutex{}
-					pm.downloadLocks[packageKey] = packageLock_
+				if err != nil {
+					unlock()
+					errChan <- err
+					return
 				}
-				pm.downloadMu.Unlock()
+			}
 
-				packageLock_.Lock()
+			// Double-check folder existence after acquiring lock
+			if !utils.FolderExists(pathPkg) {
+				// Drop a link left to a store entry that was removed
+				os.Remove(pathPkg)
+				tarballPath := filepath.Join(pm.tarball.TarballPath, tarballFilename)
 
-				// Double-check folder existence after acquiring lock
-				if !utils.FolderExists(pathPkg) {
-					tarballPath := filepath.Join(pm.tarball.TarballPath, tarballFilename)
+				// Validate tarball (checks existence and integrity)
+				shouldDownload := true
+				if utils.ValidateTarball(tarballPath) {
+					shouldDownload = false
+				} else {
+					os.Remove(tarballPath)
+				}
 
-					// Validate tarball (checks existence and integrity)
-					shouldDownload := true
-					if utils.ValidateTarball(tarballPath) {
-						shouldDownload = false
-					} else {
-						os.Remove(tarballPath)
+				if shouldDownload {
+					tracker.Move(progress.PhaseDownloading)
+					// Registry tarballs found in the local tarball directory are
+					// copied from it; the others are fetched as usual
+					var err error
+					local := false
+					if fromLocalDir {
+						err = pm.copyLocalTarball(pkgName, item.Version, tarballFilename)
+						local = !errors.Is(err, ErrNoLocalTarball)
 					}
-
-					if shouldDownload {
-						err := pm.tarball.DownloadAs(downloadURL, tarballFilename)
-						if err != nil {
-							packageLock_.Unlock()
-							errChan <- err
-							return
+					switch {
+					case local:
+						// A local tarball must be the one the lock recorded
+						if err == nil && item.Integrity != "" {
+							if verr := integrity.New().ValidateFileStrict(tarballPath, item.Integrity); verr != nil {
+								os.Remove(tarballPath)
+								err = fmt.Errorf("SECURITY: integrity check failed for %s@%s: the tarball in %s does not match the lock: %w", pkgName, item.Version, pm.config.LocalTarballDir, verr)
+							}
+						}
+					case pm.config.Offline:
+						err = errNotCached(pkgName, item.Version)
+					case validate:
+						start := time.Now()
+						_, err = pm.tarball.DownloadAndValidate(downloadURL, tarballFilename, item.Integrity)
+						timings.Since(packageKey, timing.PhaseDownload, start)
+						if errors.Is(err, integrity.ErrIntegrityMismatch) {
+							err = fmt.Errorf("SECURITY: integrity check failed for %s@%s: %w", pkgName, item.Version, err)
 						}
+					default:
+						start := time.Now()
+						_, err = pm.tarball.DownloadAs(downloadURL, tarballFilename)
+						timings.Since(packageKey, timing.PhaseDownload, start)
 					}
-
-					err := pm.extractor.Extract(tarballPath, pathPkg)
 					if err != nil {
-						packageLock_.Unlock()
+						unlock()
 						errChan <- err
 						return
 					}
 				}
-				packageLock_.Unlock()
+
+				tracker.Move(progress.PhaseExtracting)
+				start := time.Now()
+				err := pm.extractPackage(tarballPath, pathPkg)
+				timings.Since(packageKey, timing.PhaseExtract, start)
+				if err != nil {
+					unlock()
+					errChan <- err
+					return
+				}
 			}
 
-			targetPath := path.Join(pm.extractedPath, namePkg)
-			pm.progress.SetStatus(fmt.Sprintf("↓ %s@%s", pkgName, item.Version))
-			err := pm.packageCopy.CopyDirectory(pathPkg, targetPath)
+			stored, err := pm.storePackage(pathPkg, item.Integrity)
+			unlock()
 			if err != nil {
 				errChan <- err
 				return
 			}
+			pathPkg = stored
+		}
+		tracker.Done()
 
-			if err := pm.lifecycleManager.RunPackageScripts(pkgName, item.Version, targetPath, item.Scripts); err != nil {
-				errChan <- err
-				return
-			}
-		}(name, item)
+		targetPath := path.Join(pm.extractedPath, namePkg)
+		strategy := pm.installStrategy(name, pkgName, item, hasNested)
+		start := time.Now()
+		err := pm.copyPackageAtomic(pathPkg, targetPath, strategy)
+		timings.Since(pkgName+"@"+item.Version, timing.PhaseCopy, start)
+		if err != nil {
+			errChan <- err
+			return
+		}
+		pm.progress.Complete(pkgName, item.Version)
+
+		if !runScripts {
+			return
+		}
+
+		if staged {
+			scriptsMu.Lock()
+			deferredScripts = append(deferredScripts, deferredScript{pkgName: pkgName, namePkg: namePkg, item: item})
+			scriptsMu.Unlock()
+			return
+		}
+
+		if err := pm.lifecycleManager.RunPackageScripts(pkgName, item.Version, targetPath, item.Scripts); err != nil {
+			errChan <- err
+			return
+		}
 	}
 
-	wg.Wait()
-	close(errChan)
+	// Parents are placed before their nested node_modules, so that a nested
+	// package never creates its parent's directory ahead of the parent's rename
+	for _, batch := range installBatches(packagesToInstall) {
+		var wg sync.WaitGroup
+		jobs := make(chan string)
+		for i := 0; i < max(pm.concurrency, 1); i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				// Each worker times into its own buffer, merged once it is done
+				timings := pm.timing.NewBuffer()
+				defer pm.timing.Merge(timings)
+				for name := range jobs {
+					installItem(name, packagesToInstall[name], timings)
+				}
+			}()
+		}
 
-	for err := range errChan {
+		for _, name := range batch {
+			jobs <- name
+		}
+		close(jobs)
+
+		wg.Wait()
+
+		select {
+		case err := <-errChan:
+			return err
+		default:
+		}
+	}
+
+	if !pm.isGlobal {
+		if err := pm.upgradeLockIntegrity(); err != nil {
+			return err
+		}
+		if err := pm.placeFileDependencies(); err != nil {
+			return err
+		}
+	}
+
+	if staged {
+		// An interrupted install keeps the staging tree for the next run
+		// rather than swapping in an incomplete node_modules
+		if err := pm.ctx.Err(); err != nil {
+			return fmt.Errorf("install interrupted: %w", err)
+		}
+
+		if err := pm.CreateWorkspaceSymlinks(); err != nil {
+			return err
+		}
+
+		if err := swapNodeModules(pm.extractedPath, nodeModulesPath); err != nil {
+			return err
+		}
+		pm.extractedPath = nodeModulesPath
+
+		for _, script := range deferredScripts {
+			targetPath := path.Join(pm.extractedPath, script.namePkg)
+			if err := pm.lifecycleManager.RunPackageScripts(script.pkgName, script.item.Version, targetPath, script.item.Scripts); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := pm.prepareDependencies(gitPaths); err != nil {
 		return err
 	}
 
-	if err := pm.binLinker.LinkAllPackages(); err != nil {
-		return fmt.Errorf("failed to link bin executables: %w", err)
+	if !pm.config.NoBinLinks {
+		directDependencies := []string{}
+		for _, deps := range []map[string]string{pm.packageLock.Dependencies, pm.packageLock.DevDependencies, pm.packageLock.OptionalDependencies, pm.packageLock.PeerDependencies} {
+			directDependencies = slices.AppendSeq(directDependencies, maps.Keys(deps))
+		}
+		pm.binLinker.SetDirectDependencies(directDependencies)
+		pm.binLinker.SetWarn(func(format string, args ...any) {
+			pm.warn(progress.WarningOther, format, args...)
+		})
+
+		if err := pm.binLinker.LinkAllPackages(); err != nil {
+			return fmt.Errorf("failed to link bin executables: %w", err)
+		}
 	}
 
 	rootPkgJSON, err := pm.packageJsonParse.ParseDefault()
@@ -601,7 +1119,7 @@ func (pm *PackageManager) InstallFromCache() error {
 			version = v
 		}
 
-		fmt.Println()
+		fmt.Fprintln(pm.stdout())
 
 		if err := pm.lifecycleManager.RunRootPackageScripts(rootPkgJSON.Name, version, workDir, rootPkgJSON.Scripts); err != nil {
 			return fmt.Errorf("root package scripts failed: %w", err)
@@ -612,10 +1130,25 @@ func (pm *PackageManager) InstallFromCache() error {
 		}
 	}
 
-	pm.progress.Finish()
+	pm.Finish()
 	return nil
 }
 
+// warnInvalidDependencies warns about the dependencies of pkg that are
+// skipped because their value is not a version string
+func (pm *PackageManager) warnInvalidDependencies(pkg string, data *packagejson.PackageJSON) {
+	for _, dep := range data.InvalidDependencies() {
+		pm.warn(progress.WarningOther, "%s: skipping %s %q, its value is not a version string", pkg, dep.Section, dep.Name)
+	}
+}
+
+// ignoredOptional reports whether a locked package is left out of
+// node_modules by --ignore-optional, or by --no-optional for a lock
+// resolved without it
+func (pm *PackageManager) ignoredOptional(item packagejson.PackageItem) bool {
+	return (pm.config.IgnoreOptional || pm.config.Omit.Optional) && item.Optional
+}
+
 func (pm *PackageManager) removePackagesFromNodeModules(pkgList []string) error {
 	var wg sync.WaitGroup
 	errChan := make(chan error, len(pkgList))
@@ -643,14 +1176,17 @@ func (pm *PackageManager) removePackagesFromNodeModules(pkgList []string) error
 	return nil
 }
 
-func (pm *PackageManager) Add(pkgName string, version string, isInstall bool) error {
+// Add fetches pkgName and saves it into the package.json section for kind
+func (pm *PackageManager) Add(pkgName string, version string, kind packagejson.DependencyKind, isInstall bool) error {
+	pm.snapshotLock()
+
 	packageJson, err := pm.packageJsonParse.ParseDefault()
 	if err != nil {
 		return err
 	}
 
 	if !isInstall {
-		deps := packageJson.GetDependencies()
+		deps := packageJson.GetDependenciesOfKind(kind)
 		if _, exists := deps[pkgName]; exists {
 			if version != "" && deps[pkgName] == version {
 				return nil
@@ -658,25 +1194,28 @@ func (pm *PackageManager) Add(pkgName string, version string, isInstall bool) er
 		}
 	}
 
-	packageJsonAdd := packagejson.PackageJSON{
-		Dependencies: map[string]string{
-			pkgName: version,
-		},
-	}
-	err = pm.fetchToCache(packageJsonAdd, false)
+	packageJsonAdd := packagejson.PackageJSON{Overrides: packageJson.Overrides, Resolutions: packageJson.Resolutions}
+	packageJsonAdd.SetDependenciesOfKind(kind, map[string]string{
+		pkgName: version,
+	})
+	err = pm.fetchToCache(packageJsonAdd)
 	if err != nil {
 		return err
 	}
 
-	// Resolve version from lock file if not specified
-	resolvedVersion := version
-	if (version == "" || version == "latest") && pm.packageLock != nil {
-		if lockVersion, ok := pm.packageLock.Dependencies[pkgName]; ok {
-			resolvedVersion = lockVersion
+	// Like npm add, a dist-tag (or no version at all) is saved as a caret range
+	// on the version it resolved to, while the lock keeps the exact version
+	savedSpec := version
+	if !isInstall && isDistTagSpec(version) && pm.packageLock != nil {
+		if item, ok := pm.packageLock.Packages["node_modules/"+pkgName]; ok && item.Version != "" {
+			savedSpec = "^" + item.Version
+			if kind == packagejson.DependencyProd || kind == packagejson.DependencyDev {
+				lockDependenciesOfKind(pm.packageLock, kind)[pkgName] = savedSpec
+			}
 		}
 	}
 
-	err = pm.packageJsonParse.AddOrUpdateDependency(pkgName, resolvedVersion)
+	err = pm.packageJsonParse.AddOrUpdateDependency(pkgName, savedSpec, kind)
 	if err != nil {
 		return err
 	}
@@ -688,8 +1227,9 @@ func (pm *PackageManager) Add(pkgName string, version string, isInstall bool) er
 
 	pm.packageLock = pm.packageJsonParse.PackageLock
 
-	// Install packages from cache to node_modules (unless called from install command)
-	if !isInstall {
+	// Install packages from cache to node_modules (unless called from install
+	// command or for a dry run)
+	if !isInstall && !pm.config.DryRun {
 		err = pm.InstallFromCache()
 		if err != nil {
 			return err
@@ -699,39 +1239,73 @@ func (pm *PackageManager) Add(pkgName string, version string, isInstall bool) er
 	return nil
 }
 
+// isDistTagSpec reports whether spec asks for a dist-tag such as latest or
+// next rather than a version, a range or a remote source
+func isDistTagSpec(spec string) bool {
+	return !isRemoteSpec(spec) && version.IsDistTag(spec)
+}
+
+// lockDependenciesOfKind returns the top-level lock map that records kind
+func lockDependenciesOfKind(lock *packagejson.PackageLock, kind packagejson.DependencyKind) map[string]string {
+	switch kind {
+	case packagejson.DependencyDev:
+		return lock.DevDependencies
+	case packagejson.DependencyOptional:
+		return lock.OptionalDependencies
+	case packagejson.DependencyPeer:
+		return lock.PeerDependencies
+	default:
+		return lock.Dependencies
+	}
+}
+
 func (pm *PackageManager) Remove(pkg string, removeFromPackageJson bool) error {
+	pm.snapshotLock()
+
+	// uninstall reads the project here, install and SetupGlobal already have
+	if pm.packageJsonParse.PackageLock == nil {
+		if _, err := pm.packageJsonParse.ParseDefault(); err != nil {
+			return err
+		}
+		if pm.packageJsonParse.PackageLock == nil {
+			return fmt.Errorf("%s not found, run install first", pm.packageJsonParse.LockFileName)
+		}
+	}
 
 	pkgToRemove := pm.packageJsonParse.ResolveDependenciesToRemove(pkg)
 
-	err := pm.binLinker.UnlinkPackage(pkg)
-	if err != nil {
-		return err
-	}
+	if !pm.config.ResolutionOnly && !pm.config.DryRun {
+		if err := pm.binLinker.UnlinkPackage(pkg); err != nil {
+			return err
+		}
 
-	err = pm.removePackagesFromNodeModules(pkgToRemove)
-	if err != nil {
-		return err
+		if err := pm.removePackagesFromNodeModules(pkgToRemove); err != nil {
+			return err
+		}
 	}
 
 	if removeFromPackageJson {
-		err = pm.packageJsonParse.RemoveDependencies(pkg)
-		if err != nil {
+		if err := pm.packageJsonParse.RemoveDependencies(pkg); err != nil {
 			return err
 		}
 	}
 
-	err = pm.packageJsonParse.RemoveFromLockFile(pkg, pkgToRemove, true)
+	err := pm.packageJsonParse.RemoveFromLockFile(pkg, pkgToRemove, true)
 	if err != nil {
 		return err
 	}
+	pm.packageLock = pm.packageJsonParse.PackageLock
 
 	return nil
 }
 
-func (pm *PackageManager) fetchToCache(packageJson packagejson.PackageJSON, isProduction bool) error {
+func (pm *PackageManager) fetchToCache(packageJson packagejson.PackageJSON) error {
 	queue := make([]QueueItem, 0)
+	pm.warnInvalidDependencies("package.json", &packageJson)
 
-	for name, version := range packageJson.GetDependencies() {
+	dependencies := packageJson.GetDependencies()
+	for _, name := range slices.Sorted(maps.Keys(dependencies)) {
+		version := dependencies[name]
 		dep := packagejson.Dependency{Name: name, Version: version}
 
 		// Check for GitHub dependency format: "github:user/repo#ref"
@@ -755,8 +1329,10 @@ func (pm *PackageManager) fetchToCache(packageJson packagejson.PackageJSON, isPr
 		})
 	}
 
-	if !isProduction {
-		for name, version := range packageJson.GetDevDependencies() {
+	if !pm.config.Omit.Dev {
+		devDependencies := packageJson.GetDevDependencies()
+		for _, name := range slices.Sorted(maps.Keys(devDependencies)) {
+			version := devDependencies[name]
 			dep := packagejson.Dependency{Name: name, Version: version}
 
 			// Check for GitHub dependency format: "github:user/repo#ref"
@@ -779,7 +1355,12 @@ func (pm *PackageManager) fetchToCache(packageJson packagejson.PackageJSON, isPr
 		}
 	}
 
-	for name, version := range packageJson.GetOptionalDependencies() {
+	optionalDependencies := packageJson.GetOptionalDependencies()
+	if pm.config.Omit.Optional {
+		optionalDependencies = nil
+	}
+	for _, name := range slices.Sorted(maps.Keys(optionalDependencies)) {
+		version := optionalDependencies[name]
 		dep := packagejson.Dependency{Name: name, Version: version}
 
 		// Check for GitHub dependency format: "github:user/repo#ref"
@@ -802,671 +1383,1290 @@ func (pm *PackageManager) fetchToCache(packageJson packagejson.PackageJSON, isPr
 		})
 	}
 
+	// Top-level peer dependencies are installed like npm 7+, unless the same
+	// package is already listed as a regular or dev dependency
+	rootPeers := packageJson.GetPeerDependencies()
+	if pm.config.Omit.Peer {
+		rootPeers = nil
+	}
+	for _, name := range slices.Sorted(maps.Keys(rootPeers)) {
+		version := rootPeers[name]
+		if _, exists := packageJson.GetDependencies()[name]; exists {
+			continue
+		}
+		if _, exists := packageJson.GetDevDependencies()[name]; exists {
+			continue
+		}
+
+		queue = append(queue, QueueItem{
+			Dep:            newSubDependency(name, version),
+			ParentName:     "package.json",
+			IsPeer:         true,
+			IsPeerOptional: packageJson.PeerDependenciesMeta[name].Optional,
+		})
+	}
+
+	overrides := packageJson.GetOverrides()
+	resolutions := packageJson.GetResolutions()
+
 	packageLock := packagejson.PackageLock{}
+	if len(overrides) > 0 {
+		packageLock.Overrides = overrides
+	}
+	if len(resolutions) > 0 {
+		packageLock.Resolutions = resolutions
+	}
 	packageLock.Packages = make(map[string]packagejson.PackageItem)
 	packageLock.Dependencies = make(map[string]string)
 	packageLock.DevDependencies = make(map[string]string)
 	packageLock.OptionalDependencies = make(map[string]string)
 	packageLock.PeerDependencies = make(map[string]string)
+	packageLock.Registries = pm.registries()
 	packagesVersion := make(map[string]QueueItem)
 
 	var (
-		wg             sync.WaitGroup
 		mapMutex       sync.Mutex
-		activeWorkers  int
-		workerMutex    sync.Mutex
 		processingPkgs = make(map[string]bool)
+		engineChecked  sync.Map
+		signatureCheck sync.Map
 	)
 
+	// Ranges requested per package and their published versions, collected to
+	// report conflicts that force nested copies
+	trackConflicts := pm.config.ReportConflicts || pm.config.ForceResolutions
+	requestedRanges := make(map[string][]ConflictRange)
+	availableVersions := make(map[string][]string)
+
+	// Deprecated versions resolved, by name@version
+	deprecations := make(map[string]Deprecation)
+
+	// Versions whose tarball is gone from the registry, by package name, and
+	// the items that reused a copy of a package placed for another one, so
+	// they are resolved again when that copy falls back to another version
+	goneVersions := make(map[string][]string)
+	reusing := make(map[string][]QueueItem)
+
+	resume, err := pm.openResumeLog()
+	if err != nil {
+		return err
+	}
+	defer resume.close()
+
 	errChan := make(chan error, 1)
 	done := make(chan struct{})
 
-	workChan := make(chan QueueItem, len(queue))
-	for _, item := range queue {
-		if item.IsDev {
-			packageLock.DevDependencies[item.Dep.Name] = item.Dep.Version
-		} else {
-			packageLock.Dependencies[item.Dep.Name] = item.Dep.Version
+	// Cancelling the context stops workers from starting new packages like a
+	// failure does; the ones in flight finish or discard their partial files
+	watchDone := make(chan struct{})
+	var watcher sync.WaitGroup
+	watcher.Add(1)
+	go func() {
+		defer watcher.Done()
+		select {
+		case <-pm.ctx.Done():
+			select {
+			case errChan <- fmt.Errorf("install interrupted: %w", pm.ctx.Err()):
+				close(done)
+			default:
+			}
+		case <-watchDone:
 		}
-		workChan <- item
-	}
+	}()
 
-	for {
-		workerMutex.Lock()
-		workers := activeWorkers
-		workerMutex.Unlock()
-
-		if len(workChan) == 0 && workers == 0 {
-			break
+	// The tree is resolved one level at a time. Workers resolve and fetch the
+	// packages of a level in parallel but place them in the tree, which
+	// decides what is hoisted, in the sorted order of the level, so the tree
+	// and the lock are the same on every run. Their dependencies make up the
+	// next level.
+	var (
+		nextMu sync.Mutex
+		next   []QueueItem
+	)
+	enqueue := func(item QueueItem) {
+		nextMu.Lock()
+		next = append(next, item)
+		nextMu.Unlock()
+	}
+
+	// subDependency builds the queue dependency for name required by parent,
+	// substituting the version from a matching override, or else a matching
+	// yarn resolution, before it is resolved
+	subDependency := func(parent QueueItem, name, version string) (packagejson.Dependency, []string) {
+		ancestry := append(slices.Clone(parent.Ancestry), parent.Dep.Name)
+		if override, ok := packagejson.ResolveOverride(overrides, ancestry, name); ok {
+			version = override
+		} else if resolution, ok := packagejson.ResolveResolution(resolutions, ancestry, name); ok {
+			version = resolution
 		}
+		return newSubDependency(name, version), ancestry
+	}
 
-		select {
-		case item := <-workChan:
-			workerMutex.Lock()
-			activeWorkers++
-			workerMutex.Unlock()
+	// Optional packages skipped for this platform by a previous run are reused
+	// from the lock instead of fetching and parsing their manifest again
+	previousLock := pm.packageLock
+	if previousLock == nil {
+		previousLock = pm.packageJsonParse.PackageLock
+	}
 
-			wg.Add(1)
+	// recordSkippedOptional adds an optional package that is not installed on
+	// this platform to the lock, without downloading it
+	recordSkippedOptional := func(item QueueItem, pckItem packagejson.PackageItem) {
+		mapMutex.Lock()
+		defer mapMutex.Unlock()
 
-			go func(item QueueItem) {
-				defer func() {
-					wg.Done()
-					workerMutex.Lock()
-					activeWorkers--
-					workerMutex.Unlock()
-				}()
+		packageResolved := "node_modules/" + item.Dep.Name
+		if existing, ok := packageLock.Packages[packageResolved]; ok && existing.Version != pckItem.Version {
+			// Never clobber a different version already hoisted at this path
+			return
+		}
+		packageLock.Packages[packageResolved] = pckItem
+		if item.ParentName == "package.json" {
+			packageLock.OptionalDependencies[item.Dep.Name] = pckItem.Version
+		}
+	}
 
-				if item.Dep.Name == "" {
-					return
-				}
+	// recordIgnoredOptional adds an optional package left out by
+	// --ignore-optional to the lock, unless another package already took its
+	// path. It reports whether the package was recorded.
+	recordIgnoredOptional := func(item QueueItem, pckItem packagejson.PackageItem) bool {
+		mapMutex.Lock()
+		defer mapMutex.Unlock()
 
-				select {
-				case <-done:
+		packageResolved := "node_modules/" + item.Dep.Name
+		if _, ok := packageLock.Packages[packageResolved]; ok {
+			return false
+		}
+		packageLock.Packages[packageResolved] = pckItem
+		if item.ParentName == "package.json" {
+			packageLock.OptionalDependencies[item.Dep.Name] = pckItem.Version
+		}
+		return true
+	}
+
+	// recordLink adds a package linked from the local directory dir (a
+	// workspace or a file: dependency) to the lock and queues its
+	// dependencies. rootSpec is recorded when package.json requires it.
+	recordLink := func(item QueueItem, version, rootSpec, dir string, dependencies map[string]string) {
+		mapMutex.Lock()
+		defer mapMutex.Unlock()
+
+		packageResolved := "node_modules/" + item.Dep.Name
+		pckItem := packagejson.PackageItem{
+			Name:     item.Dep.Name,
+			Version:  version,
+			Resolved: "file:" + dir,
+			Link:     true,
+		}
+
+		if item.ParentName == "package.json" {
+			if item.IsDev {
+				packageLock.DevDependencies[item.Dep.Name] = rootSpec
+			} else {
+				packageLock.Dependencies[item.Dep.Name] = rootSpec
+			}
+		}
+
+		for _, depName := range slices.Sorted(maps.Keys(dependencies)) {
+			depVersion := dependencies[depName]
+			if pckItem.Dependencies == nil {
+				pckItem.Dependencies = make(map[string]string)
+			}
+			pckItem.Dependencies[depName] = depVersion
+
+			subDep, ancestry := subDependency(item, depName, depVersion)
+			enqueue(QueueItem{
+				Dep:        subDep,
+				ParentName: packageResolved,
+				IsDev:      item.IsDev,
+				Ancestry:   ancestry,
+				Path:       resolutionPath(item, item.Dep.Name, version),
+			})
+		}
+		packageLock.Packages[packageResolved] = pckItem
+	}
+
+	// fallBack handles a registry tarball that is gone: version is left out
+	// of every later resolution of the package, and item, with the items that
+	// reused its copy, is resolved again in the next level. It returns the
+	// version that is tried instead, or false when no other version satisfies
+	// item or the package ran out of fallbacks.
+	fallBack := func(item QueueItem, actualName string, npmPackage *manifestpkg.NPMPackage, version, packageResolved, processingKey string) (string, bool) {
+		mapMutex.Lock()
+		defer mapMutex.Unlock()
+
+		gone := append(slices.Clone(goneVersions[actualName]), version)
+		if len(gone) > maxTarballFallbacks {
+			return "", false
+		}
+		next := pm.versionInfo.GetVersion(item.Dep.Version, withoutVersions(npmPackage, gone))
+		if next == "" || !pm.versionInfo.SatisfiesConstraint(next, item.Dep.Version) {
+			return "", false
+		}
+
+		goneVersions[actualName] = gone
+		delete(processingPkgs, processingKey)
+		if hoisted, ok := packagesVersion[item.Dep.Name]; ok && hoisted.Dep.Version == version && packageResolved == "node_modules/"+item.Dep.Name {
+			delete(packagesVersion, item.Dep.Name)
+		}
+		enqueue(item)
+		for _, reused := range reusing[item.Dep.Name] {
+			enqueue(reused)
+		}
+		delete(reusing, item.Dep.Name)
+		return next, true
+	}
+
+	processItem := func(item QueueItem, order *turns, index int, timings *timing.Buffer) {
+		// Returning before placing the package gives up its turn
+		defer order.finish(index)
+
+		if item.Dep.Name == "" {
+			return
+		}
+
+		select {
+		case <-done:
+			return
+		default:
+		}
+
+		tracker := pm.progress.Track(progress.PhaseResolving)
+		defer tracker.Done()
+
+		// Use ActualName for downloading (handles aliases)
+		actualName := item.Dep.ActualName
+		if actualName == "" {
+			actualName = item.Dep.Name
+		}
+
+		// A forced resolution replaces the registry range everywhere; the lock
+		// still records what package.json asked for
+		spec := item.Dep.Version
+		if forced, ok := pm.forcedVersions[actualName]; ok && actualName == item.Dep.Name && !isRemoteSpec(spec) {
+			item.Dep.Version = forced
+		}
+
+		if pm.workspaceRegistry != nil {
+			if wsPkg, isWorkspace := pm.workspaceRegistry.GetWorkspacePackage(actualName); isWorkspace {
+				if !order.wait(index) {
 					return
+				}
+				mapMutex.Lock()
+				if packageLock.Workspaces == nil {
+					packageLock.Workspaces = make(map[string]string)
+				}
+				packageLock.Workspaces[item.Dep.Name] = wsPkg.Version
+				mapMutex.Unlock()
+
+				// Siblings required with the workspace: protocol are locked
+				// with the range it stands for
+				dependencies, err := pm.workspaceRegistry.ResolveDependencies(wsPkg.PackageJSON.GetDependencies())
+				if err != nil {
+					select {
+					case errChan <- fmt.Errorf("failed to resolve dependencies of workspace %s: %w", item.Dep.Name, err):
+						close(done)
+					default:
+					}
+					return
+				}
+
+				recordLink(item, wsPkg.Version, wsPkg.Version, wsPkg.Path, dependencies)
+				return
+			}
+		}
+
+		if strings.HasPrefix(spec, workspace.Protocol) {
+			select {
+			case errChan <- fmt.Errorf("%s@%s: %s is not a workspace package", item.Dep.Name, spec, item.Dep.Name):
+				close(done)
+			default:
+			}
+			return
+		}
+
+		// file: dependencies are linked like workspaces, from a directory
+		// relative to the project root
+		if dir, isFile := parseFileDependency(spec); isFile {
+			pkgJSON, err := pm.packageJsonParse.Parse(filepath.Join(dir, "package.json"))
+			if err != nil {
+				select {
+				case errChan <- fmt.Errorf("failed to read file dependency %s: %w", item.Dep.Name, err):
+					close(done)
 				default:
 				}
+				return
+			}
 
-				// Use ActualName for downloading (handles aliases)
-				actualName := item.Dep.ActualName
-				if actualName == "" {
-					actualName = item.Dep.Name
+			version, _ := pkgJSON.Version.(string)
+			if !order.wait(index) {
+				return
+			}
+			recordLink(item, version, spec, dir, pkgJSON.GetDependencies())
+			return
+		}
+
+		if item.IsOptional {
+			if skipped, ok := pm.previouslySkippedOptional(previousLock, item.Dep); ok {
+				if order.wait(index) {
+					recordSkippedOptional(item, skipped)
 				}
+				return
+			}
+		}
 
-				if pm.workspaceRegistry != nil {
-					if wsPkg, isWorkspace := pm.workspaceRegistry.GetWorkspacePackage(actualName); isWorkspace {
-						mapMutex.Lock()
-						packageResolved := "node_modules/" + item.Dep.Name
+		var version string
+		var tarballURL string
+		var resolvedURL string
+		var resolvedIntegrity string
+		var currentEtag string
+		var isRemoteDep bool
+		var commitSHA string
+		var gitDep *GitDependency
+		var npmPackage *manifestpkg.NPMPackage
+		var manifestTime time.Duration
+		var err error
+
+		// Check if this is a GitHub dependency
+		if ghDep, isGitHub := parseGitHubDependency(item.Dep.Version); isGitHub {
+			isRemoteDep = true
+
+			// Resolve GitHub ref to commit SHA
+			if pm.config.Offline {
+				commitSHA, err = offlineGitRef(ghDep.Ref, item.Dep.Version)
+			} else {
+				commitSHA, err = resolveGitHubRef(ghDep.Owner, ghDep.Repo, ghDep.Ref)
+			}
+			if err != nil {
+				if item.IsOptional || item.IsPeerOptional {
+					pm.warn(progress.WarningSkippedOptional, "Optional GitHub dependency %s failed to resolve: %v", item.Dep.Name, err)
+					return
+				}
+				select {
+				case errChan <- fmt.Errorf("failed to resolve GitHub dependency %s: %w", item.Dep.Name, err):
+					close(done)
+				default:
+				}
+				return
+			}
 
-						pckItem := packagejson.PackageItem{
-							Name:     item.Dep.Name,
-							Version:  wsPkg.Version,
-							Resolved: "file:" + wsPkg.Path,
-							Link:     true,
-						}
-						packageLock.Packages[packageResolved] = pckItem
+			// Use full commit SHA as version (needed for lock file and sub-dependency resolution)
+			version = commitSHA
+			tarballURL = buildGitHubTarballURL(ghDep.Owner, ghDep.Repo, commitSHA)
+			resolvedURL = buildGitHubResolvedURL(ghDep.Owner, ghDep.Repo, commitSHA)
+		} else if parsed, isGit := parseGitDependency(item.Dep.Version); isGit {
+			isRemoteDep = true
+			gitDep = parsed
+
+			// Resolve the tag, branch or semver: range to a commit with git
+			// ls-remote; local git+file repositories need no network
+			if pm.config.Offline && gitDep.Protocol != "file" {
+				commitSHA, err = offlineGitRef(gitDep.Ref, item.Dep.Version)
+			} else {
+				commitSHA, err = resolveGitRef(gitDep)
+			}
+			if err != nil {
+				if item.IsOptional || item.IsPeerOptional {
+					pm.warn(progress.WarningSkippedOptional, "Optional git dependency %s failed to resolve: %v", item.Dep.Name, err)
+					return
+				}
+				select {
+				case errChan <- fmt.Errorf("failed to resolve git dependency %s: %w", item.Dep.Name, err):
+					close(done)
+				default:
+				}
+				return
+			}
 
-						if packageLock.Workspaces == nil {
-							packageLock.Workspaces = make(map[string]string)
-						}
-						packageLock.Workspaces[item.Dep.Name] = wsPkg.Version
+			version = commitSHA
+			resolvedURL = gitDep.ResolvedURL(commitSHA)
+			// Hosts without an archive endpoint leave tarballURL empty and are cloned
+			tarballURL, _ = gitDep.ArchiveURL(commitSHA)
+		} else if isTarballURL(item.Dep.Version) {
+			isRemoteDep = true
+
+			// The version is only known once the tarball's package.json is read,
+			// unless a failed install already cached it
+			if entry, ok := resume.lookup(actualName, item.Dep.Version); ok && utils.FolderExists(filepath.Join(pm.packagesPath, actualName+"@"+tarball.CacheVersion(actualName, entry.Version, item.Dep.Version))) {
+				version, resolvedIntegrity = entry.Version, entry.Integrity
+			} else {
+				version, resolvedIntegrity, err = pm.fetchTarballURL(actualName, item.Dep.Version)
+			}
+			if err != nil {
+				if item.IsOptional || item.IsPeerOptional {
+					pm.warn(progress.WarningSkippedOptional, "Optional tarball dependency %s failed to download: %v", item.Dep.Name, err)
+					return
+				}
+				select {
+				case errChan <- fmt.Errorf("failed to fetch tarball dependency %s: %w", item.Dep.Name, err):
+					close(done)
+				default:
+				}
+				return
+			}
 
-						if item.ParentName == "package.json" {
-							if item.IsDev {
-								packageLock.DevDependencies[item.Dep.Name] = wsPkg.Version
-							} else {
-								packageLock.Dependencies[item.Dep.Name] = wsPkg.Version
-							}
-						}
+			tarballURL = item.Dep.Version
+			resolvedURL = item.Dep.Version
+		} else if pm.config.LocalTarballDir != "" {
+			// Registry packages come from the local tarball directory instead:
+			// the version is resolved against the tarballs there and the chosen
+			// one is copied into the tarball cache, so it is never downloaded
+			npmPackage, version, _, err = pm.resolveLocalTarball(actualName, item.Dep.Version)
+			if err == nil {
+				err = pm.copyLocalTarball(actualName, version, generateUniqueTarballName(actualName, version))
+			}
+			if err != nil {
+				if item.IsOptional || item.IsPeerOptional {
+					pm.warn(progress.WarningSkippedOptional, "Optional dependency %s is not in the local tarball directory: %v", item.Dep.Name, err)
+					return
+				}
+				select {
+				case errChan <- err:
+					close(done)
+				default:
+				}
+				return
+			}
+		} else {
+			// NPM package - download manifest and resolve version
+			pm.downloadMu.Lock()
+			pkgLock, exists := pm.downloadLocks[actualName]
+			if !exists {
+				pkgLock = &sync.Mutex{}
+				pm.downloadLocks[actualName] = pkgLock
+			}
+			pm.downloadMu.Unlock()
 
-						for depName, depVersion := range wsPkg.PackageJSON.GetDependencies() {
-							pkgItem := packageLock.Packages[packageResolved]
-							if pkgItem.Dependencies == nil {
-								pkgItem.Dependencies = make(map[string]string)
-							}
-							pkgItem.Dependencies[depName] = depVersion
-							packageLock.Packages[packageResolved] = pkgItem
-
-							subDep := packagejson.Dependency{Name: depName, Version: depVersion, ActualName: depName}
-							workChan <- QueueItem{
-								Dep:        subDep,
-								ParentName: packageResolved,
-								IsDev:      item.IsDev,
-							}
-						}
+			pkgLock.Lock()
 
-						mapMutex.Unlock()
+			manifestPath := pm.manifest.FilePath(actualName)
 
-						return
+			downloadManifest := func() error {
+				if pm.config.Offline {
+					return errNotCached(actualName, item.Dep.Version)
+				}
+				var downloadErr error
+				start := time.Now()
+				currentEtag, _, downloadErr = pm.manifest.Download(actualName, pm.Etag.Get(pm.manifest.EtagKey(actualName)))
+				manifestTime += time.Since(start)
+				if downloadErr == nil {
+					pm.Etag.Set(pm.manifest.EtagKey(actualName), currentEtag, time.Now())
+				}
+				return downloadErr
+			}
+
+			manifestCached := false
+			if _, err := os.Stat(manifestPath); err == nil {
+				currentEtag = pm.Etag.Get(pm.manifest.EtagKey(actualName))
+				manifestCached = true
+
+				// Past its max-age the cached manifest is revalidated: a 304
+				// keeps it and a 200 replaces it. If the registry cannot be
+				// reached the cached one is still used.
+				if pm.manifestStale(actualName) {
+					if downloadErr := downloadManifest(); downloadErr != nil {
+						pm.logger.Debugf("could not revalidate the manifest of %s, using the cached one: %v", actualName, downloadErr)
 					}
 				}
+			} else if downloadErr := downloadManifest(); downloadErr != nil {
+				pkgLock.Unlock()
+				if item.IsOptional || item.IsPeerOptional {
+					pm.warn(progress.WarningSkippedOptional, "Optional dependency %s failed to download manifest: %v", item.Dep.Name, downloadErr)
+					return
+				}
+				select {
+				case errChan <- downloadErr:
+					close(done)
+				default:
+				}
+				return
+			}
 
-				var version string
-				var tarballURL string
-				var resolvedURL string
-				var currentEtag string
-				var isGitHubDep bool
-				var commitSHA string
-				var npmPackage *manifestpkg.NPMPackage
-				var err error
+			npmPackage, err = pm.parseJsonManifest.Parse(manifestPath)
 
-				// Check if this is a GitHub dependency
-				if ghDep, isGitHub := parseGitHubDependency(item.Dep.Version); isGitHub {
-					isGitHubDep = true
+			// With --prefer-offline a cached manifest without a matching
+			// version is a miss, refreshed from the registry
+			if err == nil && manifestCached && pm.config.PreferOffline && !pm.matchesManifest(item.Dep.Version, npmPackage) {
+				if err = downloadManifest(); err == nil {
+					npmPackage, err = pm.parseJsonManifest.Parse(manifestPath)
+				}
+			}
 
-					// Resolve GitHub ref to commit SHA
-					commitSHA, err = resolveGitHubRef(ghDep.Owner, ghDep.Repo, ghDep.Ref)
-					if err != nil {
-						if item.IsOptional || item.IsPeerOptional {
-							fmt.Printf("Warning: Optional GitHub dependency %s failed to resolve: %v\n", item.Dep.Name, err)
-							return
-						}
-						select {
-						case errChan <- fmt.Errorf("failed to resolve GitHub dependency %s: %w", item.Dep.Name, err):
-							close(done)
-						default:
-						}
-						return
-					}
+			if err == nil && pm.config.Offline && !pm.matchesManifest(item.Dep.Version, npmPackage) {
+				err = errNotCached(actualName, item.Dep.Version)
+			}
+			pkgLock.Unlock()
 
-					// Use full commit SHA as version (needed for lock file and sub-dependency resolution)
-					version = commitSHA
-					tarballURL = buildGitHubTarballURL(ghDep.Owner, ghDep.Repo, commitSHA)
-					resolvedURL = buildGitHubResolvedURL(ghDep.Owner, ghDep.Repo, commitSHA)
-				} else {
-					// NPM package - download manifest and resolve version
-					pm.downloadMu.Lock()
-					pkgLock, exists := pm.downloadLocks[actualName]
-					if !exists {
-						pkgLock = &sync.Mutex{}
-						pm.downloadLocks[actualName] = pkgLock
-					}
-					pm.downloadMu.Unlock()
-
-					pkgLock.Lock()
-
-					manifestPath := filepath.Join(pm.manifest.Path, actualName+".json")
-
-					if _, err := os.Stat(manifestPath); err == nil {
-						currentEtag = pm.Etag.Get(actualName)
-					} else {
-						etag := pm.Etag.Get(actualName)
-						var downloadErr error
-						currentEtag, _, downloadErr = pm.manifest.Download(actualName, etag)
-						if downloadErr != nil {
-							pkgLock.Unlock()
-							if item.IsOptional || item.IsPeerOptional {
-								fmt.Printf("Warning: Optional dependency %s failed to download manifest: %v\n", item.Dep.Name, downloadErr)
-								return
-							}
-							select {
-							case errChan <- downloadErr:
-								close(done)
-							default:
-							}
-							return
-						}
+			if err != nil {
+				if item.IsOptional || item.IsPeerOptional {
+					pm.warn(progress.WarningSkippedOptional, "Optional dependency %s failed to parse manifest: %v", item.Dep.Name, err)
+					return
+				}
+				select {
+				case errChan <- err:
+					close(done)
+				default:
+				}
+				return
+			}
+
+			// Versions whose tarball turned out to be gone are never picked again
+			mapMutex.Lock()
+			gone := goneVersions[actualName]
+			mapMutex.Unlock()
+			if len(gone) > 0 {
+				npmPackage = withoutVersions(npmPackage, gone)
+			}
+
+			// A failed install keeps the versions it resolved, so the resumed one
+			// settles on the same tree
+			if entry, ok := resume.lookup(actualName, item.Dep.Version); ok && npmPackage.Versions[entry.Version].Version != "" {
+				version = entry.Version
+			} else {
+				version = pm.versionInfo.GetVersion(item.Dep.Version, npmPackage)
+			}
+
+			if trackConflicts && actualName == item.Dep.Name {
+				mapMutex.Lock()
+				if _, ok := availableVersions[actualName]; !ok {
+					versions := make([]string, 0, len(npmPackage.Versions))
+					for v := range npmPackage.Versions {
+						versions = append(versions, v)
 					}
+					availableVersions[actualName] = versions
+				}
+				requestedRanges[actualName] = append(requestedRanges[actualName], ConflictRange{
+					Parent: item.ParentName,
+					Range:  item.Dep.Version,
+				})
+				mapMutex.Unlock()
+			}
+		}
 
-					npmPackage, err = pm.parseJsonManifest.Parse(manifestPath)
-					pkgLock.Unlock()
+		packageKey := actualName + "@" + version
+		pm.logger.Debugf("resolved %s@%s to %s, required by %s", item.Dep.Name, item.Dep.Version, version, item.ParentName)
+		if manifestTime > 0 {
+			timings.Add(packageKey, timing.PhaseManifest, manifestTime)
+		}
 
-					if err != nil {
-						if item.IsOptional || item.IsPeerOptional {
-							fmt.Printf("Warning: Optional dependency %s failed to parse manifest: %v\n", item.Dep.Name, err)
-							return
-						}
-						select {
-						case errChan <- err:
-							close(done)
-						default:
-						}
+		// Check platform compatibility for optional dependencies
+		if item.IsOptional {
+			if versionData, ok := npmPackage.Versions[version]; ok {
+				if !utils.IsCompatiblePlatform(versionData.OS, versionData.CPU) {
+					// Still add to lock file but skip download
+					if !order.wait(index) {
 						return
 					}
-
-					version = pm.versionInfo.GetVersion(item.Dep.Version, npmPackage)
+					recordSkippedOptional(item, packagejson.PackageItem{
+						Name:     item.Dep.Name,
+						Version:  version,
+						Resolved: "",
+						Optional: true,
+						Skipped:  true,
+						OS:       versionData.OS,
+						CPU:      versionData.CPU,
+					})
+					return
 				}
+			}
+		} else if !pm.config.Force && !item.IsPeerOptional && npmPackage != nil {
+			// Like npm, a required package that excludes the current
+			// platform fails the install
+			if versionData, ok := npmPackage.Versions[version]; ok {
+				if err := checkPlatform(actualName+"@"+version, versionData.OS, versionData.CPU); err != nil {
+					select {
+					case errChan <- err:
+						close(done)
+					default:
+					}
+					return
+				}
+			}
+		}
 
-				packageKey := actualName + "@" + version
+		// With --ignore-optional the package is locked, so that other machines
+		// install it from the lock, but never downloaded here. Its dependencies
+		// are locked the same way, as optional too.
+		if item.IsOptional && pm.config.IgnoreOptional {
+			pckItem := packagejson.PackageItem{
+				Name:      item.Dep.Name,
+				Version:   version,
+				Resolved:  resolvedURL,
+				Integrity: resolvedIntegrity,
+				Optional:  true,
+			}
+			var dependencies map[string]string
+			if npmPackage != nil {
+				versionData := npmPackage.Versions[version]
+				pckItem.Resolved = pm.tarballURL(actualName, version)
+				pckItem.Integrity = versionData.Dist.Integrity
+				pckItem.OS = versionData.OS
+				pckItem.CPU = versionData.CPU
+				if len(versionData.Dependencies) > 0 {
+					pckItem.Dependencies = versionData.Dependencies
+				}
+				if len(versionData.OptionalDependencies) > 0 {
+					pckItem.OptionalDependencies = versionData.OptionalDependencies
+				}
+				dependencies = maps.Clone(versionData.Dependencies)
+				if dependencies == nil {
+					dependencies = make(map[string]string)
+				}
+				maps.Copy(dependencies, versionData.OptionalDependencies)
+			}
 
-				// Check platform compatibility for optional dependencies
-				if item.IsOptional {
-					if versionData, ok := npmPackage.Versions[version]; ok {
-						if !utils.IsCompatiblePlatform(versionData.OS, versionData.CPU) {
-							// Still add to lock file but skip download
-							mapMutex.Lock()
-							packageResolved := "node_modules/" + item.Dep.Name
-							pckItem := packagejson.PackageItem{
-								Name:     item.Dep.Name,
-								Version:  version,
-								Resolved: "",
-								Optional: true,
-								OS:       versionData.OS,
-								CPU:      versionData.CPU,
-							}
-							packageLock.Packages[packageResolved] = pckItem
-							if item.ParentName == "package.json" {
-								packageLock.OptionalDependencies[item.Dep.Name] = version
-							}
-							mapMutex.Unlock()
-							return
-						}
+			if order.wait(index) && recordIgnoredOptional(item, pckItem) {
+				packageResolved := "node_modules/" + item.Dep.Name
+				for _, name := range slices.Sorted(maps.Keys(dependencies)) {
+					depVersion := dependencies[name]
+					if name == actualName {
+						continue
 					}
+					subDep, ancestry := subDependency(item, name, depVersion)
+					enqueue(QueueItem{
+						Dep:        subDep,
+						ParentName: packageResolved,
+						IsOptional: true,
+						Ancestry:   ancestry,
+						Path:       resolutionPath(item, actualName, version),
+					})
 				}
+			}
+			return
+		}
 
-				var packageResolved string
-				var processingKey string
+		// Check engines.node once per resolved package@version
+		if npmPackage != nil {
+			engines := npmPackage.Versions[version].Engines
+			check, _ := engineChecked.LoadOrStore(packageKey, sync.OnceValue(func() error {
+				return pm.checkEngine(actualName, version, engines)
+			}))
+			if err := check.(func() error)(); err != nil {
+				if item.IsOptional || item.IsPeerOptional {
+					pm.warn(progress.WarningSkippedOptional, "Skipping optional dependency %s: %v", item.Dep.Name, err)
+					return
+				}
+				select {
+				case errChan <- err:
+					close(done)
+				default:
+				}
+				return
+			}
+		}
 
-				mapMutex.Lock()
-				// Check if this exact package@version has already been processed or is being processed
-				if processingPkgs[packageKey] {
-					mapMutex.Unlock()
+		// Verify the registry signature once per resolved package@version
+		if npmPackage != nil {
+			check, _ := signatureCheck.LoadOrStore(packageKey, sync.OnceValue(func() error {
+				return pm.checkSignature(actualName, version, npmPackage)
+			}))
+			if err := check.(func() error)(); err != nil {
+				if item.IsOptional || item.IsPeerOptional {
+					pm.warn(progress.WarningSkippedOptional, "Skipping optional dependency %s: %v", item.Dep.Name, err)
 					return
 				}
-				if existingPkg, ok := packagesVersion[item.Dep.Name]; ok {
-					// Check if the existing hoisted version satisfies the current constraint
-					// existingPkg.Dep.Version is the resolved version (e.g., "0.1.0")
-					// item.Dep.Version is the version constraint (e.g., "^0.3.0")
-					existingSatisfiesConstraint := pm.versionInfo.SatisfiesConstraint(existingPkg.Dep.Version, item.Dep.Version)
-
-					if !existingSatisfiesConstraint {
-						// ParentName is now the full resolved path (e.g., "node_modules/wrap-ansi")
-						// or "package.json" for top-level dependencies
-						if item.ParentName == "package.json" {
-							packageResolved = "node_modules/" + item.Dep.Name
-							processingKey = packageKey
-						} else {
-							packageResolved = item.ParentName + "/node_modules/" + item.Dep.Name
-							// Use a nested-specific key that includes the parent path
-							// This allows the same version to be nested under multiple parents
-							processingKey = packageResolved + "@" + version
-						}
+				select {
+				case errChan <- err:
+					close(done)
+				default:
+				}
+				return
+			}
+		}
 
-						// Check if this specific nested location has already been processed
-						if processingPkgs[processingKey] {
-							mapMutex.Unlock()
-							return
-						}
+		var packageResolved string
+		var processingKey string
 
-						processingPkgs[processingKey] = true
-					} else {
-						mapMutex.Unlock()
-						return
-					}
-				} else {
+		if !order.wait(index) {
+			return
+		}
+		mapMutex.Lock()
+		// Check if this exact package@version has already been processed or is being processed
+		if processingPkgs[packageKey] {
+			reusing[item.Dep.Name] = append(reusing[item.Dep.Name], item)
+			mapMutex.Unlock()
+			pm.logger.Debugf("%s already resolved", packageKey)
+			return
+		}
+		if existingPkg, ok := packagesVersion[item.Dep.Name]; ok {
+			// Check if the existing hoisted version satisfies the current constraint
+			// existingPkg.Dep.Version is the resolved version (e.g., "0.1.0")
+			// item.Dep.Version is the version constraint (e.g., "^0.3.0")
+			existingSatisfiesConstraint := pm.versionInfo.SatisfiesConstraint(existingPkg.Dep.Version, item.Dep.Version)
+
+			if !existingSatisfiesConstraint {
+				// An ancestor installed in a directory item's parent resolves
+				// through closes a cycle. Node's lookup up the tree finds it,
+				// so the cycle stops here instead of nesting another copy at
+				// every level.
+				if closesCycle(item, packageKey) {
+					mapMutex.Unlock()
+					pm.logger.Debugf("%s closes a dependency cycle through %s", packageKey, item.ParentName)
+					return
+				}
+
+				// ParentName is now the full resolved path (e.g., "node_modules/wrap-ansi")
+				// or "package.json" for top-level dependencies
+				if item.ParentName == "package.json" {
 					packageResolved = "node_modules/" + item.Dep.Name
 					processingKey = packageKey
-					packagesVersion[item.Dep.Name] = QueueItem{
-						Dep:        packagejson.Dependency{Name: item.Dep.Name, Version: version},
-						ParentName: item.ParentName,
-					}
+				} else {
+					packageResolved = item.ParentName + "/node_modules/" + item.Dep.Name
+					// Use a nested-specific key that includes the parent path
+					// This allows the same version to be nested under multiple parents
+					processingKey = packageResolved + "@" + version
+				}
 
-					processingPkgs[processingKey] = true
+				// Check if this specific nested location has already been processed
+				if processingPkgs[processingKey] {
+					reusing[item.Dep.Name] = append(reusing[item.Dep.Name], item)
+					mapMutex.Unlock()
+					pm.logger.Debugf("%s already resolved at %s", packageKey, packageResolved)
+					return
 				}
+
+				processingPkgs[processingKey] = true
+				pm.logger.Debugf("placing %s at %s: hoisted %s does not satisfy %s", packageKey, packageResolved, existingPkg.Dep.Version, item.Dep.Version)
+			} else {
+				reusing[item.Dep.Name] = append(reusing[item.Dep.Name], item)
 				mapMutex.Unlock()
+				pm.logger.Debugf("hoisted %s@%s satisfies %s required by %s", item.Dep.Name, existingPkg.Dep.Version, item.Dep.Version, item.ParentName)
+				return
+			}
+		} else {
+			packageResolved = "node_modules/" + item.Dep.Name
+			processingKey = packageKey
+			packagesVersion[item.Dep.Name] = QueueItem{
+				Dep:        packagejson.Dependency{Name: item.Dep.Name, Version: version},
+				ParentName: item.ParentName,
+			}
 
-				configPackageVersion := filepath.Join(pm.packagesPath, actualName+"@"+version)
+			processingPkgs[processingKey] = true
+		}
+		mapMutex.Unlock()
+		order.finish(index)
 
-				// Build tarball URL if not already set (for npm packages)
-				if !isGitHubDep {
-					tarballName := actualName
-					if strings.HasPrefix(actualName, "@") && strings.Contains(actualName, "/") {
-						parts := strings.Split(actualName, "/")
-						tarballName = parts[1]
-					}
-					tarballURL = fmt.Sprintf("%s%s/-/%s-%s.tgz", npmRegistryURL, actualName, tarballName, version)
-					resolvedURL = tarballURL
-				}
+		// Build tarball URL if not already set (for npm packages)
+		if !isRemoteDep {
+			tarballURL = pm.tarballURL(actualName, version)
+			resolvedURL = tarballURL
+		}
 
-				uniqueTarballName := generateUniqueTarballName(actualName, version)
+		// Tarball URL dependencies are cached apart from the registry package
+		// of the same version
+		cacheVersion := tarball.CacheVersion(actualName, version, resolvedURL)
+		configPackageVersion := filepath.Join(pm.packagesPath, actualName+"@"+cacheVersion)
+		uniqueTarballName := generateUniqueTarballName(actualName, cacheVersion)
 
-				// Lock based on package@version to prevent concurrent processing of the same package
-				pm.downloadMu.Lock()
-				packageLock_, exists := pm.downloadLocks[packageKey]
-				if !exists {
-					packageLock_ = &sync.Mutex{}
-					pm.downloadLocks[packageKey] = packageLock_
+		// Lock based on package@version to prevent concurrent processing of the same package
+		unlock, err := pm.lockPackage(actualName + "@" + cacheVersion)
+		if err != nil {
+			select {
+			case errChan <- err:
+				close(done)
+			default:
+			}
+			return
+		}
+		defer unlock()
+
+		if gitDep != nil && tarballURL == "" && !utils.FolderExists(configPackageVersion) {
+			var err error
+			if pm.config.Offline && gitDep.Protocol != "file" {
+				err = errNotCached(actualName, version)
+			} else {
+				err = cloneGitPackage(gitDep.CloneURL, commitSHA, configPackageVersion)
+			}
+			if err != nil {
+				if item.IsOptional || item.IsPeerOptional {
+					pm.warn(progress.WarningSkippedOptional, "Optional dependency %s failed to clone: %v", item.Dep.Name, err)
+					return
 				}
-				pm.downloadMu.Unlock()
+				select {
+				case errChan <- err:
+					close(done)
+				default:
+				}
+				return
+			}
+		}
 
-				packageLock_.Lock()
-				defer packageLock_.Unlock()
+		// Check again if folder exists after acquiring lock
+		if !utils.FolderExists(configPackageVersion) {
+			if tarballURL == "" || version == "" {
+				return
+			}
+			// Drop a link left to a store entry that was removed
+			os.Remove(configPackageVersion)
 
-				// Check again if folder exists after acquiring lock
-				if !utils.FolderExists(configPackageVersion) {
-					if tarballURL == "" || version == "" {
-						return
-					}
+			tarballPath := filepath.Join(pm.tarball.TarballPath, uniqueTarballName)
 
-					tarballPath := filepath.Join(pm.tarball.TarballPath, uniqueTarballName)
+			// Validate tarball (checks existence and integrity)
+			shouldDownloadTarball := true
+			if utils.ValidateTarball(tarballPath) {
+				shouldDownloadTarball = false
+			} else {
+				os.Remove(tarballPath)
+			}
 
-					// Validate tarball (checks existence and integrity)
-					shouldDownloadTarball := true
-					if utils.ValidateTarball(tarballPath) {
-						shouldDownloadTarball = false
-					} else {
-						os.Remove(tarballPath)
+			if shouldDownloadTarball {
+				tracker.Move(progress.PhaseDownloading)
+				start := time.Now()
+				if pm.config.Offline {
+					err = errNotCached(actualName, version)
+				} else if isRemoteDep {
+					// Git and tarball URL deps skip integrity validation (HTTPS provides integrity)
+					_, err = pm.tarball.DownloadAs(tarballURL, uniqueTarballName)
+				} else {
+					// npm packages: validate integrity hash (strict mode)
+					var integrityHash string
+					if versionData, ok := npmPackage.Versions[version]; ok {
+						integrityHash = versionData.Dist.Integrity
 					}
-
-					if shouldDownloadTarball {
-						if isGitHubDep {
-							// GitHub deps skip integrity validation (HTTPS provides integrity)
-							err = pm.tarball.DownloadAs(tarballURL, uniqueTarballName)
-						} else {
-							// npm packages: validate integrity hash (strict mode)
-							var integrityHash string
-							if versionData, ok := npmPackage.Versions[version]; ok {
-								integrityHash = versionData.Dist.Integrity
-							}
-							err = pm.tarball.DownloadAndValidate(tarballURL, uniqueTarballName, integrityHash)
-						}
-						if err != nil {
-							// Handle integrity errors with clear security message
-							if errors.Is(err, integrity.ErrIntegrityMismatch) {
-								err = fmt.Errorf("SECURITY: integrity check failed for %s@%s: %w", actualName, version, err)
-							} else if errors.Is(err, integrity.ErrNoIntegrity) {
-								err = fmt.Errorf("SECURITY: no integrity hash available for %s@%s (strict mode)", actualName, version)
-							}
-							if item.IsOptional || item.IsPeerOptional {
-								fmt.Printf("Warning: Optional dependency %s failed to download tarball: %v\n", item.Dep.Name, err)
-								return
-							}
-							select {
-							case errChan <- err:
-								close(done)
-							default:
-							}
-							return
-						}
+					// Lock the registry that served the tarball, a fallback
+					// one when the primary registry is down
+					var servedURL string
+					servedURL, err = pm.tarball.DownloadAndValidate(tarballURL, uniqueTarballName, integrityHash)
+					if err == nil {
+						resolvedURL = servedURL
 					}
-
-					// Extract tarball (extractor strips first dir component for both npm and GitHub)
-					err = pm.extractor.Extract(tarballPath, configPackageVersion)
-					if err != nil {
-						if item.IsOptional || item.IsPeerOptional {
-							fmt.Printf("Warning: Optional dependency %s failed to extract: %v\n", item.Dep.Name, err)
+				}
+				timings.Since(packageKey, timing.PhaseDownload, start)
+				if err != nil {
+					// Handle integrity errors with clear security message
+					if errors.Is(err, integrity.ErrIntegrityMismatch) {
+						err = fmt.Errorf("SECURITY: integrity check failed for %s@%s: %w", actualName, version, err)
+					} else if errors.Is(err, integrity.ErrNoIntegrity) {
+						err = fmt.Errorf("SECURITY: no integrity hash available for %s@%s (strict mode)", actualName, version)
+					}
+					if item.IsOptional || item.IsPeerOptional {
+						pm.warn(progress.WarningSkippedOptional, "Optional dependency %s failed to download tarball: %v", item.Dep.Name, err)
+						return
+					}
+					// Like npm, a version whose tarball was removed falls back
+					// to the next best one satisfying the range
+					if !isRemoteDep && errors.Is(err, tarball.ErrNotFound) {
+						if next, ok := fallBack(item, actualName, npmPackage, version, packageResolved, processingKey); ok {
+							pm.warn(progress.WarningOther, "The tarball of %s@%s is gone from the registry, trying %s", actualName, version, next)
 							return
 						}
-						select {
-						case errChan <- err:
-							close(done)
-						default:
-						}
-						return
 					}
+					select {
+					case errChan <- err:
+						close(done)
+					default:
+					}
+					return
 				}
+			}
 
-				mapMutex.Lock()
-				pckItem := packagejson.PackageItem{
-					Name:     item.Dep.Name,
-					Version:  version,
-					Resolved: resolvedURL,
-					Etag:     currentEtag,
-					Optional: item.IsOptional,
+			// Extract tarball (extractor strips first dir component for both npm and GitHub)
+			tracker.Move(progress.PhaseExtracting)
+			start := time.Now()
+			err = pm.extractPackage(tarballPath, configPackageVersion)
+			timings.Since(packageKey, timing.PhaseExtract, start)
+			if err != nil {
+				if item.IsOptional || item.IsPeerOptional {
+					pm.warn(progress.WarningSkippedOptional, "Optional dependency %s failed to extract: %v", item.Dep.Name, err)
+					return
 				}
-				// Add OS, CPU, and Integrity fields if available (npm packages only)
-				if !isGitHubDep {
-					if versionData, ok := npmPackage.Versions[version]; ok {
-						if len(versionData.OS) > 0 {
-							pckItem.OS = versionData.OS
-						}
-						if len(versionData.CPU) > 0 {
-							pckItem.CPU = versionData.CPU
-						}
-						if versionData.Dist.Integrity != "" {
-							pckItem.Integrity = versionData.Dist.Integrity
-						}
-					}
+				select {
+				case errChan <- err:
+					close(done)
+				default:
+				}
+				return
+			}
+		}
+
+		// npm packages record a sha512 integrity, computed from the tarball when
+		// the registry only publishes an older algorithm or none
+		if !isRemoteDep {
+			if versionData, ok := npmPackage.Versions[version]; ok {
+				resolvedIntegrity = versionData.Dist.Integrity
+			}
+			if !strings.Contains(resolvedIntegrity, "sha512-") {
+				tarballPath := filepath.Join(pm.tarball.TarballPath, uniqueTarballName)
+				if sri, err := integrity.GenerateIntegrity(tarballPath, []string{"sha512"}); err == nil {
+					resolvedIntegrity = sri
+				}
+			}
+
+			// Also moves entries cached before the content store was enabled
+			if _, err := pm.storePackage(configPackageVersion, resolvedIntegrity); err != nil {
+				if item.IsOptional || item.IsPeerOptional {
+					pm.warn(progress.WarningSkippedOptional, "Optional dependency %s failed to store: %v", item.Dep.Name, err)
+					return
+				}
+				select {
+				case errChan <- err:
+					close(done)
+				default:
 				}
-				packageLock.Packages[packageResolved] = pckItem
-				pm.progress.SetStatus(fmt.Sprintf("↓ %s@%s", item.Dep.Name, version))
+				return
+			}
+		}
 
-				// Update Dependencies/DevDependencies with resolved version for top-level packages
-				if item.ParentName == "package.json" {
-					if item.IsDev {
-						packageLock.DevDependencies[item.Dep.Name] = item.Dep.Version
-					} else if !item.IsOptional && !item.IsPeer {
-						packageLock.Dependencies[item.Dep.Name] = item.Dep.Version
-					}
-				}
+		if !isRemoteDep || isTarballURL(item.Dep.Version) {
+			if err := resume.record(actualName, item.Dep.Version, version, resolvedIntegrity); err != nil {
+				pm.warn(progress.WarningOther, "%v", err)
+			}
+		}
 
-				// Add to OptionalDependencies in lock if this is a top-level optional dependency
-				if item.IsOptional && item.ParentName == "package.json" {
-					packageLock.OptionalDependencies[item.Dep.Name] = version
+		mapMutex.Lock()
+		pckItem := packagejson.PackageItem{
+			Name:      item.Dep.Name,
+			Version:   version,
+			Resolved:  resolvedURL,
+			Integrity: resolvedIntegrity,
+			Etag:      currentEtag,
+			Optional:  item.IsOptional,
+		}
+		// Add OS and CPU fields if available (npm packages only)
+		if !isRemoteDep {
+			if versionData, ok := npmPackage.Versions[version]; ok {
+				if len(versionData.OS) > 0 {
+					pckItem.OS = versionData.OS
 				}
-
-				// Track peer dependencies that were auto-installed
-				if item.IsPeer {
-					packageLock.PeerDependencies[item.Dep.Name] = version
+				if len(versionData.CPU) > 0 {
+					pckItem.CPU = versionData.CPU
 				}
-				mapMutex.Unlock()
+				if versionData.Deprecated != "" {
+					deprecation := Deprecation{Name: actualName, Version: version, Message: string(versionData.Deprecated)}
+					if _, seen := deprecations[deprecation.String()]; !seen {
+						deprecations[deprecation.String()] = deprecation
+						pm.logger.Warnf("%s", deprecation.Warning())
+						pm.progress.Report(progress.WarningDeprecation, deprecation.Warning())
+					}
+				}
+			}
+		}
+		packageLock.Packages[packageResolved] = pckItem
+
+		// Update Dependencies/DevDependencies with resolved version for top-level packages
+		if item.ParentName == "package.json" {
+			if item.IsDev {
+				packageLock.DevDependencies[item.Dep.Name] = spec
+			} else if !item.IsOptional && !item.IsPeer {
+				packageLock.Dependencies[item.Dep.Name] = spec
+			}
+		}
 
-				packageDir := filepath.Join(pm.packagesPath, actualName+"@"+version)
-				packageJsonPath := filepath.Join(packageDir, "package.json")
+		// Add to OptionalDependencies in lock if this is a top-level optional dependency
+		if item.IsOptional && item.ParentName == "package.json" {
+			packageLock.OptionalDependencies[item.Dep.Name] = version
+		}
 
-				// Validate package.json exists and is not corrupted (non-zero size)
-				fileInfo, statErr := os.Stat(packageJsonPath)
-				if statErr != nil || fileInfo.Size() == 0 {
-					// Package.json is missing or empty - remove corrupted package directory
-					err = os.RemoveAll(packageDir)
-					if err != nil {
-						errChan <- fmt.Errorf("failed to remove corrupted package %s: %w", actualName, err)
-						close(done)
-						return
-					}
+		// Track peer dependencies that were auto-installed
+		if item.IsPeer {
+			packageLock.PeerDependencies[item.Dep.Name] = version
+		}
+		mapMutex.Unlock()
 
-					// Re-extract from tarball
-					uniqueTarballName := generateUniqueTarballName(actualName, version)
-					tarballPath := filepath.Join(pm.tarball.TarballPath, uniqueTarballName)
+		packageJsonPath := filepath.Join(configPackageVersion, "package.json")
 
-					if extractErr := pm.extractor.Extract(tarballPath, packageDir); extractErr != nil {
-						select {
-						case errChan <- fmt.Errorf("failed to re-extract corrupted package %s: %w", actualName, extractErr):
-							close(done)
-						default:
-						}
-						return
-					}
+		// Validate package.json exists and is not corrupted (non-zero size)
+		fileInfo, statErr := os.Stat(packageJsonPath)
+		if statErr != nil || fileInfo.Size() == 0 {
+			// Package.json is missing or empty - remove corrupted package directory
+			err = os.RemoveAll(configPackageVersion)
+			if err != nil {
+				select {
+				case errChan <- fmt.Errorf("failed to remove corrupted package %s: %w", actualName, err):
+					close(done)
+				default:
 				}
+				return
+			}
 
-				data, err := pm.packageJsonParse.Parse(packageJsonPath)
-				if err != nil {
-					select {
-					case errChan <- err:
-						close(done)
-					default:
-					}
-					return
-				}
+			// Re-extract from tarball
+			tarballPath := filepath.Join(pm.tarball.TarballPath, uniqueTarballName)
 
-				mapMutex.Lock()
-				pkgItem := packageLock.Packages[packageResolved]
-				pkgItem.Scripts = data.Scripts
-				packageLock.Packages[packageResolved] = pkgItem
-				mapMutex.Unlock()
+			if extractErr := pm.extractPackage(tarballPath, configPackageVersion); extractErr != nil {
+				select {
+				case errChan <- fmt.Errorf("failed to re-extract corrupted package %s: %w", actualName, extractErr):
+					close(done)
+				default:
+				}
+				return
+			}
+		}
 
-				mapMutex.Lock()
-				currentPkgName := extractPackageName(packageResolved)
-				for name, depVersion := range data.GetDependencies() {
-					pkgItem := packageLock.Packages[packageResolved]
-					if pkgItem.Dependencies == nil {
-						pkgItem.Dependencies = make(map[string]string)
-					}
-					pkgItem.Dependencies[name] = depVersion
-					packageLock.Packages[packageResolved] = pkgItem
+		data, err := pm.packageJsonParse.Parse(packageJsonPath)
+		if err != nil {
+			select {
+			case errChan <- err:
+				close(done)
+			default:
+			}
+			return
+		}
 
-					// Skip if package is trying to install itself as nested dependency
-					if name == currentPkgName {
-						continue
-					}
+		// Record the package's scripts and dependency maps in a single critical
+		// section so concurrent workers never see or overwrite a half-built entry
+		currentPkgName := extractPackageName(packageResolved)
+		pm.warnInvalidDependencies(actualName+"@"+version, data)
+		dependencies := data.GetDependencies()
+		optionalDependencies := data.GetOptionalDependencies()
+		peerDependencies := data.GetPeerDependencies()
+
+		// Bundled dependencies ship inside the package's own node_modules, so
+		// they are never fetched from the registry
+		bundled := data.GetBundledDependencies()
+		isBundled := make(map[string]bool, len(bundled))
+		for _, name := range bundled {
+			isBundled[name] = true
+		}
 
-					// Check if sub-dependency is also an alias
-					subDep := packagejson.Dependency{Name: name, Version: depVersion}
-					if actualPkg, actualVersion, isAlias := parseAliasVersion(depVersion); isAlias {
-						subDep.ActualName = actualPkg
-						subDep.Version = actualVersion
-					} else {
-						subDep.ActualName = name
-					}
+		mapMutex.Lock()
+		pkgItem, ok := packageLock.Packages[packageResolved]
+		if !ok || pkgItem.Version != version {
+			// Another version took over this path; its own worker records its dependencies
+			mapMutex.Unlock()
+			return
+		}
+		pkgItem.Scripts = data.Scripts
+		pkgItem.BundleDependencies = bundled
+		if len(dependencies) > 0 {
+			pkgItem.Dependencies = dependencies
+		}
+		if len(optionalDependencies) > 0 {
+			pkgItem.OptionalDependencies = optionalDependencies
+		}
+		// With peers omitted the lock records none, so a later install from it
+		// does not see them either
+		if len(peerDependencies) > 0 && !pm.config.Omit.Peer {
+			pkgItem.PeerDependencies = peerDependencies
+			if len(data.PeerDependenciesMeta) > 0 {
+				pkgItem.PeerDependenciesMeta = data.PeerDependenciesMeta
+			}
+		}
+		packageLock.Packages[packageResolved] = pkgItem
+		mapMutex.Unlock()
+
+		childPath := resolutionPath(item, actualName, version)
+		for _, name := range slices.Sorted(maps.Keys(dependencies)) {
+			depVersion := dependencies[name]
+			// Skip if package is trying to install itself as nested dependency
+			if name == currentPkgName || isBundled[name] {
+				continue
+			}
 
-					workChan <- QueueItem{
-						Dep:        subDep,
-						ParentName: packageResolved,
-						IsDev:      item.IsDev,
-					}
-				}
+			subDep, ancestry := subDependency(item, name, depVersion)
+			enqueue(QueueItem{
+				Dep:        subDep,
+				ParentName: packageResolved,
+				IsDev:      item.IsDev,
+				Ancestry:   ancestry,
+				Path:       childPath,
+			})
+		}
 
-				// Process optional dependencies from sub-packages
-				for name, depVersion := range data.GetOptionalDependencies() {
-					pkgItem := packageLock.Packages[packageResolved]
-					if pkgItem.OptionalDependencies == nil {
-						pkgItem.OptionalDependencies = make(map[string]string)
-					}
-					pkgItem.OptionalDependencies[name] = depVersion
-					packageLock.Packages[packageResolved] = pkgItem
+		// Process optional dependencies from sub-packages
+		for _, name := range slices.Sorted(maps.Keys(optionalDependencies)) {
+			depVersion := optionalDependencies[name]
+			if name == currentPkgName || isBundled[name] || pm.config.Omit.Optional {
+				continue
+			}
 
-					// Skip if package is trying to install itself as nested dependency
-					if name == currentPkgName {
-						continue
-					}
+			subDep, ancestry := subDependency(item, name, depVersion)
+			enqueue(QueueItem{
+				Dep:        subDep,
+				ParentName: packageResolved,
+				IsDev:      false,
+				IsOptional: true,
+				Ancestry:   ancestry,
+				Path:       childPath,
+			})
+		}
 
-					// Check if sub-dependency is also an alias
-					subDep := packagejson.Dependency{Name: name, Version: depVersion}
-					if actualPkg, actualVersion, isAlias := parseAliasVersion(depVersion); isAlias {
-						subDep.ActualName = actualPkg
-						subDep.Version = actualVersion
-					} else {
-						subDep.ActualName = name
-					}
+		// Process peer dependencies from sub-packages (auto-install per npm 7+ behavior)
+		for _, name := range slices.Sorted(maps.Keys(peerDependencies)) {
+			depVersion := peerDependencies[name]
+			if name == currentPkgName || isBundled[name] || pm.config.Omit.Peer {
+				continue
+			}
 
-					workChan <- QueueItem{
-						Dep:        subDep,
-						ParentName: packageResolved,
-						IsDev:      false,
-						IsOptional: true,
-					}
+			// Check if this peer dependency is optional
+			isPeerOptional := false
+			if data.PeerDependenciesMeta != nil {
+				if meta, exists := data.PeerDependenciesMeta[name]; exists {
+					isPeerOptional = meta.Optional
 				}
+			}
 
-				// Process peer dependencies from sub-packages (auto-install per npm 7+ behavior)
-				for name, depVersion := range data.GetPeerDependencies() {
-					pkgItem := packageLock.Packages[packageResolved]
-					if pkgItem.PeerDependencies == nil {
-						pkgItem.PeerDependencies = make(map[string]string)
-					}
-					pkgItem.PeerDependencies[name] = depVersion
-					packageLock.Packages[packageResolved] = pkgItem
+			subDep, ancestry := subDependency(item, name, depVersion)
+			enqueue(QueueItem{
+				Dep:            subDep,
+				ParentName:     packageResolved,
+				IsDev:          false,
+				IsOptional:     false,
+				IsPeer:         true,
+				IsPeerOptional: isPeerOptional,
+				Ancestry:       ancestry,
+				Path:           childPath,
+			})
+		}
+	}
 
-					// Skip if package is trying to install itself as nested dependency
-					if name == currentPkgName {
-						continue
-					}
+	for _, item := range queue {
+		if item.IsDev {
+			packageLock.DevDependencies[item.Dep.Name] = item.Dep.Version
+		} else if item.IsPeer {
+			packageLock.PeerDependencies[item.Dep.Name] = item.Dep.Version
+		} else {
+			packageLock.Dependencies[item.Dep.Name] = item.Dep.Version
+		}
+	}
 
-					// Check if this peer dependency is optional
-					isPeerOptional := false
-					if data.PeerDependenciesMeta != nil {
-						if meta, exists := data.PeerDependenciesMeta[name]; exists {
-							isPeerOptional = meta.Optional
-						}
-					}
+	for level := queue; len(level) > 0; {
+		sortLevel(level)
+		order := newTurns()
+		items := make(chan int)
 
-					// Check if sub-dependency is also an alias
-					subDep := packagejson.Dependency{Name: name, Version: depVersion}
-					if actualPkg, actualVersion, isAlias := parseAliasVersion(depVersion); isAlias {
-						subDep.ActualName = actualPkg
-						subDep.Version = actualVersion
-					} else {
-						subDep.ActualName = name
-					}
+		// An install that fails or is interrupted stops the workers waiting
+		// for their turn
+		levelDone := make(chan struct{})
+		go func() {
+			select {
+			case <-done:
+				order.stop()
+			case <-levelDone:
+			}
+		}()
 
-					workChan <- QueueItem{
-						Dep:            subDep,
-						ParentName:     packageResolved,
-						IsDev:          false,
-						IsOptional:     false,
-						IsPeer:         true,
-						IsPeerOptional: isPeerOptional,
-					}
+		var wg sync.WaitGroup
+		for i := 0; i < max(pm.concurrency, 1); i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				// Each worker times into its own buffer, merged once it is done
+				timings := pm.timing.NewBuffer()
+				defer pm.timing.Merge(timings)
+				for index := range items {
+					processItem(level[index], order, index, timings)
 				}
-				mapMutex.Unlock()
-			}(item)
+			}()
+		}
+
+		// Items are handed out in order, so the lowest one still waiting for
+		// its turn is always being processed
+		for index := range level {
+			items <- index
+		}
+		close(items)
+		wg.Wait()
+		close(levelDone)
+
+		select {
+		case <-done:
+			next = nil
 		default:
-			workerMutex.Lock()
-			if activeWorkers == 0 {
-				workerMutex.Unlock()
-				break
-			}
-			workerMutex.Unlock()
 		}
+		level, next = next, nil
 	}
 
-	wg.Wait()
+	close(watchDone)
+	watcher.Wait()
 	close(errChan)
 
 	if err := <-errChan; err != nil {
 		return err
 	}
-	pm.packageLock = &packageLock
 
-	// Validate peer dependencies and print warnings
-	warnings := pm.validatePeerDependencies(&packageLock)
-	if len(warnings) > 0 {
-		fmt.Fprintln(os.Stderr, "\n⚠️  Peer dependency warnings:")
-		for _, warning := range warnings {
-			fmt.Fprintln(os.Stderr, "  ", warning)
+	if trackConflicts && pm.forcedVersions == nil {
+		pm.conflicts = pm.findConflicts(requestedRanges, availableVersions)
+		if pm.config.ForceResolutions && len(pm.conflicts) > 0 {
+			// Resolve again with every conflicting package pinned to one version
+			pm.forcedVersions = pm.forceResolutions(pm.conflicts, availableVersions)
+			defer func() { pm.forcedVersions = nil }()
+			printConflicts(os.Stderr, pm.conflicts)
+			resume.close()
+			return pm.fetchToCache(packageJson)
+		}
+		if len(pm.conflicts) > 0 {
+			printConflicts(os.Stderr, pm.conflicts)
 		}
-		fmt.Fprintln(os.Stderr)
 	}
 
-	return nil
-}
+	if err := resume.finish(); err != nil {
+		return err
+	}
+	if err := pm.Etag.Save(); err != nil {
+		return err
+	}
 
-// validatePeerDependencies checks if peer dependency requirements are satisfied
-func (pm *PackageManager) validatePeerDependencies(packageLock *packagejson.PackageLock) []string {
-	warnings := []string{}
+	pm.deprecations = sortDeprecations(deprecations)
+	if len(pm.deprecations) > 0 {
+		printDeprecations(os.Stderr, pm.deprecations)
+	}
 
-	// Iterate through all packages and check their peer dependencies
-	for pkgPath, pkgItem := range packageLock.Packages {
-		if len(pkgItem.PeerDependencies) == 0 {
-			continue
-		}
+	pm.packageLock = &packageLock
 
-		for peerName, peerVersionConstraint := range pkgItem.PeerDependencies {
-			// Check if peer dependency is installed
-			installedVersion := ""
-			peerPath := "node_modules/" + peerName
+	// Validate peer dependencies and print warnings, unless peers are managed manually
+	if pm.config.Omit.Peer {
+		return nil
+	}
+	warnings := pm.validatePeerDependencies(&packageLock)
+	for _, warning := range warnings {
+		pm.progress.Report(progress.WarningPeer, warning)
+	}
+	if len(warnings) > 0 {
+		pm.logger.Warnf("unmet peer dependencies:\n   %s\n   Run 'go-npm peers' for the full peer dependency matrix\n",
+			strings.Join(warnings, "\n   "))
+	}
 
-			if peerPkg, exists := packageLock.Packages[peerPath]; exists {
-				installedVersion = peerPkg.Version
-			}
+	return nil
+}
 
-			if installedVersion == "" {
-				warnings = append(warnings, fmt.Sprintf(
-					"%s requires peer %s@%s but it is not installed",
-					pkgPath, peerName, peerVersionConstraint,
-				))
-				continue
-			}
+// previouslySkippedOptional returns the lock entry of an optional dependency
+// that an earlier run skipped as incompatible, when it still matches dep's
+// version constraint and the current platform is still unsupported
+func (pm *PackageManager) previouslySkippedOptional(lock *packagejson.PackageLock, dep packagejson.Dependency) (packagejson.PackageItem, bool) {
+	if lock == nil {
+		return packagejson.PackageItem{}, false
+	}
 
-			// Check if installed version satisfies the peer requirement
-			npmPackage := &manifestpkg.NPMPackage{
-				Versions: map[string]manifestpkg.Version{
-					installedVersion: {Version: installedVersion},
-				},
-				DistTags: manifestpkg.DistTags{"latest": installedVersion},
-			}
+	item, ok := lock.Packages["node_modules/"+dep.Name]
+	if !ok || !item.Skipped || utils.IsCompatiblePlatform(item.OS, item.CPU) {
+		return packagejson.PackageItem{}, false
+	}
 
-			resolvedVersion := pm.versionInfo.GetVersion(peerVersionConstraint, npmPackage)
-			if resolvedVersion != installedVersion {
-				warnings = append(warnings, fmt.Sprintf(
-					"%s requires peer %s@%s but version %s is installed",
-					pkgPath, peerName, peerVersionConstraint, installedVersion,
-				))
-			}
-		}
+	if !pm.versionInfo.SatisfiesConstraint(item.Version, dep.Version) {
+		return packagejson.PackageItem{}, false
+	}
+	if pm.config.Omit.Peer {
+		item.PeerDependencies, item.PeerDependenciesMeta = nil, nil
 	}
 
-	return warnings
+	return item, true
+}
+
+// validatePeerDependencies checks that every package's peer dependencies are
+// satisfied by the copy it loads and describes the ones that are not
+func (pm *PackageManager) validatePeerDependencies(packageLock *packagejson.PackageLock) []string {
+	return peers.Warnings(peers.BuildMatrix(packageLock))
 }
 
-func (pm *PackageManager) addBinToPath() error {
-	homeDir, err := os.UserHomeDir()
+// addBinToPath appends the PATH line for the global bin directory to the
+// startup file of the user's shell, once, and returns that file
+func (pm *PackageManager) addBinToPath() (string, error) {
+	rcPath, err := config.ShellRCFile()
 	if err != nil {
-		return fmt.Errorf("failed to get home directory: %w", err)
+		return "", err
 	}
+	exportLine := pm.config.GlobalBinExportLine()
 
-	bashrcPath := filepath.Join(homeDir, ".bashrc")
-	exportLine := fmt.Sprintf("export PATH=\"%s:$PATH\"", pm.config.GlobalBinDir)
-
-	content, err := os.ReadFile(bashrcPath)
+	content, err := os.ReadFile(rcPath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			content = []byte{}
 		} else {
-			return fmt.Errorf("failed to read .bashrc: %w", err)
+			return "", fmt.Errorf("failed to read %s: %w", rcPath, err)
 		}
 	}
 
 	if strings.Contains(string(content), exportLine) {
-		return nil
+		return rcPath, nil
 	}
 
 	newContent := string(content)
@@ -1475,11 +2675,15 @@ func (pm *PackageManager) addBinToPath() error {
 	}
 	newContent += fmt.Sprintf("\n# Added by go-npm\n%s\n", exportLine)
 
-	if err := os.WriteFile(bashrcPath, []byte(newContent), 0644); err != nil {
-		return fmt.Errorf("failed to write .bashrc: %w", err)
+	// fish keeps its config under ~/.config/fish, which may not exist yet
+	if err := os.MkdirAll(filepath.Dir(rcPath), 0755); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", filepath.Dir(rcPath), err)
+	}
+	if err := os.WriteFile(rcPath, []byte(newContent), 0644); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", rcPath, err)
 	}
 
-	return nil
+	return rcPath, nil
 }
 
 func (pm *PackageManager) InstallGlobal(pkgName, version string) error {
@@ -1488,6 +2692,7 @@ func (pm *PackageManager) InstallGlobal(pkgName, version string) error {
 	}
 
 	pm.progress.Start()
+	pm.snapshotLock()
 
 	if version == "" {
 		version = "latest"
@@ -1499,7 +2704,7 @@ func (pm *PackageManager) InstallGlobal(pkgName, version string) error {
 		},
 	}
 
-	if err := pm.fetchToCache(packageJsonToInstall, false); err != nil {
+	if err := pm.fetchToCache(packageJsonToInstall); err != nil {
 		return fmt.Errorf("failed to fetch package to cache: %w", err)
 	}
 
@@ -1516,19 +2721,216 @@ func (pm *PackageManager) InstallGlobal(pkgName, version string) error {
 			return fmt.Errorf("failed to create global lock file: %w", err)
 		}
 	}
-	// Add bin directory to PATH in .bashrc
-	if err := pm.addBinToPath(); err != nil {
-		fmt.Printf("Warning: Failed to add bin directory to PATH: %v\n", err)
-		fmt.Printf("Please manually add to PATH: export PATH=\"%s:$PATH\"\n", pm.config.GlobalBinDir)
+	// Add bin directory to PATH in the shell's startup file
+	if rcPath, err := pm.addBinToPath(); err != nil {
+		pm.logger.Warnf("Failed to add bin directory to PATH: %v\nPlease manually add to PATH: %s", err, pm.config.GlobalBinExportLine())
 	} else {
-		fmt.Printf("\n✓ Successfully installed %s globally\n", pkgName)
-		fmt.Printf("✓ Added bin directory to PATH in ~/.bashrc\n")
-		fmt.Printf("  Run 'source ~/.bashrc' to apply changes in current terminal\n")
+		pm.logger.Infof("\n✓ Successfully installed %s globally", pkgName)
+		pm.logger.Infof("✓ Added bin directory to PATH in %s", rcPath)
+		pm.logger.Infof("  Run 'source %s' to apply changes in current terminal", rcPath)
 		return nil
 	}
 
-	fmt.Printf("\n✓ Successfully installed %s globally\n", pkgName)
-	fmt.Printf("Binaries available in: %s\n", pm.config.GlobalBinDir)
+	pm.logger.Infof("\n✓ Successfully installed %s globally", pkgName)
+	pm.logger.Infof("Binaries available in: %s", pm.config.GlobalBinDir)
+
+	return nil
+}
+
+// LinkGlobal links the project in projectDir globally so the bins declared
+// in its package.json are available on PATH (see Link)
+func (pm *PackageManager) LinkGlobal(projectDir string) error {
+	_, pkgJSON, err := readLinkedPackageJSON(projectDir)
+	if err != nil {
+		return err
+	}
+
+	if pkgJSON.Bin == nil {
+		return fmt.Errorf("package %s does not declare a bin", pkgJSON.Name)
+	}
+
+	return pm.Link(projectDir)
+}
+
+// Link registers the package in projectDir in the global link store, like
+// npm link: the global node_modules entry is a symlink to projectDir and every
+// bin declared in its package.json gets a global shim pointing at the local file.
+// Consumers then pick it up with LinkPackage.
+func (pm *PackageManager) Link(projectDir string) error {
+	if !pm.isGlobal {
+		return fmt.Errorf("package manager is not in global mode")
+	}
+
+	projectDir, pkgJSON, err := readLinkedPackageJSON(projectDir)
+	if err != nil {
+		return err
+	}
+
+	// The linked package resolves its own dependencies (including devDependencies
+	// needed by dev tools) from the project's node_modules
+	if len(pkgJSON.GetDependencies())+len(pkgJSON.GetDevDependencies()) > 0 {
+		if _, err := os.Stat(filepath.Join(projectDir, "node_modules")); os.IsNotExist(err) {
+			pm.logger.Warnf("%s has no node_modules, run 'go-npm install' in %s first", pkgJSON.Name, projectDir)
+		}
+	}
+
+	linkPath := filepath.Join(pm.config.GlobalNodeModules, pkgJSON.Name)
+	if info, err := os.Lstat(linkPath); err == nil && info.Mode()&os.ModeSymlink == 0 {
+		return fmt.Errorf("%s is already installed globally, uninstall it first", pkgJSON.Name)
+	}
+
+	if err := workspace.SymlinkPackage(pm.config.GlobalNodeModules, pkgJSON.Name, projectDir); err != nil {
+		return fmt.Errorf("failed to link %s: %w", pkgJSON.Name, err)
+	}
+
+	if err := pm.binLinker.CreateBinDirectory(); err != nil {
+		return err
+	}
+
+	if err := pm.binLinker.LinkPackage(projectDir); err != nil {
+		return fmt.Errorf("failed to link bin for %s: %w", pkgJSON.Name, err)
+	}
+
+	version, _ := pkgJSON.Version.(string)
+	linkLock := &packagejson.PackageLock{
+		Dependencies: map[string]string{
+			pkgJSON.Name: "file:" + projectDir,
+		},
+		Packages: map[string]packagejson.PackageItem{
+			"node_modules/" + pkgJSON.Name: {
+				Name:     pkgJSON.Name,
+				Version:  version,
+				Resolved: "file:" + projectDir,
+				Link:     true,
+				Bin:      pkgJSON.Bin,
+			},
+		},
+	}
+
+	if _, err := os.Stat(pm.config.GlobalLockFile); err == nil {
+		if err := pm.packageJsonParse.UpdateLockFile(linkLock, true); err != nil {
+			return fmt.Errorf("failed to update global lock file: %w", err)
+		}
+	} else {
+		linkLock.Name = "global"
+		linkLock.Version = "1.0.0"
+		linkLock.LockfileVersion = 3
+		linkLock.Requires = true
+		if err := pm.packageJsonParse.CreateLockFile(linkLock, true); err != nil {
+			return fmt.Errorf("failed to create global lock file: %w", err)
+		}
+	}
+	pm.packageLock = pm.packageJsonParse.PackageLock
+
+	pm.logger.Infof("\n✓ Linked %s globally -> %s", pkgJSON.Name, projectDir)
+
+	if pkgJSON.Bin != nil {
+		if _, err := pm.addBinToPath(); err != nil {
+			pm.logger.Warnf("Failed to add bin directory to PATH: %v\nPlease manually add to PATH: %s", err, pm.config.GlobalBinExportLine())
+		}
+		pm.logger.Infof("Binaries available in: %s", pm.config.GlobalBinDir)
+	}
+
+	return nil
+}
+
+// UnlinkGlobal removes the package in projectDir from the global link store
+func (pm *PackageManager) UnlinkGlobal(projectDir string) error {
+	if !pm.isGlobal {
+		return fmt.Errorf("package manager is not in global mode")
+	}
+
+	_, pkgJSON, err := readLinkedPackageJSON(projectDir)
+	if err != nil {
+		return err
+	}
+
+	linkPath := filepath.Join(pm.config.GlobalNodeModules, pkgJSON.Name)
+	if info, err := os.Lstat(linkPath); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		return fmt.Errorf("package %s is not linked globally", pkgJSON.Name)
+	}
+
+	return pm.Remove(pkgJSON.Name, false)
+}
+
+// LinkPackage symlinks a globally linked package into the project's node_modules
+// and links its bins into node_modules/.bin
+func (pm *PackageManager) LinkPackage(pkgName string) error {
+	storePath := filepath.Join(pm.config.GlobalNodeModules, pkgName)
+	if info, err := os.Lstat(storePath); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		return fmt.Errorf("package %s is not linked, run 'go-npm link' in its directory first", pkgName)
+	}
+
+	target, err := filepath.EvalSymlinks(storePath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve linked package %s: %w", pkgName, err)
+	}
+
+	linkPath := filepath.Join(pm.extractedPath, pkgName)
+	if info, err := os.Lstat(linkPath); err == nil && info.Mode()&os.ModeSymlink == 0 {
+		if err := os.RemoveAll(linkPath); err != nil {
+			return fmt.Errorf("failed to remove installed package %s: %w", pkgName, err)
+		}
+	}
+
+	if err := workspace.SymlinkPackage(pm.extractedPath, pkgName, target); err != nil {
+		return fmt.Errorf("failed to link %s: %w", pkgName, err)
+	}
+
+	if err := pm.binLinker.CreateBinDirectory(); err != nil {
+		return err
+	}
+
+	if err := pm.binLinker.LinkPackage(linkPath); err != nil {
+		return fmt.Errorf("failed to link bin for %s: %w", pkgName, err)
+	}
+
+	pm.logger.Infof("✓ Linked %s -> %s", linkPath, target)
+
+	return nil
+}
+
+// UnlinkPackage removes a linked package from the project's node_modules
+func (pm *PackageManager) UnlinkPackage(pkgName string) error {
+	linkPath := filepath.Join(pm.extractedPath, pkgName)
+	if info, err := os.Lstat(linkPath); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		return fmt.Errorf("package %s is not linked in %s", pkgName, pm.extractedPath)
+	}
+
+	if err := pm.binLinker.UnlinkPackage(pkgName); err != nil {
+		return err
+	}
+
+	if err := os.Remove(linkPath); err != nil {
+		return fmt.Errorf("failed to remove link %s: %w", linkPath, err)
+	}
+
+	pm.logger.Infof("✓ Unlinked %s", pkgName)
 
 	return nil
 }
+
+// readLinkedPackageJSON reads the package.json of a package being linked and
+// returns its absolute directory
+func readLinkedPackageJSON(projectDir string) (string, *packagejson.PackageJSON, error) {
+	projectDir, err := filepath.Abs(projectDir)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to resolve project directory: %w", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(projectDir, "package.json"))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read package.json in %s: %w", projectDir, err)
+	}
+
+	var pkgJSON packagejson.PackageJSON
+	if err := json.Unmarshal(data, &pkgJSON); err != nil {
+		return "", nil, fmt.Errorf("failed to parse package.json in %s: %w", projectDir, err)
+	}
+
+	if pkgJSON.Name == "" {
+		return "", nil, fmt.Errorf("package.json in %s has no name", projectDir)
+	}
+
+	return projectDir, &pkgJSON, nil
+}
@@ -0,0 +1,251 @@
+package manager
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ernesto27/go-npm/config"
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupAtomicInstall caches a, b (nested under a) and @scope/c and points the
+// lock at them, so InstallFromCache runs without the network
+func setupAtomicInstall(t *testing.T, pm *PackageManager) {
+	t.Helper()
+
+	writeCachedPackage(t, pm, "a", "1.0.0", `{"name": "a", "version": "1.0.0"}`)
+	writeCachedPackage(t, pm, "b", "2.0.0", `{"name": "b", "version": "2.0.0"}`)
+	writeCachedPackage(t, pm, "@scope/c", "1.0.0", `{"name": "@scope/c", "version": "1.0.0"}`)
+
+	pm.packageLock = &packagejson.PackageLock{
+		Dependencies: map[string]string{"a": "^1.0.0", "@scope/c": "^1.0.0"},
+		Packages: map[string]packagejson.PackageItem{
+			"":                              {Name: "root"},
+			"node_modules/a":                {Name: "a", Version: "1.0.0"},
+			"node_modules/a/node_modules/b": {Name: "b", Version: "2.0.0"},
+			"node_modules/@scope/c":         {Name: "@scope/c", Version: "1.0.0"},
+		},
+	}
+}
+
+func TestInstallFromCacheAtomicPackages(t *testing.T) {
+	testCases := []struct {
+		name      string
+		setupFunc func(t *testing.T, nodeModules string)
+		validate  func(t *testing.T, nodeModules string)
+	}{
+		{
+			name: "installs nested and scoped packages",
+			validate: func(t *testing.T, nodeModules string) {
+				assert.FileExists(t, filepath.Join(nodeModules, "a", "package.json"))
+				assert.FileExists(t, filepath.Join(nodeModules, "a", "node_modules", "b", "package.json"))
+				assert.FileExists(t, filepath.Join(nodeModules, "@scope", "c", "package.json"))
+			},
+		},
+		{
+			name: "discards a partial copy left by an interrupted install",
+			setupFunc: func(t *testing.T, nodeModules string) {
+				partial := filepath.Join(nodeModules, "a.tmp")
+				require.NoError(t, os.MkdirAll(partial, 0755))
+				require.NoError(t, os.WriteFile(filepath.Join(partial, "half-written.js"), []byte("x"), 0644))
+			},
+			validate: func(t *testing.T, nodeModules string) {
+				assert.FileExists(t, filepath.Join(nodeModules, "a", "package.json"))
+				assert.NoFileExists(t, filepath.Join(nodeModules, "a", "half-written.js"))
+				assert.NoDirExists(t, filepath.Join(nodeModules, "a.tmp"))
+			},
+		},
+		{
+			name: "skips packages that already exist in node_modules",
+			setupFunc: func(t *testing.T, nodeModules string) {
+				existing := filepath.Join(nodeModules, "@scope", "c")
+				require.NoError(t, os.MkdirAll(existing, 0755))
+				require.NoError(t, os.WriteFile(filepath.Join(existing, "test-marker.txt"), []byte("test"), 0644))
+			},
+			validate: func(t *testing.T, nodeModules string) {
+				content, err := os.ReadFile(filepath.Join(nodeModules, "@scope", "c", "test-marker.txt"))
+				assert.NoError(t, err)
+				assert.Equal(t, "test", string(content))
+				assert.NoFileExists(t, filepath.Join(nodeModules, "@scope", "c", "package.json"))
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pm, _, origDir := setupTestPackageManager(t)
+			defer os.Chdir(origDir)
+
+			setupAtomicInstall(t, pm)
+			if tc.setupFunc != nil {
+				tc.setupFunc(t, pm.extractedPath)
+			}
+
+			require.NoError(t, pm.InstallFromCache())
+			tc.validate(t, pm.extractedPath)
+		})
+	}
+}
+
+func TestInstallFromCacheStaged(t *testing.T) {
+	testCases := []struct {
+		name      string
+		setupFunc func(t *testing.T, nodeModules string)
+		validate  func(t *testing.T, nodeModules string)
+	}{
+		{
+			name: "swaps in a complete tree without stale packages",
+			setupFunc: func(t *testing.T, nodeModules string) {
+				stale := filepath.Join(nodeModules, "stale")
+				require.NoError(t, os.MkdirAll(stale, 0755))
+				require.NoError(t, os.WriteFile(filepath.Join(stale, "package.json"), []byte(`{"name": "stale"}`), 0644))
+			},
+			validate: func(t *testing.T, nodeModules string) {
+				assert.FileExists(t, filepath.Join(nodeModules, "a", "package.json"))
+				assert.FileExists(t, filepath.Join(nodeModules, "a", "node_modules", "b", "package.json"))
+				assert.FileExists(t, filepath.Join(nodeModules, "@scope", "c", "package.json"))
+				assert.NoDirExists(t, filepath.Join(nodeModules, "stale"))
+			},
+		},
+		{
+			name: "resumes a staging tree left by an interrupted install",
+			setupFunc: func(t *testing.T, nodeModules string) {
+				resumed := filepath.Join(stagingPath(nodeModules), "a")
+				require.NoError(t, os.MkdirAll(resumed, 0755))
+				require.NoError(t, os.WriteFile(filepath.Join(resumed, "package.json"), []byte(`{"name": "a"}`), 0644))
+				require.NoError(t, os.WriteFile(filepath.Join(resumed, "test-marker.txt"), []byte("test"), 0644))
+			},
+			validate: func(t *testing.T, nodeModules string) {
+				assert.FileExists(t, filepath.Join(nodeModules, "a", "test-marker.txt"), "completed packages should be kept")
+				assert.FileExists(t, filepath.Join(nodeModules, "a", "node_modules", "b", "package.json"))
+				assert.FileExists(t, filepath.Join(nodeModules, "@scope", "c", "package.json"))
+			},
+		},
+		{
+			name: "works without an existing node_modules",
+			validate: func(t *testing.T, nodeModules string) {
+				assert.FileExists(t, filepath.Join(nodeModules, "a", "package.json"))
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pm, _, origDir := setupTestPackageManager(t)
+			defer os.Chdir(origDir)
+
+			setupAtomicInstall(t, pm)
+			pm.config.AtomicInstall = true
+			nodeModules := pm.extractedPath
+			if tc.setupFunc != nil {
+				tc.setupFunc(t, nodeModules)
+			}
+
+			require.NoError(t, pm.InstallFromCache())
+
+			assert.Equal(t, nodeModules, pm.extractedPath)
+			assert.NoDirExists(t, stagingPath(nodeModules))
+			assert.NoDirExists(t, filepath.Clean(nodeModules)+".old")
+			assert.DirExists(t, filepath.Join(nodeModules, ".bin"))
+			tc.validate(t, nodeModules)
+		})
+	}
+}
+
+func TestInstallFromCacheStrategies(t *testing.T) {
+	sameFile := func(t *testing.T, a, b string) bool {
+		t.Helper()
+		aInfo, err := os.Stat(a)
+		require.NoError(t, err)
+		bInfo, err := os.Stat(b)
+		require.NoError(t, err)
+		return os.SameFile(aInfo, bInfo)
+	}
+
+	testCases := []struct {
+		name      string
+		strategy  string
+		setupFunc func(t *testing.T, pm *PackageManager)
+		validate  func(t *testing.T, pm *PackageManager)
+	}{
+		{
+			name:     "hardlink shares files with the cache",
+			strategy: config.InstallStrategyHardlink,
+			validate: func(t *testing.T, pm *PackageManager) {
+				assert.True(t, sameFile(t,
+					filepath.Join(pm.extractedPath, "a", "package.json"),
+					filepath.Join(pm.packagesPath, "a@1.0.0", "package.json")))
+			},
+		},
+		{
+			name:     "copy gives every package its own files",
+			strategy: config.InstallStrategyCopy,
+			validate: func(t *testing.T, pm *PackageManager) {
+				assert.False(t, sameFile(t,
+					filepath.Join(pm.extractedPath, "a", "package.json"),
+					filepath.Join(pm.packagesPath, "a@1.0.0", "package.json")))
+			},
+		},
+		{
+			name:     "symlink links packages without nested node_modules",
+			strategy: config.InstallStrategySymlink,
+			validate: func(t *testing.T, pm *PackageManager) {
+				target, err := os.Readlink(filepath.Join(pm.extractedPath, "@scope", "c"))
+				require.NoError(t, err)
+				assert.Equal(t, filepath.Join(pm.packagesPath, "@scope", "c@1.0.0"), target)
+
+				target, err = os.Readlink(filepath.Join(pm.extractedPath, "a", "node_modules", "b"))
+				require.NoError(t, err)
+				assert.Equal(t, filepath.Join(pm.packagesPath, "b@2.0.0"), target)
+
+				// a has b nested in it, which must not be written into the cache
+				info, err := os.Lstat(filepath.Join(pm.extractedPath, "a"))
+				require.NoError(t, err)
+				assert.True(t, info.IsDir())
+				assert.NoDirExists(t, filepath.Join(pm.packagesPath, "a@1.0.0", "node_modules"))
+			},
+		},
+		{
+			name:     "package with a postinstall script gets a private copy",
+			strategy: config.InstallStrategySymlink,
+			setupFunc: func(t *testing.T, pm *PackageManager) {
+				item := pm.packageLock.Packages["node_modules/@scope/c"]
+				item.Scripts = map[string]string{"postinstall": "echo built > package.json"}
+				pm.packageLock.Packages["node_modules/@scope/c"] = item
+				pm.lifecycleManager.SetTrustedDependencies([]string{"@scope/c"})
+			},
+			validate: func(t *testing.T, pm *PackageManager) {
+				info, err := os.Lstat(filepath.Join(pm.extractedPath, "@scope", "c"))
+				require.NoError(t, err)
+				assert.True(t, info.IsDir())
+
+				content, err := os.ReadFile(filepath.Join(pm.extractedPath, "@scope", "c", "package.json"))
+				require.NoError(t, err)
+				assert.Equal(t, "built\n", string(content))
+
+				content, err = os.ReadFile(filepath.Join(pm.packagesPath, "@scope", "c@1.0.0", "package.json"))
+				require.NoError(t, err)
+				assert.Contains(t, string(content), `"name": "@scope/c"`)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pm, _, origDir := setupTestPackageManager(t)
+			defer os.Chdir(origDir)
+
+			setupAtomicInstall(t, pm)
+			pm.config.InstallStrategy = tc.strategy
+			if tc.setupFunc != nil {
+				tc.setupFunc(t, pm)
+			}
+
+			require.NoError(t, pm.InstallFromCache())
+			tc.validate(t, pm)
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package manager
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchToCacheBundledDependencies(t *testing.T) {
+	pm, _, origDir := setupTestPackageManager(t)
+	defer os.Chdir(origDir)
+
+	// The registry does not know "bundled": fetching it would fail the install
+	setupTestRegistry(t, pm, map[string]map[string]map[string]string{
+		"a": {"1.0.0": {"bundled": "^1.0.0", "c": "^1.0.0"}},
+		"c": {"1.0.0": nil},
+	})
+	writeCachedPackage(t, pm, "a", "1.0.0", `{"name": "a", "version": "1.0.0", "dependencies": {"bundled": "^1.0.0", "c": "^1.0.0"}, "bundleDependencies": ["bundled"]}`)
+	bundledDir := filepath.Join(pm.packagesPath, "a@1.0.0", "node_modules", "bundled")
+	require.NoError(t, os.MkdirAll(bundledDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(bundledDir, "package.json"), []byte(`{"name": "bundled", "version": "1.0.0"}`), 0644))
+
+	err := pm.fetchToCache(packagejson.PackageJSON{Dependencies: map[string]string{"a": "^1.0.0"}})
+	require.NoError(t, err)
+
+	lock := pm.packageLock
+	assert.Contains(t, lock.Packages, "node_modules/c")
+	assert.NotContains(t, lock.Packages, "node_modules/bundled")
+	assert.NotContains(t, lock.Packages, "node_modules/a/node_modules/bundled")
+	assert.Equal(t, []string{"bundled"}, lock.Packages["node_modules/a"].BundleDependencies)
+}
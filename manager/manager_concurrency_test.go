@@ -0,0 +1,214 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ernesto27/go-npm/manifest"
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFetchToCacheBoundedConcurrency(t *testing.T) {
+	testCases := []struct {
+		name        string
+		concurrency int
+		topLevel    int
+	}{
+		{
+			name:        "never exceeds a limit of 3 workers",
+			concurrency: 3,
+			topLevel:    8,
+		},
+		{
+			name:        "single worker processes packages sequentially",
+			concurrency: 1,
+			topLevel:    4,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pm, _, origDir := setupTestPackageManager(t)
+			defer os.Chdir(origDir)
+
+			var inFlight, peak int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				current := atomic.AddInt32(&inFlight, 1)
+				defer atomic.AddInt32(&inFlight, -1)
+				for {
+					old := atomic.LoadInt32(&peak)
+					if current <= old || atomic.CompareAndSwapInt32(&peak, old, current) {
+						break
+					}
+				}
+
+				time.Sleep(20 * time.Millisecond)
+
+				name := strings.TrimPrefix(r.URL.Path, "/")
+				fmt.Fprintf(w, `{"name": %q, "dist-tags": {"latest": "1.0.0"}, "versions": {"1.0.0": {"name": %q, "version": "1.0.0"}}}`, name, name)
+			}))
+			defer server.Close()
+
+			m, err := manifest.NewManifest(t.TempDir(), server.URL+"/")
+			assert.NoError(t, err)
+			pm.manifest = m
+			pm.concurrency = tc.concurrency
+
+			// Pre-populate the package cache so only manifests hit the network;
+			// every top-level package pulls in one child to exercise nested enqueues
+			deps := make(map[string]string)
+			for i := 0; i < tc.topLevel; i++ {
+				parent := fmt.Sprintf("parent-%d", i)
+				child := fmt.Sprintf("child-%d", i)
+				deps[parent] = "^1.0.0"
+
+				writeCachedPackage(t, pm, parent, "1.0.0", fmt.Sprintf(`{"name": %q, "version": "1.0.0", "dependencies": {%q: "^1.0.0"}}`, parent, child))
+				writeCachedPackage(t, pm, child, "1.0.0", fmt.Sprintf(`{"name": %q, "version": "1.0.0"}`, child))
+			}
+
+			err = pm.fetchToCache(packagejson.PackageJSON{Dependencies: deps})
+			assert.NoError(t, err)
+
+			assert.LessOrEqual(t, atomic.LoadInt32(&peak), int32(tc.concurrency))
+			assert.Greater(t, atomic.LoadInt32(&peak), int32(0))
+			assert.Len(t, pm.packageLock.Packages, tc.topLevel*2)
+		})
+	}
+}
+
+func TestFetchToCacheWideTreeLockConsistency(t *testing.T) {
+	pm, _, origDir := setupTestPackageManager(t)
+	defer os.Chdir(origDir)
+
+	// name -> version -> dependencies of that version
+	registry := map[string]map[string]map[string]string{
+		"shared-a":    {"1.0.0": {"leaf-common": "^1.0.0"}},
+		"shared-b":    {"1.0.0": {"leaf-old": "^1.0.0"}, "2.0.0": {"leaf-common": "^1.0.0", "leaf-extra": "^1.0.0"}},
+		"leaf-common": {"1.0.0": nil},
+		"leaf-extra":  {"1.0.0": nil},
+		"leaf-old":    {"1.0.0": nil},
+	}
+
+	// The root also wants shared-b@2, which races with the transitive shared-b@1
+	// for the hoisted node_modules/shared-b slot
+	const topLevel = 40
+	rootDeps := map[string]string{"shared-b": "^2.0.0"}
+	for i := 0; i < topLevel; i++ {
+		name := fmt.Sprintf("pkg-%d", i)
+		leaf := fmt.Sprintf("leaf-%d", i)
+		sharedB := "^1.0.0"
+		if i%2 == 1 {
+			sharedB = "^2.0.0"
+		}
+
+		rootDeps[name] = "^1.0.0"
+		registry[name] = map[string]map[string]string{"1.0.0": {"shared-a": "^1.0.0", "shared-b": sharedB, leaf: "^1.0.0"}}
+		registry[leaf] = map[string]map[string]string{"1.0.0": nil}
+	}
+
+	setupTestRegistry(t, pm, registry)
+	pm.concurrency = 16
+
+	err := pm.fetchToCache(packagejson.PackageJSON{Dependencies: rootDeps})
+	assert.NoError(t, err)
+
+	lock := pm.packageLock
+	assert.Len(t, lock.Dependencies, topLevel+1)
+
+	for path, item := range lock.Packages {
+		name := path[strings.LastIndex(path, "node_modules/")+len("node_modules/"):]
+		versions, ok := registry[name]
+		if !assert.True(t, ok, "unexpected package %s", path) {
+			continue
+		}
+
+		// Every entry's dependency map must match the package.json of its own version
+		expected, ok := versions[item.Version]
+		if !assert.True(t, ok, "%s has unknown version %s", path, item.Version) {
+			continue
+		}
+		if len(expected) == 0 {
+			assert.Empty(t, item.Dependencies, path)
+		} else {
+			assert.Equal(t, expected, item.Dependencies, path)
+		}
+
+		// Every dependency must be resolvable from the entry's location
+		for depName := range item.Dependencies {
+			found := false
+			for dir := path; ; {
+				if _, ok := lock.Packages[dir+"/node_modules/"+depName]; ok {
+					found = true
+					break
+				}
+				idx := strings.LastIndex(dir, "/node_modules/")
+				if idx == -1 {
+					_, found = lock.Packages["node_modules/"+depName]
+					break
+				}
+				dir = dir[:idx]
+			}
+			assert.True(t, found, "%s depends on %s which is missing from the lock", path, depName)
+		}
+	}
+
+	for i := 0; i < topLevel; i++ {
+		assert.Contains(t, lock.Packages, fmt.Sprintf("node_modules/pkg-%d", i))
+		assert.Contains(t, lock.Packages, fmt.Sprintf("node_modules/leaf-%d", i))
+	}
+}
+
+func writeCachedPackage(t *testing.T, pm *PackageManager, name, version, packageJSON string) {
+	t.Helper()
+
+	pkgDir := filepath.Join(pm.packagesPath, name+"@"+version)
+	assert.NoError(t, os.MkdirAll(pkgDir, 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(pkgDir, "package.json"), []byte(packageJSON), 0644))
+}
+
+// setupTestRegistry serves registry (name -> version -> dependencies) as npm
+// manifests and pre-populates the package cache, so fetchToCache runs offline
+func setupTestRegistry(t *testing.T, pm *PackageManager, registry map[string]map[string]map[string]string) {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/")
+		versions, ok := registry[name]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		entries := []string{}
+		latest := ""
+		for v := range versions {
+			entries = append(entries, fmt.Sprintf(`%q: {"name": %q, "version": %q}`, v, name, v))
+			if v > latest {
+				latest = v
+			}
+		}
+		fmt.Fprintf(w, `{"name": %q, "dist-tags": {"latest": %q}, "versions": {%s}}`, name, latest, strings.Join(entries, ","))
+	}))
+	t.Cleanup(server.Close)
+
+	m, err := manifest.NewManifest(t.TempDir(), server.URL+"/")
+	assert.NoError(t, err)
+	pm.manifest = m
+
+	for name, versions := range registry {
+		for v, deps := range versions {
+			data, err := json.Marshal(map[string]any{"name": name, "version": v, "dependencies": deps})
+			assert.NoError(t, err)
+			writeCachedPackage(t, pm, name, v, string(data))
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package manager
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchToCacheConflicts(t *testing.T) {
+	registry := map[string]map[string]map[string]string{
+		"a":      {"1.0.0": {"shared": "^1.0.0"}},
+		"b":      {"1.0.0": {"shared": "^2.0.0"}},
+		"c":      {"1.0.0": {"shared": "~2.0.0"}},
+		"shared": {"1.0.0": nil, "2.0.0": nil, "2.1.0": nil},
+	}
+
+	testCases := []struct {
+		name         string
+		dependencies map[string]string
+		force        bool
+		validate     func(t *testing.T, pm *PackageManager)
+	}{
+		{
+			name:         "conflict is reported and copies are nested",
+			dependencies: map[string]string{"a": "^1.0.0", "b": "^1.0.0", "c": "^1.0.0"},
+			validate: func(t *testing.T, pm *PackageManager) {
+				require.Len(t, pm.conflicts, 1)
+				conflict := pm.conflicts[0]
+				assert.Equal(t, "shared", conflict.Name)
+				assert.Empty(t, conflict.Chosen)
+				assert.Equal(t, []ConflictRange{
+					{Parent: "node_modules/a", Range: "^1.0.0"},
+					{Parent: "node_modules/b", Range: "^2.0.0"},
+					{Parent: "node_modules/c", Range: "~2.0.0"},
+				}, conflict.Ranges)
+
+				// Which copy gets hoisted depends on worker order; every dependent
+				// still sees a version in its own range
+				assert.Equal(t, "1.0.0", lockVersionSeenBy(pm.packageLock, "node_modules/a", "shared"))
+				assert.True(t, pm.versionInfo.SatisfiesConstraint(lockVersionSeenBy(pm.packageLock, "node_modules/b", "shared"), "^2.0.0"))
+				assert.Equal(t, "2.0.0", lockVersionSeenBy(pm.packageLock, "node_modules/c", "shared"))
+			},
+		},
+		{
+			name:         "forced resolution picks the highest version satisfying the most ranges",
+			dependencies: map[string]string{"a": "^1.0.0", "b": "^1.0.0", "c": "^1.0.0"},
+			force:        true,
+			validate: func(t *testing.T, pm *PackageManager) {
+				require.Len(t, pm.conflicts, 1)
+				conflict := pm.conflicts[0]
+				assert.Equal(t, "2.0.0", conflict.Chosen)
+				assert.Equal(t, []ConflictRange{
+					{Parent: "node_modules/a", Range: "^1.0.0", Satisfied: false},
+					{Parent: "node_modules/b", Range: "^2.0.0", Satisfied: true},
+					{Parent: "node_modules/c", Range: "~2.0.0", Satisfied: true},
+				}, conflict.Ranges)
+
+				for key, item := range pm.packageLock.Packages {
+					if key != "node_modules/shared" {
+						assert.NotEqual(t, "shared", item.Name, "unexpected nested copy at %s", key)
+					}
+				}
+				assert.Equal(t, "2.0.0", pm.packageLock.Packages["node_modules/shared"].Version)
+				assert.Equal(t, "^1.0.0", pm.packageLock.Dependencies["a"])
+			},
+		},
+		{
+			name:         "compatible ranges are not a conflict",
+			dependencies: map[string]string{"b": "^1.0.0", "c": "^1.0.0"},
+			force:        true,
+			validate: func(t *testing.T, pm *PackageManager) {
+				assert.Empty(t, pm.conflicts)
+				assert.Equal(t, "2.0.0", lockVersionSeenBy(pm.packageLock, "node_modules/c", "shared"))
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pm, _, origDir := setupTestPackageManager(t)
+			defer os.Chdir(origDir)
+
+			setupTestRegistry(t, pm, registry)
+			pm.config.ReportConflicts = true
+			pm.config.ForceResolutions = tc.force
+
+			err := pm.fetchToCache(packagejson.PackageJSON{Dependencies: tc.dependencies})
+			require.NoError(t, err)
+
+			tc.validate(t, pm)
+			assert.Nil(t, pm.forcedVersions)
+		})
+	}
+}
+
+func TestPrintConflicts(t *testing.T) {
+	var buf bytes.Buffer
+	printConflicts(&buf, []VersionConflict{
+		{
+			Name:   "shared",
+			Chosen: "2.0.0",
+			Ranges: []ConflictRange{
+				{Parent: "node_modules/a", Range: "^1.0.0"},
+				{Parent: "node_modules/b", Range: "^2.0.0", Satisfied: true},
+			},
+		},
+		{
+			Name: "other",
+			Ranges: []ConflictRange{
+				{Parent: "package.json", Range: "1.0.0"},
+				{Parent: "node_modules/b", Range: "2.0.0"},
+			},
+		},
+	})
+
+	output := buf.String()
+	assert.Contains(t, output, "shared: forced to 2.0.0")
+	assert.Contains(t, output, "^1.0.0 from node_modules/a (unsatisfied)")
+	assert.Contains(t, output, "^2.0.0 from node_modules/b\n")
+	assert.Contains(t, output, "other: no single version satisfies all ranges")
+	assert.Contains(t, output, "1.0.0 from package.json\n")
+}
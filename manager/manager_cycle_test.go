@@ -0,0 +1,106 @@
+package manager
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchToCacheCircularDependencies(t *testing.T) {
+	testCases := []struct {
+		name         string
+		dependencies map[string]string
+		registry     map[string]map[string]map[string]string
+		expected     map[string]string
+	}{
+		{
+			name:         "packages depending on each other",
+			dependencies: map[string]string{"a": "^1.0.0"},
+			registry: map[string]map[string]map[string]string{
+				"a": {"1.0.0": {"b": "^1.0.0"}},
+				"b": {"1.0.0": {"a": "^1.0.0"}},
+			},
+			expected: map[string]string{
+				"node_modules/a": "1.0.0",
+				"node_modules/b": "1.0.0",
+			},
+		},
+		{
+			name:         "cycle through versions that never hoist",
+			dependencies: map[string]string{"a": "^1.0.0"},
+			registry: map[string]map[string]map[string]string{
+				"a": {
+					"1.0.0": {"b": "^1.0.0"},
+					"2.0.0": {"b": "^2.0.0"},
+				},
+				"b": {
+					"1.0.0": {"a": "^2.0.0"},
+					"2.0.0": {"a": "^2.0.0"},
+				},
+			},
+			expected: map[string]string{
+				"node_modules/a":                               "1.0.0",
+				"node_modules/b":                               "1.0.0",
+				"node_modules/b/node_modules/a":                "2.0.0",
+				"node_modules/b/node_modules/a/node_modules/b": "2.0.0",
+			},
+		},
+		{
+			name:         "an ancestor outside the lookup path is nested again",
+			dependencies: map[string]string{"a": "^2.0.0", "x": "^1.0.0"},
+			registry: map[string]map[string]map[string]string{
+				"a": {
+					"1.0.0": {"b": "^1.0.0"},
+					"2.0.0": {},
+				},
+				"b": {"1.0.0": {"a": "^1.0.0"}},
+				"x": {"1.0.0": {"a": "^1.0.0"}},
+			},
+			expected: map[string]string{
+				"node_modules/a":                "2.0.0",
+				"node_modules/b":                "1.0.0",
+				"node_modules/b/node_modules/a": "1.0.0",
+				"node_modules/x":                "1.0.0",
+				"node_modules/x/node_modules/a": "1.0.0",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pm, tmpDir, origDir := setupTestPackageManager(t)
+			defer os.Chdir(origDir)
+
+			setupTestRegistry(t, pm, tc.registry)
+
+			done := make(chan error, 1)
+			go func() {
+				done <- pm.fetchToCache(packagejson.PackageJSON{Dependencies: tc.dependencies})
+			}()
+			select {
+			case err := <-done:
+				require.NoError(t, err)
+			case <-time.After(10 * time.Second):
+				t.Fatal("fetchToCache did not terminate on a dependency cycle")
+			}
+
+			packages := make(map[string]string)
+			for pkgPath, item := range pm.packageLock.Packages {
+				if pkgPath != "" {
+					packages[pkgPath] = item.Version
+				}
+			}
+			assert.Equal(t, tc.expected, packages)
+
+			require.NoError(t, pm.InstallFromCache())
+			for pkgPath := range tc.expected {
+				assert.DirExists(t, filepath.Join(tmpDir, pkgPath))
+			}
+		})
+	}
+}
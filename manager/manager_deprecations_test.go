@@ -0,0 +1,45 @@
+package manager
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchToCacheCollectsDeprecations(t *testing.T) {
+	pm, _, origDir := setupTestPackageManager(t)
+	defer os.Chdir(origDir)
+
+	setupTestRegistry(t, pm, map[string]map[string]map[string]string{
+		"app":     {"1.0.0": {"old-lib": "^1.0.0", "request": "^2.0.0"}},
+		"old-lib": {"1.0.0": nil},
+		"request": {"2.88.2": nil},
+	})
+
+	// Cached manifests are used as is, so they can carry deprecation messages
+	manifests := map[string]string{
+		"old-lib": `{"name": "old-lib", "dist-tags": {"latest": "1.0.0"}, "versions": {"1.0.0": {"name": "old-lib", "version": "1.0.0", "deprecated": "use new-lib instead"}}}`,
+		"request": `{"name": "request", "dist-tags": {"latest": "2.88.2"}, "versions": {"2.88.2": {"name": "request", "version": "2.88.2", "deprecated": "request has been deprecated"}}}`,
+	}
+	for name, content := range manifests {
+		require.NoError(t, os.WriteFile(pm.manifest.FilePath(name), []byte(content), 0644))
+	}
+
+	require.NoError(t, pm.fetchToCache(packagejson.PackageJSON{Dependencies: map[string]string{"app": "^1.0.0", "request": "^2.0.0"}}))
+
+	// Each deprecated version is reported once, however many dependents it has
+	assert.Equal(t, []Deprecation{
+		{Name: "old-lib", Version: "1.0.0", Message: "use new-lib instead"},
+		{Name: "request", Version: "2.88.2", Message: "request has been deprecated"},
+	}, pm.deprecations)
+	assert.Equal(t, "old-lib@1.0.0 is deprecated: use new-lib instead", pm.deprecations[0].Warning())
+
+	var buf bytes.Buffer
+	printDeprecations(&buf, pm.deprecations)
+	assert.Contains(t, buf.String(), "Deprecated packages:")
+	assert.Contains(t, buf.String(), "   request@2.88.2: request has been deprecated\n")
+}
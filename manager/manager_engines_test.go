@@ -0,0 +1,167 @@
+package manager
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ernesto27/go-npm/manifest"
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckEngine(t *testing.T) {
+	testCases := []struct {
+		name         string
+		nodeVersion  string
+		engines      any
+		engineStrict bool
+		expectError  bool
+	}{
+		{
+			name:        "satisfied range",
+			nodeVersion: "20.11.0",
+			engines:     map[string]any{"node": ">=18"},
+		},
+		{
+			name:        "unsatisfied range only warns by default",
+			nodeVersion: "16.20.0",
+			engines:     map[string]any{"node": ">=18"},
+		},
+		{
+			name:         "unsatisfied range fails under engine-strict",
+			nodeVersion:  "16.20.0",
+			engines:      map[string]any{"node": ">=18"},
+			engineStrict: true,
+			expectError:  true,
+		},
+		{
+			name:         "or ranges",
+			nodeVersion:  "16.20.0",
+			engines:      map[string]any{"node": "^14.17.0 || >=16"},
+			engineStrict: true,
+		},
+		{
+			name:         "missing node binary skips the check",
+			nodeVersion:  "",
+			engines:      map[string]any{"node": ">=18"},
+			engineStrict: true,
+		},
+		{
+			name:         "no node engine",
+			nodeVersion:  "16.20.0",
+			engines:      map[string]any{"npm": ">=9"},
+			engineStrict: true,
+		},
+		{
+			name:         "legacy array engines are ignored",
+			nodeVersion:  "16.20.0",
+			engines:      []any{"node >=18"},
+			engineStrict: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pm, _, origDir := setupTestPackageManager(t)
+			defer os.Chdir(origDir)
+
+			pm.nodeVersion = func() string { return tc.nodeVersion }
+			pm.config.EngineStrict = tc.engineStrict
+
+			err := pm.checkEngine("pkg", "1.0.0", tc.engines)
+			if tc.expectError {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), "unsupported engine for pkg@1.0.0")
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestFetchToCacheEngineStrict(t *testing.T) {
+	testCases := []struct {
+		name         string
+		dependencies map[string]string
+		optional     map[string]string
+		engineStrict bool
+		expectError  bool
+		validate     func(t *testing.T, lock *packagejson.PackageLock)
+	}{
+		{
+			name:         "incompatible engine warns and installs",
+			dependencies: map[string]string{"modern": "^1.0.0"},
+			validate: func(t *testing.T, lock *packagejson.PackageLock) {
+				assert.Contains(t, lock.Packages, "node_modules/modern")
+			},
+		},
+		{
+			name:         "incompatible engine fails under engine-strict",
+			dependencies: map[string]string{"legacy": "^1.0.0"},
+			engineStrict: true,
+			expectError:  true,
+		},
+		{
+			name:         "incompatible optional dependency is skipped under engine-strict",
+			dependencies: map[string]string{"compatible": "^1.0.0"},
+			optional:     map[string]string{"modern": "^1.0.0"},
+			engineStrict: true,
+			validate: func(t *testing.T, lock *packagejson.PackageLock) {
+				assert.Contains(t, lock.Packages, "node_modules/compatible")
+				assert.NotContains(t, lock.Packages, "node_modules/modern")
+			},
+		},
+	}
+
+	engines := map[string]string{
+		"modern":     ">=22",
+		"compatible": ">=18",
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pm, _, origDir := setupTestPackageManager(t)
+			defer os.Chdir(origDir)
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				name := strings.TrimPrefix(r.URL.Path, "/")
+				// legacy depends on modern, so the strict failure comes from a transitive package
+				deps := ""
+				if name == "legacy" {
+					deps = `, "dependencies": {"modern": "^1.0.0"}`
+				}
+				fmt.Fprintf(w, `{"name": %q, "dist-tags": {"latest": "1.0.0"}, "versions": {"1.0.0": {"name": %q, "version": "1.0.0", "engines": {"node": %q}%s}}}`,
+					name, name, engines[name], deps)
+			}))
+			defer server.Close()
+
+			m, err := manifest.NewManifest(t.TempDir(), server.URL+"/")
+			assert.NoError(t, err)
+			pm.manifest = m
+			pm.nodeVersion = func() string { return "20.0.0" }
+			pm.config.EngineStrict = tc.engineStrict
+
+			writeCachedPackage(t, pm, "modern", "1.0.0", `{"name": "modern", "version": "1.0.0"}`)
+			writeCachedPackage(t, pm, "compatible", "1.0.0", `{"name": "compatible", "version": "1.0.0"}`)
+			writeCachedPackage(t, pm, "legacy", "1.0.0", `{"name": "legacy", "version": "1.0.0", "dependencies": {"modern": "^1.0.0"}}`)
+
+			err = pm.fetchToCache(packagejson.PackageJSON{
+				Dependencies:         tc.dependencies,
+				OptionalDependencies: tc.optional,
+			})
+
+			if tc.expectError {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), "unsupported engine for modern@1.0.0")
+				return
+			}
+
+			assert.NoError(t, err)
+			tc.validate(t, pm.packageLock)
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package manager
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizePackageRoot(t *testing.T) {
+	testCases := []struct {
+		name         string
+		files        map[string]string
+		expectMoved  bool
+		expectExists []string
+	}{
+		{
+			name: "single nested package is moved up",
+			files: map[string]string{
+				"inner/package.json":  `{"name": "pkg"}`,
+				"inner/lib/index.js":  "",
+				"inner/inner/data.js": "",
+			},
+			expectMoved:  true,
+			expectExists: []string{"package.json", "lib/index.js", "inner/data.js"},
+		},
+		{
+			name: "two nested packages are left alone",
+			files: map[string]string{
+				"a/package.json": `{"name": "a"}`,
+				"b/package.json": `{"name": "b"}`,
+			},
+			expectExists: []string{"a/package.json", "b/package.json"},
+		},
+		{
+			name: "package.json at the root is left alone",
+			files: map[string]string{
+				"package.json":          `{"name": "pkg"}`,
+				"fixtures/package.json": `{"name": "fixture"}`,
+			},
+			expectExists: []string{"package.json", "fixtures/package.json"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tc.files {
+				filePath := filepath.Join(dir, filepath.FromSlash(name))
+				require.NoError(t, os.MkdirAll(filepath.Dir(filePath), 0755))
+				require.NoError(t, os.WriteFile(filePath, []byte(content), 0644))
+			}
+
+			moved, err := normalizePackageRoot(dir)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectMoved, moved)
+			for _, name := range tc.expectExists {
+				assert.FileExists(t, filepath.Join(dir, filepath.FromSlash(name)))
+			}
+		})
+	}
+}
+
+func TestFetchToCacheNestedPackageJSON(t *testing.T) {
+	tarballData := buildTestTarball(t, map[string]string{
+		"pkg/package.json": `{"name": "pkg", "version": "1.0.0"}`,
+		"pkg/index.js":     "module.exports = 'pkg'",
+	})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(tarballData)
+	}))
+	defer server.Close()
+
+	pm, tmpDir, origDir := setupTestPackageManager(t)
+	defer os.Chdir(origDir)
+
+	require.NoError(t, pm.fetchToCache(packagejson.PackageJSON{
+		Dependencies: map[string]string{"pkg": server.URL + "/pkg-1.0.0.tgz"},
+	}))
+	assert.Equal(t, "1.0.0", pm.packageLock.Packages["node_modules/pkg"].Version)
+
+	require.NoError(t, pm.InstallFromCache())
+	assert.FileExists(t, filepath.Join(tmpDir, "node_modules", "pkg", "package.json"))
+	assert.FileExists(t, filepath.Join(tmpDir, "node_modules", "pkg", "index.js"))
+	assert.NoDirExists(t, filepath.Join(tmpDir, "node_modules", "pkg", "pkg"))
+}
@@ -0,0 +1,133 @@
+package manager
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/ernesto27/go-npm/workspace"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstallFileDependency(t *testing.T) {
+	testCases := []struct {
+		name         string
+		installLinks bool
+		packLinks    bool
+		validate     func(t *testing.T, nodeModules, sourceDir string)
+	}{
+		{
+			name: "file: dependency is symlinked by default",
+			validate: func(t *testing.T, nodeModules, sourceDir string) {
+				target, err := os.Readlink(filepath.Join(nodeModules, "shared"))
+				require.NoError(t, err)
+				assert.Equal(t, filepath.Join("..", "shared"), target)
+			},
+		},
+		{
+			name:         "file: dependency is copied with --install-links",
+			installLinks: true,
+			validate: func(t *testing.T, nodeModules, sourceDir string) {
+				info, err := os.Lstat(filepath.Join(nodeModules, "shared"))
+				require.NoError(t, err)
+				assert.True(t, info.IsDir(), "node_modules/shared should be a real directory")
+
+				assert.FileExists(t, filepath.Join(nodeModules, "shared", "index.js"))
+				assert.NoDirExists(t, filepath.Join(nodeModules, "shared", "node_modules"))
+
+				copied, err := os.Stat(filepath.Join(nodeModules, "shared", "index.js"))
+				require.NoError(t, err)
+				source, err := os.Stat(filepath.Join(sourceDir, "index.js"))
+				require.NoError(t, err)
+				assert.False(t, os.SameFile(copied, source))
+				assert.FileExists(t, filepath.Join(nodeModules, "shared", "src", "index.ts"))
+			},
+		},
+		{
+			name:         "file: dependency is copied with its published files only with --pack-links",
+			installLinks: true,
+			packLinks:    true,
+			validate: func(t *testing.T, nodeModules, sourceDir string) {
+				assert.FileExists(t, filepath.Join(nodeModules, "shared", "package.json"))
+				assert.FileExists(t, filepath.Join(nodeModules, "shared", "index.js"))
+				assert.NoDirExists(t, filepath.Join(nodeModules, "shared", "src"))
+				assert.NoDirExists(t, filepath.Join(nodeModules, "shared", "node_modules"))
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pm, tmpDir, origDir := setupTestPackageManager(t)
+			defer os.Chdir(origDir)
+
+			sourceDir := filepath.Join(tmpDir, "shared")
+			require.NoError(t, os.MkdirAll(filepath.Join(sourceDir, "node_modules", "stale"), 0755))
+			require.NoError(t, os.MkdirAll(filepath.Join(sourceDir, "src"), 0755))
+			require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "package.json"), []byte(`{"name": "shared", "version": "0.1.0", "files": ["index.js"], "dependencies": {"leaf": "^1.0.0"}}`), 0644))
+			require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "index.js"), []byte("module.exports = 1"), 0644))
+			require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "src", "index.ts"), []byte("export default 1"), 0644))
+
+			setupTestRegistry(t, pm, map[string]map[string]map[string]string{"leaf": {"1.0.0": nil}})
+			pm.config.InstallLinks = tc.installLinks
+			pm.config.PackLinks = tc.packLinks
+
+			err := pm.fetchToCache(packagejson.PackageJSON{Dependencies: map[string]string{"shared": "file:shared"}})
+			require.NoError(t, err)
+
+			item := pm.packageLock.Packages["node_modules/shared"]
+			assert.True(t, item.Link)
+			assert.Equal(t, "0.1.0", item.Version)
+			assert.Equal(t, "file:shared", item.Resolved)
+			assert.Equal(t, "file:shared", pm.packageLock.Dependencies["shared"])
+			assert.Equal(t, "1.0.0", pm.packageLock.Packages["node_modules/leaf"].Version)
+
+			require.NoError(t, pm.InstallFromCache())
+			assert.FileExists(t, filepath.Join(pm.extractedPath, "leaf", "package.json"))
+			tc.validate(t, pm.extractedPath, sourceDir)
+		})
+	}
+}
+
+func TestCreateWorkspaceSymlinksPackLinks(t *testing.T) {
+	pm, tmpDir, origDir := setupTestPackageManager(t)
+	defer os.Chdir(origDir)
+
+	files := map[string]string{
+		"package.json":               `{"name": "root", "workspaces": ["packages/*"]}`,
+		"packages/ui/package.json":   `{"name": "@app/ui", "version": "1.0.0"}`,
+		"packages/ui/.npmignore":     "test/\n*.map\n",
+		"packages/ui/index.js":       "module.exports = 1",
+		"packages/ui/index.js.map":   "{}",
+		"packages/ui/test/ui.js":     "",
+		"packages/ui/lib/helpers.js": "",
+	}
+	for name, content := range files {
+		filePath := filepath.Join(tmpDir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(filePath), 0755))
+		require.NoError(t, os.WriteFile(filePath, []byte(content), 0644))
+	}
+
+	data, err := pm.packageJsonParse.ParseDefault()
+	require.NoError(t, err)
+	registry := workspace.NewWorkspaceRegistry(tmpDir, pm.packageJsonParse)
+	require.NoError(t, registry.Discover(data))
+	pm.workspaceRegistry = registry
+	pm.config.InstallLinks = true
+	pm.config.PackLinks = true
+
+	require.NoError(t, pm.CreateWorkspaceSymlinks())
+
+	placed := filepath.Join(pm.extractedPath, "@app", "ui")
+	info, err := os.Lstat(placed)
+	require.NoError(t, err)
+	assert.True(t, info.IsDir(), "workspace should be copied, not symlinked")
+	for _, file := range []string{"package.json", "index.js", "lib/helpers.js"} {
+		assert.FileExists(t, filepath.Join(placed, filepath.FromSlash(file)))
+	}
+	assert.NoFileExists(t, filepath.Join(placed, "index.js.map"))
+	assert.NoFileExists(t, filepath.Join(placed, ".npmignore"))
+	assert.NoDirExists(t, filepath.Join(placed, "test"))
+}
@@ -0,0 +1,310 @@
+package manager
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGitDependency(t *testing.T) {
+	testCases := []struct {
+		name     string
+		spec     string
+		expected *GitDependency
+	}{
+		{
+			name: "git+https with a tag",
+			spec: "git+https://github.com/owner/repo.git#v1.2.0",
+			expected: &GitDependency{
+				URL:      "git+https://github.com/owner/repo.git",
+				Protocol: "https",
+				Host:     "github.com",
+				Path:     "owner/repo",
+				CloneURL: "https://github.com/owner/repo.git",
+				Ref:      "v1.2.0",
+			},
+		},
+		{
+			name: "git+ssh with a semver range on another host",
+			spec: "git+ssh://git@git.example.com/team/lib.git#semver:^1.0.0",
+			expected: &GitDependency{
+				URL:         "git+ssh://git@git.example.com/team/lib.git",
+				Protocol:    "ssh",
+				Host:        "git.example.com",
+				Path:        "team/lib",
+				CloneURL:    "ssh://git@git.example.com/team/lib.git",
+				SemverRange: "^1.0.0",
+			},
+		},
+		{
+			name: "git+ssh scp-like form with a commit",
+			spec: "git+ssh://git@gitlab.com:group/project.git#0123456789abcdef0123456789abcdef01234567",
+			expected: &GitDependency{
+				URL:      "git+ssh://git@gitlab.com:group/project.git",
+				Protocol: "ssh",
+				Host:     "gitlab.com",
+				Path:     "group/project",
+				CloneURL: "git@gitlab.com:group/project.git",
+				Ref:      "0123456789abcdef0123456789abcdef01234567",
+			},
+		},
+		{
+			name: "git protocol without a ref",
+			spec: "git://example.org/repo.git",
+			expected: &GitDependency{
+				URL:      "git://example.org/repo.git",
+				Protocol: "git",
+				Host:     "example.org",
+				Path:     "repo",
+				CloneURL: "git://example.org/repo.git",
+			},
+		},
+		{
+			name: "git+file",
+			spec: "git+file:///srv/repos/lib.git#main",
+			expected: &GitDependency{
+				URL:      "git+file:///srv/repos/lib.git",
+				Protocol: "file",
+				Path:     "/srv/repos/lib",
+				CloneURL: "file:///srv/repos/lib.git",
+				Ref:      "main",
+			},
+		},
+		{
+			name: "github shorthand is handled elsewhere",
+			spec: "github:owner/repo#v1.0.0",
+		},
+		{
+			name: "semver range",
+			spec: "^1.0.0",
+		},
+		{
+			name: "missing repository path",
+			spec: "git+https://github.com#v1.0.0",
+		},
+		{
+			name: "scp-like host read as an ssh option",
+			spec: "git+ssh://-oProxyCommand=touch-pwned:owner/repo.git",
+		},
+		{
+			name: "url host read as an ssh option",
+			spec: "git+ssh://-oProxyCommand=touch-pwned/owner/repo.git",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dep, ok := parseGitDependency(tc.spec)
+			if tc.expected == nil {
+				assert.False(t, ok)
+				assert.Nil(t, dep)
+				return
+			}
+
+			assert.True(t, ok)
+			assert.Equal(t, tc.expected, dep)
+		})
+	}
+}
+
+func TestSelectGitRef(t *testing.T) {
+	lsRemote := strings.Join([]string{
+		"1111111111111111111111111111111111111111\tHEAD",
+		"1111111111111111111111111111111111111111\trefs/heads/main",
+		"2222222222222222222222222222222222222222\trefs/heads/next",
+		"3333333333333333333333333333333333333333\trefs/tags/v1.0.0",
+		"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa\trefs/tags/v1.4.0",
+		"4444444444444444444444444444444444444444\trefs/tags/v1.4.0^{}",
+		"5555555555555555555555555555555555555555\trefs/tags/v2.0.0",
+		"6666666666666666666666666666666666666666\trefs/tags/not-semver",
+	}, "\n")
+	refs := parseLsRemote(lsRemote)
+
+	testCases := []struct {
+		name        string
+		dep         *GitDependency
+		expected    string
+		expectError bool
+	}{
+		{
+			name:     "default branch",
+			dep:      &GitDependency{},
+			expected: "1111111111111111111111111111111111111111",
+		},
+		{
+			name:     "branch",
+			dep:      &GitDependency{Ref: "next"},
+			expected: "2222222222222222222222222222222222222222",
+		},
+		{
+			name:     "lightweight tag",
+			dep:      &GitDependency{Ref: "v1.0.0"},
+			expected: "3333333333333333333333333333333333333333",
+		},
+		{
+			name:     "annotated tag resolves to its commit",
+			dep:      &GitDependency{Ref: "v1.4.0"},
+			expected: "4444444444444444444444444444444444444444",
+		},
+		{
+			name:     "semver range picks the highest matching tag",
+			dep:      &GitDependency{SemverRange: "^1.0.0"},
+			expected: "4444444444444444444444444444444444444444",
+		},
+		{
+			name:        "semver range without a matching tag",
+			dep:         &GitDependency{SemverRange: "^3.0.0"},
+			expectError: true,
+		},
+		{
+			name:        "unknown ref",
+			dep:         &GitDependency{Ref: "missing"},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sha, err := selectGitRef(refs, tc.dep)
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, sha)
+		})
+	}
+}
+
+func TestCloneGitPackageRejectsRefs(t *testing.T) {
+	for _, ref := range []string{"main", "--upload-pack=touch pwned", "0123456"} {
+		t.Run(ref, func(t *testing.T) {
+			dest := filepath.Join(t.TempDir(), "pkg")
+			err := cloneGitPackage("file:///srv/repos/lib.git", ref, dest)
+			assert.ErrorContains(t, err, "expected a full commit SHA")
+			assert.NoDirExists(t, dest)
+		})
+	}
+}
+
+func TestGitDependencyArchiveURL(t *testing.T) {
+	const sha = "0123456789abcdef0123456789abcdef01234567"
+
+	testCases := []struct {
+		name       string
+		spec       string
+		expected   string
+		hasArchive bool
+	}{
+		{
+			name:       "github",
+			spec:       "git+ssh://git@github.com/owner/repo.git",
+			expected:   "https://github.com/owner/repo/archive/" + sha + ".tar.gz",
+			hasArchive: true,
+		},
+		{
+			name:       "gitlab",
+			spec:       "git+https://gitlab.com/group/project.git",
+			expected:   "https://gitlab.com/group/project/-/archive/" + sha + "/project-" + sha + ".tar.gz",
+			hasArchive: true,
+		},
+		{
+			name:       "bitbucket",
+			spec:       "git+https://bitbucket.org/team/lib.git",
+			expected:   "https://bitbucket.org/team/lib/get/" + sha + ".tar.gz",
+			hasArchive: true,
+		},
+		{
+			name: "self-hosted server is cloned",
+			spec: "git+https://git.example.com/team/lib.git",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dep, ok := parseGitDependency(tc.spec)
+			require.True(t, ok)
+
+			archiveURL, hasArchive := dep.ArchiveURL(sha)
+			assert.Equal(t, tc.hasArchive, hasArchive)
+			assert.Equal(t, tc.expected, archiveURL)
+		})
+	}
+}
+
+func TestFetchToCacheWithGitDependency(t *testing.T) {
+	repoDir := t.TempDir()
+	git := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com", "-c", "init.defaultBranch=main"}, args...)...)
+		cmd.Dir = repoDir
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(output))
+		return strings.TrimSpace(string(output))
+	}
+
+	// One commit per tag; each version's package.json records its own version
+	git("init", "--quiet")
+	tags := map[string]string{}
+	for _, v := range []string{"1.0.0", "1.1.0", "2.0.0"} {
+		require.NoError(t, os.WriteFile(filepath.Join(repoDir, "package.json"), []byte(`{"name": "git-lib", "version": "`+v+`"}`), 0644))
+		git("add", "package.json")
+		git("commit", "--quiet", "-m", "release "+v)
+		git("tag", "-a", "v"+v, "-m", "v"+v)
+		tags[v] = git("rev-parse", "HEAD")
+	}
+
+	testCases := []struct {
+		name            string
+		fragment        string
+		expectedVersion string
+	}{
+		{
+			name:            "semver range resolves against tags",
+			fragment:        "#semver:^1.0.0",
+			expectedVersion: "1.1.0",
+		},
+		{
+			name:            "tag",
+			fragment:        "#v1.0.0",
+			expectedVersion: "1.0.0",
+		},
+		{
+			name:            "default branch",
+			fragment:        "",
+			expectedVersion: "2.0.0",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pm, _, origDir := setupTestPackageManager(t)
+			defer os.Chdir(origDir)
+
+			spec := "git+file://" + repoDir + tc.fragment
+			err := pm.fetchToCache(packagejson.PackageJSON{
+				Dependencies: map[string]string{"git-lib": spec},
+			})
+			require.NoError(t, err)
+
+			commit := tags[tc.expectedVersion]
+			item, ok := pm.packageLock.Packages["node_modules/git-lib"]
+			require.True(t, ok)
+			assert.Equal(t, commit, item.Version, "lock should record the resolved commit")
+			assert.Equal(t, "git+file://"+repoDir+"#"+commit, item.Resolved)
+			assert.Equal(t, spec, pm.packageLock.Dependencies["git-lib"])
+
+			cached := filepath.Join(pm.packagesPath, "git-lib@"+commit)
+			content, err := os.ReadFile(filepath.Join(cached, "package.json"))
+			require.NoError(t, err)
+			assert.Contains(t, string(content), `"version": "`+tc.expectedVersion+`"`)
+			assert.NoDirExists(t, filepath.Join(cached, ".git"))
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package manager
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ernesto27/go-npm/integrity"
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstallFromCacheUpgradesWeakIntegrity(t *testing.T) {
+	testCases := []struct {
+		name          string
+		tamper        bool
+		expectError   bool
+		errorContains string
+	}{
+		{
+			name: "sha1 and missing integrity are upgraded",
+		},
+		{
+			name:          "tarball not matching its sha1",
+			tamper:        true,
+			expectError:   true,
+			errorContains: "SECURITY: integrity check failed for a@1.0.0",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pm, tmpDir, origDir := setupTestPackageManager(t)
+			defer os.Chdir(origDir)
+
+			// a and b have their tarball cached, c only its extracted package
+			sha1SRIs := make(map[string]string)
+			for _, name := range []string{"a", "b", "c"} {
+				packageJSON := `{"name": "` + name + `", "version": "1.0.0"}`
+				writeCachedPackage(t, pm, name, "1.0.0", packageJSON)
+
+				data := buildTestTarball(t, map[string]string{"package.json": packageJSON})
+				sum := sha1.Sum(data)
+				sha1SRIs[name] = "sha1-" + base64.StdEncoding.EncodeToString(sum[:])
+				if name == "c" {
+					continue
+				}
+				if tc.tamper {
+					data = buildTestTarball(t, map[string]string{"package.json": packageJSON, "evil.js": "pwned"})
+				}
+				require.NoError(t, os.MkdirAll(pm.tarball.TarballPath, 0755))
+				require.NoError(t, os.WriteFile(filepath.Join(pm.tarball.TarballPath, generateUniqueTarballName(name, "1.0.0")), data, 0644))
+			}
+
+			require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "package.json"), []byte(`{"name": "app", "dependencies": {"a": "^1.0.0", "b": "^1.0.0", "c": "^1.0.0"}}`), 0644))
+			lock := packagejson.PackageLock{
+				Name:         "app",
+				Dependencies: map[string]string{"a": "^1.0.0", "b": "^1.0.0", "c": "^1.0.0"},
+				Packages: map[string]packagejson.PackageItem{
+					"node_modules/a": {Version: "1.0.0", Resolved: "https://registry.npmjs.org/a/-/a-1.0.0.tgz", Integrity: sha1SRIs["a"]},
+					"node_modules/b": {Version: "1.0.0", Resolved: "https://registry.npmjs.org/b/-/b-1.0.0.tgz"},
+					"node_modules/c": {Version: "1.0.0", Resolved: "https://registry.npmjs.org/c/-/c-1.0.0.tgz", Integrity: sha1SRIs["c"]},
+				},
+			}
+			content, err := json.Marshal(lock)
+			require.NoError(t, err)
+			require.NoError(t, os.WriteFile(pm.packageJsonParse.LockFileName, content, 0644))
+
+			_, err = pm.packageJsonParse.ParseDefault()
+			require.NoError(t, err)
+			require.NoError(t, pm.ParsePackageJSON())
+
+			err = pm.InstallFromCache()
+			if tc.expectError {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.errorContains)
+				return
+			}
+			require.NoError(t, err)
+
+			written, err := pm.packageJsonParse.ParseLockFile()
+			require.NoError(t, err)
+			for _, name := range []string{"a", "b"} {
+				expected, err := integrity.ComputeSRI(filepath.Join(pm.tarball.TarballPath, generateUniqueTarballName(name, "1.0.0")), "sha512")
+				require.NoError(t, err)
+				assert.Equal(t, expected, written.Packages["node_modules/"+name].Integrity, name)
+			}
+
+			// Without its tarball, c keeps its sha1 until a later install
+			assert.Equal(t, sha1SRIs["c"], written.Packages["node_modules/c"].Integrity)
+		})
+	}
+}
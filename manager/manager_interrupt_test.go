@@ -0,0 +1,130 @@
+package manager
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// assertNoPartialFiles fails if dir holds temporary download or extraction
+// leftovers, or entries starting with any of prefixes
+func assertNoPartialFiles(t *testing.T, dir string, prefixes ...string) {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return
+	}
+	require.NoError(t, err)
+
+	for _, entry := range entries {
+		assert.False(t, strings.HasSuffix(entry.Name(), ".tmp"), "partial %s left in %s", entry.Name(), dir)
+		for _, prefix := range prefixes {
+			assert.False(t, strings.HasPrefix(entry.Name(), prefix), "partial %s left in %s", entry.Name(), dir)
+		}
+	}
+}
+
+func TestFetchToCacheInterrupted(t *testing.T) {
+	tarballData := buildTestTarball(t, map[string]string{
+		"package.json": `{"name": "pkg", "version": "1.2.3"}`,
+		"index.js":     strings.Repeat("// padding\n", 1000),
+	})
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var startOnce sync.Once
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Send half of the tarball, then stall until the client gives up
+		w.Write(tarballData[:len(tarballData)/2])
+		w.(http.Flusher).Flush()
+		startOnce.Do(func() { close(started) })
+
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	pm, _, origDir := setupTestPackageManager(t)
+	defer os.Chdir(origDir)
+
+	setupTestRegistry(t, pm, map[string]map[string]map[string]string{"leaf": {"1.0.0": nil}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pm.SetContext(ctx)
+
+	go func() {
+		<-started
+		cancel()
+	}()
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- pm.fetchToCache(packagejson.PackageJSON{Dependencies: map[string]string{
+			"pkg":  server.URL + "/pkg-1.2.3.tgz",
+			"leaf": "^1.0.0",
+		}})
+	}()
+
+	select {
+	case err := <-errChan:
+		require.Error(t, err)
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(10 * time.Second):
+		t.Fatal("fetchToCache did not stop after the context was cancelled")
+	}
+
+	assert.Nil(t, pm.packageLock, "an interrupted install should not produce a lock")
+	assertNoPartialFiles(t, pm.tarball.TarballPath, "pkg-")
+	assertNoPartialFiles(t, pm.packagesPath, "pkg@")
+}
+
+func TestInstallFromCacheInterrupted(t *testing.T) {
+	testCases := []struct {
+		name   string
+		atomic bool
+	}{
+		{name: "direct install"},
+		{name: "atomic install keeps node_modules untouched", atomic: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pm, _, origDir := setupTestPackageManager(t)
+			defer os.Chdir(origDir)
+
+			setupTestRegistry(t, pm, map[string]map[string]map[string]string{
+				"a": {"1.0.0": {"b": "^1.0.0"}},
+				"b": {"1.0.0": nil},
+			})
+			require.NoError(t, pm.fetchToCache(packagejson.PackageJSON{Dependencies: map[string]string{"a": "^1.0.0"}}))
+
+			pm.config.AtomicInstall = tc.atomic
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			pm.SetContext(ctx)
+
+			err := pm.InstallFromCache()
+			require.Error(t, err)
+			assert.ErrorIs(t, err, context.Canceled)
+
+			assert.NoDirExists(t, filepath.Join(pm.extractedPath, "a"))
+			assert.NoDirExists(t, filepath.Join(pm.extractedPath, "b"))
+			assertNoPartialFiles(t, pm.extractedPath)
+		})
+	}
+}
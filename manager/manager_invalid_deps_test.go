@@ -0,0 +1,36 @@
+package manager
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/ernesto27/go-npm/logger"
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchToCacheWarnsAboutNonStringDependencies(t *testing.T) {
+	pm, _, origDir := setupTestPackageManager(t)
+	defer os.Chdir(origDir)
+
+	var buf bytes.Buffer
+	pm.logger = logger.New(logger.LevelInfo)
+	pm.logger.SetOutput(&buf)
+
+	setupTestRegistry(t, pm, map[string]map[string]map[string]string{
+		"a": {"1.0.0": nil},
+		"b": {"1.0.0": nil},
+	})
+	writeCachedPackage(t, pm, "a", "1.0.0", `{"name": "a", "version": "1.0.0", "dependencies": {"b": "^1.0.0", "broken": {"version": "1.0.0"}}}`)
+
+	require.NoError(t, pm.fetchToCache(packagejson.PackageJSON{
+		Dependencies: map[string]string{"a": "^1.0.0"},
+	}))
+
+	assert.Contains(t, buf.String(), `Warning: a@1.0.0: skipping dependencies "broken", its value is not a version string`)
+	assert.Equal(t, map[string]string{"b": "^1.0.0"}, pm.packageLock.Packages["node_modules/a"].Dependencies)
+	assert.Equal(t, "1.0.0", pm.packageLock.Packages["node_modules/b"].Version)
+	assert.NotContains(t, pm.packageLock.Packages, "node_modules/broken")
+}
@@ -0,0 +1,243 @@
+package manager
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ernesto27/go-npm/integrity"
+	"github.com/ernesto27/go-npm/manifest"
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalTarballPrefixes(t *testing.T) {
+	assert.Equal(t, []string{"lib-"}, localTarballPrefixes("lib"))
+	assert.Equal(t, []string{"myorg-util-", "@myorg-util-", filepath.Join("@myorg", "util-")}, localTarballPrefixes("@myorg/util"))
+}
+
+func TestFetchToCacheLocalTarballDir(t *testing.T) {
+	tarballs := map[string]string{
+		"lib-1.0.0.tgz":         `{"name": "lib", "version": "1.0.0"}`,
+		"lib-1.2.0.tgz":         `{"name": "lib", "version": "1.2.0", "dependencies": {"leaf": "^1.0.0"}}`,
+		"lib-2.0.0-beta.1.tgz":  `{"name": "lib", "version": "2.0.0-beta.1"}`,
+		"lib-extra-9.0.0.tgz":   `{"name": "lib-extra", "version": "9.0.0"}`,
+		"leaf-1.0.0.tgz":        `{"name": "leaf", "version": "1.0.0"}`,
+		"myorg-util-1.0.0.tgz":  `{"name": "@myorg/util", "version": "1.0.0"}`,
+		"@other/tool-3.1.0.tgz": `{"name": "@other/tool", "version": "3.1.0"}`,
+	}
+
+	testCases := []struct {
+		name                 string
+		dependencies         map[string]string
+		optionalDependencies map[string]string
+		expected             map[string]string
+		expectError          error
+	}{
+		{
+			name:         "resolves ranges and sub-dependencies from the directory",
+			dependencies: map[string]string{"lib": "^1.0.0", "@myorg/util": "^1.0.0", "@other/tool": "~3.1.0"},
+			expected:     map[string]string{"lib": "1.2.0", "leaf": "1.0.0", "@myorg/util": "1.0.0", "@other/tool": "3.1.0"},
+		},
+		{
+			name:         "latest skips prereleases",
+			dependencies: map[string]string{"lib": "latest"},
+			expected:     map[string]string{"lib": "1.2.0", "leaf": "1.0.0"},
+		},
+		{
+			name:         "missing version fails the install",
+			dependencies: map[string]string{"lib": "^3.0.0"},
+			expectError:  ErrNoLocalTarball,
+		},
+		{
+			name:         "missing package fails the install",
+			dependencies: map[string]string{"other": "^1.0.0"},
+			expectError:  ErrNoLocalTarball,
+		},
+		{
+			name:                 "missing optional package is skipped",
+			dependencies:         map[string]string{"leaf": "^1.0.0"},
+			optionalDependencies: map[string]string{"other": "^1.0.0"},
+			expected:             map[string]string{"leaf": "1.0.0"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pm, _, origDir := setupTestPackageManager(t)
+			defer os.Chdir(origDir)
+
+			// Nothing may come from the registry
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				t.Errorf("unexpected registry request %s", r.URL.Path)
+				http.NotFound(w, r)
+			}))
+			defer server.Close()
+			m, err := manifest.NewManifest(t.TempDir(), server.URL+"/")
+			require.NoError(t, err)
+			pm.manifest = m
+
+			dir := t.TempDir()
+			for name, pkgJSON := range tarballs {
+				path := filepath.Join(dir, name)
+				require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+				require.NoError(t, os.WriteFile(path, buildTestTarball(t, map[string]string{"package.json": pkgJSON}), 0644))
+			}
+			pm.config.LocalTarballDir = dir
+
+			err = pm.fetchToCache(packagejson.PackageJSON{
+				Dependencies:         tc.dependencies,
+				OptionalDependencies: tc.optionalDependencies,
+			})
+			if tc.expectError != nil {
+				assert.ErrorIs(t, err, tc.expectError)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Len(t, pm.packageLock.Packages, len(tc.expected))
+			for name, version := range tc.expected {
+				item := pm.packageLock.Packages["node_modules/"+name]
+				assert.Equal(t, version, item.Version, name)
+
+				// The integrity is computed from the local tarball
+				tarballPath := filepath.Join(pm.tarball.TarballPath, generateUniqueTarballName(name, version))
+				assert.True(t, strings.HasPrefix(item.Integrity, "sha512-"), "integrity %q should be sha512 SRI", item.Integrity)
+				assert.NoError(t, integrity.New().ValidateFileStrict(tarballPath, item.Integrity))
+			}
+
+			require.NoError(t, pm.InstallFromCache())
+			for name := range tc.expected {
+				assert.FileExists(t, filepath.Join(pm.extractedPath, name, "package.json"))
+			}
+		})
+	}
+}
+
+func TestInstallFromCacheLocalTarballDir(t *testing.T) {
+	pm, _, origDir := setupTestPackageManager(t)
+	defer os.Chdir(origDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected registry request %s", r.URL.Path)
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "lib-1.0.0.tgz"), buildTestTarball(t, map[string]string{"package.json": `{"name": "lib", "version": "1.0.0"}`}), 0644))
+	pm.config.LocalTarballDir = dir
+
+	// The locked tarball is copied from the directory, not downloaded
+	pm.packageLock = &packagejson.PackageLock{
+		Dependencies: map[string]string{"lib": "^1.0.0"},
+		Packages: map[string]packagejson.PackageItem{
+			"node_modules/lib": {Version: "1.0.0", Resolved: server.URL + "/lib/-/lib-1.0.0.tgz"},
+		},
+	}
+	require.NoError(t, pm.InstallFromCache())
+	assert.FileExists(t, filepath.Join(pm.extractedPath, "lib", "package.json"))
+}
+
+func TestInstallFromCacheLocalTarballDirSkipsOtherSources(t *testing.T) {
+	const commit = "0123456789abcdef0123456789abcdef01234567"
+
+	testCases := []struct {
+		name     string
+		resolved func(serverURL string) string
+	}{
+		{
+			name:     "git dependency",
+			resolved: func(string) string { return "git+https://github.com/owner/lib.git#" + commit },
+		},
+		{
+			name:     "tarball URL dependency",
+			resolved: func(serverURL string) string { return serverURL + "/downloads/lib.tgz" },
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pm, _, origDir := setupTestPackageManager(t)
+			defer os.Chdir(origDir)
+
+			remote := buildTestTarball(t, map[string]string{"package.json": `{"name": "lib", "version": "1.0.0", "main": "remote.js"}`})
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write(remote)
+			}))
+			defer server.Close()
+
+			// Every host, GitHub's archive one included, is served by server
+			target, err := url.Parse(server.URL)
+			require.NoError(t, err)
+			transport := http.DefaultTransport
+			http.DefaultTransport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+				r = r.Clone(r.Context())
+				r.URL.Scheme = target.Scheme
+				r.URL.Host = target.Host
+				return transport.RoundTrip(r)
+			})
+			defer func() { http.DefaultTransport = transport }()
+
+			// The directory has a tarball of the same name and version that
+			// must not take the place of the locked source
+			dir := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "lib-1.0.0.tgz"), buildTestTarball(t, map[string]string{"package.json": `{"name": "lib", "version": "1.0.0", "main": "local.js"}`}), 0644))
+			pm.config.LocalTarballDir = dir
+
+			pm.packageLock = &packagejson.PackageLock{
+				Dependencies: map[string]string{"lib": tc.resolved(server.URL)},
+				Packages: map[string]packagejson.PackageItem{
+					"node_modules/lib": {Version: "1.0.0", Resolved: tc.resolved(server.URL)},
+				},
+			}
+			require.NoError(t, pm.InstallFromCache())
+
+			installed, err := os.ReadFile(filepath.Join(pm.extractedPath, "lib", "package.json"))
+			require.NoError(t, err)
+			assert.Contains(t, string(installed), "remote.js")
+		})
+	}
+}
+
+func TestInstallFromCacheLocalTarballIntegrity(t *testing.T) {
+	pm, _, origDir := setupTestPackageManager(t)
+	defer os.Chdir(origDir)
+
+	dir := t.TempDir()
+	tarballPath := filepath.Join(dir, "lib-1.0.0.tgz")
+	require.NoError(t, os.WriteFile(tarballPath, buildTestTarball(t, map[string]string{"package.json": `{"name": "lib", "version": "1.0.0"}`}), 0644))
+	sri, err := integrity.ComputeSRI(tarballPath, "sha512")
+	require.NoError(t, err)
+	pm.config.LocalTarballDir = dir
+
+	// A tarball replaced in the directory after locking is refused
+	other := buildTestTarball(t, map[string]string{"package.json": `{"name": "lib", "version": "1.0.0", "main": "evil.js"}`})
+	otherPath := filepath.Join(t.TempDir(), "other.tgz")
+	require.NoError(t, os.WriteFile(otherPath, other, 0644))
+	otherSRI, err := integrity.ComputeSRI(otherPath, "sha512")
+	require.NoError(t, err)
+
+	pm.packageLock = &packagejson.PackageLock{
+		Dependencies: map[string]string{"lib": "^1.0.0"},
+		Packages: map[string]packagejson.PackageItem{
+			"node_modules/lib": {Version: "1.0.0", Resolved: "https://registry.npmjs.org/lib/-/lib-1.0.0.tgz", Integrity: otherSRI},
+		},
+	}
+	err = pm.InstallFromCache()
+	assert.ErrorContains(t, err, "integrity check failed for lib@1.0.0")
+	assert.ErrorIs(t, err, integrity.ErrIntegrityMismatch)
+	assert.NoFileExists(t, filepath.Join(pm.extractedPath, "lib", "package.json"))
+	assert.NoFileExists(t, filepath.Join(pm.tarball.TarballPath, generateUniqueTarballName("lib", "1.0.0")))
+
+	item := pm.packageLock.Packages["node_modules/lib"]
+	item.Integrity = sri
+	pm.packageLock.Packages["node_modules/lib"] = item
+	require.NoError(t, pm.InstallFromCache())
+	assert.FileExists(t, filepath.Join(pm.extractedPath, "lib", "package.json"))
+}
@@ -0,0 +1,47 @@
+package manager
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchToCacheWritesStableLock(t *testing.T) {
+	registry := map[string]map[string]map[string]string{
+		"a":      {"1.0.0": {"shared": "^1.0.0", "leaf": "^1.0.0"}},
+		"b":      {"1.0.0": {"shared": "^2.0.0", "leaf": "^1.0.0"}},
+		"c":      {"1.0.0": {"shared": "^2.0.0", "b": "^1.0.0"}},
+		"shared": {"1.0.0": nil, "2.0.0": {"leaf": "^1.0.0"}},
+		"leaf":   {"1.0.0": nil},
+		"dev":    {"1.0.0": {"shared": "^1.0.0"}},
+		"opt":    {"1.0.0": {"leaf": "^1.0.0"}},
+	}
+	packageJSON := packagejson.PackageJSON{
+		Dependencies:         map[string]string{"a": "^1.0.0", "b": "^1.0.0", "c": "^1.0.0"},
+		DevDependencies:      map[string]string{"dev": "^1.0.0"},
+		OptionalDependencies: map[string]string{"opt": "^1.0.0"},
+	}
+
+	pm, tmpDir, origDir := setupTestPackageManager(t)
+	defer os.Chdir(origDir)
+	setupTestRegistry(t, pm, registry)
+
+	writeLock := func() []byte {
+		require.NoError(t, pm.fetchToCache(packageJSON))
+		require.NoError(t, pm.packageJsonParse.CreateLockFile(pm.packageLock, false))
+
+		data, err := os.ReadFile(filepath.Join(tmpDir, pm.packageJsonParse.LockFileName))
+		require.NoError(t, err)
+		return data
+	}
+
+	first := writeLock()
+	assert.Contains(t, string(first), `"node_modules/b/node_modules/shared"`)
+	for range 10 {
+		require.Equal(t, string(first), string(writeLock()))
+	}
+}
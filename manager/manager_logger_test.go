@@ -0,0 +1,102 @@
+package manager
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/ernesto27/go-npm/logger"
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchToCacheLogsResolutionAtDebug(t *testing.T) {
+	testCases := []struct {
+		name       string
+		level      logger.Level
+		contains   []string
+		notContain []string
+	}{
+		{
+			name:  "debug level logs resolution decisions",
+			level: logger.LevelDebug,
+			contains: []string{
+				"debug: resolved a@^1.0.0 to 1.0.0, required by package.json",
+				"debug: b@1.0.0 already resolved",
+			},
+		},
+		{
+			name:       "info level keeps them out",
+			level:      logger.LevelInfo,
+			notContain: []string{"debug:"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pm, _, origDir := setupTestPackageManager(t)
+			defer os.Chdir(origDir)
+
+			var buf bytes.Buffer
+			pm.logger = logger.New(tc.level)
+			pm.logger.SetOutput(&buf)
+
+			setupTestRegistry(t, pm, map[string]map[string]map[string]string{
+				"a": {"1.0.0": {"c": "^1.0.0"}},
+				"b": {"1.0.0": nil},
+				"c": {"1.0.0": {"b": "^1.0.0"}},
+			})
+
+			require.NoError(t, pm.fetchToCache(packagejson.PackageJSON{
+				Dependencies: map[string]string{"a": "^1.0.0", "b": "^1.0.0"},
+			}))
+
+			for _, expected := range tc.contains {
+				assert.Contains(t, buf.String(), expected)
+			}
+			for _, unexpected := range tc.notContain {
+				assert.NotContains(t, buf.String(), unexpected)
+			}
+		})
+	}
+}
+
+func TestFetchToCacheLogsPeerWarnings(t *testing.T) {
+	testCases := []struct {
+		name     string
+		level    logger.Level
+		expected bool
+	}{
+		{name: "warn level prints unmet peers", level: logger.LevelWarn, expected: true},
+		{name: "error level keeps them out", level: logger.LevelError, expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pm, _, origDir := setupTestPackageManager(t)
+			defer os.Chdir(origDir)
+
+			var buf bytes.Buffer
+			pm.logger = logger.New(tc.level)
+			pm.logger.SetOutput(&buf)
+
+			setupTestRegistry(t, pm, map[string]map[string]map[string]string{
+				"plugin": {"1.0.0": nil},
+				"host":   {"1.0.0": nil},
+			})
+			writeCachedPackage(t, pm, "plugin", "1.0.0", `{"name": "plugin", "version": "1.0.0", "peerDependencies": {"host": "^2.0.0"}}`)
+
+			require.NoError(t, pm.fetchToCache(packagejson.PackageJSON{
+				Dependencies: map[string]string{"plugin": "^1.0.0", "host": "^1.0.0"},
+			}))
+
+			if tc.expected {
+				assert.Contains(t, buf.String(), "Warning: unmet peer dependencies:")
+				assert.Contains(t, buf.String(), "go-npm peers")
+			} else {
+				assert.NotContains(t, buf.String(), "peer")
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package manager
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ernesto27/go-npm/manifest"
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchToCacheRevalidatesStaleManifest(t *testing.T) {
+	const (
+		v1 = `{"name": "lib", "dist-tags": {"latest": "1.0.0"}, "versions": {"1.0.0": {"name": "lib", "version": "1.0.0"}}}`
+		v2 = `{"name": "lib", "dist-tags": {"latest": "1.1.0"}, "versions": {"1.0.0": {"name": "lib", "version": "1.0.0"}, "1.1.0": {"name": "lib", "version": "1.1.0"}}}`
+	)
+
+	testCases := []struct {
+		name            string
+		fetchedAgo      time.Duration
+		preferOffline   bool
+		published       string
+		publishedEtag   string
+		expectedVersion string
+		expectedEtag    string
+		expectRequest   bool
+		expectRefresh   bool
+	}{
+		{
+			name:            "fresh manifest is used without a request",
+			fetchedAgo:      time.Second,
+			published:       v2,
+			publishedEtag:   `"v2"`,
+			expectedVersion: "1.0.0",
+			expectedEtag:    `"v1"`,
+		},
+		{
+			name:            "stale manifest answered with 304 is kept and refreshed",
+			fetchedAgo:      time.Hour,
+			published:       v1,
+			publishedEtag:   `"v1"`,
+			expectedVersion: "1.0.0",
+			expectedEtag:    `"v1"`,
+			expectRequest:   true,
+			expectRefresh:   true,
+		},
+		{
+			name:            "stale manifest answered with 200 is replaced",
+			fetchedAgo:      time.Hour,
+			published:       v2,
+			publishedEtag:   `"v2"`,
+			expectedVersion: "1.1.0",
+			expectedEtag:    `"v2"`,
+			expectRequest:   true,
+			expectRefresh:   true,
+		},
+		{
+			name:            "--prefer-offline does not revalidate",
+			fetchedAgo:      time.Hour,
+			preferOffline:   true,
+			published:       v2,
+			publishedEtag:   `"v2"`,
+			expectedVersion: "1.0.0",
+			expectedEtag:    `"v1"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pm, _, origDir := setupTestPackageManager(t)
+			defer os.Chdir(origDir)
+			pm.config.ManifestMaxAge = time.Minute
+			pm.config.PreferOffline = tc.preferOffline
+
+			var mu sync.Mutex
+			var ifNoneMatch []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				ifNoneMatch = append(ifNoneMatch, r.Header.Get("If-None-Match"))
+				mu.Unlock()
+				if r.Header.Get("If-None-Match") == tc.publishedEtag {
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+				w.Header().Set("ETag", tc.publishedEtag)
+				fmt.Fprint(w, tc.published)
+			}))
+			defer server.Close()
+
+			m, err := manifest.NewManifest(t.TempDir(), server.URL+"/")
+			require.NoError(t, err)
+			pm.manifest = m
+
+			require.NoError(t, os.WriteFile(pm.manifest.FilePath("lib"), []byte(v1), 0644))
+			fetchedAt := time.Now().Add(-tc.fetchedAgo)
+			pm.Etag.Set("lib", `"v1"`, fetchedAt)
+			writeCachedPackage(t, pm, "lib", "1.0.0", `{"name": "lib", "version": "1.0.0"}`)
+			writeCachedPackage(t, pm, "lib", "1.1.0", `{"name": "lib", "version": "1.1.0"}`)
+
+			start := time.Now()
+			require.NoError(t, pm.fetchToCache(packagejson.PackageJSON{
+				Dependencies: map[string]string{"lib": "^1.0.0"},
+			}))
+
+			assert.Equal(t, tc.expectedVersion, pm.packageLock.Packages["node_modules/lib"].Version)
+			assert.Equal(t, tc.expectedEtag, pm.Etag.Get("lib"))
+			if tc.expectRequest {
+				assert.Equal(t, []string{`"v1"`}, ifNoneMatch, "the cached etag should be sent once")
+			} else {
+				assert.Empty(t, ifNoneMatch)
+			}
+			if tc.expectRefresh {
+				assert.False(t, pm.Etag.FetchedAt("lib").Before(start))
+			} else {
+				assert.True(t, fetchedAt.Equal(pm.Etag.FetchedAt("lib")))
+			}
+		})
+	}
+}
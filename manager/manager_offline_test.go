@@ -0,0 +1,114 @@
+package manager
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	manifestpkg "github.com/ernesto27/go-npm/manifest"
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/ernesto27/go-npm/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchToCacheOffline(t *testing.T) {
+	testCases := []struct {
+		name          string
+		dependencies  map[string]string
+		setupFunc     func(t *testing.T, pm *PackageManager)
+		errorContains string
+	}{
+		{
+			name:         "resolves from cached manifests and packages",
+			dependencies: map[string]string{"a": "^1.0.0"},
+		},
+		{
+			name:          "missing manifest",
+			dependencies:  map[string]string{"a": "^1.0.0", "missing": "^1.0.0"},
+			errorContains: "missing@^1.0.0 is not in the cache",
+		},
+		{
+			name:          "cached manifest without a matching version",
+			dependencies:  map[string]string{"a": "^2.0.0"},
+			errorContains: "a@^2.0.0 is not in the cache",
+		},
+		{
+			name:         "missing package",
+			dependencies: map[string]string{"a": "^1.0.0"},
+			setupFunc: func(t *testing.T, pm *PackageManager) {
+				require.NoError(t, os.RemoveAll(filepath.Join(pm.packagesPath, "b@1.0.0")))
+			},
+			errorContains: "b@1.0.0 is not in the cache",
+		},
+		{
+			name:          "git dependency on a branch",
+			dependencies:  map[string]string{"lib": "git+https://example.com/org/lib.git#main"},
+			errorContains: "needs the network to resolve",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pm, _, origDir := setupTestPackageManager(t)
+			defer os.Chdir(origDir)
+
+			// An online install fills the manifest cache
+			setupTestRegistry(t, pm, map[string]map[string]map[string]string{
+				"a": {"1.0.0": {"b": "^1.0.0"}},
+				"b": {"1.0.0": nil},
+			})
+			require.NoError(t, pm.fetchToCache(packagejson.PackageJSON{Dependencies: map[string]string{"a": "^1.0.0"}}))
+			pm.packageLock = nil
+
+			if tc.setupFunc != nil {
+				tc.setupFunc(t, pm)
+			}
+
+			// Any request from now on fails the test
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				t.Errorf("unexpected request in offline mode: %s", r.URL.Path)
+				http.NotFound(w, r)
+			}))
+			defer server.Close()
+
+			offlineManifest, err := manifestpkg.NewManifest(filepath.Dir(pm.manifest.Path), server.URL+"/")
+			require.NoError(t, err)
+			offlineManifest.SetOffline(true)
+			pm.manifest = offlineManifest
+			pm.tarball.SetOffline(true)
+			pm.config.Offline = true
+
+			err = pm.fetchToCache(packagejson.PackageJSON{Dependencies: tc.dependencies})
+			if tc.errorContains != "" {
+				require.Error(t, err)
+				assert.ErrorIs(t, err, utils.ErrOffline)
+				assert.Contains(t, err.Error(), tc.errorContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "1.0.0", pm.packageLock.Packages["node_modules/a"].Version)
+			assert.Equal(t, "1.0.0", pm.packageLock.Packages["node_modules/b"].Version)
+		})
+	}
+}
+
+func TestFetchToCachePreferOffline(t *testing.T) {
+	pm, _, origDir := setupTestPackageManager(t)
+	defer os.Chdir(origDir)
+
+	registry := map[string]map[string]map[string]string{"a": {"1.0.0": nil}}
+	setupTestRegistry(t, pm, registry)
+	require.NoError(t, pm.fetchToCache(packagejson.PackageJSON{Dependencies: map[string]string{"a": "^1.0.0"}}))
+
+	// 2.0.0 is published after the manifest was cached
+	registry["a"]["2.0.0"] = nil
+	writeCachedPackage(t, pm, "a", "2.0.0", `{"name": "a", "version": "2.0.0"}`)
+
+	pm.config.PreferOffline = true
+	pm.packageLock = nil
+	require.NoError(t, pm.fetchToCache(packagejson.PackageJSON{Dependencies: map[string]string{"a": "^2.0.0"}}))
+	assert.Equal(t, "2.0.0", pm.packageLock.Packages["node_modules/a"].Version)
+}
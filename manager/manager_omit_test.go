@@ -0,0 +1,124 @@
+package manager
+
+import (
+	"os"
+	"testing"
+
+	"github.com/ernesto27/go-npm/config"
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchToCacheOmit(t *testing.T) {
+	testCases := []struct {
+		name      string
+		omit      config.OmitSet
+		locked    []string
+		notLocked []string
+		devLocked bool
+	}{
+		{
+			name:      "omit=optional keeps dev dependencies",
+			omit:      config.OmitSet{Optional: true},
+			locked:    []string{"core", "shared", "lint", "lint-dep"},
+			notLocked: []string{"opt", "opt-dep"},
+			devLocked: true,
+		},
+		{
+			name:      "omit=dev,optional keeps only production dependencies",
+			omit:      config.OmitSet{Dev: true, Optional: true},
+			locked:    []string{"core", "shared"},
+			notLocked: []string{"lint", "lint-dep", "opt", "opt-dep"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pm, _, origDir := setupTestPackageManager(t)
+			defer os.Chdir(origDir)
+			pm.config.Omit = tc.omit
+
+			setupTestRegistry(t, pm, map[string]map[string]map[string]string{
+				"core":     {"1.0.0": {"shared": "^1.0.0"}},
+				"shared":   {"1.0.0": nil},
+				"lint":     {"1.0.0": {"lint-dep": "^1.0.0", "shared": "^1.0.0"}},
+				"lint-dep": {"1.0.0": nil},
+				"opt":      {"1.0.0": {"opt-dep": "^1.0.0"}},
+				"opt-dep":  {"1.0.0": nil},
+			})
+
+			require.NoError(t, pm.fetchToCache(packagejson.PackageJSON{
+				Dependencies:         map[string]string{"core": "^1.0.0"},
+				DevDependencies:      map[string]string{"lint": "^1.0.0"},
+				OptionalDependencies: map[string]string{"opt": "^1.0.0"},
+			}))
+
+			for _, name := range tc.locked {
+				assert.Contains(t, pm.packageLock.Packages, "node_modules/"+name)
+			}
+			for _, name := range tc.notLocked {
+				assert.NotContains(t, pm.packageLock.Packages, "node_modules/"+name)
+			}
+			assert.Equal(t, tc.devLocked, len(pm.packageLock.DevDependencies) > 0)
+			assert.Empty(t, pm.packageLock.OptionalDependencies)
+		})
+	}
+}
+
+func TestRemoveOmittedPackages(t *testing.T) {
+	testCases := []struct {
+		name     string
+		omit     config.OmitSet
+		expected []string
+	}{
+		{
+			name:     "nothing omitted",
+			expected: []string{"core", "shared", "lint", "lint-dep", "opt", "opt-dep"},
+		},
+		{
+			name:     "omit=optional",
+			omit:     config.OmitSet{Optional: true},
+			expected: []string{"core", "shared", "lint", "lint-dep"},
+		},
+		{
+			name:     "omit=dev,optional keeps packages shared with production",
+			omit:     config.OmitSet{Dev: true, Optional: true},
+			expected: []string{"core", "shared"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pm, _, origDir := setupTestPackageManager(t)
+			defer os.Chdir(origDir)
+			pm.config.Omit = tc.omit
+
+			pm.packageJsonParse.PackageLock = &packagejson.PackageLock{
+				Dependencies:         map[string]string{"core": "^1.0.0"},
+				DevDependencies:      map[string]string{"lint": "^1.0.0"},
+				OptionalDependencies: map[string]string{"opt": "1.0.0"},
+				Packages: map[string]packagejson.PackageItem{
+					"node_modules/core":     {Version: "1.0.0", Dependencies: map[string]string{"shared": "^1.0.0"}},
+					"node_modules/shared":   {Version: "1.0.0"},
+					"node_modules/lint":     {Version: "1.0.0", Dependencies: map[string]string{"lint-dep": "^1.0.0", "shared": "^1.0.0"}},
+					"node_modules/lint-dep": {Version: "1.0.0"},
+					"node_modules/opt":      {Version: "1.0.0", Optional: true, Dependencies: map[string]string{"opt-dep": "^1.0.0"}},
+					"node_modules/opt-dep":  {Version: "1.0.0", Optional: true},
+				},
+			}
+
+			pm.removeOmittedPackages()
+
+			expected := make([]string, 0, len(tc.expected))
+			for _, name := range tc.expected {
+				expected = append(expected, "node_modules/"+name)
+			}
+			actual := make([]string, 0)
+			for pkgPath := range pm.packageJsonParse.PackageLock.Packages {
+				actual = append(actual, pkgPath)
+			}
+			assert.ElementsMatch(t, expected, actual)
+		})
+	}
+}
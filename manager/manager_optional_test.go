@@ -0,0 +1,110 @@
+package manager
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ernesto27/go-npm/manifest"
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchToCacheOptionalModes(t *testing.T) {
+	manifests := map[string]string{
+		"core":    `{"name": "core", "dist-tags": {"latest": "1.0.0"}, "versions": {"1.0.0": {"name": "core", "version": "1.0.0"}}}`,
+		"opt":     `{"name": "opt", "dist-tags": {"latest": "1.0.0"}, "versions": {"1.0.0": {"name": "opt", "version": "1.0.0", "dependencies": {"opt-dep": "^2.0.0"}, "dist": {"integrity": "sha512-b3B0"}}}}`,
+		"opt-dep": `{"name": "opt-dep", "dist-tags": {"latest": "2.1.0"}, "versions": {"2.1.0": {"name": "opt-dep", "version": "2.1.0", "dist": {"integrity": "sha512-ZGVw"}}}}`,
+	}
+
+	testCases := []struct {
+		name           string
+		ignoreOptional bool
+		noOptional     bool
+		validate       func(t *testing.T, pm *PackageManager, requested map[string]int)
+	}{
+		{
+			name:           "--ignore-optional locks the optional tree without downloading it",
+			ignoreOptional: true,
+			validate: func(t *testing.T, pm *PackageManager, requested map[string]int) {
+				lock := pm.packageLock
+				opt := lock.Packages["node_modules/opt"]
+				assert.Equal(t, "1.0.0", opt.Version)
+				assert.True(t, opt.Optional)
+				assert.False(t, opt.Skipped)
+				assert.Equal(t, buildTarballURL("opt", "1.0.0"), opt.Resolved)
+				assert.Equal(t, "sha512-b3B0", opt.Integrity)
+				assert.Equal(t, map[string]string{"opt-dep": "^2.0.0"}, opt.Dependencies)
+				assert.Equal(t, "1.0.0", lock.OptionalDependencies["opt"])
+
+				optDep := lock.Packages["node_modules/opt-dep"]
+				assert.Equal(t, "2.1.0", optDep.Version)
+				assert.True(t, optDep.Optional)
+
+				assert.NoDirExists(t, filepath.Join(pm.packagesPath, "opt@1.0.0"))
+				assert.NoDirExists(t, filepath.Join(pm.packagesPath, "opt-dep@2.1.0"))
+				assert.Equal(t, 1, requested["opt"])
+			},
+		},
+		{
+			name:       "--no-optional drops optional dependencies from the lock",
+			noOptional: true,
+			validate: func(t *testing.T, pm *PackageManager, requested map[string]int) {
+				lock := pm.packageLock
+				assert.NotContains(t, lock.Packages, "node_modules/opt")
+				assert.NotContains(t, lock.Packages, "node_modules/opt-dep")
+				assert.Empty(t, lock.OptionalDependencies)
+				assert.Zero(t, requested["opt"], "the optional manifest should not be fetched")
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pm, tmpDir, origDir := setupTestPackageManager(t)
+			defer os.Chdir(origDir)
+			pm.config.IgnoreOptional = tc.ignoreOptional
+			pm.config.Omit.Optional = tc.noOptional
+
+			var mu sync.Mutex
+			requested := make(map[string]int)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				name := strings.TrimPrefix(r.URL.Path, "/")
+				mu.Lock()
+				requested[name]++
+				mu.Unlock()
+				data, ok := manifests[name]
+				if !ok {
+					http.NotFound(w, r)
+					return
+				}
+				fmt.Fprint(w, data)
+			}))
+			defer server.Close()
+
+			m, err := manifest.NewManifest(t.TempDir(), server.URL+"/")
+			require.NoError(t, err)
+			pm.manifest = m
+
+			writeCachedPackage(t, pm, "core", "1.0.0", `{"name": "core", "version": "1.0.0"}`)
+
+			require.NoError(t, pm.fetchToCache(packagejson.PackageJSON{
+				Dependencies:         map[string]string{"core": "^1.0.0"},
+				OptionalDependencies: map[string]string{"opt": "^1.0.0"},
+			}))
+			tc.validate(t, pm, requested)
+
+			// Only the required package is placed in node_modules
+			require.NoError(t, pm.InstallFromCache())
+			assert.DirExists(t, filepath.Join(tmpDir, "node_modules", "core"))
+			assert.NoDirExists(t, filepath.Join(tmpDir, "node_modules", "opt"))
+			assert.NoDirExists(t, filepath.Join(tmpDir, "node_modules", "opt-dep"))
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package manager
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddBinToPath(t *testing.T) {
+	testCases := []struct {
+		name     string
+		shell    string
+		rcFile   string
+		pathLine string
+	}{
+		{name: "bash", shell: "/bin/bash", rcFile: ".bashrc", pathLine: "export PATH="},
+		{name: "zsh", shell: "/bin/zsh", rcFile: ".zshrc", pathLine: "export PATH="},
+		{name: "fish", shell: "/usr/bin/fish", rcFile: filepath.Join(".config", "fish", "config.fish"), pathLine: "set -gx PATH "},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pm, _, origDir := setupTestPackageManager(t)
+			defer os.Chdir(origDir)
+
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+			t.Setenv("SHELL", tc.shell)
+
+			// Running twice must not add the line again
+			for range 2 {
+				rcPath, err := pm.addBinToPath()
+				require.NoError(t, err)
+				assert.Equal(t, filepath.Join(home, tc.rcFile), rcPath)
+			}
+
+			content, err := os.ReadFile(filepath.Join(home, tc.rcFile))
+			require.NoError(t, err)
+			assert.Equal(t, 1, strings.Count(string(content), pm.config.GlobalBinExportLine()))
+			assert.Contains(t, string(content), tc.pathLine+`"`+pm.config.GlobalBinDir)
+
+			// Only the current shell's startup file is touched
+			for _, other := range []string{".bashrc", ".zshrc", filepath.Join(".config", "fish", "config.fish")} {
+				if other != tc.rcFile {
+					assert.NoFileExists(t, filepath.Join(home, other))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package manager
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidatePeerDependenciesSkipsOptionalPeers(t *testing.T) {
+	pm, tmpDir, origDir := setupTestPackageManager(t)
+	defer os.Chdir(origDir)
+
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "package.json"), []byte(`{
+  "name": "app",
+  "dependencies": {"a": "^1.0.0"},
+  "peerDependencies": {"root-peer": "^1.0.0"},
+  "peerDependenciesMeta": {"root-peer": {"optional": true}}
+}`), 0644))
+
+	// Neither optional peer is published, so neither gets installed
+	setupTestRegistry(t, pm, map[string]map[string]map[string]string{"a": {"1.0.0": nil}})
+	writeCachedPackage(t, pm, "a", "1.0.0", `{
+  "name": "a",
+  "version": "1.0.0",
+  "peerDependencies": {"missing-peer": "^1.0.0"},
+  "peerDependenciesMeta": {"missing-peer": {"optional": true}}
+}`)
+
+	require.NoError(t, pm.ParsePackageJSON())
+
+	item := pm.packageLock.Packages["node_modules/a"]
+	assert.Equal(t, map[string]string{"missing-peer": "^1.0.0"}, item.PeerDependencies)
+	assert.True(t, item.PeerDependenciesMeta["missing-peer"].Optional)
+	assert.NotContains(t, pm.packageLock.Packages, "node_modules/missing-peer")
+	assert.NotContains(t, pm.packageLock.Packages, "node_modules/root-peer")
+
+	assert.Empty(t, pm.validatePeerDependencies(pm.packageLock))
+
+	// Without the meta the same missing peer is reported
+	item.PeerDependenciesMeta = nil
+	pm.packageLock.Packages["node_modules/a"] = item
+	assert.Equal(t, []string{"node_modules/a requires peer missing-peer@^1.0.0 but it is not installed"}, pm.validatePeerDependencies(pm.packageLock))
+}
@@ -0,0 +1,145 @@
+package manager
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ernesto27/go-npm/manifest"
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/ernesto27/go-npm/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePackageJSONRootPlatform(t *testing.T) {
+	otherOS := "win32"
+	if utils.GetCurrentOS() == "win32" {
+		otherOS = "linux"
+	}
+
+	testCases := []struct {
+		name        string
+		platform    string
+		expectError bool
+	}{
+		{
+			name:        "other os",
+			platform:    `"os": ["` + otherOS + `"]`,
+			expectError: true,
+		},
+		{
+			name:        "current os excluded",
+			platform:    `"os": ["!` + utils.GetCurrentOS() + `"]`,
+			expectError: true,
+		},
+		{
+			name:        "other cpu as a string",
+			platform:    `"cpu": "not-a-cpu"`,
+			expectError: true,
+		},
+		{
+			name:     "current os and cpu",
+			platform: `"os": ["` + utils.GetCurrentOS() + `"], "cpu": ["` + utils.GetCurrentCPU() + `"]`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pm, tmpDir, origDir := setupTestPackageManager(t)
+			defer os.Chdir(origDir)
+
+			var requests atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests.Add(1)
+				http.NotFound(w, r)
+			}))
+			defer server.Close()
+
+			m, err := manifest.NewManifest(t.TempDir(), server.URL+"/")
+			require.NoError(t, err)
+			pm.manifest = m
+
+			require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "package.json"), []byte(`{
+  "name": "app",
+  "version": "1.0.0",
+  `+tc.platform+`
+}`), 0644))
+
+			err = pm.ParsePackageJSON()
+			if !tc.expectError {
+				assert.NoError(t, err)
+				return
+			}
+
+			require.ErrorIs(t, err, ErrBadPlatform)
+			assert.Contains(t, err.Error(), "unsupported platform for app@1.0.0")
+			assert.Zero(t, requests.Load())
+			assert.NoFileExists(t, pm.packageJsonParse.LockFileName)
+		})
+	}
+}
+
+func TestFetchToCacheRequiredPlatform(t *testing.T) {
+	otherOS := "win32"
+	if utils.GetCurrentOS() == "win32" {
+		otherOS = "linux"
+	}
+
+	testCases := []struct {
+		name        string
+		optional    bool
+		force       bool
+		expectError bool
+		skipped     bool
+	}{
+		{name: "required dependency fails", expectError: true},
+		{name: "required dependency with force", force: true},
+		{name: "optional dependency is skipped", optional: true, skipped: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pm, _, origDir := setupTestPackageManager(t)
+			defer os.Chdir(origDir)
+			pm.config.Force = tc.force
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/native" {
+					http.NotFound(w, r)
+					return
+				}
+				w.Write([]byte(`{"name": "native", "dist-tags": {"latest": "1.0.0"}, "versions": {
+  "1.0.0": {"name": "native", "version": "1.0.0", "os": ["` + otherOS + `"]}
+}}`))
+			}))
+			defer server.Close()
+
+			m, err := manifest.NewManifest(t.TempDir(), server.URL+"/")
+			require.NoError(t, err)
+			pm.manifest = m
+			writeCachedPackage(t, pm, "native", "1.0.0", `{"name": "native", "version": "1.0.0", "os": ["`+otherOS+`"]}`)
+
+			pkg := packagejson.PackageJSON{Dependencies: map[string]string{"native": "^1.0.0"}}
+			if tc.optional {
+				pkg = packagejson.PackageJSON{OptionalDependencies: map[string]string{"native": "^1.0.0"}}
+			}
+
+			err = pm.fetchToCache(pkg)
+			if tc.expectError {
+				require.ErrorIs(t, err, ErrBadPlatform)
+				assert.Contains(t, err.Error(), `unsupported platform for native@1.0.0: wanted {"os":"`+otherOS+`","cpu":"any"}`)
+				return
+			}
+			require.NoError(t, err)
+
+			item, ok := pm.packageLock.Packages["node_modules/native"]
+			require.True(t, ok)
+			assert.Equal(t, "1.0.0", item.Version)
+			assert.Equal(t, tc.skipped, item.Skipped)
+		})
+	}
+}
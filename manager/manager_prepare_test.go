@@ -0,0 +1,168 @@
+package manager
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ernesto27/go-npm/config"
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/ernesto27/go-npm/scripts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstallRunsPrepareOfFileAndGitDependencies(t *testing.T) {
+	const prepare = `{"prepare": "mkdir -p dist && echo built > dist/index.js"}`
+
+	// newGitRepo commits packageJSON to a new repository and returns its path
+	newGitRepo := func(t *testing.T, packageJSON string) string {
+		t.Helper()
+		repoDir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(repoDir, "package.json"), []byte(packageJSON), 0644))
+		for _, args := range [][]string{
+			{"init", "--quiet"},
+			{"add", "package.json"},
+			{"commit", "--quiet", "-m", "initial"},
+		} {
+			cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com", "-c", "init.defaultBranch=main"}, args...)...)
+			cmd.Dir = repoDir
+			output, err := cmd.CombinedOutput()
+			require.NoError(t, err, strings.TrimSpace(string(output)))
+		}
+		return repoDir
+	}
+
+	testCases := []struct {
+		name          string
+		setupFunc     func(t *testing.T, tmpDir string) (string, string)
+		trusted       []string
+		ignoreScripts bool
+		expectBuilt   bool
+	}{
+		{
+			name: "trusted file: dependency is prepared",
+			setupFunc: func(t *testing.T, tmpDir string) (string, string) {
+				sourceDir := filepath.Join(tmpDir, "shared")
+				require.NoError(t, os.MkdirAll(sourceDir, 0755))
+				require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "package.json"), []byte(`{"name": "shared", "version": "0.1.0", "scripts": `+prepare+`}`), 0644))
+				return "shared", "file:shared"
+			},
+			trusted:     []string{"shared"},
+			expectBuilt: true,
+		},
+		{
+			name: "trusted git dependency is prepared",
+			setupFunc: func(t *testing.T, tmpDir string) (string, string) {
+				repoDir := newGitRepo(t, `{"name": "git-lib", "version": "1.0.0", "scripts": `+prepare+`}`)
+				return "git-lib", "git+file://" + repoDir
+			},
+			trusted:     []string{"git-lib"},
+			expectBuilt: true,
+		},
+		{
+			name: "untrusted dependency is not prepared",
+			setupFunc: func(t *testing.T, tmpDir string) (string, string) {
+				sourceDir := filepath.Join(tmpDir, "shared")
+				require.NoError(t, os.MkdirAll(sourceDir, 0755))
+				require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "package.json"), []byte(`{"name": "shared", "version": "0.1.0", "scripts": `+prepare+`}`), 0644))
+				return "shared", "file:shared"
+			},
+			expectBuilt: false,
+		},
+		{
+			name: "--ignore-scripts skips prepare",
+			setupFunc: func(t *testing.T, tmpDir string) (string, string) {
+				repoDir := newGitRepo(t, `{"name": "git-lib", "version": "1.0.0", "scripts": `+prepare+`}`)
+				return "git-lib", "git+file://" + repoDir
+			},
+			trusted:       []string{"git-lib"},
+			ignoreScripts: true,
+			expectBuilt:   false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pm, tmpDir, origDir := setupTestPackageManager(t)
+			defer os.Chdir(origDir)
+
+			name, spec := tc.setupFunc(t, tmpDir)
+
+			pm.lifecycleManager = scripts.NewLifecycleManager(pm.extractedPath, tc.ignoreScripts)
+			pm.lifecycleManager.SetTrustedDependencies(tc.trusted)
+
+			require.NoError(t, pm.fetchToCache(packagejson.PackageJSON{Dependencies: map[string]string{name: spec}}))
+			require.NoError(t, pm.InstallFromCache())
+
+			artifact := filepath.Join(pm.extractedPath, name, "dist", "index.js")
+			if tc.expectBuilt {
+				assert.FileExists(t, artifact)
+			} else {
+				assert.NoFileExists(t, artifact)
+			}
+		})
+	}
+}
+
+func TestInstallPrepareLeavesCacheUntouched(t *testing.T) {
+	// prepare appends to a file that is in the cache too
+	const packageJSON = `{"name": "git-lib", "version": "1.0.0", "scripts": {"prepare": "echo built >> index.js"}}`
+
+	for _, strategy := range []string{config.InstallStrategyHardlink, config.InstallStrategySymlink} {
+		t.Run(strategy, func(t *testing.T) {
+			pm, tmpDir, origDir := setupTestPackageManager(t)
+			defer os.Chdir(origDir)
+			pm.config.InstallStrategy = strategy
+
+			repoDir := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(repoDir, "package.json"), []byte(packageJSON), 0644))
+			require.NoError(t, os.WriteFile(filepath.Join(repoDir, "index.js"), []byte("module.exports = 1\n"), 0644))
+			for _, args := range [][]string{
+				{"init", "--quiet"},
+				{"add", "."},
+				{"commit", "--quiet", "-m", "initial"},
+			} {
+				cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com", "-c", "init.defaultBranch=main"}, args...)...)
+				cmd.Dir = repoDir
+				output, err := cmd.CombinedOutput()
+				require.NoError(t, err, strings.TrimSpace(string(output)))
+			}
+
+			pm.lifecycleManager = scripts.NewLifecycleManager(pm.extractedPath, false)
+			pm.lifecycleManager.SetTrustedDependencies([]string{"git-lib"})
+
+			require.NoError(t, pm.fetchToCache(packagejson.PackageJSON{Dependencies: map[string]string{"git-lib": "git+file://" + repoDir}}))
+			require.NoError(t, pm.InstallFromCache())
+
+			installed, err := os.ReadFile(filepath.Join(pm.extractedPath, "git-lib", "index.js"))
+			require.NoError(t, err)
+			assert.Equal(t, "module.exports = 1\nbuilt\n", string(installed))
+
+			// Every cached copy of index.js still has its original content
+			nodeModules, err := filepath.Abs(pm.extractedPath)
+			require.NoError(t, err)
+			var cached int
+			err = filepath.WalkDir(tmpDir, func(path string, d os.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if path == nodeModules {
+					return filepath.SkipDir
+				}
+				if d.Name() != "index.js" || !d.Type().IsRegular() {
+					return nil
+				}
+				cached++
+				data, err := os.ReadFile(path)
+				require.NoError(t, err)
+				assert.Equal(t, "module.exports = 1\n", string(data), path)
+				return nil
+			})
+			require.NoError(t, err)
+			assert.NotZero(t, cached)
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package manager
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/ernesto27/go-npm/progress"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstallFromCacheLinesProgress(t *testing.T) {
+	pm, _, origDir := setupTestPackageManager(t)
+	defer os.Chdir(origDir)
+
+	var out bytes.Buffer
+	pm.progress.SetRenderer(progress.RendererLines)
+	pm.progress.SetOutput(&out)
+
+	setupAtomicInstall(t, pm)
+	require.NoError(t, pm.InstallFromCache())
+
+	var completed []string
+	for _, line := range strings.Split(out.String(), "\n") {
+		if strings.HasPrefix(line, "✓ ") {
+			completed = append(completed, strings.TrimPrefix(line, "✓ "))
+		}
+	}
+	assert.ElementsMatch(t, []string{"a@1.0.0", "b@2.0.0", "@scope/c@1.0.0"}, completed, "each installed package should print exactly one line")
+}
+
+func TestInstallFromCacheVerboseProgress(t *testing.T) {
+	pm, _, origDir := setupTestPackageManager(t)
+	defer os.Chdir(origDir)
+
+	install := func() string {
+		var out bytes.Buffer
+		pm.progress = progress.New("test", true)
+		pm.progress.SetOutput(&out)
+		require.NoError(t, pm.InstallFromCache())
+		assert.Empty(t, pm.progress.Phases(), "every package should leave its phase")
+		return out.String()
+	}
+
+	writeCachedPackage(t, pm, "a", "1.0.0", `{"name": "a", "version": "1.0.0"}`)
+	writeCachedPackage(t, pm, "@scope/c", "1.0.0", `{"name": "@scope/c", "version": "1.0.0"}`)
+	pm.packageLock = &packagejson.PackageLock{
+		Dependencies: map[string]string{"a": "^1.0.0", "@scope/c": "^1.0.0"},
+		Packages: map[string]packagejson.PackageItem{
+			"node_modules/a":        {Name: "a", Version: "1.0.0"},
+			"node_modules/@scope/c": {Name: "@scope/c", Version: "1.0.0"},
+		},
+	}
+
+	first := install()
+	assert.Contains(t, first, "2 packages installed")
+	for _, pkg := range []string{"a@1.0.0", "@scope/c@1.0.0"} {
+		assert.Equal(t, 1, strings.Count(first, "✓ "+pkg+"\n"), "%s should print once", pkg)
+	}
+
+	// Packages already in node_modules are not printed but still counted
+	second := install()
+	assert.NotContains(t, second, "✓ ")
+	assert.Contains(t, second, "2 packages installed")
+}
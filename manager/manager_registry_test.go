@@ -0,0 +1,95 @@
+package manager
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/ernesto27/go-npm/manifest"
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockRegistries(t *testing.T) {
+	pm, _, origDir := setupTestPackageManager(t)
+	defer os.Chdir(origDir)
+
+	setupTestRegistry(t, pm, map[string]map[string]map[string]string{"leaf": {"1.0.0": nil}})
+	registryURL := pm.manifest.RegistryURL()
+
+	require.NoError(t, pm.fetchToCache(packagejson.PackageJSON{Dependencies: map[string]string{"leaf": "^1.0.0"}}))
+	assert.Equal(t, map[string]string{"default": registryURL}, pm.packageLock.Registries)
+
+	// Round trip through go-npm-lock.json
+	require.NoError(t, pm.packageJsonParse.CreateLockFile(pm.packageLock, false))
+	lock, err := pm.packageJsonParse.ParseLockFile()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"default": registryURL}, lock.Registries)
+
+	var buf bytes.Buffer
+	warnRegistryChange(&buf, lock.Registries, pm.registries())
+	assert.Empty(t, buf.String(), "same registry should not warn")
+
+	// Reinstall against another registry
+	m, err := manifest.NewManifest(t.TempDir(), "https://mirror.example.com/npm/")
+	require.NoError(t, err)
+	pm.manifest = m
+
+	warnRegistryChange(&buf, lock.Registries, pm.registries())
+	assert.Contains(t, buf.String(), "Registry changed since the lock file was resolved")
+	assert.Contains(t, buf.String(), "default: "+registryURL+" (lock) -> https://mirror.example.com/npm/ (current)")
+}
+
+func TestFetchToCacheScopeRegistries(t *testing.T) {
+	pm, _, origDir := setupTestPackageManager(t)
+	defer os.Chdir(origDir)
+
+	// Only the scope registry knows @myorg/lib and only the default one knows
+	// left, so a package routed to the wrong registry fails to resolve
+	setupTestRegistry(t, pm, map[string]map[string]map[string]string{"@myorg/lib": {"1.0.0": nil}})
+	scopeURL := pm.manifest.RegistryURL()
+	setupTestRegistry(t, pm, map[string]map[string]map[string]string{"left": {"1.0.0": nil}})
+	defaultURL := pm.manifest.RegistryURL()
+	pm.manifest.SetScopeRegistries(map[string]string{"@myorg": scopeURL})
+
+	require.NoError(t, pm.fetchToCache(packagejson.PackageJSON{
+		Dependencies: map[string]string{"@myorg/lib": "^1.0.0", "left": "^1.0.0"},
+	}))
+
+	assert.Equal(t, scopeURL+"@myorg/lib/-/lib-1.0.0.tgz", pm.packageLock.Packages["node_modules/@myorg/lib"].Resolved)
+	assert.Equal(t, npmRegistryURL+"left/-/left-1.0.0.tgz", pm.packageLock.Packages["node_modules/left"].Resolved)
+	assert.Equal(t, map[string]string{"default": defaultURL, "@myorg": scopeURL}, pm.packageLock.Registries)
+}
+
+func TestRegistryMismatches(t *testing.T) {
+	testCases := []struct {
+		name     string
+		locked   map[string]string
+		current  map[string]string
+		expected []string
+	}{
+		{
+			name:    "lock without registries",
+			locked:  nil,
+			current: map[string]string{"default": "https://registry.npmjs.org/"},
+		},
+		{
+			name:    "trailing slash is ignored",
+			locked:  map[string]string{"default": "https://registry.npmjs.org"},
+			current: map[string]string{"default": "https://registry.npmjs.org/"},
+		},
+		{
+			name:     "scope falls back to the default registry",
+			locked:   map[string]string{"default": "https://registry.npmjs.org/", "@corp": "https://npm.corp.example/"},
+			current:  map[string]string{"default": "https://registry.npmjs.org/"},
+			expected: []string{"@corp: https://npm.corp.example/ (lock) -> https://registry.npmjs.org/ (current)"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, registryMismatches(tc.locked, tc.current))
+		})
+	}
+}
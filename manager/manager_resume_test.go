@@ -0,0 +1,81 @@
+package manager
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchToCacheResumesFailedInstall(t *testing.T) {
+	pm, tmpDir, origDir := setupTestPackageManager(t)
+	defer os.Chdir(origDir)
+
+	var (
+		mu          sync.Mutex
+		downloads   = make(map[string]int)
+		bPublished  bool
+		tarballData = make(map[string][]byte)
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		downloads[r.URL.Path]++
+		data, ok := tarballData[r.URL.Path]
+		if !ok || (r.URL.Path == "/b-1.0.0.tgz" && !bPublished) {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	// a is resolved first; b, which it depends on, fails the first install
+	tarballData["/a-1.0.0.tgz"] = buildTestTarball(t, map[string]string{
+		"package.json": `{"name": "a", "version": "1.0.0", "dependencies": {"leaf": "^1.0.0", "b": "` + server.URL + `/b-1.0.0.tgz"}}`,
+	})
+	tarballData["/b-1.0.0.tgz"] = buildTestTarball(t, map[string]string{
+		"package.json": `{"name": "b", "version": "1.0.0"}`,
+	})
+	setupTestRegistry(t, pm, map[string]map[string]map[string]string{"leaf": {"1.0.0": nil}})
+
+	dependencies := map[string]string{"a": server.URL + "/a-1.0.0.tgz"}
+	err := pm.fetchToCache(packagejson.PackageJSON{Dependencies: dependencies})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "b-1.0.0.tgz")
+
+	logPath := pm.resumeLogPath(tmpDir)
+	content, err := os.ReadFile(logPath)
+	require.NoError(t, err, "a failed install keeps its resume log")
+	assert.Contains(t, string(content), `"name":"a"`)
+
+	// Without the log, the version of a is only known by reading its tarball,
+	// which is no longer cached
+	require.NoError(t, os.RemoveAll(pm.tarball.TarballPath))
+	require.NoError(t, os.MkdirAll(pm.tarball.TarballPath, 0755))
+
+	mu.Lock()
+	bPublished = true
+	mu.Unlock()
+
+	pm.packageLock = nil
+	require.NoError(t, pm.fetchToCache(packagejson.PackageJSON{Dependencies: dependencies}))
+
+	assert.Equal(t, "1.0.0", pm.packageLock.Packages["node_modules/a"].Version)
+	assert.True(t, strings.HasPrefix(pm.packageLock.Packages["node_modules/a"].Integrity, "sha512-"))
+	assert.Equal(t, "1.0.0", pm.packageLock.Packages["node_modules/b"].Version)
+	assert.Equal(t, "1.0.0", pm.packageLock.Packages["node_modules/leaf"].Version)
+
+	mu.Lock()
+	assert.Equal(t, 1, downloads["/a-1.0.0.tgz"], "a should not be downloaded again")
+	assert.Equal(t, 2, downloads["/b-1.0.0.tgz"])
+	mu.Unlock()
+
+	assert.NoFileExists(t, logPath, "a complete install removes the resume log")
+}
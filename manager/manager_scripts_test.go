@@ -0,0 +1,112 @@
+package manager
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockRecordsInstallScripts(t *testing.T) {
+	testCases := []struct {
+		name     string
+		scripts  map[string]string
+		expected bool
+	}{
+		{
+			name:     "tree without scripts",
+			expected: false,
+		},
+		{
+			name:     "tree with a build script only",
+			scripts:  map[string]string{"build": "tsc"},
+			expected: false,
+		},
+		{
+			name:     "tree with a postinstall script",
+			scripts:  map[string]string{"postinstall": "node build.js"},
+			expected: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pm, tmpDir, origDir := setupTestPackageManager(t)
+			defer os.Chdir(origDir)
+
+			setupTestRegistry(t, pm, map[string]map[string]map[string]string{
+				"a": {"1.0.0": {"b": "^2.0.0"}},
+				"b": {"2.0.0": nil},
+			})
+			data, err := json.Marshal(map[string]any{"name": "b", "version": "2.0.0", "scripts": tc.scripts})
+			require.NoError(t, err)
+			writeCachedPackage(t, pm, "b", "2.0.0", string(data))
+
+			require.NoError(t, pm.fetchToCache(packagejson.PackageJSON{
+				Dependencies: map[string]string{"a": "^1.0.0"},
+			}))
+			require.NoError(t, pm.packageJsonParse.CreateLockFile(pm.packageLock, false))
+
+			content, err := os.ReadFile(filepath.Join(tmpDir, packagejson.LOCK_FILE_NAME_GO_NPM))
+			require.NoError(t, err)
+			var lock packagejson.PackageLock
+			require.NoError(t, json.Unmarshal(content, &lock))
+			require.NotNil(t, lock.HasInstallScripts)
+			assert.Equal(t, tc.expected, *lock.HasInstallScripts)
+			assert.Equal(t, tc.expected, pm.packageLock.RunsInstallScripts())
+		})
+	}
+}
+
+func TestInstallFromCacheSkipsScriptPass(t *testing.T) {
+	hasScripts := func(v bool) *bool { return &v }
+
+	testCases := []struct {
+		name              string
+		hasInstallScripts *bool
+		expectRun         bool
+	}{
+		{
+			name:              "lock recording no install scripts skips the pass",
+			hasInstallScripts: hasScripts(false),
+			expectRun:         false,
+		},
+		{
+			name:              "lock recording install scripts runs them",
+			hasInstallScripts: hasScripts(true),
+			expectRun:         true,
+		},
+		{
+			name:      "older lock without the aggregate runs them",
+			expectRun: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pm, tmpDir, origDir := setupTestPackageManager(t)
+			defer os.Chdir(origDir)
+
+			setupAtomicInstall(t, pm)
+			marker := filepath.Join(tmpDir, "postinstall-ran")
+			item := pm.packageLock.Packages["node_modules/a"]
+			item.Scripts = map[string]string{"postinstall": "touch " + marker}
+			pm.packageLock.Packages["node_modules/a"] = item
+			pm.packageLock.HasInstallScripts = tc.hasInstallScripts
+			pm.lifecycleManager.SetTrustedDependencies([]string{"a"})
+
+			require.NoError(t, pm.InstallFromCache())
+
+			assert.DirExists(t, filepath.Join(tmpDir, "node_modules", "a"))
+			if tc.expectRun {
+				assert.FileExists(t, marker)
+			} else {
+				assert.NoFileExists(t, marker)
+			}
+		})
+	}
+}
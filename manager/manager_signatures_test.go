@@ -0,0 +1,218 @@
+package manager
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ernesto27/go-npm/manifest"
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/ernesto27/go-npm/signature"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchToCacheVerifySignatures(t *testing.T) {
+	const integrity = "sha512-dGVzdA=="
+
+	registryKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&registryKey.PublicKey)
+	require.NoError(t, err)
+
+	sign := func(message string) string {
+		digest := sha256.Sum256([]byte(message))
+		sig, err := ecdsa.SignASN1(rand.Reader, registryKey, digest[:])
+		require.NoError(t, err)
+		return base64.StdEncoding.EncodeToString(sig)
+	}
+
+	testCases := []struct {
+		name             string
+		signedMessage    string
+		verifySignatures bool
+		expectError      bool
+	}{
+		{
+			name:             "valid signature installs",
+			signedMessage:    "signed-pkg@1.0.0:" + integrity,
+			verifySignatures: true,
+		},
+		{
+			name:             "invalid signature fails the install",
+			signedMessage:    "signed-pkg@1.0.0:sha512-dGFtcGVyZWQ=",
+			verifySignatures: true,
+			expectError:      true,
+		},
+		{
+			name:          "signatures are not checked without the flag",
+			signedMessage: "signed-pkg@1.0.0:sha512-dGFtcGVyZWQ=",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pm, _, origDir := setupTestPackageManager(t)
+			defer os.Chdir(origDir)
+
+			sig := sign(tc.signedMessage)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/-/npm/v1/keys" {
+					fmt.Fprintf(w, `{"keys": [{"keyid": "SHA256:test", "keytype": "ecdsa-sha2-nistp256", "scheme": "ecdsa-sha2-nistp256", "key": %q, "expires": null}]}`,
+						base64.StdEncoding.EncodeToString(der))
+					return
+				}
+				name := strings.TrimPrefix(r.URL.Path, "/")
+				fmt.Fprintf(w, `{"name": %q, "dist-tags": {"latest": "1.0.0"}, "versions": {"1.0.0": {"name": %q, "version": "1.0.0", "dist": {"integrity": %q, "signatures": [{"keyid": "SHA256:test", "sig": %q}]}}}}`,
+					name, name, integrity, sig)
+			}))
+			defer server.Close()
+
+			m, err := manifest.NewManifest(t.TempDir(), server.URL+"/")
+			require.NoError(t, err)
+			m.SetRetries(0)
+			pm.manifest = m
+			pm.config.VerifySignatures = tc.verifySignatures
+
+			writeCachedPackage(t, pm, "signed-pkg", "1.0.0", `{"name": "signed-pkg", "version": "1.0.0"}`)
+
+			err = pm.fetchToCache(packagejson.PackageJSON{
+				Dependencies: map[string]string{"signed-pkg": "^1.0.0"},
+			})
+
+			if tc.expectError {
+				assert.Error(t, err)
+				assert.ErrorIs(t, err, signature.ErrInvalidSignature)
+				assert.Contains(t, err.Error(), "signature verification failed for signed-pkg@1.0.0")
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Contains(t, pm.packageLock.Packages, "node_modules/signed-pkg")
+		})
+	}
+}
+
+func TestInstallFromCacheVerifiesLockSignatures(t *testing.T) {
+	const integrity = "sha512-dGVzdA=="
+
+	// Each registry signs with its own key, so a package verified against
+	// the keys of another registry fails
+	newRegistry := func(keyRequests *atomic.Int32) *httptest.Server {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+		require.NoError(t, err)
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/-/npm/v1/keys" {
+				keyRequests.Add(1)
+				fmt.Fprintf(w, `{"keys": [{"keyid": "SHA256:test", "keytype": "ecdsa-sha2-nistp256", "scheme": "ecdsa-sha2-nistp256", "key": %q, "expires": null}]}`,
+					base64.StdEncoding.EncodeToString(der))
+				return
+			}
+			name := strings.TrimPrefix(r.URL.Path, "/")
+			signed := integrity
+			if name == "tampered" {
+				signed = "sha512-dGFtcGVyZWQ="
+			}
+			digest := sha256.Sum256([]byte(name + "@1.0.0:" + signed))
+			sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+			require.NoError(t, err)
+			fmt.Fprintf(w, `{"name": %q, "versions": {"1.0.0": {"name": %q, "version": "1.0.0", "dist": {"integrity": %q, "signatures": [{"keyid": "SHA256:test", "sig": %q}]}}}}`,
+				name, name, integrity, base64.StdEncoding.EncodeToString(sig))
+		}))
+		t.Cleanup(server.Close)
+		return server
+	}
+
+	var primaryKeys, scopeKeys atomic.Int32
+	primary := newRegistry(&primaryKeys)
+	scope := newRegistry(&scopeKeys)
+
+	testCases := []struct {
+		name             string
+		packages         []string
+		verifySignatures bool
+		expectError      bool
+		primaryKeys      int32
+		scopeKeys        int32
+	}{
+		{
+			name:             "locked packages are verified against the keys of their registry",
+			packages:         []string{"plain", "@corp/lib"},
+			verifySignatures: true,
+			primaryKeys:      1,
+			scopeKeys:        1,
+		},
+		{
+			name:             "an invalid signature fails an install from the lock",
+			packages:         []string{"tampered"},
+			verifySignatures: true,
+			expectError:      true,
+			primaryKeys:      1,
+		},
+		{
+			name:     "signatures are not checked without the flag",
+			packages: []string{"tampered"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			primaryKeys.Store(0)
+			scopeKeys.Store(0)
+			pm, _, origDir := setupTestPackageManager(t)
+			defer os.Chdir(origDir)
+
+			m, err := manifest.NewManifest(t.TempDir(), primary.URL+"/")
+			require.NoError(t, err)
+			m.SetRetries(0)
+			m.SetScopeRegistries(map[string]string{"@corp": scope.URL + "/"})
+			pm.manifest = m
+			pm.config.VerifySignatures = tc.verifySignatures
+
+			lock := &packagejson.PackageLock{
+				Dependencies: map[string]string{},
+				Packages:     map[string]packagejson.PackageItem{},
+			}
+			for _, name := range tc.packages {
+				registry := primary.URL + "/"
+				if strings.HasPrefix(name, "@corp/") {
+					registry = scope.URL + "/"
+				}
+				lock.Dependencies[name] = "^1.0.0"
+				lock.Packages["node_modules/"+name] = packagejson.PackageItem{
+					Version:   "1.0.0",
+					Resolved:  registryTarballURL(registry, name, "1.0.0"),
+					Integrity: integrity,
+				}
+				writeCachedPackage(t, pm, name, "1.0.0", fmt.Sprintf(`{"name": %q, "version": "1.0.0"}`, name))
+			}
+			pm.packageLock = lock
+
+			err = pm.InstallFromCache()
+
+			assert.Equal(t, tc.primaryKeys, primaryKeys.Load())
+			assert.Equal(t, tc.scopeKeys, scopeKeys.Load())
+			if tc.expectError {
+				assert.ErrorIs(t, err, signature.ErrInvalidSignature)
+				assert.Contains(t, err.Error(), "signature verification failed for tampered@1.0.0")
+				assert.NoDirExists(t, filepath.Join(pm.extractedPath, "tampered"))
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
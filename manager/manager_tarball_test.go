@@ -1,9 +1,28 @@
 package manager
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
 
+	"github.com/ernesto27/go-npm/config"
+	"github.com/ernesto27/go-npm/integrity"
+	"github.com/ernesto27/go-npm/manifest"
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/ernesto27/go-npm/tarball"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGenerateUniqueTarballNameRealWorldScenarios(t *testing.T) {
@@ -84,3 +103,473 @@ func TestGenerateUniqueTarballNameRealWorldScenarios(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildTarballURL(t *testing.T) {
+	testCases := []struct {
+		name        string
+		packageName string
+		version     string
+		expected    string
+	}{
+		{
+			name:        "unscoped package",
+			packageName: "express",
+			version:     "4.18.2",
+			expected:    npmRegistryURL + "express/-/express-4.18.2.tgz",
+		},
+		{
+			name:        "scoped package keeps the literal slash",
+			packageName: "@types/node",
+			version:     "20.0.0",
+			expected:    npmRegistryURL + "@types/node/-/node-20.0.0.tgz",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			url := buildTarballURL(tc.packageName, tc.version)
+			assert.Equal(t, tc.expected, url)
+			assert.NotContains(t, url, "%2f")
+		})
+	}
+}
+
+func TestIsTarballURL(t *testing.T) {
+	testCases := []struct {
+		spec     string
+		expected bool
+	}{
+		{spec: "https://example.com/pkg-1.2.3.tgz", expected: true},
+		{spec: "http://localhost:8080/files/pkg.tar.gz?token=abc", expected: true},
+		{spec: "^1.2.3", expected: false},
+		{spec: "file:../pkg", expected: false},
+		{spec: "github:owner/repo", expected: false},
+		{spec: "https://", expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.spec, func(t *testing.T) {
+			assert.Equal(t, tc.expected, isTarballURL(tc.spec))
+		})
+	}
+}
+
+// buildTestTarball returns a gzipped npm-style tarball with files under package/
+func buildTestTarball(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gzw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gzw)
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{
+			Name:     "package/" + name,
+			Mode:     0644,
+			Size:     int64(len(content)),
+			Typeflag: tar.TypeReg,
+		}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gzw.Close())
+	return buf.Bytes()
+}
+
+func TestFetchToCacheTarballURL(t *testing.T) {
+	tarballData := buildTestTarball(t, map[string]string{
+		"package.json": `{"name": "pkg", "version": "1.2.3", "dependencies": {"leaf": "^1.0.0"}}`,
+		"index.js":     "module.exports = 'pkg'",
+	})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/pkg-1.2.3.tgz" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(tarballData)
+	}))
+	defer server.Close()
+
+	testCases := []struct {
+		name         string
+		dependencies map[string]string
+		// registryCached caches the registry package pkg@1.2.3 first
+		registryCached bool
+		expectError    bool
+		validate       func(t *testing.T, pm *PackageManager)
+	}{
+		{
+			name:         "downloads the tarball and resolves its dependencies from the registry",
+			dependencies: map[string]string{"pkg": server.URL + "/pkg-1.2.3.tgz"},
+			validate: func(t *testing.T, pm *PackageManager) {
+				item := pm.packageLock.Packages["node_modules/pkg"]
+				assert.Equal(t, "1.2.3", item.Version)
+				assert.Equal(t, server.URL+"/pkg-1.2.3.tgz", item.Resolved)
+				assert.Equal(t, map[string]string{"leaf": "^1.0.0"}, item.Dependencies)
+				sum := sha512.Sum512(tarballData)
+				assert.Equal(t, "sha512-"+base64.StdEncoding.EncodeToString(sum[:]), item.Integrity)
+				assert.Equal(t, server.URL+"/pkg-1.2.3.tgz", pm.packageLock.Dependencies["pkg"])
+				assert.Equal(t, "1.0.0", pm.packageLock.Packages["node_modules/leaf"].Version)
+
+				assert.FileExists(t, filepath.Join(pm.packagesPath, "pkg@"+tarball.URLCacheVersion(server.URL+"/pkg-1.2.3.tgz"), "index.js"))
+				assert.NoDirExists(t, filepath.Join(pm.packagesPath, "pkg@1.2.3"))
+				matches, err := filepath.Glob(filepath.Join(pm.packagesPath, "pkg@url-*.tmp"))
+				require.NoError(t, err)
+				assert.Empty(t, matches, "staging directory should be removed")
+
+				require.NoError(t, pm.InstallFromCache())
+				assert.FileExists(t, filepath.Join(pm.extractedPath, "pkg", "index.js"))
+				assert.FileExists(t, filepath.Join(pm.extractedPath, "leaf", "package.json"))
+			},
+		},
+		{
+			name:           "is cached apart from the registry package of the same version",
+			dependencies:   map[string]string{"pkg": server.URL + "/pkg-1.2.3.tgz"},
+			registryCached: true,
+			validate: func(t *testing.T, pm *PackageManager) {
+				assert.Equal(t, "1.2.3", pm.packageLock.Packages["node_modules/pkg"].Version)
+
+				require.NoError(t, pm.InstallFromCache())
+				data, err := os.ReadFile(filepath.Join(pm.extractedPath, "pkg", "index.js"))
+				require.NoError(t, err)
+				assert.Equal(t, "module.exports = 'pkg'", string(data))
+
+				// The registry package keeps its own files
+				assert.NoFileExists(t, filepath.Join(pm.packagesPath, "pkg@1.2.3", "index.js"))
+				assert.FileExists(t, filepath.Join(pm.packagesPath, "pkg@1.2.3", "package.json"))
+			},
+		},
+		{
+			name:         "missing tarball fails the install",
+			dependencies: map[string]string{"pkg": server.URL + "/missing.tgz"},
+			expectError:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pm, _, origDir := setupTestPackageManager(t)
+			defer os.Chdir(origDir)
+
+			setupTestRegistry(t, pm, map[string]map[string]map[string]string{"leaf": {"1.0.0": nil}})
+			if tc.registryCached {
+				writeCachedPackage(t, pm, "pkg", "1.2.3", `{"name": "pkg", "version": "1.2.3"}`)
+			}
+
+			err := pm.fetchToCache(packagejson.PackageJSON{Dependencies: tc.dependencies})
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			tc.validate(t, pm)
+		})
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// serveRegistry sends requests for the npm registry to server for the rest of the test
+func serveRegistry(t *testing.T, server *httptest.Server) {
+	t.Helper()
+
+	registry, err := url.Parse(npmRegistryURL)
+	require.NoError(t, err)
+	target, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	transport := http.DefaultTransport
+	http.DefaultTransport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Host == registry.Host {
+			r = r.Clone(r.Context())
+			r.URL.Scheme = target.Scheme
+			r.URL.Host = target.Host
+		}
+		return transport.RoundTrip(r)
+	})
+	t.Cleanup(func() { http.DefaultTransport = transport })
+}
+
+func TestFetchToCacheFallsBackFromGoneTarball(t *testing.T) {
+	tarballs := map[string][]byte{}
+	integrities := map[string]string{}
+	for _, v := range []string{"1.0.0", "1.1.0", "1.2.0"} {
+		tarballs[v] = buildTestTarball(t, map[string]string{"package.json": fmt.Sprintf(`{"name": "leaf", "version": %q}`, v)})
+		tarballFile := filepath.Join(t.TempDir(), "leaf.tgz")
+		require.NoError(t, os.WriteFile(tarballFile, tarballs[v], 0644))
+		hash, err := integrity.ComputeHash(tarballFile, "sha512")
+		require.NoError(t, err)
+		integrities[v] = "sha512-" + hash
+	}
+
+	// The tarballs of 1.2.0 and 1.1.0 were removed after they were published
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/leaf":
+			var entries []string
+			for _, v := range []string{"1.0.0", "1.1.0", "1.2.0"} {
+				entries = append(entries, fmt.Sprintf(`%q: {"name": "leaf", "version": %q, "dist": {"integrity": %q}}`, v, v, integrities[v]))
+			}
+			fmt.Fprintf(w, `{"name": "leaf", "dist-tags": {"latest": "1.2.0"}, "versions": {%s}}`, strings.Join(entries, ","))
+		case "/leaf/-/leaf-1.0.0.tgz":
+			w.Write(tarballs["1.0.0"])
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	serveRegistry(t, server)
+
+	testCases := []struct {
+		name         string
+		dependencies map[string]string
+		expectError  bool
+		expected     string
+	}{
+		{
+			name:         "installs the next highest version satisfying the range",
+			dependencies: map[string]string{"leaf": "^1.0.0"},
+			expected:     "1.0.0",
+		},
+		{
+			name:         "fails when no other version satisfies the range",
+			dependencies: map[string]string{"leaf": "~1.2.0"},
+			expectError:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pm, _, origDir := setupTestPackageManager(t)
+			defer os.Chdir(origDir)
+
+			m, err := manifest.NewManifest(t.TempDir(), server.URL+"/")
+			require.NoError(t, err)
+			m.SetRetries(0)
+			pm.manifest = m
+			pm.tarball.SetRetries(0)
+
+			err = pm.fetchToCache(packagejson.PackageJSON{Dependencies: tc.dependencies})
+			if tc.expectError {
+				assert.ErrorIs(t, err, tarball.ErrNotFound)
+				return
+			}
+			require.NoError(t, err)
+
+			leaf := pm.packageLock.Packages["node_modules/leaf"]
+			assert.Equal(t, tc.expected, leaf.Version)
+			assert.Equal(t, integrities[tc.expected], leaf.Integrity)
+			assert.Equal(t, tc.dependencies["leaf"], pm.packageLock.Dependencies["leaf"])
+		})
+	}
+}
+
+func TestFetchToCacheComputesIntegrity(t *testing.T) {
+	pm, _, origDir := setupTestPackageManager(t)
+	defer os.Chdir(origDir)
+
+	// The test registry publishes no dist.integrity, so it comes from the tarball
+	setupTestRegistry(t, pm, map[string]map[string]map[string]string{"leaf": {"1.0.0": nil}})
+	tarballPath := filepath.Join(pm.tarball.TarballPath, generateUniqueTarballName("leaf", "1.0.0"))
+	require.NoError(t, os.MkdirAll(filepath.Dir(tarballPath), 0755))
+	require.NoError(t, os.WriteFile(tarballPath, buildTestTarball(t, map[string]string{"package.json": `{"name": "leaf", "version": "1.0.0"}`}), 0644))
+
+	require.NoError(t, pm.fetchToCache(packagejson.PackageJSON{Dependencies: map[string]string{"leaf": "^1.0.0"}}))
+
+	sri := pm.packageLock.Packages["node_modules/leaf"].Integrity
+	assert.True(t, strings.HasPrefix(sri, "sha512-"), "integrity %q should be sha512 SRI", sri)
+	assert.NoError(t, integrity.New().ValidateFileStrict(tarballPath, sri))
+}
+
+func TestFetchToCacheRegistryFallback(t *testing.T) {
+	pm, _, origDir := setupTestPackageManager(t)
+	defer os.Chdir(origDir)
+
+	tarballData := buildTestTarball(t, map[string]string{"package.json": `{"name": "leaf", "version": "1.0.0"}`})
+	tarballFile := filepath.Join(t.TempDir(), "leaf.tgz")
+	require.NoError(t, os.WriteFile(tarballFile, tarballData, 0644))
+	sri, err := integrity.ComputeSRI(tarballFile, "sha512")
+	require.NoError(t, err)
+
+	// The primary registry is down, so the fallback serves both the
+	// manifest and the tarball
+	down := httptest.NewServer(http.NotFoundHandler())
+	downURL := down.URL + "/"
+	down.Close()
+
+	fallback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/leaf":
+			fmt.Fprintf(w, `{"name": "leaf", "dist-tags": {"latest": "1.0.0"}, "versions": {"1.0.0": {"name": "leaf", "version": "1.0.0", "dist": {"integrity": %q}}}}`, sri)
+		case "/leaf/-/leaf-1.0.0.tgz":
+			w.Write(tarballData)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer fallback.Close()
+
+	m, err := manifest.NewManifest(t.TempDir(), downURL)
+	require.NoError(t, err)
+	m.SetRetries(0)
+	m.SetFallbackRegistries([]string{fallback.URL + "/"})
+	pm.manifest = m
+	pm.tarball.SetRetries(0)
+	pm.tarball.SetRegistries([]string{downURL, fallback.URL + "/"})
+
+	require.NoError(t, pm.fetchToCache(packagejson.PackageJSON{Dependencies: map[string]string{"leaf": "^1.0.0"}}))
+
+	leaf := pm.packageLock.Packages["node_modules/leaf"]
+	assert.Equal(t, fallback.URL+"/leaf/-/leaf-1.0.0.tgz", leaf.Resolved)
+	assert.Equal(t, sri, leaf.Integrity)
+}
+
+func TestInstallFromCacheKeepsExecutableBits(t *testing.T) {
+	var buf bytes.Buffer
+	gzw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gzw)
+	for _, entry := range []struct {
+		name     string
+		mode     int64
+		content  string
+		linkname string
+	}{
+		{name: "package.json", mode: 0644, content: `{"name": "cli", "version": "1.0.0", "bin": {"cli": "bin/cli.js"}}`},
+		{name: "bin/cli.js", mode: 0755, content: "#!/usr/bin/env node\n"},
+		{name: "scripts/build.sh", mode: 0700, content: "#!/bin/sh\n"},
+		{name: "index.js", mode: 0, content: "module.exports = 'cli'"},
+		{name: "main.js", linkname: "index.js"},
+	} {
+		header := &tar.Header{Name: "package/" + entry.name, Mode: entry.mode, Size: int64(len(entry.content)), Typeflag: tar.TypeReg}
+		if entry.linkname != "" {
+			header = &tar.Header{Name: "package/" + entry.name, Mode: 0777, Typeflag: tar.TypeSymlink, Linkname: entry.linkname}
+		}
+		require.NoError(t, tw.WriteHeader(header))
+		_, err := tw.Write([]byte(entry.content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gzw.Close())
+	tarballData := buf.Bytes()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(tarballData)
+	}))
+	defer server.Close()
+
+	for _, strategy := range []string{config.InstallStrategyHardlink, config.InstallStrategyCopy} {
+		t.Run(strategy, func(t *testing.T) {
+			pm, _, origDir := setupTestPackageManager(t)
+			defer os.Chdir(origDir)
+			pm.config.InstallStrategy = strategy
+
+			require.NoError(t, pm.fetchToCache(packagejson.PackageJSON{
+				Dependencies: map[string]string{"cli": server.URL + "/cli-1.0.0.tgz"},
+			}))
+			require.NoError(t, pm.InstallFromCache())
+
+			installed := filepath.Join(pm.extractedPath, "cli")
+			modes := map[string]os.FileMode{
+				"bin/cli.js":       0755,
+				"scripts/build.sh": 0744,
+				"index.js":         0644,
+			}
+			for name, expected := range modes {
+				info, err := os.Stat(filepath.Join(installed, name))
+				require.NoError(t, err)
+				assert.Equal(t, expected, info.Mode().Perm(), name)
+			}
+
+			link, err := os.Readlink(filepath.Join(installed, "main.js"))
+			require.NoError(t, err, "main.js should stay a symlink")
+			assert.Equal(t, "index.js", link)
+
+			info, err := os.Stat(filepath.Join(pm.extractedPath, ".bin", "cli"))
+			require.NoError(t, err)
+			assert.NotZero(t, info.Mode().Perm()&0100, "the linked CLI should be executable")
+		})
+	}
+}
+
+func TestInstallFromCacheValidatesRegistryTarballs(t *testing.T) {
+	genuine := buildTestTarball(t, map[string]string{"package.json": `{"name": "@corp/lib", "version": "1.0.0"}`, "index.js": "module.exports = 'corp'"})
+	impostor := buildTestTarball(t, map[string]string{"package.json": `{"name": "@corp/lib", "version": "1.0.0"}`, "index.js": "module.exports = 'public'"})
+	sum := sha512.Sum512(genuine)
+	sri := "sha512-" + base64.StdEncoding.EncodeToString(sum[:])
+
+	scopeRegistry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/@corp/lib/-/lib-1.0.0.tgz" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(genuine)
+	}))
+	defer scopeRegistry.Close()
+
+	var publicRequests atomic.Int32
+	publicRegistry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		publicRequests.Add(1)
+		w.Write(impostor)
+	}))
+	defer publicRegistry.Close()
+
+	testCases := []struct {
+		name           string
+		scopes         map[string]string
+		integrity      string
+		errorContains  string
+		publicRequests int32
+	}{
+		{
+			name:      "a scoped package is downloaded from its scope registry whatever the lock says",
+			scopes:    map[string]string{"@corp": scopeRegistry.URL + "/"},
+			integrity: sri,
+		},
+		{
+			name:           "a tarball not matching the locked integrity fails the install",
+			integrity:      sri,
+			errorContains:  "SECURITY: integrity check failed for @corp/lib@1.0.0",
+			publicRequests: 1,
+		},
+		{
+			name:   "a registry package without a locked integrity is installed unverified",
+			scopes: map[string]string{"@corp": scopeRegistry.URL + "/"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			publicRequests.Store(0)
+			pm, _, origDir := setupTestPackageManager(t)
+			defer os.Chdir(origDir)
+			pm.manifest.SetScopeRegistries(tc.scopes)
+			pm.tarball.SetRetries(0)
+
+			// The lock was written with --omit-lockfile-registry
+			pm.packageLock = &packagejson.PackageLock{
+				Dependencies: map[string]string{"@corp/lib": "^1.0.0"},
+				Packages: map[string]packagejson.PackageItem{
+					"node_modules/@corp/lib": {Version: "1.0.0", Resolved: publicRegistry.URL + "/@corp/lib/-/lib-1.0.0.tgz", Integrity: tc.integrity},
+				},
+			}
+
+			err := pm.InstallFromCache()
+			assert.Equal(t, tc.publicRequests, publicRequests.Load())
+			if tc.errorContains != "" {
+				assert.ErrorContains(t, err, tc.errorContains)
+				assert.NoFileExists(t, filepath.Join(pm.extractedPath, "@corp", "lib", "index.js"))
+				return
+			}
+			require.NoError(t, err)
+
+			data, err := os.ReadFile(filepath.Join(pm.extractedPath, "@corp", "lib", "index.js"))
+			require.NoError(t, err)
+			assert.Equal(t, "module.exports = 'corp'", string(data))
+		})
+	}
+}
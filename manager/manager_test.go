@@ -1,8 +1,14 @@
 package manager
 
 import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/ernesto27/go-npm/binlink"
@@ -16,6 +22,7 @@ import (
 	"github.com/ernesto27/go-npm/progress"
 	"github.com/ernesto27/go-npm/scripts"
 	"github.com/ernesto27/go-npm/tarball"
+	"github.com/ernesto27/go-npm/types"
 	"github.com/ernesto27/go-npm/utils"
 	"github.com/ernesto27/go-npm/version"
 	"github.com/ernesto27/go-npm/yarnlock"
@@ -405,6 +412,7 @@ func TestInstallGlobal(t *testing.T) {
 
 				// Override HOME environment variable to use temp directory
 				// This prevents addBinToPath() from modifying user's actual ~/.bashrc
+				t.Setenv("SHELL", "/bin/bash")
 				originalHome := os.Getenv("HOME")
 				err := os.Setenv("HOME", tmpDir)
 				assert.NoError(t, err)
@@ -483,6 +491,186 @@ func TestInstallGlobal(t *testing.T) {
 	}
 }
 
+func TestLinkGlobal(t *testing.T) {
+	testCases := []struct {
+		name        string
+		packageJSON string
+		global      bool
+		expectError bool
+		validate    func(t *testing.T, pm *PackageManager, projectDir string)
+	}{
+		{
+			name: "links project bin globally",
+			packageJSON: `{
+  "name": "my-cli",
+  "version": "0.1.0",
+  "bin": {"my-cli": "bin/cli.js"}
+}`,
+			global:      true,
+			expectError: false,
+			validate: func(t *testing.T, pm *PackageManager, projectDir string) {
+				shim := filepath.Join(pm.config.GlobalBinDir, "my-cli")
+				target, err := os.Readlink(shim)
+				assert.NoError(t, err)
+				assert.Equal(t, filepath.Join(projectDir, "bin", "cli.js"), target)
+
+				link := filepath.Join(pm.config.GlobalNodeModules, "my-cli")
+				linkTarget, err := filepath.EvalSymlinks(link)
+				assert.NoError(t, err)
+				expectedTarget, err := filepath.EvalSymlinks(projectDir)
+				assert.NoError(t, err)
+				assert.Equal(t, expectedTarget, linkTarget)
+
+				item := pm.packageLock.Packages["node_modules/my-cli"]
+				assert.True(t, item.Link)
+				assert.Equal(t, "0.1.0", item.Version)
+				assert.Equal(t, "file:"+projectDir, pm.packageLock.Dependencies["my-cli"])
+			},
+		},
+		{
+			name: "returns error when project declares no bin",
+			packageJSON: `{
+  "name": "my-lib",
+  "version": "0.1.0"
+}`,
+			global:      true,
+			expectError: true,
+		},
+		{
+			name: "returns error when not in global mode",
+			packageJSON: `{
+  "name": "my-cli",
+  "bin": "bin/cli.js"
+}`,
+			global:      false,
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pm, tmpDir, origDir := setupTestPackageManager(t)
+			defer os.Chdir(origDir)
+
+			originalHome := os.Getenv("HOME")
+			os.Setenv("HOME", tmpDir)
+			t.Cleanup(func() {
+				os.Setenv("HOME", originalHome)
+			})
+
+			projectDir := filepath.Join(tmpDir, "my-cli")
+			assert.NoError(t, os.MkdirAll(filepath.Join(projectDir, "bin"), 0755))
+			assert.NoError(t, os.WriteFile(filepath.Join(projectDir, "package.json"), []byte(tc.packageJSON), 0644))
+			assert.NoError(t, os.WriteFile(filepath.Join(projectDir, "bin", "cli.js"), []byte("#!/usr/bin/env node\n"), 0644))
+
+			if tc.global {
+				assert.NoError(t, pm.SetupGlobal())
+			}
+
+			err := pm.LinkGlobal(projectDir)
+
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			if tc.validate != nil {
+				tc.validate(t, pm, projectDir)
+			}
+		})
+	}
+}
+
+func TestLinkPackage(t *testing.T) {
+	testCases := []struct {
+		name        string
+		register    bool
+		pkgName     string
+		expectError bool
+	}{
+		{
+			name:        "consumer links a globally registered package",
+			register:    true,
+			pkgName:     "@me/my-lib",
+			expectError: false,
+		},
+		{
+			name:        "returns error when package was never linked",
+			register:    false,
+			pkgName:     "@me/my-lib",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pm, tmpDir, origDir := setupTestPackageManager(t)
+			defer os.Chdir(origDir)
+
+			pkgDir := filepath.Join(tmpDir, "my-lib")
+			assert.NoError(t, os.MkdirAll(filepath.Join(pkgDir, "bin"), 0755))
+			assert.NoError(t, os.WriteFile(filepath.Join(pkgDir, "package.json"), []byte(`{"name": "@me/my-lib", "version": "1.2.0", "bin": {"my-lib": "bin/cli.js"}}`), 0644))
+			assert.NoError(t, os.WriteFile(filepath.Join(pkgDir, "bin", "cli.js"), []byte("#!/usr/bin/env node\n"), 0644))
+
+			if tc.register {
+				originalHome := os.Getenv("HOME")
+				os.Setenv("HOME", tmpDir)
+				t.Cleanup(func() {
+					os.Setenv("HOME", originalHome)
+				})
+
+				assert.NoError(t, pm.SetupGlobal())
+				assert.NoError(t, pm.Link(pkgDir))
+			}
+
+			// Consume the link from a separate project
+			consumerDir := filepath.Join(tmpDir, "consumer")
+			assert.NoError(t, os.MkdirAll(consumerDir, 0755))
+			assert.NoError(t, os.Chdir(consumerDir))
+
+			consumer, err := New(createMockDependencies(t, tmpDir))
+			assert.NoError(t, err)
+
+			err = consumer.LinkPackage(tc.pkgName)
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+
+			linkPath := filepath.Join(consumerDir, "node_modules", "@me", "my-lib")
+			info, err := os.Lstat(linkPath)
+			assert.NoError(t, err)
+			assert.True(t, info.Mode()&os.ModeSymlink != 0, "node_modules entry should be a symlink")
+
+			resolved, err := filepath.EvalSymlinks(linkPath)
+			assert.NoError(t, err)
+			expected, err := filepath.EvalSymlinks(pkgDir)
+			assert.NoError(t, err)
+			assert.Equal(t, expected, resolved)
+
+			assert.FileExists(t, filepath.Join(consumerDir, "node_modules", ".bin", "my-lib"))
+
+			// Unlinking removes the symlink and bin but leaves the package itself
+			assert.NoError(t, consumer.UnlinkPackage(tc.pkgName))
+			_, err = os.Lstat(linkPath)
+			assert.True(t, os.IsNotExist(err))
+			_, err = os.Lstat(filepath.Join(consumerDir, "node_modules", ".bin", "my-lib"))
+			assert.True(t, os.IsNotExist(err))
+			assert.FileExists(t, filepath.Join(pkgDir, "package.json"))
+
+			// Unregistering the package removes it from the global store
+			assert.NoError(t, pm.UnlinkGlobal(pkgDir))
+			_, err = os.Lstat(filepath.Join(pm.config.GlobalNodeModules, "@me", "my-lib"))
+			assert.True(t, os.IsNotExist(err))
+			_, err = os.Lstat(filepath.Join(pm.config.GlobalBinDir, "my-lib"))
+			assert.True(t, os.IsNotExist(err))
+			assert.FileExists(t, filepath.Join(pkgDir, "package.json"))
+		})
+	}
+}
+
 func TestParseAliasVersion(t *testing.T) {
 	testCases := []struct {
 		name            string
@@ -742,7 +930,7 @@ func TestFetchToCache(t *testing.T) {
 				}
 			}()
 
-			err := pm.fetchToCache(tc.packageJSON, false)
+			err := pm.fetchToCache(tc.packageJSON)
 
 			if tc.expectError {
 				assert.Error(t, err)
@@ -756,6 +944,149 @@ func TestFetchToCache(t *testing.T) {
 	}
 }
 
+func TestFetchToCacheWithOverrides(t *testing.T) {
+	registry := map[string]map[string]map[string]string{
+		"foo":    {"1.0.0": {"bar": "^1.0.0", "lodash": "^4.0.0"}},
+		"qux":    {"1.0.0": {"bar": "^1.0.0"}},
+		"bar":    {"1.0.0": nil, "1.5.0": nil, "2.0.0": nil},
+		"lodash": {"4.17.20": nil, "4.17.21": nil},
+	}
+
+	testCases := []struct {
+		name      string
+		overrides string
+		validate  func(t *testing.T, lock *packagejson.PackageLock)
+	}{
+		{
+			name:      "global override pins every transitive copy",
+			overrides: `{"lodash": "4.17.20"}`,
+			validate: func(t *testing.T, lock *packagejson.PackageLock) {
+				assert.Equal(t, "4.17.20", lock.Packages["node_modules/lodash"].Version)
+				assert.Equal(t, map[string]string{"lodash": "4.17.20"}, lock.Overrides)
+			},
+		},
+		{
+			name:      "nested override applies only under the matching parent",
+			overrides: `{"foo>bar": "2.0.0"}`,
+			validate: func(t *testing.T, lock *packagejson.PackageLock) {
+				assert.Equal(t, "2.0.0", lockVersionSeenBy(lock, "node_modules/foo", "bar"))
+				assert.Equal(t, "1.5.0", lockVersionSeenBy(lock, "node_modules/qux", "bar"))
+				assert.Equal(t, "4.17.21", lock.Packages["node_modules/lodash"].Version)
+				assert.Equal(t, map[string]string{"foo>bar": "2.0.0"}, lock.Overrides)
+			},
+		},
+		{
+			name:      "object form with version reference",
+			overrides: `{"qux": {"bar": "1.0.0"}, "lodash": "$lodash"}`,
+			validate: func(t *testing.T, lock *packagejson.PackageLock) {
+				assert.Equal(t, "1.0.0", lockVersionSeenBy(lock, "node_modules/qux", "bar"))
+				assert.Contains(t, []string{"1.0.0", "1.5.0"}, lockVersionSeenBy(lock, "node_modules/foo", "bar"))
+				assert.Equal(t, "4.17.20", lock.Packages["node_modules/lodash"].Version)
+				assert.Equal(t, "4.17.20", lock.Overrides["lodash"])
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pm, _, origDir := setupTestPackageManager(t)
+			defer os.Chdir(origDir)
+
+			setupTestRegistry(t, pm, registry)
+
+			var pkgJSON packagejson.PackageJSON
+			data := fmt.Sprintf(`{"dependencies": {"foo": "^1.0.0", "qux": "^1.0.0"}, "devDependencies": {"lodash": "4.17.20"}, "overrides": %s}`, tc.overrides)
+			assert.NoError(t, json.Unmarshal([]byte(data), &pkgJSON))
+
+			// Production mode skips devDependencies, which only feed the "$lodash" reference
+			pm.config.Omit.Dev = true
+			err := pm.fetchToCache(pkgJSON)
+			assert.NoError(t, err)
+
+			tc.validate(t, pm.packageLock)
+		})
+	}
+}
+
+func TestFetchToCacheWithResolutions(t *testing.T) {
+	registry := map[string]map[string]map[string]string{
+		"foo":    {"1.0.0": {"bar": "^1.0.0", "lodash": "^4.0.0"}},
+		"qux":    {"1.0.0": {"bar": "^1.0.0"}},
+		"bar":    {"1.0.0": nil, "1.5.0": nil, "2.0.0": nil},
+		"lodash": {"4.17.20": nil, "4.17.21": nil},
+	}
+
+	testCases := []struct {
+		name        string
+		resolutions string
+		overrides   string
+		validate    func(t *testing.T, lock *packagejson.PackageLock)
+	}{
+		{
+			name:        "globstar resolution pins every transitive copy",
+			resolutions: `{"**/lodash": "4.17.20"}`,
+			validate: func(t *testing.T, lock *packagejson.PackageLock) {
+				assert.Equal(t, "4.17.20", lock.Packages["node_modules/lodash"].Version)
+				assert.Equal(t, map[string]string{"**/lodash": "4.17.20"}, lock.Resolutions)
+			},
+		},
+		{
+			name:        "path resolution applies only under the matching parent",
+			resolutions: `{"foo/**/bar": "2.0.0"}`,
+			validate: func(t *testing.T, lock *packagejson.PackageLock) {
+				assert.Equal(t, "2.0.0", lockVersionSeenBy(lock, "node_modules/foo", "bar"))
+				assert.Equal(t, "1.5.0", lockVersionSeenBy(lock, "node_modules/qux", "bar"))
+			},
+		},
+		{
+			name:        "overrides win over resolutions",
+			resolutions: `{"**/bar": "1.0.0"}`,
+			overrides:   `{"foo>bar": "2.0.0"}`,
+			validate: func(t *testing.T, lock *packagejson.PackageLock) {
+				assert.Equal(t, "2.0.0", lockVersionSeenBy(lock, "node_modules/foo", "bar"))
+				assert.Equal(t, "1.0.0", lockVersionSeenBy(lock, "node_modules/qux", "bar"))
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pm, _, origDir := setupTestPackageManager(t)
+			defer os.Chdir(origDir)
+
+			setupTestRegistry(t, pm, registry)
+
+			overrides := tc.overrides
+			if overrides == "" {
+				overrides = "{}"
+			}
+
+			var pkgJSON packagejson.PackageJSON
+			data := fmt.Sprintf(`{"dependencies": {"foo": "^1.0.0", "qux": "^1.0.0"}, "resolutions": %s, "overrides": %s}`, tc.resolutions, overrides)
+			assert.NoError(t, json.Unmarshal([]byte(data), &pkgJSON))
+
+			err := pm.fetchToCache(pkgJSON)
+			assert.NoError(t, err)
+
+			tc.validate(t, pm.packageLock)
+		})
+	}
+}
+
+// lockVersionSeenBy returns the version of name that node would load from parentPath
+func lockVersionSeenBy(lock *packagejson.PackageLock, parentPath, name string) string {
+	for dir := parentPath; ; {
+		if item, ok := lock.Packages[dir+"/node_modules/"+name]; ok {
+			return item.Version
+		}
+		idx := strings.LastIndex(dir, "/node_modules/")
+		if idx == -1 {
+			return lock.Packages["node_modules/"+name].Version
+		}
+		dir = dir[:idx]
+	}
+}
+
 func TestInstallFromCache(t *testing.T) {
 	testCases := []struct {
 		name        string
@@ -775,7 +1106,7 @@ func TestInstallFromCache(t *testing.T) {
 						"is-odd": "3.0.1",
 					},
 				}
-				err := pm.fetchToCache(packageJSON, false)
+				err := pm.fetchToCache(packageJSON)
 				assert.NoError(t, err)
 
 				// Verify package is in cache but not in node_modules yet
@@ -814,7 +1145,7 @@ func TestInstallFromCache(t *testing.T) {
 						"is-even": "1.0.0",
 					},
 				}
-				err := pm.fetchToCache(packageJSON, false)
+				err := pm.fetchToCache(packageJSON)
 				assert.NoError(t, err)
 
 				return pm, origDir
@@ -846,7 +1177,7 @@ func TestInstallFromCache(t *testing.T) {
 						"is-odd": "3.0.1",
 					},
 				}
-				err := pm.fetchToCache(packageJSON, false)
+				err := pm.fetchToCache(packageJSON)
 				assert.NoError(t, err)
 
 				// Install once
@@ -1124,6 +1455,7 @@ func TestAdd(t *testing.T) {
 		setupFunc   func(t *testing.T) (*PackageManager, string)
 		pkgName     string
 		version     string
+		kind        packagejson.DependencyKind
 		isInstall   bool
 		expectError bool
 		validate    func(t *testing.T, pm *PackageManager, tmpDir string)
@@ -1351,7 +1683,7 @@ func TestAdd(t *testing.T) {
 			cwd, err := os.Getwd()
 			assert.NoError(t, err)
 
-			err = pm.Add(tc.pkgName, tc.version, tc.isInstall)
+			err = pm.Add(tc.pkgName, tc.version, tc.kind, tc.isInstall)
 
 			if tc.expectError {
 				assert.Error(t, err)
@@ -1365,6 +1697,208 @@ func TestAdd(t *testing.T) {
 	}
 }
 
+func TestAddDependencyKind(t *testing.T) {
+	testCases := []struct {
+		name     string
+		kind     packagejson.DependencyKind
+		validate func(t *testing.T, pm *PackageManager, pkgJSON map[string]map[string]string)
+	}{
+		{
+			name: "save-dev writes to devDependencies",
+			kind: packagejson.DependencyDev,
+			validate: func(t *testing.T, pm *PackageManager, pkgJSON map[string]map[string]string) {
+				assert.Equal(t, "^1.0.0", pkgJSON["devDependencies"]["jest"])
+				assert.NotContains(t, pkgJSON["dependencies"], "jest")
+				assert.Contains(t, pm.packageLock.DevDependencies, "jest")
+				assert.NotContains(t, pm.packageLock.Dependencies, "jest")
+			},
+		},
+		{
+			name: "save-optional writes to optionalDependencies",
+			kind: packagejson.DependencyOptional,
+			validate: func(t *testing.T, pm *PackageManager, pkgJSON map[string]map[string]string) {
+				assert.Equal(t, "^1.0.0", pkgJSON["optionalDependencies"]["jest"])
+				assert.NotContains(t, pkgJSON["dependencies"], "jest")
+				assert.Contains(t, pm.packageLock.OptionalDependencies, "jest")
+			},
+		},
+		{
+			name: "save-peer writes to peerDependencies",
+			kind: packagejson.DependencyPeer,
+			validate: func(t *testing.T, pm *PackageManager, pkgJSON map[string]map[string]string) {
+				assert.Equal(t, "^1.0.0", pkgJSON["peerDependencies"]["jest"])
+				assert.NotContains(t, pkgJSON["dependencies"], "jest")
+				assert.Contains(t, pm.packageLock.PeerDependencies, "jest")
+				assert.NotContains(t, pm.packageLock.Dependencies, "jest")
+			},
+		},
+		{
+			name: "default kind writes to dependencies",
+			kind: packagejson.DependencyProd,
+			validate: func(t *testing.T, pm *PackageManager, pkgJSON map[string]map[string]string) {
+				assert.Equal(t, "^1.0.0", pkgJSON["dependencies"]["jest"])
+				assert.Contains(t, pm.packageLock.Dependencies, "jest")
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pm, tmpDir, origDir := setupTestPackageManager(t)
+			defer os.Chdir(origDir)
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				name := strings.TrimPrefix(r.URL.Path, "/")
+				fmt.Fprintf(w, `{"name": %q, "dist-tags": {"latest": "1.0.0"}, "versions": {"1.0.0": {"name": %q, "version": "1.0.0"}}}`, name, name)
+			}))
+			defer server.Close()
+
+			m, err := manifest.NewManifest(t.TempDir(), server.URL+"/")
+			assert.NoError(t, err)
+			pm.manifest = m
+
+			writeCachedPackage(t, pm, "jest", "1.0.0", `{"name": "jest", "version": "1.0.0"}`)
+
+			packageJSONContent := `{
+  "name": "test-project",
+  "version": "1.0.0",
+  "dependencies": {
+    "lodash": "^4.17.21"
+  }
+}`
+			assert.NoError(t, os.WriteFile(filepath.Join(tmpDir, "package.json"), []byte(packageJSONContent), 0644))
+
+			lockContent := `{
+  "name": "test-project",
+  "version": "1.0.0",
+  "lockfileVersion": 3,
+  "requires": true,
+  "packages": {},
+  "dependencies": {}
+}`
+			assert.NoError(t, os.WriteFile(filepath.Join(tmpDir, packagejson.LOCK_FILE_NAME_GO_NPM), []byte(lockContent), 0644))
+
+			_, err = pm.packageJsonParse.ParseDefault()
+			assert.NoError(t, err)
+
+			err = pm.Add("jest", "^1.0.0", tc.kind, true)
+			assert.NoError(t, err)
+
+			data, err := os.ReadFile(filepath.Join(tmpDir, "package.json"))
+			assert.NoError(t, err)
+
+			raw := map[string]json.RawMessage{}
+			assert.NoError(t, json.Unmarshal(data, &raw), "package.json should stay valid JSON")
+			pkgJSON := make(map[string]map[string]string)
+			for _, section := range []string{"dependencies", "devDependencies", "optionalDependencies", "peerDependencies"} {
+				deps := map[string]string{}
+				if value, ok := raw[section]; ok {
+					assert.NoError(t, json.Unmarshal(value, &deps))
+				}
+				pkgJSON[section] = deps
+			}
+			assert.Equal(t, "^4.17.21", pkgJSON["dependencies"]["lodash"])
+
+			tc.validate(t, pm, pkgJSON)
+		})
+	}
+}
+
+func TestAddDistTag(t *testing.T) {
+	testCases := []struct {
+		name         string
+		version      string
+		kind         packagejson.DependencyKind
+		expectedSpec string
+		expectedLock string
+	}{
+		{name: "next tag", version: "next", kind: packagejson.DependencyProd, expectedSpec: "^19.0.0-rc.1", expectedLock: "19.0.0-rc.1"},
+		{name: "custom tag to devDependencies", version: "canary", kind: packagejson.DependencyDev, expectedSpec: "^19.1.0-canary.3", expectedLock: "19.1.0-canary.3"},
+		{name: "latest tag", version: "latest", kind: packagejson.DependencyProd, expectedSpec: "^18.3.1", expectedLock: "18.3.1"},
+		{name: "no version", version: "", kind: packagejson.DependencyProd, expectedSpec: "^18.3.1", expectedLock: "18.3.1"},
+		{name: "range is saved as given", version: "~18.2.0", kind: packagejson.DependencyProd, expectedSpec: "~18.2.0", expectedLock: "18.2.0"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pm, tmpDir, origDir := setupTestPackageManager(t)
+			defer os.Chdir(origDir)
+
+			versions := []string{"18.2.0", "18.3.1", "19.0.0-rc.1", "19.1.0-canary.3"}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				entries := make([]string, 0, len(versions))
+				for _, v := range versions {
+					entries = append(entries, fmt.Sprintf(`%q: {"name": "react", "version": %q}`, v, v))
+				}
+				fmt.Fprintf(w, `{"name": "react", "dist-tags": {"latest": "18.3.1", "next": "19.0.0-rc.1", "canary": "19.1.0-canary.3"}, "versions": {%s}}`, strings.Join(entries, ","))
+			}))
+			defer server.Close()
+
+			m, err := manifest.NewManifest(t.TempDir(), server.URL+"/")
+			assert.NoError(t, err)
+			pm.manifest = m
+			for _, v := range versions {
+				writeCachedPackage(t, pm, "react", v, fmt.Sprintf(`{"name": "react", "version": %q}`, v))
+			}
+
+			assert.NoError(t, os.WriteFile(filepath.Join(tmpDir, "package.json"), []byte(`{"name": "test-project", "version": "1.0.0"}`), 0644))
+			lockContent := `{"name": "test-project", "version": "1.0.0", "lockfileVersion": 3, "requires": true, "packages": {}, "dependencies": {}}`
+			assert.NoError(t, os.WriteFile(filepath.Join(tmpDir, packagejson.LOCK_FILE_NAME_GO_NPM), []byte(lockContent), 0644))
+
+			assert.NoError(t, pm.Add("react", tc.version, tc.kind, false))
+
+			pkgJSON, err := pm.packageJsonParse.ParseDefault()
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedSpec, pkgJSON.GetDependenciesOfKind(tc.kind)["react"])
+
+			assert.Equal(t, tc.expectedLock, pm.packageLock.Packages["node_modules/react"].Version)
+			assert.Equal(t, tc.expectedSpec, lockDependenciesOfKind(pm.packageLock, tc.kind)["react"])
+		})
+	}
+}
+
+func TestAddNoSave(t *testing.T) {
+	pm, tmpDir, origDir := setupTestPackageManager(t)
+	defer os.Chdir(origDir)
+	pm.config.NoSave = true
+
+	setupTestRegistry(t, pm, map[string]map[string]map[string]string{
+		"a": {"1.0.0": {"b": "^1.0.0"}},
+		"b": {"1.0.0": nil},
+	})
+
+	packageJSONContent := `{
+  "name": "test-project",
+  "version": "1.0.0",
+  "dependencies": {}
+}`
+	lockContent := `{
+  "name": "test-project",
+  "version": "1.0.0",
+  "lockfileVersion": 3,
+  "requires": true,
+  "packages": {},
+  "dependencies": {}
+}`
+	lockPath := filepath.Join(tmpDir, packagejson.LOCK_FILE_NAME_GO_NPM)
+	assert.NoError(t, os.WriteFile(filepath.Join(tmpDir, "package.json"), []byte(packageJSONContent), 0644))
+	assert.NoError(t, os.WriteFile(lockPath, []byte(lockContent), 0644))
+
+	assert.NoError(t, pm.Add("a", "^1.0.0", packagejson.DependencyProd, false))
+
+	// The package and its dependencies are installed
+	assert.FileExists(t, filepath.Join(pm.extractedPath, "a", "package.json"))
+	assert.FileExists(t, filepath.Join(pm.extractedPath, "b", "package.json"))
+
+	// but neither file on disk records them
+	data, err := os.ReadFile(filepath.Join(tmpDir, "package.json"))
+	assert.NoError(t, err)
+	assert.Equal(t, packageJSONContent, string(data))
+	data, err = os.ReadFile(lockPath)
+	assert.NoError(t, err)
+	assert.Equal(t, lockContent, string(data))
+}
+
 func TestUninstallGlobal(t *testing.T) {
 	testCases := []struct {
 		name        string
@@ -1926,7 +2460,7 @@ func TestFetchToCacheWithOptionalDependencies(t *testing.T) {
 				}
 			}()
 
-			err := pm.fetchToCache(tc.packageJSON, false)
+			err := pm.fetchToCache(tc.packageJSON)
 
 			if tc.expectError {
 				assert.Error(t, err)
@@ -1939,3 +2473,190 @@ func TestFetchToCacheWithOptionalDependencies(t *testing.T) {
 		})
 	}
 }
+
+func TestFetchToCacheReusesSkippedOptional(t *testing.T) {
+	testCases := []struct {
+		name              string
+		secondSpec        string
+		expectedDownloads int
+	}{
+		{
+			name:              "second install reuses the recorded skip without a manifest download",
+			secondSpec:        "^1.0.0",
+			expectedDownloads: 1,
+		},
+		{
+			name:              "changed constraint re-resolves the package",
+			secondSpec:        "^2.0.0",
+			expectedDownloads: 2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pm, _, origDir := setupTestPackageManager(t)
+			defer os.Chdir(origDir)
+
+			// A platform no test runner uses, so the package is always skipped
+			unsupportedOS := "aix"
+			if utils.GetCurrentOS() == unsupportedOS {
+				unsupportedOS = "sunos"
+			}
+
+			downloads := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				downloads++
+				fmt.Fprintf(w, `{"name": "native-only", "dist-tags": {"latest": "2.0.0"}, "versions": {
+					"1.0.0": {"name": "native-only", "version": "1.0.0", "os": [%q]},
+					"2.0.0": {"name": "native-only", "version": "2.0.0", "os": [%q]}}}`, unsupportedOS, unsupportedOS)
+			}))
+			defer server.Close()
+
+			m, err := manifest.NewManifest(t.TempDir(), server.URL+"/")
+			assert.NoError(t, err)
+			pm.manifest = m
+
+			err = pm.fetchToCache(packagejson.PackageJSON{
+				OptionalDependencies: map[string]string{"native-only": "^1.0.0"},
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, 1, downloads)
+
+			item := pm.packageLock.Packages["node_modules/native-only"]
+			assert.True(t, item.Skipped)
+			assert.Equal(t, []string{unsupportedOS}, item.OS)
+
+			// Drop the manifest cache so any re-resolution has to hit the registry
+			assert.NoError(t, os.RemoveAll(m.Path))
+
+			err = pm.fetchToCache(packagejson.PackageJSON{
+				OptionalDependencies: map[string]string{"native-only": tc.secondSpec},
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedDownloads, downloads)
+
+			item = pm.packageLock.Packages["node_modules/native-only"]
+			assert.True(t, item.Skipped)
+			assert.Empty(t, item.Resolved)
+			assert.Equal(t, pm.packageLock.OptionalDependencies["native-only"], item.Version)
+		})
+	}
+}
+
+func TestFetchToCacheNoPeer(t *testing.T) {
+	testCases := []struct {
+		name     string
+		noPeer   bool
+		validate func(t *testing.T, lock *packagejson.PackageLock, stderr string)
+	}{
+		{
+			name:   "peers are installed and recorded by default",
+			noPeer: false,
+			validate: func(t *testing.T, lock *packagejson.PackageLock, stderr string) {
+				assert.Contains(t, lock.Packages, "node_modules/host")
+				assert.Contains(t, lock.Packages, "node_modules/react")
+				assert.Contains(t, lock.PeerDependencies, "react")
+				assert.Contains(t, lock.PeerDependencies, "host")
+				assert.Equal(t, map[string]string{"host": "^1.0.0"}, lock.Packages["node_modules/plugin"].PeerDependencies)
+			},
+		},
+		{
+			name:   "no-peer skips installing, validating and recording peers",
+			noPeer: true,
+			validate: func(t *testing.T, lock *packagejson.PackageLock, stderr string) {
+				assert.Contains(t, lock.Packages, "node_modules/plugin")
+				assert.NotContains(t, lock.Packages, "node_modules/host")
+				assert.NotContains(t, lock.Packages, "node_modules/react")
+				assert.Empty(t, lock.PeerDependencies)
+				assert.Empty(t, lock.Packages["node_modules/plugin"].PeerDependencies)
+				assert.Empty(t, lock.Packages["node_modules/plugin"].PeerDependenciesMeta)
+				assert.NotContains(t, stderr, "Peer dependency warnings")
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pm, _, origDir := setupTestPackageManager(t)
+			defer os.Chdir(origDir)
+
+			setupTestRegistry(t, pm, map[string]map[string]map[string]string{
+				"plugin": {"1.0.0": nil},
+				"host":   {"1.0.0": nil},
+				"react":  {"1.0.0": nil},
+			})
+			writeCachedPackage(t, pm, "plugin", "1.0.0", `{"name": "plugin", "version": "1.0.0", "peerDependencies": {"host": "^1.0.0"}}`)
+			pm.config.Omit.Peer = tc.noPeer
+
+			// Capture stderr, where unmet peer warnings are printed
+			origStderr := os.Stderr
+			r, w, err := os.Pipe()
+			assert.NoError(t, err)
+			os.Stderr = w
+
+			err = pm.fetchToCache(packagejson.PackageJSON{
+				Dependencies:     map[string]string{"plugin": "^1.0.0"},
+				PeerDependencies: map[string]any{"react": "^1.0.0"},
+			})
+
+			w.Close()
+			os.Stderr = origStderr
+			stderr, _ := io.ReadAll(r)
+
+			assert.NoError(t, err)
+			tc.validate(t, pm.packageLock, string(stderr))
+		})
+	}
+}
+
+func TestBuildDependenciesConfigPrecedence(t *testing.T) {
+	testCases := []struct {
+		name                string
+		env                 map[string]string
+		opts                types.BuildOptions
+		expectedConcurrency int
+		expectedStrategy    string
+	}{
+		{
+			name:                "config file",
+			expectedConcurrency: 2,
+			expectedStrategy:    config.InstallStrategyCopy,
+		},
+		{
+			name:                "environment wins over the config file",
+			env:                 map[string]string{"GO_NPM_CONCURRENCY": "3", "GO_NPM_INSTALL_STRATEGY": config.InstallStrategySymlink},
+			expectedConcurrency: 3,
+			expectedStrategy:    config.InstallStrategySymlink,
+		},
+		{
+			name:                "flags win over the environment",
+			env:                 map[string]string{"GO_NPM_CONCURRENCY": "3", "GO_NPM_INSTALL_STRATEGY": config.InstallStrategySymlink},
+			opts:                types.BuildOptions{MaxConcurrency: 5, InstallStrategy: config.InstallStrategyHardlink},
+			expectedConcurrency: 5,
+			expectedStrategy:    config.InstallStrategyHardlink,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			home := t.TempDir()
+			t.Setenv("GO_NPM_HOME", home)
+			t.Setenv("GO_NPM_CONCURRENCY", "")
+			t.Setenv("GO_NPM_INSTALL_STRATEGY", "")
+			for env, value := range tc.env {
+				t.Setenv(env, value)
+			}
+
+			file, err := config.LoadFile()
+			assert.NoError(t, err)
+			assert.NoError(t, file.Set("concurrency", "2"))
+			assert.NoError(t, file.Set("install-strategy", config.InstallStrategyCopy))
+			assert.NoError(t, file.Save())
+
+			deps, err := BuildDependencies(tc.opts)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedConcurrency, deps.Config.Concurrency)
+			assert.Equal(t, tc.expectedStrategy, deps.Config.InstallStrategy)
+		})
+	}
+}
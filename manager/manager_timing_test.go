@@ -0,0 +1,32 @@
+package manager
+
+import (
+	"os"
+	"testing"
+
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/ernesto27/go-npm/timing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchToCacheRecordsTiming(t *testing.T) {
+	pm, _, origDir := setupTestPackageManager(t)
+	defer os.Chdir(origDir)
+
+	pm.timing = timing.New()
+	setupTestRegistry(t, pm, map[string]map[string]map[string]string{
+		"a": {"1.0.0": {"b": "^1.0.0"}},
+		"b": {"1.0.0": nil},
+	})
+
+	require.NoError(t, pm.fetchToCache(packagejson.PackageJSON{
+		Dependencies: map[string]string{"a": "^1.0.0"},
+	}))
+
+	packages := pm.timing.Packages()
+	assert.ElementsMatch(t, []string{"a@1.0.0", "b@1.0.0"}, pm.timing.Slowest(10))
+	for _, key := range []string{"a@1.0.0", "b@1.0.0"} {
+		assert.Positive(t, packages[key][timing.PhaseManifest], "manifest fetch of %s is timed", key)
+	}
+}
@@ -0,0 +1,143 @@
+package manager
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdate(t *testing.T) {
+	testCases := []struct {
+		name          string
+		names         []string
+		latest        bool
+		expectError   bool
+		errorContains string
+		versions      map[string]string
+		packageJSON   map[string]string
+	}{
+		{
+			name:        "named package moves within its range",
+			names:       []string{"a"},
+			versions:    map[string]string{"a": "1.1.0", "b": "1.0.0"},
+			packageJSON: map[string]string{"a": "^1.0.0", "b": "~1.0.0"},
+		},
+		{
+			name:        "every package without names",
+			versions:    map[string]string{"a": "1.1.0", "b": "1.0.1"},
+			packageJSON: map[string]string{"a": "^1.0.0", "b": "~1.0.0"},
+		},
+		{
+			name:        "--latest widens the range to the newest major",
+			names:       []string{"a", "b"},
+			latest:      true,
+			versions:    map[string]string{"a": "2.0.0", "b": "1.1.0"},
+			packageJSON: map[string]string{"a": "^2.0.0", "b": "~1.1.0"},
+		},
+		{
+			name:          "unknown package",
+			names:         []string{"missing"},
+			expectError:   true,
+			errorContains: "missing is not a dependency in package.json",
+		},
+		{
+			name:          "non-registry dependency",
+			names:         []string{"local"},
+			expectError:   true,
+			errorContains: `cannot update local: "file:./local" is not a registry version range`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pm, tmpDir, origDir := setupTestPackageManager(t)
+			defer os.Chdir(origDir)
+
+			require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, "local"), 0755))
+			require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "local", "package.json"), []byte(`{"name": "local", "version": "1.0.0"}`), 0644))
+			require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "package.json"), []byte(`{
+  "name": "app",
+  "dependencies": {"a": "^1.0.0", "local": "file:./local"},
+  "devDependencies": {"b": "~1.0.0"}
+}`), 0644))
+
+			registry := map[string]map[string]map[string]string{
+				"a": {"1.0.0": nil},
+				"b": {"1.0.0": nil},
+			}
+			setupTestRegistry(t, pm, registry)
+			require.NoError(t, pm.ParsePackageJSON())
+			require.NoError(t, pm.InstallFromCache())
+
+			// Newer versions are published after the install
+			for name, versions := range map[string][]string{"a": {"1.1.0", "2.0.0"}, "b": {"1.0.1", "1.1.0"}} {
+				for _, v := range versions {
+					registry[name][v] = nil
+					writeCachedPackage(t, pm, name, v, `{"name": "`+name+`", "version": "`+v+`"}`)
+				}
+			}
+
+			updates, err := pm.Update(tc.names, tc.latest)
+			if tc.expectError {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.errorContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotEmpty(t, updates)
+
+			lock, err := pm.packageJsonParse.ParseLockFile()
+			require.NoError(t, err)
+			for name, version := range tc.versions {
+				assert.Equal(t, version, lock.Packages["node_modules/"+name].Version, name)
+
+				installed, err := pm.packageJsonParse.Parse(filepath.Join(pm.extractedPath, name, "package.json"))
+				require.NoError(t, err)
+				assert.Equal(t, version, installed.Version, name)
+			}
+
+			data, err := pm.packageJsonParse.ParseDefault()
+			require.NoError(t, err)
+			assert.Equal(t, tc.packageJSON["a"], data.GetDependencies()["a"])
+			assert.Equal(t, "file:./local", data.GetDependencies()["local"])
+			assert.Equal(t, tc.packageJSON["b"], data.GetDevDependencies()["b"])
+		})
+	}
+}
+
+func TestUpdateUpToDate(t *testing.T) {
+	pm, tmpDir, origDir := setupTestPackageManager(t)
+	defer os.Chdir(origDir)
+
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "package.json"), []byte(`{"name": "app", "dependencies": {"a": "^1.0.0"}}`), 0644))
+	setupTestRegistry(t, pm, map[string]map[string]map[string]string{"a": {"1.0.0": nil, "2.0.0": nil}})
+	require.NoError(t, pm.ParsePackageJSON())
+
+	updates, err := pm.Update(nil, false)
+	require.NoError(t, err)
+	assert.Empty(t, updates)
+}
+
+func TestWidenRange(t *testing.T) {
+	testCases := []struct {
+		spec     string
+		newest   string
+		expected string
+	}{
+		{spec: "^1.2.0", newest: "3.0.1", expected: "^3.0.1"},
+		{spec: "~1.2.0", newest: "1.4.0", expected: "~1.4.0"},
+		{spec: "1.2.0", newest: "2.0.0", expected: "2.0.0"},
+		{spec: ">=1.0.0 <2.0.0", newest: "2.1.0", expected: "^2.1.0"},
+		{spec: "^2.0.0", newest: "2.3.0", expected: "^2.0.0"},
+		{spec: "next", newest: "2.0.0", expected: "next"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.spec, func(t *testing.T) {
+			assert.Equal(t, tc.expected, widenRange(tc.spec, tc.newest))
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package manager
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ernesto27/go-npm/manifest"
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchToCacheWildcardPrefersStable(t *testing.T) {
+	testCases := []struct {
+		name    string
+		pkgJSON packagejson.PackageJSON
+	}{
+		{name: "dependency", pkgJSON: packagejson.PackageJSON{Dependencies: map[string]string{"tool": "*"}}},
+		{name: "devDependency", pkgJSON: packagejson.PackageJSON{DevDependencies: map[string]string{"tool": "*"}}},
+		{name: "optionalDependency", pkgJSON: packagejson.PackageJSON{OptionalDependencies: map[string]string{"tool": "*"}}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pm, _, origDir := setupTestPackageManager(t)
+			defer os.Chdir(origDir)
+
+			// dist-tags.latest points to a prerelease
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				name := strings.TrimPrefix(r.URL.Path, "/")
+				fmt.Fprintf(w, `{"name": %q, "dist-tags": {"latest": "2.0.0-beta.1"}, "versions": {"1.4.0": {"name": %q, "version": "1.4.0"}, "2.0.0-beta.1": {"name": %q, "version": "2.0.0-beta.1"}}}`,
+					name, name, name)
+			}))
+			defer server.Close()
+
+			m, err := manifest.NewManifest(t.TempDir(), server.URL+"/")
+			require.NoError(t, err)
+			pm.manifest = m
+
+			writeCachedPackage(t, pm, "tool", "1.4.0", `{"name": "tool", "version": "1.4.0"}`)
+			writeCachedPackage(t, pm, "tool", "2.0.0-beta.1", `{"name": "tool", "version": "2.0.0-beta.1"}`)
+
+			require.NoError(t, pm.fetchToCache(tc.pkgJSON))
+			assert.Equal(t, "1.4.0", pm.packageLock.Packages["node_modules/tool"].Version)
+		})
+	}
+}
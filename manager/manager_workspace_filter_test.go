@@ -0,0 +1,105 @@
+package manager
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePackageJSONWorkspaceFilter(t *testing.T) {
+	workspaces := map[string]string{
+		"app":  `{"name": "@org/app", "version": "1.0.0", "dependencies": {"a": "^1.0.0", "@org/lib": "workspace:^"}}`,
+		"lib":  `{"name": "@org/lib", "version": "1.0.0", "dependencies": {"b": "^1.0.0"}}`,
+		"tool": `{"name": "tool", "version": "2.0.0", "dependencies": {"c": "^1.0.0"}}`,
+	}
+
+	testCases := []struct {
+		name          string
+		workspaces    []string
+		all           bool
+		fromLock      bool
+		expectError   bool
+		errorContains string
+		packages      []string
+	}{
+		{
+			name:       "single workspace installs its subtree only",
+			workspaces: []string{"@org/app"},
+			packages:   []string{"node_modules/@org/app", "node_modules/@org/lib", "node_modules/a", "node_modules/b"},
+		},
+		{
+			name:       "glob matches workspace names",
+			workspaces: []string{"@org/*"},
+			packages:   []string{"node_modules/@org/app", "node_modules/@org/lib", "node_modules/a", "node_modules/b"},
+		},
+		{
+			name:       "workspace directory",
+			workspaces: []string{"workspaces/tool"},
+			packages:   []string{"node_modules/tool", "node_modules/c"},
+		},
+		{
+			name:     "--workspaces selects every workspace",
+			all:      true,
+			packages: []string{"node_modules/@org/app", "node_modules/@org/lib", "node_modules/tool", "node_modules/a", "node_modules/b", "node_modules/c"},
+		},
+		{
+			name:       "subtree is taken from the lock file",
+			workspaces: []string{"@org/lib"},
+			fromLock:   true,
+			packages:   []string{"node_modules/@org/lib", "node_modules/b"},
+		},
+		{
+			name:          "unknown workspace",
+			workspaces:    []string{"@org/missing"},
+			expectError:   true,
+			errorContains: `no workspace matches "@org/missing"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pm, origDir := setupWorkspaces(t, `{"@org/app": "*", "tool": "*", "root-only": "^1.0.0"}`, workspaces)
+			defer os.Chdir(origDir)
+
+			setupTestRegistry(t, pm, map[string]map[string]map[string]string{
+				"a":         {"1.0.0": nil},
+				"b":         {"1.0.0": nil},
+				"c":         {"1.0.0": nil},
+				"root-only": {"1.0.0": nil},
+			})
+
+			if tc.fromLock {
+				require.NoError(t, pm.ParsePackageJSON())
+				lock, err := pm.packageJsonParse.ParseLockFile()
+				require.NoError(t, err)
+				pm.packageJsonParse.PackageLock = lock
+				require.NoError(t, os.Remove(pm.packageJsonParse.LockFileName))
+
+				// The registry is not consulted for a subtree found in the lock
+				setupTestRegistry(t, pm, nil)
+			}
+
+			pm.config.Workspaces = tc.workspaces
+			pm.config.AllWorkspaces = tc.all
+
+			err := pm.ParsePackageJSON()
+			if tc.expectError {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.errorContains)
+				return
+			}
+			require.NoError(t, err)
+
+			var packages []string
+			for pkgPath := range pm.packageLock.Packages {
+				packages = append(packages, pkgPath)
+			}
+			assert.ElementsMatch(t, tc.packages, packages)
+			assert.NotContains(t, pm.packageLock.Dependencies, "root-only")
+
+			assert.NoFileExists(t, pm.packageJsonParse.LockFileName, "a filtered install should not write the lock file")
+		})
+	}
+}
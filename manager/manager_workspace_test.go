@@ -11,10 +11,11 @@ import (
 
 func TestFetchToCacheWithWorkspaces(t *testing.T) {
 	testCases := []struct {
-		name        string
-		setupFunc   func(t *testing.T) (*PackageManager, string)
-		expectError bool
-		validate    func(t *testing.T, pm *PackageManager, tmpDir string)
+		name          string
+		setupFunc     func(t *testing.T) (*PackageManager, string)
+		expectError   bool
+		errorContains string
+		validate      func(t *testing.T, pm *PackageManager, tmpDir string)
 	}{
 		{
 			name: "resolves workspace packages instead of downloading from npm",
@@ -338,6 +339,69 @@ func TestFetchToCacheWithWorkspaces(t *testing.T) {
 				assert.NoDirExists(t, cachedPath, "workspace should not be cached")
 			},
 		},
+		{
+			name: "replaces the workspace: protocol with the sibling's version",
+			setupFunc: func(t *testing.T) (*PackageManager, string) {
+				t.Helper()
+				return setupWorkspaces(t, `{"@myorg/app": "workspace:*"}`, map[string]string{
+					"ui":    `{"name": "@myorg/ui", "version": "1.5.0"}`,
+					"core":  `{"name": "@myorg/core", "version": "3.0.0"}`,
+					"theme": `{"name": "@myorg/theme", "version": "0.2.0"}`,
+					"app": `{"name": "@myorg/app", "version": "2.0.0", "dependencies": {
+						"@myorg/ui": "workspace:^",
+						"@myorg/core": "workspace:*",
+						"@myorg/theme": "workspace:~",
+						"@myorg/pinned": "workspace:>=1.0.0"
+					}}`,
+					"pinned": `{"name": "@myorg/pinned", "version": "1.1.0"}`,
+				})
+			},
+			expectError: false,
+			validate: func(t *testing.T, pm *PackageManager, tmpDir string) {
+				app := pm.packageLock.Packages["node_modules/@myorg/app"]
+				assert.Equal(t, map[string]string{
+					"@myorg/ui":     "^1.5.0",
+					"@myorg/core":   "3.0.0",
+					"@myorg/theme":  "~0.2.0",
+					"@myorg/pinned": ">=1.0.0",
+				}, app.Dependencies)
+
+				assert.Equal(t, map[string]string{
+					"@myorg/app":    "2.0.0",
+					"@myorg/ui":     "1.5.0",
+					"@myorg/core":   "3.0.0",
+					"@myorg/theme":  "0.2.0",
+					"@myorg/pinned": "1.1.0",
+				}, pm.packageLock.Workspaces)
+				assert.Equal(t, "2.0.0", pm.packageLock.Dependencies["@myorg/app"])
+
+				ui := pm.packageLock.Packages["node_modules/@myorg/ui"]
+				assert.True(t, ui.Link)
+				assert.Equal(t, "1.5.0", ui.Version)
+			},
+		},
+		{
+			name: "fails when a workspace requires an unknown workspace",
+			setupFunc: func(t *testing.T) (*PackageManager, string) {
+				t.Helper()
+				return setupWorkspaces(t, `{"@myorg/app": "*"}`, map[string]string{
+					"app": `{"name": "@myorg/app", "version": "2.0.0", "dependencies": {"@myorg/missing": "workspace:^"}}`,
+				})
+			},
+			expectError:   true,
+			errorContains: "@myorg/missing@workspace:^: @myorg/missing is not a workspace package",
+		},
+		{
+			name: "fails when package.json requires an unknown workspace",
+			setupFunc: func(t *testing.T) (*PackageManager, string) {
+				t.Helper()
+				return setupWorkspaces(t, `{"@myorg/missing": "workspace:*"}`, map[string]string{
+					"app": `{"name": "@myorg/app", "version": "2.0.0"}`,
+				})
+			},
+			expectError:   true,
+			errorContains: "@myorg/missing@workspace:*: @myorg/missing is not a workspace package",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -357,10 +421,13 @@ func TestFetchToCacheWithWorkspaces(t *testing.T) {
 			data, err := pm.packageJsonParse.ParseDefault()
 			assert.NoError(t, err)
 
-			err = pm.fetchToCache(*data, false)
+			err = pm.fetchToCache(*data)
 
 			if tc.expectError {
 				assert.Error(t, err)
+				if tc.errorContains != "" {
+					assert.ErrorContains(t, err, tc.errorContains)
+				}
 			} else {
 				assert.NoError(t, err)
 				if tc.validate != nil {
@@ -370,3 +437,28 @@ func TestFetchToCacheWithWorkspaces(t *testing.T) {
 		})
 	}
 }
+
+// setupWorkspaces writes a root package.json with rootDependencies and a
+// workspaces/<dir> workspace for each package.json in workspaces, then
+// discovers them. The packages directory is left to the test cache.
+func setupWorkspaces(t *testing.T, rootDependencies string, workspaces map[string]string) (*PackageManager, string) {
+	t.Helper()
+	pm, tmpDir, origDir := setupTestPackageManager(t)
+
+	for dir, packageJSON := range workspaces {
+		wsDir := filepath.Join(tmpDir, "workspaces", dir)
+		assert.NoError(t, os.MkdirAll(wsDir, 0755))
+		assert.NoError(t, os.WriteFile(filepath.Join(wsDir, "package.json"), []byte(packageJSON), 0644))
+	}
+
+	rootPackageJSON := `{"name": "test-app", "version": "1.0.0", "workspaces": ["workspaces/*"], "dependencies": ` + rootDependencies + `}`
+	assert.NoError(t, os.WriteFile(filepath.Join(tmpDir, "package.json"), []byte(rootPackageJSON), 0644))
+
+	data, err := pm.packageJsonParse.ParseDefault()
+	assert.NoError(t, err)
+
+	pm.workspaceRegistry = workspace.NewWorkspaceRegistry(tmpDir, pm.packageJsonParse)
+	assert.NoError(t, pm.workspaceRegistry.Discover(data))
+
+	return pm, origDir
+}
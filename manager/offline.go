@@ -0,0 +1,30 @@
+package manager
+
+import (
+	"fmt"
+
+	manifestpkg "github.com/ernesto27/go-npm/manifest"
+	"github.com/ernesto27/go-npm/utils"
+	"github.com/ernesto27/go-npm/version"
+)
+
+// errNotCached reports a package that --offline would have to fetch
+func errNotCached(name, version string) error {
+	return fmt.Errorf("%w: %s@%s is not in the cache", utils.ErrOffline, name, version)
+}
+
+// offlineGitRef returns ref when it is a full commit SHA, which resolves
+// without contacting the repository, and an error for --offline otherwise
+func offlineGitRef(ref, spec string) (string, error) {
+	if gitCommitPattern.MatchString(ref) {
+		return ref, nil
+	}
+	return "", fmt.Errorf("%w: %s needs the network to resolve; pin it to a full commit SHA", utils.ErrOffline, spec)
+}
+
+// matchesManifest reports whether a version of npmPackage satisfies spec,
+// rather than spec only resolving by falling back to the latest version.
+// For a cached manifest, a false result is a cache miss.
+func (pm *PackageManager) matchesManifest(spec string, npmPackage *manifestpkg.NPMPackage) bool {
+	return pm.versionInfo.Resolve(spec, npmPackage).Reason != version.ReasonFallbackToLatest
+}
@@ -0,0 +1,44 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/ernesto27/go-npm/utils"
+)
+
+// ErrBadPlatform is returned when the root package does not support the
+// current os or cpu
+var ErrBadPlatform = errors.New("EBADPLATFORM")
+
+// checkRootPlatform rejects an install of a root package whose os or cpu
+// constraints exclude the current platform, as npm does, before anything is
+// resolved
+func checkRootPlatform(data *packagejson.PackageJSON) error {
+	name := data.Name
+	if version, ok := data.Version.(string); ok && version != "" {
+		name += "@" + version
+	}
+	return checkPlatform(name, data.GetOS(), data.GetCPU())
+}
+
+// checkPlatform returns an ErrBadPlatform error naming the package and the
+// platform it wants when its os or cpu constraints exclude the current one
+func checkPlatform(name string, osConstraints, cpuConstraints []string) error {
+	if utils.IsCompatiblePlatform(osConstraints, cpuConstraints) {
+		return nil
+	}
+
+	return fmt.Errorf("%w: unsupported platform for %s: wanted {\"os\":%q,\"cpu\":%q} (current: {\"os\":%q,\"cpu\":%q})",
+		ErrBadPlatform, name, platformList(osConstraints), platformList(cpuConstraints), utils.GetCurrentOS(), utils.GetCurrentCPU())
+}
+
+// platformList joins constraints the way npm prints them, "any" when empty
+func platformList(constraints []string) string {
+	if len(constraints) == 0 {
+		return "any"
+	}
+	return strings.Join(constraints, ",")
+}
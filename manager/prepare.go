@@ -0,0 +1,75 @@
+package manager
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ernesto27/go-npm/packagejson"
+)
+
+// gitDependencyPaths returns, sorted, the lock paths in packages that were
+// resolved from a git repository
+func gitDependencyPaths(packages map[string]packagejson.PackageItem) []string {
+	var paths []string
+	for pkgPath, item := range packages {
+		if _, isGit := parseGitDependency(item.Resolved); isGit {
+			paths = append(paths, pkgPath)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// prepareDependencies runs the prepare script of the git dependencies at
+// gitPaths, which were just installed, and of every file: dependency, the way
+// npm builds them after install. Like other dependency scripts, prepare only
+// runs for trusted packages and never with --ignore-scripts.
+func (pm *PackageManager) prepareDependencies(gitPaths []string) error {
+	for _, pkgPath := range gitPaths {
+		item := pm.packageLock.Packages[pkgPath]
+		namePkg := strings.TrimPrefix(pkgPath, "node_modules/")
+		targetPath := filepath.Join(pm.extractedPath, namePkg)
+
+		if err := pm.lifecycleManager.RunDependencyPrepare(extractPackageName(namePkg), item.Version, targetPath, item.Scripts); err != nil {
+			return err
+		}
+	}
+
+	if pm.isGlobal {
+		return nil
+	}
+
+	var filePaths []string
+	for pkgPath, item := range pm.packageLock.Packages {
+		pkgName, ok := strings.CutPrefix(pkgPath, "node_modules/")
+		if !ok || !item.Link || strings.Contains(pkgName, "/node_modules/") {
+			continue
+		}
+		if pm.workspaceRegistry != nil && pm.workspaceRegistry.IsWorkspacePackage(pkgName) {
+			continue
+		}
+		if _, isFile := parseFileDependency(item.Resolved); isFile {
+			filePaths = append(filePaths, pkgPath)
+		}
+	}
+	sort.Strings(filePaths)
+
+	for _, pkgPath := range filePaths {
+		pkgName := strings.TrimPrefix(pkgPath, "node_modules/")
+		targetPath := filepath.Join(pm.extractedPath, pkgName)
+
+		// Links do not record scripts in the lock; the directory may also have
+		// changed since it was resolved
+		pkgJSON, err := pm.packageJsonParse.Parse(filepath.Join(targetPath, "package.json"))
+		if err != nil {
+			return err
+		}
+
+		if err := pm.lifecycleManager.RunDependencyPrepare(pkgName, pm.packageLock.Packages[pkgPath].Version, targetPath, pkgJSON.Scripts); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
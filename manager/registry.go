@@ -0,0 +1,59 @@
+package manager
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// defaultRegistryScope is the lock registries key for unscoped packages
+const defaultRegistryScope = "default"
+
+// registries returns the registry base URL currently used for each scope,
+// in the form recorded in the lock
+func (pm *PackageManager) registries() map[string]string {
+	registries := map[string]string{defaultRegistryScope: pm.manifest.RegistryURL()}
+	for scope, registryURL := range pm.manifest.ScopeRegistries() {
+		registries[scope] = registryURL
+	}
+	return registries
+}
+
+// registryMismatches describes every scope in locked whose registry differs
+// from current. Scopes without a registry of their own use the default one.
+func registryMismatches(locked, current map[string]string) []string {
+	scopes := make([]string, 0, len(locked))
+	for scope := range locked {
+		scopes = append(scopes, scope)
+	}
+	sort.Strings(scopes)
+
+	var mismatches []string
+	for _, scope := range scopes {
+		want, ok := current[scope]
+		if !ok {
+			want = current[defaultRegistryScope]
+		}
+		if strings.TrimSuffix(locked[scope], "/") != strings.TrimSuffix(want, "/") {
+			mismatches = append(mismatches, fmt.Sprintf("%s: %s (lock) -> %s (current)", scope, locked[scope], want))
+		}
+	}
+	return mismatches
+}
+
+// warnRegistryChange warns when the lock was resolved against a different
+// registry than the current one. Locks without registries are not checked.
+func warnRegistryChange(w io.Writer, locked, current map[string]string) {
+	mismatches := registryMismatches(locked, current)
+	if len(mismatches) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w, "\n⚠️  Registry changed since the lock file was resolved:")
+	for _, mismatch := range mismatches {
+		fmt.Fprintln(w, "  ", mismatch)
+	}
+	fmt.Fprintln(w, "   Locked packages are still installed from their resolved URLs; delete go-npm-lock.json to re-resolve them")
+	fmt.Fprintln(w)
+}
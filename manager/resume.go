@@ -0,0 +1,126 @@
+package manager
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// resumeEntry is a package that an install resolved and placed in the cache
+type resumeEntry struct {
+	Name      string `json:"name"`
+	Spec      string `json:"spec"`
+	Version   string `json:"version"`
+	Integrity string `json:"integrity,omitempty"`
+}
+
+// resumeLog records, one JSON line at a time, the packages an install has
+// resolved and cached. When the install fails, the next one for the same
+// project reads it back and keeps those versions instead of resolving them
+// again; tarball URL dependencies are not even re-read. The log is removed
+// once an install resolves completely.
+type resumeLog struct {
+	mu      sync.Mutex
+	path    string
+	file    *os.File
+	entries map[string]resumeEntry
+}
+
+// resumeLogPath returns the resume log of the project in dir, kept in the
+// cache so the project tree is left alone
+func (pm *PackageManager) resumeLogPath(dir string) string {
+	sum := sha256.Sum256([]byte(dir))
+	return filepath.Join(pm.config.BaseDir, "resume", hex.EncodeToString(sum[:])[:16]+".jsonl")
+}
+
+// openResumeLog reads the resume log of the current project, left by an
+// install that failed, and opens it to record this one
+func (pm *PackageManager) openResumeLog() (*resumeLog, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get working directory: %w", err)
+	}
+	if pm.isGlobal {
+		dir = pm.config.GlobalDir
+	}
+
+	log := &resumeLog{
+		path:    pm.resumeLogPath(dir),
+		entries: make(map[string]resumeEntry),
+	}
+
+	if file, err := os.Open(log.path); err == nil {
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			// The last line may have been cut short by the failure
+			var entry resumeEntry
+			if json.Unmarshal(scanner.Bytes(), &entry) == nil && entry.Version != "" {
+				log.entries[entry.Name+"@"+entry.Spec] = entry
+			}
+		}
+		file.Close()
+		if len(log.entries) > 0 {
+			pm.logger.Infof("Resuming install: %d packages already resolved", len(log.entries))
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(log.path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create resume log directory: %w", err)
+	}
+	log.file, err = os.OpenFile(log.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open resume log: %w", err)
+	}
+
+	return log, nil
+}
+
+// lookup returns the entry of name requested as spec, if a previous
+// install resolved it
+func (l *resumeLog) lookup(name, spec string) (resumeEntry, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry, ok := l.entries[name+"@"+spec]
+	return entry, ok
+}
+
+// record appends name requested as spec, resolved to version and cached
+func (l *resumeLog) record(name, spec, version, integrity string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	key := name + "@" + spec
+	if entry, ok := l.entries[key]; ok && entry.Version == version && entry.Integrity == integrity {
+		return nil
+	}
+	entry := resumeEntry{Name: name, Spec: spec, Version: version, Integrity: integrity}
+	l.entries[key] = entry
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode resume log entry: %w", err)
+	}
+	if _, err := l.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write resume log: %w", err)
+	}
+	return nil
+}
+
+// close closes the log, keeping it for the next install
+func (l *resumeLog) close() {
+	l.file.Close()
+}
+
+// finish closes and removes the log once the install resolved completely
+func (l *resumeLog) finish() error {
+	l.file.Close()
+	if err := os.Remove(l.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove resume log: %w", err)
+	}
+	return nil
+}
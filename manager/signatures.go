@@ -0,0 +1,168 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	manifestpkg "github.com/ernesto27/go-npm/manifest"
+	"github.com/ernesto27/go-npm/signature"
+	"github.com/ernesto27/go-npm/tarball"
+)
+
+// checkSignature verifies the registry signature of pkgName@version when
+// --verify-signatures is set, using the integrity and signatures of the
+// version in npmPackage and the keys of the registry it comes from
+func (pm *PackageManager) checkSignature(pkgName, version string, npmPackage *manifestpkg.NPMPackage) error {
+	if !pm.config.VerifySignatures {
+		return nil
+	}
+
+	versionData, ok := npmPackage.Versions[version]
+	if !ok {
+		return nil
+	}
+
+	var publishedAt time.Time
+	if published, ok := npmPackage.Time[version]; ok {
+		publishedAt, _ = time.Parse(time.RFC3339, published)
+	}
+
+	verifier := pm.signatureVerifier(pm.signatureRegistry(pkgName, versionData.Dist.Tarball))
+	if err := verifier.Verify(pkgName, version, versionData.Dist.Integrity, versionData.Dist.Signatures, publishedAt); err != nil {
+		return fmt.Errorf("SECURITY: signature verification failed for %s@%s: %w", pkgName, version, err)
+	}
+
+	pm.verifiedSignatures.Store(pkgName+"@"+version, true)
+	return nil
+}
+
+// signatureRegistry returns the registry whose keys sign pkgName: the
+// registry of its scope, else the configured registry resolved is on, else
+// the primary registry. Keys are never fetched from a host named only by a
+// manifest or a lock.
+func (pm *PackageManager) signatureRegistry(pkgName, resolved string) string {
+	if registry, ok := pm.manifest.ScopeRegistry(pkgName); ok {
+		return registry
+	}
+	for _, registry := range pm.manifest.Registries() {
+		if strings.HasPrefix(resolved, registry) {
+			return registry
+		}
+	}
+	return pm.manifest.RegistryURL()
+}
+
+// signatureVerifier returns the verifier of the registry at registryURL,
+// created on first use with the retry policy of manifest requests
+func (pm *PackageManager) signatureVerifier(registryURL string) *signature.Verifier {
+	pm.signaturesMu.Lock()
+	defer pm.signaturesMu.Unlock()
+
+	verifier, ok := pm.signatures[registryURL]
+	if !ok {
+		verifier = signature.NewVerifier(registryURL, pm.manifest.RetryPolicy())
+		pm.signatures[registryURL] = verifier
+	}
+	return verifier
+}
+
+// verifyLockSignatures verifies, with --verify-signatures, the registry
+// signature of every registry package of the lock, so that an install from
+// an existing lock is checked like one that resolves. Packages verified while
+// resolving are not checked again.
+func (pm *PackageManager) verifyLockSignatures() error {
+	if !pm.config.VerifySignatures {
+		return nil
+	}
+
+	// The versions of each package are checked against one manifest
+	versions := make(map[string]map[string]bool)
+	for pkgPath, item := range pm.packageLock.Packages {
+		if pkgPath == "" || item.Link || item.Skipped || pm.ignoredOptional(item) || !isRegistryTarball(item.Resolved) {
+			continue
+		}
+		name := item.Name
+		if name == "" {
+			name = lockPathName(pkgPath)
+		}
+		if tarball.CacheVersion(name, item.Version, item.Resolved) != item.Version {
+			continue
+		}
+		if _, ok := pm.verifiedSignatures.Load(name + "@" + item.Version); ok {
+			continue
+		}
+		if versions[name] == nil {
+			versions[name] = make(map[string]bool)
+		}
+		versions[name][item.Version] = true
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, max(pm.concurrency, 1))
+	errChan := make(chan error, len(versions))
+	for name, pkgVersions := range versions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			if err := pm.verifyLockedPackage(name, pkgVersions); err != nil {
+				errChan <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errChan)
+
+	var errs []error
+	for err := range errChan {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// verifyLockedPackage verifies the locked versions of name against its
+// manifest, the cached one when it has them all
+func (pm *PackageManager) verifyLockedPackage(name string, versions map[string]bool) error {
+	manifestPath := pm.manifest.FilePath(name)
+
+	var npmPackage *manifestpkg.NPMPackage
+	if _, err := os.Stat(manifestPath); err == nil {
+		npmPackage, _ = pm.parseJsonManifest.Parse(manifestPath)
+	}
+
+	hasVersions := npmPackage != nil
+	for version := range versions {
+		if hasVersions {
+			_, hasVersions = npmPackage.Versions[version]
+		}
+	}
+
+	// Offline, a manifest missing from the cache cannot be downloaded and the
+	// package cannot be verified
+	if !hasVersions {
+		etag, _, err := pm.manifest.Download(name, "")
+		if err != nil {
+			return fmt.Errorf("SECURITY: cannot verify the signature of %s: %w", name, err)
+		}
+		pm.Etag.Set(pm.manifest.EtagKey(name), etag, time.Now())
+		if npmPackage, err = pm.parseJsonManifest.Parse(manifestPath); err != nil {
+			return fmt.Errorf("SECURITY: cannot verify the signature of %s: %w", name, err)
+		}
+	}
+
+	for version := range versions {
+		if _, ok := npmPackage.Versions[version]; !ok {
+			return fmt.Errorf("SECURITY: cannot verify the signature of %s@%s: the registry no longer lists it", name, version)
+		}
+		if err := pm.checkSignature(name, version, npmPackage); err != nil {
+			return err
+		}
+	}
+	return nil
+}
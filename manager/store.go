@@ -0,0 +1,106 @@
+package manager
+
+import (
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ernesto27/go-npm/integrity"
+	"github.com/ernesto27/go-npm/utils"
+)
+
+// contentStorePath is the store directory of the package content with the
+// given integrity, store/<first two hex digits>/<rest of the sha512 digest>.
+// Content without a sha512 hash (git and tarball URL dependencies, old
+// sha1-only locks) cannot be addressed and stays in the name@version layout.
+func contentStorePath(storeDir, sri string) (string, bool) {
+	hashes, err := integrity.ParseIntegrity(sri)
+	if err != nil || hashes[0].Algorithm != "sha512" {
+		return "", false
+	}
+
+	digest, err := base64.StdEncoding.DecodeString(hashes[0].Hash)
+	if err != nil || len(digest) != sha512.Size {
+		return "", false
+	}
+
+	hexDigest := hex.EncodeToString(digest)
+	return filepath.Join(storeDir, hexDigest[:2], hexDigest[2:]), true
+}
+
+// storedPackage returns the store directory holding the content with the
+// given integrity, when the content store is enabled and already has it
+func (pm *PackageManager) storedPackage(sri string) (string, bool) {
+	if !pm.config.ContentStore {
+		return "", false
+	}
+
+	dir, ok := contentStorePath(pm.config.StoreDir, sri)
+	if !ok || !utils.FolderExists(dir) {
+		return "", false
+	}
+	return dir, true
+}
+
+// storePackage moves the name@version directory pathPkg into the content
+// store and leaves a link to the store entry in its place. When the store
+// already holds the same content, e.g. the same tarball installed under an
+// alias, the duplicate is removed instead. It returns the directory to read
+// the package from, pathPkg itself when the content store is not used.
+func (pm *PackageManager) storePackage(pathPkg, sri string) (string, error) {
+	if !pm.config.ContentStore {
+		return pathPkg, nil
+	}
+
+	dir, ok := contentStorePath(pm.config.StoreDir, sri)
+	if !ok {
+		return pathPkg, nil
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", dir, err)
+	}
+
+	info, err := os.Lstat(pathPkg)
+	isDir := err == nil && info.IsDir()
+
+	if !utils.FolderExists(dir) {
+		if !isDir {
+			return pathPkg, nil
+		}
+		if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+			return "", fmt.Errorf("failed to create %s: %w", filepath.Dir(dir), err)
+		}
+		// Another package with the same content may have been stored meanwhile
+		if err := os.Rename(pathPkg, dir); err != nil && !utils.FolderExists(dir) {
+			return "", fmt.Errorf("failed to move %s into the store: %w", pathPkg, err)
+		}
+	}
+
+	if err := linkStoreEntry(dir, pathPkg); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// linkStoreEntry points the name@version path pathPkg at the store entry
+// dir, replacing a duplicate directory or a stale link left there
+func linkStoreEntry(dir, pathPkg string) error {
+	if target, err := os.Readlink(pathPkg); err == nil && target == dir {
+		return nil
+	}
+
+	if err := os.RemoveAll(pathPkg); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", pathPkg, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(pathPkg), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(pathPkg), err)
+	}
+	if err := os.Symlink(dir, pathPkg); err != nil {
+		return fmt.Errorf("failed to link %s to the store: %w", pathPkg, err)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package manager
+
+import (
+	"crypto/sha512"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testIntegrity(content string) string {
+	sum := sha512.Sum512([]byte(content))
+	return "sha512-" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func TestContentStorePath(t *testing.T) {
+	testCases := []struct {
+		name     string
+		sri      string
+		expected string
+		ok       bool
+	}{
+		{
+			name:     "sha512 integrity",
+			sri:      testIntegrity("a"),
+			expected: filepath.Join("store", "1f", "40fc92da241694750979ee6cf582f2d5d7d28e18335de05abc54d0560e0f5302860c652bf08d560252aa5e74210546f369fbbbce8c12cfc7957b2652fe9a75"),
+			ok:       true,
+		},
+		{
+			name:     "strongest hash is used",
+			sri:      "sha1-hK3Ymw== " + testIntegrity("a"),
+			expected: filepath.Join("store", "1f", "40fc92da241694750979ee6cf582f2d5d7d28e18335de05abc54d0560e0f5302860c652bf08d560252aa5e74210546f369fbbbce8c12cfc7957b2652fe9a75"),
+			ok:       true,
+		},
+		{
+			name: "sha256 only",
+			sri:  "sha256-ypeBEsobvcr6wjGzmiPcTaeG7/gUfE5yuYB3ha/uSLs=",
+		},
+		{
+			name: "no integrity",
+			sri:  "",
+		},
+		{
+			name: "truncated digest",
+			sri:  "sha512-YQ==",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir, ok := contentStorePath("store", tc.sri)
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.expected, dir)
+		})
+	}
+}
+
+func TestStorePackage(t *testing.T) {
+	sri := testIntegrity("shared tarball")
+
+	t.Run("legacy layout keeps name@version directories", func(t *testing.T) {
+		pm, _, origDir := setupTestPackageManager(t)
+		defer os.Chdir(origDir)
+
+		writeCachedPackage(t, pm, "a", "1.0.0", `{"name": "a", "version": "1.0.0"}`)
+		pathPkg := filepath.Join(pm.packagesPath, "a@1.0.0")
+
+		dir, err := pm.storePackage(pathPkg, sri)
+		require.NoError(t, err)
+		assert.Equal(t, pathPkg, dir)
+
+		info, err := os.Lstat(pathPkg)
+		require.NoError(t, err)
+		assert.True(t, info.IsDir())
+	})
+
+	t.Run("identical content is stored once", func(t *testing.T) {
+		pm, _, origDir := setupTestPackageManager(t)
+		defer os.Chdir(origDir)
+		pm.config.ContentStore = true
+
+		writeCachedPackage(t, pm, "a", "1.0.0", `{"name": "a", "version": "1.0.0"}`)
+		writeCachedPackage(t, pm, "alias", "1.0.0", `{"name": "a", "version": "1.0.0"}`)
+
+		first, err := pm.storePackage(filepath.Join(pm.packagesPath, "a@1.0.0"), sri)
+		require.NoError(t, err)
+		second, err := pm.storePackage(filepath.Join(pm.packagesPath, "alias@1.0.0"), sri)
+		require.NoError(t, err)
+
+		assert.Equal(t, first, second)
+		assert.FileExists(t, filepath.Join(first, "package.json"))
+		for _, name := range []string{"a@1.0.0", "alias@1.0.0"} {
+			target, err := os.Readlink(filepath.Join(pm.packagesPath, name))
+			require.NoError(t, err, "%s should be a link to the store", name)
+			assert.Equal(t, first, target)
+		}
+
+		entries, err := os.ReadDir(filepath.Dir(first))
+		require.NoError(t, err)
+		assert.Len(t, entries, 1)
+	})
+
+	t.Run("content without sha512 stays in place", func(t *testing.T) {
+		pm, _, origDir := setupTestPackageManager(t)
+		defer os.Chdir(origDir)
+		pm.config.ContentStore = true
+
+		writeCachedPackage(t, pm, "git-dep", "1.0.0", `{"name": "git-dep", "version": "1.0.0"}`)
+		pathPkg := filepath.Join(pm.packagesPath, "git-dep@1.0.0")
+
+		dir, err := pm.storePackage(pathPkg, "")
+		require.NoError(t, err)
+		assert.Equal(t, pathPkg, dir)
+	})
+}
+
+func TestInstallFromCacheContentStore(t *testing.T) {
+	pm, tmpDir, origDir := setupTestPackageManager(t)
+	defer os.Chdir(origDir)
+	pm.config.ContentStore = true
+
+	sri := testIntegrity("shared tarball")
+	writeCachedPackage(t, pm, "a", "1.0.0", `{"name": "a", "version": "1.0.0"}`)
+	_, err := pm.storePackage(filepath.Join(pm.packagesPath, "a@1.0.0"), sri)
+	require.NoError(t, err)
+
+	// alias@1.0.0 was never cached by name, only its content is in the store
+	pm.packageLock = &packagejson.PackageLock{
+		Dependencies: map[string]string{"a": "^1.0.0", "alias": "npm:a@^1.0.0"},
+		Packages: map[string]packagejson.PackageItem{
+			"":                   {Name: "root"},
+			"node_modules/a":     {Name: "a", Version: "1.0.0", Integrity: sri},
+			"node_modules/alias": {Name: "alias", Version: "1.0.0", Integrity: sri},
+		},
+	}
+	require.NoError(t, pm.InstallFromCache())
+
+	nodeModules := filepath.Join(tmpDir, "node_modules")
+	assert.FileExists(t, filepath.Join(nodeModules, "a", "package.json"))
+	assert.FileExists(t, filepath.Join(nodeModules, "alias", "package.json"))
+	assert.NoDirExists(t, filepath.Join(pm.packagesPath, "alias@1.0.0"))
+}
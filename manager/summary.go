@@ -0,0 +1,141 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/ernesto27/go-npm/progress"
+)
+
+// snapshotLock records the packages of the lock file on disk before the
+// command changes it, so Finish can report what was added, removed or
+// updated. Only the first call of a command takes the snapshot.
+func (pm *PackageManager) snapshotLock() {
+	if pm.previousPackages != nil {
+		return
+	}
+	pm.previousPackages = make(map[string]packagejson.PackageItem)
+
+	lockFile := pm.packageJsonParse.LockFileName
+	if pm.isGlobal {
+		lockFile = pm.config.GlobalLockFile
+	}
+	data, err := os.ReadFile(lockFile)
+	if err != nil {
+		return
+	}
+	var lock packagejson.PackageLock
+	if json.Unmarshal(data, &lock) == nil {
+		for pkgPath, item := range lock.Packages {
+			pm.previousPackages[pkgPath] = item
+		}
+	}
+}
+
+// summary compares the lock with the snapshot taken when the command started
+func (pm *PackageManager) summary() progress.Summary {
+	var current map[string]packagejson.PackageItem
+	if pm.packageLock != nil {
+		current = pm.packageLock.Packages
+	}
+
+	var summary progress.Summary
+	for pkgPath, item := range current {
+		if pkgPath == "" || item.Link {
+			continue
+		}
+		summary.Total++
+
+		previous, existed := pm.previousPackages[pkgPath]
+		change := progress.Change{Name: lockPathName(pkgPath), Version: item.Version}
+		if !existed {
+			summary.Added = append(summary.Added, change)
+		} else if previous.Version != item.Version {
+			change.Previous = previous.Version
+			summary.Updated = append(summary.Updated, change)
+		}
+	}
+	for pkgPath, item := range pm.previousPackages {
+		if _, ok := current[pkgPath]; ok || pkgPath == "" || item.Link {
+			continue
+		}
+		summary.Removed = append(summary.Removed, progress.Change{Name: lockPathName(pkgPath), Version: item.Version})
+	}
+
+	for _, changes := range [][]progress.Change{summary.Added, summary.Removed, summary.Updated} {
+		sort.Slice(changes, func(i, j int) bool {
+			if changes[i].Name != changes[j].Name {
+				return changes[i].Name < changes[j].Name
+			}
+			return changes[i].Version < changes[j].Version
+		})
+	}
+	return summary
+}
+
+// lockPathName returns the package name at a lock path, e.g. "b" for
+// "node_modules/a/node_modules/b"
+func lockPathName(pkgPath string) string {
+	if i := strings.LastIndex(pkgPath, "node_modules/"); i >= 0 {
+		return pkgPath[i+len("node_modules/"):]
+	}
+	return pkgPath
+}
+
+// timingTop is how many of the slowest packages --timing prints
+const timingTop = 10
+
+// Finish prints the summary of the command: the installed packages, or with
+// --json the packages added, removed and updated since it started.
+// With --timing the slowest packages and phase totals follow.
+func (pm *PackageManager) Finish() {
+	pm.progress.SetSummary(pm.summary())
+	pm.progress.Finish()
+	pm.timing.Write(pm.stdout(), timingTop)
+}
+
+// FinishResolution ends a --resolution-only install, which writes the lock
+// file without placing packages in node_modules. With --json the summary
+// reports the changes to the lock.
+func (pm *PackageManager) FinishResolution() {
+	if pm.progress.JSON() {
+		pm.Finish()
+		return
+	}
+
+	pm.progress.Stop()
+	count := 0
+	if pm.packageLock != nil {
+		for pkgPath := range pm.packageLock.Packages {
+			if pkgPath != "" {
+				count++
+			}
+		}
+	}
+	fmt.Printf("%s written with %d packages, node_modules left untouched\n", pm.packageJsonParse.LockFileName, count)
+	pm.timing.Write(pm.stdout(), timingTop)
+}
+
+// warn prints a warning, and records it for the JSON summary. In JSON mode
+// it is only recorded, keeping stdout to the summary.
+func (pm *PackageManager) warn(kind, format string, args ...any) {
+	message := fmt.Sprintf(format, args...)
+	pm.progress.Report(kind, message)
+	if !pm.progress.JSON() {
+		pm.logger.Warnf("%s", message)
+	}
+}
+
+// stdout is where informational messages go: stderr in JSON mode, so that
+// stdout only holds the summary
+func (pm *PackageManager) stdout() io.Writer {
+	if pm.progress.JSON() {
+		return os.Stderr
+	}
+	return os.Stdout
+}
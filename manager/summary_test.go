@@ -0,0 +1,41 @@
+package manager
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/ernesto27/go-npm/progress"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstallFromCacheJSONSummary(t *testing.T) {
+	pm, _, origDir := setupTestPackageManager(t)
+	defer os.Chdir(origDir)
+
+	var out bytes.Buffer
+	pm.progress.SetJSON(true)
+	pm.progress.SetOutput(&out)
+
+	// The previous lock had an older b and a package since removed
+	pm.previousPackages = map[string]packagejson.PackageItem{
+		"":                              {Name: "root"},
+		"node_modules/a/node_modules/b": {Name: "b", Version: "1.0.0"},
+		"node_modules/old":              {Name: "old", Version: "0.1.0"},
+	}
+	setupAtomicInstall(t, pm)
+	pm.progress.Report(progress.WarningSkippedOptional, "Skipping optional dependency fsevents")
+
+	require.NoError(t, pm.InstallFromCache())
+
+	var summary progress.Summary
+	require.NoError(t, json.Unmarshal(out.Bytes(), &summary), "stdout should hold a single JSON object: %s", out.String())
+	assert.Equal(t, []progress.Change{{Name: "@scope/c", Version: "1.0.0"}, {Name: "a", Version: "1.0.0"}}, summary.Added)
+	assert.Equal(t, []progress.Change{{Name: "old", Version: "0.1.0"}}, summary.Removed)
+	assert.Equal(t, []progress.Change{{Name: "b", Version: "2.0.0", Previous: "1.0.0"}}, summary.Updated)
+	assert.Equal(t, 3, summary.Total)
+	assert.Equal(t, []progress.Warning{{Kind: progress.WarningSkippedOptional, Message: "Skipping optional dependency fsevents"}}, summary.Warnings)
+}
@@ -0,0 +1,87 @@
+package manager
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/ernesto27/go-npm/integrity"
+	"github.com/ernesto27/go-npm/tarball"
+	"github.com/ernesto27/go-npm/utils"
+)
+
+// isTarballURL reports whether spec is a direct http(s) tarball URL such as
+// https://example.com/pkg-1.2.3.tgz. Git URLs are matched before this.
+func isTarballURL(spec string) bool {
+	parsed, err := url.Parse(spec)
+	if err != nil || parsed.Host == "" {
+		return false
+	}
+	return parsed.Scheme == "http" || parsed.Scheme == "https"
+}
+
+// fetchTarballURL downloads and extracts the tarball at tarballURL into the
+// package cache and returns the version read from the tarball's package.json
+// and the tarball's sha512 integrity. It is cached as
+// name@<tarball.CacheVersion>, apart from the registry package of the same
+// version.
+func (pm *PackageManager) fetchTarballURL(name, tarballURL string) (string, string, error) {
+	urlVersion := tarball.URLCacheVersion(tarballURL)
+
+	// Lock the cache entry so dependents sharing the URL, and other go-npm
+	// processes with --cache-lock, download and extract it once
+	unlock, err := pm.lockPackage(name + "@" + urlVersion)
+	if err != nil {
+		return "", "", err
+	}
+	defer unlock()
+
+	tarballFilename := generateUniqueTarballName(name, urlVersion)
+	tarballPath := filepath.Join(pm.tarball.TarballPath, tarballFilename)
+	if !utils.ValidateTarball(tarballPath) {
+		os.Remove(tarballPath)
+		if pm.config.Offline {
+			return "", "", errNotCached(name, tarballURL)
+		}
+		if _, err := pm.tarball.DownloadAs(tarballURL, tarballFilename); err != nil {
+			return "", "", fmt.Errorf("failed to download %s: %w", tarballURL, err)
+		}
+	}
+
+	sri, err := integrity.GenerateIntegrity(tarballPath, []string{"sha512"})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to hash %s: %w", tarballURL, err)
+	}
+
+	stagingPath := filepath.Join(pm.packagesPath, name+"@"+urlVersion+".tmp")
+	if err := os.RemoveAll(stagingPath); err != nil {
+		return "", "", fmt.Errorf("failed to remove %s: %w", stagingPath, err)
+	}
+	defer os.RemoveAll(stagingPath)
+
+	if err := pm.extractPackage(tarballPath, stagingPath); err != nil {
+		return "", "", fmt.Errorf("failed to extract %s: %w", tarballURL, err)
+	}
+
+	data, err := pm.packageJsonParse.Parse(filepath.Join(stagingPath, "package.json"))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read package.json of %s: %w", tarballURL, err)
+	}
+	version, _ := data.Version.(string)
+	if version == "" {
+		return "", "", fmt.Errorf("tarball %s has no version in package.json", tarballURL)
+	}
+
+	packagePath := filepath.Join(pm.packagesPath, name+"@"+tarball.CacheVersion(name, version, tarballURL))
+	if !utils.FolderExists(packagePath) {
+		if err := os.MkdirAll(filepath.Dir(packagePath), 0755); err != nil {
+			return "", "", fmt.Errorf("failed to create %s: %w", filepath.Dir(packagePath), err)
+		}
+		if err := os.Rename(stagingPath, packagePath); err != nil {
+			return "", "", fmt.Errorf("failed to move %s into the cache: %w", tarballURL, err)
+		}
+	}
+
+	return version, sri, nil
+}
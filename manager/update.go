@@ -0,0 +1,260 @@
+package manager
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/ernesto27/go-npm/packagejson"
+)
+
+// PackageUpdate is a direct dependency that update moves to a newer version
+type PackageUpdate struct {
+	Name     string
+	Kind     packagejson.DependencyKind
+	From     string // locked version
+	To       string
+	Range    string // package.json range
+	NewRange string // widened range with --latest, else Range
+}
+
+// updateTarget is a direct dependency whose registry manifest is looked up
+type updateTarget struct {
+	name string
+	kind packagejson.DependencyKind
+	spec string
+}
+
+// Update moves the named direct dependencies, or all of them when names is
+// empty, to the newest version satisfying their package.json range in the
+// refreshed registry manifest, and rewrites the lock and node_modules. The
+// ranges are kept unless latest is set, which widens each one to the
+// version tagged latest.
+func (pm *PackageManager) Update(names []string, latest bool) ([]PackageUpdate, error) {
+	data, err := pm.packageJsonParse.ParseDefault()
+	if err != nil {
+		return nil, err
+	}
+	lock := pm.packageJsonParse.PackageLock
+	if lock == nil {
+		return nil, fmt.Errorf("%s not found, run go-npm install first", pm.packageJsonParse.LockFileName)
+	}
+
+	targets, err := updateTargets(data, names)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := pm.lookupUpdates(targets, latest)
+
+	var updates []PackageUpdate
+	for _, update := range candidates {
+		update.From = lock.Packages["node_modules/"+update.Name].Version
+		if update.NewRange != update.Range || newerVersion(update.To, update.From) {
+			updates = append(updates, update)
+		}
+	}
+
+	locked := make(map[string]string, len(lock.Packages))
+	for pkgPath, item := range lock.Packages {
+		locked[pkgPath] = item.Version
+	}
+
+	for _, update := range updates {
+		if err := pm.Add(update.Name, update.NewRange, update.Kind, true); err != nil {
+			return nil, fmt.Errorf("failed to update %s: %w", update.Name, err)
+		}
+	}
+
+	if len(updates) > 0 {
+		// InstallFromCache keeps what is already in node_modules, so packages
+		// that moved to another version are removed first
+		var stale []string
+		for pkgPath, item := range pm.packageLock.Packages {
+			if version, ok := locked[pkgPath]; ok && version != item.Version && !item.Link {
+				stale = append(stale, strings.TrimPrefix(pkgPath, "node_modules/"))
+			}
+		}
+		if err := pm.removePackagesFromNodeModules(stale); err != nil {
+			return nil, err
+		}
+		if err := pm.InstallFromCache(); err != nil {
+			return nil, err
+		}
+	}
+
+	return updates, nil
+}
+
+// updateTargets returns the direct dependencies of data named in names, or
+// every registry dependency when names is empty. A named dependency that is
+// missing or not installed from the registry is an error.
+func updateTargets(data *packagejson.PackageJSON, names []string) ([]updateTarget, error) {
+	kinds := []packagejson.DependencyKind{
+		packagejson.DependencyProd,
+		packagejson.DependencyDev,
+		packagejson.DependencyOptional,
+	}
+
+	var all []updateTarget
+	for _, kind := range kinds {
+		for name, spec := range data.GetDependenciesOfKind(kind) {
+			all = append(all, updateTarget{name: name, kind: kind, spec: spec})
+		}
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].name < all[j].name })
+
+	if len(names) == 0 {
+		var targets []updateTarget
+		for _, target := range all {
+			if isRegistrySpec(target.spec) {
+				targets = append(targets, target)
+			}
+		}
+		return targets, nil
+	}
+
+	var targets []updateTarget
+	for _, name := range names {
+		i := sort.Search(len(all), func(i int) bool { return all[i].name >= name })
+		if i == len(all) || all[i].name != name {
+			return nil, fmt.Errorf("%s is not a dependency in package.json", name)
+		}
+		if !isRegistrySpec(all[i].spec) {
+			return nil, fmt.Errorf("cannot update %s: %q is not a registry version range", name, all[i].spec)
+		}
+		targets = append(targets, all[i])
+	}
+	return targets, nil
+}
+
+// isRegistrySpec reports whether spec is resolved against the registry
+// manifest of the package itself: a range, version or dist-tag
+func isRegistrySpec(spec string) bool {
+	return !isRemoteSpec(spec) && !strings.HasPrefix(spec, "workspace:") && !strings.HasPrefix(spec, "npm:")
+}
+
+// lookupUpdates refreshes the registry manifest of each target concurrently
+// and returns the version each one would update to. Targets whose manifest
+// cannot be fetched, or has no version in range, are reported and skipped.
+func (pm *PackageManager) lookupUpdates(targets []updateTarget, latest bool) []PackageUpdate {
+	results := make([]*PackageUpdate, len(targets))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, max(pm.concurrency, 1))
+	for i, target := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			update, err := pm.lookupUpdate(target, latest)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: skipping %s: %v\n", target.name, err)
+				return
+			}
+			results[i] = update
+		}()
+	}
+	wg.Wait()
+
+	var updates []PackageUpdate
+	for _, update := range results {
+		if update != nil {
+			updates = append(updates, *update)
+		}
+	}
+	return updates
+}
+
+// lookupUpdate downloads the current manifest of target and resolves its
+// range, and with latest its widened range, against it
+func (pm *PackageManager) lookupUpdate(target updateTarget, latest bool) (*PackageUpdate, error) {
+	if !pm.config.Offline {
+		if _, _, err := pm.manifest.Download(target.name, pm.Etag.Get(pm.manifest.EtagKey(target.name))); err != nil {
+			return nil, err
+		}
+	}
+
+	npmPackage, err := pm.parseJsonManifest.Parse(pm.manifest.FilePath(target.name))
+	if err != nil {
+		return nil, err
+	}
+
+	update := &PackageUpdate{Name: target.name, Kind: target.kind, Range: target.spec, NewRange: target.spec}
+	if latest {
+		if newest := npmPackage.DistTags["latest"]; newest != "" {
+			update.NewRange = widenRange(target.spec, newest)
+		}
+	}
+
+	if !pm.matchesManifest(update.NewRange, npmPackage) {
+		return nil, fmt.Errorf("no published version satisfies %s", update.NewRange)
+	}
+	update.To = pm.versionInfo.GetVersion(update.NewRange, npmPackage)
+
+	return update, nil
+}
+
+// widenRange returns spec moved up to newest with the same kind of range:
+// ~ stays ~, an exact version stays exact and anything else becomes ^.
+// Dist-tags and ranges that already allow newest are kept.
+func widenRange(spec, newest string) string {
+	newestVersion, err := semver.NewVersion(newest)
+	if err != nil {
+		return spec
+	}
+	constraint, err := semver.NewConstraint(spec)
+	if err != nil || constraint.Check(newestVersion) {
+		return spec
+	}
+
+	switch {
+	case strings.HasPrefix(spec, "~"):
+		return "~" + newest
+	case isExactVersion(spec):
+		return newest
+	default:
+		return "^" + newest
+	}
+}
+
+// isExactVersion reports whether spec is a plain version, like 1.2.3 or =1.2.3
+func isExactVersion(spec string) bool {
+	_, err := semver.StrictNewVersion(strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(spec), "="), "v"))
+	return err == nil
+}
+
+// newerVersion reports whether to is a higher version than from, or from is
+// not a version at all
+func newerVersion(to, from string) bool {
+	toVersion, err := semver.NewVersion(to)
+	if err != nil {
+		return false
+	}
+	fromVersion, err := semver.NewVersion(from)
+	if err != nil {
+		return true
+	}
+	return toVersion.GreaterThan(fromVersion)
+}
+
+// PrintUpdates lists the updated packages, with their widened ranges
+func PrintUpdates(updates []PackageUpdate) {
+	if len(updates) == 0 {
+		fmt.Println("All packages are up to date")
+		return
+	}
+
+	for _, update := range updates {
+		line := fmt.Sprintf("%s %s → %s", update.Name, update.From, update.To)
+		if update.NewRange != update.Range {
+			line += fmt.Sprintf(" (%s %s → %s)", update.Kind.Section(), update.Range, update.NewRange)
+		}
+		fmt.Println(line)
+	}
+}
@@ -0,0 +1,69 @@
+package manager
+
+import (
+	"fmt"
+	"maps"
+
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/ernesto27/go-npm/workspace"
+)
+
+// installWorkspaces prepares an install limited to the workspace packages
+// selected by --workspace or --workspaces and the packages they depend on.
+// The subtree is taken from the lock file when it has every selected
+// workspace, and resolved from the registry otherwise. The lock file is not
+// written, since it would no longer describe the whole project.
+func (pm *PackageManager) installWorkspaces(data *packagejson.PackageJSON) error {
+	if pm.workspaceRegistry == nil {
+		return fmt.Errorf("no workspaces defined in package.json")
+	}
+
+	selected := pm.workspaceRegistry.Sorted()
+	if len(pm.config.Workspaces) > 0 {
+		var err error
+		if selected, err = pm.workspaceRegistry.Select(pm.config.Workspaces); err != nil {
+			return err
+		}
+	}
+
+	if err := pm.CreateWorkspaceSymlinks(); err != nil {
+		return err
+	}
+
+	roots := make([]string, 0, len(selected))
+	for _, ws := range selected {
+		roots = append(roots, ws.Name)
+	}
+
+	if lock := pm.packageJsonParse.PackageLock; lock != nil && lockHasWorkspaces(lock, selected) &&
+		maps.Equal(data.GetOverrides(), lock.Overrides) && maps.Equal(data.GetResolutions(), lock.Resolutions) {
+		pm.packageLock = lock.Subtree(roots)
+		return nil
+	}
+
+	dependencies := make(map[string]string, len(selected))
+	for _, ws := range selected {
+		dependencies[ws.Name] = ws.Version
+	}
+	filtered := packagejson.PackageJSON{
+		Name:         data.Name,
+		Version:      data.Version,
+		Dependencies: dependencies,
+		Overrides:    data.Overrides,
+		Resolutions:  data.Resolutions,
+	}
+
+	return pm.fetchToCache(filtered)
+}
+
+// lockHasWorkspaces reports whether lock holds every workspace in selected
+// at its current version
+func lockHasWorkspaces(lock *packagejson.PackageLock, selected []*workspace.Workspace) bool {
+	for _, ws := range selected {
+		item, ok := lock.Packages["node_modules/"+ws.Name]
+		if !ok || !item.Link || item.Version != ws.Version {
+			return false
+		}
+	}
+	return true
+}
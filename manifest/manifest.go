@@ -1,13 +1,47 @@
 package manifest
 
 import (
-	"github.com/ernesto27/go-npm/utils"
+	"context"
+	"fmt"
 	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ernesto27/go-npm/utils"
 )
 
+// FetchMode selects which manifest document is requested from the registry
+type FetchMode string
+
+const (
+	// FetchFull requests the full manifest, with every field of every version
+	FetchFull FetchMode = "full"
+	// FetchAbbreviated requests the smaller install manifest (corgi), which
+	// only keeps the fields needed to resolve and install a version
+	FetchAbbreviated FetchMode = "abbreviated"
+)
+
+// abbreviatedAccept is the Accept header npm sends for abbreviated manifests
+const abbreviatedAccept = "application/vnd.npm.install-v1+json; q=1.0, application/json; q=0.8, */*"
+
+// ParseFetchMode checks that mode is full or abbreviated
+func ParseFetchMode(mode string) (FetchMode, error) {
+	switch FetchMode(mode) {
+	case FetchFull, FetchAbbreviated:
+		return FetchMode(mode), nil
+	}
+	return "", fmt.Errorf("unknown manifest fetch mode %q (expected full or abbreviated)", mode)
+}
+
 type Manifest struct {
 	npmResgistryURL string
+	fallbacks       []string
+	scopeRegistries map[string]string
 	Path            string
+	retryPolicy     utils.RetryPolicy
+	ctx             context.Context
+	offline         bool
+	mode            FetchMode
 }
 
 func NewManifest(configPath string, npmRegistryURL string) (*Manifest, error) {
@@ -19,14 +53,141 @@ func NewManifest(configPath string, npmRegistryURL string) (*Manifest, error) {
 	return &Manifest{
 		Path:            pathM,
 		npmResgistryURL: npmRegistryURL,
+		retryPolicy:     utils.DefaultRetryPolicy(),
+		ctx:             context.Background(),
+		mode:            FetchFull,
 	}, nil
 }
 
+// RegistryURL returns the base URL manifests are downloaded from
+func (m *Manifest) RegistryURL() string {
+	return m.npmResgistryURL
+}
+
+// Registries returns the registry and then its fallbacks, in the order
+// manifests are looked up on them
+func (m *Manifest) Registries() []string {
+	return append([]string{m.npmResgistryURL}, m.fallbacks...)
+}
+
+// SetFallbackRegistries sets the registries tried in order when the registry
+// cannot be reached or does not have a package
+func (m *Manifest) SetFallbackRegistries(registries []string) {
+	m.fallbacks = registries
+}
+
+// SetScopeRegistries routes the packages of each scope ("@myorg") to its
+// own registry. Scoped packages never fall back to other registries, so a
+// private package cannot be swapped for a public one of the same name.
+func (m *Manifest) SetScopeRegistries(registries map[string]string) {
+	m.scopeRegistries = registries
+}
+
+// ScopeRegistries returns the registry set for each scope
+func (m *Manifest) ScopeRegistries() map[string]string {
+	return m.scopeRegistries
+}
+
+// ScopeRegistry returns the registry of the scope of pkg, if one is set
+func (m *Manifest) ScopeRegistry(pkg string) (string, bool) {
+	scope, _, ok := strings.Cut(pkg, "/")
+	if !ok || !strings.HasPrefix(scope, "@") {
+		return "", false
+	}
+	registry, ok := m.scopeRegistries[scope]
+	return registry, ok
+}
+
+// SetRetries sets how many times a failed manifest download is retried
+func (m *Manifest) SetRetries(retries int) {
+	m.retryPolicy.Retries = retries
+}
+
+// SetTimeout sets how long a single manifest request may take before it is
+// abandoned and retried; zero waits indefinitely
+func (m *Manifest) SetTimeout(timeout time.Duration) {
+	m.retryPolicy.Timeout = timeout
+}
+
+// SetRateLimiters sets the limiters manifest requests wait on, one per
+// registry; nil disables them
+func (m *Manifest) SetRateLimiters(limiters *utils.RateLimiters) {
+	m.retryPolicy.Limiters = limiters
+}
+
+// RetryPolicy returns the policy manifest requests are made with, so that
+// other registry requests share its retries, timeout and limiter
+func (m *Manifest) RetryPolicy() utils.RetryPolicy {
+	return m.retryPolicy
+}
+
+// SetContext sets the context that cancels in-flight manifest downloads
+func (m *Manifest) SetContext(ctx context.Context) {
+	m.ctx = ctx
+}
+
+// SetOffline makes Download fail instead of making requests
+func (m *Manifest) SetOffline(offline bool) {
+	m.offline = offline
+}
+
+// SetFetchMode sets which manifest document Download requests
+func (m *Manifest) SetFetchMode(mode FetchMode) {
+	m.mode = mode
+}
+
+// FilePath returns where the manifest of pkg is cached for the current fetch
+// mode. Abbreviated manifests live in their own directory so that switching
+// modes never reads a document of the other kind.
+func (m *Manifest) FilePath(pkg string) string {
+	if m.mode == FetchAbbreviated {
+		return filepath.Join(m.Path, string(FetchAbbreviated), pkg+".json")
+	}
+	return filepath.Join(m.Path, pkg+".json")
+}
+
+// EtagKey returns the key the etag of the manifest of pkg is stored under for
+// the current fetch mode. The registry sends a different etag for each
+// document, so sharing a key would answer a full request with a 304 for the
+// abbreviated one.
+func (m *Manifest) EtagKey(pkg string) string {
+	if m.mode == FetchAbbreviated {
+		return string(FetchAbbreviated) + ":" + pkg
+	}
+	return pkg
+}
+
 func (m *Manifest) Download(pkg string, currentEtag string) (string, int, error) {
-	url := m.npmResgistryURL + pkg
-	filename := filepath.Join(m.Path, pkg+".json")
+	if m.offline {
+		return "", 0, fmt.Errorf("%w: no cached manifest for %s", utils.ErrOffline, pkg)
+	}
+
+	filename := m.FilePath(pkg)
 
-	eTag, statusCode, err := utils.DownloadFile(url, filename, currentEtag)
+	var headers map[string]string
+	if m.mode == FetchAbbreviated {
+		headers = map[string]string{"Accept": abbreviatedAccept}
+	}
+
+	registries := m.Registries()
+	if registry, ok := m.ScopeRegistry(pkg); ok {
+		registries = []string{registry}
+	}
+	for i, registry := range registries {
+		url := registry + EscapePackageName(pkg)
+		eTag, statusCode, err := utils.DownloadFileWithRetryHeadersContext(m.ctx, url, filename, currentEtag, headers, m.retryPolicy)
+		if i == len(registries)-1 || !utils.RegistryUnavailable(m.ctx, statusCode, err) {
+			return eTag, statusCode, err
+		}
+	}
+	return "", 0, nil
+}
 
-	return eTag, statusCode, err
+// EscapePackageName encodes the slash of a scoped package name (@scope/name ->
+// @scope%2fname), the form registries and proxies expect in manifest requests
+func EscapePackageName(pkg string) string {
+	if strings.HasPrefix(pkg, "@") {
+		return strings.Replace(pkg, "/", "%2f", 1)
+	}
+	return pkg
 }
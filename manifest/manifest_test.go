@@ -3,10 +3,14 @@ package manifest
 import (
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/ernesto27/go-npm/utils"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -103,3 +107,252 @@ func TestDownloadManifest_Download(t *testing.T) {
 		})
 	}
 }
+
+func TestDownloadManifest_DownloadRetry(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) <= 2 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("ETag", `"retry-etag"`)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"name": "retry-pkg"}`))
+	}))
+	defer server.Close()
+
+	m, err := NewManifest(setupTestDirs(t), server.URL+"/")
+	assert.NoError(t, err)
+	m.retryPolicy.BaseDelay = time.Millisecond
+
+	etag, statusCode, err := m.Download("retry-pkg", "")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.Equal(t, `"retry-etag"`, etag)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+
+	content, err := os.ReadFile(filepath.Join(m.Path, "retry-pkg.json"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "retry-pkg")
+}
+
+func TestDownloadManifest_FallbackRegistries(t *testing.T) {
+	// A registry that is down: its connections are refused
+	down := httptest.NewServer(http.NotFoundHandler())
+	downURL := down.URL + "/"
+	down.Close()
+
+	missing := httptest.NewServer(http.NotFoundHandler())
+	defer missing.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer failing.Close()
+
+	var mirrorRequests int32
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&mirrorRequests, 1)
+		w.Write([]byte(`{"name": "pkg"}`))
+	}))
+	defer mirror.Close()
+
+	testCases := []struct {
+		name           string
+		primary        string
+		fallbacks      []string
+		expectError    bool
+		mirrorRequests int32
+	}{
+		{
+			name:           "Falls back past a registry that is down and one without the package",
+			primary:        downURL,
+			fallbacks:      []string{missing.URL + "/", mirror.URL + "/"},
+			mirrorRequests: 1,
+		},
+		{
+			name:           "Does not fall back on other HTTP errors",
+			primary:        failing.URL + "/",
+			fallbacks:      []string{mirror.URL + "/"},
+			expectError:    true,
+			mirrorRequests: 0,
+		},
+		{
+			name:        "Fails once every registry is exhausted",
+			primary:     downURL,
+			fallbacks:   []string{missing.URL + "/"},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			atomic.StoreInt32(&mirrorRequests, 0)
+			m, err := NewManifest(setupTestDirs(t), tc.primary)
+			assert.NoError(t, err)
+			m.SetRetries(0)
+			m.SetFallbackRegistries(tc.fallbacks)
+
+			_, statusCode, err := m.Download("pkg", "")
+			assert.Equal(t, tc.mirrorRequests, atomic.LoadInt32(&mirrorRequests))
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusOK, statusCode)
+			assert.FileExists(t, filepath.Join(m.Path, "pkg.json"))
+		})
+	}
+}
+
+func TestDownloadManifest_ScopeRegistries(t *testing.T) {
+	var defaultPaths, scopePaths []string
+	defaultRegistry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defaultPaths = append(defaultPaths, r.URL.Path)
+		w.Write([]byte(`{"name": "pkg"}`))
+	}))
+	defer defaultRegistry.Close()
+	scopeRegistry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scopePaths = append(scopePaths, r.URL.Path)
+		http.NotFound(w, r)
+	}))
+	defer scopeRegistry.Close()
+
+	m, err := NewManifest(setupTestDirs(t), defaultRegistry.URL+"/")
+	assert.NoError(t, err)
+	m.SetRetries(0)
+	m.SetFallbackRegistries([]string{defaultRegistry.URL + "/"})
+	m.SetScopeRegistries(map[string]string{"@myorg": scopeRegistry.URL + "/"})
+
+	_, _, err = m.Download("express", "")
+	assert.NoError(t, err)
+	_, _, err = m.Download("@types/node", "")
+	assert.NoError(t, err)
+
+	// A scoped package missing from its registry never falls back
+	_, _, err = m.Download("@myorg/lib", "")
+	assert.Error(t, err)
+
+	assert.Equal(t, []string{"/express", "/@types/node"}, defaultPaths)
+	assert.Equal(t, []string{"/@myorg/lib"}, scopePaths)
+}
+
+func TestDownloadManifest_ScopedPackagePath(t *testing.T) {
+	var requestURI string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestURI = r.RequestURI
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"name": "@types/node"}`))
+	}))
+	defer server.Close()
+
+	m, err := NewManifest(setupTestDirs(t), server.URL+"/")
+	assert.NoError(t, err)
+
+	_, statusCode, err := m.Download("@types/node", "")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.Equal(t, "/@types%2fnode", requestURI)
+
+	// The cached manifest keeps the unescaped name so lookups stay unchanged
+	_, err = os.Stat(filepath.Join(m.Path, "@types", "node.json"))
+	assert.NoError(t, err)
+}
+
+func TestEscapePackageName(t *testing.T) {
+	assert.Equal(t, "express", EscapePackageName("express"))
+	assert.Equal(t, "@types%2fnode", EscapePackageName("@types/node"))
+	assert.Equal(t, "@babel%2fcore", EscapePackageName("@babel/core"))
+}
+
+func TestDownloadManifest_Offline(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Write([]byte(`{"name": "pkg"}`))
+	}))
+	defer server.Close()
+
+	m, err := NewManifest(setupTestDirs(t), server.URL+"/")
+	assert.NoError(t, err)
+	m.SetOffline(true)
+
+	_, _, err = m.Download("pkg", "")
+	assert.ErrorIs(t, err, utils.ErrOffline)
+	assert.ErrorContains(t, err, "no cached manifest for pkg")
+	assert.Equal(t, int32(0), atomic.LoadInt32(&requests))
+	assert.NoFileExists(t, filepath.Join(m.Path, "pkg.json"))
+}
+
+func TestDownloadManifest_FetchModesAreCachedSeparately(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		etag, body := `"full-etag"`, `{"name": "pkg", "readme": "full"}`
+		if r.Header.Get("Accept") == abbreviatedAccept {
+			etag, body = `"abbreviated-etag"`, `{"name": "pkg"}`
+		}
+		requests = append(requests, etag+" "+r.Header.Get("If-None-Match"))
+
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	m, err := NewManifest(setupTestDirs(t), server.URL+"/")
+	assert.NoError(t, err)
+
+	// etags stands in for the etag store, keyed the way the manager keys it
+	etags := make(map[string]string)
+	download := func(mode FetchMode) int {
+		m.SetFetchMode(mode)
+		etag, statusCode, err := m.Download("pkg", etags[m.EtagKey("pkg")])
+		assert.NoError(t, err)
+		etags[m.EtagKey("pkg")] = etag
+		return statusCode
+	}
+
+	assert.Equal(t, http.StatusOK, download(FetchAbbreviated))
+	abbreviatedPath, abbreviatedKey := m.FilePath("pkg"), m.EtagKey("pkg")
+
+	// The abbreviated etag is not sent for the full manifest, which would be
+	// answered with a 304 for a document that was never downloaded
+	assert.Equal(t, http.StatusOK, download(FetchFull))
+	fullPath, fullKey := m.FilePath("pkg"), m.EtagKey("pkg")
+
+	assert.NotEqual(t, abbreviatedPath, fullPath)
+	assert.NotEqual(t, abbreviatedKey, fullKey)
+	assert.Equal(t, filepath.Join(m.Path, "pkg.json"), fullPath)
+	assert.Equal(t, `"abbreviated-etag"`, etags[abbreviatedKey])
+	assert.Equal(t, `"full-etag"`, etags[fullKey])
+
+	abbreviated, err := os.ReadFile(abbreviatedPath)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"name": "pkg"}`, string(abbreviated))
+	full, err := os.ReadFile(fullPath)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"name": "pkg", "readme": "full"}`, string(full))
+
+	// Each mode revalidates with its own etag
+	assert.Equal(t, http.StatusNotModified, download(FetchAbbreviated))
+	assert.Equal(t, http.StatusNotModified, download(FetchFull))
+
+	assert.Equal(t, []string{
+		`"abbreviated-etag" `,
+		`"full-etag" `,
+		`"abbreviated-etag" "abbreviated-etag"`,
+		`"full-etag" "full-etag"`,
+	}, requests)
+}
+
+func TestParseFetchMode(t *testing.T) {
+	mode, err := ParseFetchMode("abbreviated")
+	assert.NoError(t, err)
+	assert.Equal(t, FetchAbbreviated, mode)
+
+	_, err = ParseFetchMode("corgi")
+	assert.ErrorContains(t, err, `unknown manifest fetch mode "corgi"`)
+}
@@ -1,5 +1,7 @@
 package manifest
 
+import "encoding/json"
+
 type NPMPackage struct {
 	ID       string             `json:"_id"`
 	Rev      string             `json:"_rev"`
@@ -59,6 +61,20 @@ type Version struct {
 	Files                  any                    `json:"files"`
 	NPMOperationalInternal NPMOperationalInternal `json:"_npmOperationalInternal"`
 	NPMSignature           string                 `json:"npm-signature"`
+	Deprecated             Deprecation            `json:"deprecated"`
+}
+
+// Deprecation is the message a publisher deprecated a version with. Some
+// registries send false instead of leaving it out, which reads as empty.
+type Deprecation string
+
+func (d *Deprecation) UnmarshalJSON(data []byte) error {
+	var message string
+	if err := json.Unmarshal(data, &message); err != nil {
+		message = ""
+	}
+	*d = Deprecation(message)
+	return nil
 }
 
 type PeerMeta struct {
@@ -0,0 +1,167 @@
+package pack
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ignoreRule is one pattern of a .npmignore or .gitignore file
+type ignoreRule struct {
+	pattern *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// IgnoreRules are the gitignore-style patterns of one ignore file. Paths
+// are matched relative to the directory holding the file, and the last
+// matching pattern wins, so a later !pattern re-includes what an earlier
+// one excluded.
+type IgnoreRules struct {
+	rules []ignoreRule
+}
+
+// ParseIgnore reads gitignore-style patterns from r
+func ParseIgnore(r io.Reader) (*IgnoreRules, error) {
+	ignore := &IgnoreRules{}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var rule ignoreRule
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+			line = line[1:]
+		}
+
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+
+		// A pattern with a slash is relative to the ignore file's directory;
+		// one without matches a name at any depth
+		anchored := strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+
+		expr := globToRegexp(line)
+		if !anchored {
+			expr = "(?:.*/)?" + expr
+		}
+		pattern, err := regexp.Compile("^" + expr + "$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid ignore pattern %q: %w", scanner.Text(), err)
+		}
+		rule.pattern = pattern
+
+		ignore.rules = append(ignore.rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read ignore patterns: %w", err)
+	}
+
+	return ignore, nil
+}
+
+// LoadIgnore parses the .npmignore of dir, or its .gitignore when it has no
+// .npmignore, the way npm picks them. It returns nil when dir has neither.
+func LoadIgnore(dir string) (*IgnoreRules, error) {
+	for _, name := range []string{".npmignore", ".gitignore"} {
+		file, err := os.Open(filepath.Join(dir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", name, err)
+		}
+		defer file.Close()
+
+		ignore, err := ParseIgnore(file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Join(dir, name), err)
+		}
+		return ignore, nil
+	}
+	return nil, nil
+}
+
+// Ignored reports whether relPath, slash-separated and relative to the
+// directory of the ignore file, is excluded
+func (i *IgnoreRules) Ignored(relPath string, isDir bool) bool {
+	ignored, _ := i.match(relPath, isDir)
+	return ignored
+}
+
+// match reports whether relPath is excluded, and whether any pattern
+// decided it at all
+func (i *IgnoreRules) match(relPath string, isDir bool) (ignored, matched bool) {
+	for _, rule := range i.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		if rule.pattern.MatchString(relPath) {
+			ignored, matched = !rule.negate, true
+		}
+	}
+	return ignored, matched
+}
+
+// globToRegexp translates a gitignore glob to a regular expression: * and ?
+// stay within a path segment, ** spans segments and [...] is a character class
+func globToRegexp(glob string) string {
+	var expr strings.Builder
+
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			if strings.HasPrefix(glob[i:], "**") {
+				switch {
+				case strings.HasPrefix(glob[i:], "**/"):
+					expr.WriteString("(?:.*/)?")
+					i += 2
+				default:
+					expr.WriteString(".*")
+					i++
+				}
+				continue
+			}
+			expr.WriteString("[^/]*")
+		case '?':
+			expr.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				expr.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expr.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+				expr.WriteString(regexp.QuoteMeta(string(glob[i])))
+			}
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	return expr.String()
+}
@@ -0,0 +1,43 @@
+package pack
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIgnoreRules(t *testing.T) {
+	testCases := []struct {
+		name     string
+		patterns string
+		path     string
+		isDir    bool
+		expected bool
+	}{
+		{name: "name matches at any depth", patterns: "*.log", path: "logs/debug.log", expected: true},
+		{name: "star stays within a segment", patterns: "/docs/*.md", path: "docs/api/index.md", expected: false},
+		{name: "leading slash anchors to the root", patterns: "/build", path: "src/build", isDir: true, expected: false},
+		{name: "anchored directory", patterns: "/build", path: "build", isDir: true, expected: true},
+		{name: "trailing slash only matches directories", patterns: "coverage/", path: "coverage", expected: false},
+		{name: "trailing slash matches a nested directory", patterns: "coverage/", path: "src/coverage", isDir: true, expected: true},
+		{name: "double star spans directories", patterns: "src/**/*.test.js", path: "src/a/b/c.test.js", expected: true},
+		{name: "leading double star", patterns: "**/fixtures", path: "test/unit/fixtures", isDir: true, expected: true},
+		{name: "trailing double star", patterns: "tmp/**", path: "tmp/a/b", expected: true},
+		{name: "negation re-includes", patterns: "*.md\n!README.md", path: "README.md", expected: false},
+		{name: "last matching pattern wins", patterns: "!keep.txt\n*.txt", path: "keep.txt", expected: true},
+		{name: "character class", patterns: "file[0-9].js", path: "file7.js", expected: true},
+		{name: "negated character class", patterns: "file[!0-9].js", path: "file7.js", expected: false},
+		{name: "comments and blank lines", patterns: "# *.js\n\n", path: "index.js", expected: false},
+		{name: "escaped hash", patterns: `\#notes`, path: "#notes", expected: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rules, err := ParseIgnore(strings.NewReader(tc.patterns))
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, rules.Ignored(tc.path, tc.isDir))
+		})
+	}
+}
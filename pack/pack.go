@@ -0,0 +1,303 @@
+package pack
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ernesto27/go-npm/packagejson"
+)
+
+// defaultIgnore lists what npm never packs, whatever the ignore files say
+var defaultIgnore = mustParseIgnore(`
+.npmignore
+.gitignore
+.git
+.svn
+.hg
+CVS
+.DS_Store
+._*
+.*.swp
+*.orig
+npm-debug.log
+.npmrc
+node_modules
+/package-lock.json
+/yarn.lock
+/pnpm-lock.yaml
+/go-npm-lock.json
+/.lock-wscript
+/.wafpickle-*
+/build/config.gypi
+`)
+
+// mandatoryFile matches the root files npm always packs
+var mandatoryFile = regexp.MustCompile(`(?i)^(package\.json|(readme|license|licence)(\..*)?)$`)
+
+// packEpoch is the modification time npm gives every packed file, so packing
+// the same files twice produces the same tarball
+var packEpoch = time.Date(1985, time.October, 26, 8, 15, 0, 0, time.UTC)
+
+// ignoreLevel is the ignore file of a directory, relative to the package root
+type ignoreLevel struct {
+	dir   string
+	rules *IgnoreRules
+}
+
+// Files returns, sorted and slash-separated, the paths relative to dir of the
+// files packed for the package in dir. Each directory's .npmignore, or its
+// .gitignore when it has none, excludes files below it, on top of the files
+// npm never packs. When package.json has a "files" list, only the files it
+// names are packed and the root ignore file is not read, as with npm.
+// package.json, the README, the license and the main file are always packed.
+func Files(dir string, pkg *packagejson.PackageJSON) ([]string, error) {
+	allow, err := filesAllowlist(pkg.Files)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	if err := walk(dir, "", nil, allow, &files); err != nil {
+		return nil, err
+	}
+
+	packed := make(map[string]bool, len(files))
+	for _, file := range files {
+		packed[file] = true
+	}
+
+	var mandatory []string
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && mandatoryFile.MatchString(entry.Name()) {
+			mandatory = append(mandatory, entry.Name())
+		}
+	}
+	if main, ok := pkg.Main.(string); ok && main != "" {
+		main = path.Clean(filepath.ToSlash(main))
+		info, err := os.Lstat(filepath.Join(dir, filepath.FromSlash(main)))
+		if err == nil && info.Mode().IsRegular() && !strings.HasPrefix(main, "../") {
+			mandatory = append(mandatory, main)
+		}
+	}
+	for _, file := range mandatory {
+		if !packed[file] {
+			packed[file] = true
+			files = append(files, file)
+		}
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+// filesAllowlist compiles the "files" list of package.json. Its patterns are
+// relative to the package root; one naming a directory includes everything
+// below it, and a !pattern excludes again what an earlier one included. It
+// returns nil when the package has no list.
+func filesAllowlist(files any) (*IgnoreRules, error) {
+	list, ok := files.([]any)
+	if !ok {
+		return nil, nil
+	}
+
+	var patterns strings.Builder
+	for _, entry := range list {
+		pattern, ok := entry.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid \"files\" entry %v in package.json: expected a string", entry)
+		}
+		prefix := ""
+		if strings.HasPrefix(pattern, "!") {
+			prefix, pattern = "!", pattern[1:]
+		}
+		pattern = strings.Trim(strings.TrimPrefix(path.Clean("/"+pattern), "/"), "/")
+		if pattern == "" {
+			continue
+		}
+		fmt.Fprintf(&patterns, "%s/%s\n%s/%s/**\n", prefix, pattern, prefix, pattern)
+	}
+
+	allow, err := ParseIgnore(strings.NewReader(patterns.String()))
+	if err != nil {
+		return nil, fmt.Errorf("invalid \"files\" in package.json: %w", err)
+	}
+	return allow, nil
+}
+
+// walk adds the packed regular files below relDir to files, keeping only
+// those allow includes when it is set. Symlinks are not packed, as npm does
+// not follow them.
+func walk(root, relDir string, levels []ignoreLevel, allow *IgnoreRules, files *[]string) error {
+	absDir := filepath.Join(root, filepath.FromSlash(relDir))
+
+	// A "files" list replaces the root ignore file, not the nested ones
+	if relDir != "" || allow == nil {
+		rules, err := LoadIgnore(absDir)
+		if err != nil {
+			return err
+		}
+		if rules != nil {
+			levels = append(levels[:len(levels):len(levels)], ignoreLevel{dir: relDir, rules: rules})
+		}
+	}
+
+	entries, err := os.ReadDir(absDir)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", absDir, err)
+	}
+
+	for _, entry := range entries {
+		relPath := path.Join(relDir, entry.Name())
+		isDir := entry.IsDir()
+		if !isDir && !entry.Type().IsRegular() {
+			continue
+		}
+		if ignored(levels, relPath, isDir) {
+			continue
+		}
+
+		if isDir {
+			if err := walk(root, relPath, levels, allow, files); err != nil {
+				return err
+			}
+			continue
+		}
+		if allow != nil && !allow.Ignored(relPath, false) {
+			continue
+		}
+		*files = append(*files, relPath)
+	}
+
+	return nil
+}
+
+// ignored reports whether relPath is excluded. The files npm never packs stay
+// excluded; otherwise the deepest ignore file with a matching pattern decides.
+func ignored(levels []ignoreLevel, relPath string, isDir bool) bool {
+	if defaultIgnore.Ignored(relPath, isDir) {
+		return true
+	}
+
+	result := false
+	for _, level := range levels {
+		rel := relPath
+		if level.dir != "" {
+			rel = strings.TrimPrefix(relPath, level.dir+"/")
+		}
+		if excluded, matched := level.rules.match(rel, isDir); matched {
+			result = excluded
+		}
+	}
+	return result
+}
+
+// Filename is the name npm gives the tarball of name@version, with a scoped
+// name's @ dropped and its slash turned into a dash: @scope/lib 1.0.0 is
+// scope-lib-1.0.0.tgz
+func Filename(name, version string) string {
+	name = strings.ReplaceAll(strings.TrimPrefix(name, "@"), "/", "-")
+	return name + "-" + version + ".tgz"
+}
+
+// Write writes files, relative to dir, to w as a gzipped tarball with every
+// file under package/, the layout registries serve and the extractor strips.
+// A file in contents is written with that content instead of the one on disk.
+func Write(w io.Writer, dir string, files []string, contents map[string][]byte) error {
+	gzw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gzw)
+
+	for _, file := range files {
+		if content, ok := contents[file]; ok {
+			if err := writeContent(tw, file, content); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := writeFile(tw, dir, file); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to finalize tarball: %w", err)
+	}
+	if err := gzw.Close(); err != nil {
+		return fmt.Errorf("failed to finalize tarball: %w", err)
+	}
+	return nil
+}
+
+func writeFile(tw *tar.Writer, dir, file string) error {
+	filePath := filepath.Join(dir, filepath.FromSlash(file))
+
+	f, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", filePath, err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", filePath, err)
+	}
+
+	mode := int64(0644)
+	if info.Mode()&0111 != 0 {
+		mode = 0755
+	}
+
+	header := &tar.Header{
+		Name:     "package/" + file,
+		Mode:     mode,
+		Size:     info.Size(),
+		ModTime:  packEpoch,
+		Typeflag: tar.TypeReg,
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write header for %s: %w", file, err)
+	}
+	if _, err := io.Copy(tw, f); err != nil {
+		return fmt.Errorf("failed to write %s: %w", file, err)
+	}
+	return nil
+}
+
+// writeContent writes content to the tarball as file, in place of the file
+// on disk
+func writeContent(tw *tar.Writer, file string, content []byte) error {
+	header := &tar.Header{
+		Name:     "package/" + file,
+		Mode:     0644,
+		Size:     int64(len(content)),
+		ModTime:  packEpoch,
+		Typeflag: tar.TypeReg,
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write header for %s: %w", file, err)
+	}
+	if _, err := tw.Write(content); err != nil {
+		return fmt.Errorf("failed to write %s: %w", file, err)
+	}
+	return nil
+}
+
+func mustParseIgnore(patterns string) *IgnoreRules {
+	rules, err := ParseIgnore(strings.NewReader(patterns))
+	if err != nil {
+		panic(err)
+	}
+	return rules
+}
@@ -0,0 +1,244 @@
+package pack
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeProject creates files (path -> content) in a new directory
+func writeProject(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		filePath := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(filePath), 0755))
+		require.NoError(t, os.WriteFile(filePath, []byte(content), 0644))
+	}
+	return dir
+}
+
+// tarballEntries returns the entry names of a gzipped tarball
+func tarballEntries(t *testing.T, data []byte) []string {
+	t.Helper()
+	gzr, err := gzip.NewReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	tr := tar.NewReader(gzr)
+
+	var names []string
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		names = append(names, header.Name)
+	}
+	return names
+}
+
+func TestFiles(t *testing.T) {
+	testCases := []struct {
+		name     string
+		files    map[string]string
+		pkg      packagejson.PackageJSON
+		expected []string
+	}{
+		{
+			name: ".npmignore excludes a directory",
+			files: map[string]string{
+				".npmignore":         "test/\n",
+				"package.json":       `{"name": "lib"}`,
+				"index.js":           "",
+				"test/index.test.js": "",
+				"src/test.js":        "",
+			},
+			expected: []string{"index.js", "package.json", "src/test.js"},
+		},
+		{
+			name: ".gitignore is used without a .npmignore",
+			files: map[string]string{
+				".gitignore":    "dist/\n*.log\n",
+				"package.json":  `{"name": "lib"}`,
+				"index.js":      "",
+				"dist/index.js": "",
+				"debug.log":     "",
+			},
+			expected: []string{"index.js", "package.json"},
+		},
+		{
+			name: ".npmignore takes precedence over .gitignore",
+			files: map[string]string{
+				".npmignore":    "*.log\n",
+				".gitignore":    "dist/\n",
+				"package.json":  `{"name": "lib"}`,
+				"dist/index.js": "",
+				"debug.log":     "",
+			},
+			expected: []string{"dist/index.js", "package.json"},
+		},
+		{
+			name: "nested ignore file applies below its directory",
+			files: map[string]string{
+				"package.json":         `{"name": "lib"}`,
+				"lib/.npmignore":       "*.map\n",
+				"lib/index.js":         "",
+				"lib/index.js.map":     "",
+				"other/index.js.map":   "",
+				"lib/deep/util.js.map": "",
+			},
+			expected: []string{"lib/index.js", "other/index.js.map", "package.json"},
+		},
+		{
+			name: "mandatory files are always packed",
+			files: map[string]string{
+				".npmignore":   "*\n",
+				"package.json": `{"name": "lib"}`,
+				"README.md":    "",
+				"LICENSE":      "",
+				"lib/main.js":  "",
+				"lib/other.js": "",
+			},
+			pkg:      packagejson.PackageJSON{Main: "./lib/main.js"},
+			expected: []string{"LICENSE", "README.md", "lib/main.js", "package.json"},
+		},
+		{
+			name: "files npm never packs",
+			files: map[string]string{
+				"package.json":              `{"name": "lib"}`,
+				"index.js":                  "",
+				"node_modules/dep/index.js": "",
+				".git/HEAD":                 "",
+				".npmrc":                    "",
+				"go-npm-lock.json":          "",
+				"package-lock.json":         "",
+			},
+			expected: []string{"index.js", "package.json"},
+		},
+		{
+			name: "files list packs only the named files and directories",
+			files: map[string]string{
+				"package.json":       `{"name": "lib"}`,
+				"README.md":          "",
+				"index.js":           "",
+				"dist/index.js":      "",
+				"dist/sub/util.js":   "",
+				"src/index.ts":       "",
+				"types/index.d.ts":   "",
+				"types/internal.txt": "",
+			},
+			pkg:      packagejson.PackageJSON{Files: []any{"dist", "./types/*.d.ts"}},
+			expected: []string{"README.md", "dist/index.js", "dist/sub/util.js", "package.json", "types/index.d.ts"},
+		},
+		{
+			name: "files list replaces the root ignore file but not nested ones",
+			files: map[string]string{
+				".npmignore":          "dist/\n",
+				"package.json":        `{"name": "lib"}`,
+				"dist/index.js":       "",
+				"dist/.npmignore":     "*.map\n",
+				"dist/index.js.map":   "",
+				"dist/test/a.test.js": "",
+			},
+			pkg:      packagejson.PackageJSON{Files: []any{"dist", "!dist/test"}},
+			expected: []string{"dist/index.js", "package.json"},
+		},
+		{
+			name: "files list never packs node_modules",
+			files: map[string]string{
+				"package.json":                  `{"name": "lib"}`,
+				"lib/index.js":                  "",
+				"lib/node_modules/dep/index.js": "",
+			},
+			pkg:      packagejson.PackageJSON{Files: []any{"lib"}},
+			expected: []string{"lib/index.js", "package.json"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := writeProject(t, tc.files)
+
+			files, err := Files(dir, &tc.pkg)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, files)
+		})
+	}
+}
+
+func TestWriteOmitsIgnoredDirectory(t *testing.T) {
+	dir := writeProject(t, map[string]string{
+		".npmignore":             "test/\n",
+		"package.json":           `{"name": "lib", "version": "1.0.0"}`,
+		"index.js":               "module.exports = 1\n",
+		"test/index.test.js":     "",
+		"test/fixtures/data.txt": "",
+	})
+
+	files, err := Files(dir, &packagejson.PackageJSON{})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, dir, files, nil))
+
+	entries := tarballEntries(t, buf.Bytes())
+	assert.Equal(t, []string{"package/index.js", "package/package.json"}, entries)
+	for _, entry := range entries {
+		assert.NotContains(t, entry, "test/")
+	}
+}
+
+func TestWriteReplacesContents(t *testing.T) {
+	dir := writeProject(t, map[string]string{
+		"package.json": `{"name": "lib", "version": "1.0.0", "dependencies": {"b": "workspace:^"}}`,
+		"index.js":     "module.exports = 1\n",
+	})
+
+	manifest := []byte(`{"name": "lib", "version": "1.0.0", "dependencies": {"b": "^2.0.0"}}`)
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, dir, []string{"index.js", "package.json"}, map[string][]byte{"package.json": manifest}))
+
+	gzr, err := gzip.NewReader(&buf)
+	require.NoError(t, err)
+	tr := tar.NewReader(gzr)
+	contents := make(map[string]string)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		data, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(data)), header.Size)
+		contents[header.Name] = string(data)
+	}
+	assert.Equal(t, "module.exports = 1\n", contents["package/index.js"])
+	assert.Equal(t, string(manifest), contents["package/package.json"])
+}
+
+func TestFilename(t *testing.T) {
+	testCases := []struct {
+		name     string
+		pkg      string
+		version  string
+		expected string
+	}{
+		{name: "unscoped package", pkg: "lib", version: "1.0.0", expected: "lib-1.0.0.tgz"},
+		{name: "scoped package", pkg: "@scope/lib", version: "2.1.0-beta.1", expected: "scope-lib-2.1.0-beta.1.tgz"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, Filename(tc.pkg, tc.version))
+		})
+	}
+}
@@ -14,7 +14,59 @@ func NewPackageCopy() *PackageCopy {
 	return &PackageCopy{}
 }
 
+// CopyDirectory places src at dst by hardlinking every file, falling back to
+// copying files that cannot be linked (e.g. across filesystems). Symlinks are
+// recreated as symlinks.
 func (pc *PackageCopy) CopyDirectory(src, dst string) error {
+	return pc.copyDirectory(src, dst, copyOptions{link: true, keepSymlinks: true})
+}
+
+// DeepCopyDirectory copies the contents of every file of src to dst, so dst
+// shares no inodes with src. Symlinks are recreated as symlinks.
+func (pc *PackageCopy) DeepCopyDirectory(src, dst string) error {
+	return pc.copyDirectory(src, dst, copyOptions{keepSymlinks: true})
+}
+
+// CopySource deep copies a local package directory (a workspace or file:
+// dependency) to dst, leaving out its top-level node_modules and .git like
+// npm pack does. Symlinks are followed, as they may point outside src.
+func (pc *PackageCopy) CopySource(src, dst string) error {
+	return pc.copyDirectory(src, dst, copyOptions{skip: map[string]bool{"node_modules": true, ".git": true}})
+}
+
+// copyOptions controls how copyDirectory places each entry of src
+type copyOptions struct {
+	// link hardlinks files instead of copying them when possible
+	link bool
+
+	// keepSymlinks recreates symlinks instead of copying what they point to
+	keepSymlinks bool
+
+	// skip holds top-level entry names left out of the copy
+	skip map[string]bool
+}
+
+// CopyFiles deep copies the files of src listed in files, slash-separated
+// paths relative to src such as those returned by pack.Files, to dst
+func (pc *PackageCopy) CopyFiles(src, dst string, files []string) error {
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return fmt.Errorf("failed to create destination directory: %v", err)
+	}
+
+	for _, file := range files {
+		dstPath := filepath.Join(dst, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
+			return fmt.Errorf("failed to create destination directory: %v", err)
+		}
+		if err := pc.copyFile(filepath.Join(src, filepath.FromSlash(file)), dstPath, false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (pc *PackageCopy) copyDirectory(src, dst string, opts copyOptions) error {
 	srcInfo, err := os.Stat(src)
 	if err != nil {
 		return fmt.Errorf("source does not exist: %v", err)
@@ -33,16 +85,27 @@ func (pc *PackageCopy) CopyDirectory(src, dst string) error {
 		return fmt.Errorf("failed to read source directory: %v", err)
 	}
 
+	nested := opts
+	nested.skip = nil
 	for _, entry := range entries {
+		if opts.skip[entry.Name()] {
+			continue
+		}
+
 		srcPath := filepath.Join(src, entry.Name())
 		dstPath := filepath.Join(dst, entry.Name())
 
-		if entry.IsDir() {
-			if err := pc.CopyDirectory(srcPath, dstPath); err != nil {
+		switch {
+		case entry.Type()&os.ModeSymlink != 0 && opts.keepSymlinks:
+			if err := copySymlink(srcPath, dstPath); err != nil {
 				return err
 			}
-		} else {
-			if err := pc.copyFile(srcPath, dstPath); err != nil {
+		case entry.IsDir():
+			if err := pc.copyDirectory(srcPath, dstPath, nested); err != nil {
+				return err
+			}
+		default:
+			if err := pc.copyFile(srcPath, dstPath, opts.link); err != nil {
 				return err
 			}
 		}
@@ -51,11 +114,12 @@ func (pc *PackageCopy) CopyDirectory(src, dst string) error {
 	return nil
 }
 
-func (pc *PackageCopy) copyFile(src, dst string) error {
+func (pc *PackageCopy) copyFile(src, dst string, link bool) error {
 	// Try hardlink first (fast, no copy, works with Node.js resolution)
-	err := os.Link(src, dst)
-	if err == nil {
-		return nil
+	if link {
+		if err := os.Link(src, dst); err == nil {
+			return nil
+		}
 	}
 
 	// Fallback to regular copy if hardlink fails (e.g., cross-device)
@@ -70,15 +134,36 @@ func (pc *PackageCopy) copyFile(src, dst string) error {
 		return fmt.Errorf("failed to stat source file: %v", err)
 	}
 
-	dstFile, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, srcInfo.Mode())
+	mode := srcInfo.Mode().Perm()
+	dstFile, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
 	if err != nil {
 		return fmt.Errorf("failed to create destination file: %v", err)
 	}
 	defer dstFile.Close()
 
+	// The umask, or an existing file being overwritten, may have left out
+	// the executable bits that bin scripts need
+	if mode&0111 != 0 {
+		if err := dstFile.Chmod(mode); err != nil {
+			return fmt.Errorf("failed to set mode of destination file: %v", err)
+		}
+	}
+
 	if _, err := io.Copy(dstFile, srcFile); err != nil {
 		return fmt.Errorf("failed to copy file contents: %v", err)
 	}
 
 	return nil
 }
+
+// copySymlink recreates the symlink src at dst with the same target
+func copySymlink(src, dst string) error {
+	target, err := os.Readlink(src)
+	if err != nil {
+		return fmt.Errorf("failed to read symlink: %v", err)
+	}
+	if err := os.Symlink(target, dst); err != nil {
+		return fmt.Errorf("failed to create symlink: %v", err)
+	}
+	return nil
+}
@@ -101,3 +101,99 @@ func TestPackageCopyCopyDirectory(t *testing.T) {
 	}
 }
 
+
+func TestPackageCopyLinking(t *testing.T) {
+	testCases := []struct {
+		name       string
+		copyFunc   func(pc *PackageCopy, src, dst string) error
+		sameInodes bool
+	}{
+		{
+			name:       "CopyDirectory hardlinks files",
+			copyFunc:   (*PackageCopy).CopyDirectory,
+			sameInodes: true,
+		},
+		{
+			name:       "DeepCopyDirectory copies file contents",
+			copyFunc:   (*PackageCopy).DeepCopyDirectory,
+			sameInodes: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			baseDir := t.TempDir()
+			src := filepath.Join(baseDir, "src")
+			dst := filepath.Join(baseDir, "dst")
+			assert.NoError(t, os.MkdirAll(filepath.Join(src, "lib"), 0o755))
+			assert.NoError(t, os.WriteFile(filepath.Join(src, "lib", "index.js"), []byte("module.exports = 1"), 0o644))
+
+			assert.NoError(t, tc.copyFunc(NewPackageCopy(), src, dst))
+
+			srcInfo, err := os.Stat(filepath.Join(src, "lib", "index.js"))
+			assert.NoError(t, err)
+			dstInfo, err := os.Stat(filepath.Join(dst, "lib", "index.js"))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.sameInodes, os.SameFile(srcInfo, dstInfo))
+
+			content, err := os.ReadFile(filepath.Join(dst, "lib", "index.js"))
+			assert.NoError(t, err)
+			assert.Equal(t, "module.exports = 1", string(content))
+		})
+	}
+}
+
+func TestPackageCopyKeepsModesAndSymlinks(t *testing.T) {
+	testCases := []struct {
+		name     string
+		copyFunc func(pc *PackageCopy, src, dst string) error
+	}{
+		{name: "CopyDirectory", copyFunc: (*PackageCopy).CopyDirectory},
+		{name: "DeepCopyDirectory", copyFunc: (*PackageCopy).DeepCopyDirectory},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			baseDir := t.TempDir()
+			src := filepath.Join(baseDir, "src")
+			dst := filepath.Join(baseDir, "dst")
+			assert.NoError(t, os.MkdirAll(filepath.Join(src, "bin"), 0o755))
+			assert.NoError(t, os.WriteFile(filepath.Join(src, "bin", "cli.js"), []byte("#!/usr/bin/env node"), 0o755))
+			assert.NoError(t, os.WriteFile(filepath.Join(src, "index.js"), []byte("module.exports = 1"), 0o644))
+			assert.NoError(t, os.Symlink("index.js", filepath.Join(src, "main.js")))
+			assert.NoError(t, os.Symlink("missing.js", filepath.Join(src, "dangling.js")))
+
+			assert.NoError(t, tc.copyFunc(NewPackageCopy(), src, dst))
+
+			info, err := os.Stat(filepath.Join(dst, "bin", "cli.js"))
+			assert.NoError(t, err)
+			assert.Equal(t, os.FileMode(0o755), info.Mode().Perm())
+
+			for link, target := range map[string]string{"main.js": "index.js", "dangling.js": "missing.js"} {
+				actual, err := os.Readlink(filepath.Join(dst, link))
+				assert.NoError(t, err, "%s should stay a symlink", link)
+				assert.Equal(t, target, actual)
+			}
+		})
+	}
+}
+
+func TestPackageCopyCopyFiles(t *testing.T) {
+	src := t.TempDir()
+	dst := filepath.Join(t.TempDir(), "pkg")
+	for _, name := range []string{"package.json", "lib/index.js", "src/index.ts"} {
+		path := filepath.Join(src, filepath.FromSlash(name))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, os.WriteFile(path, []byte(name), 0644))
+	}
+
+	assert.NoError(t, NewPackageCopy().CopyFiles(src, dst, []string{"package.json", "lib/index.js"}))
+
+	content, err := os.ReadFile(filepath.Join(dst, "lib", "index.js"))
+	assert.NoError(t, err)
+	assert.Equal(t, "lib/index.js", string(content))
+	assert.FileExists(t, filepath.Join(dst, "package.json"))
+	assert.NoDirExists(t, filepath.Join(dst, "src"))
+
+	assert.Error(t, NewPackageCopy().CopyFiles(src, dst, []string{"missing.js"}))
+}
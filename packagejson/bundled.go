@@ -0,0 +1,40 @@
+package packagejson
+
+import "sort"
+
+// GetBundledDependencies returns, sorted, the names of the dependencies the
+// package ships inside its own tarball, from "bundleDependencies" or its
+// alias "bundledDependencies". true bundles every dependency.
+func (p *PackageJSON) GetBundledDependencies() []string {
+	field := p.BundleDependencies
+	if field == nil {
+		field = p.BundledDependencies
+	}
+
+	seen := make(map[string]bool)
+	switch value := field.(type) {
+	case bool:
+		if !value {
+			return nil
+		}
+		for name := range p.GetDependencies() {
+			seen[name] = true
+		}
+	case []any:
+		for _, entry := range value {
+			if name, ok := entry.(string); ok && name != "" {
+				seen[name] = true
+			}
+		}
+	}
+
+	if len(seen) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
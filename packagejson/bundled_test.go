@@ -0,0 +1,50 @@
+package packagejson
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPackageJSON_GetBundledDependencies(t *testing.T) {
+	testCases := []struct {
+		name     string
+		content  string
+		expected []string
+	}{
+		{
+			name:     "no bundled dependencies",
+			content:  `{"name": "lib"}`,
+			expected: nil,
+		},
+		{
+			name:     "bundleDependencies list",
+			content:  `{"bundleDependencies": ["b", "@scope/a", "b"]}`,
+			expected: []string{"@scope/a", "b"},
+		},
+		{
+			name:     "bundledDependencies alias",
+			content:  `{"bundledDependencies": ["a"]}`,
+			expected: []string{"a"},
+		},
+		{
+			name:     "true bundles every dependency",
+			content:  `{"dependencies": {"b": "^1.0.0", "a": "^2.0.0"}, "bundleDependencies": true}`,
+			expected: []string{"a", "b"},
+		},
+		{
+			name:     "false bundles nothing",
+			content:  `{"dependencies": {"a": "^2.0.0"}, "bundleDependencies": false}`,
+			expected: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var pkg PackageJSON
+			assert.NoError(t, json.Unmarshal([]byte(tc.content), &pkg))
+			assert.Equal(t, tc.expected, pkg.GetBundledDependencies())
+		})
+	}
+}
@@ -0,0 +1,308 @@
+package packagejson
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// jsonMember is a key/value pair of a JSON object, located by byte offsets
+type jsonMember struct {
+	key        string
+	keyStart   int
+	valueStart int
+	valueEnd   int
+}
+
+// jsonStyle is the formatting detected from an existing JSON document
+type jsonStyle struct {
+	newline string
+	indent  string
+}
+
+// setDependencyInJSON sets section.name to version in a package.json document
+// while keeping the rest of the file byte for byte. An existing value is
+// replaced in place; a new key is inserted in sorted position using the
+// document's own indentation and line endings, and a missing section is
+// appended to the root object.
+func setDependencyInJSON(content, section, name, version string) (string, error) {
+	rootStart := skipWhitespace(content, 0)
+	if rootStart >= len(content) || content[rootStart] != '{' {
+		return "", fmt.Errorf("package.json root is not an object")
+	}
+
+	rootMembers, rootEnd, err := scanObject(content, rootStart)
+	if err != nil {
+		return "", err
+	}
+
+	style := detectJSONStyle(content)
+	quotedName := quoteJSON(name)
+	quotedVersion := quoteJSON(version)
+
+	for _, member := range rootMembers {
+		if member.key != section {
+			continue
+		}
+
+		if content[member.valueStart] != '{' {
+			return "", fmt.Errorf("%s in package.json is not an object", section)
+		}
+
+		deps, depsEnd, err := scanObject(content, member.valueStart)
+		if err != nil {
+			return "", err
+		}
+
+		for _, dep := range deps {
+			if dep.key == name {
+				return content[:dep.valueStart] + quotedVersion + content[dep.valueEnd:], nil
+			}
+		}
+
+		separator := memberSeparator(content, deps)
+		entry := quotedName + separator + quotedVersion
+
+		if len(deps) == 0 {
+			sectionIndent := lineIndent(content, member.keyStart)
+			body := "{" + style.newline + sectionIndent + style.indent + entry + style.newline + sectionIndent + "}"
+			return content[:member.valueStart] + body + content[depsEnd+1:], nil
+		}
+
+		entryIndent := lineIndent(content, deps[0].keyStart)
+		for _, dep := range deps {
+			if dep.key > name {
+				insert := entry + "," + style.newline + entryIndent
+				if style.newline == "" {
+					insert = entry + ","
+				}
+				return content[:dep.keyStart] + insert + content[dep.keyStart:], nil
+			}
+		}
+
+		last := deps[len(deps)-1]
+		insert := "," + style.newline + entryIndent + entry
+		return content[:last.valueEnd] + insert + content[last.valueEnd:], nil
+	}
+
+	// The section does not exist yet: append it to the root object
+	separator := memberSeparator(content, rootMembers)
+	sectionIndent := style.indent
+	if len(rootMembers) > 0 {
+		sectionIndent = lineIndent(content, rootMembers[0].keyStart)
+	}
+	block := quoteJSON(section) + separator + "{" + style.newline +
+		sectionIndent + style.indent + quotedName + separator + quotedVersion + style.newline +
+		sectionIndent + "}"
+
+	if len(rootMembers) == 0 {
+		body := "{" + style.newline + sectionIndent + block + style.newline + "}"
+		return content[:rootStart] + body + content[rootEnd+1:], nil
+	}
+
+	last := rootMembers[len(rootMembers)-1]
+	insert := "," + style.newline + sectionIndent + block
+	return content[:last.valueEnd] + insert + content[last.valueEnd:], nil
+}
+
+// ResolveSpecsInJSON returns content, a package.json document, with the spec
+// of each dependency, dev, optional and peer dependency replaced by resolve,
+// keeping the rest of the file byte for byte
+func ResolveSpecsInJSON(content []byte, resolve func(name, spec string) (string, error)) ([]byte, error) {
+	var pkg map[string]json.RawMessage
+	if err := json.Unmarshal(content, &pkg); err != nil {
+		return nil, fmt.Errorf("failed to parse package.json: %w", err)
+	}
+
+	result := string(content)
+	for _, kind := range []DependencyKind{DependencyProd, DependencyDev, DependencyOptional, DependencyPeer} {
+		var deps map[string]string
+		if raw, ok := pkg[kind.Section()]; !ok || json.Unmarshal(raw, &deps) != nil {
+			continue
+		}
+		for _, name := range slices.Sorted(maps.Keys(deps)) {
+			spec, err := resolve(name, deps[name])
+			if err != nil {
+				return nil, err
+			}
+			if spec == deps[name] {
+				continue
+			}
+			if result, err = setDependencyInJSON(result, kind.Section(), name, spec); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return []byte(result), nil
+}
+
+// detectJSONStyle returns the line ending and the indentation unit (tabs or N
+// spaces) used by content, defaulting to two spaces for one-line documents
+func detectJSONStyle(content string) jsonStyle {
+	style := jsonStyle{newline: "\n", indent: "  "}
+	if strings.Contains(content, "\r\n") {
+		style.newline = "\r\n"
+	} else if !strings.Contains(content, "\n") {
+		return jsonStyle{}
+	}
+
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed == "" || trimmed == "\r" || len(trimmed) == len(line) {
+			continue
+		}
+		style.indent = line[:len(line)-len(trimmed)]
+		break
+	}
+
+	return style
+}
+
+// lineIndent returns the whitespace between the start of the line holding pos and pos
+func lineIndent(content string, pos int) string {
+	start := strings.LastIndex(content[:pos], "\n") + 1
+	indent := content[start:pos]
+	if strings.TrimLeft(indent, " \t") != "" {
+		return ""
+	}
+	return indent
+}
+
+// memberSeparator returns the text between the first member's key and value
+// (e.g. ": " or ":"), defaulting to ": "
+func memberSeparator(content string, members []jsonMember) string {
+	for _, member := range members {
+		keyEnd, err := skipString(content, member.keyStart)
+		if err != nil {
+			break
+		}
+		return content[keyEnd:member.valueStart]
+	}
+	return ": "
+}
+
+func quoteJSON(value string) string {
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+// scanObject parses the object starting at content[start] == '{' and returns
+// its members and the offset of the closing brace
+func scanObject(content string, start int) ([]jsonMember, int, error) {
+	members := []jsonMember{}
+	i := skipWhitespace(content, start+1)
+
+	if i < len(content) && content[i] == '}' {
+		return members, i, nil
+	}
+
+	for i < len(content) {
+		if content[i] != '"' {
+			return nil, 0, fmt.Errorf("invalid JSON: expected key at offset %d", i)
+		}
+
+		keyStart := i
+		keyEnd, err := skipString(content, i)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		var key string
+		if err := json.Unmarshal([]byte(content[keyStart:keyEnd]), &key); err != nil {
+			return nil, 0, fmt.Errorf("invalid JSON key at offset %d: %w", keyStart, err)
+		}
+
+		i = skipWhitespace(content, keyEnd)
+		if i >= len(content) || content[i] != ':' {
+			return nil, 0, fmt.Errorf("invalid JSON: expected ':' at offset %d", i)
+		}
+
+		valueStart := skipWhitespace(content, i+1)
+		valueEnd, err := skipValue(content, valueStart)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		members = append(members, jsonMember{key: key, keyStart: keyStart, valueStart: valueStart, valueEnd: valueEnd})
+
+		i = skipWhitespace(content, valueEnd)
+		if i >= len(content) {
+			break
+		}
+
+		switch content[i] {
+		case ',':
+			i = skipWhitespace(content, i+1)
+			// Tolerate a trailing comma before the closing brace
+			if i < len(content) && content[i] == '}' {
+				return members, i, nil
+			}
+		case '}':
+			return members, i, nil
+		default:
+			return nil, 0, fmt.Errorf("invalid JSON: unexpected %q at offset %d", content[i], i)
+		}
+	}
+
+	return nil, 0, fmt.Errorf("invalid JSON: unterminated object at offset %d", start)
+}
+
+// skipValue returns the offset just past the JSON value starting at content[start]
+func skipValue(content string, start int) (int, error) {
+	if start >= len(content) {
+		return 0, fmt.Errorf("invalid JSON: missing value at offset %d", start)
+	}
+
+	switch content[start] {
+	case '"':
+		return skipString(content, start)
+	case '{', '[':
+		depth := 0
+		for i := start; i < len(content); i++ {
+			switch content[i] {
+			case '"':
+				end, err := skipString(content, i)
+				if err != nil {
+					return 0, err
+				}
+				i = end - 1
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+				if depth == 0 {
+					return i + 1, nil
+				}
+			}
+		}
+		return 0, fmt.Errorf("invalid JSON: unterminated value at offset %d", start)
+	default:
+		i := start
+		for i < len(content) && !strings.ContainsRune(",}] \t\r\n", rune(content[i])) {
+			i++
+		}
+		return i, nil
+	}
+}
+
+// skipString returns the offset just past the JSON string starting at content[start] == '"'
+func skipString(content string, start int) (int, error) {
+	for i := start + 1; i < len(content); i++ {
+		switch content[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid JSON: unterminated string at offset %d", start)
+}
+
+func skipWhitespace(content string, i int) int {
+	for i < len(content) && strings.ContainsRune(" \t\r\n", rune(content[i])) {
+		i++
+	}
+	return i
+}
@@ -0,0 +1,84 @@
+package packagejson
+
+import "strings"
+
+const nodeModulesDir = "node_modules/"
+
+// Resolve finds the lock path of the copy of name that the package at
+// fromPath (or the root, at "") would load, walking up the node_modules
+// directories the way Node.js does
+func (l *PackageLock) Resolve(fromPath, name string) (string, bool) {
+	dir := fromPath
+	for {
+		candidate := nodeModulesDir + name
+		if dir != "" {
+			candidate = dir + "/" + candidate
+		}
+		if _, ok := l.Packages[candidate]; ok {
+			return candidate, true
+		}
+
+		if dir == "" {
+			return "", false
+		}
+		if i := strings.LastIndex(dir, "/"+nodeModulesDir); i >= 0 {
+			dir = dir[:i]
+		} else {
+			dir = ""
+		}
+	}
+}
+
+// Subtree returns a copy of the lock holding only the top-level packages
+// roots and everything they load, as resolved by Resolve. Roots missing from
+// the lock are skipped.
+func (l *PackageLock) Subtree(roots []string) *PackageLock {
+	subtree := &PackageLock{
+		Name:            l.Name,
+		Version:         l.Version,
+		LockfileVersion: l.LockfileVersion,
+		Requires:        l.Requires,
+		Workspaces:      l.Workspaces,
+		Dependencies:    make(map[string]string),
+		Overrides:       l.Overrides,
+		Resolutions:     l.Resolutions,
+		Registries:      l.Registries,
+		Packages:        make(map[string]PackageItem),
+	}
+
+	var queue []string
+	for _, name := range roots {
+		pkgPath := nodeModulesDir + name
+		item, ok := l.Packages[pkgPath]
+		if !ok {
+			continue
+		}
+		spec, ok := l.Dependencies[name]
+		if !ok {
+			spec = item.Version
+		}
+		subtree.Dependencies[name] = spec
+		queue = append(queue, pkgPath)
+	}
+
+	for len(queue) > 0 {
+		pkgPath := queue[0]
+		queue = queue[1:]
+		if _, seen := subtree.Packages[pkgPath]; seen {
+			continue
+		}
+
+		item := l.Packages[pkgPath]
+		subtree.Packages[pkgPath] = item
+
+		for _, deps := range []map[string]string{item.Dependencies, item.OptionalDependencies, item.PeerDependencies} {
+			for name := range deps {
+				if resolved, ok := l.Resolve(pkgPath, name); ok {
+					queue = append(queue, resolved)
+				}
+			}
+		}
+	}
+
+	return subtree
+}
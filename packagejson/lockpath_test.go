@@ -0,0 +1,103 @@
+package packagejson
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPackageLockResolve(t *testing.T) {
+	lock := &PackageLock{
+		Packages: map[string]PackageItem{
+			"node_modules/a":                               {Version: "1.0.0"},
+			"node_modules/b":                               {Version: "1.0.0"},
+			"node_modules/a/node_modules/b":                {Version: "2.0.0"},
+			"node_modules/a/node_modules/b/node_modules/c": {Version: "1.0.0"},
+			"node_modules/@scope/d":                        {Version: "1.0.0"},
+			"packages/app":                                 {Version: "1.0.0"},
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		fromPath string
+		pkg      string
+		expected string
+		found    bool
+	}{
+		{name: "root dependency", fromPath: "", pkg: "b", expected: "node_modules/b", found: true},
+		{name: "nested copy wins over top-level", fromPath: "node_modules/a", pkg: "b", expected: "node_modules/a/node_modules/b", found: true},
+		{name: "walks up to the parent node_modules", fromPath: "node_modules/a/node_modules/b/node_modules/c", pkg: "b", expected: "node_modules/a/node_modules/b", found: true},
+		{name: "scoped package from a nested package", fromPath: "node_modules/a/node_modules/b", pkg: "@scope/d", expected: "node_modules/@scope/d", found: true},
+		{name: "workspace package resolves from the root", fromPath: "packages/app", pkg: "a", expected: "node_modules/a", found: true},
+		{name: "missing package", fromPath: "node_modules/a", pkg: "missing", expected: "", found: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resolved, found := lock.Resolve(tc.fromPath, tc.pkg)
+			assert.Equal(t, tc.found, found)
+			assert.Equal(t, tc.expected, resolved)
+		})
+	}
+}
+
+func TestPackageLockSubtree(t *testing.T) {
+	lock := &PackageLock{
+		Name:         "root",
+		Dependencies: map[string]string{"@org/app": "1.0.0", "@org/lib": "1.0.0", "other": "^3.0.0"},
+		Workspaces:   map[string]string{"@org/app": "1.0.0", "@org/lib": "1.0.0"},
+		Packages: map[string]PackageItem{
+			"node_modules/@org/app":         {Version: "1.0.0", Link: true, Dependencies: map[string]string{"a": "^1.0.0", "@org/lib": "^1.0.0"}},
+			"node_modules/@org/lib":         {Version: "1.0.0", Link: true, Dependencies: map[string]string{"c": "^1.0.0"}},
+			"node_modules/a":                {Version: "1.0.0", Dependencies: map[string]string{"b": "^2.0.0"}, OptionalDependencies: map[string]string{"opt": "^1.0.0"}},
+			"node_modules/a/node_modules/b": {Version: "2.0.0"},
+			"node_modules/b":                {Version: "1.0.0"},
+			"node_modules/c":                {Version: "1.0.0"},
+			"node_modules/opt":              {Version: "1.0.0"},
+			"node_modules/other":            {Version: "3.0.0", Dependencies: map[string]string{"b": "^1.0.0"}},
+		},
+	}
+
+	testCases := []struct {
+		name         string
+		roots        []string
+		dependencies map[string]string
+		packages     []string
+	}{
+		{
+			name:         "follows nested, optional and sibling workspace dependencies",
+			roots:        []string{"@org/app"},
+			dependencies: map[string]string{"@org/app": "1.0.0"},
+			packages:     []string{"node_modules/@org/app", "node_modules/@org/lib", "node_modules/a", "node_modules/a/node_modules/b", "node_modules/c", "node_modules/opt"},
+		},
+		{
+			name:         "leaf workspace",
+			roots:        []string{"@org/lib"},
+			dependencies: map[string]string{"@org/lib": "1.0.0"},
+			packages:     []string{"node_modules/@org/lib", "node_modules/c"},
+		},
+		{
+			name:         "missing roots are skipped",
+			roots:        []string{"missing", "other"},
+			dependencies: map[string]string{"other": "^3.0.0"},
+			packages:     []string{"node_modules/b", "node_modules/other"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			subtree := lock.Subtree(tc.roots)
+
+			assert.Equal(t, "root", subtree.Name)
+			assert.Equal(t, lock.Workspaces, subtree.Workspaces)
+			assert.Equal(t, tc.dependencies, subtree.Dependencies)
+
+			var packages []string
+			for pkgPath := range subtree.Packages {
+				packages = append(packages, pkgPath)
+			}
+			assert.ElementsMatch(t, tc.packages, packages)
+		})
+	}
+}
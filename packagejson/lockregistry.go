@@ -0,0 +1,68 @@
+package packagejson
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/ernesto27/go-npm/config"
+)
+
+// WithPublicRegistry returns a copy of the lock whose registry tarball URLs
+// point at the public npm registry, without the registries it was resolved
+// against, so a lock resolved through a private mirror can be committed
+// without leaking the mirror. Git, file and other tarball URLs are kept.
+func (l *PackageLock) WithPublicRegistry() *PackageLock {
+	public := *l
+	public.Registries = nil
+	public.Packages = make(map[string]PackageItem, len(l.Packages))
+
+	for key, item := range l.Packages {
+		if resolved, ok := publicTarballURL(item.Resolved, item.Version); ok {
+			item.Resolved = resolved
+		}
+		public.Packages[key] = item
+	}
+
+	return &public
+}
+
+// publicTarballURL rewrites resolved to the public registry when it is a
+// registry tarball URL of version, <registry>/<name>/-/<file>-<version>.tgz,
+// whatever the registry base path
+func publicTarballURL(resolved, version string) (string, bool) {
+	u, err := url.Parse(resolved)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || version == "" {
+		return "", false
+	}
+
+	pkgPath, file, ok := strings.Cut(u.EscapedPath(), "/-/")
+	if !ok || !strings.HasSuffix(file, "-"+version+".tgz") {
+		return "", false
+	}
+
+	// The name is the last path segment, or the last two for a scoped
+	// package, whose slash some registries escape
+	pkgPath, err = url.PathUnescape(pkgPath)
+	if err != nil {
+		return "", false
+	}
+	segments := strings.Split(strings.Trim(pkgPath, "/"), "/")
+	name := segments[len(segments)-1]
+	if len(segments) > 1 && strings.HasPrefix(segments[len(segments)-2], "@") {
+		name = segments[len(segments)-2] + "/" + name
+	}
+	if name == "" || strings.HasPrefix(name, "@") && !strings.Contains(name, "/") {
+		return "", false
+	}
+
+	baseName := name[strings.LastIndex(name, "/")+1:]
+	return config.NPMRegistryURL + name + "/-/" + baseName + "-" + version + ".tgz", true
+}
+
+// lockToWrite returns the form of lock written to disk
+func (p *PackageJSONParser) lockToWrite(lock *PackageLock) *PackageLock {
+	if p.Config != nil && p.Config.OmitLockfileRegistry {
+		return lock.WithPublicRegistry()
+	}
+	return lock
+}
@@ -0,0 +1,118 @@
+package packagejson
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ernesto27/go-npm/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPackageLockWithPublicRegistry(t *testing.T) {
+	testCases := []struct {
+		name     string
+		resolved string
+		version  string
+		expected string
+	}{
+		{
+			name:     "mirror tarball",
+			resolved: "https://npm.corp.example/repository/npm-proxy/lodash/-/lodash-4.17.21.tgz",
+			version:  "4.17.21",
+			expected: "https://registry.npmjs.org/lodash/-/lodash-4.17.21.tgz",
+		},
+		{
+			name:     "scoped mirror tarball",
+			resolved: "https://npm.corp.example/npm/@types/node/-/node-20.1.0.tgz",
+			version:  "20.1.0",
+			expected: "https://registry.npmjs.org/@types/node/-/node-20.1.0.tgz",
+		},
+		{
+			name:     "escaped scope and scoped file name",
+			resolved: "http://artifactory.internal:8081/api/npm/npm/@babel%2fcore/-/@babel/core-7.24.0.tgz",
+			version:  "7.24.0",
+			expected: "https://registry.npmjs.org/@babel/core/-/core-7.24.0.tgz",
+		},
+		{
+			name:     "public registry is unchanged",
+			resolved: "https://registry.npmjs.org/lodash/-/lodash-4.17.21.tgz",
+			version:  "4.17.21",
+			expected: "https://registry.npmjs.org/lodash/-/lodash-4.17.21.tgz",
+		},
+		{
+			name:     "tarball of another version is kept",
+			resolved: "https://cdn.example.com/lodash/-/lodash-4.17.20.tgz",
+			version:  "4.17.21",
+			expected: "https://cdn.example.com/lodash/-/lodash-4.17.20.tgz",
+		},
+		{
+			name:     "remote tarball is kept",
+			resolved: "https://example.com/downloads/lib-1.0.0.tgz",
+			version:  "1.0.0",
+			expected: "https://example.com/downloads/lib-1.0.0.tgz",
+		},
+		{
+			name:     "git dependency is kept",
+			resolved: "git+ssh://git@github.com/org/lib.git#0123456789abcdef0123456789abcdef01234567",
+			version:  "1.0.0",
+			expected: "git+ssh://git@github.com/org/lib.git#0123456789abcdef0123456789abcdef01234567",
+		},
+		{
+			name:     "file dependency is kept",
+			resolved: "file:../shared",
+			version:  "1.0.0",
+			expected: "file:../shared",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			lock := &PackageLock{
+				Registries: map[string]string{"default": "https://npm.corp.example/"},
+				Packages:   map[string]PackageItem{"node_modules/pkg": {Version: tc.version, Resolved: tc.resolved}},
+			}
+
+			public := lock.WithPublicRegistry()
+			assert.Equal(t, tc.expected, public.Packages["node_modules/pkg"].Resolved)
+			assert.Nil(t, public.Registries)
+
+			// The lock used for the install is left alone
+			assert.Equal(t, tc.resolved, lock.Packages["node_modules/pkg"].Resolved)
+			assert.NotNil(t, lock.Registries)
+		})
+	}
+}
+
+func TestPackageJSONParser_CreateLockFileOmitLockfileRegistry(t *testing.T) {
+	const mirrorURL = "https://npm.corp.example/npm/lodash/-/lodash-4.17.21.tgz"
+
+	for _, omit := range []bool{false, true} {
+		dir := t.TempDir()
+		parser := NewPackageJSONParser(&config.Config{OmitLockfileRegistry: omit}, nil)
+		parser.LockFileName = filepath.Join(dir, LOCK_FILE_NAME_GO_NPM)
+
+		lock := &PackageLock{
+			Dependencies: map[string]string{"lodash": "^4.17.0"},
+			Registries:   map[string]string{"default": "https://npm.corp.example/npm/"},
+			Packages:     map[string]PackageItem{"node_modules/lodash": {Version: "4.17.21", Resolved: mirrorURL}},
+		}
+		require.NoError(t, parser.CreateLockFile(lock, false))
+
+		content, err := os.ReadFile(parser.LockFileName)
+		require.NoError(t, err)
+		var written PackageLock
+		require.NoError(t, json.Unmarshal(content, &written))
+
+		if omit {
+			assert.Equal(t, "https://registry.npmjs.org/lodash/-/lodash-4.17.21.tgz", written.Packages["node_modules/lodash"].Resolved)
+			assert.NotContains(t, string(content), "npm.corp.example")
+		} else {
+			assert.Equal(t, mirrorURL, written.Packages["node_modules/lodash"].Resolved)
+			assert.Equal(t, lock.Registries, written.Registries)
+		}
+		assert.Equal(t, mirrorURL, parser.PackageLock.Packages["node_modules/lodash"].Resolved)
+	}
+}
@@ -0,0 +1,88 @@
+package packagejson
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/ernesto27/go-npm/yarnlock"
+)
+
+// ForeignLockFiles are the lock files of other package managers, in the
+// order FindForeignLockFile looks for them. npm-shrinkwrap.json comes first
+// because npm prefers it over package-lock.json.
+var ForeignLockFiles = []string{LOCK_FILE_NAME_SHRINKWRAP, LOCK_FILE_NAME_NPM, LOCK_FILE_NAME_YARN, LOCK_FILE_NAME_PNPM}
+
+// FindForeignLockFile returns the first of ForeignLockFiles present in dir
+func FindForeignLockFile(dir string) (string, bool) {
+	for _, name := range ForeignLockFiles {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// Migrate converts lockFile, one of ForeignLockFiles, to go-npm-lock.json.
+// It returns warnings about what the go-npm lock could not keep.
+func (p *PackageJSONParser) Migrate(lockFile string) ([]string, error) {
+	var warnings []string
+	switch filepath.Base(lockFile) {
+	case LOCK_FILE_NAME_SHRINKWRAP, LOCK_FILE_NAME_NPM:
+		if err := p.migrateFromNpmLock(lockFile); err != nil {
+			return nil, err
+		}
+	case LOCK_FILE_NAME_YARN:
+		if err := p.migrateFromYarnLock(lockFile); err != nil {
+			return nil, err
+		}
+		yarnLock, err := p.YarnLockParser.ParseContent(p.LockFileContent)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse yarn.lock: %w", err)
+		}
+		warnings = append(warnings, droppedYarnVersions(yarnLock, p.PackageLock)...)
+	case LOCK_FILE_NAME_PNPM:
+		pnpmWarnings, err := p.migrateFromPnpmLock(lockFile)
+		if err != nil {
+			return nil, err
+		}
+		warnings = append(warnings, pnpmWarnings...)
+	default:
+		return nil, fmt.Errorf("unknown lock file %s (expected one of %s)", lockFile, strings.Join(ForeignLockFiles, ", "))
+	}
+
+	return append(warnings, missingIntegrity(p.PackageLock)...), nil
+}
+
+// droppedYarnVersions reports the yarn.lock versions left out of lock. The
+// conversion hoists every package, so only one version of each is kept.
+func droppedYarnVersions(yarnLock *yarnlock.YarnLock, lock *PackageLock) []string {
+	var warnings []string
+	for _, key := range slices.Sorted(maps.Keys(yarnLock.Entries)) {
+		entry := yarnLock.Entries[key]
+		kept := lock.Packages["node_modules/"+entry.Name]
+		if kept.Version != entry.Version {
+			warnings = append(warnings, fmt.Sprintf("%s@%s is dropped, only %s@%s is locked", entry.Name, entry.Version, entry.Name, kept.Version))
+		}
+	}
+	return warnings
+}
+
+// missingIntegrity reports the locked packages whose tarballs cannot be
+// verified, sorted by package path
+func missingIntegrity(lock *PackageLock) []string {
+	var warnings []string
+	for _, pkgPath := range slices.Sorted(maps.Keys(lock.Packages)) {
+		item := lock.Packages[pkgPath]
+		if item.Link {
+			continue
+		}
+		if item.Integrity == "" {
+			warnings = append(warnings, fmt.Sprintf("%s@%s has no integrity, its tarball will not be verified", extractLockPackageName(pkgPath), item.Version))
+		}
+	}
+	return warnings
+}
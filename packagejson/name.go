@@ -0,0 +1,86 @@
+package packagejson
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MaxNameLength is the longest package name the npm registry accepts
+const MaxNameLength = 214
+
+// reservedNames can never be used as package names
+var reservedNames = []string{"node_modules", "favicon.ico"}
+
+// ValidateName checks name against npm's package name rules
+// (validate-npm-package-name) and returns an error with the specific reason
+// when it cannot be published or installed
+func ValidateName(name string) error {
+	if name == "" {
+		return fmt.Errorf("invalid package name: name cannot be empty")
+	}
+
+	if strings.TrimSpace(name) != name {
+		return fmt.Errorf("invalid package name %q: name cannot contain leading or trailing spaces", name)
+	}
+
+	if strings.HasPrefix(name, ".") {
+		return fmt.Errorf("invalid package name %q: name cannot start with a period", name)
+	}
+
+	if strings.HasPrefix(name, "_") {
+		return fmt.Errorf("invalid package name %q: name cannot start with an underscore", name)
+	}
+
+	for _, reserved := range reservedNames {
+		if strings.EqualFold(name, reserved) {
+			return fmt.Errorf("invalid package name %q: %s is not a valid package name", name, reserved)
+		}
+	}
+
+	if len(name) > MaxNameLength {
+		return fmt.Errorf("invalid package name %q: name cannot contain more than %d characters", name, MaxNameLength)
+	}
+
+	if strings.ToLower(name) != name {
+		return fmt.Errorf("invalid package name %q: name cannot contain capital letters", name)
+	}
+
+	if strings.ContainsAny(name, "~'!()*") {
+		return fmt.Errorf("invalid package name %q: name cannot contain special characters (\"~'!()*\")", name)
+	}
+
+	pkgName := name
+	if strings.HasPrefix(name, "@") {
+		scope, rest, found := strings.Cut(name[1:], "/")
+		if !found || scope == "" || rest == "" || strings.Contains(rest, "/") {
+			return fmt.Errorf("invalid package name %q: scoped names must look like @scope/name", name)
+		}
+		if !isURLFriendly(scope) {
+			return fmt.Errorf("invalid package name %q: scope can only contain URL-friendly characters", name)
+		}
+		if strings.HasPrefix(rest, ".") || strings.HasPrefix(rest, "_") {
+			return fmt.Errorf("invalid package name %q: name cannot start with a period or underscore", name)
+		}
+		pkgName = rest
+	}
+
+	if !isURLFriendly(pkgName) {
+		return fmt.Errorf("invalid package name %q: name can only contain URL-friendly characters", name)
+	}
+
+	return nil
+}
+
+// isURLFriendly reports whether s is left unchanged by JavaScript's
+// encodeURIComponent, which is how npm decides a name is URL-safe
+func isURLFriendly(s string) bool {
+	for _, c := range s {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case strings.ContainsRune("-_.!~*'()", c):
+		default:
+			return false
+		}
+	}
+	return true
+}
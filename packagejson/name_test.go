@@ -0,0 +1,114 @@
+package packagejson
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateName(t *testing.T) {
+	testCases := []struct {
+		name          string
+		pkgName       string
+		expectError   bool
+		errorContains string
+	}{
+		{
+			name:    "valid name",
+			pkgName: "express",
+		},
+		{
+			name:    "valid scoped name",
+			pkgName: "@types/node",
+		},
+		{
+			name:    "valid name with dots and dashes",
+			pkgName: "lodash.merge-v2",
+		},
+		{
+			name:          "uppercase name",
+			pkgName:       "MyPackage",
+			expectError:   true,
+			errorContains: "capital letters",
+		},
+		{
+			name:          "name with spaces",
+			pkgName:       "my package",
+			expectError:   true,
+			errorContains: "URL-friendly characters",
+		},
+		{
+			name:          "leading space",
+			pkgName:       " express",
+			expectError:   true,
+			errorContains: "leading or trailing spaces",
+		},
+		{
+			name:          "over-long name",
+			pkgName:       strings.Repeat("a", MaxNameLength+1),
+			expectError:   true,
+			errorContains: "more than 214 characters",
+		},
+		{
+			name:          "empty name",
+			pkgName:       "",
+			expectError:   true,
+			errorContains: "cannot be empty",
+		},
+		{
+			name:          "starts with a period",
+			pkgName:       ".hidden",
+			expectError:   true,
+			errorContains: "start with a period",
+		},
+		{
+			name:          "starts with an underscore",
+			pkgName:       "_private",
+			expectError:   true,
+			errorContains: "start with an underscore",
+		},
+		{
+			name:          "reserved name",
+			pkgName:       "node_modules",
+			expectError:   true,
+			errorContains: "not a valid package name",
+		},
+		{
+			name:          "special characters",
+			pkgName:       "hello!",
+			expectError:   true,
+			errorContains: "special characters",
+		},
+		{
+			name:          "scope without package name",
+			pkgName:       "@types/",
+			expectError:   true,
+			errorContains: "@scope/name",
+		},
+		{
+			name:          "scoped name starting with a period",
+			pkgName:       "@types/.node",
+			expectError:   true,
+			errorContains: "period or underscore",
+		},
+		{
+			name:          "scope with invalid characters",
+			pkgName:       "@my scope/node",
+			expectError:   true,
+			errorContains: "scope can only contain URL-friendly characters",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateName(tc.pkgName)
+			if tc.expectError {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tc.errorContains)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
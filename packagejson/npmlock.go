@@ -0,0 +1,216 @@
+package packagejson
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// NPMPackageLock is the npm lockfileVersion 3 (package-lock.json) schema.
+// The root project is stored under the "" key of Packages.
+type NPMPackageLock struct {
+	Name            string                    `json:"name"`
+	Version         string                    `json:"version,omitempty"`
+	LockfileVersion int                       `json:"lockfileVersion"`
+	Requires        bool                      `json:"requires"`
+	Packages        map[string]NPMPackageItem `json:"packages"`
+}
+
+// NPMPackageItem is a "packages" entry of package-lock.json, with only the
+// fields npm records. Name is set for aliased packages and the root.
+type NPMPackageItem struct {
+	Name                 string              `json:"name,omitempty"`
+	Version              string              `json:"version,omitempty"`
+	Resolved             string              `json:"resolved,omitempty"`
+	Integrity            string              `json:"integrity,omitempty"`
+	Link                 bool                `json:"link,omitempty"`
+	Dev                  bool                `json:"dev,omitempty"`
+	Optional             bool                `json:"optional,omitempty"`
+	HasInstallScript     bool                `json:"hasInstallScript,omitempty"`
+	License              any                 `json:"license,omitempty"`
+	Dependencies         map[string]string   `json:"dependencies,omitempty"`
+	DevDependencies      map[string]string   `json:"devDependencies,omitempty"`
+	OptionalDependencies map[string]string   `json:"optionalDependencies,omitempty"`
+	PeerDependencies     map[string]string   `json:"peerDependencies,omitempty"`
+	PeerDependenciesMeta map[string]PeerMeta `json:"peerDependenciesMeta,omitempty"`
+	BundleDependencies   []string            `json:"bundleDependencies,omitempty"`
+	Bin                  any                 `json:"bin,omitempty"`
+	Engines              any                 `json:"engines,omitempty"`
+	OS                   []string            `json:"os,omitempty"`
+	CPU                  []string            `json:"cpu,omitempty"`
+}
+
+// BuildNPMPackageLock converts the internal lock into the npm v3 schema,
+// adding the root "" entry and the dev/optional flags npm expects
+func (p *PackageJSONParser) BuildNPMPackageLock(lock *PackageLock) *NPMPackageLock {
+	name := lock.Name
+	version := lock.Version
+	if p.PackageJSONRoot != nil {
+		if p.PackageJSONRoot.Name != "" {
+			name = p.PackageJSONRoot.Name
+		}
+		if v, ok := p.PackageJSONRoot.Version.(string); ok {
+			version = v
+		}
+	}
+
+	root := NPMPackageItem{
+		Name:                 name,
+		Version:              version,
+		Dependencies:         nonEmpty(lock.Dependencies),
+		DevDependencies:      nonEmpty(lock.DevDependencies),
+		OptionalDependencies: nonEmpty(lock.OptionalDependencies),
+	}
+
+	npmLock := &NPMPackageLock{
+		Name:            name,
+		Version:         version,
+		LockfileVersion: 3,
+		Requires:        true,
+		Packages:        map[string]NPMPackageItem{"": root},
+	}
+
+	cwd, _ := os.Getwd()
+	nonDev := reachableLockPaths(lock, []map[string]string{lock.Dependencies, lock.OptionalDependencies, lock.PeerDependencies}, true)
+	nonOptional := reachableLockPaths(lock, []map[string]string{lock.Dependencies, lock.DevDependencies, lock.PeerDependencies}, false)
+
+	for key, item := range lock.Packages {
+		if key == "" {
+			continue
+		}
+
+		npmItem := NPMPackageItem{
+			Version:              item.Version,
+			Resolved:             item.Resolved,
+			Integrity:            item.Integrity,
+			Link:                 item.Link,
+			HasInstallScript:     !item.Link && item.HasInstallScript(),
+			License:              item.License,
+			Dependencies:         item.Dependencies,
+			DevDependencies:      item.DevDependencies,
+			OptionalDependencies: item.OptionalDependencies,
+			PeerDependencies:     item.PeerDependencies,
+			PeerDependenciesMeta: item.PeerDependenciesMeta,
+			BundleDependencies:   item.BundleDependencies,
+			Bin:                  item.Bin,
+			Engines:              item.Engines,
+			OS:                   item.OS,
+			CPU:                  item.CPU,
+		}
+
+		// npm only records "name" for aliased packages
+		if item.Name != extractLockPackageName(key) {
+			npmItem.Name = item.Name
+		}
+
+		if item.Link && strings.HasPrefix(item.Resolved, "file:") && cwd != "" {
+			if rel, err := filepath.Rel(cwd, strings.TrimPrefix(item.Resolved, "file:")); err == nil {
+				npmItem.Resolved = filepath.ToSlash(rel)
+			}
+		}
+
+		_, isNonDev := nonDev[key]
+		_, isNonOptional := nonOptional[key]
+		npmItem.Dev = item.Dev || (!isNonDev && isNonOptional)
+		npmItem.Optional = item.Optional || (!isNonOptional && isNonDev)
+
+		npmLock.Packages[key] = npmItem
+	}
+
+	return npmLock
+}
+
+// WriteNPMLockFile writes lock as an npm v3 package-lock.json to filePath
+func (p *PackageJSONParser) WriteNPMLockFile(lock *PackageLock, filePath string) error {
+	data, err := json.MarshalIndent(p.BuildNPMPackageLock(lock), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal npm lock file: %w", err)
+	}
+
+	if err := os.WriteFile(filePath, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write file %s: %w", filePath, err)
+	}
+
+	return nil
+}
+
+// reachableLockPaths walks the lock from the given top-level dependency maps
+// and returns every package path reached. Optional edges are only followed
+// when followOptional is true.
+func reachableLockPaths(lock *PackageLock, roots []map[string]string, followOptional bool) map[string]struct{} {
+	reached := make(map[string]struct{})
+	queue := []string{}
+
+	for _, deps := range roots {
+		for name := range deps {
+			if path, ok := resolveLockPath(lock.Packages, "", name); ok {
+				queue = append(queue, path)
+			}
+		}
+	}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		if _, seen := reached[current]; seen {
+			continue
+		}
+		reached[current] = struct{}{}
+
+		item := lock.Packages[current]
+		edges := []map[string]string{item.Dependencies, item.PeerDependencies}
+		if followOptional {
+			edges = append(edges, item.OptionalDependencies)
+		}
+
+		for _, deps := range edges {
+			for name := range deps {
+				if path, ok := resolveLockPath(lock.Packages, current, name); ok {
+					queue = append(queue, path)
+				}
+			}
+		}
+	}
+
+	return reached
+}
+
+// resolveLockPath finds the package path node would load for name when
+// required from parentPath, walking up the nested node_modules directories
+func resolveLockPath(packages map[string]PackageItem, parentPath, name string) (string, bool) {
+	dir := parentPath
+	for {
+		candidate := "node_modules/" + name
+		if dir != "" {
+			candidate = dir + "/node_modules/" + name
+		}
+		if _, ok := packages[candidate]; ok {
+			return candidate, true
+		}
+
+		if dir == "" {
+			return "", false
+		}
+
+		if idx := strings.LastIndex(dir, "/node_modules/"); idx != -1 {
+			dir = dir[:idx]
+		} else {
+			dir = ""
+		}
+	}
+}
+
+func extractLockPackageName(pkgPath string) string {
+	parts := strings.Split(pkgPath, "node_modules/")
+	return parts[len(parts)-1]
+}
+
+func nonEmpty(m map[string]string) map[string]string {
+	if len(m) == 0 {
+		return nil
+	}
+	return m
+}
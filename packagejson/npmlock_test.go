@@ -0,0 +1,198 @@
+package packagejson
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/ernesto27/go-npm/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestNPMLock() *PackageLock {
+	return &PackageLock{
+		Name:            "test-project",
+		Version:         "1.0.0",
+		LockfileVersion: 3,
+		Requires:        true,
+		Dependencies: map[string]string{
+			"express": "^4.18.0",
+		},
+		DevDependencies: map[string]string{
+			"jest": "^29.0.0",
+		},
+		OptionalDependencies: map[string]string{
+			"fsevents": "^2.3.0",
+		},
+		Packages: map[string]PackageItem{
+			"node_modules/express": {
+				Name:      "express",
+				Version:   "4.18.2",
+				Resolved:  "https://registry.npmjs.org/express/-/express-4.18.2.tgz",
+				Integrity: "sha512-express",
+				Etag:      `"abc"`,
+				Dependencies: map[string]string{
+					"debug": "2.6.9",
+				},
+			},
+			"node_modules/debug": {
+				Name:      "debug",
+				Version:   "2.6.9",
+				Resolved:  "https://registry.npmjs.org/debug/-/debug-2.6.9.tgz",
+				Integrity: "sha512-debug",
+			},
+			"node_modules/jest": {
+				Name:      "jest",
+				Version:   "29.5.0",
+				Resolved:  "https://registry.npmjs.org/jest/-/jest-29.5.0.tgz",
+				Integrity: "sha512-jest",
+				Dependencies: map[string]string{
+					"debug": "^4.3.4",
+					"chalk": "^4.0.0",
+				},
+			},
+			"node_modules/jest/node_modules/debug": {
+				Name:      "debug",
+				Version:   "4.3.4",
+				Resolved:  "https://registry.npmjs.org/debug/-/debug-4.3.4.tgz",
+				Integrity: "sha512-debug4",
+			},
+			"node_modules/chalk": {
+				Name:      "chalk",
+				Version:   "4.1.2",
+				Resolved:  "https://registry.npmjs.org/chalk/-/chalk-4.1.2.tgz",
+				Integrity: "sha512-chalk",
+			},
+			"node_modules/fsevents": {
+				Name:      "fsevents",
+				Version:   "2.3.3",
+				Resolved:  "https://registry.npmjs.org/fsevents/-/fsevents-2.3.3.tgz",
+				Integrity: "sha512-fsevents",
+				Scripts:   map[string]string{"install": "node install.js"},
+			},
+		},
+	}
+}
+
+func TestPackageJSONParser_BuildNPMPackageLock(t *testing.T) {
+	testCases := []struct {
+		name     string
+		lock     *PackageLock
+		validate func(t *testing.T, npmLock *NPMPackageLock)
+	}{
+		{
+			name: "Root entry holds the project dependencies",
+			lock: newTestNPMLock(),
+			validate: func(t *testing.T, npmLock *NPMPackageLock) {
+				assert.Equal(t, 3, npmLock.LockfileVersion)
+				assert.True(t, npmLock.Requires)
+				assert.Equal(t, "test-project", npmLock.Name)
+
+				root, ok := npmLock.Packages[""]
+				assert.True(t, ok, "root package entry should exist")
+				assert.Equal(t, "test-project", root.Name)
+				assert.Equal(t, "1.0.0", root.Version)
+				assert.Equal(t, map[string]string{"express": "^4.18.0"}, root.Dependencies)
+				assert.Equal(t, map[string]string{"jest": "^29.0.0"}, root.DevDependencies)
+				assert.Equal(t, map[string]string{"fsevents": "^2.3.0"}, root.OptionalDependencies)
+			},
+		},
+		{
+			name: "Dev and optional flags follow reachability",
+			lock: newTestNPMLock(),
+			validate: func(t *testing.T, npmLock *NPMPackageLock) {
+				assert.False(t, npmLock.Packages["node_modules/express"].Dev)
+				assert.False(t, npmLock.Packages["node_modules/debug"].Dev, "debug is shared with a production dependency")
+				assert.True(t, npmLock.Packages["node_modules/jest"].Dev)
+				assert.True(t, npmLock.Packages["node_modules/chalk"].Dev)
+				assert.True(t, npmLock.Packages["node_modules/jest/node_modules/debug"].Dev)
+				assert.True(t, npmLock.Packages["node_modules/fsevents"].Optional)
+				assert.False(t, npmLock.Packages["node_modules/fsevents"].Dev)
+				assert.False(t, npmLock.Packages["node_modules/express"].Optional)
+			},
+		},
+		{
+			name: "go-npm specific fields are stripped",
+			lock: newTestNPMLock(),
+			validate: func(t *testing.T, npmLock *NPMPackageLock) {
+				express := npmLock.Packages["node_modules/express"]
+				assert.Empty(t, express.Name)
+				assert.Equal(t, "4.18.2", express.Version)
+				assert.Equal(t, "sha512-express", express.Integrity)
+				assert.Equal(t, "https://registry.npmjs.org/express/-/express-4.18.2.tgz", express.Resolved)
+				assert.Equal(t, map[string]string{"debug": "2.6.9"}, express.Dependencies)
+				assert.True(t, npmLock.Packages["node_modules/fsevents"].HasInstallScript)
+				assert.False(t, express.HasInstallScript)
+
+				data, err := json.Marshal(npmLock)
+				assert.NoError(t, err)
+				for _, field := range []string{`"etag"`, `"scripts"`, `"skipped"`} {
+					assert.NotContains(t, string(data), field)
+				}
+			},
+		},
+		{
+			name: "Aliased packages keep their real name",
+			lock: &PackageLock{
+				Name:         "test-project",
+				Version:      "1.0.0",
+				Dependencies: map[string]string{"my-lodash": "npm:lodash@^4.17.21"},
+				Packages: map[string]PackageItem{
+					"node_modules/my-lodash": {
+						Name:    "lodash",
+						Version: "4.17.21",
+					},
+				},
+			},
+			validate: func(t *testing.T, npmLock *NPMPackageLock) {
+				assert.Equal(t, "lodash", npmLock.Packages["node_modules/my-lodash"].Name)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			parser := NewPackageJSONParser(nil, nil)
+			tc.validate(t, parser.BuildNPMPackageLock(tc.lock))
+		})
+	}
+}
+
+func TestPackageJSONParser_WriteNPMLockFileRoundTrip(t *testing.T) {
+	originalDir, err := os.Getwd()
+	assert.NoError(t, err)
+	defer os.Chdir(originalDir)
+	assert.NoError(t, os.Chdir(t.TempDir()))
+
+	cfg, err := config.New()
+	assert.NoError(t, err)
+
+	lock := newTestNPMLock()
+	parser := NewPackageJSONParser(cfg, nil)
+	assert.NoError(t, parser.WriteNPMLockFile(lock, LOCK_FILE_NAME_NPM))
+
+	data, err := os.ReadFile(LOCK_FILE_NAME_NPM)
+	assert.NoError(t, err)
+	var raw map[string]any
+	assert.NoError(t, json.Unmarshal(data, &raw))
+	assert.Contains(t, raw["packages"], "")
+
+	migrated := NewPackageJSONParser(cfg, nil)
+	assert.NoError(t, migrated.MigrateFromPackageLock())
+
+	assert.Equal(t, lock.Dependencies, migrated.PackageLock.Dependencies)
+	assert.Equal(t, lock.DevDependencies, migrated.PackageLock.DevDependencies)
+	assert.Equal(t, lock.OptionalDependencies, migrated.PackageLock.OptionalDependencies)
+	assert.Len(t, migrated.PackageLock.Packages, len(lock.Packages))
+
+	for key, item := range lock.Packages {
+		got, ok := migrated.PackageLock.Packages[key]
+		if !assert.True(t, ok, "missing package %s", key) {
+			continue
+		}
+		assert.Equal(t, item.Version, got.Version, key)
+		assert.Equal(t, item.Resolved, got.Resolved, key)
+		assert.Equal(t, item.Integrity, got.Integrity, key)
+		assert.Equal(t, item.Dependencies, got.Dependencies, key)
+	}
+}
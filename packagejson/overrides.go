@@ -0,0 +1,123 @@
+package packagejson
+
+import (
+	"sort"
+	"strings"
+)
+
+// OverrideSeparator joins the parent selectors of a nested override,
+// e.g. "foo>bar" overrides bar only inside foo's dependency tree
+const OverrideSeparator = ">"
+
+// GetOverrides flattens the npm "overrides" field into selector -> version pairs.
+// Nested objects become ">"-joined selectors ({"foo": {"bar": "1.0.0"}} is
+// "foo>bar"), a "." key overrides the parent package itself, and "$name"
+// references resolve to the version of name declared in the root package.json.
+func (p *PackageJSON) GetOverrides() map[string]string {
+	overrides := make(map[string]string)
+
+	m, ok := p.Overrides.(map[string]any)
+	if !ok {
+		return overrides
+	}
+
+	p.flattenOverrides(overrides, "", m)
+	return overrides
+}
+
+func (p *PackageJSON) flattenOverrides(overrides map[string]string, prefix string, m map[string]any) {
+	for key, value := range m {
+		selector := key
+		if key == "." {
+			selector = prefix
+		} else if prefix != "" {
+			selector = prefix + OverrideSeparator + key
+		}
+
+		if selector == "" {
+			continue
+		}
+
+		switch v := value.(type) {
+		case string:
+			overrides[selector] = p.resolveOverrideReference(v)
+		case map[string]any:
+			p.flattenOverrides(overrides, selector, v)
+		}
+	}
+}
+
+// resolveOverrideReference replaces a "$name" reference with the version of
+// name from the root dependencies, devDependencies or optionalDependencies
+func (p *PackageJSON) resolveOverrideReference(version string) string {
+	name, isReference := strings.CutPrefix(version, "$")
+	if !isReference {
+		return version
+	}
+
+	for _, deps := range []map[string]string{p.GetDependencies(), p.GetDevDependencies(), p.GetOptionalDependencies()} {
+		if v, ok := deps[name]; ok {
+			return v
+		}
+	}
+
+	return version
+}
+
+// ResolveOverride returns the override version for name when required through
+// ancestry (the names of the packages leading to it, outermost first). The most
+// specific matching selector wins: every parent in a selector must appear in
+// ancestry, in order, but not necessarily as the direct parent.
+func ResolveOverride(overrides map[string]string, ancestry []string, name string) (string, bool) {
+	best := ""
+	bestDepth := -1
+
+	selectors := make([]string, 0, len(overrides))
+	for selector := range overrides {
+		selectors = append(selectors, selector)
+	}
+	sort.Strings(selectors)
+
+	for _, selector := range selectors {
+		parts := strings.Split(selector, OverrideSeparator)
+		if overrideSelectorName(parts[len(parts)-1]) != name {
+			continue
+		}
+
+		parents := parts[:len(parts)-1]
+		if !matchesAncestry(parents, ancestry) {
+			continue
+		}
+
+		if len(parents) > bestDepth {
+			best = selector
+			bestDepth = len(parents)
+		}
+	}
+
+	if bestDepth == -1 {
+		return "", false
+	}
+
+	return overrides[best], true
+}
+
+// matchesAncestry reports whether parents appear in ancestry in order
+func matchesAncestry(parents, ancestry []string) bool {
+	i := 0
+	for _, ancestor := range ancestry {
+		if i < len(parents) && overrideSelectorName(parents[i]) == ancestor {
+			i++
+		}
+	}
+	return i == len(parents)
+}
+
+// overrideSelectorName strips an optional version from a selector part,
+// e.g. "foo@1.x" -> "foo" and "@scope/foo@2" -> "@scope/foo"
+func overrideSelectorName(part string) string {
+	if idx := strings.LastIndex(part, "@"); idx > 0 {
+		return part[:idx]
+	}
+	return part
+}
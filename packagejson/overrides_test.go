@@ -0,0 +1,121 @@
+package packagejson
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPackageJSON_GetOverrides(t *testing.T) {
+	testCases := []struct {
+		name     string
+		content  string
+		expected map[string]string
+	}{
+		{
+			name:     "no overrides field",
+			content:  `{"name": "app"}`,
+			expected: map[string]string{},
+		},
+		{
+			name:     "flat overrides",
+			content:  `{"overrides": {"bar": "1.0.0", "@scope/baz": "^2.0.0"}}`,
+			expected: map[string]string{"bar": "1.0.0", "@scope/baz": "^2.0.0"},
+		},
+		{
+			name:     "nested overrides with self key",
+			content:  `{"overrides": {"foo": {".": "1.2.0", "bar": {"qux": "3.0.0"}}}}`,
+			expected: map[string]string{"foo": "1.2.0", "foo>bar>qux": "3.0.0"},
+		},
+		{
+			name: "version references resolve from root dependencies",
+			content: `{
+				"dependencies": {"react": "18.2.0"},
+				"devDependencies": {"typescript": "5.1.0"},
+				"overrides": {"react": "$react", "foo": {"typescript": "$typescript"}, "missing": "$missing"}
+			}`,
+			expected: map[string]string{"react": "18.2.0", "foo>typescript": "5.1.0", "missing": "$missing"},
+		},
+		{
+			name:     "non object overrides are ignored",
+			content:  `{"overrides": ["bar"]}`,
+			expected: map[string]string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var pkg PackageJSON
+			assert.NoError(t, json.Unmarshal([]byte(tc.content), &pkg))
+			assert.Equal(t, tc.expected, pkg.GetOverrides())
+		})
+	}
+}
+
+func TestResolveOverride(t *testing.T) {
+	overrides := map[string]string{
+		"bar":         "1.0.0",
+		"foo>bar":     "2.0.0",
+		"foo@1>a>bar": "3.0.0",
+		"@scope/x":    "4.0.0",
+	}
+
+	testCases := []struct {
+		name          string
+		ancestry      []string
+		pkgName       string
+		expected      string
+		expectedFound bool
+	}{
+		{
+			name:          "global override applies at the root",
+			ancestry:      nil,
+			pkgName:       "bar",
+			expected:      "1.0.0",
+			expectedFound: true,
+		},
+		{
+			name:          "nested selector wins over global",
+			ancestry:      []string{"foo"},
+			pkgName:       "bar",
+			expected:      "2.0.0",
+			expectedFound: true,
+		},
+		{
+			name:          "parents do not need to be direct",
+			ancestry:      []string{"foo", "z", "a", "y"},
+			pkgName:       "bar",
+			expected:      "3.0.0",
+			expectedFound: true,
+		},
+		{
+			name:          "parents must appear in order",
+			ancestry:      []string{"a", "foo"},
+			pkgName:       "bar",
+			expected:      "2.0.0",
+			expectedFound: true,
+		},
+		{
+			name:          "scoped package name",
+			ancestry:      []string{"foo"},
+			pkgName:       "@scope/x",
+			expected:      "4.0.0",
+			expectedFound: true,
+		},
+		{
+			name:          "no matching selector",
+			ancestry:      []string{"foo"},
+			pkgName:       "qux",
+			expectedFound: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			version, found := ResolveOverride(overrides, tc.ancestry, tc.pkgName)
+			assert.Equal(t, tc.expectedFound, found)
+			assert.Equal(t, tc.expected, version)
+		})
+	}
+}
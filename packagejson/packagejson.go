@@ -3,13 +3,14 @@ package packagejson
 import (
 	"encoding/json"
 	"fmt"
+	"maps"
 	"os"
+	"slices"
 	"strings"
 
 	"github.com/ernesto27/go-npm/config"
 	"github.com/ernesto27/go-npm/yarnlock"
 
-	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
 )
 
@@ -17,6 +18,9 @@ const (
 	LOCK_FILE_NAME_GO_NPM = "go-npm-lock.json"
 	LOCK_FILE_NAME_NPM    = "package-lock.json"
 	LOCK_FILE_NAME_YARN   = "yarn.lock"
+
+	LOCK_FILE_NAME_SHRINKWRAP = "npm-shrinkwrap.json"
+	LOCK_FILE_NAME_PNPM       = "pnpm-lock.yaml"
 )
 
 type Dependency struct {
@@ -25,6 +29,31 @@ type Dependency struct {
 	Version    string
 	Etag       string
 	Nested     bool
+	Kind       DependencyKind
+}
+
+// DependencyKind is the package.json section a dependency belongs to
+type DependencyKind int
+
+const (
+	DependencyProd DependencyKind = iota
+	DependencyDev
+	DependencyOptional
+	DependencyPeer
+)
+
+// Section returns the package.json key for the dependency kind
+func (k DependencyKind) Section() string {
+	switch k {
+	case DependencyDev:
+		return "devDependencies"
+	case DependencyOptional:
+		return "optionalDependencies"
+	case DependencyPeer:
+		return "peerDependencies"
+	default:
+		return "dependencies"
+	}
 }
 
 type PackageJSON struct {
@@ -43,7 +72,11 @@ type PackageJSON struct {
 	OptionalDependencies any                 `json:"optionalDependencies"`
 	PeerDependencies     any                 `json:"peerDependencies"`
 	PeerDependenciesMeta map[string]PeerMeta `json:"peerDependenciesMeta"`
+	BundleDependencies   any                 `json:"bundleDependencies"`
+	BundledDependencies  any                 `json:"bundledDependencies"`
 	Engines              any                 `json:"engines"`
+	OS                   any                 `json:"os"`
+	CPU                  any                 `json:"cpu"`
 	Files                any                 `json:"files"`
 	Scripts              map[string]string   `json:"scripts"`
 	Main                 any                 `json:"main"`
@@ -53,6 +86,9 @@ type PackageJSON struct {
 	Private              bool                `json:"private"`
 	Workspaces           any                 `json:"workspaces"`
 	TrustedDependencies  []string            `json:"trustedDependencies"`
+	Overrides            any                 `json:"overrides"`
+	Resolutions          any                 `json:"resolutions"`
+	PublishConfig        any                 `json:"publishConfig"`
 }
 
 type Funding struct {
@@ -80,6 +116,34 @@ func (p *PackageJSON) GetPeerDependencies() map[string]string {
 	return extractDependencyMap(p.PeerDependencies)
 }
 
+// GetDependenciesOfKind returns the dependency map for the given package.json section
+func (p *PackageJSON) GetDependenciesOfKind(kind DependencyKind) map[string]string {
+	switch kind {
+	case DependencyDev:
+		return p.GetDevDependencies()
+	case DependencyOptional:
+		return p.GetOptionalDependencies()
+	case DependencyPeer:
+		return p.GetPeerDependencies()
+	default:
+		return p.GetDependencies()
+	}
+}
+
+// SetDependenciesOfKind replaces the dependency map for the given package.json section
+func (p *PackageJSON) SetDependenciesOfKind(kind DependencyKind, deps map[string]string) {
+	switch kind {
+	case DependencyDev:
+		p.DevDependencies = deps
+	case DependencyOptional:
+		p.OptionalDependencies = deps
+	case DependencyPeer:
+		p.PeerDependencies = deps
+	default:
+		p.Dependencies = deps
+	}
+}
+
 // GetWorkspaces extracts workspace patterns from package.json
 // Supports both array format: ["packages/*"] and object format: {"packages": ["packages/*"]}
 func (p *PackageJSON) GetWorkspaces() []string {
@@ -114,6 +178,16 @@ func (p *PackageJSON) GetWorkspaces() []string {
 	return []string{}
 }
 
+// GetOS returns the os constraints, given as a list or a single string
+func (p *PackageJSON) GetOS() []string {
+	return extractStringList(p.OS)
+}
+
+// GetCPU returns the cpu constraints, given as a list or a single string
+func (p *PackageJSON) GetCPU() []string {
+	return extractStringList(p.CPU)
+}
+
 func (p *PackageJSON) GetTrustedDependencies() []string {
 	if p.TrustedDependencies == nil {
 		return []string{}
@@ -121,6 +195,63 @@ func (p *PackageJSON) GetTrustedDependencies() []string {
 	return p.TrustedDependencies
 }
 
+func extractStringList(value any) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []string:
+		return v
+	case []any:
+		result := make([]string, 0, len(v))
+		for _, item := range v {
+			if str, ok := item.(string); ok {
+				result = append(result, str)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+// InvalidDependency is a dependency whose package.json value is not a
+// version string, e.g. an object in a malformed registry package
+type InvalidDependency struct {
+	Section string
+	Name    string
+	Value   any
+}
+
+// InvalidDependencies returns the entries the dependency getters skip
+// because their value is not a string, sorted by section and name
+func (p *PackageJSON) InvalidDependencies() []InvalidDependency {
+	var invalid []InvalidDependency
+	for _, kind := range []DependencyKind{DependencyProd, DependencyDev, DependencyOptional, DependencyPeer} {
+		var deps any
+		switch kind {
+		case DependencyDev:
+			deps = p.DevDependencies
+		case DependencyOptional:
+			deps = p.OptionalDependencies
+		case DependencyPeer:
+			deps = p.PeerDependencies
+		default:
+			deps = p.Dependencies
+		}
+
+		m, ok := deps.(map[string]any)
+		if !ok {
+			continue
+		}
+		names := slices.Sorted(maps.Keys(m))
+		for _, name := range names {
+			if _, ok := m[name].(string); !ok {
+				invalid = append(invalid, InvalidDependency{Section: kind.Section(), Name: name, Value: m[name]})
+			}
+		}
+	}
+	return invalid
+}
+
 func extractDependencyMap(deps any) map[string]string {
 	if deps == nil {
 		return make(map[string]string)
@@ -167,6 +298,10 @@ type PackageLock struct {
 	DevDependencies      map[string]string      `json:"devDependencies,omitempty"`
 	OptionalDependencies map[string]string      `json:"optionalDependencies,omitempty"`
 	PeerDependencies     map[string]string      `json:"peerDependencies,omitempty"`
+	Overrides            map[string]string      `json:"overrides,omitempty"`
+	Resolutions          map[string]string      `json:"resolutions,omitempty"`
+	Registries           map[string]string      `json:"registries,omitempty"`        // registry base URL per scope ("default" for unscoped packages)
+	HasInstallScripts    *bool                  `json:"hasInstallScripts,omitempty"` // whether any package has an install script; nil in older locks
 	Packages             map[string]PackageItem `json:"packages"`
 }
 
@@ -183,7 +318,9 @@ type PackageItem struct {
 	OptionalDependencies map[string]string   `json:"optionalDependencies,omitempty"`
 	PeerDependencies     map[string]string   `json:"peerDependencies,omitempty"`
 	PeerDependenciesMeta map[string]PeerMeta `json:"peerDependenciesMeta,omitempty"`
+	BundleDependencies   []string            `json:"bundleDependencies,omitempty"`
 	Optional             bool                `json:"optional,omitempty"`
+	Skipped              bool                `json:"skipped,omitempty"`
 	Dev                  bool                `json:"dev,omitempty"`
 	Bin                  any                 `json:"bin,omitempty"`
 	Engines              any                 `json:"engines,omitempty"`
@@ -192,6 +329,35 @@ type PackageItem struct {
 	Scripts              map[string]string   `json:"scripts,omitempty"`
 }
 
+// HasInstallScript reports whether the package declares a preinstall,
+// install or postinstall script
+func (i PackageItem) HasInstallScript() bool {
+	for _, hook := range []string{"preinstall", "install", "postinstall"} {
+		if _, ok := i.Scripts[hook]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// RecordInstallScripts sets HasInstallScripts from the packages of the lock
+func (l *PackageLock) RecordInstallScripts() {
+	hasInstallScripts := false
+	for pkgPath, item := range l.Packages {
+		if pkgPath != "" && !item.Link && item.HasInstallScript() {
+			hasInstallScripts = true
+			break
+		}
+	}
+	l.HasInstallScripts = &hasInstallScripts
+}
+
+// RunsInstallScripts reports whether installing the lock needs the script
+// pass, which is true unless the lock recorded that no package has one
+func (l *PackageLock) RunsInstallScripts() bool {
+	return l.HasInstallScripts == nil || *l.HasInstallScripts
+}
+
 func NewPackageJSONParser(cfg *config.Config, yarnParser *yarnlock.YarnLockParser) *PackageJSONParser {
 	return &PackageJSONParser{
 		Config:         cfg,
@@ -253,24 +419,49 @@ func (p *PackageJSONParser) ParseLockFile() (*PackageLock, error) {
 	return &packageLock, nil
 }
 
+// inMemory reports whether package.json and the lock file are only updated in
+// memory, for --dry-run and --no-save
+func (p *PackageJSONParser) inMemory() bool {
+	return p.Config != nil && (p.Config.DryRun || p.Config.NoSave)
+}
+
+// marshalLock encodes the lock as it is written to disk. encoding/json sorts
+// map keys, and the maps that are always written are never null, so the same
+// resolved tree gives the same bytes whether the lock is created or updated.
+func (p *PackageJSONParser) marshalLock(lock *PackageLock) ([]byte, error) {
+	if lock.Dependencies == nil {
+		lock.Dependencies = make(map[string]string)
+	}
+	if lock.Packages == nil {
+		lock.Packages = make(map[string]PackageItem)
+	}
+
+	content, err := json.MarshalIndent(p.lockToWrite(lock), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(content, '\n'), nil
+}
+
 func (p *PackageJSONParser) CreateLockFile(data *PackageLock, isGlobal bool) error {
 	lockFile := p.LockFileName
 	if isGlobal {
 		lockFile = p.Config.GlobalLockFile
 	}
 
-	file, err := os.Create(lockFile)
+	data.RecordInstallScripts()
+	if p.inMemory() {
+		p.PackageLock = data
+		return nil
+	}
 
+	content, err := p.marshalLock(data)
 	if err != nil {
-		return fmt.Errorf("failed to create file %s: %w", lockFile, err)
+		return fmt.Errorf("failed to write JSON to file %s: %w", lockFile, err)
 	}
-	defer file.Close()
-
-	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "  ")
 
-	if err := encoder.Encode(data); err != nil {
-		return fmt.Errorf("failed to write JSON to file %s: %w", lockFile, err)
+	if err := os.WriteFile(lockFile, content, 0644); err != nil {
+		return fmt.Errorf("failed to create file %s: %w", lockFile, err)
 	}
 
 	p.PackageLock = data
@@ -300,6 +491,13 @@ func (p *PackageJSONParser) UpdateLockFile(data *PackageLock, isGlobal bool) err
 		existingLock.Dependencies[key] = version
 	}
 
+	for key, version := range data.DevDependencies {
+		if existingLock.DevDependencies == nil {
+			existingLock.DevDependencies = make(map[string]string)
+		}
+		existingLock.DevDependencies[key] = version
+	}
+
 	for key, version := range data.OptionalDependencies {
 		if existingLock.OptionalDependencies == nil {
 			existingLock.OptionalDependencies = make(map[string]string)
@@ -307,6 +505,32 @@ func (p *PackageJSONParser) UpdateLockFile(data *PackageLock, isGlobal bool) err
 		existingLock.OptionalDependencies[key] = version
 	}
 
+	if data.Overrides != nil {
+		existingLock.Overrides = data.Overrides
+	}
+
+	if data.Resolutions != nil {
+		existingLock.Resolutions = data.Resolutions
+	}
+
+	// Keep the registries the existing packages were resolved against, so a
+	// registry change keeps being reported until the lock is re-resolved
+	for scope, registryURL := range data.Registries {
+		if existingLock.Registries == nil {
+			existingLock.Registries = make(map[string]string)
+		}
+		if _, ok := existingLock.Registries[scope]; !ok {
+			existingLock.Registries[scope] = registryURL
+		}
+	}
+
+	for key, version := range data.PeerDependencies {
+		if existingLock.PeerDependencies == nil {
+			existingLock.PeerDependencies = make(map[string]string)
+		}
+		existingLock.PeerDependencies[key] = version
+	}
+
 	if existingLock.Packages == nil {
 		existingLock.Packages = make(map[string]PackageItem)
 	}
@@ -319,13 +543,16 @@ func (p *PackageJSONParser) UpdateLockFile(data *PackageLock, isGlobal bool) err
 		existingLock.Packages[key] = packageItem
 	}
 
-	updatedContent, err := json.MarshalIndent(existingLock, "", "  ")
+	existingLock.RecordInstallScripts()
+	updatedContent, err := p.marshalLock(&existingLock)
 	if err != nil {
 		return fmt.Errorf("failed to marshal updated lock file: %w", err)
 	}
 
-	if err := os.WriteFile(lockFileName, updatedContent, 0644); err != nil {
-		return fmt.Errorf("failed to write lock file: %w", err)
+	if !p.inMemory() {
+		if err := os.WriteFile(lockFileName, updatedContent, 0644); err != nil {
+			return fmt.Errorf("failed to write lock file: %w", err)
+		}
 	}
 
 	p.PackageLock = &existingLock
@@ -352,7 +579,8 @@ func (p *PackageJSONParser) resolveVersionMismatch(existingLock *PackageLock, ke
 	}
 }
 
-func (p *PackageJSONParser) AddOrUpdateDependency(name string, version string) error {
+// AddOrUpdateDependency writes name@version into the package.json section for kind
+func (p *PackageJSONParser) AddOrUpdateDependency(name string, version string, kind DependencyKind) error {
 	if p.PackageJSONRoot == nil {
 		return fmt.Errorf("package.json not loaded, call Parse() first")
 	}
@@ -361,7 +589,7 @@ func (p *PackageJSONParser) AddOrUpdateDependency(name string, version string) e
 		return fmt.Errorf("original content not cached, call Parse() first")
 	}
 
-	deps := p.PackageJSONRoot.GetDependencies()
+	deps := p.PackageJSONRoot.GetDependenciesOfKind(kind)
 
 	if version == "" || version == "latest" {
 		if existingVersion, exists := p.PackageLock.Packages[name]; exists {
@@ -370,30 +598,18 @@ func (p *PackageJSONParser) AddOrUpdateDependency(name string, version string) e
 	}
 
 	deps[name] = version
-	p.PackageJSONRoot.Dependencies = deps
+	p.PackageJSONRoot.SetDependenciesOfKind(kind, deps)
 
-	// Check if dependency already exists (using cached content)
-	jsonStr := string(p.OriginalContentRoot)
-	existingValue := gjson.Get(jsonStr, "dependencies."+name)
-	isNewDependency := !existingValue.Exists()
-
-	// Use sjson to update the dependency
-	var err error
-	jsonStr, err = sjson.SetRaw(jsonStr, "dependencies."+name, fmt.Sprintf(`"%s"`, version))
+	jsonStr, err := setDependencyInJSON(string(p.OriginalContentRoot), kind.Section(), name, version)
 	if err != nil {
 		return fmt.Errorf("failed to update dependency: %w", err)
 	}
 
-	// Fix formatting if it's a new dependency (sjson adds it incorrectly)
-	if isNewDependency {
-		malformed := "\n  ,\"" + name + `":"` + version + `"}`
-		wellFormed := `,` + "\n" + `    "` + name + `": "` + version + `"` + "\n  }"
-		jsonStr = strings.Replace(jsonStr, malformed, wellFormed, 1)
-	}
-
 	// Write back to file
-	if err := os.WriteFile("package.json", []byte(jsonStr), 0644); err != nil {
-		return fmt.Errorf("failed to write file %s: %w", p.FilePath, err)
+	if !p.inMemory() {
+		if err := os.WriteFile("package.json", []byte(jsonStr), 0644); err != nil {
+			return fmt.Errorf("failed to write file %s: %w", p.FilePath, err)
+		}
 	}
 
 	// Update cached content for subsequent calls
@@ -422,6 +638,7 @@ func (p *PackageJSONParser) ResolveDependencies() (toInstall []Dependency, toRem
 			toInstall = append(toInstall, Dependency{
 				Name:    name,
 				Version: versionInJSON,
+				Kind:    DependencyDev,
 			})
 		}
 	}
@@ -432,6 +649,7 @@ func (p *PackageJSONParser) ResolveDependencies() (toInstall []Dependency, toRem
 			toInstall = append(toInstall, Dependency{
 				Name:    name,
 				Version: versionInJSON,
+				Kind:    DependencyOptional,
 			})
 		}
 	}
@@ -540,8 +758,10 @@ func (p *PackageJSONParser) RemoveDependencies(pkg string) error {
 		return fmt.Errorf("failed to remove dependency from package.json: %w", err)
 	}
 
-	if err := os.WriteFile("package.json", []byte(jsonStr), 0644); err != nil {
-		return fmt.Errorf("failed to write file package.json: %w", err)
+	if !p.inMemory() {
+		if err := os.WriteFile("package.json", []byte(jsonStr), 0644); err != nil {
+			return fmt.Errorf("failed to write file package.json: %w", err)
+		}
 	}
 
 	delete(deps, pkg)
@@ -583,24 +803,38 @@ func (p *PackageJSONParser) RemoveFromLockFile(pkg string, pkgToRemove []string,
 }
 
 func (p *PackageJSONParser) MigrateFromPackageLock() error {
-	lockData, err := os.ReadFile(LOCK_FILE_NAME_NPM)
+	return p.migrateFromNpmLock(LOCK_FILE_NAME_NPM)
+}
+
+// migrateFromNpmLock converts an npm lockfileVersion 2 or 3 lock, such as
+// package-lock.json or npm-shrinkwrap.json, to go-npm-lock.json
+func (p *PackageJSONParser) migrateFromNpmLock(lockFile string) error {
+	lockData, err := os.ReadFile(lockFile)
 	if err != nil {
-		return fmt.Errorf("failed to read %s: %w", LOCK_FILE_NAME_NPM, err)
+		return fmt.Errorf("failed to read %s: %w", lockFile, err)
 	}
 
 	var packageLock PackageLock
 	if err = json.Unmarshal(lockData, &packageLock); err != nil {
-		return fmt.Errorf("failed to parse %s: %w", LOCK_FILE_NAME_NPM, err)
+		return fmt.Errorf("failed to parse %s: %w", lockFile, err)
+	}
+	if packageLock.Packages == nil {
+		return fmt.Errorf("unsupported %s format: lockfileVersion %d has no packages section, only 2 and 3 are supported", lockFile, packageLock.LockfileVersion)
 	}
 
 	for key, item := range packageLock.Packages {
 		if key == "" {
 			packageLock.Dependencies = item.Dependencies
 			packageLock.DevDependencies = item.DevDependencies
+			packageLock.OptionalDependencies = item.OptionalDependencies
 			delete(packageLock.Packages, key)
 		}
 	}
 
+	if packageLock.Dependencies == nil {
+		packageLock.Dependencies = make(map[string]string)
+	}
+
 	err = p.CreateLockFile(&packageLock, false)
 	if err != nil {
 		return fmt.Errorf("failed to create go-npm lock file: %w", err)
@@ -612,15 +846,25 @@ func (p *PackageJSONParser) MigrateFromPackageLock() error {
 	return nil
 }
 
+// MigrateFromPnpmLock converts pnpm-lock.yaml to go-npm-lock.json and
+// returns the versions it dropped and the dependencies it left out
+func (p *PackageJSONParser) MigrateFromPnpmLock() ([]string, error) {
+	return p.migrateFromPnpmLock(LOCK_FILE_NAME_PNPM)
+}
+
 // MigrateFromYarnLock converts yarn.lock (v1) to go-npm-lock.json
 func (p *PackageJSONParser) MigrateFromYarnLock() error {
+	return p.migrateFromYarnLock(LOCK_FILE_NAME_YARN)
+}
+
+func (p *PackageJSONParser) migrateFromYarnLock(lockFile string) error {
 	if p.YarnLockParser == nil {
 		return fmt.Errorf("yarn lock parser not initialized")
 	}
 
-	lockData, err := os.ReadFile(LOCK_FILE_NAME_YARN)
+	lockData, err := os.ReadFile(lockFile)
 	if err != nil {
-		return fmt.Errorf("failed to read %s: %w", LOCK_FILE_NAME_YARN, err)
+		return fmt.Errorf("failed to read %s: %w", lockFile, err)
 	}
 
 	if !p.YarnLockParser.IsYarnV1(lockData) {
@@ -655,8 +899,14 @@ func (p *PackageJSONParser) convertYarnToPackageLock(yarnLock *yarnlock.YarnLock
 		Dependencies:    make(map[string]string),
 	}
 
-	// Get top-level dependencies from package.json
+	// Get top-level dependencies and resolutions from package.json
+	resolutions := map[string]string{}
 	if p.PackageJSONRoot != nil {
+		resolutions = p.PackageJSONRoot.GetResolutions()
+		if len(resolutions) > 0 {
+			packageLock.Resolutions = resolutions
+		}
+
 		deps := p.PackageJSONRoot.GetDependencies()
 		for name, version := range deps {
 			packageLock.Dependencies[name] = version
@@ -683,6 +933,15 @@ func (p *PackageJSONParser) convertYarnToPackageLock(yarnLock *yarnlock.YarnLock
 	for _, entry := range yarnLock.Entries {
 		pkgPath := "node_modules/" + entry.Name
 
+		// A resolution that applies at the top level decides which of several
+		// versions of a package is hoisted
+		if resolution, ok := ResolveResolution(resolutions, nil, entry.Name); ok {
+			existing, taken := packageLock.Packages[pkgPath]
+			if taken && (satisfiesResolution(existing.Version, resolution) || !satisfiesResolution(entry.Version, resolution)) {
+				continue
+			}
+		}
+
 		packageItem := PackageItem{
 			Name:      entry.Name,
 			Version:   entry.Version,
@@ -267,3 +267,270 @@ func TestPackageJSONParser_MigrateFromPackageLock(t *testing.T) {
 		})
 	}
 }
+
+func TestPackageJSONParser_AddOrUpdateDependency(t *testing.T) {
+	testCases := []struct {
+		name        string
+		packageJSON string
+		depName     string
+		version     string
+		kind        DependencyKind
+		expected    string
+	}{
+		{
+			name: "Adds to existing dependencies section",
+			packageJSON: `{
+  "name": "test-project",
+  "dependencies": {
+    "lodash": "^4.17.21"
+  }
+}`,
+			depName: "express",
+			version: "^4.18.0",
+			kind:    DependencyProd,
+			expected: `{
+  "name": "test-project",
+  "dependencies": {
+    "express": "^4.18.0",
+    "lodash": "^4.17.21"
+  }
+}`,
+		},
+		{
+			name: "Creates missing devDependencies section",
+			packageJSON: `{
+  "name": "test-project",
+  "dependencies": {
+    "lodash": "^4.17.21"
+  }
+}`,
+			depName: "jest",
+			version: "^29.0.0",
+			kind:    DependencyDev,
+			expected: `{
+  "name": "test-project",
+  "dependencies": {
+    "lodash": "^4.17.21"
+  },
+  "devDependencies": {
+    "jest": "^29.0.0"
+  }
+}`,
+		},
+		{
+			name: "Adds to existing optionalDependencies section",
+			packageJSON: `{
+  "name": "test-project",
+  "optionalDependencies": {
+    "fsevents": "^2.3.0"
+  }
+}`,
+			depName: "esbuild",
+			version: "^0.19.0",
+			kind:    DependencyOptional,
+			expected: `{
+  "name": "test-project",
+  "optionalDependencies": {
+    "esbuild": "^0.19.0",
+    "fsevents": "^2.3.0"
+  }
+}`,
+		},
+		{
+			name: "Appends after the last key when it sorts last",
+			packageJSON: `{
+  "name": "test-project",
+  "dependencies": {
+    "express": "^4.18.0",
+    "lodash": "^4.17.21"
+  }
+}
+`,
+			depName: "zod",
+			version: "^3.22.0",
+			kind:    DependencyProd,
+			expected: `{
+  "name": "test-project",
+  "dependencies": {
+    "express": "^4.18.0",
+    "lodash": "^4.17.21",
+    "zod": "^3.22.0"
+  }
+}
+`,
+		},
+		{
+			name:        "Keeps tab indentation",
+			packageJSON: "{\n\t\"name\": \"test-project\",\n\t\"dependencies\": {\n\t\t\"lodash\": \"^4.17.21\"\n\t}\n}\n",
+			depName:     "axios",
+			version:     "^1.6.0",
+			kind:        DependencyProd,
+			expected:    "{\n\t\"name\": \"test-project\",\n\t\"dependencies\": {\n\t\t\"axios\": \"^1.6.0\",\n\t\t\"lodash\": \"^4.17.21\"\n\t}\n}\n",
+		},
+		{
+			name:        "Creates a section with tab indentation",
+			packageJSON: "{\n\t\"name\": \"test-project\"\n}\n",
+			depName:     "jest",
+			version:     "^29.0.0",
+			kind:        DependencyDev,
+			expected:    "{\n\t\"name\": \"test-project\",\n\t\"devDependencies\": {\n\t\t\"jest\": \"^29.0.0\"\n\t}\n}\n",
+		},
+		{
+			name:        "Keeps CRLF line endings and 4-space indentation",
+			packageJSON: "{\r\n    \"name\": \"test-project\",\r\n    \"dependencies\": {\r\n        \"lodash\": \"^4.17.21\"\r\n    }\r\n}\r\n",
+			depName:     "zod",
+			version:     "^3.22.0",
+			kind:        DependencyProd,
+			expected:    "{\r\n    \"name\": \"test-project\",\r\n    \"dependencies\": {\r\n        \"lodash\": \"^4.17.21\",\r\n        \"zod\": \"^3.22.0\"\r\n    }\r\n}\r\n",
+		},
+		{
+			name: "Fills an empty section",
+			packageJSON: `{
+  "name": "test-project",
+  "devDependencies": {}
+}`,
+			depName: "jest",
+			version: "^29.0.0",
+			kind:    DependencyDev,
+			expected: `{
+  "name": "test-project",
+  "devDependencies": {
+    "jest": "^29.0.0"
+  }
+}`,
+		},
+		{
+			name: "Leaves other sections untouched",
+			packageJSON: `{
+  "name": "test-project",
+  "scripts": {"build": "tsc",   "test": "jest"},
+  "dependencies": {
+    "lodash": "^4.17.21"
+  },
+  "keywords": ["a", "b"]
+}`,
+			depName: "lodash.merge",
+			version: "^4.6.2",
+			kind:    DependencyProd,
+			expected: `{
+  "name": "test-project",
+  "scripts": {"build": "tsc",   "test": "jest"},
+  "dependencies": {
+    "lodash": "^4.17.21",
+    "lodash.merge": "^4.6.2"
+  },
+  "keywords": ["a", "b"]
+}`,
+		},
+		{
+			name: "Updates an existing peer dependency",
+			packageJSON: `{
+  "name": "test-project",
+  "peerDependencies": {
+    "react": "^17.0.0"
+  }
+}`,
+			depName: "react",
+			version: "^18.0.0",
+			kind:    DependencyPeer,
+			expected: `{
+  "name": "test-project",
+  "peerDependencies": {
+    "react": "^18.0.0"
+  }
+}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			originalDir, err := os.Getwd()
+			assert.NoError(t, err)
+			defer os.Chdir(originalDir)
+			assert.NoError(t, os.Chdir(t.TempDir()))
+
+			assert.NoError(t, os.WriteFile("package.json", []byte(tc.packageJSON), 0644))
+
+			parser := NewPackageJSONParser(nil, nil)
+			_, err = parser.ParseDefault()
+			assert.NoError(t, err)
+			parser.PackageLock = &PackageLock{Packages: map[string]PackageItem{}}
+
+			err = parser.AddOrUpdateDependency(tc.depName, tc.version, tc.kind)
+			assert.NoError(t, err)
+
+			data, err := os.ReadFile("package.json")
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, string(data))
+			assert.Equal(t, tc.version, parser.PackageJSONRoot.GetDependenciesOfKind(tc.kind)[tc.depName])
+
+			// Writing the same dependency again must not change the file
+			err = parser.AddOrUpdateDependency(tc.depName, tc.version, tc.kind)
+			assert.NoError(t, err)
+
+			data, err = os.ReadFile("package.json")
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, string(data))
+		})
+	}
+}
+
+func TestPackageJSON_InvalidDependencies(t *testing.T) {
+	testCases := []struct {
+		name     string
+		content  string
+		expected []InvalidDependency
+	}{
+		{
+			name:    "only string values",
+			content: `{"dependencies": {"a": "^1.0.0"}, "devDependencies": {"b": "2.0.0"}}`,
+		},
+		{
+			name:    "object and number values are reported",
+			content: `{"dependencies": {"a": "^1.0.0", "b": {"version": "1.0.0"}}, "peerDependencies": {"c": 2}}`,
+			expected: []InvalidDependency{
+				{Section: "dependencies", Name: "b", Value: map[string]any{"version": "1.0.0"}},
+				{Section: "peerDependencies", Name: "c", Value: float64(2)},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var pkg PackageJSON
+			assert.NoError(t, json.Unmarshal([]byte(tc.content), &pkg))
+
+			assert.Equal(t, tc.expected, pkg.InvalidDependencies())
+			assert.NotContains(t, pkg.GetDependencies(), "b")
+		})
+	}
+}
+
+func TestPackageJSONParser_LockFileIsStable(t *testing.T) {
+	dir := t.TempDir()
+	parser := NewPackageJSONParser(&config.Config{}, nil)
+	parser.LockFileName = filepath.Join(dir, LOCK_FILE_NAME_GO_NPM)
+
+	lock := func() *PackageLock {
+		return &PackageLock{
+			DevDependencies: map[string]string{"b": "^2.0.0", "a": "^1.0.0"},
+			Packages: map[string]PackageItem{
+				"node_modules/b": {Version: "2.0.0", Dependencies: map[string]string{"y": "1", "x": "1"}},
+				"node_modules/a": {Version: "1.0.0", BundleDependencies: []string{}},
+			},
+		}
+	}
+
+	assert.NoError(t, parser.CreateLockFile(lock(), false))
+	created, err := os.ReadFile(parser.LockFileName)
+	assert.NoError(t, err)
+	assert.Contains(t, string(created), `"dependencies": {},`)
+	assert.NotContains(t, string(created), "bundleDependencies")
+
+	// Updating with the same tree writes the same bytes
+	parser.LockFileContent = created
+	assert.NoError(t, parser.UpdateLockFile(lock(), false))
+	updated, err := os.ReadFile(parser.LockFileName)
+	assert.NoError(t, err)
+	assert.Equal(t, string(created), string(updated))
+}
@@ -0,0 +1,298 @@
+package packagejson
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/ernesto27/go-npm/config"
+	"gopkg.in/yaml.v3"
+)
+
+// pnpmLock is the part of pnpm-lock.yaml the migration reads. lockfileVersion
+// 6 lists the root project dependencies at the top level and the dependencies
+// of each package under packages; 9 moves the former under importers["."]
+// and the latter under snapshots, keyed by name@version(peers).
+type pnpmLock struct {
+	LockfileVersion string                  `yaml:"lockfileVersion"`
+	Importers       map[string]pnpmImporter `yaml:"importers"`
+	pnpmImporter    `yaml:",inline"`
+	Packages        map[string]pnpmPackage  `yaml:"packages"`
+	Snapshots       map[string]pnpmSnapshot `yaml:"snapshots"`
+}
+
+type pnpmImporter struct {
+	Dependencies         map[string]pnpmImporterDependency `yaml:"dependencies"`
+	DevDependencies      map[string]pnpmImporterDependency `yaml:"devDependencies"`
+	OptionalDependencies map[string]pnpmImporterDependency `yaml:"optionalDependencies"`
+}
+
+type pnpmImporterDependency struct {
+	Specifier string `yaml:"specifier"`
+	Version   string `yaml:"version"`
+}
+
+type pnpmPackage struct {
+	Version    string `yaml:"version"`
+	Resolution struct {
+		Integrity string `yaml:"integrity"`
+		Tarball   string `yaml:"tarball"`
+		Directory string `yaml:"directory"`
+		Repo      string `yaml:"repo"`
+		Commit    string `yaml:"commit"`
+	} `yaml:"resolution"`
+	PeerDependencies map[string]string `yaml:"peerDependencies"`
+	Engines          map[string]string `yaml:"engines"`
+	OS               []string          `yaml:"os"`
+	CPU              []string          `yaml:"cpu"`
+	pnpmSnapshot     `yaml:",inline"`
+}
+
+type pnpmSnapshot struct {
+	Dependencies         map[string]string `yaml:"dependencies"`
+	OptionalDependencies map[string]string `yaml:"optionalDependencies"`
+}
+
+// migrateFromPnpmLock converts pnpm-lock.yaml (lockfileVersion 6 or 9) to
+// go-npm-lock.json. Like yarn.lock, the conversion hoists every package: the
+// version the project depends on, else the highest, is kept and the others
+// are reported. Local directory and link: dependencies are left to install.
+func (p *PackageJSONParser) migrateFromPnpmLock(lockFile string) ([]string, error) {
+	lockData, err := os.ReadFile(lockFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", lockFile, err)
+	}
+
+	var pnpm pnpmLock
+	if err := yaml.Unmarshal(lockData, &pnpm); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", lockFile, err)
+	}
+	major, _, _ := strings.Cut(pnpm.LockfileVersion, ".")
+	if major != "6" && major != "9" {
+		return nil, fmt.Errorf("unsupported %s format: lockfileVersion %q, only 6 and 9 are supported", lockFile, pnpm.LockfileVersion)
+	}
+
+	packageLock, warnings := p.convertPnpmToPackageLock(&pnpm)
+
+	if err := p.CreateLockFile(packageLock, false); err != nil {
+		return nil, fmt.Errorf("failed to create go-npm lock file: %w", err)
+	}
+
+	p.PackageLock = packageLock
+	p.LockFileContent = lockData
+
+	return warnings, nil
+}
+
+// convertPnpmToPackageLock converts a parsed pnpm lock to PackageLock format
+func (p *PackageJSONParser) convertPnpmToPackageLock(pnpm *pnpmLock) (*PackageLock, []string) {
+	packageLock := &PackageLock{
+		LockfileVersion: 3,
+		Requires:        true,
+		Packages:        make(map[string]PackageItem),
+	}
+	if p.PackageJSONRoot != nil {
+		packageLock.Name = p.PackageJSONRoot.Name
+	}
+	root := pnpm.pnpmImporter
+	if importer, ok := pnpm.Importers["."]; ok {
+		root = importer
+	}
+	packageLock.Dependencies = pnpmSpecifiers(root.Dependencies)
+	packageLock.DevDependencies = nonEmpty(pnpmSpecifiers(root.DevDependencies))
+	packageLock.OptionalDependencies = nonEmpty(pnpmSpecifiers(root.OptionalDependencies))
+
+	// Each package key, without its peers suffix, with its dependencies from
+	// the first of its snapshots
+	packages := make(map[string]pnpmPackage)
+	for _, key := range slices.Sorted(maps.Keys(pnpm.Packages)) {
+		packages[pnpmPackageKey(key)] = pnpm.Packages[key]
+	}
+	for _, key := range slices.Sorted(maps.Keys(pnpm.Snapshots)) {
+		pkgKey := pnpmPackageKey(key)
+		if pkg, ok := packages[pkgKey]; ok && pkg.Dependencies == nil && pkg.OptionalDependencies == nil {
+			pkg.pnpmSnapshot = pnpm.Snapshots[key]
+			packages[pkgKey] = pkg
+		}
+	}
+
+	var warnings []string
+
+	// The versions the project depends on are hoisted, then the highest
+	// version of every other package
+	hoisted := make(map[string]string)
+	direct := make(map[string]bool)
+	for _, deps := range []map[string]pnpmImporterDependency{root.Dependencies, root.DevDependencies, root.OptionalDependencies} {
+		for _, name := range slices.Sorted(maps.Keys(deps)) {
+			version := deps[name].Version
+			if strings.HasPrefix(version, "link:") || strings.HasPrefix(version, "file:") {
+				warnings = append(warnings, fmt.Sprintf("%s is a local dependency (%s) and is not migrated, go-npm install links it", name, version))
+				continue
+			}
+			hoisted[name] = pnpmDependencyKey(name, version)
+			direct[name] = true
+		}
+	}
+	for _, key := range slices.Sorted(maps.Keys(packages)) {
+		if packages[key].Resolution.Directory != "" {
+			continue
+		}
+		name, version := splitPnpmKey(key)
+		if direct[name] {
+			continue
+		}
+		if current, taken := hoisted[name]; taken {
+			if _, currentVersion := splitPnpmKey(current); !pnpmNewer(version, currentVersion) {
+				continue
+			}
+		}
+		hoisted[name] = key
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(hoisted)) {
+		key := hoisted[name]
+		pkg, ok := packages[key]
+		if !ok {
+			warnings = append(warnings, fmt.Sprintf("%s is missing from the pnpm lock and is not migrated", key))
+			continue
+		}
+		packageLock.Packages["node_modules/"+name] = p.pnpmPackageItem(name, key, pkg)
+	}
+
+	// The Dev and Optional flags follow from the dependencies, as npm sets them
+	nonDev := reachableLockPaths(packageLock, []map[string]string{packageLock.Dependencies, packageLock.OptionalDependencies}, true)
+	nonOptional := reachableLockPaths(packageLock, []map[string]string{packageLock.Dependencies, packageLock.DevDependencies}, false)
+	for pkgPath, item := range packageLock.Packages {
+		_, isNonDev := nonDev[pkgPath]
+		_, isNonOptional := nonOptional[pkgPath]
+		item.Dev = !isNonDev && isNonOptional
+		item.Optional = !isNonOptional && isNonDev
+		packageLock.Packages[pkgPath] = item
+	}
+
+	kept := slices.Collect(maps.Values(hoisted))
+	for _, key := range slices.Sorted(maps.Keys(packages)) {
+		name, version := splitPnpmKey(key)
+		if slices.Contains(kept, key) || packages[key].Resolution.Directory != "" {
+			continue
+		}
+		_, keptVersion := splitPnpmKey(hoisted[name])
+		warnings = append(warnings, fmt.Sprintf("%s@%s is dropped, only %s@%s is locked", name, version, name, keptVersion))
+	}
+
+	return packageLock, warnings
+}
+
+// pnpmPackageItem converts the pnpm package at key, installed as name
+func (p *PackageJSONParser) pnpmPackageItem(name, key string, pkg pnpmPackage) PackageItem {
+	pkgName, version := splitPnpmKey(key)
+	if pkg.Version != "" {
+		version = pkg.Version
+	}
+
+	item := PackageItem{
+		Name:                 name,
+		Version:              version,
+		Integrity:            pkg.Resolution.Integrity,
+		PeerDependencies:     nonEmpty(pkg.PeerDependencies),
+		Dependencies:         nonEmpty(pnpmVersions(pkg.Dependencies)),
+		OptionalDependencies: nonEmpty(pnpmVersions(pkg.OptionalDependencies)),
+		OS:                   pkg.OS,
+		CPU:                  pkg.CPU,
+	}
+	if len(pkg.Engines) > 0 {
+		item.Engines = pkg.Engines
+	}
+
+	switch {
+	case pkg.Resolution.Commit != "":
+		item.Version = pkg.Resolution.Commit
+		item.Resolved = "git+" + pkg.Resolution.Repo + "#" + pkg.Resolution.Commit
+	case pkg.Resolution.Tarball != "":
+		item.Resolved = pkg.Resolution.Tarball
+	default:
+		baseName := pkgName[strings.LastIndex(pkgName, "/")+1:]
+		item.Resolved = p.pnpmRegistry(pkgName) + pkgName + "/-/" + baseName + "-" + version + ".tgz"
+	}
+	return item
+}
+
+// pnpmRegistry returns the registry a pnpm registry package is locked
+// against: the registry of its scope, else the configured one. pnpm does not
+// record it.
+func (p *PackageJSONParser) pnpmRegistry(name string) string {
+	if p.Config == nil {
+		return config.NPMRegistryURL
+	}
+	if scope, _, ok := strings.Cut(name, "/"); ok && p.Config.ScopeRegistries[scope] != "" {
+		return p.Config.ScopeRegistries[scope]
+	}
+	return p.Config.PrimaryRegistry()
+}
+
+// pnpmSpecifiers returns the ranges of importer dependencies, as written in
+// package.json
+func pnpmSpecifiers(deps map[string]pnpmImporterDependency) map[string]string {
+	specifiers := make(map[string]string, len(deps))
+	for name, dep := range deps {
+		specifiers[name] = dep.Specifier
+	}
+	return specifiers
+}
+
+// pnpmVersions strips the peers suffix of dependency versions, e.g.
+// "1.0.0(react@18.2.0)" -> "1.0.0", and turns aliases ("foo@1.0.0") into
+// npm: specs
+func pnpmVersions(deps map[string]string) map[string]string {
+	versions := make(map[string]string, len(deps))
+	for name, version := range deps {
+		version, _, _ = strings.Cut(version, "(")
+		if key := strings.TrimPrefix(version, "/"); len(key) > 1 && strings.Contains(key[1:], "@") {
+			version = "npm:" + key
+		}
+		versions[name] = version
+	}
+	return versions
+}
+
+// pnpmDependencyKey returns the package key a dependency on name resolved to
+// version refers to. An alias has the key of its package as version.
+func pnpmDependencyKey(name, version string) string {
+	version, _, _ = strings.Cut(version, "(")
+	if key := strings.TrimPrefix(version, "/"); len(key) > 1 && strings.Contains(key[1:], "@") {
+		return key
+	}
+	return name + "@" + version
+}
+
+// pnpmPackageKey returns key without the leading slash of lockfileVersion 6
+// or the peers suffix
+func pnpmPackageKey(key string) string {
+	key, _, _ = strings.Cut(strings.TrimPrefix(key, "/"), "(")
+	return key
+}
+
+// splitPnpmKey splits a package key into name and version
+func splitPnpmKey(key string) (string, string) {
+	if key == "" {
+		return "", ""
+	}
+	if i := strings.Index(key[1:], "@"); i >= 0 {
+		return key[:i+1], key[i+2:]
+	}
+	return key, ""
+}
+
+// pnpmNewer reports whether version is higher than current; versions that
+// are not semver are never newer
+func pnpmNewer(version, current string) bool {
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return false
+	}
+	c, err := semver.NewVersion(current)
+	return err != nil || v.GreaterThan(c)
+}
@@ -0,0 +1,135 @@
+package packagejson
+
+import (
+	"os"
+	"testing"
+
+	"github.com/ernesto27/go-npm/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPackageJSONParser_MigrateFromPnpmLock(t *testing.T) {
+	react := PackageItem{
+		Name:         "react",
+		Version:      "18.2.0",
+		Resolved:     "https://registry.npmjs.org/react/-/react-18.2.0.tgz",
+		Integrity:    "sha512-react",
+		Dependencies: map[string]string{"loose-envify": "1.4.0"},
+		Engines:      map[string]string{"node": ">=0.10.0"},
+	}
+	looseEnvify := PackageItem{
+		Name:         "loose-envify",
+		Version:      "1.4.0",
+		Resolved:     "https://registry.npmjs.org/loose-envify/-/loose-envify-1.4.0.tgz",
+		Integrity:    "sha512-envify",
+		Dependencies: map[string]string{"js-tokens": "4.0.0"},
+	}
+	jsTokens := PackageItem{
+		Name:      "js-tokens",
+		Version:   "4.0.0",
+		Resolved:  "https://registry.npmjs.org/js-tokens/-/js-tokens-4.0.0.tgz",
+		Integrity: "sha512-tokens4",
+	}
+	tester := PackageItem{
+		Name:      "tester",
+		Version:   "2.0.0",
+		Resolved:  "https://registry.npmjs.org/tester/-/tester-2.0.0.tgz",
+		Integrity: "sha512-tester",
+		Dev:       true,
+	}
+
+	testCases := []struct {
+		name                 string
+		fixture              string
+		expectedDependencies map[string]string
+		expectedPackages     map[string]PackageItem
+		expectedWarnings     []string
+		errorContains        string
+	}{
+		{
+			name:                 "lockfileVersion 9 packages and snapshots",
+			fixture:              "testdata/pnpm-lock-v9.yaml",
+			expectedDependencies: map[string]string{"@scope/ui": "^1.0.0", "react": "^18.2.0", "shared": "link:packages/shared"},
+			expectedPackages: map[string]PackageItem{
+				"node_modules/@scope/ui": {
+					Name:             "@scope/ui",
+					Version:          "1.2.0",
+					Resolved:         "https://npm.scope.example/@scope/ui/-/ui-1.2.0.tgz",
+					Integrity:        "sha512-ui",
+					Dependencies:     map[string]string{"react": "18.2.0"},
+					PeerDependencies: map[string]string{"react": ">=17"},
+				},
+				"node_modules/react":        react,
+				"node_modules/loose-envify": looseEnvify,
+				"node_modules/js-tokens":    jsTokens,
+				// The js-tokens it depends on is dropped for the hoisted one
+				"node_modules/tester": {
+					Name:         "tester",
+					Version:      "2.0.0",
+					Resolved:     "https://registry.npmjs.org/tester/-/tester-2.0.0.tgz",
+					Integrity:    "sha512-tester",
+					Dependencies: map[string]string{"js-tokens": "3.0.0"},
+					Dev:          true,
+				},
+			},
+			expectedWarnings: []string{
+				"shared is a local dependency (link:packages/shared) and is not migrated, go-npm install links it",
+				"js-tokens@3.0.0 is dropped, only js-tokens@4.0.0 is locked",
+			},
+		},
+		{
+			name:                 "lockfileVersion 6 packages",
+			fixture:              "testdata/pnpm-lock-v6.yaml",
+			expectedDependencies: map[string]string{"react": "^18.2.0"},
+			expectedPackages: map[string]PackageItem{
+				"node_modules/react":        react,
+				"node_modules/loose-envify": looseEnvify,
+				"node_modules/js-tokens":    jsTokens,
+				"node_modules/tester":       tester,
+			},
+		},
+		{
+			name:          "lockfileVersion 5 is not supported",
+			fixture:       "",
+			errorContains: `lockfileVersion "5.4", only 6 and 9 are supported`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			content := []byte("lockfileVersion: 5.4\n")
+			if tc.fixture != "" {
+				var err error
+				content, err = os.ReadFile(tc.fixture)
+				require.NoError(t, err)
+			}
+
+			originalDir, err := os.Getwd()
+			require.NoError(t, err)
+			defer os.Chdir(originalDir)
+			require.NoError(t, os.Chdir(t.TempDir()))
+			require.NoError(t, os.WriteFile(LOCK_FILE_NAME_PNPM, content, 0644))
+
+			cfg, err := config.New()
+			require.NoError(t, err)
+			cfg.Registries = nil
+			cfg.ScopeRegistries = map[string]string{"@scope": "https://npm.scope.example/"}
+			parser := NewPackageJSONParser(cfg, nil)
+
+			warnings, err := parser.Migrate(LOCK_FILE_NAME_PNPM)
+			if tc.errorContains != "" {
+				assert.ErrorContains(t, err, tc.errorContains)
+				assert.NoFileExists(t, LOCK_FILE_NAME_GO_NPM)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.FileExists(t, LOCK_FILE_NAME_GO_NPM)
+			assert.Equal(t, tc.expectedDependencies, parser.PackageLock.Dependencies)
+			assert.Equal(t, map[string]string{"tester": "^2.0.0"}, parser.PackageLock.DevDependencies)
+			assert.Equal(t, tc.expectedPackages, parser.PackageLock.Packages)
+			assert.Equal(t, tc.expectedWarnings, warnings)
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package packagejson
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrPrivatePackage is returned when publishing a package marked
+// "private": true
+var ErrPrivatePackage = errors.New("cannot publish private package")
+
+// GetPublishConfig returns the string settings of the "publishConfig" field,
+// e.g. {"registry": "https://npm.example.com/", "access": "public"}. They
+// override the matching config when the package is packed or published.
+func (p *PackageJSON) GetPublishConfig() map[string]string {
+	publishConfig := make(map[string]string)
+
+	m, ok := p.PublishConfig.(map[string]any)
+	if !ok {
+		return publishConfig
+	}
+
+	for key, value := range m {
+		if str, ok := value.(string); ok {
+			publishConfig[key] = str
+		}
+	}
+
+	return publishConfig
+}
+
+// PublishRegistry returns the registry the package is published to:
+// publishConfig.registry when set, otherwise defaultRegistry. The URL always
+// ends with a slash so package names can be appended to it.
+func (p *PackageJSON) PublishRegistry(defaultRegistry string) string {
+	registry := strings.TrimSpace(p.GetPublishConfig()["registry"])
+	if registry == "" {
+		registry = defaultRegistry
+	}
+
+	if !strings.HasSuffix(registry, "/") {
+		registry += "/"
+	}
+	return registry
+}
+
+// CheckPublishable fails with ErrPrivatePackage for a package marked
+// "private": true, which npm refuses to publish
+func (p *PackageJSON) CheckPublishable() error {
+	if p.Private {
+		return fmt.Errorf("%w %s", ErrPrivatePackage, p.Name)
+	}
+	return nil
+}
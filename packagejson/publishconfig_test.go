@@ -0,0 +1,154 @@
+package packagejson
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPackageJSON_GetPublishConfig(t *testing.T) {
+	testCases := []struct {
+		name        string
+		packageJSON string
+		expected    map[string]string
+	}{
+		{
+			name:        "no publishConfig",
+			packageJSON: `{"name": "app"}`,
+			expected:    map[string]string{},
+		},
+		{
+			name:        "string settings are returned",
+			packageJSON: `{"publishConfig": {"registry": "https://npm.example.com/", "access": "public", "tag": "next"}}`,
+			expected:    map[string]string{"registry": "https://npm.example.com/", "access": "public", "tag": "next"},
+		},
+		{
+			name:        "non-string values are ignored",
+			packageJSON: `{"publishConfig": {"access": "restricted", "provenance": true, "exports": {"./a": "./a.js"}}}`,
+			expected:    map[string]string{"access": "restricted"},
+		},
+		{
+			name:        "non-object publishConfig is ignored",
+			packageJSON: `{"publishConfig": "https://npm.example.com/"}`,
+			expected:    map[string]string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var pkg PackageJSON
+			require.NoError(t, json.Unmarshal([]byte(tc.packageJSON), &pkg))
+			assert.Equal(t, tc.expected, pkg.GetPublishConfig())
+		})
+	}
+}
+
+func TestPackageJSON_PublishRegistry(t *testing.T) {
+	const defaultRegistry = "https://registry.npmjs.org/"
+
+	testCases := []struct {
+		name        string
+		packageJSON string
+		expected    string
+	}{
+		{
+			name:        "default registry without publishConfig",
+			packageJSON: `{"name": "app"}`,
+			expected:    defaultRegistry,
+		},
+		{
+			name:        "publishConfig.registry overrides the default",
+			packageJSON: `{"publishConfig": {"registry": "https://npm.example.com/"}}`,
+			expected:    "https://npm.example.com/",
+		},
+		{
+			name:        "trailing slash is added",
+			packageJSON: `{"publishConfig": {"registry": "https://npm.example.com/api/npm"}}`,
+			expected:    "https://npm.example.com/api/npm/",
+		},
+		{
+			name:        "empty registry falls back to the default",
+			packageJSON: `{"publishConfig": {"registry": " ", "access": "public"}}`,
+			expected:    defaultRegistry,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var pkg PackageJSON
+			require.NoError(t, json.Unmarshal([]byte(tc.packageJSON), &pkg))
+			assert.Equal(t, tc.expected, pkg.PublishRegistry(defaultRegistry))
+		})
+	}
+}
+
+func TestPublishTargetsPublishConfigRegistry(t *testing.T) {
+	var mu sync.Mutex
+	newRegistry := func(received *[]string) *httptest.Server {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+			*received = append(*received, r.Method+" "+r.URL.EscapedPath())
+		}))
+		t.Cleanup(server.Close)
+		return server
+	}
+
+	var defaultReceived, customReceived []string
+	defaultRegistry := newRegistry(&defaultReceived)
+	customRegistry := newRegistry(&customReceived)
+
+	// publish is a stub of npm publish: a PUT of the package document to the
+	// registry at the escaped package name
+	publish := func(pkg *PackageJSON) {
+		require.NoError(t, pkg.CheckPublishable())
+		url := pkg.PublishRegistry(defaultRegistry.URL) + strings.Replace(pkg.Name, "/", "%2f", 1)
+		req, err := http.NewRequest(http.MethodPut, url, strings.NewReader(`{}`))
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	var pkg PackageJSON
+	require.NoError(t, json.Unmarshal([]byte(`{"name": "@corp/app", "version": "1.0.0", "publishConfig": {"registry": "`+customRegistry.URL+`"}}`), &pkg))
+	publish(&pkg)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"PUT /@corp%2fapp"}, customReceived)
+	assert.Empty(t, defaultReceived)
+}
+
+func TestPackageJSON_CheckPublishable(t *testing.T) {
+	testCases := []struct {
+		name        string
+		packageJSON string
+		expectError bool
+	}{
+		{name: "public package", packageJSON: `{"name": "lib", "version": "1.0.0"}`},
+		{name: "private false", packageJSON: `{"name": "lib", "private": false}`},
+		{name: "private package", packageJSON: `{"name": "@corp/app", "private": true}`, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var pkg PackageJSON
+			require.NoError(t, json.Unmarshal([]byte(tc.packageJSON), &pkg))
+
+			err := pkg.CheckPublishable()
+			if !tc.expectError {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, ErrPrivatePackage)
+			assert.EqualError(t, err, "cannot publish private package @corp/app")
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package packagejson
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// resolutionGlobstar matches any number of packages in a resolution pattern
+const resolutionGlobstar = "**"
+
+// GetResolutions returns the yarn "resolutions" field as pattern -> version
+// pairs, e.g. {"**/lodash": "4.17.21", "foo/**/bar": "1.0.0"}
+func (p *PackageJSON) GetResolutions() map[string]string {
+	resolutions := make(map[string]string)
+
+	m, ok := p.Resolutions.(map[string]any)
+	if !ok {
+		return resolutions
+	}
+
+	for pattern, value := range m {
+		if version, ok := value.(string); ok && pattern != "" {
+			resolutions[pattern] = version
+		}
+	}
+
+	return resolutions
+}
+
+// ResolveResolution returns the forced version for name when required through
+// ancestry (the names of the packages leading to it, outermost first).
+// Patterns are matched against the whole dependency path: "**" stands for any
+// number of packages, "foo/bar" only matches bar as a direct dependency of the
+// top-level foo, and a bare name applies at any depth like "**/name". The
+// pattern with the most package names wins.
+func ResolveResolution(resolutions map[string]string, ancestry []string, name string) (string, bool) {
+	depPath := append(append([]string{}, ancestry...), name)
+
+	best := ""
+	bestNames := -1
+
+	patterns := make([]string, 0, len(resolutions))
+	for pattern := range resolutions {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+
+	for _, pattern := range patterns {
+		segments := splitResolutionPattern(pattern)
+		if len(segments) == 1 {
+			segments = []string{resolutionGlobstar, segments[0]}
+		}
+
+		if !matchResolutionPattern(segments, depPath) {
+			continue
+		}
+
+		names := 0
+		for _, segment := range segments {
+			if segment != resolutionGlobstar {
+				names++
+			}
+		}
+		if names > bestNames {
+			best = pattern
+			bestNames = names
+		}
+	}
+
+	if bestNames == -1 {
+		return "", false
+	}
+
+	return resolutions[best], true
+}
+
+// splitResolutionPattern splits a pattern on "/" while keeping scoped names
+// together: "@scope/a/**/b" -> ["@scope/a", "**", "b"]
+func splitResolutionPattern(pattern string) []string {
+	parts := strings.Split(strings.Trim(pattern, "/"), "/")
+
+	segments := make([]string, 0, len(parts))
+	for i := 0; i < len(parts); i++ {
+		if strings.HasPrefix(parts[i], "@") && i+1 < len(parts) {
+			segments = append(segments, parts[i]+"/"+parts[i+1])
+			i++
+			continue
+		}
+		segments = append(segments, parts[i])
+	}
+
+	return segments
+}
+
+// matchResolutionPattern reports whether segments match the whole depPath
+func matchResolutionPattern(segments, depPath []string) bool {
+	if len(segments) == 0 {
+		return len(depPath) == 0
+	}
+
+	if segments[0] == resolutionGlobstar {
+		for skip := 0; skip <= len(depPath); skip++ {
+			if matchResolutionPattern(segments[1:], depPath[skip:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(depPath) == 0 || segments[0] != depPath[0] {
+		return false
+	}
+
+	return matchResolutionPattern(segments[1:], depPath[1:])
+}
+
+// satisfiesResolution reports whether version is the forced version or
+// falls inside a forced range
+func satisfiesResolution(version, resolution string) bool {
+	if version == resolution {
+		return true
+	}
+
+	constraint, err := semver.NewConstraint(resolution)
+	if err != nil {
+		return false
+	}
+
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return false
+	}
+
+	return constraint.Check(v)
+}
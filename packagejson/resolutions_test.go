@@ -0,0 +1,195 @@
+package packagejson
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ernesto27/go-npm/yarnlock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPackageJSON_GetResolutions(t *testing.T) {
+	testCases := []struct {
+		name        string
+		packageJSON string
+		expected    map[string]string
+	}{
+		{
+			name:        "no resolutions",
+			packageJSON: `{"name": "app"}`,
+			expected:    map[string]string{},
+		},
+		{
+			name:        "glob patterns are kept as is",
+			packageJSON: `{"resolutions": {"**/lodash": "4.17.21", "foo/**/bar": "1.0.0", "@scope/a/b": "2.0.0"}}`,
+			expected:    map[string]string{"**/lodash": "4.17.21", "foo/**/bar": "1.0.0", "@scope/a/b": "2.0.0"},
+		},
+		{
+			name:        "non-string values are ignored",
+			packageJSON: `{"resolutions": {"lodash": "4.17.21", "bad": {"nested": "1.0.0"}}}`,
+			expected:    map[string]string{"lodash": "4.17.21"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var pkg PackageJSON
+			require.NoError(t, json.Unmarshal([]byte(tc.packageJSON), &pkg))
+			assert.Equal(t, tc.expected, pkg.GetResolutions())
+		})
+	}
+}
+
+func TestResolveResolution(t *testing.T) {
+	resolutions := map[string]string{
+		"**/lodash":        "4.17.21",
+		"left-pad":         "1.3.0",
+		"foo/**/bar":       "2.0.0",
+		"foo/baz/bar":      "3.0.0",
+		"qux/bar":          "1.5.0",
+		"@scope/a/**/@s/b": "5.0.0",
+		"@types/node":      "20.0.0",
+	}
+
+	testCases := []struct {
+		name          string
+		ancestry      []string
+		pkgName       string
+		expected      string
+		expectedFound bool
+	}{
+		{
+			name:          "globstar matches at the top level",
+			pkgName:       "lodash",
+			expected:      "4.17.21",
+			expectedFound: true,
+		},
+		{
+			name:          "globstar matches deep in the tree",
+			ancestry:      []string{"a", "b", "c"},
+			pkgName:       "lodash",
+			expected:      "4.17.21",
+			expectedFound: true,
+		},
+		{
+			name:          "bare name applies at any depth",
+			ancestry:      []string{"a", "b"},
+			pkgName:       "left-pad",
+			expected:      "1.3.0",
+			expectedFound: true,
+		},
+		{
+			name:          "inner globstar matches a direct child",
+			ancestry:      []string{"foo"},
+			pkgName:       "bar",
+			expected:      "2.0.0",
+			expectedFound: true,
+		},
+		{
+			name:          "inner globstar matches a nested child",
+			ancestry:      []string{"foo", "x", "y"},
+			pkgName:       "bar",
+			expected:      "2.0.0",
+			expectedFound: true,
+		},
+		{
+			name:          "more specific pattern wins",
+			ancestry:      []string{"foo", "baz"},
+			pkgName:       "bar",
+			expected:      "3.0.0",
+			expectedFound: true,
+		},
+		{
+			name:          "path patterns are anchored at the root",
+			ancestry:      []string{"other", "qux"},
+			pkgName:       "bar",
+			expectedFound: false,
+		},
+		{
+			name:          "direct child pattern",
+			ancestry:      []string{"qux"},
+			pkgName:       "bar",
+			expected:      "1.5.0",
+			expectedFound: true,
+		},
+		{
+			name:          "scoped names in patterns",
+			ancestry:      []string{"@scope/a", "x"},
+			pkgName:       "@s/b",
+			expected:      "5.0.0",
+			expectedFound: true,
+		},
+		{
+			name:          "bare scoped name",
+			ancestry:      []string{"a"},
+			pkgName:       "@types/node",
+			expected:      "20.0.0",
+			expectedFound: true,
+		},
+		{
+			name:          "no matching pattern",
+			ancestry:      []string{"a"},
+			pkgName:       "react",
+			expectedFound: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			version, found := ResolveResolution(resolutions, tc.ancestry, tc.pkgName)
+			assert.Equal(t, tc.expectedFound, found)
+			assert.Equal(t, tc.expected, version)
+		})
+	}
+}
+
+func TestConvertYarnToPackageLockResolutions(t *testing.T) {
+	testCases := []struct {
+		name                string
+		resolutions         string
+		expectedVersion     string
+		expectedResolutions map[string]string
+	}{
+		{
+			name:                "top-level resolution picks the hoisted version",
+			resolutions:         `{"**/lodash": "4.17.21"}`,
+			expectedVersion:     "4.17.21",
+			expectedResolutions: map[string]string{"**/lodash": "4.17.21"},
+		},
+		{
+			name:                "range resolution",
+			resolutions:         `{"lodash": "~4.17.0"}`,
+			expectedVersion:     "4.17.21",
+			expectedResolutions: map[string]string{"lodash": "~4.17.0"},
+		},
+		{
+			name:                "nested resolution does not pick the hoisted version",
+			resolutions:         `{"foo/**/lodash": "3.10.1"}`,
+			expectedVersion:     "",
+			expectedResolutions: map[string]string{"foo/**/lodash": "3.10.1"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var root PackageJSON
+			require.NoError(t, json.Unmarshal([]byte(`{"name": "app", "dependencies": {"lodash": "^4.0.0"}, "resolutions": `+tc.resolutions+`}`), &root))
+
+			parser := &PackageJSONParser{PackageJSONRoot: &root}
+			lock := parser.convertYarnToPackageLock(&yarnlock.YarnLock{
+				Entries: map[string]yarnlock.YarnLockEntry{
+					"lodash@3.10.1":  {Name: "lodash", Version: "3.10.1"},
+					"lodash@4.17.21": {Name: "lodash", Version: "4.17.21"},
+				},
+			})
+
+			assert.Equal(t, tc.expectedResolutions, lock.Resolutions)
+			if tc.expectedVersion != "" {
+				assert.Equal(t, tc.expectedVersion, lock.Packages["node_modules/lodash"].Version)
+			} else {
+				assert.Contains(t, []string{"3.10.1", "4.17.21"}, lock.Packages["node_modules/lodash"].Version)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/ernesto27/go-npm/manifest"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -42,6 +43,32 @@ func TestParserParse(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "manifest with deprecated versions",
+			setup: func(t *testing.T) string {
+				t.Helper()
+				dir := t.TempDir()
+				filePath := filepath.Join(dir, "package.json")
+				content := `{"name":"example","dist-tags":{"latest":"1.2.0"},"versions":{` +
+					`"1.0.0":{"name":"example","version":"1.0.0","deprecated":"Critical bug, upgrade to 1.2.0"},` +
+					`"1.1.0":{"name":"example","version":"1.1.0","deprecated":false},` +
+					`"1.2.0":{"name":"example","version":"1.2.0"}}}`
+
+				if err := os.WriteFile(filePath, []byte(content), 0o600); err != nil {
+					t.Fatalf("write manifest: %v", err)
+				}
+
+				return filePath
+			},
+			validate: func(t *testing.T, filePath string) {
+				parser := New()
+				pkg, err := parser.Parse(filePath)
+				assert.NoError(t, err)
+				assert.Equal(t, manifest.Deprecation("Critical bug, upgrade to 1.2.0"), pkg.Versions["1.0.0"].Deprecated)
+				assert.Empty(t, pkg.Versions["1.1.0"].Deprecated)
+				assert.Empty(t, pkg.Versions["1.2.0"].Deprecated)
+			},
+		},
 		{
 			name: "file missing",
 			setup: func(t *testing.T) string {
@@ -0,0 +1,142 @@
+package peers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/ernesto27/go-npm/version"
+)
+
+// Dependent is a package requiring a peer dependency
+type Dependent struct {
+	Path      string `json:"path"`
+	Range     string `json:"range"`
+	Version   string `json:"version"` // version the dependent loads, empty if missing
+	Optional  bool   `json:"optional"`
+	Satisfied bool   `json:"satisfied"`
+}
+
+// Entry is one peer dependency with every package requiring it
+type Entry struct {
+	Name       string      `json:"name"`
+	Installed  string      `json:"installed"` // top-level version, empty if not installed
+	Dependents []Dependent `json:"dependents"`
+	Conflict   bool        `json:"conflict"` // some dependent is not satisfied
+}
+
+// BuildMatrix returns, sorted by name, every peer dependency recorded in the
+// lock with the packages requiring it. Each dependent is checked against the
+// copy it would load; a missing optional peer counts as satisfied.
+func BuildMatrix(lock *packagejson.PackageLock) []Entry {
+	versionInfo := version.New()
+	byName := make(map[string]*Entry)
+
+	for pkgPath, item := range lock.Packages {
+		for peerName, peerRange := range item.PeerDependencies {
+			entry, ok := byName[peerName]
+			if !ok {
+				entry = &Entry{Name: peerName, Installed: lock.Packages["node_modules/"+peerName].Version}
+				byName[peerName] = entry
+			}
+
+			dependent := Dependent{
+				Path:     pkgPath,
+				Range:    peerRange,
+				Optional: item.PeerDependenciesMeta[peerName].Optional,
+			}
+			if resolved, ok := lock.Resolve(pkgPath, peerName); ok {
+				dependent.Version = lock.Packages[resolved].Version
+				dependent.Satisfied = versionInfo.SatisfiesConstraint(dependent.Version, peerRange)
+			} else {
+				dependent.Satisfied = dependent.Optional
+			}
+
+			entry.Dependents = append(entry.Dependents, dependent)
+			if !dependent.Satisfied {
+				entry.Conflict = true
+			}
+		}
+	}
+
+	entries := make([]Entry, 0, len(byName))
+	for _, entry := range byName {
+		sort.Slice(entry.Dependents, func(i, j int) bool {
+			return entry.Dependents[i].Path < entry.Dependents[j].Path
+		})
+		entries = append(entries, *entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+
+	return entries
+}
+
+// Warnings describes every unsatisfied dependent in entries
+func Warnings(entries []Entry) []string {
+	var warnings []string
+	for _, entry := range entries {
+		for _, dependent := range entry.Dependents {
+			switch {
+			case dependent.Satisfied:
+			case dependent.Version == "":
+				warnings = append(warnings, fmt.Sprintf("%s requires peer %s@%s but it is not installed", dependent.Path, entry.Name, dependent.Range))
+			default:
+				warnings = append(warnings, fmt.Sprintf("%s requires peer %s@%s but version %s is installed", dependent.Path, entry.Name, dependent.Range, dependent.Version))
+			}
+		}
+	}
+	return warnings
+}
+
+// WriteTable prints entries as a table with one row per dependent. Rows of
+// unsatisfied dependents are marked so conflicts stand out.
+func WriteTable(w io.Writer, entries []Entry) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PEER\tINSTALLED\tDEPENDENT\tRANGE\tLOADS\tSTATUS")
+
+	for _, entry := range entries {
+		for i, dependent := range entry.Dependents {
+			name, installed := "", ""
+			if i == 0 {
+				name, installed = entry.Name, orDash(entry.Installed)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", name, installed, dependent.Path, dependent.Range, orDash(dependent.Version), status(dependent))
+		}
+	}
+
+	return tw.Flush()
+}
+
+// WriteJSON prints entries as an indented JSON array
+func WriteJSON(w io.Writer, entries []Entry) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(entries)
+}
+
+// status labels whether dependent is satisfied
+func status(dependent Dependent) string {
+	switch {
+	case dependent.Satisfied && dependent.Version == "":
+		return "ok (optional, missing)"
+	case dependent.Satisfied:
+		return "ok"
+	case dependent.Version == "":
+		return "✗ missing"
+	default:
+		return "✗ conflict"
+	}
+}
+
+// orDash shows an empty version as "-"
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
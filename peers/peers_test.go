@@ -0,0 +1,134 @@
+package peers
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildMatrix(t *testing.T) {
+	testCases := []struct {
+		name     string
+		lock     *packagejson.PackageLock
+		expected []Entry
+	}{
+		{
+			name: "overlapping but conflicting ranges",
+			lock: &packagejson.PackageLock{
+				Packages: map[string]packagejson.PackageItem{
+					"node_modules/react":      {Version: "18.2.0"},
+					"node_modules/ui-kit":     {Version: "1.0.0", PeerDependencies: map[string]string{"react": "^17.0.0 || ^18.0.0"}},
+					"node_modules/router":     {Version: "2.0.0", PeerDependencies: map[string]string{"react": ">=18.1.0"}},
+					"node_modules/old-charts": {Version: "0.9.0", PeerDependencies: map[string]string{"react": "^17.0.2"}},
+				},
+			},
+			expected: []Entry{{
+				Name:      "react",
+				Installed: "18.2.0",
+				Conflict:  true,
+				Dependents: []Dependent{
+					{Path: "node_modules/old-charts", Range: "^17.0.2", Version: "18.2.0", Satisfied: false},
+					{Path: "node_modules/router", Range: ">=18.1.0", Version: "18.2.0", Satisfied: true},
+					{Path: "node_modules/ui-kit", Range: "^17.0.0 || ^18.0.0", Version: "18.2.0", Satisfied: true},
+				},
+			}},
+		},
+		{
+			name: "nested copy is checked instead of the top-level one",
+			lock: &packagejson.PackageLock{
+				Packages: map[string]packagejson.PackageItem{
+					"node_modules/react":                         {Version: "18.2.0"},
+					"node_modules/old-charts":                    {Version: "0.9.0", PeerDependencies: map[string]string{"react": "^17.0.2"}},
+					"node_modules/old-charts/node_modules/react": {Version: "17.0.2"},
+				},
+			},
+			expected: []Entry{{
+				Name:      "react",
+				Installed: "18.2.0",
+				Dependents: []Dependent{
+					{Path: "node_modules/old-charts", Range: "^17.0.2", Version: "17.0.2", Satisfied: true},
+				},
+			}},
+		},
+		{
+			name: "missing peers, optional or not",
+			lock: &packagejson.PackageLock{
+				Packages: map[string]packagejson.PackageItem{
+					"node_modules/plugin": {
+						Version:              "1.0.0",
+						PeerDependencies:     map[string]string{"typescript": "^5.0.0", "eslint": "^8.0.0"},
+						PeerDependenciesMeta: map[string]packagejson.PeerMeta{"typescript": {Optional: true}},
+					},
+				},
+			},
+			expected: []Entry{
+				{
+					Name:       "eslint",
+					Conflict:   true,
+					Dependents: []Dependent{{Path: "node_modules/plugin", Range: "^8.0.0"}},
+				},
+				{
+					Name:       "typescript",
+					Dependents: []Dependent{{Path: "node_modules/plugin", Range: "^5.0.0", Optional: true, Satisfied: true}},
+				},
+			},
+		},
+		{
+			name:     "no peer dependencies",
+			lock:     &packagejson.PackageLock{Packages: map[string]packagejson.PackageItem{"node_modules/a": {Version: "1.0.0"}}},
+			expected: []Entry{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, BuildMatrix(tc.lock))
+		})
+	}
+}
+
+func TestMatrixOutput(t *testing.T) {
+	entries := []Entry{{
+		Name:      "react",
+		Installed: "18.2.0",
+		Conflict:  true,
+		Dependents: []Dependent{
+			{Path: "node_modules/old-charts", Range: "^17.0.2", Version: "18.2.0"},
+			{Path: "node_modules/router", Range: ">=18.1.0", Version: "18.2.0", Satisfied: true},
+			{Path: "node_modules/themes", Range: "^18.0.0"},
+		},
+	}}
+
+	t.Run("warnings", func(t *testing.T) {
+		assert.Equal(t, []string{
+			"node_modules/old-charts requires peer react@^17.0.2 but version 18.2.0 is installed",
+			"node_modules/themes requires peer react@^18.0.0 but it is not installed",
+		}, Warnings(entries))
+	})
+
+	t.Run("table", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, WriteTable(&buf, entries))
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		require.Len(t, lines, 4)
+		assert.Equal(t, []string{"PEER", "INSTALLED", "DEPENDENT", "RANGE", "LOADS", "STATUS"}, strings.Fields(lines[0]))
+		assert.Equal(t, []string{"react", "18.2.0", "node_modules/old-charts", "^17.0.2", "18.2.0", "✗", "conflict"}, strings.Fields(lines[1]))
+		assert.Equal(t, []string{"node_modules/router", ">=18.1.0", "18.2.0", "ok"}, strings.Fields(lines[2]))
+		assert.Equal(t, []string{"node_modules/themes", "^18.0.0", "-", "✗", "missing"}, strings.Fields(lines[3]))
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, WriteJSON(&buf, entries))
+
+		var decoded []Entry
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+		assert.Equal(t, entries, decoded)
+	})
+}
@@ -1,13 +1,70 @@
 package progress
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
 	"sync"
 	"time"
 
 	"github.com/briandowns/spinner"
 )
 
+// Renderers selected with --progress
+const (
+	RendererSpinner = "spinner" // a spinner showing the package being installed
+	RendererLines   = "lines"   // one line per completed package, for CI logs
+)
+
+// Phases a package goes through while it is fetched. The spinner shows how
+// many packages are in each.
+const (
+	PhaseResolving   = "resolving"
+	PhaseDownloading = "downloading"
+	PhaseExtracting  = "extracting"
+)
+
+// Warning kinds reported in the JSON summary
+const (
+	WarningPeer            = "peer"
+	WarningDeprecation     = "deprecation"
+	WarningSkippedOptional = "skipped-optional"
+	WarningEngine          = "engine"
+	WarningOther           = "other"
+)
+
+// Warning is a warning reported in the JSON summary
+type Warning struct {
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+}
+
+// Change is a package added, removed or updated by a command. Previous is
+// the version an updated package had before.
+type Change struct {
+	Name     string `json:"name"`
+	Version  string `json:"version"`
+	Previous string `json:"previous,omitempty"`
+}
+
+// Summary is the JSON object Finish prints in JSON mode
+type Summary struct {
+	Added          []Change  `json:"added"`
+	Removed        []Change  `json:"removed"`
+	Updated        []Change  `json:"updated"`
+	Total          int       `json:"total"`
+	ElapsedSeconds float64   `json:"elapsedSeconds"`
+	Warnings       []Warning `json:"warnings"`
+
+	// DryRun marks the summary of a --dry-run. Installs are the packages it
+	// would place in node_modules and Downloads those of them missing from
+	// the cache.
+	DryRun    bool     `json:"dryRun,omitempty"`
+	Installs  []Change `json:"installs,omitempty"`
+	Downloads []Change `json:"downloads,omitempty"`
+}
+
 type PackageInfo struct {
 	Name    string
 	Version string
@@ -21,6 +78,19 @@ type Progress struct {
 	mu         sync.Mutex
 	version    string
 	verbose    bool
+	lines      bool
+	json       bool
+	summary    Summary
+	warnings   []Warning
+	out        io.Writer
+	phases     map[string]int  // packages in each phase
+	completed  map[string]bool // package@version already printed by Complete
+}
+
+// Tracker follows one package through the phases, see Track
+type Tracker struct {
+	p     *Progress
+	phase string
 }
 
 // New creates a new Progress instance with the given version
@@ -29,18 +99,75 @@ func New(version string, verbose bool) *Progress {
 	s.Color("cyan")
 
 	return &Progress{
-		spinner:  s,
-		topLevel: make([]PackageInfo, 0),
-		version:  version,
-		verbose:  verbose,
+		spinner:   s,
+		startTime: time.Now(),
+		topLevel:  make([]PackageInfo, 0),
+		version:   version,
+		verbose:   verbose,
+		out:       os.Stdout,
+		phases:    make(map[string]int),
+		completed: make(map[string]bool),
+	}
+}
+
+// ValidateRenderer checks that renderer is spinner or lines
+func ValidateRenderer(renderer string) error {
+	switch renderer {
+	case RendererSpinner, RendererLines:
+		return nil
 	}
+	return fmt.Errorf("unknown progress renderer %q (expected spinner or lines)", renderer)
+}
+
+// SetRenderer selects the spinner or lines renderer. The lines renderer never
+// moves the cursor, so its output can be scraped from logs.
+func (p *Progress) SetRenderer(renderer string) {
+	p.lines = renderer == RendererLines
+}
+
+// SetJSON replaces the spinner and the human summary with a single JSON
+// object printed by Finish, so scripts can parse the result
+func (p *Progress) SetJSON(enabled bool) {
+	p.json = enabled
+}
+
+// JSON reports whether the summary is printed as JSON
+func (p *Progress) JSON() bool {
+	return p.json
+}
+
+// SetSummary sets the package changes and total reported by Finish in JSON
+// mode; the elapsed time and warnings are filled in by Finish
+func (p *Progress) SetSummary(summary Summary) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.summary = summary
+}
+
+// Report records a warning of the given kind for the JSON summary. It never
+// prints: callers print their own warnings outside JSON mode.
+func (p *Progress) Report(kind, message string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.warnings = append(p.warnings, Warning{Kind: kind, Message: message})
+}
+
+// SetOutput sets where progress is written, stdout by default
+func (p *Progress) SetOutput(w io.Writer) {
+	p.out = w
 }
 
 // Start prints the header and starts the spinner
 func (p *Progress) Start() {
 	p.startTime = time.Now()
-	fmt.Printf("go-npm install %s\n\n", p.version)
-	p.spinner.Suffix = " Resolving dependencies..."
+	if p.json {
+		return
+	}
+	fmt.Fprintf(p.out, "go-npm install %s\n\n", p.version)
+	if p.lines {
+		return
+	}
+	p.setSuffix(" Resolving dependencies...")
 	p.spinner.Start()
 }
 
@@ -49,15 +176,110 @@ func (p *Progress) Start() {
 func (p *Progress) SetStatus(msg string) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
-	p.spinner.Suffix = " " + msg
+	if p.json {
+		return
+	}
+	if p.lines {
+		if p.verbose {
+			fmt.Fprintf(p.out, "  %s\n", msg)
+		}
+		return
+	}
+	p.setSuffix(" " + msg)
 
 	if p.verbose {
 		p.spinner.Stop()
-		fmt.Printf("  %s\n", msg)
+		fmt.Fprintf(p.out, "  %s\n", msg)
 		p.spinner.Start()
 	}
 }
 
+// Complete reports that name@version has been installed, counting it in the
+// total. The lines renderer and --verbose print it once, in completion order;
+// otherwise the spinner only shows the count.
+func (p *Progress) Complete(name, version string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.totalCount++
+	p.updatePhases()
+
+	key := name + "@" + version
+	if p.json || !(p.lines || p.verbose) || p.completed[key] {
+		return
+	}
+	p.completed[key] = true
+	if p.lines || !p.spinner.Active() {
+		fmt.Fprintf(p.out, "✓ %s\n", key)
+		return
+	}
+	p.spinner.Stop()
+	fmt.Fprintf(p.out, "✓ %s\n", key)
+	p.spinner.Start()
+}
+
+// Track starts following a package in phase; an empty phase counts it in
+// none until Move. Many workers track packages at once.
+func (p *Progress) Track(phase string) *Tracker {
+	t := &Tracker{p: p}
+	t.Move(phase)
+	return t
+}
+
+// Move moves the package to phase, or out of every phase when phase is empty
+func (t *Tracker) Move(phase string) {
+	p := t.p
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if t.phase != "" {
+		p.phases[t.phase]--
+	}
+	if phase != "" {
+		p.phases[phase]++
+	}
+	t.phase = phase
+	p.updatePhases()
+}
+
+// Done stops following the package, whether it completed or failed
+func (t *Tracker) Done() {
+	t.Move("")
+}
+
+// updatePhases shows the packages in each phase and the installed count on
+// the spinner. It is called with p.mu held.
+func (p *Progress) updatePhases() {
+	if p.json || p.lines {
+		return
+	}
+	status := fmt.Sprintf(" %s %d / %s %d / %s %d", PhaseResolving, p.phases[PhaseResolving],
+		PhaseDownloading, p.phases[PhaseDownloading], PhaseExtracting, p.phases[PhaseExtracting])
+	if p.totalCount > 0 {
+		status += fmt.Sprintf(" · %d installed", p.totalCount)
+	}
+	p.setSuffix(status)
+}
+
+// setSuffix sets the spinner text under the spinner's own lock, which its
+// drawing goroutine reads it under
+func (p *Progress) setSuffix(suffix string) {
+	p.spinner.Lock()
+	defer p.spinner.Unlock()
+	p.spinner.Suffix = suffix
+}
+
+// Phases returns how many packages are in each phase
+func (p *Progress) Phases() map[string]int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	phases := make(map[string]int, len(p.phases))
+	for phase, n := range p.phases {
+		if n > 0 {
+			phases[phase] = n
+		}
+	}
+	return phases
+}
+
 // AddTopLevel adds a top-level package to be shown in the summary
 func (p *Progress) AddTopLevel(name, version string) {
 	p.mu.Lock()
@@ -74,30 +296,91 @@ func (p *Progress) IncrementCount() {
 
 // Finish stops the spinner and prints the final summary
 func (p *Progress) Finish() {
-	p.spinner.Stop()
+	if p.json {
+		p.finishJSON()
+		return
+	}
+	if !p.lines {
+		p.spinner.Stop()
+	}
 
 	// Print top-level packages with + prefix
 	for _, pkg := range p.topLevel {
-		fmt.Printf("+ %s@%s\n", pkg.Name, pkg.Version)
+		fmt.Fprintf(p.out, "+ %s@%s\n", pkg.Name, pkg.Version)
 	}
 
 	if len(p.topLevel) > 0 {
-		fmt.Println()
+		fmt.Fprintln(p.out)
 	}
 
 	// Print summary
 	duration := time.Since(p.startTime)
-	fmt.Printf("%d packages installed [%.2fs]\n", p.totalCount, duration.Seconds())
+	fmt.Fprintf(p.out, "%d packages installed [%.2fs]\n", p.totalCount, duration.Seconds())
 }
 
-// Warn prints a warning message (doesn't interrupt spinner)
+// Stop stops the spinner without printing the install summary
+func (p *Progress) Stop() {
+	if p.json || p.lines {
+		return
+	}
+	p.spinner.Stop()
+}
+
+// finishJSON prints the summary as a single line of JSON
+func (p *Progress) finishJSON() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	summary := p.summary
+	summary.ElapsedSeconds = time.Since(p.startTime).Seconds()
+	summary.Warnings = append([]Warning{}, p.warnings...)
+	for _, changes := range []*[]Change{&summary.Added, &summary.Removed, &summary.Updated} {
+		if *changes == nil {
+			*changes = []Change{}
+		}
+	}
+
+	data, err := json.Marshal(summary)
+	if err != nil {
+		return
+	}
+	fmt.Fprintln(p.out, string(data))
+}
+
+// Pause stops a running spinner while fn writes to the terminal and restarts
+// it afterwards, so the line is not drawn over
+func (p *Progress) Pause(fn func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.json || p.lines || !p.spinner.Active() {
+		fn()
+		return
+	}
+	p.spinner.Stop()
+	fn()
+	p.spinner.Start()
+}
+
+// Warn prints a warning message (doesn't interrupt spinner). In JSON mode
+// it is only recorded for the summary.
 func (p *Progress) Warn(format string, args ...interface{}) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
+	if p.json {
+		p.warnings = append(p.warnings, Warning{Kind: WarningOther, Message: fmt.Sprintf(format, args...)})
+		return
+	}
+
+	if p.lines {
+		fmt.Fprintf(p.out, "warning: "+format+"\n", args...)
+		return
+	}
+
 	// Temporarily stop spinner to print warning cleanly
 	p.spinner.Stop()
-	fmt.Printf("warning: "+format+"\n", args...)
+	fmt.Fprintf(p.out, "warning: "+format+"\n", args...)
 	p.spinner.Start()
 }
 
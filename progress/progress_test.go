@@ -1,9 +1,14 @@
 package progress
 
 import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNew(t *testing.T) {
@@ -255,3 +260,139 @@ func TestPackageInfo(t *testing.T) {
 		})
 	}
 }
+
+func TestComplete(t *testing.T) {
+	testCases := []struct {
+		name     string
+		renderer string
+		verbose  bool
+		expected string
+	}{
+		{name: "lines renderer prints each package", renderer: RendererLines, expected: "✓ is-odd@3.0.1\n✓ @scope/pkg@1.0.0\n"},
+		{name: "spinner renderer prints nothing", renderer: RendererSpinner, expected: ""},
+		{name: "verbose spinner prints each package", renderer: RendererSpinner, verbose: true, expected: "✓ is-odd@3.0.1\n✓ @scope/pkg@1.0.0\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			p := New("1.0.0", tc.verbose)
+			p.SetRenderer(tc.renderer)
+			p.SetOutput(&out)
+
+			p.Complete("is-odd", "3.0.1")
+			p.Complete("@scope/pkg", "1.0.0")
+			// A package placed at a second path is counted but printed once
+			p.Complete("is-odd", "3.0.1")
+
+			assert.Equal(t, tc.expected, out.String())
+			assert.Equal(t, 3, p.totalCount)
+		})
+	}
+}
+
+func TestTrack(t *testing.T) {
+	p := New("1.0.0", false)
+
+	resolving := p.Track(PhaseResolving)
+	downloading := p.Track(PhaseResolving)
+	downloading.Move(PhaseDownloading)
+	idle := p.Track("")
+	assert.Equal(t, map[string]int{PhaseResolving: 1, PhaseDownloading: 1}, p.Phases())
+	assert.Equal(t, " resolving 1 / downloading 1 / extracting 0", p.spinner.Suffix)
+
+	downloading.Move(PhaseExtracting)
+	p.Complete("is-odd", "3.0.1")
+	assert.Equal(t, " resolving 1 / downloading 0 / extracting 1 · 1 installed", p.spinner.Suffix)
+
+	resolving.Done()
+	downloading.Done()
+	downloading.Done()
+	idle.Done()
+	assert.Empty(t, p.Phases())
+}
+
+func TestTrackConcurrent(t *testing.T) {
+	p := New("1.0.0", false)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tracker := p.Track(PhaseResolving)
+			defer tracker.Done()
+			tracker.Move(PhaseDownloading)
+			tracker.Move(PhaseExtracting)
+			p.Complete("pkg", "1.0.0")
+		}()
+	}
+	wg.Wait()
+
+	assert.Empty(t, p.Phases())
+	assert.Equal(t, 50, p.totalCount)
+}
+
+func TestPause(t *testing.T) {
+	testCases := []struct {
+		name     string
+		renderer string
+		json     bool
+	}{
+		{name: "spinner renderer", renderer: RendererSpinner},
+		{name: "lines renderer", renderer: RendererLines},
+		{name: "json mode", json: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := New("1.0.0", false)
+			p.SetRenderer(tc.renderer)
+			p.SetJSON(tc.json)
+
+			ran := false
+			p.Pause(func() { ran = true })
+
+			assert.True(t, ran)
+			assert.False(t, p.spinner.Active(), "a spinner that was not running must not be started")
+		})
+	}
+}
+
+func TestValidateRenderer(t *testing.T) {
+	assert.NoError(t, ValidateRenderer(RendererSpinner))
+	assert.NoError(t, ValidateRenderer(RendererLines))
+	assert.ErrorContains(t, ValidateRenderer("bars"), `unknown progress renderer "bars"`)
+}
+
+func TestFinishJSON(t *testing.T) {
+	var out bytes.Buffer
+	p := New("1.0.0", false)
+	p.SetJSON(true)
+	p.SetOutput(&out)
+
+	p.Start()
+	p.SetStatus("↓ is-odd@3.0.1")
+	p.Complete("is-odd", "3.0.1")
+	p.Report(WarningPeer, "react@18.2.0 requires react-dom")
+	p.Warn("slow registry")
+	p.SetSummary(Summary{
+		Added: []Change{{Name: "is-odd", Version: "3.0.1"}},
+		Total: 1,
+	})
+	p.Finish()
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 1, "JSON mode should print only the summary")
+
+	var summary Summary
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &summary))
+	assert.Equal(t, []Change{{Name: "is-odd", Version: "3.0.1"}}, summary.Added)
+	assert.Equal(t, []Change{}, summary.Removed)
+	assert.Equal(t, []Change{}, summary.Updated)
+	assert.Equal(t, 1, summary.Total)
+	assert.Equal(t, []Warning{
+		{Kind: WarningPeer, Message: "react@18.2.0 requires react-dom"},
+		{Kind: WarningOther, Message: "slow registry"},
+	}, summary.Warnings)
+}
@@ -0,0 +1,295 @@
+package prune
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/ernesto27/go-npm/utils"
+)
+
+const nodeModules = "node_modules"
+
+// Pruner finds packages in node_modules that the lock file does not expect
+type Pruner struct {
+	lock        *packagejson.PackageLock
+	production  bool
+	packagesDir string
+	storeDir    string
+}
+
+// New creates a Pruner for lock
+func New(lock *packagejson.PackageLock) *Pruner {
+	return &Pruner{lock: lock}
+}
+
+// SetProduction also treats packages only reachable from devDependencies as
+// extraneous
+func (p *Pruner) SetProduction(production bool) {
+	p.production = production
+}
+
+// SetPackagesDir sets the package cache. Unexpected symlinks into it were
+// placed by the symlink install strategy and are pruned; other symlinks
+// (workspaces, `go-npm link`) are always kept.
+func (p *Pruner) SetPackagesDir(dir string) {
+	p.packagesDir = dir
+}
+
+// SetStoreDir sets the content store, whose entries symlinked packages point
+// to instead of the package cache when the store is enabled
+func (p *Pruner) SetStoreDir(dir string) {
+	p.storeDir = dir
+}
+
+// Plan returns the sorted lock-style paths ("node_modules/a/node_modules/b")
+// of the extraneous packages in the node_modules tree under dir. Nested
+// node_modules are only scanned inside expected packages, and the packages
+// they bundle are kept whole.
+func (p *Pruner) Plan(dir string) ([]string, error) {
+	expected := p.expected()
+	bundled := p.bundled(expected)
+
+	var extraneous []string
+	pending := []string{nodeModules}
+	for len(pending) > 0 {
+		current := pending[0]
+		pending = pending[1:]
+
+		paths, err := packagePaths(dir, current)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, pkgPath := range paths {
+			info, err := os.Lstat(filepath.Join(dir, pkgPath))
+			if err != nil {
+				return nil, fmt.Errorf("failed to stat %s: %w", pkgPath, err)
+			}
+			isLink := info.Mode()&os.ModeSymlink != 0
+
+			if bundled[pkgPath] {
+				continue
+			}
+			if expected[pkgPath] {
+				if !isLink {
+					pending = append(pending, pkgPath+"/"+nodeModules)
+				}
+				continue
+			}
+
+			if isLink && !p.isCacheLink(filepath.Join(dir, pkgPath)) {
+				continue
+			}
+			extraneous = append(extraneous, pkgPath)
+		}
+	}
+
+	sort.Strings(extraneous)
+	return extraneous, nil
+}
+
+// Apply removes paths from the node_modules tree under dir, then the .bin
+// links and @scope directories they leave behind, and returns the bytes
+// freed. With dryRun nothing is removed; the size is only measured.
+func Apply(dir string, paths []string, dryRun bool) (int64, error) {
+	var saved int64
+
+	for _, pkgPath := range paths {
+		fullPath := filepath.Join(dir, pkgPath)
+		size, err := utils.DirSize(fullPath)
+		if err != nil {
+			return saved, err
+		}
+		saved += size
+
+		if dryRun {
+			continue
+		}
+		if err := os.RemoveAll(fullPath); err != nil {
+			return saved, fmt.Errorf("failed to remove %s: %w", fullPath, err)
+		}
+
+		// Drop the @scope directory once its last package is gone
+		if parent := filepath.Dir(fullPath); strings.HasPrefix(filepath.Base(parent), "@") {
+			if entries, err := os.ReadDir(parent); err == nil && len(entries) == 0 {
+				os.Remove(parent)
+			}
+		}
+	}
+
+	if dryRun || len(paths) == 0 {
+		return saved, nil
+	}
+
+	return saved, removeDanglingBins(filepath.Join(dir, nodeModules, ".bin"))
+}
+
+// expected returns the lock paths that belong in node_modules. With
+// production, only packages reachable from the root's and the workspaces'
+// dependencies, optional dependencies and peer dependencies are expected.
+func (p *Pruner) expected() map[string]bool {
+	expected := make(map[string]bool)
+
+	if !p.production {
+		for key := range p.lock.Packages {
+			if key != "" {
+				expected[key] = true
+			}
+		}
+		return expected
+	}
+
+	// Workspace packages live outside node_modules and are production roots
+	// like the project itself
+	pending := []string{""}
+	for key, item := range p.lock.Packages {
+		if item.Link {
+			expected[key] = true
+		} else if key != "" && !strings.HasPrefix(key, nodeModules+"/") {
+			pending = append(pending, key)
+		}
+	}
+
+	visited := make(map[string]bool)
+	for len(pending) > 0 {
+		current := pending[0]
+		pending = pending[1:]
+		if visited[current] {
+			continue
+		}
+		visited[current] = true
+		if strings.HasPrefix(current, nodeModules+"/") {
+			expected[current] = true
+		}
+
+		for name := range p.productionRequires(current) {
+			if resolved, ok := p.lock.Resolve(current, name); ok {
+				pending = append(pending, resolved)
+			}
+		}
+	}
+
+	return expected
+}
+
+// bundled returns the lock-style paths of the packages the expected packages
+// ship inside their own node_modules, which the lock does not list
+func (p *Pruner) bundled(expected map[string]bool) map[string]bool {
+	bundled := make(map[string]bool)
+	for pkgPath := range expected {
+		for _, name := range p.lock.Packages[pkgPath].BundleDependencies {
+			bundled[pkgPath+"/"+nodeModules+"/"+name] = true
+		}
+	}
+	return bundled
+}
+
+// productionRequires returns the names pkgPath (or the root, at "") needs
+// at runtime
+func (p *Pruner) productionRequires(pkgPath string) map[string]bool {
+	var sections []map[string]string
+	if pkgPath == "" {
+		sections = []map[string]string{p.lock.Dependencies, p.lock.OptionalDependencies, p.lock.PeerDependencies}
+	} else {
+		item := p.lock.Packages[pkgPath]
+		sections = []map[string]string{item.Dependencies, item.OptionalDependencies, item.PeerDependencies}
+	}
+
+	names := make(map[string]bool)
+	for _, section := range sections {
+		for name := range section {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// isCacheLink reports whether the symlink at linkPath points into the
+// package cache
+func (p *Pruner) isCacheLink(linkPath string) bool {
+	target, err := os.Readlink(linkPath)
+	if err != nil {
+		return false
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(linkPath), target)
+	}
+
+	return isWithin(p.packagesDir, target) || isWithin(p.storeDir, target)
+}
+
+// isWithin reports whether target is inside dir, false for an unset dir
+func isWithin(dir, target string) bool {
+	if dir == "" {
+		return false
+	}
+	rel, err := filepath.Rel(dir, target)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// packagePaths lists the packages in the node_modules directory at the lock
+// path nodeModulesPath, descending into @scope directories. Dot entries
+// such as .bin are skipped.
+func packagePaths(dir, nodeModulesPath string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(dir, nodeModulesPath))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", nodeModulesPath, err)
+	}
+
+	var paths []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+
+		if !strings.HasPrefix(name, "@") || !entry.IsDir() {
+			paths = append(paths, nodeModulesPath+"/"+name)
+			continue
+		}
+
+		scoped, err := os.ReadDir(filepath.Join(dir, nodeModulesPath, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s/%s: %w", nodeModulesPath, name, err)
+		}
+		for _, scopedEntry := range scoped {
+			if !strings.HasPrefix(scopedEntry.Name(), ".") {
+				paths = append(paths, nodeModulesPath+"/"+name+"/"+scopedEntry.Name())
+			}
+		}
+	}
+
+	return paths, nil
+}
+
+// removeDanglingBins removes the links in binDir whose target is gone
+func removeDanglingBins(binDir string) error {
+	entries, err := os.ReadDir(binDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read %s: %w", binDir, err)
+	}
+
+	for _, entry := range entries {
+		binPath := filepath.Join(binDir, entry.Name())
+		if entry.Type()&os.ModeSymlink == 0 {
+			continue
+		}
+		if _, err := os.Stat(binPath); os.IsNotExist(err) {
+			if err := os.Remove(binPath); err != nil {
+				return fmt.Errorf("failed to remove %s: %w", binPath, err)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,162 @@
+package prune
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ernesto27/go-npm/packagejson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writePackage(t *testing.T, dir, pkgPath string) {
+	t.Helper()
+	pkgDir := filepath.Join(dir, pkgPath)
+	require.NoError(t, os.MkdirAll(pkgDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(pkgDir, "package.json"), []byte(`{"name": "`+filepath.Base(pkgPath)+`"}`), 0644))
+}
+
+func testLock() *packagejson.PackageLock {
+	return &packagejson.PackageLock{
+		Dependencies:    map[string]string{"a": "^1.0.0", "app": "1.0.0"},
+		DevDependencies: map[string]string{"jest": "^29.0.0"},
+		Packages: map[string]packagejson.PackageItem{
+			"node_modules/a":                {Version: "1.0.0", Dependencies: map[string]string{"b": "^2.0.0"}},
+			"node_modules/a/node_modules/b": {Version: "2.0.0"},
+			"node_modules/b":                {Version: "1.0.0", BundleDependencies: []string{"bundled"}},
+			"node_modules/jest":             {Version: "29.0.0", Dependencies: map[string]string{"b": "^1.0.0", "@types/node": "*"}},
+			"node_modules/@types/node":      {Version: "20.0.0"},
+			"node_modules/app":              {Version: "1.0.0", Resolved: "file:packages/app", Link: true},
+		},
+	}
+}
+
+func TestPrunerPlan(t *testing.T) {
+	testCases := []struct {
+		name       string
+		production bool
+		setupFunc  func(t *testing.T, dir, packagesDir string)
+		expected   []string
+	}{
+		{
+			name: "nothing to prune",
+		},
+		{
+			name: "packages missing from the lock are extraneous",
+			setupFunc: func(t *testing.T, dir, packagesDir string) {
+				writePackage(t, dir, "node_modules/old")
+				writePackage(t, dir, "node_modules/@types/old")
+				writePackage(t, dir, "node_modules/a/node_modules/old")
+				writePackage(t, dir, "node_modules/old/node_modules/nested")
+			},
+			expected: []string{
+				"node_modules/@types/old",
+				"node_modules/a/node_modules/old",
+				"node_modules/old",
+			},
+		},
+		{
+			name: "bundled packages are kept whole",
+			setupFunc: func(t *testing.T, dir, packagesDir string) {
+				writePackage(t, dir, "node_modules/b/node_modules/bundled")
+				writePackage(t, dir, "node_modules/b/node_modules/bundled/node_modules/inner")
+				writePackage(t, dir, "node_modules/b/node_modules/old")
+			},
+			expected: []string{"node_modules/b/node_modules/old"},
+		},
+		{
+			name:       "production also prunes dev-only packages",
+			production: true,
+			expected: []string{
+				"node_modules/@types/node",
+				"node_modules/b",
+				"node_modules/jest",
+			},
+		},
+		{
+			name: "workspace and linked symlinks are kept",
+			setupFunc: func(t *testing.T, dir, packagesDir string) {
+				linked := filepath.Join(dir, "elsewhere", "linked")
+				require.NoError(t, os.MkdirAll(linked, 0755))
+				require.NoError(t, os.Symlink(linked, filepath.Join(dir, "node_modules", "linked")))
+			},
+		},
+		{
+			name: "symlinks into the package cache are pruned",
+			setupFunc: func(t *testing.T, dir, packagesDir string) {
+				cached := filepath.Join(packagesDir, "old@1.0.0")
+				require.NoError(t, os.MkdirAll(cached, 0755))
+				require.NoError(t, os.Symlink(cached, filepath.Join(dir, "node_modules", "old")))
+			},
+			expected: []string{"node_modules/old"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			packagesDir := filepath.Join(t.TempDir(), "packages")
+
+			for _, pkgPath := range []string{"node_modules/a", "node_modules/a/node_modules/b", "node_modules/b", "node_modules/jest", "node_modules/@types/node"} {
+				writePackage(t, dir, pkgPath)
+			}
+			require.NoError(t, os.MkdirAll(filepath.Join(dir, "packages", "app"), 0755))
+			require.NoError(t, os.Symlink(filepath.Join(dir, "packages", "app"), filepath.Join(dir, "node_modules", "app")))
+			require.NoError(t, os.MkdirAll(filepath.Join(dir, "node_modules", ".bin"), 0755))
+
+			if tc.setupFunc != nil {
+				tc.setupFunc(t, dir, packagesDir)
+			}
+
+			pruner := New(testLock())
+			pruner.SetProduction(tc.production)
+			pruner.SetPackagesDir(packagesDir)
+
+			extraneous, err := pruner.Plan(dir)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, extraneous)
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	setup := func(t *testing.T) string {
+		dir := t.TempDir()
+		writePackage(t, dir, "node_modules/a")
+		writePackage(t, dir, "node_modules/old")
+		writePackage(t, dir, "node_modules/@scope/old")
+
+		binDir := filepath.Join(dir, "node_modules", ".bin")
+		require.NoError(t, os.MkdirAll(binDir, 0755))
+		require.NoError(t, os.Symlink("../a/package.json", filepath.Join(binDir, "a")))
+		require.NoError(t, os.Symlink("../old/package.json", filepath.Join(binDir, "old")))
+		return dir
+	}
+	paths := []string{"node_modules/@scope/old", "node_modules/old"}
+
+	t.Run("removes packages, empty scopes and dangling bins", func(t *testing.T) {
+		dir := setup(t)
+
+		saved, err := Apply(dir, paths, false)
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(`{"name": "old"}`)*2), saved)
+
+		assert.NoDirExists(t, filepath.Join(dir, "node_modules", "old"))
+		assert.NoDirExists(t, filepath.Join(dir, "node_modules", "@scope"))
+		assert.DirExists(t, filepath.Join(dir, "node_modules", "a"))
+		assert.FileExists(t, filepath.Join(dir, "node_modules", ".bin", "a"))
+		_, err = os.Lstat(filepath.Join(dir, "node_modules", ".bin", "old"))
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("dry run only measures", func(t *testing.T) {
+		dir := setup(t)
+
+		saved, err := Apply(dir, paths, true)
+		require.NoError(t, err)
+		assert.Positive(t, saved)
+		assert.DirExists(t, filepath.Join(dir, "node_modules", "old"))
+		assert.DirExists(t, filepath.Join(dir, "node_modules", "@scope", "old"))
+	})
+}
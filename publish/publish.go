@@ -0,0 +1,149 @@
+package publish
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ernesto27/go-npm/integrity"
+)
+
+// Options are the settings of a publish, from the flags or publishConfig
+type Options struct {
+	// Tag is the dist-tag pointed at the published version, "latest" when empty
+	Tag string
+	// Access is "public" or "restricted"; empty leaves it to the registry,
+	// which makes scoped packages restricted
+	Access string
+}
+
+// Client publishes packages to a registry
+type Client struct {
+	registryURL string
+	token       string
+	httpClient  *http.Client
+}
+
+// New creates a Client for registryURL authenticating with token
+func New(registryURL, token string, httpClient *http.Client) *Client {
+	if !strings.HasSuffix(registryURL, "/") {
+		registryURL += "/"
+	}
+	return &Client{
+		registryURL: registryURL,
+		token:       token,
+		httpClient:  httpClient,
+	}
+}
+
+// Document builds the package document npm PUTs to publish a version: the
+// manifest of the version, with its dist, under "versions", the dist-tag
+// pointing at it and the tarball base64 encoded under "_attachments"
+func Document(registryURL string, manifest map[string]any, tarball []byte, opts Options) (map[string]any, error) {
+	name, _ := manifest["name"].(string)
+	version, _ := manifest["version"].(string)
+	if name == "" || version == "" {
+		return nil, fmt.Errorf("package.json must have a name and a version to be published")
+	}
+	if !strings.HasSuffix(registryURL, "/") {
+		registryURL += "/"
+	}
+	tag := opts.Tag
+	if tag == "" {
+		tag = "latest"
+	}
+
+	sri, err := integrity.GenerateIntegrityFrom(bytes.NewReader(tarball), []string{"sha512"})
+	if err != nil {
+		return nil, err
+	}
+	// shasum is the hex sha1 older clients check, not an SRI string
+	sha1Sum := sha1.Sum(tarball)
+	tarballName := name + "-" + version + ".tgz"
+
+	versionManifest := make(map[string]any, len(manifest)+2)
+	for key, value := range manifest {
+		versionManifest[key] = value
+	}
+	versionManifest["_id"] = name + "@" + version
+	versionManifest["dist"] = map[string]any{
+		"shasum":    hex.EncodeToString(sha1Sum[:]),
+		"integrity": sri,
+		"tarball":   registryURL + name + "/-/" + tarballName,
+	}
+
+	document := map[string]any{
+		"_id":       name,
+		"name":      name,
+		"dist-tags": map[string]string{tag: version},
+		"versions":  map[string]any{version: versionManifest},
+		"_attachments": map[string]any{
+			tarballName: map[string]any{
+				"content_type": "application/octet-stream",
+				"data":         base64.StdEncoding.EncodeToString(tarball),
+				"length":       len(tarball),
+			},
+		},
+	}
+	if description, ok := manifest["description"]; ok {
+		document["description"] = description
+	}
+	if opts.Access != "" {
+		document["access"] = opts.Access
+	}
+	return document, nil
+}
+
+// Publish PUTs document, built by Document, to the registry
+func (c *Client) Publish(ctx context.Context, document map[string]any) error {
+	name, _ := document["name"].(string)
+	body, err := json.Marshal(document)
+	if err != nil {
+		return fmt.Errorf("failed to encode package document: %w", err)
+	}
+
+	// A scoped name is sent as one path segment: @scope%2Flib
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, c.registryURL+url.PathEscape(name), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach %s: %w", c.registryURL, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("%s rejected the auth token (HTTP %d)%s", c.registryURL, resp.StatusCode, registryError(resp.Body))
+	case resp.StatusCode == http.StatusConflict:
+		return fmt.Errorf("%s already has this version (HTTP %d)%s", c.registryURL, resp.StatusCode, registryError(resp.Body))
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return fmt.Errorf("publish to %s failed: HTTP %d%s", c.registryURL, resp.StatusCode, registryError(resp.Body))
+	}
+	return nil
+}
+
+// registryError returns ": <error>" for the JSON error a registry answers a
+// failed publish with, or nothing
+func registryError(body io.Reader) string {
+	var payload struct {
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(io.LimitReader(body, 64<<10)).Decode(&payload); err != nil || payload.Error == "" {
+		return ""
+	}
+	return ": " + payload.Error
+}
@@ -0,0 +1,110 @@
+package publish
+
+import (
+	"context"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDocument(t *testing.T) {
+	tarball := []byte("tarball bytes")
+	sum := sha512.Sum512(tarball)
+
+	document, err := Document("https://npm.example", map[string]any{
+		"name":        "@scope/lib",
+		"version":     "1.2.0",
+		"description": "A library",
+		"main":        "index.js",
+	}, tarball, Options{Access: "public"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "@scope/lib", document["name"])
+	assert.Equal(t, "A library", document["description"])
+	assert.Equal(t, "public", document["access"])
+	assert.Equal(t, map[string]string{"latest": "1.2.0"}, document["dist-tags"])
+
+	version := document["versions"].(map[string]any)["1.2.0"].(map[string]any)
+	assert.Equal(t, "@scope/lib@1.2.0", version["_id"])
+	assert.Equal(t, "index.js", version["main"])
+	dist := version["dist"].(map[string]any)
+	assert.Equal(t, "sha512-"+base64.StdEncoding.EncodeToString(sum[:]), dist["integrity"])
+	assert.Equal(t, "https://npm.example/@scope/lib/-/@scope/lib-1.2.0.tgz", dist["tarball"])
+
+	attachment := document["_attachments"].(map[string]any)["@scope/lib-1.2.0.tgz"].(map[string]any)
+	assert.Equal(t, base64.StdEncoding.EncodeToString(tarball), attachment["data"])
+	assert.Equal(t, len(tarball), attachment["length"])
+
+	_, err = Document("https://npm.example/", map[string]any{"name": "lib"}, tarball, Options{})
+	assert.Error(t, err)
+}
+
+func TestPublish(t *testing.T) {
+	testCases := []struct {
+		name          string
+		status        int
+		body          string
+		errorContains string
+	}{
+		{
+			name:   "publishes the document",
+			status: http.StatusOK,
+			body:   `{"ok": true}`,
+		},
+		{
+			name:          "rejected token",
+			status:        http.StatusUnauthorized,
+			body:          `{"error": "unauthorized"}`,
+			errorContains: "rejected the auth token (HTTP 401): unauthorized",
+		},
+		{
+			name:          "version already published",
+			status:        http.StatusConflict,
+			errorContains: "already has this version",
+		},
+		{
+			name:          "server error",
+			status:        http.StatusInternalServerError,
+			errorContains: "HTTP 500",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var method, authHeader, path string
+			var received map[string]any
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				method = r.Method
+				authHeader = r.Header.Get("Authorization")
+				path = r.URL.EscapedPath()
+				body, _ := io.ReadAll(r.Body)
+				json.Unmarshal(body, &received)
+				w.WriteHeader(tc.status)
+				w.Write([]byte(tc.body))
+			}))
+			defer server.Close()
+
+			document, err := Document(server.URL, map[string]any{"name": "@scope/lib", "version": "1.0.0"}, []byte("tgz"), Options{Tag: "next"})
+			require.NoError(t, err)
+
+			err = New(server.URL, "secret", server.Client()).Publish(context.Background(), document)
+			assert.Equal(t, http.MethodPut, method)
+			assert.Equal(t, "Bearer secret", authHeader)
+			assert.Equal(t, "/@scope%2Flib", path)
+			assert.Equal(t, map[string]any{"next": "1.0.0"}, received["dist-tags"])
+
+			if tc.errorContains != "" {
+				assert.ErrorContains(t, err, tc.errorContains)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
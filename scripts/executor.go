@@ -2,11 +2,14 @@ package scripts
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 	"time"
 )
@@ -14,15 +17,31 @@ import (
 type ScriptExecutor struct {
 	nodeModulesPath string
 	timeout         time.Duration
+	config          map[string]string
+	stdout          io.Writer
 }
 
 func NewScriptExecutor(nodeModulesPath string) *ScriptExecutor {
 	return &ScriptExecutor{
 		nodeModulesPath: nodeModulesPath,
 		timeout:         5 * time.Minute,
+		config:          make(map[string]string),
+		stdout:          os.Stdout,
 	}
 }
 
+// SetOutput sets where scripts' standard output and the echoed commands go,
+// stdout by default
+func (se *ScriptExecutor) SetOutput(w io.Writer) {
+	se.stdout = w
+}
+
+// SetConfig records an effective config value, exposed to scripts as
+// npm_config_<key> (dashes become underscores, like npm)
+func (se *ScriptExecutor) SetConfig(key, value string) {
+	se.config[strings.ReplaceAll(key, "-", "_")] = value
+}
+
 func (se *ScriptExecutor) Execute(script, workDir, pkgName, pkgVersion, event string) error {
 	if script == "" {
 		return nil
@@ -39,11 +58,13 @@ func (se *ScriptExecutor) Execute(script, workDir, pkgName, pkgVersion, event st
 	}
 
 	cmd.Dir = workDir
-	cmd.Env = se.buildEnvironment(pkgName, pkgVersion, event)
-	cmd.Stdout = os.Stdout
+	env := se.buildEnvironment(pkgName, pkgVersion, event)
+	env = se.setEnv(env, "npm_lifecycle_script", script)
+	cmd.Env = se.addPackageEnvironment(env, workDir)
+	cmd.Stdout = se.stdout
 	cmd.Stderr = os.Stderr
 
-	fmt.Printf("$ %s\n", script)
+	fmt.Fprintf(se.stdout, "$ %s\n", script)
 
 	if err := cmd.Run(); err != nil {
 		if ctx.Err() == context.DeadlineExceeded {
@@ -58,11 +79,30 @@ func (se *ScriptExecutor) Execute(script, workDir, pkgName, pkgVersion, event st
 func (se *ScriptExecutor) buildEnvironment(pkgName, pkgVersion, event string) []string {
 	env := os.Environ()
 
-	env = append(env,
-		"npm_lifecycle_event="+event,
-		"npm_package_name="+pkgName,
-		"npm_package_version="+pkgVersion,
-	)
+	// Set rather than appended, so values inherited from an outer npm run are replaced
+	env = se.setEnv(env, "npm_lifecycle_event", event)
+	env = se.setEnv(env, "npm_package_name", pkgName)
+	env = se.setEnv(env, "npm_package_version", pkgVersion)
+
+	if cwd, err := os.Getwd(); err == nil {
+		env = se.setEnv(env, "INIT_CWD", cwd)
+	}
+	if execPath, err := os.Executable(); err == nil {
+		env = se.setEnv(env, "npm_execpath", execPath)
+	}
+	if nodePath, err := exec.LookPath("node"); err == nil {
+		env = se.setEnv(env, "npm_node_execpath", nodePath)
+		env = se.setEnv(env, "NODE", nodePath)
+	}
+
+	keys := make([]string, 0, len(se.config))
+	for key := range se.config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		env = se.setEnv(env, "npm_config_"+key, se.config[key])
+	}
 
 	binPath := filepath.Join(se.nodeModulesPath, ".bin")
 	path := os.Getenv("PATH")
@@ -78,6 +118,67 @@ func (se *ScriptExecutor) buildEnvironment(pkgName, pkgVersion, event string) []
 	return env
 }
 
+// addPackageEnvironment adds the package.json derived variables npm exposes
+// to scripts: npm_package_json plus the engines and config fields as
+// npm_package_engines_<name> and npm_package_config_<key>
+func (se *ScriptExecutor) addPackageEnvironment(env []string, workDir string) []string {
+	pkgJSONPath := filepath.Join(workDir, "package.json")
+	data, err := os.ReadFile(pkgJSONPath)
+	if err != nil {
+		return env
+	}
+
+	var pkg struct {
+		Engines map[string]any `json:"engines"`
+		Config  map[string]any `json:"config"`
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return env
+	}
+
+	if absPath, err := filepath.Abs(pkgJSONPath); err == nil {
+		pkgJSONPath = absPath
+	}
+	env = se.setEnv(env, "npm_package_json", pkgJSONPath)
+
+	for _, field := range []struct {
+		prefix string
+		values map[string]any
+	}{
+		{"npm_package_engines_", pkg.Engines},
+		{"npm_package_config_", pkg.Config},
+	} {
+		keys := make([]string, 0, len(field.values))
+		for key := range field.values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			value, ok := envValue(field.values[key])
+			if !ok {
+				continue
+			}
+			env = se.setEnv(env, field.prefix+strings.ReplaceAll(key, "-", "_"), value)
+		}
+	}
+
+	return env
+}
+
+// envValue renders a scalar package.json value the way npm puts it in the
+// environment; nested objects and arrays are skipped
+func envValue(value any) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case bool, float64:
+		return fmt.Sprint(v), true
+	default:
+		return "", false
+	}
+}
+
 func (se *ScriptExecutor) setEnv(env []string, key, value string) []string {
 	prefix := key + "="
 	for i, e := range env {
@@ -142,6 +142,74 @@ func TestBuildEnvironment(t *testing.T) {
 	}
 }
 
+func TestExecute_PackageEnvironment(t *testing.T) {
+	testCases := []struct {
+		name        string
+		packageJSON string
+		config      map[string]string
+		script      string
+		expected    string
+	}{
+		{
+			name:        "npm_package_version",
+			packageJSON: `{"name": "test-pkg", "version": "1.0.0"}`,
+			script:      `echo "$npm_package_version"`,
+			expected:    "1.0.0",
+		},
+		{
+			name:        "npm_package_name and npm_lifecycle_event",
+			packageJSON: `{"name": "test-pkg", "version": "1.0.0"}`,
+			script:      `echo "$npm_package_name $npm_lifecycle_event"`,
+			expected:    "test-pkg postinstall",
+		},
+		{
+			name:        "npm_lifecycle_script",
+			packageJSON: `{"name": "test-pkg", "version": "1.0.0"}`,
+			script:      `echo "$npm_lifecycle_script" | grep -c "grep -c"`,
+			expected:    "1",
+		},
+		{
+			name:        "engines and config fields",
+			packageJSON: `{"name": "test-pkg", "engines": {"node": ">=18"}, "config": {"port": 8080, "log-level": "debug", "nested": {"a": 1}}}`,
+			script:      `echo "$npm_package_engines_node $npm_package_config_port $npm_package_config_log_level [$npm_package_config_nested]"`,
+			expected:    ">=18 8080 debug []",
+		},
+		{
+			name:        "npm_package_json",
+			packageJSON: `{"name": "test-pkg"}`,
+			script:      `basename "$npm_package_json"`,
+			expected:    "package.json",
+		},
+		{
+			name:        "effective config",
+			packageJSON: `{"name": "test-pkg"}`,
+			config:      map[string]string{"user-agent": "go-npm/1.2.3", "ignore-scripts": "false"},
+			script:      `echo "$npm_config_user_agent $npm_config_ignore_scripts"`,
+			expected:    "go-npm/1.2.3 false",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			assert.NoError(t, os.WriteFile(filepath.Join(dir, "package.json"), []byte(tc.packageJSON), 0644))
+
+			executor := NewScriptExecutor(dir)
+			for key, value := range tc.config {
+				executor.SetConfig(key, value)
+			}
+
+			outFile := filepath.Join(dir, "out.txt")
+			err := executor.Execute("("+tc.script+") > "+outFile, dir, "test-pkg", "1.0.0", "postinstall")
+			assert.NoError(t, err)
+
+			content, err := os.ReadFile(outFile)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, strings.TrimSpace(string(content)))
+		})
+	}
+}
+
 func TestSetEnv(t *testing.T) {
 	testCases := []struct {
 		name      string
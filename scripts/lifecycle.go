@@ -2,6 +2,7 @@ package scripts
 
 import (
 	"fmt"
+	"io"
 )
 
 type LifecycleManager struct {
@@ -24,10 +25,52 @@ func (lm *LifecycleManager) SetTrustedDependencies(trustedDeps []string) {
 	lm.trustChecker.SetTrustedDependencies(trustedDeps)
 }
 
+// SetScriptPolicy sets the config-level allow and deny lists of package name
+// globs consulted before trustedDependencies
+func (lm *LifecycleManager) SetScriptPolicy(allow, deny []string) {
+	lm.trustChecker.SetPolicy(allow, deny)
+}
+
+// SetConfig exposes an effective config value to every script as npm_config_<key>
+func (lm *LifecycleManager) SetConfig(key, value string) {
+	lm.executor.SetConfig(key, value)
+}
+
+// SetOutput sets where scripts' standard output goes, stdout by default
+func (lm *LifecycleManager) SetOutput(w io.Writer) {
+	lm.executor.SetOutput(w)
+}
+
 func (lm *LifecycleManager) RunPackageScripts(pkgName, pkgVersion, pkgPath string, scripts any) error {
 	return lm.runPackageScripts(pkgName, pkgVersion, pkgPath, scripts, true)
 }
 
+// WillRunPackageScripts reports whether RunPackageScripts would run any
+// preinstall, install or postinstall script of pkgName
+func (lm *LifecycleManager) WillRunPackageScripts(pkgName string, scripts any) bool {
+	return lm.willRun(pkgName, scripts, "preinstall", "install", "postinstall")
+}
+
+// WillRunPrepare reports whether RunDependencyPrepare would run the prepare
+// script of pkgName
+func (lm *LifecycleManager) WillRunPrepare(pkgName string, scripts any) bool {
+	return lm.willRun(pkgName, scripts, "prepare")
+}
+
+func (lm *LifecycleManager) willRun(pkgName string, scripts any, hooks ...string) bool {
+	if lm.ignoreScripts || !lm.trustChecker.IsTrusted(pkgName) {
+		return false
+	}
+
+	scriptMap := extractScripts(scripts)
+	for _, hook := range hooks {
+		if _, exists := scriptMap[hook]; exists {
+			return true
+		}
+	}
+	return false
+}
+
 func (lm *LifecycleManager) RunRootPackageScripts(pkgName, pkgVersion, pkgPath string, scripts any) error {
 	return lm.runPackageScripts(pkgName, pkgVersion, pkgPath, scripts, false)
 }
@@ -68,10 +111,27 @@ func (lm *LifecycleManager) runPackageScripts(pkgName, pkgVersion, pkgPath strin
 }
 
 func (lm *LifecycleManager) RunPrepare(pkgName, pkgVersion, rootPath string, scripts any) error {
+	return lm.runPrepare(pkgName, pkgVersion, rootPath, scripts, false)
+}
+
+// RunDependencyPrepare runs the prepare script of a git or file: dependency
+// installed at pkgPath, if the package is trusted
+func (lm *LifecycleManager) RunDependencyPrepare(pkgName, pkgVersion, pkgPath string, scripts any) error {
+	if err := lm.runPrepare(pkgName, pkgVersion, pkgPath, scripts, true); err != nil {
+		return fmt.Errorf("%s: %w", pkgName, err)
+	}
+	return nil
+}
+
+func (lm *LifecycleManager) runPrepare(pkgName, pkgVersion, rootPath string, scripts any, checkTrust bool) error {
 	if lm.ignoreScripts {
 		return nil
 	}
 
+	if checkTrust && !lm.trustChecker.IsTrusted(pkgName) {
+		return nil
+	}
+
 	scriptMap := extractScripts(scripts)
 	if scriptMap == nil {
 		return nil
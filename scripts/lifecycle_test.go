@@ -1,6 +1,7 @@
 package scripts
 
 import (
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -141,6 +142,23 @@ func TestRunPackageScripts(t *testing.T) {
 	}
 }
 
+func TestRunPackageScripts_ConfigDenyWins(t *testing.T) {
+	dir := t.TempDir()
+	lm := NewLifecycleManager(dir, false)
+	lm.SetTrustedDependencies([]string{"native-pkg"})
+	lm.SetScriptPolicy(nil, []string{"native-*"})
+
+	scripts := map[string]string{"postinstall": "touch ran"}
+	assert.False(t, lm.WillRunPackageScripts("native-pkg", scripts))
+	assert.NoError(t, lm.RunPackageScripts("native-pkg", "1.0.0", dir, scripts))
+	assert.NoFileExists(t, filepath.Join(dir, "ran"))
+
+	// The same package runs its scripts once the config stops denying it
+	lm.SetScriptPolicy(nil, nil)
+	assert.NoError(t, lm.RunPackageScripts("native-pkg", "1.0.0", dir, scripts))
+	assert.FileExists(t, filepath.Join(dir, "ran"))
+}
+
 func TestRunRootPackageScripts(t *testing.T) {
 	testCases := []struct {
 		name      string
@@ -233,3 +251,58 @@ func TestRunPrepare(t *testing.T) {
 		})
 	}
 }
+
+func TestRunDependencyPrepare(t *testing.T) {
+	testCases := []struct {
+		name      string
+		setupFunc func() (string, *LifecycleManager)
+		scripts   any
+		validate  func(t *testing.T, err error)
+	}{
+		{
+			name: "trusted dependency runs prepare",
+			setupFunc: func() (string, *LifecycleManager) {
+				dir := t.TempDir()
+				lm := NewLifecycleManager(dir, false)
+				lm.SetTrustedDependencies([]string{"git-dep"})
+				return dir, lm
+			},
+			scripts: map[string]string{"prepare": "exit 3"},
+			validate: func(t *testing.T, err error) {
+				assert.ErrorContains(t, err, "git-dep: prepare script failed")
+			},
+		},
+		{
+			name: "untrusted dependency skips prepare",
+			setupFunc: func() (string, *LifecycleManager) {
+				dir := t.TempDir()
+				return dir, NewLifecycleManager(dir, false)
+			},
+			scripts: map[string]string{"prepare": "exit 1"},
+			validate: func(t *testing.T, err error) {
+				assert.NoError(t, err)
+			},
+		},
+		{
+			name: "ignoreScripts skips prepare",
+			setupFunc: func() (string, *LifecycleManager) {
+				dir := t.TempDir()
+				lm := NewLifecycleManager(dir, true)
+				lm.SetTrustedDependencies([]string{"git-dep"})
+				return dir, lm
+			},
+			scripts: map[string]string{"prepare": "exit 1"},
+			validate: func(t *testing.T, err error) {
+				assert.NoError(t, err)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir, lm := tc.setupFunc()
+			err := lm.RunDependencyPrepare("git-dep", "1.0.0", dir, tc.scripts)
+			tc.validate(t, err)
+		})
+	}
+}
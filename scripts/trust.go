@@ -1,7 +1,14 @@
 package scripts
 
+import "path"
+
 type TrustChecker struct {
 	trustedDependencies []string
+
+	// allow and deny are config-level name globs applied on top of
+	// trustedDependencies; deny wins over both
+	allow []string
+	deny  []string
 }
 
 func NewTrustChecker(trustedDeps []string) *TrustChecker {
@@ -11,14 +18,35 @@ func NewTrustChecker(trustedDeps []string) *TrustChecker {
 }
 
 func (tc *TrustChecker) IsTrusted(packageName string) bool {
+	if matchesAny(tc.deny, packageName) {
+		return false
+	}
 	for _, trusted := range tc.trustedDependencies {
 		if trusted == packageName {
 			return true
 		}
 	}
-	return false
+	return matchesAny(tc.allow, packageName)
 }
 
 func (tc *TrustChecker) SetTrustedDependencies(trustedDeps []string) {
 	tc.trustedDependencies = trustedDeps
 }
+
+// SetPolicy sets the config-level allow and deny lists of package name
+// globs, like "@org/*". A denied package never runs scripts, even when listed
+// in trustedDependencies; an allowed one runs them without being listed.
+func (tc *TrustChecker) SetPolicy(allow, deny []string) {
+	tc.allow = allow
+	tc.deny = deny
+}
+
+// matchesAny reports whether name matches one of the globs in patterns
+func matchesAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
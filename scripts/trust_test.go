@@ -53,6 +53,42 @@ func TestIsTrusted(t *testing.T) {
 				assert.True(t, result, "Scoped package should be trusted")
 			},
 		},
+		{
+			name: "allowed by config glob",
+			setupFunc: func() *TrustChecker {
+				checker := NewTrustChecker([]string{})
+				checker.SetPolicy([]string{"@org/*"}, nil)
+				return checker
+			},
+			packageName: "@org/native",
+			validate: func(t *testing.T, result bool) {
+				assert.True(t, result, "Package matching the allow list should be trusted")
+			},
+		},
+		{
+			name: "denied by config overrides trustedDependencies",
+			setupFunc: func() *TrustChecker {
+				checker := NewTrustChecker([]string{"esbuild"})
+				checker.SetPolicy(nil, []string{"esbuild"})
+				return checker
+			},
+			packageName: "esbuild",
+			validate: func(t *testing.T, result bool) {
+				assert.False(t, result, "Denied package should not be trusted")
+			},
+		},
+		{
+			name: "deny wins over allow",
+			setupFunc: func() *TrustChecker {
+				checker := NewTrustChecker([]string{})
+				checker.SetPolicy([]string{"@org/*"}, []string{"@org/legacy-*"})
+				return checker
+			},
+			packageName: "@org/legacy-build",
+			validate: func(t *testing.T, result bool) {
+				assert.False(t, result, "Deny should win over allow")
+			},
+		},
 		{
 			name: "partial match not trusted",
 			setupFunc: func() *TrustChecker {
@@ -0,0 +1,158 @@
+package signature
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ernesto27/go-npm/manifest"
+	"github.com/ernesto27/go-npm/utils"
+)
+
+// keysPath is where npm-compatible registries publish their signing keys
+const keysPath = "-/npm/v1/keys"
+
+// keyTypeECDSA is the only key type the npm registry signs with
+const keyTypeECDSA = "ecdsa-sha2-nistp256"
+
+var (
+	// ErrInvalidSignature is returned when no registry key verifies a package
+	ErrInvalidSignature = errors.New("invalid registry signature")
+	// ErrMissingSignature is returned when a registry publishes keys but a
+	// package has no signature made with one of them
+	ErrMissingSignature = errors.New("missing registry signature")
+)
+
+// Key is a registry public key as returned by /-/npm/v1/keys
+type Key struct {
+	KeyID   string     `json:"keyid"`
+	KeyType string     `json:"keytype"`
+	Scheme  string     `json:"scheme"`
+	Key     string     `json:"key"` // base64 encoded SPKI (DER) public key
+	Expires *time.Time `json:"expires"`
+}
+
+type keysResponse struct {
+	Keys []Key `json:"keys"`
+}
+
+// Verifier checks the dist.signatures of registry packages against the
+// registry's published keys. Keys are fetched once, on first use.
+type Verifier struct {
+	keysURL string
+	policy  utils.RetryPolicy
+	keys    func() (map[string]Key, error)
+}
+
+// NewVerifier creates a Verifier for the registry at registryURL (with a
+// trailing slash, like the manifest registry URL) that fetches the keys
+// with policy
+func NewVerifier(registryURL string, policy utils.RetryPolicy) *Verifier {
+	v := &Verifier{keysURL: registryURL + keysPath, policy: policy}
+	v.keys = sync.OnceValues(v.fetchKeys)
+	return v
+}
+
+// Verify checks that one of signatures is a valid registry signature of
+// name@version:integrity. A key that expired before publishedAt does not
+// count; a zero publishedAt skips the expiry check. Registries that publish
+// no keys cannot be verified and are accepted.
+func (v *Verifier) Verify(name, version, integrity string, signatures []manifest.Signature, publishedAt time.Time) error {
+	keys, err := v.keys()
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	message := []byte(name + "@" + version + ":" + integrity)
+	digest := sha256.Sum256(message)
+
+	known := false
+	for _, sig := range signatures {
+		key, ok := keys[sig.KeyID]
+		if !ok {
+			continue
+		}
+		known = true
+
+		if key.Expires != nil && !publishedAt.IsZero() && publishedAt.After(*key.Expires) {
+			continue
+		}
+
+		publicKey, err := parsePublicKey(key)
+		if err != nil {
+			return err
+		}
+
+		rawSig, err := base64.StdEncoding.DecodeString(sig.Sig)
+		if err != nil {
+			continue
+		}
+
+		if ecdsa.VerifyASN1(publicKey, digest[:], rawSig) {
+			return nil
+		}
+	}
+
+	if !known {
+		return fmt.Errorf("%w for %s@%s", ErrMissingSignature, name, version)
+	}
+	return fmt.Errorf("%w for %s@%s", ErrInvalidSignature, name, version)
+}
+
+// fetchKeys downloads the registry keys, indexed by key ID. A 404 means the
+// registry does not sign packages and yields no keys.
+func (v *Verifier) fetchKeys() (map[string]Key, error) {
+	headers := map[string]string{"User-Agent": "go-npm"}
+	body, statusCode, err := utils.GetWithRetryContext(context.Background(), v.keysURL, headers, v.policy)
+	if statusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch registry keys from %s: %w", v.keysURL, err)
+	}
+
+	var keysResp keysResponse
+	if err := json.Unmarshal(body, &keysResp); err != nil {
+		return nil, fmt.Errorf("failed to parse registry keys: %w", err)
+	}
+
+	keys := make(map[string]Key, len(keysResp.Keys))
+	for _, key := range keysResp.Keys {
+		keys[key.KeyID] = key
+	}
+	return keys, nil
+}
+
+// parsePublicKey decodes an ecdsa-sha2-nistp256 registry key
+func parsePublicKey(key Key) (*ecdsa.PublicKey, error) {
+	if key.KeyType != keyTypeECDSA {
+		return nil, fmt.Errorf("unsupported registry key type %q for key %s", key.KeyType, key.KeyID)
+	}
+
+	der, err := base64.StdEncoding.DecodeString(key.Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode registry key %s: %w", key.KeyID, err)
+	}
+
+	publicKey, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse registry key %s: %w", key.KeyID, err)
+	}
+
+	ecdsaKey, ok := publicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("registry key %s is not an ECDSA key", key.KeyID)
+	}
+	return ecdsaKey, nil
+}
@@ -0,0 +1,186 @@
+package signature
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ernesto27/go-npm/manifest"
+	"github.com/ernesto27/go-npm/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testIntegrity = "sha512-dGVzdA=="
+
+// testPolicy makes a single attempt, so a failing keys request fails at once
+var testPolicy = utils.RetryPolicy{}
+
+func generateKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	return key
+}
+
+func publicKey(t *testing.T, keyID string, key *ecdsa.PrivateKey, expires *time.Time) Key {
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	return Key{
+		KeyID:   keyID,
+		KeyType: keyTypeECDSA,
+		Scheme:  keyTypeECDSA,
+		Key:     base64.StdEncoding.EncodeToString(der),
+		Expires: expires,
+	}
+}
+
+func sign(t *testing.T, key *ecdsa.PrivateKey, message string) string {
+	digest := sha256.Sum256([]byte(message))
+	sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	require.NoError(t, err)
+	return base64.StdEncoding.EncodeToString(sig)
+}
+
+func newKeysServer(t *testing.T, keys []Key) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+keysPath || keys == nil {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(keysResponse{Keys: keys})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestVerifierVerify(t *testing.T) {
+	registryKey := generateKey(t)
+	otherKey := generateKey(t)
+	expiry := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	message := "lodash@4.17.21:" + testIntegrity
+
+	testCases := []struct {
+		name          string
+		keys          []Key
+		signatures    []manifest.Signature
+		publishedAt   time.Time
+		expectedError error
+	}{
+		{
+			name:       "valid signature",
+			keys:       []Key{publicKey(t, "SHA256:registry", registryKey, nil)},
+			signatures: []manifest.Signature{{KeyID: "SHA256:registry", Sig: sign(t, registryKey, message)}},
+		},
+		{
+			name:          "signature of another package version",
+			keys:          []Key{publicKey(t, "SHA256:registry", registryKey, nil)},
+			signatures:    []manifest.Signature{{KeyID: "SHA256:registry", Sig: sign(t, registryKey, "lodash@4.17.20:"+testIntegrity)}},
+			expectedError: ErrInvalidSignature,
+		},
+		{
+			name:          "signature made with another key",
+			keys:          []Key{publicKey(t, "SHA256:registry", registryKey, nil)},
+			signatures:    []manifest.Signature{{KeyID: "SHA256:registry", Sig: sign(t, otherKey, message)}},
+			expectedError: ErrInvalidSignature,
+		},
+		{
+			name:          "no signatures",
+			keys:          []Key{publicKey(t, "SHA256:registry", registryKey, nil)},
+			expectedError: ErrMissingSignature,
+		},
+		{
+			name:          "signature with an unknown key id",
+			keys:          []Key{publicKey(t, "SHA256:registry", registryKey, nil)},
+			signatures:    []manifest.Signature{{KeyID: "SHA256:unknown", Sig: sign(t, registryKey, message)}},
+			expectedError: ErrMissingSignature,
+		},
+		{
+			name:        "key valid when the version was published",
+			keys:        []Key{publicKey(t, "SHA256:registry", registryKey, &expiry)},
+			signatures:  []manifest.Signature{{KeyID: "SHA256:registry", Sig: sign(t, registryKey, message)}},
+			publishedAt: expiry.Add(-time.Hour),
+		},
+		{
+			name:          "key expired before the version was published",
+			keys:          []Key{publicKey(t, "SHA256:registry", registryKey, &expiry)},
+			signatures:    []manifest.Signature{{KeyID: "SHA256:registry", Sig: sign(t, registryKey, message)}},
+			publishedAt:   expiry.Add(time.Hour),
+			expectedError: ErrInvalidSignature,
+		},
+		{
+			name: "registry without keys is not verified",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newKeysServer(t, tc.keys)
+
+			err := NewVerifier(server.URL+"/", testPolicy).Verify("lodash", "4.17.21", testIntegrity, tc.signatures, tc.publishedAt)
+			if tc.expectedError != nil {
+				assert.True(t, errors.Is(err, tc.expectedError), "expected %v, got %v", tc.expectedError, err)
+				assert.Contains(t, err.Error(), "lodash@4.17.21")
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestVerifierFetchesKeysOnce(t *testing.T) {
+	key := generateKey(t)
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		json.NewEncoder(w).Encode(keysResponse{Keys: []Key{publicKey(t, "SHA256:registry", key, nil)}})
+	}))
+	defer server.Close()
+
+	v := NewVerifier(server.URL+"/", testPolicy)
+	for _, version := range []string{"1.0.0", "1.0.1"} {
+		sig := sign(t, key, "pkg@"+version+":"+testIntegrity)
+		err := v.Verify("pkg", version, testIntegrity, []manifest.Signature{{KeyID: "SHA256:registry", Sig: sig}}, time.Time{})
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 1, requests)
+}
+
+func TestVerifierKeysError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	err := NewVerifier(server.URL+"/", testPolicy).Verify("pkg", "1.0.0", testIntegrity, nil, time.Time{})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to fetch registry keys")
+}
+
+func TestVerifierRetriesKeys(t *testing.T) {
+	key := generateKey(t)
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(keysResponse{Keys: []Key{publicKey(t, "SHA256:registry", key, nil)}})
+	}))
+	defer server.Close()
+
+	v := NewVerifier(server.URL+"/", utils.RetryPolicy{Retries: 1, BaseDelay: time.Millisecond})
+	sig := sign(t, key, "pkg@1.0.0:"+testIntegrity)
+	err := v.Verify("pkg", "1.0.0", testIntegrity, []manifest.Signature{{KeyID: "SHA256:registry", Sig: sig}}, time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, requests)
+}
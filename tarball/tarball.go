@@ -1,71 +1,224 @@
 package tarball
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"os"
+	"net/http"
+	"net/url"
 	"path"
 	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/ernesto27/go-npm/config"
 	"github.com/ernesto27/go-npm/integrity"
 	"github.com/ernesto27/go-npm/utils"
 )
 
+// ErrNotFound is returned when the registry no longer has the tarball, like
+// one removed after it was published
+var ErrNotFound = errors.New("tarball not found")
+
 type Tarball struct {
 	TarballPath string
 	validator   *integrity.Validator
+	retryPolicy utils.RetryPolicy
+	ctx         context.Context
+	offline     bool
+	registries  []string
+}
+
+// UniqueName returns the cache filename of packageName@version. Scope slashes
+// become dashes so @jest/expect and expect never share expect-30.2.0.tgz
+func UniqueName(packageName, version string) string {
+	safeName := strings.ReplaceAll(packageName, "/", "-")
+	return safeName + "-" + version + ".tgz"
+}
+
+// CacheVersion returns the version packageName@version, downloaded from
+// resolved, is cached under in the package and tarball caches. A tarball URL
+// dependency is cached as URLCacheVersion(resolved), so it never takes the
+// place of the registry package of the same version; registry tarballs, told
+// apart by their <registry>/<name>/-/<file>-<version>.tgz path, and git
+// dependencies keep their version.
+func CacheVersion(packageName, version, resolved string) string {
+	u, err := url.Parse(resolved)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Fragment != "" {
+		return version
+	}
+	baseName := packageName[strings.LastIndex(packageName, "/")+1:]
+	if unescaped, err := url.PathUnescape(u.EscapedPath()); err == nil && strings.HasSuffix(unescaped, "/"+packageName+"/-/"+baseName+"-"+version+".tgz") {
+		return version
+	}
+	return URLCacheVersion(resolved)
+}
+
+// URLCacheVersion is the version a tarball URL dependency is cached under:
+// url-<first 12 hex digits of the sha256 of the URL>
+func URLCacheVersion(tarballURL string) string {
+	sum := sha256.Sum256([]byte(tarballURL))
+	return "url-" + hex.EncodeToString(sum[:])[:12]
 }
 
 func NewTarball(tarballPath string) *Tarball {
 	return &Tarball{
 		TarballPath: tarballPath,
 		validator:   integrity.New(),
+		retryPolicy: utils.DefaultRetryPolicy(),
+		ctx:         context.Background(),
 	}
 }
 
+// SetRegistries sets the registries a registry tarball is downloaded from, in
+// order. A URL on any of them, or on the public registry, is tried on each in
+// turn with the same path while the registry cannot be reached or answers
+// 404. Other URLs are only downloaded from where they point.
+func (d *Tarball) SetRegistries(registries []string) {
+	d.registries = registries
+}
+
+// SetRetries sets how many times a failed tarball download is retried
+func (d *Tarball) SetRetries(retries int) {
+	d.retryPolicy.Retries = retries
+}
+
+// SetTimeout sets how long a single tarball request may take before it is
+// abandoned and retried; zero waits indefinitely
+func (d *Tarball) SetTimeout(timeout time.Duration) {
+	d.retryPolicy.Timeout = timeout
+}
+
+// SetRateLimiters sets the limiters tarball requests wait on, one per
+// registry; nil disables them
+func (d *Tarball) SetRateLimiters(limiters *utils.RateLimiters) {
+	d.retryPolicy.Limiters = limiters
+}
+
+// SetContext sets the context that cancels in-flight downloads
+func (d *Tarball) SetContext(ctx context.Context) {
+	d.ctx = ctx
+}
+
+// SetOffline makes downloads fail instead of making requests
+func (d *Tarball) SetOffline(offline bool) {
+	d.offline = offline
+}
+
+// checkOnline fails in offline mode for a tarball that is not cached
+func (d *Tarball) checkOnline(filename string) error {
+	if d.offline {
+		return fmt.Errorf("%w: tarball %s is not cached", utils.ErrOffline, filename)
+	}
+	return nil
+}
+
 func (d *Tarball) Download(url string) error {
 	filename := path.Base(url)
+	if err := d.checkOnline(filename); err != nil {
+		return err
+	}
 	filePath := filepath.Join(d.TarballPath, filename)
 
-	_, _, err := utils.DownloadFile(url, filePath, "")
-	return err
+	statusCode, err := utils.DownloadFileResumable(d.ctx, url, filePath, nil, d.retryPolicy)
+	return notFound(statusCode, err)
 }
 
-// DownloadAs downloads a tarball from url and saves it with a custom filename
-func (d *Tarball) DownloadAs(url, filename string) error {
+// DownloadAs downloads a tarball from url and saves it with a custom filename.
+// It returns the URL the tarball was served from, which differs from url when
+// a fallback registry served it.
+func (d *Tarball) DownloadAs(url, filename string) (string, error) {
+	if err := d.checkOnline(filename); err != nil {
+		return "", err
+	}
 	filePath := filepath.Join(d.TarballPath, filename)
-	_, _, err := utils.DownloadFile(url, filePath, "")
-	return err
+	return d.download(url, filePath, nil)
+}
+
+// download downloads url to filePath, falling back to the same path on the
+// next registry, and returns the URL that served it. The tarball is written
+// to filePath.part, resumed with a Range request after a dropped connection,
+// and only renamed to filePath once validate, if any, accepts it.
+func (d *Tarball) download(url, filePath string, validate func(path string) error) (string, error) {
+	candidates := d.candidateURLs(url)
+	for i, candidate := range candidates {
+		statusCode, err := utils.DownloadFileResumable(d.ctx, candidate, filePath, validate, d.retryPolicy)
+		if err == nil {
+			return candidate, nil
+		}
+		if i == len(candidates)-1 || !utils.RegistryUnavailable(d.ctx, statusCode, err) {
+			return "", notFound(statusCode, err)
+		}
+	}
+	return "", nil
+}
+
+// candidateURLs returns the URLs url is downloaded from: the same path on
+// each configured registry when url is a registry tarball, otherwise url only
+func (d *Tarball) candidateURLs(url string) []string {
+	if len(d.registries) == 0 {
+		return []string{url}
+	}
+
+	for _, base := range append([]string{config.NPMRegistryURL}, d.registries...) {
+		base = strings.TrimSuffix(base, "/") + "/"
+		tarballPath, ok := strings.CutPrefix(url, base)
+		if !ok {
+			continue
+		}
+
+		candidates := make([]string, 0, len(d.registries))
+		for _, registry := range d.registries {
+			candidate := strings.TrimSuffix(registry, "/") + "/" + tarballPath
+			if !slices.Contains(candidates, candidate) {
+				candidates = append(candidates, candidate)
+			}
+		}
+		return candidates
+	}
+	return []string{url}
 }
 
 // DownloadAndValidate downloads a tarball and validates its integrity hash
 // Downloads to temp file first, validates, then finalizes only if valid
 // Returns ErrNoIntegrity if integrityHash is empty (strict mode)
-func (d *Tarball) DownloadAndValidate(url, filename, integrityHash string) error {
+// Like DownloadAs, it returns the URL the tarball was served from
+func (d *Tarball) DownloadAndValidate(url, filename, integrityHash string) (string, error) {
 	if integrityHash == "" {
-		return integrity.ErrNoIntegrity
+		return "", integrity.ErrNoIntegrity
+	}
+	if err := d.checkOnline(filename); err != nil {
+		return "", err
 	}
 
 	filePath := filepath.Join(d.TarballPath, filename)
-	tempPath := filePath + ".tmp"
 
-	// Download to temp file
-	_, _, err := utils.DownloadFile(url, tempPath, "")
-	if err != nil {
-		os.Remove(tempPath)
-		return fmt.Errorf("download failed: %w", err)
+	// The integrity is validated before the part file is renamed, so an
+	// invalid tarball never takes the final name
+	var validationErr error
+	validate := func(path string) error {
+		validationErr = d.validator.ValidateFileStrict(path, integrityHash)
+		return validationErr
 	}
 
-	// Validate integrity before finalizing
-	if err := d.validator.ValidateFileStrict(tempPath, integrityHash); err != nil {
-		os.Remove(tempPath)
-		return fmt.Errorf("integrity validation failed for %s: %w", filename, err)
+	servedURL, err := d.download(url, filePath, validate)
+	if validationErr != nil {
+		return "", fmt.Errorf("integrity validation failed for %s: %w", filename, validationErr)
 	}
-
-	// Atomic rename: only succeeds if validation passed
-	if err := os.Rename(tempPath, filePath); err != nil {
-		os.Remove(tempPath)
-		return fmt.Errorf("failed to finalize download: %w", err)
+	if err != nil {
+		return "", fmt.Errorf("download failed: %w", err)
 	}
 
-	return nil
+	return servedURL, nil
+}
+
+// notFound wraps a download error in ErrNotFound when the registry answered 404
+func notFound(statusCode int, err error) error {
+	if err != nil && statusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: %w", ErrNotFound, err)
+	}
+	return err
 }
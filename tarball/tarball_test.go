@@ -1,11 +1,23 @@
 package tarball
 
 import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/ernesto27/go-npm/integrity"
+	"github.com/ernesto27/go-npm/utils"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestDownloadTarball_Download(t *testing.T) {
@@ -68,3 +80,332 @@ func TestDownloadTarball_Download(t *testing.T) {
 		})
 	}
 }
+
+func TestTarball_DownloadAsRetry(t *testing.T) {
+	testCases := []struct {
+		name             string
+		handler          func(attempt int32, w http.ResponseWriter)
+		retries          int
+		expectError      bool
+		notFound         bool
+		expectedAttempts int32
+	}{
+		{
+			name: "Retries transient 5xx errors then succeeds",
+			handler: func(attempt int32, w http.ResponseWriter) {
+				if attempt <= 2 {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte("tarball content"))
+			},
+			retries:          3,
+			expectError:      false,
+			expectedAttempts: 3,
+		},
+		{
+			name: "Honors Retry-After on 429",
+			handler: func(attempt int32, w http.ResponseWriter) {
+				if attempt == 1 {
+					w.Header().Set("Retry-After", "0")
+					w.WriteHeader(http.StatusTooManyRequests)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte("tarball content"))
+			},
+			retries:          3,
+			expectError:      false,
+			expectedAttempts: 2,
+		},
+		{
+			name: "Does not retry 404",
+			handler: func(attempt int32, w http.ResponseWriter) {
+				w.WriteHeader(http.StatusNotFound)
+			},
+			retries:          3,
+			expectError:      true,
+			notFound:         true,
+			expectedAttempts: 1,
+		},
+		{
+			name: "Gives up after configured retries",
+			handler: func(attempt int32, w http.ResponseWriter) {
+				w.WriteHeader(http.StatusBadGateway)
+			},
+			retries:          2,
+			expectError:      true,
+			expectedAttempts: 3,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var attempts int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				tc.handler(atomic.AddInt32(&attempts, 1), w)
+			}))
+			defer server.Close()
+
+			tb := NewTarball(t.TempDir())
+			tb.SetRetries(tc.retries)
+			tb.retryPolicy.BaseDelay = time.Millisecond
+
+			_, err := tb.DownloadAs(server.URL+"/pkg-1.0.0.tgz", "pkg-1.0.0.tgz")
+
+			if tc.expectError {
+				assert.Error(t, err)
+				assert.Equal(t, tc.notFound, errors.Is(err, ErrNotFound))
+			} else {
+				assert.NoError(t, err)
+				content, readErr := os.ReadFile(filepath.Join(tb.TarballPath, "pkg-1.0.0.tgz"))
+				assert.NoError(t, readErr)
+				assert.Equal(t, "tarball content", string(content))
+			}
+			assert.Equal(t, tc.expectedAttempts, atomic.LoadInt32(&attempts))
+		})
+	}
+}
+
+func TestTarball_Offline(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Write([]byte("tarball content"))
+	}))
+	defer server.Close()
+
+	d := NewTarball(t.TempDir())
+	d.SetOffline(true)
+
+	assert.ErrorIs(t, d.Download(server.URL+"/pkg-1.0.0.tgz"), utils.ErrOffline)
+	_, err := d.DownloadAs(server.URL+"/pkg-1.0.0.tgz", "pkg-1.0.0.tgz")
+	assert.ErrorIs(t, err, utils.ErrOffline)
+	_, err = d.DownloadAndValidate(server.URL+"/pkg-1.0.0.tgz", "pkg-1.0.0.tgz", "sha512-abc")
+	assert.ErrorIs(t, err, utils.ErrOffline)
+
+	assert.Equal(t, int32(0), atomic.LoadInt32(&requests))
+	assert.NoFileExists(t, filepath.Join(d.TarballPath, "pkg-1.0.0.tgz"))
+}
+
+func TestTarball_RegistryFallback(t *testing.T) {
+	content := []byte("tarball content")
+	contentPath := filepath.Join(t.TempDir(), "content.tgz")
+	require.NoError(t, os.WriteFile(contentPath, content, 0644))
+	sri, err := integrity.ComputeSRI(contentPath, "sha512")
+	require.NoError(t, err)
+
+	// A registry that is down: its connections are refused
+	down := httptest.NewServer(http.NotFoundHandler())
+	downURL := down.URL + "/"
+	down.Close()
+
+	missing := httptest.NewServer(http.NotFoundHandler())
+	defer missing.Close()
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/npm/pkg/-/pkg-1.0.0.tgz" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(content)
+	}))
+	defer mirror.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer failing.Close()
+
+	testCases := []struct {
+		name        string
+		registries  []string
+		url         string
+		validate    bool
+		expectError bool
+		expectedURL string
+	}{
+		{
+			name:        "Falls back past a registry that is down and one without the tarball",
+			registries:  []string{downURL, missing.URL, mirror.URL + "/npm/"},
+			url:         "https://registry.npmjs.org/pkg/-/pkg-1.0.0.tgz",
+			expectedURL: mirror.URL + "/npm/pkg/-/pkg-1.0.0.tgz",
+		},
+		{
+			name:        "Rewrites a URL locked against a configured registry",
+			registries:  []string{missing.URL + "/", mirror.URL + "/npm"},
+			url:         missing.URL + "/pkg/-/pkg-1.0.0.tgz",
+			validate:    true,
+			expectedURL: mirror.URL + "/npm/pkg/-/pkg-1.0.0.tgz",
+		},
+		{
+			name:        "Does not fall back on other HTTP errors",
+			registries:  []string{failing.URL + "/", mirror.URL + "/npm/"},
+			url:         failing.URL + "/pkg/-/pkg-1.0.0.tgz",
+			expectError: true,
+		},
+		{
+			name:        "URLs outside the registries have no fallback",
+			registries:  []string{mirror.URL + "/npm/"},
+			url:         missing.URL + "/pkg/-/pkg-1.0.0.tgz",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tb := NewTarball(t.TempDir())
+			tb.SetRetries(0)
+			tb.SetRegistries(tc.registries)
+
+			var servedURL string
+			var err error
+			if tc.validate {
+				servedURL, err = tb.DownloadAndValidate(tc.url, "pkg-1.0.0.tgz", sri)
+			} else {
+				servedURL, err = tb.DownloadAs(tc.url, "pkg-1.0.0.tgz")
+			}
+
+			if tc.expectError {
+				assert.Error(t, err)
+				assert.NoFileExists(t, filepath.Join(tb.TarballPath, "pkg-1.0.0.tgz"))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedURL, servedURL)
+			data, err := os.ReadFile(filepath.Join(tb.TarballPath, "pkg-1.0.0.tgz"))
+			require.NoError(t, err)
+			assert.Equal(t, content, data)
+		})
+	}
+}
+
+func TestTarball_DownloadResume(t *testing.T) {
+	content := []byte(strings.Repeat("tarball content ", 64))
+	contentPath := filepath.Join(t.TempDir(), "content.tgz")
+	require.NoError(t, os.WriteFile(contentPath, content, 0644))
+	sri, err := integrity.ComputeSRI(contentPath, "sha512")
+	require.NoError(t, err)
+	half := len(content) / 2
+
+	testCases := []struct {
+		name           string
+		acceptRanges   bool
+		ignoreRange    bool
+		integrity      string
+		expectedRanges []string
+		expectError    bool
+	}{
+		{
+			name:           "resumes from the bytes received",
+			acceptRanges:   true,
+			expectedRanges: []string{"", fmt.Sprintf("bytes=%d-", half)},
+		},
+		{
+			name:           "resumed tarball is validated",
+			acceptRanges:   true,
+			integrity:      sri,
+			expectedRanges: []string{"", fmt.Sprintf("bytes=%d-", half)},
+		},
+		{
+			name:           "restarts when ranges are not advertised",
+			expectedRanges: []string{"", ""},
+		},
+		{
+			name:           "downloads again when the server ignores the range",
+			acceptRanges:   true,
+			ignoreRange:    true,
+			expectedRanges: []string{"", fmt.Sprintf("bytes=%d-", half)},
+		},
+		{
+			name:           "invalid resumed tarball is not kept",
+			acceptRanges:   true,
+			integrity:      "sha512-invalid",
+			expectedRanges: []string{"", fmt.Sprintf("bytes=%d-", half)},
+			expectError:    true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var ranges []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				ranges = append(ranges, r.Header.Get("Range"))
+				attempt := len(ranges)
+				mu.Unlock()
+
+				if tc.acceptRanges {
+					w.Header().Set("Accept-Ranges", "bytes")
+				}
+				if attempt == 1 {
+					// Drop the connection halfway through the body
+					w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+					w.WriteHeader(http.StatusOK)
+					w.Write(content[:half])
+					w.(http.Flusher).Flush()
+					panic(http.ErrAbortHandler)
+				}
+
+				if r.Header.Get("Range") != "" && !tc.ignoreRange {
+					w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", half, len(content)-1, len(content)))
+					w.WriteHeader(http.StatusPartialContent)
+					w.Write(content[half:])
+					return
+				}
+				w.Write(content)
+			}))
+			defer server.Close()
+
+			tb := NewTarball(t.TempDir())
+			tb.retryPolicy.BaseDelay = time.Millisecond
+
+			if tc.integrity != "" {
+				_, err = tb.DownloadAndValidate(server.URL+"/pkg-1.0.0.tgz", "pkg-1.0.0.tgz", tc.integrity)
+			} else {
+				_, err = tb.DownloadAs(server.URL+"/pkg-1.0.0.tgz", "pkg-1.0.0.tgz")
+			}
+
+			assert.Equal(t, tc.expectedRanges, ranges)
+			assert.NoFileExists(t, filepath.Join(tb.TarballPath, "pkg-1.0.0.tgz"+utils.PartSuffix))
+			if tc.expectError {
+				require.ErrorIs(t, err, integrity.ErrIntegrityMismatch)
+				assert.NoFileExists(t, filepath.Join(tb.TarballPath, "pkg-1.0.0.tgz"))
+				return
+			}
+			require.NoError(t, err)
+			downloaded, err := os.ReadFile(filepath.Join(tb.TarballPath, "pkg-1.0.0.tgz"))
+			require.NoError(t, err)
+			assert.Equal(t, content, downloaded)
+		})
+	}
+}
+
+func TestCacheVersion(t *testing.T) {
+	testCases := []struct {
+		name        string
+		packageName string
+		resolved    string
+		urlVersion  bool
+	}{
+		{name: "Registry tarball", packageName: "lodash", resolved: "https://registry.npmjs.org/lodash/-/lodash-4.17.21.tgz"},
+		{name: "Scoped registry tarball", packageName: "@babel/core", resolved: "https://npm.example/api/@babel/core/-/core-4.17.21.tgz"},
+		{name: "Escaped scoped registry tarball", packageName: "@babel/core", resolved: "https://npm.example/@babel%2fcore/-/core-4.17.21.tgz"},
+		{name: "Git dependency", packageName: "lodash", resolved: "git+https://github.com/lodash/lodash.git#abc123"},
+		{name: "Linked dependency", packageName: "lodash", resolved: "file:../lodash"},
+		{name: "Tarball URL", packageName: "lodash", resolved: "https://example.com/lodash-4.17.21.tgz", urlVersion: true},
+		{name: "Registry tarball of another package", packageName: "lodash", resolved: "https://registry.npmjs.org/underscore/-/underscore-4.17.21.tgz", urlVersion: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			version := CacheVersion(tc.packageName, "4.17.21", tc.resolved)
+			if tc.urlVersion {
+				assert.Equal(t, URLCacheVersion(tc.resolved), version)
+				assert.Regexp(t, `^url-[0-9a-f]{12}$`, version)
+				return
+			}
+			assert.Equal(t, "4.17.21", version)
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// TestResult is the outcome of one test phase for one repository
+type TestResult struct {
+	Suite    string // "repos" or "yarn"
+	Name     string // repository or yarn project name
+	Phase    string // e.g. "without lock file"
+	Failure  string // empty when the phase passed
+	Duration time.Duration
+}
+
+// Passed reports whether the phase succeeded
+func (r TestResult) Passed() bool {
+	return r.Failure == ""
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Time      string          `xml:"time,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// JUnitWriter renders test results as JUnit XML, with one testsuite per
+// harness suite and one testcase per repository phase
+type JUnitWriter struct {
+	results []TestResult
+}
+
+// NewJUnitWriter creates a JUnitWriter for results
+func NewJUnitWriter(results []TestResult) *JUnitWriter {
+	return &JUnitWriter{results: results}
+}
+
+// Write writes the JUnit XML document to w
+func (jw *JUnitWriter) Write(w io.Writer) error {
+	report := junitTestSuites{}
+	var total time.Duration
+
+	suiteIndex := make(map[string]int)
+	suiteTimes := make(map[string]time.Duration)
+	for _, result := range jw.results {
+		i, ok := suiteIndex[result.Suite]
+		if !ok {
+			i = len(report.Suites)
+			suiteIndex[result.Suite] = i
+			report.Suites = append(report.Suites, junitTestSuite{Name: result.Suite})
+		}
+
+		testCase := junitTestCase{
+			ClassName: result.Name,
+			Name:      result.Phase,
+			Time:      formatSeconds(result.Duration),
+		}
+		if !result.Passed() {
+			testCase.Failure = &junitFailure{Message: result.Failure, Text: result.Failure}
+			report.Suites[i].Failures++
+			report.Failures++
+		}
+
+		report.Suites[i].Tests++
+		report.Suites[i].TestCases = append(report.Suites[i].TestCases, testCase)
+		report.Tests++
+		suiteTimes[result.Suite] += result.Duration
+		total += result.Duration
+	}
+
+	for i := range report.Suites {
+		report.Suites[i].Time = formatSeconds(suiteTimes[report.Suites[i].Name])
+	}
+	report.Time = formatSeconds(total)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("failed to write JUnit report: %w", err)
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return fmt.Errorf("failed to write JUnit report: %w", err)
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// WriteFile writes the JUnit XML document to path
+func (jw *JUnitWriter) WriteFile(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create JUnit report: %w", err)
+	}
+
+	if err := jw.Write(file); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// formatSeconds formats a duration as seconds, the unit JUnit uses
+func formatSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJUnitWriter(t *testing.T) {
+	results := []TestResult{
+		{Suite: "repos", Name: "express", Phase: phaseName(false), Duration: 1500 * time.Millisecond},
+		{Suite: "repos", Name: "express", Phase: phaseName(true), Duration: 500 * time.Millisecond},
+		{Suite: "repos", Name: "broken", Phase: phaseName(false), Failure: "exit status 1", Duration: 250 * time.Millisecond},
+		{Suite: "repos", Name: "broken", Phase: phaseName(true), Failure: "skipped due to first test failure"},
+		{Suite: "yarn", Name: "basic", Phase: phaseName(false), Duration: time.Second},
+	}
+
+	testCases := []struct {
+		name     string
+		results  []TestResult
+		validate func(t *testing.T, report junitTestSuites)
+	}{
+		{
+			name:    "counts and durations per suite",
+			results: results,
+			validate: func(t *testing.T, report junitTestSuites) {
+				assert.Equal(t, 5, report.Tests)
+				assert.Equal(t, 2, report.Failures)
+				assert.Equal(t, "3.250", report.Time)
+				require.Len(t, report.Suites, 2)
+
+				repos := report.Suites[0]
+				assert.Equal(t, "repos", repos.Name)
+				assert.Equal(t, 4, repos.Tests)
+				assert.Equal(t, 2, repos.Failures)
+				assert.Equal(t, "2.250", repos.Time)
+				require.Len(t, repos.TestCases, 4)
+				assert.Equal(t, "express", repos.TestCases[0].ClassName)
+				assert.Equal(t, "without lock file", repos.TestCases[0].Name)
+				assert.Equal(t, "1.500", repos.TestCases[0].Time)
+				assert.Nil(t, repos.TestCases[0].Failure)
+				require.NotNil(t, repos.TestCases[2].Failure)
+				assert.Equal(t, "exit status 1", repos.TestCases[2].Failure.Message)
+
+				yarn := report.Suites[1]
+				assert.Equal(t, "yarn", yarn.Name)
+				assert.Equal(t, 1, yarn.Tests)
+				assert.Equal(t, 0, yarn.Failures)
+			},
+		},
+		{
+			name:    "no results",
+			results: nil,
+			validate: func(t *testing.T, report junitTestSuites) {
+				assert.Equal(t, 0, report.Tests)
+				assert.Equal(t, 0, report.Failures)
+				assert.Empty(t, report.Suites)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, NewJUnitWriter(tc.results).Write(&buf))
+			assert.True(t, bytes.HasPrefix(buf.Bytes(), []byte(xml.Header)))
+
+			var report junitTestSuites
+			require.NoError(t, xml.Unmarshal(buf.Bytes(), &report))
+			tc.validate(t, report)
+		})
+	}
+}
+
+func TestJUnitWriterWriteFile(t *testing.T) {
+	reportPath := filepath.Join(t.TempDir(), "junit.xml")
+	results := []TestResult{
+		{Suite: "yarn", Name: "<special & \"chars\">", Phase: phaseName(false), Failure: "failed: <stderr>"},
+	}
+
+	require.NoError(t, NewJUnitWriter(results).WriteFile(reportPath))
+
+	data, err := os.ReadFile(reportPath)
+	require.NoError(t, err)
+
+	var report junitTestSuites
+	require.NoError(t, xml.Unmarshal(data, &report))
+	require.Len(t, report.Suites, 1)
+	require.Len(t, report.Suites[0].TestCases, 1)
+	assert.Equal(t, "<special & \"chars\">", report.Suites[0].TestCases[0].ClassName)
+	assert.Equal(t, "failed: <stderr>", report.Suites[0].TestCases[0].Failure.Text)
+}
@@ -43,6 +43,7 @@ type TestSuite struct {
 	successfulTests int
 	failedTests     int
 	failedRepos     map[string]bool
+	results         []TestResult
 }
 
 // NewTestSuite creates a new test suite with default configuration
@@ -192,12 +193,46 @@ func (ts *TestSuite) cleanupRepo(repoPath, repoName string) {
 	}
 }
 
-// testRepo tests a repository with npm-packager
-func (ts *TestSuite) testRepo(repoPath, repoName string, withLockFile bool) error {
-	testPhase := "without lock file"
+// phaseName returns the name of the test phase for withLockFile
+func phaseName(withLockFile bool) string {
 	if withLockFile {
-		testPhase = "with lock file"
+		return "with lock file"
+	}
+	return "without lock file"
+}
+
+// runPhase runs testRepo and records its result and duration under suite
+func (ts *TestSuite) runPhase(suite, repoPath, repoName string, withLockFile bool) error {
+	startTime := time.Now()
+	err := ts.testRepo(repoPath, repoName, withLockFile)
+
+	result := TestResult{
+		Suite:    suite,
+		Name:     repoName,
+		Phase:    phaseName(withLockFile),
+		Duration: time.Since(startTime),
+	}
+	if err != nil {
+		result.Failure = err.Error()
 	}
+	ts.results = append(ts.results, result)
+
+	return err
+}
+
+// recordFailure records a phase that failed without running
+func (ts *TestSuite) recordFailure(suite, repoName string, withLockFile bool, message string) {
+	ts.results = append(ts.results, TestResult{
+		Suite:   suite,
+		Name:    repoName,
+		Phase:   phaseName(withLockFile),
+		Failure: message,
+	})
+}
+
+// testRepo tests a repository with npm-packager
+func (ts *TestSuite) testRepo(repoPath, repoName string, withLockFile bool) error {
+	testPhase := phaseName(withLockFile)
 
 	fmt.Println()
 	printStatus(ColorBlue, fmt.Sprintf("│ Testing '%s' %s", repoName, testPhase))
@@ -363,6 +398,8 @@ func (ts *TestSuite) Run() error {
 			printStatus(ColorRed, fmt.Sprintf("  ✗ Failed to clone %s: %v", repo.Name, err))
 			ts.failedTests += 2 // Both tests will fail
 			ts.totalTests += 2
+			ts.recordFailure("repos", repo.Name, false, err.Error())
+			ts.recordFailure("repos", repo.Name, true, err.Error())
 			if repo.Name != "" {
 				ts.failedRepos[repo.Name] = true
 			}
@@ -377,7 +414,7 @@ func (ts *TestSuite) Run() error {
 		// Test 1: Without lock file (fresh install)
 		ts.totalTests++
 		printStatus(ColorBlue, "\n  ═══ Phase 1: Testing without lock file ═══")
-		if err := ts.testRepo(repoPath, repo.Name, false); err == nil {
+		if err := ts.runPhase("repos", repoPath, repo.Name, false); err == nil {
 			ts.successfulTests++
 		} else {
 			ts.failedTests++
@@ -388,6 +425,7 @@ func (ts *TestSuite) Run() error {
 			printStatus(ColorYellow, "  ⊙ Skipping second test due to first test failure")
 			ts.totalTests++
 			ts.failedTests++
+			ts.recordFailure("repos", repo.Name, true, "skipped due to first test failure")
 			continue
 		}
 
@@ -395,7 +433,7 @@ func (ts *TestSuite) Run() error {
 		// Don't clean up - keep the lock file and node_modules
 		ts.totalTests++
 		printStatus(ColorBlue, "\n  ═══ Phase 2: Testing with lock file ═══")
-		if err := ts.testRepo(repoPath, repo.Name, true); err == nil {
+		if err := ts.runPhase("repos", repoPath, repo.Name, true); err == nil {
 			ts.successfulTests++
 		} else {
 			ts.failedTests++
@@ -548,7 +586,7 @@ func (ts *TestSuite) RunYarnTests() error {
 		// Test 1: Without lock file (fresh install)
 		ts.totalTests++
 		printStatus(ColorBlue, "\n  ═══ Phase 1: Testing without lock file ═══")
-		if err := ts.testRepo(projectPath, projectName, false); err == nil {
+		if err := ts.runPhase("yarn", projectPath, projectName, false); err == nil {
 			ts.successfulTests++
 		} else {
 			ts.failedTests++
@@ -557,13 +595,14 @@ func (ts *TestSuite) RunYarnTests() error {
 			printStatus(ColorYellow, "  ⊙ Skipping second test due to first test failure")
 			ts.totalTests++
 			ts.failedTests++
+			ts.recordFailure("yarn", projectName, true, "skipped due to first test failure")
 			continue
 		}
 
 		// Test 2: With lock file (using existing lock file from first test)
 		ts.totalTests++
 		printStatus(ColorBlue, "\n  ═══ Phase 2: Testing with lock file ═══")
-		if err := ts.testRepo(projectPath, projectName, true); err == nil {
+		if err := ts.runPhase("yarn", projectPath, projectName, true); err == nil {
 			ts.successfulTests++
 		} else {
 			ts.failedTests++
@@ -654,8 +693,15 @@ func main() {
 	// Define command-line flags
 	yarnOnly := flag.Bool("yarn", false, "Run only yarn tests from tests/yarn directory")
 	reposOnly := flag.Bool("repos", false, "Run only repository tests")
+	reporter := flag.String("reporter", "", "Additional report format to write (junit)")
+	output := flag.String("output", "", "File to write the report to (default: tests/junit.xml)")
 	flag.Parse()
 
+	if *reporter != "" && *reporter != "junit" {
+		fmt.Fprintf(os.Stderr, "Unsupported reporter %q (supported: junit)\n", *reporter)
+		os.Exit(1)
+	}
+
 	// Detect project root by looking for go.mod or main.go
 	workDir, err := os.Getwd()
 	if err != nil {
@@ -700,6 +746,18 @@ func main() {
 		}
 	}
 
+	if *reporter == "junit" {
+		reportPath := *output
+		if reportPath == "" {
+			reportPath = filepath.Join(testsDir, "junit.xml")
+		}
+		if err := NewJUnitWriter(suite.results).WriteFile(reportPath); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		printStatus(ColorBlue, fmt.Sprintf("📝 JUnit report saved to: %s", reportPath))
+	}
+
 	if runErr != nil {
 		os.Exit(1)
 	}
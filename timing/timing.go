@@ -0,0 +1,180 @@
+package timing
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Phase is a step of installing a package that --timing measures
+type Phase string
+
+const (
+	PhaseManifest Phase = "manifest"
+	PhaseDownload Phase = "download"
+	PhaseExtract  Phase = "extract"
+	PhaseCopy     Phase = "copy"
+)
+
+// Phases are the measured phases, in the order they run
+var Phases = []Phase{PhaseManifest, PhaseDownload, PhaseExtract, PhaseCopy}
+
+// Durations are the time a package spent in each phase
+type Durations map[Phase]time.Duration
+
+// Total is the time spent in every phase
+func (d Durations) Total() time.Duration {
+	var total time.Duration
+	for _, duration := range d {
+		total += duration
+	}
+	return total
+}
+
+// Buffer collects the durations of a single worker without locking. It is
+// merged into its Report once the worker is done. A nil Buffer discards
+// everything, so callers need not check whether timing is enabled.
+type Buffer struct {
+	packages map[string]Durations
+}
+
+// Add records d spent in phase by the package keyed name@version
+func (b *Buffer) Add(key string, phase Phase, d time.Duration) {
+	if b == nil {
+		return
+	}
+	durations, ok := b.packages[key]
+	if !ok {
+		durations = Durations{}
+		b.packages[key] = durations
+	}
+	durations[phase] += d
+}
+
+// Since records the time elapsed since start, for use with defer
+func (b *Buffer) Since(key string, phase Phase, start time.Time) {
+	b.Add(key, phase, time.Since(start))
+}
+
+// Report is the per-package timing of a command, keyed by name@version. A
+// nil Report is disabled.
+type Report struct {
+	mu       sync.Mutex
+	packages map[string]Durations
+}
+
+// New creates an empty report
+func New() *Report {
+	return &Report{packages: make(map[string]Durations)}
+}
+
+// NewBuffer returns a buffer for one worker, or nil when r is disabled
+func (r *Report) NewBuffer() *Buffer {
+	if r == nil {
+		return nil
+	}
+	return &Buffer{packages: make(map[string]Durations)}
+}
+
+// Merge adds the durations collected in b
+func (r *Report) Merge(b *Buffer) {
+	if r == nil || b == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for key, durations := range b.packages {
+		merged, ok := r.packages[key]
+		if !ok {
+			merged = Durations{}
+			r.packages[key] = merged
+		}
+		for phase, d := range durations {
+			merged[phase] += d
+		}
+	}
+}
+
+// Packages returns a copy of the durations recorded for each package
+func (r *Report) Packages() map[string]Durations {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	packages := make(map[string]Durations, len(r.packages))
+	for key, durations := range r.packages {
+		copied := make(Durations, len(durations))
+		for phase, d := range durations {
+			copied[phase] = d
+		}
+		packages[key] = copied
+	}
+	return packages
+}
+
+// Slowest returns up to n package keys ordered by total time, slowest first
+func (r *Report) Slowest(n int) []string {
+	packages := r.Packages()
+	keys := make([]string, 0, len(packages))
+	for key := range packages {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b string) int {
+		if c := cmp.Compare(packages[b].Total(), packages[a].Total()); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	})
+	if len(keys) > n {
+		keys = keys[:n]
+	}
+	return keys
+}
+
+// Totals returns the time spent in each phase across every package
+func (r *Report) Totals() Durations {
+	totals := Durations{}
+	for _, durations := range r.Packages() {
+		for phase, d := range durations {
+			totals[phase] += d
+		}
+	}
+	return totals
+}
+
+// Write prints the top slowest packages with their phases, followed by the
+// phase totals. Phases run concurrently, so totals can exceed the wall time.
+func (r *Report) Write(w io.Writer, top int) {
+	if r == nil {
+		return
+	}
+	slowest := r.Slowest(top)
+	fmt.Fprintf(w, "\nSlowest packages:\n")
+	if len(slowest) == 0 {
+		fmt.Fprintf(w, "  (none)\n")
+	}
+	packages := r.Packages()
+	for _, key := range slowest {
+		durations := packages[key]
+		fmt.Fprintf(w, "  %-40s %8s", key, formatDuration(durations.Total()))
+		for _, phase := range Phases {
+			if d, ok := durations[phase]; ok {
+				fmt.Fprintf(w, "  %s %s", phase, formatDuration(d))
+			}
+		}
+		fmt.Fprintln(w)
+	}
+
+	totals := r.Totals()
+	fmt.Fprintf(w, "\nPhase totals:\n")
+	for _, phase := range Phases {
+		fmt.Fprintf(w, "  %-10s %8s\n", phase, formatDuration(totals[phase]))
+	}
+}
+
+// formatDuration prints d in milliseconds with two decimals
+func formatDuration(d time.Duration) string {
+	return fmt.Sprintf("%.2fms", float64(d)/float64(time.Millisecond))
+}
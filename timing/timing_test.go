@@ -0,0 +1,90 @@
+package timing
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReportMergesWorkerBuffers(t *testing.T) {
+	report := New()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			buf := report.NewBuffer()
+			defer report.Merge(buf)
+			buf.Add("a@1.0.0", PhaseDownload, time.Millisecond)
+			buf.Add("b@1.0.0", PhaseCopy, 2*time.Millisecond)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, map[string]Durations{
+		"a@1.0.0": {PhaseDownload: 4 * time.Millisecond},
+		"b@1.0.0": {PhaseCopy: 8 * time.Millisecond},
+	}, report.Packages())
+	assert.Equal(t, Durations{PhaseDownload: 4 * time.Millisecond, PhaseCopy: 8 * time.Millisecond}, report.Totals())
+}
+
+func TestReportSlowest(t *testing.T) {
+	testCases := []struct {
+		name     string
+		top      int
+		expected []string
+	}{
+		{name: "all packages slowest first", top: 10, expected: []string{"c@1.0.0", "a@1.0.0", "b@1.0.0"}},
+		{name: "ties are ordered by name", top: 2, expected: []string{"c@1.0.0", "a@1.0.0"}},
+		{name: "none", top: 0, expected: []string{}},
+	}
+
+	report := New()
+	buf := report.NewBuffer()
+	buf.Add("a@1.0.0", PhaseManifest, 2*time.Millisecond)
+	buf.Add("b@1.0.0", PhaseExtract, 2*time.Millisecond)
+	buf.Add("c@1.0.0", PhaseDownload, 2*time.Millisecond)
+	buf.Add("c@1.0.0", PhaseCopy, time.Millisecond)
+	report.Merge(buf)
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, report.Slowest(tc.top))
+		})
+	}
+}
+
+func TestReportWrite(t *testing.T) {
+	report := New()
+	buf := report.NewBuffer()
+	buf.Add("a@1.0.0", PhaseDownload, 1500*time.Microsecond)
+	buf.Add("a@1.0.0", PhaseExtract, 500*time.Microsecond)
+	report.Merge(buf)
+
+	var out bytes.Buffer
+	report.Write(&out, 10)
+
+	assert.Contains(t, out.String(), "Slowest packages:")
+	assert.Regexp(t, `a@1\.0\.0\s+2\.00ms  download 1\.50ms  extract 0\.50ms\n`, out.String())
+	assert.Contains(t, out.String(), "Phase totals:")
+	assert.Regexp(t, `manifest\s+0\.00ms\n`, out.String())
+	assert.Regexp(t, `download\s+1\.50ms\n`, out.String())
+}
+
+func TestDisabledReport(t *testing.T) {
+	var report *Report
+	buf := report.NewBuffer()
+	assert.Nil(t, buf)
+
+	// A disabled report and its buffers accept and discard everything
+	buf.Add("a@1.0.0", PhaseCopy, time.Millisecond)
+	report.Merge(buf)
+
+	var out bytes.Buffer
+	report.Write(&out, 10)
+	assert.Empty(t, out.String())
+}
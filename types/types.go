@@ -1,7 +1,39 @@
 package types
 
 type BuildOptions struct {
-	Version       string
-	Verbose       bool
-	IgnoreScripts bool
+	Version              string
+	Verbose              bool
+	IgnoreScripts        bool
+	ExplainResolution    bool
+	IncludePrerelease    bool
+	MaxConcurrency       int
+	EngineStrict         bool
+	NoPeer               bool
+	VerifySignatures     bool
+	AtomicInstall        bool
+	ReportConflicts      bool
+	ForceResolutions     bool
+	InstallStrategy      string
+	InstallLinks         bool
+	PackLinks            bool
+	Workspaces           []string
+	AllWorkspaces        bool
+	Offline              bool
+	PreferOffline        bool
+	OmitLockfileRegistry bool
+	Progress             string
+	JSON                 bool
+	CacheLock            bool
+	ResolutionOnly       bool
+	NoOptional           bool
+	Production           bool
+	Omit                 []string
+	IgnoreOptional       bool
+	DryRun               bool
+	NoSave               bool
+	NoBinLinks           bool
+	LogLevel             string
+	Quiet                bool
+	Timing               bool
+	Force                bool
 }
@@ -0,0 +1,17 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// LockFile takes an exclusive lock on path, shared by every process on the
+// machine, creating the file and its directory if needed. It blocks until the
+// lock is free and returns the function that releases it.
+func LockFile(path string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory: %w", err)
+	}
+	return lockFile(path)
+}
@@ -0,0 +1,27 @@
+//go:build !unix
+
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// lockFile holds the lock while path exists, polling until another process
+// removes it. Unlike flock, a lock left by a crashed process must be removed
+// by hand.
+func lockFile(path string) (func(), error) {
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("failed to create lock file %s: %w", path, err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
@@ -0,0 +1,114 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLockFileHelperProcess is not a real test: it is re-executed as a
+// separate process by TestLockFileAcrossProcesses, extracting a fake package
+// into a shared cache under the file lock the way the manager does
+func TestLockFileHelperProcess(t *testing.T) {
+	cacheDir := os.Getenv("GO_NPM_LOCK_HELPER_CACHE")
+	if cacheDir == "" {
+		t.Skip("helper process")
+	}
+
+	unlock, err := LockFile(filepath.Join(cacheDir, "locks", "pkg@1.0.0.lock"))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer unlock()
+
+	pkgDir := filepath.Join(cacheDir, "pkg@1.0.0")
+	if FolderExists(pkgDir) {
+		return
+	}
+
+	log, err := os.OpenFile(filepath.Join(cacheDir, "extractions.log"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Fprintln(log, os.Getpid())
+	log.Close()
+
+	// Write the entry slowly so an unlocked second process would see it half done
+	if err := os.MkdirAll(pkgDir, 0755); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	for i := range 5 {
+		time.Sleep(20 * time.Millisecond)
+		name := filepath.Join(pkgDir, fmt.Sprintf("file%d.js", i))
+		if err := os.WriteFile(name, []byte("module.exports = 1\n"), 0644); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+}
+
+func TestLockFileAcrossProcesses(t *testing.T) {
+	cacheDir := t.TempDir()
+
+	cmds := make([]*exec.Cmd, 2)
+	for i := range cmds {
+		cmds[i] = exec.Command(os.Args[0], "-test.run=^TestLockFileHelperProcess$")
+		cmds[i].Env = append(os.Environ(), "GO_NPM_LOCK_HELPER_CACHE="+cacheDir)
+		require.NoError(t, cmds[i].Start())
+	}
+	for _, cmd := range cmds {
+		require.NoError(t, cmd.Wait())
+	}
+
+	log, err := os.ReadFile(filepath.Join(cacheDir, "extractions.log"))
+	require.NoError(t, err)
+	assert.Len(t, strings.Fields(string(log)), 1, "the package should be extracted once")
+
+	entries, err := os.ReadDir(filepath.Join(cacheDir, "pkg@1.0.0"))
+	require.NoError(t, err)
+	assert.Len(t, entries, 5)
+	for _, entry := range entries {
+		data, err := os.ReadFile(filepath.Join(cacheDir, "pkg@1.0.0", entry.Name()))
+		require.NoError(t, err)
+		assert.Equal(t, "module.exports = 1\n", string(data))
+	}
+}
+
+func TestLockFileReleases(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "locks", "a@1.0.0.lock")
+
+	unlock, err := LockFile(path)
+	require.NoError(t, err)
+
+	acquired := make(chan struct{})
+	go func() {
+		unlock2, err := LockFile(path)
+		if err == nil {
+			unlock2()
+		}
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("lock acquired while held")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	unlock()
+	select {
+	case <-acquired:
+	case <-time.After(2 * time.Second):
+		t.Fatal("lock not acquired after release")
+	}
+}
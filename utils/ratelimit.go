@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token bucket holding a single token, shared by every
+// request to one registry: requests are spaced by 1/rate whichever worker
+// makes them. A nil *RateLimiter does not limit.
+type RateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// NewRateLimiter returns a limiter allowing perSecond requests per second, or
+// nil, which does not limit, when perSecond is not positive
+func NewRateLimiter(perSecond float64) *RateLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return &RateLimiter{interval: time.Duration(float64(time.Second) / perSecond)}
+}
+
+// Wait blocks until a request may be made or ctx is cancelled
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	// Reserve the next slot, then sleep until it without holding the lock
+	l.mu.Lock()
+	now := time.Now()
+	at := l.next
+	if at.Before(now) {
+		at = now
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+
+	delay := time.Until(at)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Pause holds every request back for d, e.g. after a 429 with Retry-After
+func (l *RateLimiter) Pause(d time.Duration) {
+	if l == nil || d <= 0 {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if until := time.Now().Add(d); l.next.Before(until) {
+		l.next = until
+	}
+}
+
+// RateLimiters hands out one RateLimiter per registry host, so that the
+// rate, and the pause of a 429, hold for each registry (e.g. the registry of
+// a scope) without throttling the others. A nil *RateLimiters does not limit.
+type RateLimiters struct {
+	mu        sync.Mutex
+	perSecond float64
+	limiters  map[string]*RateLimiter
+}
+
+// NewRateLimiters returns limiters allowing perSecond requests per second to
+// each registry, or nil, which does not limit, when perSecond is not positive
+func NewRateLimiters(perSecond float64) *RateLimiters {
+	if perSecond <= 0 {
+		return nil
+	}
+	return &RateLimiters{perSecond: perSecond, limiters: make(map[string]*RateLimiter)}
+}
+
+// For returns the limiter of the host rawURL is on, created on first use
+func (ls *RateLimiters) For(rawURL string) *RateLimiter {
+	if ls == nil {
+		return nil
+	}
+
+	host := rawURL
+	if u, err := url.Parse(rawURL); err == nil && u.Host != "" {
+		host = u.Host
+	}
+
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	limiter, ok := ls.limiters[host]
+	if !ok {
+		limiter = NewRateLimiter(ls.perSecond)
+		ls.limiters[host] = limiter
+	}
+	return limiter
+}
@@ -0,0 +1,153 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// PartSuffix is appended to the name of a file while it is downloaded by
+// DownloadFileResumable
+const PartSuffix = ".part"
+
+// partState is what a resumable download learns from its first response:
+// whether the server accepts ranges, the ETag that a resumed request must
+// still match, and the full size, -1 when unknown
+type partState struct {
+	acceptRanges bool
+	etag         string
+	size         int64
+}
+
+// DownloadFileResumable downloads url to filename through filename.part,
+// retrying like DownloadFileWithRetryContext. A failed attempt keeps the
+// bytes already received and, when the server advertises Accept-Ranges, the
+// retry asks only for the rest with a Range header; a server that ignores it
+// sends the whole file again. The complete part file is checked against the
+// size the server announced and, when validate is not nil, by validate,
+// before it is renamed to filename.
+func DownloadFileResumable(ctx context.Context, url, filename string, validate func(path string) error, policy RetryPolicy) (int, error) {
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return 0, fmt.Errorf("failed to create directory structure: %w", err)
+	}
+
+	partPath := filename + PartSuffix
+	os.Remove(partPath)
+
+	state := &partState{size: -1}
+	statusCode, err := policy.retry(ctx, url, func(ctx context.Context) (int, error) {
+		return downloadPart(ctx, url, partPath, state, max(policy.Timeout, 0))
+	})
+	if err != nil {
+		os.Remove(partPath)
+		return statusCode, err
+	}
+
+	if validate != nil {
+		if err := validate(partPath); err != nil {
+			os.Remove(partPath)
+			return statusCode, err
+		}
+	}
+
+	if err := os.Rename(partPath, filename); err != nil {
+		os.Remove(partPath)
+		return statusCode, fmt.Errorf("failed to finalize download: %w", err)
+	}
+	return statusCode, nil
+}
+
+// downloadPart makes one attempt at completing partPath, resuming from its
+// size when the server accepts ranges
+func downloadPart(ctx context.Context, url, partPath string, state *partState, timeout time.Duration) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	var offset int64
+	if state.acceptRanges {
+		if info, err := os.Stat(partPath); err == nil && info.Size() > 0 {
+			offset = info.Size()
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+			// A file changed on the server since is sent whole
+			if state.etag != "" {
+				req.Header.Set("If-Range", state.etag)
+			}
+		}
+	}
+
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, &retryableError{err: fmt.Errorf("failed to fetch URL: %w", err)}
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0:
+		start, total, ok := parseContentRange(resp.Header.Get("Content-Range"))
+		if !ok || start != offset {
+			// Not the range asked for: start over without ranges
+			os.Remove(partPath)
+			state.acceptRanges = false
+			return resp.StatusCode, &retryableError{err: fmt.Errorf("unexpected Content-Range %q from %s", resp.Header.Get("Content-Range"), url)}
+		}
+		if total >= 0 {
+			state.size = total
+		}
+		flags = os.O_WRONLY | os.O_APPEND
+	case resp.StatusCode == http.StatusOK:
+		// A first request, or a server that ignored the Range
+		offset = 0
+		state.acceptRanges = resp.Header.Get("Accept-Ranges") == "bytes"
+		state.etag = resp.Header.Get("ETag")
+		state.size = resp.ContentLength
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		os.Remove(partPath)
+		state.acceptRanges = false
+		return resp.StatusCode, &retryableError{err: fmt.Errorf("HTTP error: %s, %d %s", url, resp.StatusCode, resp.Status)}
+	default:
+		err := fmt.Errorf("HTTP error: %s, %d %s", url, resp.StatusCode, resp.Status)
+		if isRetryableStatus(resp.StatusCode) {
+			return resp.StatusCode, &retryableError{err: err, retryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
+		}
+		return resp.StatusCode, err
+	}
+
+	file, err := os.OpenFile(partPath, flags, 0644)
+	if err != nil {
+		return resp.StatusCode, fmt.Errorf("failed to create file: %w", err)
+	}
+	written, err := io.Copy(file, resp.Body)
+	file.Close()
+	if err != nil {
+		// The bytes received are kept for the next attempt to resume from
+		return resp.StatusCode, &retryableError{err: fmt.Errorf("failed to write file: %w", err)}
+	}
+
+	if state.size >= 0 && offset+written != state.size {
+		os.Remove(partPath)
+		state.acceptRanges = false
+		return resp.StatusCode, &retryableError{err: fmt.Errorf("incomplete download of %s: got %d of %d bytes", url, offset+written, state.size)}
+	}
+	return resp.StatusCode, nil
+}
+
+// parseContentRange parses a "bytes start-end/total" Content-Range header.
+// total is -1 when the server does not know it.
+func parseContentRange(value string) (start, total int64, ok bool) {
+	var end int64
+	if _, err := fmt.Sscanf(value, "bytes %d-%d/%d", &start, &end, &total); err == nil {
+		return start, total, true
+	}
+	if _, err := fmt.Sscanf(value, "bytes %d-%d/*", &start, &end); err == nil {
+		return start, -1, true
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,266 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// DefaultRetries is how many times a failed registry request is retried
+// unless fetch-retries says otherwise
+const DefaultRetries = 3
+
+// ErrOffline is returned for a download attempted in offline mode
+var ErrOffline = errors.New("offline mode")
+
+// ErrTimeout is returned for a download whose last attempt exceeded the
+// policy's timeout
+var ErrTimeout = errors.New("request timed out")
+
+// RetryPolicy controls how DownloadFileWithRetry retries transient failures
+type RetryPolicy struct {
+	Retries   int
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+
+	// Timeout bounds each attempt, from connecting to reading the whole
+	// body; zero waits indefinitely
+	Timeout time.Duration
+
+	// Limiters, when set, space every attempt, retries included, on the
+	// limiter of the registry it goes to, which is paused for the
+	// Retry-After of a 429 response
+	Limiters *RateLimiters
+}
+
+// DefaultRetryPolicy returns the policy used for registry downloads
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		Retries:   DefaultRetries,
+		BaseDelay: 500 * time.Millisecond,
+		MaxDelay:  30 * time.Second,
+	}
+}
+
+// retryableError marks a download failure that may succeed on a later attempt
+// (network errors, 5xx and 429 responses)
+type retryableError struct {
+	err        error
+	retryAfter time.Duration
+}
+
+func (e *retryableError) Error() string {
+	return e.err.Error()
+}
+
+func (e *retryableError) Unwrap() error {
+	return e.err
+}
+
+// RegistryUnavailable reports whether a failed download is worth trying on a
+// fallback registry: the registry could not be reached or answered 404.
+// Cancelled downloads and other HTTP errors are not.
+func RegistryUnavailable(ctx context.Context, statusCode int, err error) bool {
+	if err == nil || ctx.Err() != nil || errors.Is(err, ErrOffline) {
+		return false
+	}
+	return statusCode == 0 || statusCode == http.StatusNotFound
+}
+
+// DownloadFileWithRetry behaves like DownloadFile but retries network errors and
+// 5xx/429 responses with exponential backoff and jitter, honoring Retry-After.
+// Other HTTP errors (e.g. 404) are returned immediately.
+func DownloadFileWithRetry(url, filename string, etag string, policy RetryPolicy) (string, int, error) {
+	return DownloadFileWithRetryContext(context.Background(), url, filename, etag, policy)
+}
+
+// DownloadFileWithRetryContext behaves like DownloadFileWithRetry but stops
+// downloading and retrying once ctx is cancelled
+func DownloadFileWithRetryContext(ctx context.Context, url, filename string, etag string, policy RetryPolicy) (string, int, error) {
+	return DownloadFileWithRetryHeadersContext(ctx, url, filename, etag, nil, policy)
+}
+
+// DownloadFileWithRetryHeadersContext behaves like DownloadFileWithRetryContext
+// but also sends headers with each request
+func DownloadFileWithRetryHeadersContext(ctx context.Context, url, filename string, etag string, headers map[string]string, policy RetryPolicy) (string, int, error) {
+	var newEtag string
+	statusCode, err := policy.retry(ctx, url, func(ctx context.Context) (int, error) {
+		var statusCode int
+		var err error
+		newEtag, statusCode, err = downloadFile(ctx, url, filename, etag, headers, max(policy.Timeout, 0))
+		return statusCode, err
+	})
+	return newEtag, statusCode, err
+}
+
+// retry runs attempt until it succeeds, fails with an error that is not
+// retryable or runs out of retries, backing off in between. Each attempt is
+// cancelled once the policy's timeout elapses.
+func (p RetryPolicy) retry(ctx context.Context, url string, attempt func(ctx context.Context) (int, error)) (int, error) {
+	limiter := p.Limiters.For(url)
+	for i := 0; ; i++ {
+		statusCode, err := p.attempt(ctx, limiter, attempt)
+		if err == nil {
+			return statusCode, nil
+		}
+		if ctx.Err() != nil {
+			return statusCode, ctx.Err()
+		}
+		if p.Timeout > 0 && isTimeout(err) {
+			err = &retryableError{err: fmt.Errorf("%w: %s did not complete within %s", ErrTimeout, url, p.Timeout)}
+		}
+
+		var retryErr *retryableError
+		if !errors.As(err, &retryErr) || i >= p.Retries {
+			return statusCode, err
+		}
+
+		delay := retryErr.retryAfter
+		if delay == 0 {
+			delay = p.backoff(i)
+		} else if p.MaxDelay > 0 && delay > p.MaxDelay {
+			delay = p.MaxDelay
+		}
+
+		// The registry throttles the client, not this one request
+		if statusCode == http.StatusTooManyRequests && retryErr.retryAfter > 0 {
+			limiter.Pause(delay)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return statusCode, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// attempt makes one attempt once limiter allows it, cancelled once the
+// policy's timeout elapses
+func (p RetryPolicy) attempt(ctx context.Context, limiter *RateLimiter, fn func(ctx context.Context) (int, error)) (int, error) {
+	if err := limiter.Wait(ctx); err != nil {
+		return 0, err
+	}
+	if p.Timeout <= 0 {
+		return fn(ctx)
+	}
+
+	attemptCtx, cancel := context.WithTimeout(ctx, p.Timeout)
+	defer cancel()
+	return fn(attemptCtx)
+}
+
+// isTimeout reports whether err comes from a request or body read that
+// exceeded its deadline
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay << attempt
+	if p.MaxDelay > 0 && (delay > p.MaxDelay || delay <= 0) {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+
+	// Equal jitter: keep half of the delay, randomize the other half
+	half := delay / 2
+	return half + time.Duration(rand.Int64N(int64(half)+1))
+}
+
+func isRetryableStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= 500
+}
+
+// parseRetryAfter parses a Retry-After header given either as seconds or as an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(date); delay > 0 {
+			return delay
+		}
+	}
+
+	return 0
+}
+
+// PostWithRetryContext POSTs body to url and returns the response body,
+// retrying network errors and 5xx/429 responses like
+// DownloadFileWithRetryContext. Other HTTP errors are returned immediately.
+func PostWithRetryContext(ctx context.Context, url string, body []byte, headers map[string]string, policy RetryPolicy) ([]byte, int, error) {
+	return requestWithRetry(ctx, http.MethodPost, url, body, headers, policy)
+}
+
+// GetWithRetryContext GETs url and returns the response body, retrying like
+// PostWithRetryContext
+func GetWithRetryContext(ctx context.Context, url string, headers map[string]string, policy RetryPolicy) ([]byte, int, error) {
+	return requestWithRetry(ctx, http.MethodGet, url, nil, headers, policy)
+}
+
+func requestWithRetry(ctx context.Context, method, url string, body []byte, headers map[string]string, policy RetryPolicy) ([]byte, int, error) {
+	var response []byte
+	statusCode, err := policy.retry(ctx, url, func(ctx context.Context) (int, error) {
+		var statusCode int
+		var err error
+		response, statusCode, err = request(ctx, method, url, body, headers, max(policy.Timeout, 0))
+		return statusCode, err
+	})
+	return response, statusCode, err
+}
+
+// request makes one attempt and reads the whole response
+func request(ctx context.Context, method, url string, body []byte, headers map[string]string, timeout time.Duration) ([]byte, int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, &retryableError{err: fmt.Errorf("failed to fetch URL: %w", err)}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err := fmt.Errorf("HTTP error: %s, %d %s", url, resp.StatusCode, resp.Status)
+		if isRetryableStatus(resp.StatusCode) {
+			return nil, resp.StatusCode, &retryableError{err: err, retryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
+		}
+		return nil, resp.StatusCode, err
+	}
+
+	response, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, resp.StatusCode, &retryableError{err: fmt.Errorf("failed to read response: %w", err)}
+	}
+	return response, resp.StatusCode, nil
+}
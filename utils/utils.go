@@ -2,27 +2,51 @@ package utils
 
 import (
 	"compress/gzip"
+	"context"
 	"fmt"
 	"io"
+	"io/fs"
 	"net/http"
 	"os"
 	"path/filepath"
+	"time"
 )
 
 func DownloadFile(url, filename string, etag string) (string, int, error) {
-	req, err := http.NewRequest("GET", url, nil)
+	return DownloadFileContext(context.Background(), url, filename, etag)
+}
+
+// DownloadFileContext behaves like DownloadFile but aborts the request, and
+// removes the partial download, when ctx is cancelled
+func DownloadFileContext(ctx context.Context, url, filename string, etag string) (string, int, error) {
+	return DownloadFileWithHeadersContext(ctx, url, filename, etag, nil)
+}
+
+// DownloadFileWithHeadersContext behaves like DownloadFileContext but also
+// sends headers with the request
+func DownloadFileWithHeadersContext(ctx context.Context, url, filename string, etag string, headers map[string]string) (string, int, error) {
+	return downloadFile(ctx, url, filename, etag, headers, 0)
+}
+
+// downloadFile downloads url to filename, giving up on a request that takes
+// longer than timeout; a zero timeout waits indefinitely
+func downloadFile(ctx context.Context, url, filename string, etag string, headers map[string]string, timeout time.Duration) (string, int, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return "", 0, fmt.Errorf("failed to create request: %w", err)
 	}
 
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
 	if etag != "" {
 		req.Header.Set("If-None-Match", etag)
 	}
 
-	client := &http.Client{}
+	client := &http.Client{Timeout: timeout}
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", 0, fmt.Errorf("failed to fetch URL: %w", err)
+		return "", 0, &retryableError{err: fmt.Errorf("failed to fetch URL: %w", err)}
 	}
 	defer resp.Body.Close()
 
@@ -31,7 +55,11 @@ func DownloadFile(url, filename string, etag string) (string, int, error) {
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return "", resp.StatusCode, fmt.Errorf("HTTP error: %s, %d %s", url, resp.StatusCode, resp.Status)
+		err := fmt.Errorf("HTTP error: %s, %d %s", url, resp.StatusCode, resp.Status)
+		if isRetryableStatus(resp.StatusCode) {
+			return "", resp.StatusCode, &retryableError{err: err, retryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
+		}
+		return "", resp.StatusCode, err
 	}
 
 	dir := filepath.Dir(filename)
@@ -51,7 +79,7 @@ func DownloadFile(url, filename string, etag string) (string, int, error) {
 
 	if err != nil {
 		os.Remove(tempFile) // Clean up temp file on failure
-		return "", resp.StatusCode, fmt.Errorf("failed to write file: %w", err)
+		return "", resp.StatusCode, &retryableError{err: fmt.Errorf("failed to write file: %w", err)}
 	}
 
 	// Atomic rename: only succeeds if download completed
@@ -110,3 +138,47 @@ func ValidateTarball(filePath string) bool {
 
 	return true
 }
+
+// FormatBytes renders a byte count as a human readable size, e.g. 1.50 KB
+func FormatBytes(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.2f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
+// DirSize returns the total size of the regular files under dir. A missing
+// dir has size 0.
+func DirSize(dir string) (int64, error) {
+	var size int64
+
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to measure %s: %w", dir, err)
+	}
+
+	return size, nil
+}
@@ -1,14 +1,20 @@
 package utils
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestDownloadFile(t *testing.T) {
@@ -222,6 +228,182 @@ func TestDownloadFile(t *testing.T) {
 	}
 }
 
+func TestDownloadFileWithRetryContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		cancel()
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	filename := filepath.Join(t.TempDir(), "test.tgz")
+	policy := RetryPolicy{Retries: 3, BaseDelay: time.Minute, MaxDelay: time.Minute}
+
+	start := time.Now()
+	_, _, err := DownloadFileWithRetryContext(ctx, server.URL, filename, "", policy)
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, int32(1), attempts.Load(), "a cancelled download should not be retried")
+	assert.Less(t, time.Since(start), 10*time.Second, "the retry backoff should be interrupted")
+	assert.NoFileExists(t, filename)
+	assert.NoFileExists(t, filename+".tmp")
+}
+
+func TestDownloadFileWithRetryTimeout(t *testing.T) {
+	var attempts atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	filename := filepath.Join(t.TempDir(), "test.tgz")
+	policy := RetryPolicy{Retries: 1, Timeout: 50 * time.Millisecond}
+
+	start := time.Now()
+	_, _, err := DownloadFileWithRetry(server.URL, filename, "", policy)
+
+	assert.ErrorIs(t, err, ErrTimeout)
+	assert.Contains(t, err.Error(), "did not complete within 50ms")
+	assert.Equal(t, int32(2), attempts.Load(), "a timed out download should be retried")
+	assert.Less(t, time.Since(start), 10*time.Second)
+	assert.NoFileExists(t, filename)
+}
+
+func TestRateLimiterSpacesRequests(t *testing.T) {
+	var mu sync.Mutex
+	var arrivals []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		arrivals = append(arrivals, time.Now())
+		mu.Unlock()
+		fmt.Fprint(w, "{}")
+	}))
+	defer server.Close()
+
+	// Manifest and tarball downloads share the limiter, as in an install
+	const interval = 50 * time.Millisecond
+	policy := DefaultRetryPolicy()
+	policy.Limiters = NewRateLimiters(float64(time.Second / interval))
+	dir := t.TempDir()
+
+	var wg sync.WaitGroup
+	for i := range 6 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			filename := filepath.Join(dir, fmt.Sprintf("file-%d", i))
+			var err error
+			if i%2 == 0 {
+				_, _, err = DownloadFileWithRetryContext(context.Background(), server.URL, filename, "", policy)
+			} else {
+				_, err = DownloadFileResumable(context.Background(), server.URL, filename, nil, policy)
+			}
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	require.Len(t, arrivals, 6)
+	slices.SortFunc(arrivals, func(a, b time.Time) int { return a.Compare(b) })
+	assert.GreaterOrEqual(t, arrivals[5].Sub(arrivals[0]), 5*interval-10*time.Millisecond,
+		"6 requests at 20 per second should span at least 250ms")
+	for i := 1; i < len(arrivals); i++ {
+		assert.GreaterOrEqual(t, arrivals[i].Sub(arrivals[i-1]), interval/2, "request %d came too soon after the previous one", i)
+	}
+}
+
+func TestRateLimiterPausesOn429(t *testing.T) {
+	throttled := make(chan time.Time, 1)
+	var mu sync.Mutex
+	var otherArrival time.Time
+	var throttle atomic.Bool
+	throttle.Store(true)
+	var otherRegistryArrival time.Time
+	otherRegistry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		otherRegistryArrival = time.Now()
+		mu.Unlock()
+		fmt.Fprint(w, "{}")
+	}))
+	defer otherRegistry.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/other" {
+			mu.Lock()
+			otherArrival = time.Now()
+			mu.Unlock()
+			fmt.Fprint(w, "{}")
+			return
+		}
+		if throttle.CompareAndSwap(true, false) {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			throttled <- time.Now()
+			return
+		}
+		fmt.Fprint(w, "{}")
+	}))
+	defer server.Close()
+
+	// MaxDelay caps the 1s Retry-After, and so the pause, to keep the test fast
+	const pause = 200 * time.Millisecond
+	policy := RetryPolicy{Retries: 1, MaxDelay: pause, Limiters: NewRateLimiters(1000)}
+	dir := t.TempDir()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, _, err := DownloadFileWithRetry(server.URL+"/throttled", filepath.Join(dir, "throttled"), "", policy)
+		assert.NoError(t, err)
+	}()
+
+	// A request to another package made while the bucket is paused waits too,
+	// one to another registry does not
+	throttledAt := <-throttled
+	time.Sleep(10 * time.Millisecond)
+	_, _, err := DownloadFileWithRetry(otherRegistry.URL+"/other", filepath.Join(dir, "other-registry"), "", policy)
+	require.NoError(t, err)
+	_, _, err = DownloadFileWithRetry(server.URL+"/other", filepath.Join(dir, "other"), "", policy)
+	require.NoError(t, err)
+	wg.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.GreaterOrEqual(t, otherArrival.Sub(throttledAt), pause-20*time.Millisecond)
+	assert.Less(t, otherRegistryArrival.Sub(throttledAt), pause/2)
+}
+
+func TestNewRateLimiterDisabled(t *testing.T) {
+	assert.Nil(t, NewRateLimiter(0))
+	assert.Nil(t, NewRateLimiter(-1))
+
+	var limiter *RateLimiter
+	limiter.Pause(time.Hour)
+	assert.NoError(t, limiter.Wait(context.Background()))
+
+	assert.Nil(t, NewRateLimiters(0))
+	var limiters *RateLimiters
+	assert.Nil(t, limiters.For("https://registry.npmjs.org/lodash"))
+}
+
+func TestRateLimitersPerRegistry(t *testing.T) {
+	limiters := NewRateLimiters(10)
+	npm := limiters.For("https://registry.npmjs.org/lodash")
+	require.NotNil(t, npm)
+	assert.Same(t, npm, limiters.For("https://registry.npmjs.org/react/-/react-18.2.0.tgz"))
+	assert.NotSame(t, npm, limiters.For("https://npm.pkg.github.com/@myorg%2fui"))
+}
+
 func TestCreateDir(t *testing.T) {
 	testCases := []struct {
 		name        string
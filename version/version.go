@@ -1,43 +1,143 @@
 package version
 
 import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/ernesto27/go-npm/manifest"
 
 	"github.com/Masterminds/semver/v3"
 )
 
+const (
+	ReasonDistTag          = "dist-tag"
+	ReasonLatestStable     = "latest-stable"
+	ReasonExact            = "exact"
+	ReasonRangeMatch       = "range-match"
+	ReasonFallbackToLatest = "fallback-to-latest"
+)
+
+// Resolution describes how a version spec was resolved against a manifest
+type Resolution struct {
+	Package    string   `json:"package"`
+	Spec       string   `json:"spec"`
+	Candidates []string `json:"candidates"`
+	Version    string   `json:"version"`
+	Reason     string   `json:"reason"`
+}
+
+// prereleaseComparatorPattern matches the [major, minor, patch] of versions
+// with a prerelease inside a range, e.g. "1.0.0" in "^1.0.0-beta.1"
+var prereleaseComparatorPattern = regexp.MustCompile(`(\d+)\.(\d+)\.(\d+)-[0-9A-Za-z]`)
+
+// comparatorSet is one "||" alternative of an npm range together with the
+// [major, minor, patch] tuples that carry a prerelease in it
+type comparatorSet struct {
+	constraint  *semver.Constraints
+	prereleases map[[3]uint64]bool
+}
+
 type Info struct {
+	traceMu           sync.Mutex
+	trace             io.Writer
+	includePrerelease bool
 }
 
 func New() *Info {
 	return &Info{}
 }
 
+// SetTraceWriter enables the resolution trace: every GetVersion call writes
+// its Resolution as a JSON line to w. Passing nil disables tracing.
+func (v *Info) SetTraceWriter(w io.Writer) {
+	v.traceMu.Lock()
+	defer v.traceMu.Unlock()
+	v.trace = w
+}
+
+// SetIncludePrerelease allows empty, "*" and "latest" specs to resolve to
+// dist-tags.latest even when it points to a prerelease
+func (v *Info) SetIncludePrerelease(include bool) {
+	v.includePrerelease = include
+}
+
 // GetVersion resolves a version constraint to a specific version string
 // It supports all npm semver ranges: ^, ~, >=, <=, >, <, ||, hyphen ranges, wildcards, and exact versions
 func (v *Info) GetVersion(version string, npmPackage *manifest.NPMPackage) string {
-	// Handle empty version or "latest" keyword
-	if version == "" || version == "latest" || version == "*" {
-		return npmPackage.DistTags["latest"]
+	resolution := v.Resolve(version, npmPackage)
+	v.writeTrace(resolution)
+	return resolution.Version
+}
+
+func (v *Info) writeTrace(resolution Resolution) {
+	v.traceMu.Lock()
+	defer v.traceMu.Unlock()
+
+	if v.trace == nil {
+		return
 	}
 
-	// Check if version is a known dist-tag
-	if version == "next" && npmPackage.DistTags["next"] != "" {
-		return npmPackage.DistTags["next"]
+	data, err := json.Marshal(resolution)
+	if err != nil {
+		return
+	}
+	fmt.Fprintln(v.trace, string(data))
+}
+
+// Resolve resolves a version constraint like GetVersion but returns the full
+// Resolution (candidates considered and the reason for the choice) without tracing
+func (v *Info) Resolve(version string, npmPackage *manifest.NPMPackage) Resolution {
+	resolution := Resolution{
+		Package:    npmPackage.Name,
+		Spec:       version,
+		Candidates: []string{},
+	}
+
+	latest := func() Resolution {
+		resolution.Version = npmPackage.DistTags["latest"]
+		resolution.Reason = ReasonFallbackToLatest
+		return resolution
+	}
+
+	// Handle empty version, "latest" keyword and bare wildcards, whichever
+	// dependency section declared them
+	if isWildcard(version) {
+		resolution.Version = npmPackage.DistTags["latest"]
+		resolution.Reason = ReasonDistTag
+
+		if !v.includePrerelease {
+			if stable, ok := highestStableForPrereleaseLatest(npmPackage); ok {
+				resolution.Version = stable
+				resolution.Reason = ReasonLatestStable
+			}
+		}
+		return resolution
+	}
+
+	// Check if version is a dist-tag the package publishes, e.g. next or canary
+	if tagged := npmPackage.DistTags[version]; tagged != "" && IsDistTag(version) {
+		resolution.Version = tagged
+		resolution.Reason = ReasonDistTag
+		return resolution
 	}
 
 	// Try to parse as semver constraint
-	constraint, err := semver.NewConstraint(version)
+	sets, err := parseRange(version)
 	if err != nil {
 		// If parsing fails, try as exact version match
 		if versionObj, exists := npmPackage.Versions[version]; exists {
-			return versionObj.Version
+			resolution.Version = versionObj.Version
+			resolution.Reason = ReasonExact
+			return resolution
 		}
 		// Fallback to latest for invalid constraints
-		return npmPackage.DistTags["latest"]
+		return latest()
 	}
 
 	// Filter versions that match the constraint
@@ -47,21 +147,86 @@ func (v *Info) GetVersion(version string, npmPackage *manifest.NPMPackage) strin
 		if err != nil {
 			continue // Skip invalid versions in registry
 		}
-		if constraint.Check(semverVersion) {
+		if matchesRange(sets, semverVersion) {
 			matchingVersions = append(matchingVersions, semverVersion)
 		}
 	}
 
 	// If no versions match, fallback to latest
 	if len(matchingVersions) == 0 {
-		return npmPackage.DistTags["latest"]
+		return latest()
 	}
 
 	// Sort versions and return the highest
 	sort.Sort(semver.Collection(matchingVersions))
+	for _, candidate := range matchingVersions {
+		resolution.Candidates = append(resolution.Candidates, candidate.Original())
+	}
 	bestVersion := matchingVersions[len(matchingVersions)-1]
 
-	// Return the original version string (preserves exact format from registry)
+	resolution.Version = registryVersionString(bestVersion, npmPackage)
+	resolution.Reason = ReasonRangeMatch
+	return resolution
+}
+
+// distTagPattern matches the names npm accepts for dist-tags
+var distTagPattern = regexp.MustCompile(`^[A-Za-z][0-9A-Za-z._-]*$`)
+
+// IsDistTag reports whether spec names a dist-tag rather than a version or
+// range: empty (latest), "latest", "next", "canary" and so on. Like npm, a
+// name that parses as a range, such as "x", is not a tag.
+func IsDistTag(spec string) bool {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return true
+	}
+	if !distTagPattern.MatchString(spec) {
+		return false
+	}
+	_, err := parseRange(spec)
+	return err != nil
+}
+
+// isWildcard reports whether spec accepts any version: empty, "latest", or a
+// bare "*", "x" or "X"
+func isWildcard(spec string) bool {
+	switch strings.TrimSpace(spec) {
+	case "", "latest", "*", "x", "X":
+		return true
+	}
+	return false
+}
+
+// highestStableForPrereleaseLatest returns the highest non-prerelease version
+// when dist-tags.latest is a prerelease. It reports false when latest is stable
+// or the package has no stable versions at all.
+func highestStableForPrereleaseLatest(npmPackage *manifest.NPMPackage) (string, bool) {
+	latest, err := semver.NewVersion(npmPackage.DistTags["latest"])
+	if err != nil || latest.Prerelease() == "" {
+		return "", false
+	}
+
+	var best *semver.Version
+	for vStr := range npmPackage.Versions {
+		semverVersion, err := semver.NewVersion(vStr)
+		if err != nil || semverVersion.Prerelease() != "" {
+			continue
+		}
+		if best == nil || semverVersion.GreaterThan(best) {
+			best = semverVersion
+		}
+	}
+
+	if best == nil {
+		return "", false
+	}
+
+	return registryVersionString(best, npmPackage), true
+}
+
+// registryVersionString returns the version string as it appears in the registry
+// (preserves exact format from registry)
+func registryVersionString(bestVersion *semver.Version, npmPackage *manifest.NPMPackage) string {
 	originalVersion := bestVersion.Original()
 
 	// Fallback to String() if Original() doesn't exist in the map (normalization edge case)
@@ -89,26 +254,68 @@ func (v *Info) GetVersion(version string, npmPackage *manifest.NPMPackage) strin
 	return trimmedOriginal
 }
 
-// SatisfiesConstraint checks if a resolved version satisfies a version constraint
-// Returns true if the constraint is satisfied, false otherwise
+// SatisfiesConstraint reports whether resolvedVersion satisfies constraint,
+// parsing the range exactly as GetVersion does, so a version GetVersion could
+// pick for constraint always satisfies it. Wildcards ("", "latest", "*", "x")
+// accept any version but a prerelease, unless prereleases are included.
 func (v *Info) SatisfiesConstraint(resolvedVersion, constraint string) bool {
-	// Handle empty constraint or "latest" - always satisfied
-	if constraint == "" || constraint == "latest" || constraint == "*" {
-		return true
-	}
-
-	// Parse the resolved version
 	semverVersion, err := semver.NewVersion(resolvedVersion)
+
+	if isWildcard(constraint) {
+		return err != nil || semverVersion.Prerelease() == "" || v.includePrerelease
+	}
 	if err != nil {
 		return false
 	}
 
-	// Parse the constraint
-	semverConstraint, err := semver.NewConstraint(constraint)
+	sets, err := parseRange(constraint)
 	if err != nil {
-		// If constraint parsing fails, check exact match
+		// Like GetVersion, a spec that is not a range only matches exactly
 		return resolvedVersion == constraint
 	}
 
-	return semverConstraint.Check(semverVersion)
+	return matchesRange(sets, semverVersion)
+}
+
+// parseRange parses an npm range into its "||" alternatives
+func parseRange(spec string) ([]comparatorSet, error) {
+	var sets []comparatorSet
+
+	for _, part := range strings.Split(spec, "||") {
+		constraint, err := semver.NewConstraint(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+
+		set := comparatorSet{constraint: constraint, prereleases: make(map[[3]uint64]bool)}
+		for _, m := range prereleaseComparatorPattern.FindAllStringSubmatch(part, -1) {
+			var tuple [3]uint64
+			for i := range tuple {
+				tuple[i], _ = strconv.ParseUint(m[i+1], 10, 64)
+			}
+			set.prereleases[tuple] = true
+		}
+		sets = append(sets, set)
+	}
+
+	return sets, nil
+}
+
+// matchesRange reports whether v satisfies one of the alternatives. Like npm,
+// a prerelease only matches an alternative that itself has a prerelease on the
+// same [major, minor, patch]: "^1.0.0-beta.1" accepts 1.0.0-beta.2 but not
+// 1.1.0-alpha.
+func matchesRange(sets []comparatorSet, v *semver.Version) bool {
+	tuple := [3]uint64{v.Major(), v.Minor(), v.Patch()}
+
+	for _, set := range sets {
+		if v.Prerelease() != "" && !set.prereleases[tuple] {
+			continue
+		}
+		if set.constraint.Check(v) {
+			return true
+		}
+	}
+
+	return false
 }
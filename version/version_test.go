@@ -1,9 +1,13 @@
 package version
 
 import (
-	"github.com/ernesto27/go-npm/manifest"
+	"bytes"
+	"encoding/json"
+	"slices"
 	"testing"
 
+	"github.com/ernesto27/go-npm/manifest"
+
 	"github.com/stretchr/testify/assert"
 )
 
@@ -533,3 +537,425 @@ func TestInfo_GetVersion(t *testing.T) {
 		})
 	}
 }
+
+func TestInfo_ResolutionTrace(t *testing.T) {
+	testCases := []struct {
+		name               string
+		version            string
+		versions           []string
+		latest             string
+		expectedVersion    string
+		expectedReason     string
+		expectedCandidates []string
+	}{
+		{
+			name:               "Caret range reports candidates and range match",
+			version:            "^1.2.0",
+			versions:           []string{"1.0.0", "1.2.0", "1.4.1", "2.0.0"},
+			latest:             "2.0.0",
+			expectedVersion:    "1.4.1",
+			expectedReason:     ReasonRangeMatch,
+			expectedCandidates: []string{"1.2.0", "1.4.1"},
+		},
+		{
+			name:               "Latest keyword reports dist-tag",
+			version:            "latest",
+			versions:           []string{"1.0.0", "2.0.0"},
+			latest:             "2.0.0",
+			expectedVersion:    "2.0.0",
+			expectedReason:     ReasonDistTag,
+			expectedCandidates: []string{},
+		},
+		{
+			name:               "No match reports fallback to latest",
+			version:            "^5.0.0",
+			versions:           []string{"1.0.0", "2.0.0"},
+			latest:             "2.0.0",
+			expectedVersion:    "2.0.0",
+			expectedReason:     ReasonFallbackToLatest,
+			expectedCandidates: []string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			vi := New()
+			vi.SetTraceWriter(&buf)
+
+			pkg := createTestPackage(tc.versions, tc.latest)
+			pkg.Name = "test-pkg"
+
+			result := vi.GetVersion(tc.version, pkg)
+			assert.Equal(t, tc.expectedVersion, result)
+
+			var resolution Resolution
+			assert.NoError(t, json.Unmarshal(buf.Bytes(), &resolution))
+			assert.Equal(t, "test-pkg", resolution.Package)
+			assert.Equal(t, tc.version, resolution.Spec)
+			assert.Equal(t, tc.expectedVersion, resolution.Version)
+			assert.Equal(t, tc.expectedReason, resolution.Reason)
+			assert.Equal(t, tc.expectedCandidates, resolution.Candidates)
+		})
+	}
+}
+
+func TestInfo_ResolveDistTags(t *testing.T) {
+	pkg := createTestPackage([]string{"18.2.0", "18.3.1", "19.0.0-rc.1", "19.1.0-canary.3"}, "18.3.1")
+	pkg.DistTags["next"] = "19.0.0-rc.1"
+	pkg.DistTags["canary"] = "19.1.0-canary.3"
+
+	testCases := []struct {
+		name           string
+		spec           string
+		expected       string
+		expectedReason string
+	}{
+		{name: "next tag", spec: "next", expected: "19.0.0-rc.1", expectedReason: ReasonDistTag},
+		{name: "custom tag", spec: "canary", expected: "19.1.0-canary.3", expectedReason: ReasonDistTag},
+		{name: "unknown tag falls back to latest", spec: "beta", expected: "18.3.1", expectedReason: ReasonFallbackToLatest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resolution := New().Resolve(tc.spec, pkg)
+			assert.Equal(t, tc.expected, resolution.Version)
+			assert.Equal(t, tc.expectedReason, resolution.Reason)
+		})
+	}
+}
+
+func TestIsDistTag(t *testing.T) {
+	testCases := []struct {
+		spec     string
+		expected bool
+	}{
+		{spec: "", expected: true},
+		{spec: "latest", expected: true},
+		{spec: "next", expected: true},
+		{spec: "canary", expected: true},
+		{spec: "release-1.x", expected: true},
+		{spec: "x", expected: false},
+		{spec: "*", expected: false},
+		{spec: "1.2.3", expected: false},
+		{spec: "^18.3.0", expected: false},
+		{spec: ">=1.0.0 <2.0.0", expected: false},
+		{spec: "npm:react@next", expected: false},
+		{spec: "github:user/repo", expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.spec, func(t *testing.T) {
+			assert.Equal(t, tc.expected, IsDistTag(tc.spec))
+		})
+	}
+}
+
+func TestInfo_GetVersionPrereleaseLatest(t *testing.T) {
+	testCases := []struct {
+		name              string
+		version           string
+		versions          []string
+		latest            string
+		includePrerelease bool
+		expected          string
+	}{
+		{
+			name:     "Empty spec prefers highest stable over prerelease latest",
+			version:  "",
+			versions: []string{"1.0.0", "1.2.0", "2.0.0-beta.1"},
+			latest:   "2.0.0-beta.1",
+			expected: "1.2.0",
+		},
+		{
+			name:     "Latest keyword prefers highest stable over prerelease latest",
+			version:  "latest",
+			versions: []string{"1.0.0", "1.2.0", "2.0.0-beta.1"},
+			latest:   "2.0.0-beta.1",
+			expected: "1.2.0",
+		},
+		{
+			name:     "Asterisk prefers highest stable over prerelease latest",
+			version:  "*",
+			versions: []string{"1.0.0", "1.2.0", "2.0.0-rc.0"},
+			latest:   "2.0.0-rc.0",
+			expected: "1.2.0",
+		},
+		{
+			name:     "X wildcard prefers highest stable over prerelease latest",
+			version:  "x",
+			versions: []string{"1.0.0", "1.2.0", "2.0.0-rc.0"},
+			latest:   "2.0.0-rc.0",
+			expected: "1.2.0",
+		},
+		{
+			name:     "Padded asterisk prefers highest stable over prerelease latest",
+			version:  " * ",
+			versions: []string{"1.0.0", "1.2.0", "2.0.0-rc.0"},
+			latest:   "2.0.0-rc.0",
+			expected: "1.2.0",
+		},
+		{
+			name:              "Include prerelease keeps prerelease latest",
+			version:           "latest",
+			versions:          []string{"1.0.0", "1.2.0", "2.0.0-beta.1"},
+			latest:            "2.0.0-beta.1",
+			includePrerelease: true,
+			expected:          "2.0.0-beta.1",
+		},
+		{
+			name:     "Only prerelease versions falls back to latest",
+			version:  "",
+			versions: []string{"1.0.0-alpha.1", "1.0.0-alpha.2"},
+			latest:   "1.0.0-alpha.2",
+			expected: "1.0.0-alpha.2",
+		},
+		{
+			name:     "Stable latest is unchanged",
+			version:  "",
+			versions: []string{"1.0.0", "2.0.0", "3.0.0-beta.1"},
+			latest:   "2.0.0",
+			expected: "2.0.0",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			vi := New()
+			vi.SetIncludePrerelease(tc.includePrerelease)
+			pkg := createTestPackage(tc.versions, tc.latest)
+			result := vi.GetVersion(tc.version, pkg)
+			assert.Equal(t, tc.expected, result)
+		})
+	}
+}
+
+func TestInfo_GetVersionPrereleaseRanges(t *testing.T) {
+	testCases := []struct {
+		name     string
+		version  string
+		versions []string
+		latest   string
+		expected string
+	}{
+		{
+			name:     "Exact prerelease",
+			version:  "1.0.0-rc.2",
+			versions: []string{"1.0.0-rc.1", "1.0.0-rc.2", "1.0.0", "1.1.0"},
+			latest:   "1.1.0",
+			expected: "1.0.0-rc.2",
+		},
+		{
+			name:     "Exact prerelease with equals",
+			version:  "=1.0.0-rc.2",
+			versions: []string{"1.0.0-rc.1", "1.0.0-rc.2", "1.0.0"},
+			latest:   "1.0.0",
+			expected: "1.0.0-rc.2",
+		},
+		{
+			name:     "Caret prerelease prefers the release",
+			version:  "^1.0.0-beta.1",
+			versions: []string{"1.0.0-beta.1", "1.0.0-beta.2", "1.0.0", "1.1.0-alpha.1", "2.0.0"},
+			latest:   "2.0.0",
+			expected: "1.0.0",
+		},
+		{
+			name:     "Caret prerelease stays on its own tuple",
+			version:  "^1.0.0-beta.1",
+			versions: []string{"1.0.0-beta.1", "1.0.0-beta.2", "1.1.0-alpha.1", "2.0.0"},
+			latest:   "2.0.0",
+			expected: "1.0.0-beta.2",
+		},
+		{
+			name:     "Range with -0 includes prereleases of the same tuple only",
+			version:  ">=1.0.0-0 <2.0.0",
+			versions: []string{"0.9.0", "1.0.0-alpha", "1.5.0-beta", "2.0.0-rc.1", "2.0.0"},
+			latest:   "2.0.0",
+			expected: "1.0.0-alpha",
+		},
+		{
+			name:     "Range without prerelease ignores prereleases",
+			version:  "^1.0.0",
+			versions: []string{"1.0.0", "1.1.0", "1.2.0-beta.1", "2.0.0"},
+			latest:   "2.0.0",
+			expected: "1.1.0",
+		},
+		{
+			name:     "Prerelease in one alternative does not leak into another",
+			version:  "1.0.0-beta.1 || ^2.0.0",
+			versions: []string{"1.0.0-beta.1", "2.0.0", "2.1.0-rc.1"},
+			latest:   "2.0.0",
+			expected: "2.0.0",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			vi := New()
+			pkg := createTestPackage(tc.versions, tc.latest)
+			result := vi.GetVersion(tc.version, pkg)
+			assert.Equal(t, tc.expected, result)
+		})
+	}
+}
+
+func TestInfo_ResolvePrereleaseOrdering(t *testing.T) {
+	testCases := []struct {
+		name     string
+		version  string
+		versions []string
+		expected []string
+	}{
+		{
+			name:     "Prerelease sorts before its release",
+			version:  ">=1.0.0-alpha <=1.0.0",
+			versions: []string{"1.0.0", "1.0.0-alpha"},
+			expected: []string{"1.0.0-alpha", "1.0.0"},
+		},
+		{
+			name:     "alpha < beta < rc",
+			version:  ">=1.0.0-0",
+			versions: []string{"1.0.0-rc.1", "1.0.0-alpha", "1.0.0-beta"},
+			expected: []string{"1.0.0-alpha", "1.0.0-beta", "1.0.0-rc.1"},
+		},
+		{
+			name:     "Numeric identifiers compare numerically and sort before alphanumeric",
+			version:  ">=1.0.0-0",
+			versions: []string{"1.0.0-beta.11", "1.0.0-alpha.beta", "1.0.0-beta.2", "1.0.0-alpha.1", "1.0.0-alpha"},
+			expected: []string{"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta.2", "1.0.0-beta.11"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			vi := New()
+			pkg := createTestPackage(tc.versions, tc.versions[0])
+			resolution := vi.Resolve(tc.version, pkg)
+			assert.Equal(t, ReasonRangeMatch, resolution.Reason)
+			assert.Equal(t, tc.expected, resolution.Candidates)
+		})
+	}
+}
+
+func TestInfo_SatisfiesConstraintPrerelease(t *testing.T) {
+	testCases := []struct {
+		name       string
+		version    string
+		constraint string
+		expected   bool
+	}{
+		{name: "Exact prerelease", version: "1.0.0-rc.2", constraint: "1.0.0-rc.2", expected: true},
+		{name: "Release does not satisfy exact prerelease", version: "1.0.0", constraint: "1.0.0-rc.2", expected: false},
+		{name: "Prerelease on same tuple", version: "1.0.0-beta.2", constraint: "^1.0.0-beta.1", expected: true},
+		{name: "Prerelease on another tuple", version: "1.1.0-alpha", constraint: "^1.0.0-beta.1", expected: false},
+		{name: "Prerelease without prerelease range", version: "1.1.0-alpha", constraint: "^1.0.0", expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, New().SatisfiesConstraint(tc.version, tc.constraint))
+		})
+	}
+}
+
+func TestInfo_SatisfiesConstraint(t *testing.T) {
+	testCases := []struct {
+		name       string
+		version    string
+		constraint string
+		expected   bool
+	}{
+		// Wildcards
+		{name: "Empty constraint", version: "1.2.3", constraint: "", expected: true},
+		{name: "Latest keyword", version: "1.2.3", constraint: "latest", expected: true},
+		{name: "Asterisk", version: "0.0.1", constraint: "*", expected: true},
+		{name: "Bare x", version: "3.0.0", constraint: "x", expected: true},
+		{name: "Bare X", version: "3.0.0", constraint: "X", expected: true},
+		{name: "Wildcard rejects prerelease", version: "2.0.0-beta.1", constraint: "*", expected: false},
+
+		// Exact versions
+		{name: "Exact match", version: "1.2.3", constraint: "1.2.3", expected: true},
+		{name: "Exact mismatch", version: "1.2.4", constraint: "1.2.3", expected: false},
+		{name: "Exact with equals", version: "1.2.3", constraint: "=1.2.3", expected: true},
+
+		// Caret ranges
+		{name: "Caret minor update", version: "1.9.9", constraint: "^1.2.3", expected: true},
+		{name: "Caret below base", version: "1.2.2", constraint: "^1.2.3", expected: false},
+		{name: "Caret next major", version: "2.0.0", constraint: "^1.2.3", expected: false},
+		{name: "Caret zero major allows patch", version: "0.2.5", constraint: "^0.2.3", expected: true},
+		{name: "Caret zero major rejects minor", version: "0.3.0", constraint: "^0.2.3", expected: false},
+		{name: "Caret zero minor is exact", version: "0.0.4", constraint: "^0.0.3", expected: false},
+
+		// Tilde ranges
+		{name: "Tilde patch update", version: "1.2.9", constraint: "~1.2.3", expected: true},
+		{name: "Tilde rejects minor", version: "1.3.0", constraint: "~1.2.3", expected: false},
+		{name: "Tilde major only", version: "1.9.0", constraint: "~1", expected: true},
+
+		// Comparators
+		{name: "Greater or equal", version: "2.1.2", constraint: ">= 2.1.2 < 3.0.0", expected: true},
+		{name: "Upper bound is exclusive", version: "3.0.0", constraint: ">= 2.1.2 < 3.0.0", expected: false},
+		{name: "Greater than", version: "1.0.1", constraint: ">1.0.0", expected: true},
+		{name: "Less or equal", version: "1.0.0", constraint: "<=1.0.0", expected: true},
+
+		// Hyphen ranges
+		{name: "Hyphen inside", version: "1.5.0", constraint: "1.0.0 - 2.0.0", expected: true},
+		{name: "Hyphen upper bound is inclusive", version: "2.0.0", constraint: "1.0.0 - 2.0.0", expected: true},
+		{name: "Hyphen above", version: "2.0.1", constraint: "1.0.0 - 2.0.0", expected: false},
+		{name: "Hyphen with partial upper bound", version: "2.9.9", constraint: "1.0.0 - 2", expected: true},
+
+		// OR ranges
+		{name: "OR first alternative", version: "1.4.0", constraint: "^1.0.0 || ^3.0.0", expected: true},
+		{name: "OR second alternative", version: "3.1.0", constraint: "^1.0.0 || ^3.0.0", expected: true},
+		{name: "OR neither alternative", version: "2.0.0", constraint: "^1.0.0 || ^3.0.0", expected: false},
+		{name: "OR of exact versions", version: "1.0.2", constraint: "1.0.1 || 1.0.2", expected: true},
+
+		// X-ranges
+		{name: "Major x-range", version: "1.7.3", constraint: "1.x", expected: true},
+		{name: "Major x-range rejects next major", version: "2.0.0", constraint: "1.x", expected: false},
+		{name: "Minor x-range", version: "1.2.9", constraint: "1.2.x", expected: true},
+		{name: "Minor x-range rejects next minor", version: "1.3.0", constraint: "1.2.x", expected: false},
+		{name: "Partial version", version: "1.2.7", constraint: "1.2", expected: true},
+
+		// Invalid input
+		{name: "Invalid version", version: "not-a-version", constraint: "^1.0.0", expected: false},
+		{name: "Dist-tag only matches itself", version: "1.0.0", constraint: "next", expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, New().SatisfiesConstraint(tc.version, tc.constraint))
+		})
+	}
+}
+
+func TestInfo_SatisfiesConstraintIncludePrerelease(t *testing.T) {
+	vi := New()
+	vi.SetIncludePrerelease(true)
+	assert.True(t, vi.SatisfiesConstraint("2.0.0-beta.1", "*"))
+	assert.False(t, vi.SatisfiesConstraint("2.0.0-beta.1", "^1.0.0"))
+}
+
+// TestInfo_SatisfiesConstraintAgreesWithResolve checks that the candidates
+// Resolve considers for a range are exactly the versions satisfying it
+func TestInfo_SatisfiesConstraintAgreesWithResolve(t *testing.T) {
+	versions := []string{
+		"0.1.0", "0.2.3", "0.2.5", "0.3.0", "1.0.0", "1.0.1-beta.1", "1.0.1", "1.2.3",
+		"1.2.5", "1.3.0", "1.9.9", "2.0.0-rc.1", "2.0.0", "2.1.0", "3.0.0", "3.1.4",
+	}
+	constraints := []string{
+		"^1.2.3", "^0.2.3", "~1.2.3", "~1", ">= 1.0.0 < 2.0.0", ">1.9.9", "<=0.3.0",
+		"1.0.0 - 2.0.0", "1.x", "1.2.x", "2", "^1.0.0 || ^3.0.0", "1.0.1-beta.1",
+		"^1.0.1-beta.1", "^2.0.0-rc.1", ">=2.0.0-rc.1", "^5.0.0",
+	}
+	pkg := createTestPackage(versions, "3.1.4")
+
+	for _, constraint := range constraints {
+		t.Run(constraint, func(t *testing.T) {
+			vi := New()
+			resolution := vi.Resolve(constraint, pkg)
+			for _, v := range versions {
+				assert.Equal(t, slices.Contains(resolution.Candidates, v), vi.SatisfiesConstraint(v, constraint), "version %s", v)
+			}
+		})
+	}
+}
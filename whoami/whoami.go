@@ -0,0 +1,64 @@
+package whoami
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Client asks a registry which user an auth token belongs to
+type Client struct {
+	registryURL string
+	token       string
+	httpClient  *http.Client
+}
+
+// New creates a Client for registryURL authenticating with token
+func New(registryURL, token string, httpClient *http.Client) *Client {
+	if !strings.HasSuffix(registryURL, "/") {
+		registryURL += "/"
+	}
+	return &Client{
+		registryURL: registryURL,
+		token:       token,
+		httpClient:  httpClient,
+	}
+}
+
+// Username calls the registry's /-/whoami endpoint and returns the username
+// of the token
+func (c *Client) Username(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.registryURL+"-/whoami", nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to reach %s: %w", c.registryURL, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return "", fmt.Errorf("%s rejected the auth token (HTTP %d)", c.registryURL, resp.StatusCode)
+	case resp.StatusCode != http.StatusOK:
+		return "", fmt.Errorf("whoami request to %s failed: HTTP %d", c.registryURL, resp.StatusCode)
+	}
+
+	var payload struct {
+		Username string `json:"username"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return "", fmt.Errorf("failed to parse whoami response: %w", err)
+	}
+	if payload.Username == "" {
+		return "", fmt.Errorf("whoami response from %s has no username", c.registryURL)
+	}
+
+	return payload.Username, nil
+}
@@ -0,0 +1,69 @@
+package whoami
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsername(t *testing.T) {
+	testCases := []struct {
+		name          string
+		status        int
+		body          string
+		expected      string
+		errorContains string
+	}{
+		{
+			name:     "returns the username",
+			status:   http.StatusOK,
+			body:     `{"username": "octocat"}`,
+			expected: "octocat",
+		},
+		{
+			name:          "rejected token",
+			status:        http.StatusUnauthorized,
+			body:          `{"error": "unauthorized"}`,
+			errorContains: "rejected the auth token",
+		},
+		{
+			name:          "server error",
+			status:        http.StatusInternalServerError,
+			errorContains: "HTTP 500",
+		},
+		{
+			name:          "response without username",
+			status:        http.StatusOK,
+			body:          `{}`,
+			errorContains: "no username",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var authHeader, path string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				authHeader = r.Header.Get("Authorization")
+				path = r.URL.Path
+				w.WriteHeader(tc.status)
+				w.Write([]byte(tc.body))
+			}))
+			defer server.Close()
+
+			username, err := New(server.URL, "secret", server.Client()).Username(context.Background())
+			assert.Equal(t, "Bearer secret", authHeader)
+			assert.Equal(t, "/-/whoami", path)
+
+			if tc.errorContains != "" {
+				assert.ErrorContains(t, err, tc.errorContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, username)
+		})
+	}
+}
@@ -3,12 +3,18 @@ package workspace
 import (
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/ernesto27/go-npm/packagejson"
 )
 
+// Protocol is the version prefix that requires a sibling workspace package,
+// e.g. "workspace:^"
+const Protocol = "workspace:"
+
 // Workspace represents a single workspace package in a monorepo
 type Workspace struct {
 	Name        string
@@ -33,6 +39,43 @@ func NewWorkspaceRegistry(rootDir string, parser *packagejson.PackageJSONParser)
 	}
 }
 
+// FindRegistry returns the workspaces of the monorepo dir is in: those of
+// the nearest directory, dir included, whose package.json declares
+// workspaces that dir is the root of or one of. Outside a monorepo the
+// registry has no packages.
+func FindRegistry(dir string, parser *packagejson.PackageJSONParser) (*WorkspaceRegistry, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path for %s: %w", dir, err)
+	}
+
+	for current := absDir; ; current = filepath.Dir(current) {
+		pkg, err := parser.Parse(filepath.Join(current, "package.json"))
+		if err == nil && len(pkg.GetWorkspaces()) > 0 {
+			registry := NewWorkspaceRegistry(current, parser)
+			if err := registry.Discover(pkg); err != nil {
+				return nil, err
+			}
+			if current == absDir || registry.containsPath(absDir) {
+				return registry, nil
+			}
+		}
+		if filepath.Dir(current) == current {
+			return NewWorkspaceRegistry(absDir, parser), nil
+		}
+	}
+}
+
+// containsPath reports whether dir is the directory of a workspace package
+func (wr *WorkspaceRegistry) containsPath(dir string) bool {
+	for _, ws := range wr.Packages {
+		if ws.Path == dir {
+			return true
+		}
+	}
+	return false
+}
+
 // Discover discovers all workspace packages based on the root package.json
 func (wr *WorkspaceRegistry) Discover(rootPackageJSON *packagejson.PackageJSON) error {
 	patterns := rootPackageJSON.GetWorkspaces()
@@ -141,6 +184,229 @@ func (wr *WorkspaceRegistry) GetWorkspacePackage(name string) (*Workspace, bool)
 	return pkg, exists
 }
 
+// ResolveSpec replaces the workspace: protocol in spec, the version name is
+// required with, by the version of the workspace package name:
+// workspace:* → 1.5.0, workspace:^ → ^1.5.0, workspace:~ → ~1.5.0 and
+// workspace:<range> → <range>. Other specs are returned unchanged.
+func (wr *WorkspaceRegistry) ResolveSpec(name, spec string) (string, error) {
+	rng, ok := strings.CutPrefix(spec, Protocol)
+	if !ok {
+		return spec, nil
+	}
+
+	pkg, exists := wr.Packages[name]
+	if !exists {
+		return "", fmt.Errorf("%s@%s: %s is not a workspace package", name, spec, name)
+	}
+
+	switch rng {
+	case "", "*":
+		return pkg.Version, nil
+	case "^", "~":
+		return rng + pkg.Version, nil
+	default:
+		return rng, nil
+	}
+}
+
+// ResolveDependencies returns a copy of deps with every workspace: protocol
+// replaced by ResolveSpec, as recorded in the lock and published
+func (wr *WorkspaceRegistry) ResolveDependencies(deps map[string]string) (map[string]string, error) {
+	names := make([]string, 0, len(deps))
+	for name := range deps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	resolved := make(map[string]string, len(deps))
+	for _, name := range names {
+		spec, err := wr.ResolveSpec(name, deps[name])
+		if err != nil {
+			return nil, err
+		}
+		resolved[name] = spec
+	}
+
+	return resolved, nil
+}
+
+// ResolveManifest returns content, the package.json of a package being packed
+// or published, with every workspace: protocol resolved by ResolveSpec, so
+// that consumers of the tarball get registry ranges
+func (wr *WorkspaceRegistry) ResolveManifest(content []byte) ([]byte, error) {
+	return packagejson.ResolveSpecsInJSON(content, wr.ResolveSpec)
+}
+
+// Sorted returns every workspace package, sorted by name
+func (wr *WorkspaceRegistry) Sorted() []*Workspace {
+	packages := make([]*Workspace, 0, len(wr.Packages))
+	for _, pkg := range wr.Packages {
+		packages = append(packages, pkg)
+	}
+	sort.Slice(packages, func(i, j int) bool {
+		return packages[i].Name < packages[j].Name
+	})
+	return packages
+}
+
+// Select returns, sorted by name, the workspace packages matching patterns.
+// A pattern is a package name, a glob over names such as "@org/*", or the
+// package's directory relative to the root. A pattern matching no workspace
+// is an error.
+func (wr *WorkspaceRegistry) Select(patterns []string) ([]*Workspace, error) {
+	selected := make(map[string]bool)
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid workspace pattern %q: %w", pattern, err)
+		}
+
+		matched := false
+		for name, pkg := range wr.Packages {
+			if ok, _ := path.Match(pattern, name); ok || wr.relativePath(pkg) == filepath.Clean(pattern) {
+				selected[name] = true
+				matched = true
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("no workspace matches %q", pattern)
+		}
+	}
+
+	var packages []*Workspace
+	for _, pkg := range wr.Sorted() {
+		if selected[pkg.Name] {
+			packages = append(packages, pkg)
+		}
+	}
+	return packages, nil
+}
+
+// Publishable returns the packages that can be published, skipping those
+// marked "private": true, so bulk pack and publish operations leave them out
+func Publishable(packages []*Workspace) []*Workspace {
+	var publishable []*Workspace
+	for _, pkg := range packages {
+		if pkg.PackageJSON != nil && pkg.PackageJSON.CheckPublishable() != nil {
+			continue
+		}
+		publishable = append(publishable, pkg)
+	}
+	return publishable
+}
+
+// relativePath returns the directory of pkg relative to the root
+func (wr *WorkspaceRegistry) relativePath(pkg *Workspace) string {
+	rootDir, err := filepath.Abs(wr.RootDir)
+	if err != nil {
+		return pkg.Path
+	}
+	rel, err := filepath.Rel(rootDir, pkg.Path)
+	if err != nil {
+		return pkg.Path
+	}
+	return rel
+}
+
+// TopologicalSort orders packages so that every workspace comes after the
+// workspaces it depends on. Packages are grouped into layers: a layer only
+// depends on earlier layers, so the packages within one can be processed in
+// parallel. Only dependencies between the given packages are considered, and
+// each layer is sorted by name. A dependency cycle is an error naming it.
+func (wr *WorkspaceRegistry) TopologicalSort(packages []*Workspace) ([][]*Workspace, error) {
+	byName := make(map[string]*Workspace, len(packages))
+	for _, pkg := range packages {
+		byName[pkg.Name] = pkg
+	}
+
+	// edges maps each package to the selected workspaces it depends on
+	edges := make(map[string][]string, len(packages))
+	pending := make(map[string]int, len(packages))
+	for name, pkg := range byName {
+		pending[name] = 0
+		if pkg.PackageJSON == nil {
+			continue
+		}
+		for dep := range pkg.PackageJSON.GetDependencies() {
+			if _, ok := byName[dep]; ok && dep != name {
+				edges[name] = append(edges[name], dep)
+				pending[name]++
+			}
+		}
+		sort.Strings(edges[name])
+	}
+
+	dependents := make(map[string][]string)
+	for name, deps := range edges {
+		for _, dep := range deps {
+			dependents[dep] = append(dependents[dep], name)
+		}
+	}
+
+	var layers [][]*Workspace
+	var ready []string
+	for name, count := range pending {
+		if count == 0 {
+			ready = append(ready, name)
+		}
+	}
+
+	sorted := 0
+	for len(ready) > 0 {
+		sort.Strings(ready)
+		layer := make([]*Workspace, 0, len(ready))
+		var next []string
+		for _, name := range ready {
+			layer = append(layer, byName[name])
+			for _, dependent := range dependents[name] {
+				pending[dependent]--
+				if pending[dependent] == 0 {
+					next = append(next, dependent)
+				}
+			}
+		}
+		layers = append(layers, layer)
+		sorted += len(layer)
+		ready = next
+	}
+
+	if sorted < len(byName) {
+		return nil, fmt.Errorf("workspace dependency cycle: %s", strings.Join(findCycle(edges, pending), " -> "))
+	}
+
+	return layers, nil
+}
+
+// findCycle follows the dependencies of the packages left unsorted (pending
+// count above zero) from the first one by name until a package repeats, and
+// returns the cycle it closed, starting and ending with the same package
+func findCycle(edges map[string][]string, pending map[string]int) []string {
+	var remaining []string
+	for name, count := range pending {
+		if count > 0 {
+			remaining = append(remaining, name)
+		}
+	}
+	sort.Strings(remaining)
+
+	var walk []string
+	seen := make(map[string]int)
+	for name := remaining[0]; ; {
+		if i, ok := seen[name]; ok {
+			return append(walk[i:], name)
+		}
+		seen[name] = len(walk)
+		walk = append(walk, name)
+
+		// Every unsorted package depends on at least one other unsorted one
+		for _, dep := range edges[name] {
+			if pending[dep] > 0 {
+				name = dep
+				break
+			}
+		}
+	}
+}
+
 // Validate performs validation checks on the workspace registry
 func (wr *WorkspaceRegistry) Validate() []error {
 	var errors []error
@@ -166,12 +432,18 @@ func (wr *WorkspaceRegistry) Validate() []error {
 
 // CreateSymlink creates a symlink for a workspace package in node_modules
 func (wr *WorkspaceRegistry) CreateSymlink(nodeModulesDir, packageName, workspacePath string) error {
+	return SymlinkPackage(nodeModulesDir, packageName, workspacePath)
+}
+
+// SymlinkPackage links nodeModulesDir/packageName to the package directory at
+// targetPath using a relative symlink, replacing a stale link if needed
+func SymlinkPackage(nodeModulesDir, packageName, targetPath string) error {
 	absNodeModules, err := filepath.Abs(nodeModulesDir)
 	if err != nil {
 		return fmt.Errorf("failed to get absolute path for node_modules: %w", err)
 	}
 
-	absWorkspace, err := filepath.Abs(workspacePath)
+	absWorkspace, err := filepath.Abs(targetPath)
 	if err != nil {
 		return fmt.Errorf("failed to get absolute path for workspace: %w", err)
 	}
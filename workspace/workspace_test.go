@@ -421,3 +421,216 @@ func createWorkspacePackage(t *testing.T, rootDir, relativePath, name, version s
 	pkgJSON, _ := json.MarshalIndent(pkg, "", "  ")
 	require.NoError(t, os.WriteFile(filepath.Join(pkgPath, "package.json"), pkgJSON, 0644))
 }
+
+func TestResolveSpec(t *testing.T) {
+	wr := &WorkspaceRegistry{Packages: map[string]*Workspace{
+		"@myorg/ui": {Name: "@myorg/ui", Version: "1.5.0"},
+	}}
+
+	testCases := []struct {
+		name        string
+		pkgName     string
+		spec        string
+		expected    string
+		expectError bool
+	}{
+		{name: "workspace:* is the exact version", pkgName: "@myorg/ui", spec: "workspace:*", expected: "1.5.0"},
+		{name: "bare workspace: is the exact version", pkgName: "@myorg/ui", spec: "workspace:", expected: "1.5.0"},
+		{name: "workspace:^ is a caret range", pkgName: "@myorg/ui", spec: "workspace:^", expected: "^1.5.0"},
+		{name: "workspace:~ is a tilde range", pkgName: "@myorg/ui", spec: "workspace:~", expected: "~1.5.0"},
+		{name: "workspace:<range> is the range", pkgName: "@myorg/ui", spec: "workspace:^1.0.0", expected: "^1.0.0"},
+		{name: "other specs are unchanged", pkgName: "lodash", spec: "^4.17.21", expected: "^4.17.21"},
+		{name: "unknown workspace", pkgName: "@myorg/missing", spec: "workspace:^", expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resolved, err := wr.ResolveSpec(tc.pkgName, tc.spec)
+			if tc.expectError {
+				assert.ErrorContains(t, err, "is not a workspace package")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, resolved)
+		})
+	}
+
+	t.Run("ResolveDependencies replaces every protocol", func(t *testing.T) {
+		deps := map[string]string{"@myorg/ui": "workspace:^", "lodash": "^4.17.21"}
+		resolved, err := wr.ResolveDependencies(deps)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"@myorg/ui": "^1.5.0", "lodash": "^4.17.21"}, resolved)
+		assert.Equal(t, "workspace:^", deps["@myorg/ui"], "input should not be modified")
+	})
+
+	t.Run("ResolveManifest rewrites only the protocols", func(t *testing.T) {
+		content := "{\n  \"name\": \"app\",\n  \"dependencies\": {\n    \"@myorg/ui\": \"workspace:*\",\n    \"lodash\": \"^4.17.21\"\n  },\n  \"peerDependencies\": {\n    \"@myorg/ui\": \"workspace:~\"\n  }\n}\n"
+		resolved, err := wr.ResolveManifest([]byte(content))
+		require.NoError(t, err)
+		assert.Equal(t, "{\n  \"name\": \"app\",\n  \"dependencies\": {\n    \"@myorg/ui\": \"1.5.0\",\n    \"lodash\": \"^4.17.21\"\n  },\n  \"peerDependencies\": {\n    \"@myorg/ui\": \"~1.5.0\"\n  }\n}\n", string(resolved))
+
+		_, err = wr.ResolveManifest([]byte(`{"devDependencies": {"@myorg/missing": "workspace:^"}}`))
+		assert.ErrorContains(t, err, "is not a workspace package")
+	})
+}
+
+func TestFindRegistry(t *testing.T) {
+	rootDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(rootDir, "package.json"), []byte(`{"name": "root", "workspaces": ["packages/*"]}`), 0644))
+	createWorkspacePackage(t, rootDir, "packages/ui", "@myorg/ui", "1.5.0")
+	createWorkspacePackage(t, rootDir, "tools/lint", "lint", "1.0.0")
+
+	testCases := []struct {
+		name     string
+		dir      string
+		expected []string
+	}{
+		{name: "from the root", dir: rootDir, expected: []string{"@myorg/ui"}},
+		{name: "from a workspace package", dir: filepath.Join(rootDir, "packages", "ui"), expected: []string{"@myorg/ui"}},
+		{name: "from a package outside the workspaces", dir: filepath.Join(rootDir, "tools", "lint"), expected: []string{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			registry, err := FindRegistry(tc.dir, packagejson.NewPackageJSONParser(nil, nil))
+			require.NoError(t, err)
+			names := []string{}
+			for _, ws := range registry.Sorted() {
+				names = append(names, ws.Name)
+			}
+			assert.Equal(t, tc.expected, names)
+		})
+	}
+}
+
+func TestSelect(t *testing.T) {
+	wr := &WorkspaceRegistry{RootDir: "/repo", Packages: map[string]*Workspace{
+		"@myorg/ui":    {Name: "@myorg/ui", Path: "/repo/packages/ui"},
+		"@myorg/utils": {Name: "@myorg/utils", Path: "/repo/packages/utils"},
+		"docs":         {Name: "docs", Path: "/repo/apps/docs"},
+	}}
+
+	testCases := []struct {
+		name          string
+		patterns      []string
+		expected      []string
+		errorContains string
+	}{
+		{name: "exact name", patterns: []string{"docs"}, expected: []string{"docs"}},
+		{name: "glob over a scope", patterns: []string{"@myorg/*"}, expected: []string{"@myorg/ui", "@myorg/utils"}},
+		{name: "directory relative to the root", patterns: []string{"packages/ui/"}, expected: []string{"@myorg/ui"}},
+		{name: "overlapping patterns are deduplicated", patterns: []string{"docs", "@myorg/u*", "@myorg/ui"}, expected: []string{"@myorg/ui", "@myorg/utils", "docs"}},
+		{name: "pattern matching nothing", patterns: []string{"docs", "@other/*"}, errorContains: `no workspace matches "@other/*"`},
+		{name: "malformed pattern", patterns: []string{"[docs"}, errorContains: "invalid workspace pattern"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			selected, err := wr.Select(tc.patterns)
+			if tc.errorContains != "" {
+				assert.ErrorContains(t, err, tc.errorContains)
+				return
+			}
+			require.NoError(t, err)
+
+			var names []string
+			for _, ws := range selected {
+				names = append(names, ws.Name)
+			}
+			assert.Equal(t, tc.expected, names)
+		})
+	}
+}
+
+func TestPublishable(t *testing.T) {
+	packages := []*Workspace{
+		{Name: "@myorg/ui", PackageJSON: &packagejson.PackageJSON{Name: "@myorg/ui"}},
+		{Name: "@myorg/internal", PackageJSON: &packagejson.PackageJSON{Name: "@myorg/internal", Private: true}},
+		{Name: "docs", PackageJSON: &packagejson.PackageJSON{Name: "docs", Private: true}},
+		{Name: "@myorg/utils", PackageJSON: &packagejson.PackageJSON{Name: "@myorg/utils"}},
+	}
+
+	var names []string
+	for _, ws := range Publishable(packages) {
+		names = append(names, ws.Name)
+	}
+	assert.Equal(t, []string{"@myorg/ui", "@myorg/utils"}, names)
+}
+
+func TestTopologicalSort(t *testing.T) {
+	newWorkspace := func(name string, deps ...string) *Workspace {
+		dependencies := make(map[string]any)
+		for _, dep := range deps {
+			dependencies[dep] = "workspace:*"
+		}
+		return &Workspace{Name: name, PackageJSON: &packagejson.PackageJSON{Dependencies: dependencies}}
+	}
+
+	testCases := []struct {
+		name          string
+		packages      []*Workspace
+		expected      [][]string
+		errorContains string
+	}{
+		{
+			name: "dependents come after their dependencies",
+			packages: []*Workspace{
+				newWorkspace("app", "ui", "utils", "lodash"),
+				newWorkspace("ui", "utils"),
+				newWorkspace("utils"),
+				newWorkspace("docs"),
+			},
+			expected: [][]string{{"docs", "utils"}, {"ui"}, {"app"}},
+		},
+		{
+			name: "dependencies outside the packages are ignored",
+			packages: []*Workspace{
+				newWorkspace("app", "ui"),
+				newWorkspace("api", "db"),
+			},
+			expected: [][]string{{"api", "app"}},
+		},
+		{
+			name: "cycle is named",
+			packages: []*Workspace{
+				newWorkspace("a", "b"),
+				newWorkspace("b", "c"),
+				newWorkspace("c", "a"),
+				newWorkspace("d", "a"),
+				newWorkspace("e"),
+			},
+			errorContains: "workspace dependency cycle: a -> b -> c -> a",
+		},
+		{
+			name:     "self-dependency is not a cycle",
+			packages: []*Workspace{newWorkspace("a", "a")},
+			expected: [][]string{{"a"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			wr := &WorkspaceRegistry{Packages: make(map[string]*Workspace)}
+			for _, pkg := range tc.packages {
+				wr.Packages[pkg.Name] = pkg
+			}
+
+			layers, err := wr.TopologicalSort(tc.packages)
+			if tc.errorContains != "" {
+				assert.ErrorContains(t, err, tc.errorContains)
+				return
+			}
+			require.NoError(t, err)
+
+			var names [][]string
+			for _, layer := range layers {
+				var layerNames []string
+				for _, ws := range layer {
+					layerNames = append(layerNames, ws.Name)
+				}
+				names = append(names, layerNames)
+			}
+			assert.Equal(t, tc.expected, names)
+		})
+	}
+}
@@ -284,13 +284,32 @@ func IsYarnV1(content []byte) bool {
 	return false
 }
 
-// saveEntry saves the entry to the YarnLock, deduplicating by name@version
+// saveEntry saves the entry to the YarnLock, deduplicating by name@version.
+// When several selectors resolve to the same name@version, the entry with the
+// most metadata wins: one with integrity beats one without, then one with a
+// resolved URL beats one without. On a tie the first entry in the file is kept,
+// so the result does not depend on which duplicate comes last.
 func saveEntry(yarnLock *YarnLock, entry *YarnLockEntry, keys []string) {
 	// Use name@version as the deduplicated key
 	dedupeKey := entry.Name + "@" + entry.Version
+	if existing, ok := yarnLock.Entries[dedupeKey]; ok && entryRank(&existing) >= entryRank(entry) {
+		return
+	}
 	yarnLock.Entries[dedupeKey] = *entry
 }
 
+// entryRank scores how complete an entry's download metadata is
+func entryRank(entry *YarnLockEntry) int {
+	rank := 0
+	if entry.Integrity != "" {
+		rank += 2
+	}
+	if entry.Resolved != "" {
+		rank++
+	}
+	return rank
+}
+
 // parseEntryHeader parses a package entry header line
 // Examples:
 //   - "express@^4.18.2:"
@@ -3,6 +3,7 @@ package yarnlock
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -141,6 +142,62 @@ debug@2.6.9, debug@^2.6.0:
 	assert.Equal(t, "2.6.9", entry.Version)
 }
 
+func TestParseContent_DuplicateNameVersion(t *testing.T) {
+	withIntegrity := `debug@^2.6.0:
+  version "2.6.9"
+  resolved "https://registry.yarnpkg.com/debug/-/debug-2.6.9.tgz"
+  integrity sha512-abc
+`
+	withoutIntegrity := `debug@2.6.9:
+  version "2.6.9"
+  resolved "https://registry.yarnpkg.com/debug/-/debug-2.6.9.tgz"
+`
+	withoutResolved := `"debug@>=2.0.0":
+  version "2.6.9"
+`
+
+	testCases := []struct {
+		name              string
+		entries           []string
+		expectedIntegrity string
+		expectedResolved  string
+	}{
+		{
+			name:              "integrity entry first",
+			entries:           []string{withIntegrity, withoutIntegrity},
+			expectedIntegrity: "sha512-abc",
+			expectedResolved:  "https://registry.yarnpkg.com/debug/-/debug-2.6.9.tgz",
+		},
+		{
+			name:              "integrity entry last",
+			entries:           []string{withoutIntegrity, withIntegrity},
+			expectedIntegrity: "sha512-abc",
+			expectedResolved:  "https://registry.yarnpkg.com/debug/-/debug-2.6.9.tgz",
+		},
+		{
+			name:             "resolved entry beats a bare one",
+			entries:          []string{withoutResolved, withoutIntegrity},
+			expectedResolved: "https://registry.yarnpkg.com/debug/-/debug-2.6.9.tgz",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			content := []byte("# yarn lockfile v1\n\n" + strings.Join(tc.entries, "\n"))
+
+			for _, parse := range []func([]byte) (*YarnLock, error){ParseContent, NewYarnLockParser().ParseContent} {
+				yarnLock, err := parse(content)
+				assert.NoError(t, err)
+				assert.Len(t, yarnLock.Entries, 1)
+
+				entry := yarnLock.Entries["debug@2.6.9"]
+				assert.Equal(t, tc.expectedIntegrity, entry.Integrity)
+				assert.Equal(t, tc.expectedResolved, entry.Resolved)
+			}
+		})
+	}
+}
+
 func TestParseContent_MultipleEntries(t *testing.T) {
 	content := []byte(`# yarn lockfile v1
 